@@ -0,0 +1,13 @@
+// Package api embeds the OpenAPI description of the HTTP API.
+// The spec is served to clients and, when enabled, enforced on incoming requests.
+package api
+
+import _ "embed"
+
+//go:embed openapi.yaml
+var spec []byte
+
+// Spec returns the embedded OpenAPI document
+func Spec() []byte {
+	return spec
+}
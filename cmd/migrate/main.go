@@ -0,0 +1,179 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/migrations"
+	"gbt-be-template/pkg/logger"
+)
+
+const usage = `Usage: go run ./cmd/migrate [-dir=migrations] <command>
+
+Commands:
+  up [N]           Apply all pending migrations, or the next N
+  down [N]         Roll back the last applied migration, or the last N
+  force VERSION    Record VERSION as applied after repairing a failed migration by hand
+  version          Print the version of the last applied migration
+  create NAME      Create empty up and down files for a new migration, e.g. add_user_nickname`
+
+func main() {
+	// Command line flags
+	dir := flag.String("dir", "migrations", "Migrations directory, used by create")
+	flag.Usage = func() { fmt.Fprintln(flag.CommandLine.Output(), usage) }
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	switch args[0] {
+	case "up", "down", "force", "version":
+	case "create":
+		// New migrations are only files; they are embedded into the binary when it is next built
+		if len(args) != 2 || !migrations.ValidName(args[1]) {
+			flag.Usage()
+			os.Exit(1)
+		}
+		if err := createMigration(*dir, args[1]); err != nil {
+			log.Fatalf("Failed to create migration: %v", err)
+		}
+		return
+	default:
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	// The migrations are Postgres SQL; other databases are auto migrated instead
+	if cfg.Database.Driver != "postgres" {
+		log.Fatalf("Migrations can only be applied to a Postgres database, not %s", cfg.Database.Driver)
+	}
+
+	db, err := repository.NewDatabase(cfg, logger.New(cfg.Log.Level, cfg.Log.Format))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	migrator, err := db.Migrator()
+	if err != nil {
+		log.Fatalf("Failed to open migrations: %v", err)
+	}
+	defer migrator.Close()
+
+	if err := run(migrator, args[0], args[1:]); err != nil {
+		migrator.Close()
+		db.Close()
+		log.Fatalf("Failed to run %s: %v", args[0], err)
+	}
+	printVersion(migrator)
+}
+
+// run executes a command against the database
+func run(migrator *repository.Migrator, command string, args []string) error {
+	switch command {
+	case "up":
+		if len(args) == 0 {
+			return migrator.Up()
+		}
+		n, err := steps(args)
+		if err != nil {
+			return err
+		}
+		return migrator.Steps(n)
+	case "down":
+		n := 1
+		if len(args) > 0 {
+			var err error
+			if n, err = steps(args); err != nil {
+				return err
+			}
+		}
+		return migrator.Steps(-n)
+	case "force":
+		if len(args) != 1 {
+			return fmt.Errorf("force takes the version to record")
+		}
+		version, err := strconv.Atoi(args[0])
+		if err != nil || version < -1 {
+			return fmt.Errorf("invalid version %q", args[0])
+		}
+		return migrator.Force(version)
+	case "version":
+		if len(args) != 0 {
+			return fmt.Errorf("version takes no arguments")
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown command %q", command)
+	}
+}
+
+// steps parses the number of migrations to apply or roll back
+func steps(args []string) (int, error) {
+	if len(args) != 1 {
+		return 0, fmt.Errorf("expected a single number of migrations")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid number of migrations %q", args[0])
+	}
+	return n, nil
+}
+
+// printVersion prints the version of the last applied migration
+func printVersion(migrator *repository.Migrator) {
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		log.Printf("Failed to read the migration version: %v", err)
+		return
+	}
+	fmt.Println(describeVersion(version, dirty))
+}
+
+// describeVersion describes the version of the last applied migration
+func describeVersion(version uint, dirty bool) string {
+	switch {
+	case version == 0:
+		return "No migrations applied"
+	case dirty:
+		return fmt.Sprintf("Version %d (dirty: the migration failed halfway, repair it and run force)", version)
+	default:
+		return fmt.Sprintf("Version %d", version)
+	}
+}
+
+// createMigration creates empty up and down files for the migration following the highest
+// one in dir
+func createMigration(dir, name string) error {
+	version, err := migrations.NextVersion(dir)
+	if err != nil {
+		return err
+	}
+
+	base := migrations.BasePath(dir, version, name)
+	for _, path := range []string{base + ".up.sql", base + ".down.sql"} {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err != nil {
+			return err
+		}
+		if err := f.Close(); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("Created %s.up.sql and %s.down.sql\n", base, base)
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCreateMigration(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "000037_add_user_version.up.sql"), []byte("SELECT 1;"), 0o644))
+
+	require.NoError(t, createMigration(dir, "add_user_nickname"))
+	for _, name := range []string{"000038_add_user_nickname.up.sql", "000038_add_user_nickname.down.sql"} {
+		content, err := os.ReadFile(filepath.Join(dir, name))
+		require.NoError(t, err, name)
+		assert.Empty(t, content)
+	}
+
+	// The next migration follows the one just created
+	require.NoError(t, createMigration(dir, "add_user_bio"))
+	assert.FileExists(t, filepath.Join(dir, "000039_add_user_bio.up.sql"))
+
+	assert.Error(t, createMigration(filepath.Join(dir, "missing"), "add_user_bio"))
+}
+
+func TestRun_Version(t *testing.T) {
+	// version only prints, so it doesn't touch the database
+	assert.NoError(t, run(nil, "version", nil))
+	assert.Error(t, run(nil, "version", []string{"3"}))
+}
+
+func TestDescribeVersion(t *testing.T) {
+	assert.Equal(t, "No migrations applied", describeVersion(0, false))
+	assert.Equal(t, "Version 37", describeVersion(37, false))
+	assert.Contains(t, describeVersion(37, true), "dirty")
+}
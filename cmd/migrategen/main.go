@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/migrations"
+	"gbt-be-template/pkg/logger"
+)
+
+func main() {
+	// Command line flags
+	name := flag.String("name", "", "Migration name, e.g. add_user_nickname (required)")
+	dir := flag.String("dir", "migrations", "Migrations directory")
+	flag.Parse()
+
+	if !migrations.ValidName(*name) {
+		fmt.Println("Usage: go run cmd/migrategen/main.go -name=add_user_nickname [-dir=migrations]")
+		os.Exit(1)
+	}
+
+	// Load configuration
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	// The migrations are Postgres SQL; other databases are auto migrated instead
+	if cfg.Database.Driver != "postgres" {
+		log.Fatalf("Migrations can only be generated against a Postgres database, not %s", cfg.Database.Driver)
+	}
+
+	// Connect to the database holding the current schema, normally one migrated with 'make migrate-up'
+	db, err := repository.NewDatabase(cfg, logger.New(cfg.Log.Level, cfg.Log.Format))
+	if err != nil {
+		log.Fatalf("Failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	up, err := db.PlanMigration(ctx)
+	if err != nil {
+		log.Fatalf("Failed to diff models against the schema: %v", err)
+	}
+	if len(up) == 0 {
+		fmt.Println("Schema matches the models, no migration generated")
+		return
+	}
+
+	version, err := migrations.NextVersion(*dir)
+	if err != nil {
+		log.Fatalf("Failed to read migrations: %v", err)
+	}
+
+	base := migrations.BasePath(*dir, version, *name)
+	header := "-- Generated from the GORM models by cmd/migrategen. Review before applying:\n" +
+		"-- removed fields are not detected and TODO statements must be written by hand.\n"
+	if err := writeMigration(base+".up.sql", header, up); err != nil {
+		log.Fatalf("Failed to write migration: %v", err)
+	}
+	if err := writeMigration(base+".down.sql", header, repository.ReverseMigration(up)); err != nil {
+		log.Fatalf("Failed to write migration: %v", err)
+	}
+
+	fmt.Printf("Generated %s.up.sql and %s.down.sql with %d statements\n", base, base, len(up))
+}
+
+// writeMigration writes statements to a new migration file, refusing to overwrite an existing one
+func writeMigration(path, header string, statements []string) error {
+	var b strings.Builder
+	b.WriteString(header)
+	b.WriteString("\n")
+	for _, stmt := range statements {
+		b.WriteString(stmt)
+		if !strings.HasPrefix(stmt, "--") {
+			b.WriteString(";")
+		}
+		b.WriteString("\n")
+	}
+
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(b.String()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
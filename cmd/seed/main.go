@@ -10,6 +10,7 @@ import (
 	"gbt-be-template/internal/config"
 	"gbt-be-template/internal/models"
 	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
 
 	"golang.org/x/crypto/bcrypt"
 )
@@ -36,21 +37,29 @@ func main() {
 	}
 
 	// Initialize logger
-	// logger := logger.New(cfg.Log.Level, cfg.Log.Format)
-	// removed logger from below "db, err := repository.NewDatabase(cfg)" because newDatabase function accept only 1 argument
+	appLogger := logger.New(cfg.Log.Level, cfg.Log.Format)
 
 	// Initialize database
-	db, err := repository.NewDatabase(cfg)
+	db, err := repository.NewDatabase(cfg, appLogger)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
 	defer db.Close()
 
-	// Initialize repository
+	// Initialize repositories
 	userRepo := repository.NewUserRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	permissionRepo := repository.NewPermissionRepository(db)
 
-	// Check if admin already exists
+	// Create the default roles and permissions, keeping those that already exist
 	ctx := context.Background()
+	fmt.Println("Seeding roles and permissions...")
+	roles, err := seedRBAC(ctx, roleRepo, permissionRepo)
+	if err != nil {
+		log.Fatalf("Failed to seed roles and permissions: %v", err)
+	}
+
+	// Check if admin already exists
 	existingUser, err := userRepo.GetByEmail(ctx, *email)
 	if err != nil {
 		log.Fatalf("Failed to check existing user: %v", err)
@@ -89,10 +98,14 @@ func main() {
 	if err := userRepo.Create(ctx, adminUser); err != nil {
 		log.Fatalf("Failed to create admin user: %v", err)
 	}
+	if err := roleRepo.AssignToUser(ctx, adminUser.ID, []uint{roles[models.RoleAdmin].ID}); err != nil {
+		log.Fatalf("Failed to assign admin role: %v", err)
+	}
 
 	fmt.Printf("✅ Admin user created successfully!\n")
 	fmt.Printf("   Email: %s\n", adminUser.Email)
 	fmt.Printf("   Username: %s\n", adminUser.Username)
 	fmt.Printf("   ID: %d\n", adminUser.ID)
 	fmt.Printf("   Is Admin: %t\n", adminUser.IsAdmin)
+	fmt.Printf("   Roles: %s\n", models.RoleAdmin)
 }
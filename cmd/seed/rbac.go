@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+)
+
+// seedRBAC creates the known permissions and the default roles that are missing. Roles that
+// already exist get the default permissions they lack, keeping any others they were given. It
+// returns the roles by name.
+func seedRBAC(ctx context.Context, roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository) (map[string]*models.Role, error) {
+	permissionIDs := make(map[string]uint, len(models.KnownPermissions))
+	for _, entry := range models.PermissionCatalog() {
+		permission, err := permissionRepo.GetByName(ctx, entry.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get permission %s: %w", entry.Name, err)
+		}
+		if permission == nil {
+			permission = &models.Permission{
+				Name:        entry.Name,
+				Description: permissionDescription(entry),
+				Resource:    entry.Resource,
+				Action:      entry.Action,
+			}
+			if err := permissionRepo.Create(ctx, permission); err != nil {
+				return nil, fmt.Errorf("failed to create permission %s: %w", entry.Name, err)
+			}
+			fmt.Printf("   Created permission %s\n", entry.Name)
+		}
+		permissionIDs[entry.Name] = permission.ID
+	}
+
+	roles := make(map[string]*models.Role, len(models.DefaultRoles))
+	for _, defaultRole := range models.DefaultRoles {
+		role, err := roleRepo.GetByName(ctx, defaultRole.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role %s: %w", defaultRole.Name, err)
+		}
+		if role == nil {
+			role = &models.Role{Name: defaultRole.Name, Description: defaultRole.Description, IsActive: true}
+			if err := roleRepo.Create(ctx, role); err != nil {
+				return nil, fmt.Errorf("failed to create role %s: %w", defaultRole.Name, err)
+			}
+			fmt.Printf("   Created role %s\n", defaultRole.Name)
+		}
+
+		// GetByName doesn't load permissions
+		role, err = roleRepo.GetByID(ctx, role.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get role %s: %w", defaultRole.Name, err)
+		}
+		ids := make([]uint, 0, len(role.Permissions)+len(defaultRole.Permissions))
+		for _, permission := range role.Permissions {
+			ids = append(ids, permission.ID)
+		}
+		missing := 0
+		for _, name := range defaultRole.Permissions {
+			if id := permissionIDs[name]; !slices.Contains(ids, id) {
+				ids = append(ids, id)
+				missing++
+			}
+		}
+		if missing > 0 {
+			if err := roleRepo.ReplacePermissions(ctx, role.ID, ids); err != nil {
+				return nil, fmt.Errorf("failed to set permissions of role %s: %w", defaultRole.Name, err)
+			}
+			fmt.Printf("   Granted %d permissions to role %s\n", missing, defaultRole.Name)
+		}
+		roles[role.Name] = role
+	}
+	return roles, nil
+}
+
+// permissionDescription describes a permission by its resource and action, like "List all users"
+func permissionDescription(entry models.PermissionCatalogEntry) string {
+	action := strings.ToUpper(entry.Action[:1]) + entry.Action[1:]
+	switch entry.Action {
+	case "list":
+		return fmt.Sprintf("%s all %ss", action, entry.Resource)
+	case "create":
+		return fmt.Sprintf("%s new %ss", action, entry.Resource)
+	}
+	return fmt.Sprintf("%s %ss", action, entry.Resource)
+}
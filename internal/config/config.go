@@ -2,35 +2,82 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
+	"gbt-be-template/pkg/utils"
+
 	"github.com/joho/godotenv"
 )
 
 // Config holds all configuration for our application
 type Config struct {
-	Server    ServerConfig
-	Database  DatabaseConfig
-	JWT       JWTConfig
-	Logger    LoggerConfig
-	CORS      CORSConfig
-	RateLimit RateLimitConfig
-	Log      LogConfig
+	Server        ServerConfig
+	Database      DatabaseConfig
+	JWT           JWTConfig
+	Logger        LoggerConfig
+	CORS          CORSConfig
+	RateLimit     RateLimitConfig
+	Lockout       LockoutConfig
+	LoginBackoff  LoginBackoffConfig
+	Session       SessionConfig
+	LoginHistory  LoginHistoryConfig
+	Suspicious    SuspiciousLoginConfig
+	AuthCookies   AuthCookieConfig
+	Suspension    SuspensionConfig
+	Deletion      AccountDeletionConfig
+	Reactivation  ReactivationConfig
+	PasswordReset PasswordResetConfig
+	EmailChange   EmailChangeConfig
+	Password      PasswordPolicyConfig
+	Pwned         PwnedPasswordsConfig
+	TwoFactor     TwoFactorConfig
+	OTP           OTPConfig
+	Mail          MailConfig
+	SMS           SMSConfig
+	Encryption    EncryptionConfig
+	Concurrency   ConcurrencyConfig
+	Storage       StorageConfig
+	Upload        UploadConfig
+	Scanner       ScannerConfig
+	Avatar        AvatarConfig
+	Jobs          JobsConfig
+	Redis         RedisConfig
+	Lock          LockConfig
+	Revocation    RevocationConfig
+	Leader        LeaderConfig
+	Static        StaticConfig
+	Batch         BatchConfig
+	OpenAPI       OpenAPIConfig
+	OAuth2        OAuth2Config
+	TokenExchange TokenExchangeConfig
+	OIDC          OIDCConfig
+	SAML          SAMLConfig
+	LDAP          LDAPConfig
+	WebAuthn      WebAuthnConfig
+	APIKeys       APIKeyConfig
+	MTLS          MTLSConfig
+	Authz         AuthzConfig
+	Tenancy       TenancyConfig
+	Metrics       MetricsConfig
+	Log           LogConfig
 }
 
 type LogConfig struct {
-    Level  string
-    Format string
+	Level  string
+	Format string
 }
 
 // ServerConfig holds server configuration
 type ServerConfig struct {
-	Port string
-	Host string
-	Env  string
+	Port        string
+	Host        string
+	Env         string
+	DrainPeriod time.Duration // Time between failing readiness and closing the listener on shutdown
 }
 
 // GetTimeout returns the server timeout duration
@@ -40,21 +87,67 @@ func (s *ServerConfig) GetTimeout() time.Duration {
 
 // DatabaseConfig holds database configuration
 type DatabaseConfig struct {
+	Driver          string // postgres, mysql or sqlite
 	Host            string
 	Port            string
 	User            string
 	Password        string
-	Name            string
+	Name            string // Path of the database file with the sqlite driver
 	SSLMode         string
-	MaxOpenConns    int
-	MaxIdleConns    int
-	ConnMaxLifetime time.Duration
+	MaxOpenConns    int           // 0 for no limit
+	MaxIdleConns    int           // Open connections kept for reuse
+	ConnMaxLifetime time.Duration // Connections are closed once this old, 0 to keep them
+	ConnMaxIdleTime time.Duration // Idle connections are closed after this long, 0 to keep them
+
+	// Queries taking longer than SlowQueryThreshold are logged as warnings, 0 to turn it off
+	SlowQueryThreshold time.Duration
+	LogQueryParams     bool // Logged SQL includes the values of its parameters rather than placeholders
+
+	// Connecting at startup is retried while the database isn't reachable yet
+	ConnectRetries    int           // Attempts after the first one, 0 to fail at once
+	ConnectRetryDelay time.Duration // Wait before the first retry, doubled before every further one
+	ConnectMaxWait    time.Duration // Startup fails once it has waited this long
+
+	// Read replicas in the driver's DSN format, serving reads that may lag behind writes
+	ReplicaDSNs []string
+
+	// Apply the embedded SQL migrations at startup. Postgres only.
+	MigrateOnStart bool
+
+	// Rotating credentials, reloaded on SIGHUP and every CredentialsRefresh. Postgres only.
+	CredentialsSource  string // env (User and Password above), file or vault
+	CredentialsFile    string // JSON file with username and password keys
+	CredentialsRefresh time.Duration
+	VaultAddr          string
+	VaultToken         string
+	VaultPath          string // e.g. database/static-creds/app or secret/data/app/db
 }
 
 // JWTConfig holds JWT configuration
 type JWTConfig struct {
-	Secret string
-	Expiry time.Duration
+	Secret        string
+	KeyID         string // kid header of tokens signed with Secret
+	Expiry        time.Duration
+	RefreshExpiry time.Duration
+
+	// Lifetime of refresh tokens of logins asking to be remembered
+	RememberMeExpiry time.Duration
+
+	// Lifetime of tokens issued to admins impersonating a user, which can't be refreshed
+	ImpersonationExpiry time.Duration
+
+	// Previous signing key, still accepted while its tokens expire after a rotation
+	PreviousSecret string
+	PreviousKeyID  string
+}
+
+// Keys returns the keys tokens are signed and validated with
+func (c JWTConfig) Keys() utils.JWTKeys {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: c.KeyID, Secret: c.Secret}}
+	if c.PreviousSecret != "" {
+		keys.Previous = append(keys.Previous, utils.JWTKey{ID: c.PreviousKeyID, Secret: c.PreviousSecret})
+	}
+	return keys
 }
 
 // LoggerConfig holds logger configuration
@@ -70,10 +163,490 @@ type CORSConfig struct {
 	AllowedHeaders []string
 }
 
-// RateLimitConfig holds rate limiting configuration
+// RateLimitConfig holds rate limiting configuration.
+// Requests and Window are the default limit for route groups without a named policy.
 type RateLimitConfig struct {
 	Requests int
 	Window   time.Duration
+	Policies map[string]RateLimitPolicy
+}
+
+// RateLimitPolicy is a named throttle policy attachable to a route group
+type RateLimitPolicy struct {
+	Name      string
+	Requests  int
+	Window    time.Duration
+	Key       string // "ip" or "user"
+	Unlimited bool
+}
+
+// Policy returns the named policy, falling back to the default limit per IP
+func (c *RateLimitConfig) Policy(name string) RateLimitPolicy {
+	if policy, ok := c.Policies[name]; ok {
+		return policy
+	}
+	return RateLimitPolicy{Name: name, Requests: c.Requests, Window: c.Window, Key: "ip"}
+}
+
+// defaultRateLimitPolicies is used when RATE_LIMIT_POLICIES is not set
+const defaultRateLimitPolicies = "auth=10/1m:ip,read=600/1m:user,write=120/1m:user,admin=unlimited"
+
+// parseRateLimitPolicies parses a comma separated list of policies in the form
+// name=requests/window[:ip|user] or name=unlimited
+func parseRateLimitPolicies(value string) (map[string]RateLimitPolicy, error) {
+	policies := make(map[string]RateLimitPolicy)
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		name, spec, ok := strings.Cut(entry, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid rate limit policy %q", entry)
+		}
+
+		policy := RateLimitPolicy{Name: name, Key: "ip"}
+		if spec == "unlimited" {
+			policy.Unlimited = true
+			policies[name] = policy
+			continue
+		}
+
+		if limit, key, hasKey := strings.Cut(spec, ":"); hasKey {
+			if key != "ip" && key != "user" {
+				return nil, fmt.Errorf("invalid key %q in rate limit policy %q", key, name)
+			}
+			spec, policy.Key = limit, key
+		}
+
+		requests, window, ok := strings.Cut(spec, "/")
+		if !ok {
+			return nil, fmt.Errorf("invalid limit in rate limit policy %q", name)
+		}
+		var err error
+		if policy.Requests, err = strconv.Atoi(requests); err != nil || policy.Requests <= 0 {
+			return nil, fmt.Errorf("invalid request count in rate limit policy %q", name)
+		}
+		if policy.Window, err = time.ParseDuration(window); err != nil || policy.Window <= 0 {
+			return nil, fmt.Errorf("invalid window in rate limit policy %q", name)
+		}
+
+		policies[name] = policy
+	}
+	return policies, nil
+}
+
+// StorageConfig holds object storage configuration
+type StorageConfig struct {
+	Driver        string // "local", "s3", "gcs" or "azure", defaults to "s3" in production
+	LocalPath     string
+	PublicBaseURL string // Base URL public objects such as avatars are served from
+	S3            S3Config
+	GCS           GCSConfig
+	Azure         AzureConfig
+}
+
+// GCSConfig holds settings for a Google Cloud Storage bucket
+type GCSConfig struct {
+	Bucket          string
+	CredentialsFile string // Service account key file; empty for unauthenticated emulators
+	Endpoint        string
+}
+
+// AzureConfig holds settings for an Azure Blob Storage container
+type AzureConfig struct {
+	AccountName string
+	AccountKey  string
+	Container   string
+	Endpoint    string // Empty for the account's default endpoint
+}
+
+// S3Config holds settings for an S3-compatible bucket such as AWS S3 or MinIO
+type S3Config struct {
+	Endpoint        string
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // For temporary credentials
+	PathStyle       bool   // Required by MinIO and most self-hosted S3 implementations
+	CreateBucket    bool   // Create the bucket on startup if it's missing, for development with MinIO
+}
+
+// UploadConfig holds chunked upload configuration
+type UploadConfig struct {
+	ChunkSize       int64
+	SessionExpiry   time.Duration // Unfinished uploads are cleaned up after this
+	CleanupInterval time.Duration
+	UserQuota       int64         // Total bytes of stored files per user, 0 for unlimited
+	PresignExpiry   time.Duration // Validity of presigned upload and download URLs
+	Avatar          UploadPolicy
+	Import          UploadPolicy
+}
+
+// UploadPolicy restricts what may be uploaded for one upload purpose.
+// Empty allowlists and zero limits are not enforced.
+type UploadPolicy struct {
+	MaxSize      int64
+	Extensions   []string // Allowed filename extensions including the dot
+	ContentTypes []string // Allowed content types, detected from the file content
+	MaxWidth     int      // Image dimension limits in pixels
+	MaxHeight    int
+}
+
+// Policy returns the upload policy of a purpose
+func (c UploadConfig) Policy(purpose string) UploadPolicy {
+	if purpose == "avatar" {
+		return c.Avatar
+	}
+	return c.Import
+}
+
+// ScannerConfig holds malware scanning configuration for uploads
+type ScannerConfig struct {
+	Driver      string // "none", "clamav" or "icap"
+	Address     string // clamd host:port or unix:/path, or ICAP host:port
+	ICAPService string
+	Timeout     time.Duration
+	FailOpen    bool // Accept uploads unscanned when the scanner is unavailable
+}
+
+// AvatarConfig holds avatar image processing configuration
+type AvatarConfig struct {
+	Variants    []int // Square thumbnail sizes in pixels
+	JPEGQuality int
+	MaxPixels   int // Source images with more pixels are rejected before decoding
+}
+
+// RedisConfig holds the connection settings of the shared Redis instance
+type RedisConfig struct {
+	Addr     string
+	Password string
+	DB       int
+}
+
+// RevocationConfig holds settings for revoking access tokens before they expire, such as on logout
+type RevocationConfig struct {
+	Driver string // memory (single instance only) or redis
+}
+
+// LockConfig holds distributed lock configuration used to run scheduled tasks on one instance
+type LockConfig struct {
+	Driver     string   // postgres (the default on Postgres databases), redis or local (single instance only)
+	RedisAddrs []string // Independent Redis masters for Redlock; defaults to the shared Redis instance
+}
+
+// LeaderConfig holds leader election settings for singleton background work
+type LeaderConfig struct {
+	Enabled       bool          // Run the scheduler only on the elected instance
+	LeaseTTL      time.Duration // Failover happens within this long after the leader dies
+	RetryInterval time.Duration // How often followers try to take over
+}
+
+// StaticConfig holds static file and single-page app serving configuration
+type StaticConfig struct {
+	Enabled        bool
+	Dir            string        // Directory to serve; empty serves the build embedded from web/dist
+	SPAFallback    bool          // Serve index.html for unknown paths so client-side routes work
+	ImmutablePaths []string      // URL prefixes of content hashed assets
+	MaxAge         time.Duration // Cache lifetime of content hashed assets
+}
+
+// MetricsConfig holds configuration of the Prometheus metrics endpoint
+type MetricsConfig struct {
+	Enabled bool
+	Path    string
+	Token   string // Bearer token scrapers must send, empty to serve metrics to anyone
+}
+
+// BatchConfig holds limits for the batch API endpoint
+type BatchConfig struct {
+	MaxRequests int   // Sub-requests allowed per batch
+	MaxBodySize int64 // Maximum size of the whole batch request body in bytes
+}
+
+// OpenAPIConfig holds settings for enforcing the OpenAPI spec on incoming requests
+type OpenAPIConfig struct {
+	Validate bool   // Reject requests that do not match the spec with a 400
+	SpecFile string // Optional spec to load instead of the embedded api/openapi.yaml
+}
+
+// JobsConfig holds background job queue configuration
+type JobsConfig struct {
+	PollInterval time.Duration
+	Concurrency  int
+	MaxAttempts  int
+	StaleAfter   time.Duration // Running jobs older than this are assumed abandoned and retried
+}
+
+// ConcurrencyConfig holds in-flight request limits used for load shedding
+type ConcurrencyConfig struct {
+	MaxInFlight  int            // Global cap on concurrent API requests, 0 disables
+	QueueSize    int            // Requests allowed to wait for a slot
+	QueueTimeout time.Duration  // How long a request may wait, 0 sheds immediately
+	GroupLimits  map[string]int // Per route group caps
+}
+
+// LockoutConfig holds account lockout configuration for failed logins
+type LockoutConfig struct {
+	MaxFailedAttempts int // 0 disables lockout
+	Duration          time.Duration
+}
+
+// Ways of enforcing SessionConfig.MaxPerUser
+const (
+	SessionLimitEvictOldest = "evict_oldest" // End the oldest sessions to make room
+	SessionLimitReject      = "reject"       // Refuse the login
+)
+
+// SessionConfig holds limits on the signed-in sessions of a user
+type SessionConfig struct {
+	MaxPerUser  int    // 0 allows any number of sessions
+	LimitPolicy string // SessionLimitEvictOldest or SessionLimitReject
+}
+
+// AuthCookieConfig holds settings for handing tokens to browser frontends in cookies
+type AuthCookieConfig struct {
+	Enabled  bool
+	Domain   string // Empty limits the cookies to the API host
+	Secure   bool   // Only send the cookies over HTTPS
+	SameSite string // lax, strict or none
+}
+
+// LoginBackoffConfig holds settings for slowing down repeated failed logins
+type LoginBackoffConfig struct {
+	Enabled          bool
+	IPThreshold      int           // Failed logins from one IP before it is made to wait
+	AccountThreshold int           // Failed logins for one account before it is made to wait
+	BaseDelay        time.Duration // First wait, doubled by every further failure
+	MaxDelay         time.Duration
+	ResetAfter       time.Duration // Failures are forgotten after this long without a new one
+}
+
+// LoginHistoryConfig holds settings for the recorded login history of users
+type LoginHistoryConfig struct {
+	Retention     time.Duration // How long login events are kept, 0 to keep them forever
+	PurgeInterval time.Duration // How often login events past the retention are removed
+}
+
+// SuspiciousLoginConfig holds settings for spotting logins from unfamiliar places
+type SuspiciousLoginConfig struct {
+	Enabled             bool          // Email users about logins from an IP address and device not seen before
+	RequireVerification bool          // Hold such logins until the user confirms them with an emailed link
+	VerificationTTL     time.Duration // How long the emailed link stays valid
+}
+
+// SuspensionConfig holds settings for admin account suspensions
+type SuspensionConfig struct {
+	LiftInterval time.Duration // How often suspensions past their end time are cleared
+}
+
+// AccountDeletionConfig holds settings for self-service account deletion
+type AccountDeletionConfig struct {
+	GracePeriod     time.Duration // How long a deleted account can be restored by logging in again
+	PurgeInterval   time.Duration // How often accounts past their grace period are anonymized
+	HardDeleteAfter time.Duration // How long soft-deleted users are kept before they are removed for good, 0 to keep them forever
+	Anonymize       bool          // Scrub the personal data of soft-deleted users, who then can't be restored
+	AnonymizeAfter  time.Duration // How long soft-deleted users keep their personal data, 0 to scrub it on deletion
+}
+
+// ReactivationConfig holds settings for reactivating deactivated accounts
+type ReactivationConfig struct {
+	TokenTTL time.Duration // How long an emailed reactivation link stays valid
+}
+
+// PasswordResetConfig holds settings for resetting forgotten passwords
+type PasswordResetConfig struct {
+	TokenTTL time.Duration // How long an emailed reset link stays valid
+}
+
+// EmailChangeConfig holds settings for changing the email of an account
+type EmailChangeConfig struct {
+	TokenTTL time.Duration // How long the confirmation link emailed to the new address stays valid
+}
+
+// PasswordPolicyConfig holds rules local passwords must follow
+type PasswordPolicyConfig struct {
+	MaxAge time.Duration // Passwords older than this must be reset before logging in; zero disables expiry
+}
+
+// PwnedPasswordsConfig holds settings for refusing passwords known from data breaches
+type PwnedPasswordsConfig struct {
+	Enabled  bool          // Check new passwords against Have I Been Pwned
+	Timeout  time.Duration // How long to wait for the service
+	FailOpen bool          // Accept passwords when the service can't be reached
+}
+
+// TwoFactorConfig holds settings for TOTP two-factor authentication
+type TwoFactorConfig struct {
+	Issuer       string        // Shown next to the account in authenticator apps
+	ChallengeTTL time.Duration // How long a password login waits for the TOTP code
+
+	// How long devices the user trusts skip the TOTP code, 0 to always ask for it
+	TrustedDeviceTTL time.Duration
+}
+
+// OTPConfig holds settings for logging in with a code texted to the user's phone
+type OTPConfig struct {
+	Enabled       bool
+	CodeTTL       time.Duration // How long a texted code stays valid
+	MaxAttempts   int           // Wrong codes allowed before the code is discarded
+	MaxRequests   int           // Codes that may be texted to one number per RequestWindow
+	RequestWindow time.Duration
+}
+
+// MailConfig holds outgoing email configuration
+type MailConfig struct {
+	Driver       string // "log" or "smtp"
+	From         string
+	SMTPHost     string
+	SMTPPort     int
+	SMTPUsername string
+	SMTPPassword string
+	Timeout      time.Duration
+	LinkBaseURL  string // Frontend URL that links in emails point to
+}
+
+// SMSConfig holds outgoing text message configuration
+type SMSConfig struct {
+	Driver           string // "log" or "twilio"
+	From             string // Sending number or Twilio messaging service SID
+	TwilioAccountSID string
+	TwilioAuthToken  string
+	Timeout          time.Duration
+}
+
+// EncryptionConfig holds keys for encrypting sensitive user columns; encryption is off without keys
+type EncryptionConfig struct {
+	Keys             string        // Comma separated "id:base64" AES-256 keys, old keys kept for decryption
+	PrimaryKeyID     string        // Key new values are encrypted with, defaults to the last key listed
+	IndexKey         string        // Base64 HMAC key for blind indexes; changing it breaks email lookups
+	RotationInterval time.Duration // How often rows are re-encrypted with the primary key
+}
+
+// OAuth2Config holds configuration for the optional authorization server mode
+type OAuth2Config struct {
+	Enabled           bool
+	Issuer            string
+	ConsentURL        string // Frontend consent screen advertised as the authorization endpoint
+	AuthCodeExpiry    time.Duration
+	AccessTokenExpiry time.Duration
+}
+
+// TokenExchangeConfig holds configuration for RFC 8693 token exchange with external IdPs
+type TokenExchangeConfig struct {
+	Enabled       bool
+	Issuers       []string // Trusted external issuers, discovered via OIDC metadata
+	Audience      string   // Required audience of subject tokens (empty to skip the check)
+	AutoProvision bool     // Create local users for unknown external identities
+	GroupsClaim   string
+	AdminGroup    string // Members of this external group receive admin tokens
+}
+
+// OIDCConfig holds configuration for accepting access tokens of an external OpenID Provider
+// on protected routes, next to locally issued tokens
+type OIDCConfig struct {
+	Enabled       bool
+	Issuer        string // Discovered via OIDC metadata
+	Audience      string // Required audience of access tokens (empty to skip the check)
+	AutoProvision bool   // Create local users for unknown external identities
+	GroupsClaim   string
+	AdminGroup    string // Members of this external group act as admins
+}
+
+// SAMLConfig holds configuration for single sign-on through a SAML identity provider
+type SAMLConfig struct {
+	Enabled           bool
+	BaseURL           string // Public URL of the API; the metadata and ACS URLs are derived from it
+	EntityID          string // Defaults to the metadata URL
+	IDPMetadataURL    string
+	IDPMetadataFile   string // Read instead of fetching IDPMetadataURL when set
+	CertFile          string // Service provider certificate and key, used to sign requests and decrypt assertions
+	KeyFile           string
+	AllowIDPInitiated bool
+	RedirectURL       string        // Frontend page receiving the single-use login code
+	CodeTTL           time.Duration // Lifetime of login codes
+	AutoProvision     bool          // Create local users for unknown external identities
+	TrustEmail        bool          // Link existing users by the asserted email address
+
+	// Names of the assertion attributes mapped onto users
+	EmailAttribute     string
+	UsernameAttribute  string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	GroupsAttribute    string
+	AdminGroup         string // When set, membership of this group decides admin status
+}
+
+// LDAPConfig holds configuration for checking login passwords against an LDAP directory
+// or Active Directory instead of the local password hashes
+type LDAPConfig struct {
+	Enabled            bool
+	URL                string // ldap:// or ldaps://
+	StartTLS           bool
+	BindDN             string // Service account searching for users; anonymous search when empty
+	BindPassword       string
+	BaseDN             string
+	UserFilter         string // {login} is replaced by the escaped login
+	Timeout            time.Duration
+	AutoProvision      bool // Create local users for directory users signing in for the first time
+	IDAttribute        string
+	EmailAttribute     string
+	UsernameAttribute  string
+	FirstNameAttribute string
+	LastNameAttribute  string
+	GroupsAttribute    string
+	AdminGroup         string // When set, membership of this group decides admin status
+}
+
+// WebAuthnConfig holds configuration for passkey registration and login
+type WebAuthnConfig struct {
+	Enabled         bool
+	RPID            string   // Relying party ID, the domain passkeys are bound to
+	RPDisplayName   string   // Shown by the browser during registration
+	RPOrigins       []string // Origins of the frontends allowed to use the passkeys
+	Timeout         time.Duration
+	ChallengeDriver string // memory (single instance only) or redis
+}
+
+// APIKeyConfig holds configuration for long-lived API keys used by integrations
+type APIKeyConfig struct {
+	Enabled    bool
+	MaxPerUser int // Keys a user may hold at once
+}
+
+// MTLSConfig holds configuration for the separate listener serving service-to-service routes
+// to clients presenting a certificate
+type MTLSConfig struct {
+	Enabled         bool
+	Port            string
+	CertFile        string // Server certificate and key
+	KeyFile         string
+	ClientCAFile    string   // CA bundle client certificates must chain to
+	AllowedServices []string // Service identities allowed in; any verified certificate when empty
+}
+
+// AuthzConfig holds configuration for deciding what users may do based on their roles
+type AuthzConfig struct {
+	Driver               string        // "rbac" or "casbin"
+	CasbinModelFile      string        // Casbin model; the built-in RBAC model when empty
+	CasbinReloadInterval time.Duration // How often the casbin policy is reloaded from the database
+	RulesEnabled         bool          // Evaluate attribute-based access rules on top of role checks
+	RulesTimezone        string        // Time zone of the env attributes of access rules
+	AdminFromRoles       bool          // Decide admin status by the admin role instead of the is_admin claim
+	RolesInToken         bool          // Put the user's roles in access tokens
+	PermissionsInToken   bool          // Put the user's permissions in access tokens, checked before the database
+	DefaultRoles         []string      // Names of the roles given to users when they are created
+}
+
+// TenancyConfig holds configuration for serving several isolated tenants from one deployment
+type TenancyConfig struct {
+	Enabled    bool
+	Header     string // Request header naming the tenant
+	BaseDomain string // Tenants are also named by the subdomain of this domain when set
+	Claim      string // Access token claim holding the tenant of the user
 }
 
 // Load loads configuration from environment variables
@@ -81,15 +654,25 @@ func Load() (*Config, error) {
 	// Load .env file if it exists (ignore error if file doesn't exist)
 	_ = godotenv.Load()
 
+	rateLimitPolicies, err := parseRateLimitPolicies(getEnv("RATE_LIMIT_POLICIES", defaultRateLimitPolicies))
+	if err != nil {
+		return nil, fmt.Errorf("config validation failed: %w", err)
+	}
+
+	env := getEnv("ENV", "development")
+	dbDriver := getEnv("DB_DRIVER", "postgres")
+
 	config := &Config{
 		Server: ServerConfig{
-			Port: getEnv("PORT", "8080"),
-			Host: getEnv("HOST", "localhost"),
-			Env:  getEnv("ENV", "development"),
+			Port:        getEnv("PORT", "8080"),
+			Host:        getEnv("HOST", "localhost"),
+			Env:         env,
+			DrainPeriod: getEnvAsDuration("SHUTDOWN_DRAIN_PERIOD", defaultDrainPeriod(env)),
 		},
 		Database: DatabaseConfig{
+			Driver:          dbDriver,
 			Host:            getEnv("DB_HOST", "localhost"),
-			Port:            getEnv("DB_PORT", "5432"),
+			Port:            getEnv("DB_PORT", defaultDBPort(dbDriver)),
 			User:            getEnv("DB_USER", "postgres"),
 			Password:        getEnv("DB_PASSWORD", "password"),
 			Name:            getEnv("DB_NAME", "gbt_template"),
@@ -97,10 +680,33 @@ func Load() (*Config, error) {
 			MaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
 			MaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 25),
 			ConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", 5*time.Minute),
+			ConnMaxIdleTime: getEnvAsDuration("DB_CONN_MAX_IDLE_TIME", time.Minute),
+			ReplicaDSNs:     getEnvAsSlice("DB_REPLICA_DSNS", []string{}),
+			MigrateOnStart:  getEnvAsBool("DB_MIGRATE_ON_START", false),
+
+			SlowQueryThreshold: time.Duration(getEnvAsInt("DB_SLOW_QUERY_MS", 200)) * time.Millisecond,
+			LogQueryParams:     getEnvAsBool("DB_LOG_QUERY_PARAMS", env == "development"),
+
+			ConnectRetries:    getEnvAsInt("DB_CONNECT_RETRIES", 5),
+			ConnectRetryDelay: getEnvAsDuration("DB_CONNECT_RETRY_DELAY", time.Second),
+			ConnectMaxWait:    getEnvAsDuration("DB_CONNECT_MAX_WAIT", time.Minute),
+
+			CredentialsSource:  getEnv("DB_CREDENTIALS_SOURCE", "env"),
+			CredentialsFile:    getEnv("DB_CREDENTIALS_FILE", ""),
+			CredentialsRefresh: getEnvAsDuration("DB_CREDENTIALS_REFRESH_INTERVAL", time.Minute),
+			VaultAddr:          getEnv("VAULT_ADDR", "http://127.0.0.1:8200"),
+			VaultToken:         getEnv("VAULT_TOKEN", ""),
+			VaultPath:          getEnv("DB_VAULT_PATH", ""),
 		},
 		JWT: JWTConfig{
-			Secret: getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
-			Expiry: getEnvAsDuration("JWT_EXPIRY", 24*time.Hour),
+			Secret:              getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-this-in-production"),
+			KeyID:               getEnv("JWT_KEY_ID", "v1"),
+			Expiry:              getEnvAsDuration("JWT_EXPIRY", 24*time.Hour),
+			RefreshExpiry:       getEnvAsDuration("JWT_REFRESH_EXPIRY", 7*24*time.Hour),
+			RememberMeExpiry:    getEnvAsDuration("JWT_REMEMBER_ME_EXPIRY", 30*24*time.Hour),
+			ImpersonationExpiry: getEnvAsDuration("JWT_IMPERSONATION_EXPIRY", 15*time.Minute),
+			PreviousSecret:      getEnv("JWT_PREVIOUS_SECRET", ""),
+			PreviousKeyID:       getEnv("JWT_PREVIOUS_KEY_ID", ""),
 		},
 		Logger: LoggerConfig{
 			Level:  getEnv("LOG_LEVEL", "info"),
@@ -109,11 +715,309 @@ func Load() (*Config, error) {
 		CORS: CORSConfig{
 			AllowedOrigins: getEnvAsSlice("CORS_ALLOWED_ORIGINS", []string{"http://localhost:3000", "http://localhost:8080"}),
 			AllowedMethods: getEnvAsSlice("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}),
-			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization"}),
+			AllowedHeaders: getEnvAsSlice("CORS_ALLOWED_HEADERS", []string{"Content-Type", "Authorization", "X-CSRF-Token"}),
 		},
 		RateLimit: RateLimitConfig{
 			Requests: getEnvAsInt("RATE_LIMIT_REQUESTS", 100),
 			Window:   getEnvAsDuration("RATE_LIMIT_WINDOW", time.Minute),
+			Policies: rateLimitPolicies,
+		},
+		Concurrency: ConcurrencyConfig{
+			MaxInFlight:  getEnvAsInt("CONCURRENCY_MAX_IN_FLIGHT", 200),
+			QueueSize:    getEnvAsInt("CONCURRENCY_QUEUE_SIZE", 100),
+			QueueTimeout: getEnvAsDuration("CONCURRENCY_QUEUE_TIMEOUT", 0),
+			GroupLimits:  getEnvAsIntMap("CONCURRENCY_GROUP_LIMITS", map[string]int{"auth": 32}),
+		},
+		Storage: StorageConfig{
+			Driver:        getEnv("STORAGE_DRIVER", defaultStorageDriver(env)),
+			LocalPath:     getEnv("STORAGE_LOCAL_PATH", "./data/storage"),
+			PublicBaseURL: getEnv("STORAGE_PUBLIC_BASE_URL", "http://localhost:8080/api/v1/media"),
+			S3: S3Config{
+				Endpoint:        getEnv("STORAGE_S3_ENDPOINT", "https://s3.amazonaws.com"),
+				Region:          getEnv("STORAGE_S3_REGION", getEnv("AWS_REGION", "us-east-1")),
+				Bucket:          getEnv("STORAGE_S3_BUCKET", ""),
+				AccessKeyID:     getEnv("STORAGE_S3_ACCESS_KEY_ID", os.Getenv("AWS_ACCESS_KEY_ID")),
+				SecretAccessKey: getEnv("STORAGE_S3_SECRET_ACCESS_KEY", os.Getenv("AWS_SECRET_ACCESS_KEY")),
+				SessionToken:    getEnv("STORAGE_S3_SESSION_TOKEN", os.Getenv("AWS_SESSION_TOKEN")),
+				PathStyle:       getEnvAsBool("STORAGE_S3_PATH_STYLE", false),
+				CreateBucket:    getEnvAsBool("STORAGE_S3_CREATE_BUCKET", false),
+			},
+			GCS: GCSConfig{
+				Bucket:          getEnv("STORAGE_GCS_BUCKET", ""),
+				CredentialsFile: getEnv("STORAGE_GCS_CREDENTIALS_FILE", os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")),
+				Endpoint:        getEnv("STORAGE_GCS_ENDPOINT", "https://storage.googleapis.com"),
+			},
+			Azure: AzureConfig{
+				AccountName: getEnv("STORAGE_AZURE_ACCOUNT_NAME", ""),
+				AccountKey:  getEnv("STORAGE_AZURE_ACCOUNT_KEY", ""),
+				Container:   getEnv("STORAGE_AZURE_CONTAINER", ""),
+				Endpoint:    getEnv("STORAGE_AZURE_ENDPOINT", ""),
+			},
+		},
+		Upload: UploadConfig{
+			ChunkSize:       int64(getEnvAsInt("UPLOAD_CHUNK_SIZE", 5*1024*1024)),
+			SessionExpiry:   getEnvAsDuration("UPLOAD_SESSION_EXPIRY", 24*time.Hour),
+			CleanupInterval: getEnvAsDuration("UPLOAD_CLEANUP_INTERVAL", time.Hour),
+			UserQuota:       int64(getEnvAsInt("UPLOAD_USER_QUOTA", 1024*1024*1024)),
+			PresignExpiry:   getEnvAsDuration("UPLOAD_PRESIGN_EXPIRY", 15*time.Minute),
+			Avatar: UploadPolicy{
+				MaxSize:      int64(getEnvAsInt("UPLOAD_MAX_AVATAR_SIZE", 5*1024*1024)),
+				Extensions:   getEnvAsSlice("UPLOAD_AVATAR_EXTENSIONS", []string{".jpg", ".jpeg", ".png", ".gif", ".webp", ".svg"}),
+				ContentTypes: getEnvAsSlice("UPLOAD_AVATAR_CONTENT_TYPES", []string{"image/jpeg", "image/png", "image/gif", "image/webp", "image/svg+xml"}),
+				MaxWidth:     getEnvAsInt("UPLOAD_AVATAR_MAX_WIDTH", 4096),
+				MaxHeight:    getEnvAsInt("UPLOAD_AVATAR_MAX_HEIGHT", 4096),
+			},
+			Import: UploadPolicy{
+				MaxSize:      int64(getEnvAsInt("UPLOAD_MAX_IMPORT_SIZE", 500*1024*1024)),
+				Extensions:   getEnvAsSlice("UPLOAD_IMPORT_EXTENSIONS", []string{".csv"}),
+				ContentTypes: getEnvAsSlice("UPLOAD_IMPORT_CONTENT_TYPES", []string{"text/csv"}),
+			},
+		},
+		Scanner: ScannerConfig{
+			Driver:      getEnv("SCANNER_DRIVER", "none"),
+			Address:     getEnv("SCANNER_ADDRESS", "localhost:3310"),
+			ICAPService: getEnv("SCANNER_ICAP_SERVICE", "avscan"),
+			Timeout:     getEnvAsDuration("SCANNER_TIMEOUT", time.Minute),
+			FailOpen:    getEnvAsBool("SCANNER_FAIL_OPEN", false),
+		},
+		Avatar: AvatarConfig{
+			Variants:    getEnvAsIntSlice("AVATAR_VARIANTS", []int{64, 128, 256}),
+			JPEGQuality: getEnvAsInt("AVATAR_JPEG_QUALITY", 85),
+			MaxPixels:   getEnvAsInt("AVATAR_MAX_PIXELS", 40_000_000),
+		},
+		Jobs: JobsConfig{
+			PollInterval: getEnvAsDuration("JOBS_POLL_INTERVAL", time.Second),
+			Concurrency:  getEnvAsInt("JOBS_CONCURRENCY", 2),
+			MaxAttempts:  getEnvAsInt("JOBS_MAX_ATTEMPTS", 5),
+			StaleAfter:   getEnvAsDuration("JOBS_STALE_AFTER", 10*time.Minute),
+		},
+		Redis: RedisConfig{
+			Addr:     getEnv("REDIS_ADDR", "localhost:6379"),
+			Password: getEnv("REDIS_PASSWORD", ""),
+			DB:       getEnvAsInt("REDIS_DB", 0),
+		},
+		Lock: LockConfig{
+			Driver:     getEnv("LOCK_DRIVER", defaultLockDriver(dbDriver)),
+			RedisAddrs: getEnvAsSlice("LOCK_REDIS_ADDRS", []string{}),
+		},
+		Revocation: RevocationConfig{
+			Driver: getEnv("TOKEN_REVOCATION_DRIVER", "memory"),
+		},
+		Leader: LeaderConfig{
+			Enabled:       getEnvAsBool("LEADER_ELECTION_ENABLED", true),
+			LeaseTTL:      getEnvAsDuration("LEADER_LEASE_TTL", 15*time.Second),
+			RetryInterval: getEnvAsDuration("LEADER_RETRY_INTERVAL", 5*time.Second),
+		},
+		Static: StaticConfig{
+			Enabled:        getEnvAsBool("STATIC_ENABLED", false),
+			Dir:            getEnv("STATIC_DIR", ""),
+			SPAFallback:    getEnvAsBool("STATIC_SPA_FALLBACK", true),
+			ImmutablePaths: getEnvAsSlice("STATIC_IMMUTABLE_PATHS", []string{"/assets/", "/static/"}),
+			MaxAge:         getEnvAsDuration("STATIC_MAX_AGE", 365*24*time.Hour),
+		},
+		Metrics: MetricsConfig{
+			Enabled: getEnvAsBool("METRICS_ENABLED", false),
+			Path:    getEnv("METRICS_PATH", "/metrics"),
+			Token:   getEnv("METRICS_TOKEN", ""),
+		},
+		Batch: BatchConfig{
+			MaxRequests: getEnvAsInt("BATCH_MAX_REQUESTS", 20),
+			MaxBodySize: int64(getEnvAsInt("BATCH_MAX_BODY_SIZE", 1<<20)),
+		},
+		OpenAPI: OpenAPIConfig{
+			Validate: getEnvAsBool("OPENAPI_VALIDATION_ENABLED", false),
+			SpecFile: getEnv("OPENAPI_SPEC_FILE", ""),
+		},
+		Lockout: LockoutConfig{
+			MaxFailedAttempts: getEnvAsInt("LOCKOUT_MAX_FAILED_ATTEMPTS", 5),
+			Duration:          getEnvAsDuration("LOCKOUT_DURATION", 15*time.Minute),
+		},
+		Session: SessionConfig{
+			MaxPerUser:  getEnvAsInt("SESSION_MAX_PER_USER", 0),
+			LimitPolicy: getEnv("SESSION_LIMIT_POLICY", SessionLimitEvictOldest),
+		},
+		AuthCookies: AuthCookieConfig{
+			Enabled:  getEnvAsBool("AUTH_COOKIES_ENABLED", false),
+			Domain:   getEnv("AUTH_COOKIE_DOMAIN", ""),
+			Secure:   getEnvAsBool("AUTH_COOKIE_SECURE", true),
+			SameSite: getEnv("AUTH_COOKIE_SAMESITE", "lax"),
+		},
+		LoginBackoff: LoginBackoffConfig{
+			Enabled:          getEnvAsBool("LOGIN_BACKOFF_ENABLED", true),
+			IPThreshold:      getEnvAsInt("LOGIN_BACKOFF_IP_THRESHOLD", 10),
+			AccountThreshold: getEnvAsInt("LOGIN_BACKOFF_ACCOUNT_THRESHOLD", 3),
+			BaseDelay:        getEnvAsDuration("LOGIN_BACKOFF_BASE_DELAY", time.Second),
+			MaxDelay:         getEnvAsDuration("LOGIN_BACKOFF_MAX_DELAY", 15*time.Minute),
+			ResetAfter:       getEnvAsDuration("LOGIN_BACKOFF_RESET_AFTER", time.Hour),
+		},
+		LoginHistory: LoginHistoryConfig{
+			Retention:     getEnvAsDuration("LOGIN_HISTORY_RETENTION", 90*24*time.Hour),
+			PurgeInterval: getEnvAsDuration("LOGIN_HISTORY_PURGE_INTERVAL", time.Hour),
+		},
+		Suspicious: SuspiciousLoginConfig{
+			Enabled:             getEnvAsBool("SUSPICIOUS_LOGIN_ENABLED", true),
+			RequireVerification: getEnvAsBool("SUSPICIOUS_LOGIN_REQUIRE_VERIFICATION", false),
+			VerificationTTL:     getEnvAsDuration("SUSPICIOUS_LOGIN_VERIFICATION_TTL", 15*time.Minute),
+		},
+		Suspension: SuspensionConfig{
+			LiftInterval: getEnvAsDuration("SUSPENSION_LIFT_INTERVAL", time.Minute),
+		},
+		Deletion: AccountDeletionConfig{
+			GracePeriod:     getEnvAsDuration("ACCOUNT_DELETION_GRACE_PERIOD", 30*24*time.Hour),
+			PurgeInterval:   getEnvAsDuration("ACCOUNT_DELETION_PURGE_INTERVAL", time.Hour),
+			HardDeleteAfter: getEnvAsDuration("ACCOUNT_DELETION_HARD_DELETE_AFTER", 0),
+			Anonymize:       getEnvAsBool("ACCOUNT_DELETION_ANONYMIZE", false),
+			AnonymizeAfter:  getEnvAsDuration("ACCOUNT_DELETION_ANONYMIZE_AFTER", 0),
+		},
+		Reactivation: ReactivationConfig{
+			TokenTTL: getEnvAsDuration("REACTIVATION_TOKEN_TTL", 24*time.Hour),
+		},
+		PasswordReset: PasswordResetConfig{
+			TokenTTL: getEnvAsDuration("PASSWORD_RESET_TOKEN_TTL", time.Hour),
+		},
+		EmailChange: EmailChangeConfig{
+			TokenTTL: getEnvAsDuration("EMAIL_CHANGE_TOKEN_TTL", 24*time.Hour),
+		},
+		Password: PasswordPolicyConfig{
+			MaxAge: getEnvAsDuration("PASSWORD_MAX_AGE", 0),
+		},
+		Pwned: PwnedPasswordsConfig{
+			Enabled:  getEnvAsBool("PWNED_PASSWORDS_ENABLED", false),
+			Timeout:  getEnvAsDuration("PWNED_PASSWORDS_TIMEOUT", 2*time.Second),
+			FailOpen: getEnvAsBool("PWNED_PASSWORDS_FAIL_OPEN", true),
+		},
+		TwoFactor: TwoFactorConfig{
+			Issuer:           getEnv("TOTP_ISSUER", "gbt-be-template"),
+			ChallengeTTL:     getEnvAsDuration("TOTP_CHALLENGE_TTL", 5*time.Minute),
+			TrustedDeviceTTL: getEnvAsDuration("TOTP_TRUSTED_DEVICE_TTL", 30*24*time.Hour),
+		},
+		OTP: OTPConfig{
+			Enabled:       getEnvAsBool("OTP_LOGIN_ENABLED", false),
+			CodeTTL:       getEnvAsDuration("OTP_CODE_TTL", 5*time.Minute),
+			MaxAttempts:   getEnvAsInt("OTP_MAX_ATTEMPTS", 5),
+			MaxRequests:   getEnvAsInt("OTP_MAX_REQUESTS", 3),
+			RequestWindow: getEnvAsDuration("OTP_REQUEST_WINDOW", 15*time.Minute),
+		},
+		Mail: MailConfig{
+			Driver:       getEnv("MAIL_DRIVER", "log"),
+			From:         getEnv("MAIL_FROM", "no-reply@localhost"),
+			SMTPHost:     getEnv("SMTP_HOST", "localhost"),
+			SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
+			SMTPUsername: getEnv("SMTP_USERNAME", ""),
+			SMTPPassword: getEnv("SMTP_PASSWORD", ""),
+			Timeout:      getEnvAsDuration("SMTP_TIMEOUT", 30*time.Second),
+			LinkBaseURL:  strings.TrimSuffix(getEnv("MAIL_LINK_BASE_URL", "http://localhost:3000"), "/"),
+		},
+		SMS: SMSConfig{
+			Driver:           getEnv("SMS_DRIVER", "log"),
+			From:             getEnv("SMS_FROM", ""),
+			TwilioAccountSID: getEnv("TWILIO_ACCOUNT_SID", ""),
+			TwilioAuthToken:  getEnv("TWILIO_AUTH_TOKEN", ""),
+			Timeout:          getEnvAsDuration("SMS_TIMEOUT", 10*time.Second),
+		},
+		Encryption: EncryptionConfig{
+			Keys:             getEnv("FIELD_ENCRYPTION_KEYS", ""),
+			PrimaryKeyID:     getEnv("FIELD_ENCRYPTION_PRIMARY_KEY", ""),
+			IndexKey:         getEnv("FIELD_ENCRYPTION_INDEX_KEY", ""),
+			RotationInterval: getEnvAsDuration("FIELD_ENCRYPTION_ROTATION_INTERVAL", time.Hour),
+		},
+		OAuth2: OAuth2Config{
+			Enabled:           getEnvAsBool("OAUTH2_ENABLED", false),
+			Issuer:            getEnv("OAUTH2_ISSUER", "http://localhost:8080"),
+			ConsentURL:        getEnv("OAUTH2_CONSENT_URL", "http://localhost:3000/oauth/authorize"),
+			AuthCodeExpiry:    getEnvAsDuration("OAUTH2_AUTH_CODE_EXPIRY", 5*time.Minute),
+			AccessTokenExpiry: getEnvAsDuration("OAUTH2_ACCESS_TOKEN_EXPIRY", time.Hour),
+		},
+		TokenExchange: TokenExchangeConfig{
+			Enabled:       getEnvAsBool("TOKEN_EXCHANGE_ENABLED", false),
+			Issuers:       getEnvAsSlice("TOKEN_EXCHANGE_ISSUERS", []string{}),
+			Audience:      getEnv("TOKEN_EXCHANGE_AUDIENCE", ""),
+			AutoProvision: getEnvAsBool("TOKEN_EXCHANGE_AUTO_PROVISION", false),
+			GroupsClaim:   getEnv("TOKEN_EXCHANGE_GROUPS_CLAIM", "groups"),
+			AdminGroup:    getEnv("TOKEN_EXCHANGE_ADMIN_GROUP", ""),
+		},
+		SAML: SAMLConfig{
+			Enabled:            getEnvAsBool("SAML_ENABLED", false),
+			BaseURL:            getEnv("SAML_BASE_URL", "http://localhost:8080"),
+			EntityID:           getEnv("SAML_ENTITY_ID", ""),
+			IDPMetadataURL:     getEnv("SAML_IDP_METADATA_URL", ""),
+			IDPMetadataFile:    getEnv("SAML_IDP_METADATA_FILE", ""),
+			CertFile:           getEnv("SAML_SP_CERT_FILE", ""),
+			KeyFile:            getEnv("SAML_SP_KEY_FILE", ""),
+			AllowIDPInitiated:  getEnvAsBool("SAML_ALLOW_IDP_INITIATED", false),
+			RedirectURL:        getEnv("SAML_REDIRECT_URL", "http://localhost:3000/auth/saml/callback"),
+			CodeTTL:            getEnvAsDuration("SAML_CODE_TTL", time.Minute),
+			AutoProvision:      getEnvAsBool("SAML_AUTO_PROVISION", false),
+			TrustEmail:         getEnvAsBool("SAML_TRUST_EMAIL", true),
+			EmailAttribute:     getEnv("SAML_EMAIL_ATTRIBUTE", "email"),
+			UsernameAttribute:  getEnv("SAML_USERNAME_ATTRIBUTE", "username"),
+			FirstNameAttribute: getEnv("SAML_FIRST_NAME_ATTRIBUTE", "firstName"),
+			LastNameAttribute:  getEnv("SAML_LAST_NAME_ATTRIBUTE", "lastName"),
+			GroupsAttribute:    getEnv("SAML_GROUPS_ATTRIBUTE", "groups"),
+			AdminGroup:         getEnv("SAML_ADMIN_GROUP", ""),
+		},
+		LDAP: LDAPConfig{
+			Enabled:            getEnvAsBool("LDAP_ENABLED", false),
+			URL:                getEnv("LDAP_URL", "ldap://localhost:389"),
+			StartTLS:           getEnvAsBool("LDAP_START_TLS", false),
+			BindDN:             getEnv("LDAP_BIND_DN", ""),
+			BindPassword:       getEnv("LDAP_BIND_PASSWORD", ""),
+			BaseDN:             getEnv("LDAP_BASE_DN", ""),
+			UserFilter:         getEnv("LDAP_USER_FILTER", "(&(objectClass=person)(|(uid={login})(mail={login})))"),
+			Timeout:            getEnvAsDuration("LDAP_TIMEOUT", 10*time.Second),
+			AutoProvision:      getEnvAsBool("LDAP_AUTO_PROVISION", true),
+			IDAttribute:        getEnv("LDAP_ID_ATTRIBUTE", "entryUUID"),
+			EmailAttribute:     getEnv("LDAP_EMAIL_ATTRIBUTE", "mail"),
+			UsernameAttribute:  getEnv("LDAP_USERNAME_ATTRIBUTE", "uid"),
+			FirstNameAttribute: getEnv("LDAP_FIRST_NAME_ATTRIBUTE", "givenName"),
+			LastNameAttribute:  getEnv("LDAP_LAST_NAME_ATTRIBUTE", "sn"),
+			GroupsAttribute:    getEnv("LDAP_GROUPS_ATTRIBUTE", "memberOf"),
+			AdminGroup:         getEnv("LDAP_ADMIN_GROUP", ""),
+		},
+		WebAuthn: WebAuthnConfig{
+			Enabled:         getEnvAsBool("WEBAUTHN_ENABLED", false),
+			RPID:            getEnv("WEBAUTHN_RP_ID", "localhost"),
+			RPDisplayName:   getEnv("WEBAUTHN_RP_DISPLAY_NAME", "gbt-be-template"),
+			RPOrigins:       getEnvAsSlice("WEBAUTHN_RP_ORIGINS", []string{"http://localhost:3000"}),
+			Timeout:         getEnvAsDuration("WEBAUTHN_TIMEOUT", 5*time.Minute),
+			ChallengeDriver: getEnv("WEBAUTHN_CHALLENGE_DRIVER", "memory"),
+		},
+		APIKeys: APIKeyConfig{
+			Enabled:    getEnvAsBool("API_KEYS_ENABLED", false),
+			MaxPerUser: getEnvAsInt("API_KEYS_MAX_PER_USER", 10),
+		},
+		MTLS: MTLSConfig{
+			Enabled:         getEnvAsBool("MTLS_ENABLED", false),
+			Port:            getEnv("MTLS_PORT", "8443"),
+			CertFile:        getEnv("MTLS_CERT_FILE", ""),
+			KeyFile:         getEnv("MTLS_KEY_FILE", ""),
+			ClientCAFile:    getEnv("MTLS_CLIENT_CA_FILE", ""),
+			AllowedServices: getEnvAsSlice("MTLS_ALLOWED_SERVICES", []string{}),
+		},
+		Authz: AuthzConfig{
+			Driver:               getEnv("AUTHZ_DRIVER", "rbac"),
+			CasbinModelFile:      getEnv("AUTHZ_CASBIN_MODEL_FILE", ""),
+			CasbinReloadInterval: getEnvAsDuration("AUTHZ_CASBIN_RELOAD_INTERVAL", time.Minute),
+			RulesEnabled:         getEnvAsBool("AUTHZ_RULES_ENABLED", false),
+			RulesTimezone:        getEnv("AUTHZ_RULES_TIMEZONE", "UTC"),
+			AdminFromRoles:       getEnvAsBool("AUTHZ_ADMIN_FROM_ROLES", false),
+			RolesInToken:         getEnvAsBool("AUTHZ_ROLES_IN_TOKEN", true),
+			PermissionsInToken:   getEnvAsBool("AUTHZ_PERMISSIONS_IN_TOKEN", false),
+			DefaultRoles:         getEnvAsSlice("AUTH_DEFAULT_ROLES", []string{}),
+		},
+		Tenancy: TenancyConfig{
+			Enabled:    getEnvAsBool("TENANCY_ENABLED", false),
+			Header:     getEnv("TENANCY_HEADER", "X-Tenant-ID"),
+			BaseDomain: getEnv("TENANCY_BASE_DOMAIN", ""),
+			Claim:      getEnv("TENANCY_CLAIM", "tenant_id"),
+		},
+		OIDC: OIDCConfig{
+			Enabled:       getEnvAsBool("OIDC_ENABLED", false),
+			Issuer:        getEnv("OIDC_ISSUER", ""),
+			Audience:      getEnv("OIDC_AUDIENCE", ""),
+			AutoProvision: getEnvAsBool("OIDC_AUTO_PROVISION", false),
+			GroupsClaim:   getEnv("OIDC_GROUPS_CLAIM", "groups"),
+			AdminGroup:    getEnv("OIDC_ADMIN_GROUP", ""),
 		},
 	}
 
@@ -130,25 +1034,384 @@ func (c *Config) Validate() error {
 		return fmt.Errorf("server port is required")
 	}
 
-	if c.Database.Host == "" {
-		return fmt.Errorf("database host is required")
+	switch c.Database.Driver {
+	case "postgres", "mysql":
+		if c.Database.Host == "" {
+			return fmt.Errorf("database host is required")
+		}
+	case "sqlite":
+		if len(c.Database.ReplicaDSNs) > 0 {
+			return fmt.Errorf("read replicas aren't supported with the sqlite driver")
+		}
+	default:
+		return fmt.Errorf("unsupported database driver %q", c.Database.Driver)
 	}
 
 	if c.Database.Name == "" {
 		return fmt.Errorf("database name is required")
 	}
 
+	switch c.Database.CredentialsSource {
+	case "env":
+	case "file":
+		if c.Database.CredentialsFile == "" {
+			return fmt.Errorf("DB_CREDENTIALS_FILE is required for the file credentials source")
+		}
+	case "vault":
+		if c.Database.VaultToken == "" || c.Database.VaultPath == "" {
+			return fmt.Errorf("VAULT_TOKEN and DB_VAULT_PATH are required for the vault credentials source")
+		}
+	default:
+		return fmt.Errorf("unsupported database credentials source %q", c.Database.CredentialsSource)
+	}
+	if c.Database.MaxOpenConns < 0 || c.Database.MaxIdleConns < 0 {
+		return fmt.Errorf("database connection pool sizes can't be negative")
+	}
+	if c.Database.MaxOpenConns > 0 && c.Database.MaxIdleConns > c.Database.MaxOpenConns {
+		return fmt.Errorf("DB_MAX_IDLE_CONNS can't exceed DB_MAX_OPEN_CONNS")
+	}
+	if c.Database.ConnMaxLifetime < 0 || c.Database.ConnMaxIdleTime < 0 {
+		return fmt.Errorf("database connection lifetimes can't be negative")
+	}
+	if c.Database.ConnectRetries < 0 || c.Database.ConnectRetryDelay < 0 || c.Database.ConnectMaxWait < 0 {
+		return fmt.Errorf("database connect retries can't be negative")
+	}
+	if c.Database.SlowQueryThreshold < 0 {
+		return fmt.Errorf("DB_SLOW_QUERY_MS can't be negative")
+	}
+	if c.Database.MigrateOnStart && c.Database.Driver != "postgres" {
+		return fmt.Errorf("DB_MIGRATE_ON_START requires the postgres driver; other databases are auto migrated")
+	}
+	if c.Database.CredentialsSource != "env" && c.Database.Driver != "postgres" {
+		return fmt.Errorf("rotating database credentials require the postgres driver")
+	}
+
 	if c.JWT.Secret == "" || c.JWT.Secret == "your-super-secret-jwt-key-change-this-in-production" {
 		if c.Server.Env == "production" {
 			return fmt.Errorf("JWT secret must be set in production")
 		}
 	}
 
+	if c.JWT.KeyID == "" {
+		return fmt.Errorf("JWT key ID is required")
+	}
+
+	if c.JWT.PreviousSecret != "" && (c.JWT.PreviousKeyID == "" || c.JWT.PreviousKeyID == c.JWT.KeyID) {
+		return fmt.Errorf("JWT_PREVIOUS_KEY_ID must be set and differ from JWT_KEY_ID when JWT_PREVIOUS_SECRET is set")
+	}
+
+	if c.JWT.RememberMeExpiry <= 0 {
+		return fmt.Errorf("JWT_REMEMBER_ME_EXPIRY must be positive")
+	}
+
+	if c.JWT.ImpersonationExpiry <= 0 {
+		return fmt.Errorf("JWT_IMPERSONATION_EXPIRY must be positive")
+	}
+
+	if c.OAuth2.Enabled && c.OAuth2.Issuer == "" {
+		return fmt.Errorf("OAuth2 issuer is required when OAuth2 is enabled")
+	}
+
+	switch c.Storage.Driver {
+	case "local":
+	case "s3":
+		if c.Storage.S3.Bucket == "" {
+			return fmt.Errorf("S3 bucket is required for the s3 storage driver")
+		}
+		if c.Storage.S3.AccessKeyID == "" || c.Storage.S3.SecretAccessKey == "" {
+			return fmt.Errorf("S3 access key ID and secret access key are required for the s3 storage driver")
+		}
+	case "gcs":
+		if c.Storage.GCS.Bucket == "" {
+			return fmt.Errorf("GCS bucket is required for the gcs storage driver")
+		}
+	case "azure":
+		if c.Storage.Azure.AccountName == "" || c.Storage.Azure.AccountKey == "" || c.Storage.Azure.Container == "" {
+			return fmt.Errorf("Azure account name, account key and container are required for the azure storage driver")
+		}
+	default:
+		return fmt.Errorf("unsupported storage driver %q", c.Storage.Driver)
+	}
+
+	if c.Upload.ChunkSize <= 0 {
+		return fmt.Errorf("upload chunk size must be positive")
+	}
+
+	for purpose, policy := range map[string]UploadPolicy{"avatar": c.Upload.Avatar, "import": c.Upload.Import} {
+		if policy.MaxSize <= 0 {
+			return fmt.Errorf("maximum %s upload size must be positive", purpose)
+		}
+		for _, ext := range policy.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				return fmt.Errorf("%s upload extension %q must start with a dot", purpose, ext)
+			}
+		}
+	}
+
+	switch c.Mail.Driver {
+	case "log", "smtp":
+	default:
+		return fmt.Errorf("unsupported mail driver %q", c.Mail.Driver)
+	}
+
+	if c.LoginHistory.Retention < 0 {
+		return fmt.Errorf("LOGIN_HISTORY_RETENTION must not be negative")
+	}
+
+	if c.Deletion.HardDeleteAfter < 0 {
+		return fmt.Errorf("ACCOUNT_DELETION_HARD_DELETE_AFTER must not be negative")
+	}
+
+	if c.Deletion.AnonymizeAfter < 0 {
+		return fmt.Errorf("ACCOUNT_DELETION_ANONYMIZE_AFTER must not be negative")
+	}
+
+	if c.Password.MaxAge < 0 {
+		return fmt.Errorf("PASSWORD_MAX_AGE must not be negative")
+	}
+
+	if c.TwoFactor.TrustedDeviceTTL < 0 {
+		return fmt.Errorf("TOTP_TRUSTED_DEVICE_TTL must not be negative")
+	}
+
+	if c.Session.MaxPerUser < 0 {
+		return fmt.Errorf("SESSION_MAX_PER_USER must not be negative")
+	}
+	switch c.Session.LimitPolicy {
+	case SessionLimitEvictOldest, SessionLimitReject:
+	default:
+		return fmt.Errorf("unsupported session limit policy %q", c.Session.LimitPolicy)
+	}
+
+	if c.AuthCookies.Enabled {
+		switch c.AuthCookies.SameSite {
+		case "lax", "strict":
+		case "none":
+			if !c.AuthCookies.Secure {
+				return fmt.Errorf("AUTH_COOKIE_SECURE must be true when AUTH_COOKIE_SAMESITE is none")
+			}
+		default:
+			return fmt.Errorf("unsupported auth cookie SameSite mode %q", c.AuthCookies.SameSite)
+		}
+		// Any origin could then make credentialed requests and read the responses
+		if slices.Contains(c.CORS.AllowedOrigins, "*") {
+			return fmt.Errorf("CORS_ALLOWED_ORIGINS must not contain * when auth cookies are enabled")
+		}
+	}
+
+	if c.LoginBackoff.Enabled {
+		if c.LoginBackoff.IPThreshold <= 0 || c.LoginBackoff.AccountThreshold <= 0 {
+			return fmt.Errorf("login backoff thresholds must be positive")
+		}
+		if c.LoginBackoff.BaseDelay <= 0 || c.LoginBackoff.MaxDelay < c.LoginBackoff.BaseDelay || c.LoginBackoff.ResetAfter <= 0 {
+			return fmt.Errorf("login backoff delays must be positive, with the maximum at least the base delay")
+		}
+	}
+
+	switch c.SMS.Driver {
+	case "log":
+	case "twilio":
+		if c.SMS.TwilioAccountSID == "" || c.SMS.TwilioAuthToken == "" || c.SMS.From == "" {
+			return fmt.Errorf("TWILIO_ACCOUNT_SID, TWILIO_AUTH_TOKEN and SMS_FROM are required for the twilio SMS driver")
+		}
+	default:
+		return fmt.Errorf("unsupported SMS driver %q", c.SMS.Driver)
+	}
+
+	if c.OTP.Enabled {
+		if c.OTP.CodeTTL <= 0 {
+			return fmt.Errorf("OTP code TTL must be positive")
+		}
+		if c.OTP.MaxAttempts <= 0 || c.OTP.MaxRequests <= 0 || c.OTP.RequestWindow <= 0 {
+			return fmt.Errorf("OTP attempt and request limits must be positive")
+		}
+	}
+
+	if c.Encryption.Keys != "" && c.Encryption.IndexKey == "" {
+		return fmt.Errorf("FIELD_ENCRYPTION_INDEX_KEY is required when FIELD_ENCRYPTION_KEYS is set")
+	}
+
+	switch c.Scanner.Driver {
+	case "none", "clamav", "icap":
+	default:
+		return fmt.Errorf("unsupported scanner driver %q", c.Scanner.Driver)
+	}
+
+	for _, size := range c.Avatar.Variants {
+		if size <= 0 || size > 2048 {
+			return fmt.Errorf("avatar variant size %d must be between 1 and 2048", size)
+		}
+	}
+
+	if c.Avatar.JPEGQuality < 1 || c.Avatar.JPEGQuality > 100 {
+		return fmt.Errorf("avatar JPEG quality must be between 1 and 100")
+	}
+
+	if c.Batch.MaxRequests <= 0 || c.Batch.MaxBodySize <= 0 {
+		return fmt.Errorf("batch request limits must be positive")
+	}
+
+	if c.Jobs.Concurrency <= 0 {
+		return fmt.Errorf("job concurrency must be positive")
+	}
+
+	switch c.Lock.Driver {
+	case "postgres":
+		if c.Database.Driver != "postgres" {
+			return fmt.Errorf("the postgres lock driver requires the postgres database driver")
+		}
+	case "local":
+	case "redis":
+		if len(c.Lock.RedisAddrs) == 0 && c.Redis.Addr == "" {
+			return fmt.Errorf("a Redis address is required for the redis lock driver")
+		}
+		if len(c.Lock.RedisAddrs)%2 == 0 && len(c.Lock.RedisAddrs) > 0 {
+			return fmt.Errorf("LOCK_REDIS_ADDRS must list an odd number of Redis nodes")
+		}
+	default:
+		return fmt.Errorf("unsupported lock driver %q", c.Lock.Driver)
+	}
+
+	switch c.Revocation.Driver {
+	case "memory":
+	case "redis":
+		if c.Redis.Addr == "" {
+			return fmt.Errorf("a Redis address is required for the redis token revocation driver")
+		}
+	default:
+		return fmt.Errorf("unsupported token revocation driver %q", c.Revocation.Driver)
+	}
+
+	if c.Leader.Enabled && (c.Leader.LeaseTTL <= 0 || c.Leader.RetryInterval <= 0) {
+		return fmt.Errorf("leader lease TTL and retry interval must be positive")
+	}
+
+	if c.Metrics.Enabled && !strings.HasPrefix(c.Metrics.Path, "/") {
+		return fmt.Errorf("METRICS_PATH must start with /")
+	}
+	if c.Static.Enabled && c.Static.Dir != "" {
+		if info, err := os.Stat(c.Static.Dir); err != nil || !info.IsDir() {
+			return fmt.Errorf("static directory %q does not exist", c.Static.Dir)
+		}
+	}
+
+	if c.TokenExchange.Enabled && len(c.TokenExchange.Issuers) == 0 {
+		return fmt.Errorf("at least one trusted issuer is required when token exchange is enabled")
+	}
+
+	if c.Tenancy.Enabled && c.Tenancy.Claim == "" {
+		return fmt.Errorf("TENANCY_CLAIM is required when tenancy is enabled")
+	}
+
+	if c.OIDC.Enabled {
+		if c.OIDC.Issuer == "" {
+			return fmt.Errorf("OIDC issuer is required when OIDC is enabled")
+		}
+		// Local tokens are told apart from provider tokens by their issuer
+		if c.OIDC.Issuer == utils.JWTIssuer || (c.OAuth2.Enabled && c.OIDC.Issuer == c.OAuth2.Issuer) {
+			return fmt.Errorf("OIDC issuer must differ from the issuer of locally issued tokens")
+		}
+	}
+
+	if c.LDAP.Enabled {
+		if c.LDAP.URL == "" || c.LDAP.BaseDN == "" {
+			return fmt.Errorf("LDAP URL and base DN are required when LDAP is enabled")
+		}
+		if !strings.Contains(c.LDAP.UserFilter, "{login}") {
+			return fmt.Errorf("LDAP user filter must contain the {login} placeholder")
+		}
+	}
+
+	if c.SAML.Enabled {
+		if c.SAML.IDPMetadataURL == "" && c.SAML.IDPMetadataFile == "" {
+			return fmt.Errorf("SAML_IDP_METADATA_URL or SAML_IDP_METADATA_FILE is required when SAML is enabled")
+		}
+		if c.SAML.CertFile == "" || c.SAML.KeyFile == "" {
+			return fmt.Errorf("SAML_SP_CERT_FILE and SAML_SP_KEY_FILE are required when SAML is enabled")
+		}
+		if c.SAML.RedirectURL == "" || c.SAML.CodeTTL <= 0 {
+			return fmt.Errorf("SAML redirect URL and a positive code TTL are required when SAML is enabled")
+		}
+	}
+
+	if c.WebAuthn.Enabled {
+		if c.WebAuthn.RPID == "" || len(c.WebAuthn.RPOrigins) == 0 {
+			return fmt.Errorf("WebAuthn relying party ID and origins are required when WebAuthn is enabled")
+		}
+		if c.WebAuthn.Timeout <= 0 {
+			return fmt.Errorf("WebAuthn timeout must be positive")
+		}
+		switch c.WebAuthn.ChallengeDriver {
+		case "memory":
+		case "redis":
+			if c.Redis.Addr == "" {
+				return fmt.Errorf("a Redis address is required for the redis WebAuthn challenge driver")
+			}
+		default:
+			return fmt.Errorf("unsupported WebAuthn challenge driver %q", c.WebAuthn.ChallengeDriver)
+		}
+	}
+
+	if c.APIKeys.Enabled && c.APIKeys.MaxPerUser <= 0 {
+		return fmt.Errorf("API_KEYS_MAX_PER_USER must be positive when API keys are enabled")
+	}
+
+	if c.MTLS.Enabled {
+		if c.MTLS.CertFile == "" || c.MTLS.KeyFile == "" || c.MTLS.ClientCAFile == "" {
+			return fmt.Errorf("MTLS_CERT_FILE, MTLS_KEY_FILE and MTLS_CLIENT_CA_FILE are required when mTLS is enabled")
+		}
+		if c.MTLS.Port == "" || c.MTLS.Port == c.Server.Port {
+			return fmt.Errorf("MTLS_PORT must be set and differ from PORT")
+		}
+	}
+
+	switch c.Authz.Driver {
+	case "rbac":
+	case "casbin":
+		if c.Authz.CasbinReloadInterval <= 0 {
+			return fmt.Errorf("AUTHZ_CASBIN_RELOAD_INTERVAL must be positive")
+		}
+	default:
+		return fmt.Errorf("unsupported authorization driver %q", c.Authz.Driver)
+	}
+	if c.Authz.RulesEnabled {
+		if _, err := time.LoadLocation(c.Authz.RulesTimezone); err != nil {
+			return fmt.Errorf("invalid AUTHZ_RULES_TIMEZONE: %w", err)
+		}
+	}
+	// Permissions in tokens would let requests past checks the database must make
+	if c.Authz.PermissionsInToken && (c.Authz.Driver != "rbac" || c.Authz.RulesEnabled) {
+		return fmt.Errorf("AUTHZ_PERMISSIONS_IN_TOKEN requires AUTHZ_DRIVER=rbac without AUTHZ_RULES_ENABLED")
+	}
+	// Registering must not make anyone an admin
+	if slices.Contains(c.Authz.DefaultRoles, "admin") {
+		return fmt.Errorf("AUTH_DEFAULT_ROLES can't include the admin role")
+	}
+
 	return nil
 }
 
-// GetDSN returns the database connection string
+// GetDSN returns the database connection string of the configured driver
 func (c *Config) GetDSN() string {
+	switch c.Database.Driver {
+	case "mysql":
+		dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s?charset=utf8mb4&parseTime=true&loc=UTC",
+			c.Database.User,
+			c.Database.Password,
+			net.JoinHostPort(c.Database.Host, c.Database.Port),
+			c.Database.Name,
+		)
+		// Postgres SSL modes are mapped onto the TLS setting of the MySQL driver
+		switch c.Database.SSLMode {
+		case "require":
+			dsn += "&tls=skip-verify"
+		case "verify-ca", "verify-full":
+			dsn += "&tls=true"
+		}
+		return dsn
+	case "sqlite":
+		return "file:" + c.Database.Name + "?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL"
+	}
 	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
 		c.Database.Host,
 		c.Database.Port,
@@ -170,6 +1433,40 @@ func (c *Config) IsDevelopment() bool {
 }
 
 // Helper functions
+// defaultStorageDriver keeps files in object storage in production and on disk elsewhere
+func defaultStorageDriver(env string) string {
+	if env == "production" {
+		return "s3"
+	}
+	return "local"
+}
+
+// defaultDBPort returns the port the database driver's server listens on by default
+func defaultDBPort(driver string) string {
+	if driver == "mysql" {
+		return "3306"
+	}
+	return "5432"
+}
+
+// defaultLockDriver takes scheduler locks in the database when it supports them, and locally
+// otherwise
+func defaultLockDriver(dbDriver string) string {
+	if dbDriver == "postgres" {
+		return "postgres"
+	}
+	return "local"
+}
+
+// defaultDrainPeriod gives load balancers time to notice a failing readiness
+// check in production, and shuts down at once everywhere else
+func defaultDrainPeriod(env string) time.Duration {
+	if env == "production" {
+		return 10 * time.Second
+	}
+	return 0
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -186,6 +1483,15 @@ func getEnvAsInt(key string, defaultValue int) int {
 	return defaultValue
 }
 
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
+
 func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	if value := os.Getenv(key); value != "" {
 		if duration, err := time.ParseDuration(value); err == nil {
@@ -201,3 +1507,41 @@ func getEnvAsSlice(key string, defaultValue []string) []string {
 	}
 	return defaultValue
 }
+
+// getEnvAsIntSlice parses a comma separated list of integers, falling back to the default if any entry is malformed
+func getEnvAsIntSlice(key string, defaultValue []int) []int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var result []int
+	for _, entry := range strings.Split(value, ",") {
+		intValue, err := strconv.Atoi(strings.TrimSpace(entry))
+		if err != nil {
+			return defaultValue
+		}
+		result = append(result, intValue)
+	}
+	return result
+}
+
+// getEnvAsIntMap parses comma separated name=value pairs, skipping malformed entries
+func getEnvAsIntMap(key string, defaultValue map[string]int) map[string]int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	result := make(map[string]int)
+	for _, entry := range strings.Split(value, ",") {
+		name, number, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			continue
+		}
+		if intValue, err := strconv.Atoi(number); err == nil {
+			result[name] = intValue
+		}
+	}
+	return result
+}
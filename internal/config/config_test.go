@@ -0,0 +1,160 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseRateLimitPolicies(t *testing.T) {
+	t.Run("default policies", func(t *testing.T) {
+		policies, err := parseRateLimitPolicies(defaultRateLimitPolicies)
+		require.NoError(t, err)
+
+		assert.Equal(t, RateLimitPolicy{Name: "auth", Requests: 10, Window: time.Minute, Key: "ip"}, policies["auth"])
+		assert.Equal(t, RateLimitPolicy{Name: "read", Requests: 600, Window: time.Minute, Key: "user"}, policies["read"])
+		assert.True(t, policies["admin"].Unlimited)
+	})
+
+	t.Run("key defaults to ip", func(t *testing.T) {
+		policies, err := parseRateLimitPolicies("search=30/10s")
+		require.NoError(t, err)
+		assert.Equal(t, RateLimitPolicy{Name: "search", Requests: 30, Window: 10 * time.Second, Key: "ip"}, policies["search"])
+	})
+
+	t.Run("invalid policies", func(t *testing.T) {
+		for _, value := range []string{"auth", "auth=10", "auth=ten/1m", "auth=10/soon", "auth=10/1m:tenant", "=10/1m"} {
+			_, err := parseRateLimitPolicies(value)
+			assert.Error(t, err, value)
+		}
+	})
+}
+
+func TestRateLimitConfig_Policy(t *testing.T) {
+	cfg := RateLimitConfig{
+		Requests: 100,
+		Window:   time.Minute,
+		Policies: map[string]RateLimitPolicy{"auth": {Name: "auth", Requests: 10, Window: time.Minute, Key: "ip"}},
+	}
+
+	assert.Equal(t, 10, cfg.Policy("auth").Requests)
+
+	// Unknown policies fall back to the default limit
+	fallback := cfg.Policy("reports")
+	assert.Equal(t, RateLimitPolicy{Name: "reports", Requests: 100, Window: time.Minute, Key: "ip"}, fallback)
+}
+
+func TestLoad_StorageDefaults(t *testing.T) {
+	t.Setenv("ENV", "production")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	t.Run("production requires a bucket", func(t *testing.T) {
+		_, err := Load()
+		assert.ErrorContains(t, err, "S3 bucket is required")
+	})
+
+	t.Run("standard AWS variables are used as credentials", func(t *testing.T) {
+		t.Setenv("STORAGE_S3_BUCKET", "files")
+		t.Setenv("AWS_ACCESS_KEY_ID", "aws-key")
+		t.Setenv("AWS_SECRET_ACCESS_KEY", "aws-secret")
+		t.Setenv("AWS_SESSION_TOKEN", "aws-token")
+		t.Setenv("AWS_REGION", "eu-central-1")
+
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "s3", cfg.Storage.Driver)
+		assert.Equal(t, "aws-key", cfg.Storage.S3.AccessKeyID)
+		assert.Equal(t, "aws-secret", cfg.Storage.S3.SecretAccessKey)
+		assert.Equal(t, "aws-token", cfg.Storage.S3.SessionToken)
+		assert.Equal(t, "eu-central-1", cfg.Storage.S3.Region)
+	})
+
+	t.Run("development defaults to local storage", func(t *testing.T) {
+		t.Setenv("ENV", "development")
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "local", cfg.Storage.Driver)
+	})
+}
+
+func TestLoad_DatabaseDrivers(t *testing.T) {
+	t.Setenv("DB_USER", "app")
+	t.Setenv("DB_PASSWORD", "secret")
+	t.Setenv("DB_NAME", "gbt")
+
+	t.Run("postgres is the default", func(t *testing.T) {
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "postgres", cfg.Database.Driver)
+		assert.Equal(t, "postgres", cfg.Lock.Driver)
+		assert.Equal(t, "host=localhost port=5432 user=app password=secret dbname=gbt sslmode=disable", cfg.GetDSN())
+	})
+
+	t.Run("mysql", func(t *testing.T) {
+		t.Setenv("DB_DRIVER", "mysql")
+		t.Setenv("DB_SSLMODE", "verify-full")
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "local", cfg.Lock.Driver)
+		assert.Equal(t, "app:secret@tcp(localhost:3306)/gbt?charset=utf8mb4&parseTime=true&loc=UTC&tls=true", cfg.GetDSN())
+	})
+
+	t.Run("sqlite", func(t *testing.T) {
+		t.Setenv("DB_DRIVER", "sqlite")
+		t.Setenv("DB_NAME", "data/gbt.db")
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, "file:data/gbt.db?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL", cfg.GetDSN())
+	})
+
+	t.Run("postgres features need postgres", func(t *testing.T) {
+		t.Setenv("DB_DRIVER", "mysql")
+		t.Setenv("LOCK_DRIVER", "postgres")
+		_, err := Load()
+		assert.ErrorContains(t, err, "postgres lock driver")
+	})
+
+	t.Run("sqlite has no replicas", func(t *testing.T) {
+		t.Setenv("DB_DRIVER", "sqlite")
+		t.Setenv("DB_REPLICA_DSNS", "file:replica.db")
+		_, err := Load()
+		assert.ErrorContains(t, err, "read replicas")
+	})
+
+	t.Run("pool settings are checked", func(t *testing.T) {
+		t.Setenv("DB_MAX_OPEN_CONNS", "10")
+		t.Setenv("DB_MAX_IDLE_CONNS", "20")
+		_, err := Load()
+		assert.ErrorContains(t, err, "DB_MAX_IDLE_CONNS")
+
+		t.Setenv("DB_MAX_IDLE_CONNS", "5")
+		t.Setenv("DB_CONN_MAX_IDLE_TIME", "30s")
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, 5, cfg.Database.MaxIdleConns)
+		assert.Equal(t, 30*time.Second, cfg.Database.ConnMaxIdleTime)
+	})
+
+	t.Run("slow query logging", func(t *testing.T) {
+		cfg, err := Load()
+		require.NoError(t, err)
+		assert.Equal(t, 200*time.Millisecond, cfg.Database.SlowQueryThreshold)
+
+		t.Setenv("DB_SLOW_QUERY_MS", "50")
+		cfg, err = Load()
+		require.NoError(t, err)
+		assert.Equal(t, 50*time.Millisecond, cfg.Database.SlowQueryThreshold)
+
+		t.Setenv("DB_SLOW_QUERY_MS", "-1")
+		_, err = Load()
+		assert.ErrorContains(t, err, "DB_SLOW_QUERY_MS")
+	})
+
+	t.Run("unknown drivers are refused", func(t *testing.T) {
+		t.Setenv("DB_DRIVER", "oracle")
+		_, err := Load()
+		assert.ErrorContains(t, err, "unsupported database driver")
+	})
+}
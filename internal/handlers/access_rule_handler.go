@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// AccessRuleHandler handles access rule management HTTP requests
+type AccessRuleHandler struct {
+	ruleService services.AccessRuleService
+	log         *logger.Logger
+	validator   *validator.Validate
+}
+
+// NewAccessRuleHandler creates a new access rule handler
+func NewAccessRuleHandler(ruleService services.AccessRuleService, log *logger.Logger) *AccessRuleHandler {
+	return &AccessRuleHandler{
+		ruleService: ruleService,
+		log:         log,
+		validator:   validator.New(),
+	}
+}
+
+// Create handles POST /admin/access-rules
+func (h *AccessRuleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.AccessRuleCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in create access rule request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	rule, err := h.ruleService.Create(r.Context(), &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to create access rule")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Access rule created successfully", rule)
+}
+
+// List handles GET /admin/access-rules
+func (h *AccessRuleHandler) List(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.ruleService.List(r.Context())
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve access rules", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Access rules retrieved successfully", rules)
+}
+
+// GetByID handles GET /admin/access-rules/{id}
+func (h *AccessRuleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	rule, err := h.ruleService.GetByID(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve access rule")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Access rule retrieved successfully", rule)
+}
+
+// Update handles PUT /admin/access-rules/{id}
+func (h *AccessRuleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AccessRuleUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in update access rule request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	rule, err := h.ruleService.Update(r.Context(), id, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to update access rule")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Access rule updated successfully", rule)
+}
+
+// Delete handles DELETE /admin/access-rules/{id}
+func (h *AccessRuleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.ruleID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.ruleService.Delete(r.Context(), id); err != nil {
+		h.writeError(w, err, "Failed to delete access rule")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Access rule deleted successfully", nil)
+}
+
+// ruleID parses the access rule ID in the URL, writing an error response when it is invalid
+func (h *AccessRuleHandler) ruleID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid access rule ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError maps errors of access rule management to HTTP status codes
+func (h *AccessRuleHandler) writeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrAccessRuleNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrAccessRuleNameTaken):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidCondition):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid condition", err.Error())
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, message, nil)
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// APIKeyHandler handles API key management HTTP requests
+type APIKeyHandler struct {
+	apiKeyService services.APIKeyService
+	log           *logger.Logger
+	validator     *validator.Validate
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(apiKeyService services.APIKeyService, log *logger.Logger) *APIKeyHandler {
+	return &APIKeyHandler{
+		apiKeyService: apiKeyService,
+		log:           log,
+		validator:     validator.New(),
+	}
+}
+
+// Create handles POST /auth/api-keys
+func (h *APIKeyHandler) Create(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req models.APIKeyCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in API key creation")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	key, err := h.apiKeyService.Create(r.Context(), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, services.ErrAPIKeyScopeNotAllowed):
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), nil)
+		case errors.Is(err, services.ErrAPIKeyExpiryInvalid):
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, services.ErrAPIKeyLimitReached):
+			utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		default:
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create API key", nil)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "API key created, store it now as it won't be shown again", key)
+}
+
+// List handles GET /auth/api-keys
+func (h *APIKeyHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	keys, err := h.apiKeyService.List(r.Context(), userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve API keys", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "API keys retrieved successfully", keys)
+}
+
+// Delete handles DELETE /auth/api-keys/{id}
+func (h *APIKeyHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid API key ID", nil)
+		return
+	}
+
+	if err := h.apiKeyService.Delete(r.Context(), userID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrAPIKeyNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete API key", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "API key deleted", nil)
+}
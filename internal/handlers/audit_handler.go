@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+)
+
+// AuditHandler handles audit log HTTP requests
+type AuditHandler struct {
+	rbacAuditService services.RBACAuditService
+	log              *logger.Logger
+}
+
+// NewAuditHandler creates a new audit handler
+func NewAuditHandler(rbacAuditService services.RBACAuditService, log *logger.Logger) *AuditHandler {
+	return &AuditHandler{
+		rbacAuditService: rbacAuditService,
+		log:              log,
+	}
+}
+
+// ListRBAC handles GET /admin/audit/rbac?actor_id=&action=&target_type=&target_id=, listing
+// changes to roles and permissions newest first
+func (h *AuditHandler) ListRBAC(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := models.RBACAuditFilter{
+		Action:     query.Get("action"),
+		TargetType: query.Get("target_type"),
+	}
+	for name, field := range map[string]*uint{"actor_id": &filter.ActorID, "target_id": &filter.TargetID} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		id, err := strconv.ParseUint(value, 10, 32)
+		if err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid "+name, nil)
+			return
+		}
+		*field = uint(id)
+	}
+	page, limit := pageParams(r)
+
+	events, total, err := h.rbacAuditService.List(r.Context(), filter, page, limit)
+	if err != nil {
+		h.log.WithError(err).Error("Failed to list RBAC audit events")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve audit events", nil)
+		return
+	}
+
+	utils.WritePaginatedResponse(w, r, http.StatusOK, "Audit events retrieved successfully", events, total, page, limit)
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+)
+
+// tokenCookies is embedded by handlers that issue tokens, so that they hand them to browser
+// frontends in cookies when cookie auth is enabled
+type tokenCookies struct {
+	cookies *middleware.AuthCookies
+}
+
+// UseAuthCookies makes the handler set tokens in cookies instead of the response body.
+// cookies may be nil to keep tokens in the body.
+func (c *tokenCookies) UseAuthCookies(cookies *middleware.AuthCookies) {
+	c.cookies = cookies
+}
+
+// writeLoginResponse writes a successful login or refresh. With cookie auth the tokens are
+// set in cookies and left out of the body, so scripts on the page can't read them.
+func (c *tokenCookies) writeLoginResponse(w http.ResponseWriter, log *logger.Logger, message string, response *models.LoginResponse) {
+	if !c.cookies.Enabled() || response.AccessToken == "" {
+		utils.WriteSuccessResponse(w, http.StatusOK, message, response)
+		return
+	}
+
+	expiresIn := time.Duration(response.ExpiresIn) * time.Second
+	if err := c.cookies.SetTokens(w, response.AccessToken, expiresIn, response.RefreshToken); err != nil {
+		log.WithError(err).Error("Failed to set auth cookies")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to log in", nil)
+		return
+	}
+
+	body := *response
+	body.AccessToken = ""
+	body.RefreshToken = ""
+	utils.WriteSuccessResponse(w, http.StatusOK, message, &body)
+}
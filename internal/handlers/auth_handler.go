@@ -0,0 +1,111 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// AuthHandler handles token lifecycle HTTP requests
+type AuthHandler struct {
+	tokenCookies
+	authService services.AuthService
+	log         *logger.Logger
+	validator   *validator.Validate
+}
+
+// NewAuthHandler creates a new auth handler
+func NewAuthHandler(authService services.AuthService, log *logger.Logger) *AuthHandler {
+	return &AuthHandler{
+		authService: authService,
+		log:         log,
+		validator:   validator.New(),
+	}
+}
+
+// Refresh handles POST /auth/refresh. With cookie auth the body may be left out, and the
+// refresh token cookie is used instead.
+func (h *AuthHandler) Refresh(w http.ResponseWriter, r *http.Request) {
+	var req models.RefreshTokenRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !(errors.Is(err, io.EOF) && h.cookies.Enabled()) {
+		h.log.WithError(err).Warn("Invalid JSON in refresh request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+	if req.RefreshToken == "" {
+		req.RefreshToken = h.cookies.RefreshToken(r)
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for refresh request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	response, err := h.authService.RotateRefreshToken(r.Context(), req.RefreshToken)
+	if err != nil {
+		if errors.Is(err, services.ErrInvalidRefreshToken) || errors.Is(err, services.ErrRefreshTokenReuse) {
+			utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid refresh token", nil)
+			return
+		}
+		h.log.WithError(err).Error("Failed to refresh token")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to refresh token", nil)
+		return
+	}
+
+	h.writeLoginResponse(w, h.log, "Token refreshed successfully", response)
+}
+
+// ListSessions handles GET /auth/sessions
+func (h *AuthHandler) ListSessions(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	sessions, err := h.authService.ListSessions(r.Context(), userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve sessions", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Sessions retrieved successfully", sessions)
+}
+
+// RevokeSession handles DELETE /auth/sessions/{id}
+func (h *AuthHandler) RevokeSession(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	if err := h.authService.RevokeSession(r.Context(), userID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrSessionNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to revoke session", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Session revoked", nil)
+}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// avatarFormOverhead is the room left for multipart headers and boundaries on top of the
+// avatar size limit
+const avatarFormOverhead = 64 << 10
+
+// avatarFormMemory is how much of a multipart avatar upload is buffered in memory before it
+// spills to a temporary file
+const avatarFormMemory = 1 << 20
+
+// AvatarHandler handles user avatar HTTP requests
+type AvatarHandler struct {
+	avatarService services.AvatarService
+	uploadService services.UploadService
+	maxSize       int64 // Largest accepted avatar in bytes, 0 for unlimited
+	log           *logger.Logger
+	validator     *validator.Validate
+}
+
+// NewAvatarHandler creates a new avatar handler. maxSize caps multipart avatar uploads,
+// 0 leaves them to the avatar upload policy.
+func NewAvatarHandler(avatarService services.AvatarService, uploadService services.UploadService, maxSize int64, log *logger.Logger) *AvatarHandler {
+	return &AvatarHandler{
+		avatarService: avatarService,
+		uploadService: uploadService,
+		maxSize:       maxSize,
+		log:           log,
+		validator:     validator.New(),
+	}
+}
+
+// avatarUserID parses the user ID of an avatar route, writing an error response when the
+// caller may not change that user's avatar
+func avatarUserID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return 0, false
+	}
+
+	// Users can only change their own avatar unless they are admin
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	if userID != uint(id) && !isAdmin {
+		utils.WriteErrorResponse(w, http.StatusForbidden, "You can only update your own avatar", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// Upload handles POST /users/{id}/avatar with the image as the "file" field of a multipart form.
+// The image goes through the same validation and scanning as an avatar upload session.
+func (h *AvatarHandler) Upload(w http.ResponseWriter, r *http.Request) {
+	id, ok := avatarUserID(w, r)
+	if !ok {
+		return
+	}
+
+	if h.maxSize > 0 {
+		r.Body = http.MaxBytesReader(w, r.Body, h.maxSize+avatarFormOverhead)
+	}
+	if err := r.ParseMultipartForm(avatarFormMemory); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			utils.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, "Avatar is too large", nil)
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid multipart form", nil)
+		return
+	}
+	defer r.MultipartForm.RemoveAll()
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Missing file", nil)
+		return
+	}
+	defer file.Close()
+
+	upload, err := h.uploadService.Store(r.Context(), id, &models.UploadInitiateRequest{
+		Filename:    header.Filename,
+		ContentType: header.Header.Get("Content-Type"),
+		Size:        header.Size,
+		Purpose:     models.UploadPurposeAvatar,
+	}, file)
+	if err != nil {
+		writeUploadError(w, h.log, err)
+		return
+	}
+
+	h.set(w, r, id, *upload.FileID)
+}
+
+// Set handles PUT /users/{id}/avatar
+func (h *AvatarHandler) Set(w http.ResponseWriter, r *http.Request) {
+	id, ok := avatarUserID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AvatarSetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	h.set(w, r, id, req.FileID)
+}
+
+// set makes an uploaded file the user's avatar
+func (h *AvatarHandler) set(w http.ResponseWriter, r *http.Request, id, fileID uint) {
+	if err := h.avatarService.Set(r.Context(), id, fileID); err != nil {
+		switch {
+		case errors.Is(err, services.ErrFileNotFound):
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, services.ErrAvatarInvalid):
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		default:
+			h.log.WithError(err).WithField("user_id", id).Error("Failed to set avatar")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to set avatar", nil)
+		}
+		return
+	}
+
+	// Variants are rendered in the background and appear in avatar_urls once ready
+	utils.WriteSuccessResponse(w, http.StatusAccepted, "Avatar processing started", nil)
+}
+
+// ServeVariant handles GET /media/avatars/*
+func (h *AvatarHandler) ServeVariant(w http.ResponseWriter, r *http.Request) {
+	key := "avatars/" + chi.URLParam(r, "*")
+
+	reader, err := h.avatarService.OpenVariant(r.Context(), key)
+	if err != nil {
+		if errors.Is(err, services.ErrFileNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, "Not found", nil)
+			return
+		}
+		h.log.WithError(err).WithField("key", key).Error("Failed to open avatar")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve avatar", nil)
+		return
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// SVG avatars are sanitized on upload; the policy keeps anything that slipped through inert
+	w.Header().Set("Content-Security-Policy", "default-src 'none'; style-src 'unsafe-inline'; img-src data:")
+	// Variant keys include the source file ID, so their content never changes
+	w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	w.WriteHeader(http.StatusOK)
+
+	if _, err := io.Copy(w, reader); err != nil {
+		h.log.WithError(err).WithField("key", key).Warn("Failed to write avatar")
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// batchPath is the route of the batch endpoint itself, which sub-requests may not call
+const batchPath = "/api/v1/batch"
+
+// inheritedHeaders are copied from the batch request onto every sub-request so
+// that they run with the caller's identity and are traceable in logs
+var inheritedHeaders = []string{"Authorization", "Cookie", "X-CSRF-Token", "X-Request-Id", "User-Agent", "Accept-Language"}
+
+// BatchHandler executes several API requests in one round trip
+type BatchHandler struct {
+	router      http.Handler
+	maxRequests int
+	maxBodySize int64
+	log         *logger.Logger
+	validator   *validator.Validate
+}
+
+// NewBatchHandler creates a batch handler dispatching sub-requests to router
+func NewBatchHandler(router http.Handler, maxRequests int, maxBodySize int64, log *logger.Logger) *BatchHandler {
+	return &BatchHandler{
+		router:      router,
+		maxRequests: maxRequests,
+		maxBodySize: maxBodySize,
+		log:         log,
+		validator:   validator.New(),
+	}
+}
+
+// Batch handles POST /batch with a JSON array of sub-requests. Sub-requests run
+// one after another through the full middleware stack, so each is authorized
+// and rate limited as if it had been sent on its own.
+func (h *BatchHandler) Batch(w http.ResponseWriter, r *http.Request) {
+	var requests []models.BatchRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, h.maxBodySize)).Decode(&requests); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			utils.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, "Batch request too large", nil)
+			return
+		}
+		h.log.WithError(err).Warn("Invalid JSON in batch request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if len(requests) == 0 || len(requests) > h.maxRequests {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, fmt.Sprintf("A batch must contain between 1 and %d requests", h.maxRequests), nil)
+		return
+	}
+
+	for i := range requests {
+		requests[i].Method = strings.ToUpper(requests[i].Method)
+		if err := h.validator.Struct(&requests[i]); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", map[string]interface{}{
+				"index": i,
+				"error": err.Error(),
+			})
+			return
+		}
+		if target, err := url.Parse(requests[i].Path); err != nil || path.Clean(target.Path) == batchPath {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Batch requests cannot be nested", map[string]interface{}{"index": i})
+			return
+		}
+	}
+
+	responses := make([]models.BatchResponse, len(requests))
+	for i, req := range requests {
+		responses[i] = h.dispatch(r, req)
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Batch executed", responses)
+}
+
+// dispatch runs one sub-request and captures its response
+func (h *BatchHandler) dispatch(parent *http.Request, req models.BatchRequest) models.BatchResponse {
+	// Drop the batch request's route context so the router matches the sub-request afresh
+	ctx := context.WithValue(parent.Context(), chi.RouteCtxKey, nil)
+
+	sub, err := http.NewRequestWithContext(ctx, req.Method, req.Path, bytes.NewReader(req.Body))
+	if err != nil {
+		return models.BatchResponse{ID: req.ID, Status: http.StatusBadRequest, Body: jsonString(err.Error())}
+	}
+	sub.RemoteAddr = parent.RemoteAddr
+	sub.Host = parent.Host
+	for name, value := range req.Headers {
+		sub.Header.Set(name, value)
+	}
+	if len(req.Body) > 0 && sub.Header.Get("Content-Type") == "" {
+		sub.Header.Set("Content-Type", "application/json")
+	}
+	// The caller's credentials always win over anything set per sub-request
+	for _, name := range inheritedHeaders {
+		if value := parent.Header.Get(name); value != "" {
+			sub.Header.Set(name, value)
+		} else if name == "Authorization" || name == "Cookie" {
+			sub.Header.Del(name)
+		}
+	}
+
+	rec := newBatchRecorder()
+	h.router.ServeHTTP(rec, sub)
+	return rec.response(req.ID)
+}
+
+// batchRecorder is an in-memory http.ResponseWriter for sub-requests
+type batchRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchRecorder() *batchRecorder {
+	return &batchRecorder{header: make(http.Header)}
+}
+
+func (r *batchRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *batchRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+func (r *batchRecorder) Write(b []byte) (int, error) {
+	r.WriteHeader(http.StatusOK)
+	return r.body.Write(b)
+}
+
+// response converts the recorded output into a batch response
+func (r *batchRecorder) response(id string) models.BatchResponse {
+	resp := models.BatchResponse{ID: id, Status: r.status}
+	if resp.Status == 0 {
+		resp.Status = http.StatusOK
+	}
+
+	for name := range r.header {
+		if name == "Set-Cookie" || name == "Content-Length" {
+			continue
+		}
+		if resp.Headers == nil {
+			resp.Headers = make(map[string]string)
+		}
+		resp.Headers[name] = r.header.Get(name)
+	}
+
+	body := bytes.TrimSpace(r.body.Bytes())
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		resp.Body = json.RawMessage(body)
+	default:
+		resp.Body = jsonString(string(body))
+	}
+	return resp
+}
+
+// jsonString encodes s as a JSON string
+func jsonString(s string) json.RawMessage {
+	encoded, _ := json.Marshal(s)
+	return encoded
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupBatchRouter() *chi.Mux {
+	r := chi.NewRouter()
+	batch := NewBatchHandler(r, 3, 4096, logger.New("error", "text"))
+
+	r.Route("/api/v1", func(r chi.Router) {
+		r.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.Header.Get("Authorization") != "Bearer caller" {
+					utils.WriteErrorResponse(w, http.StatusUnauthorized, "unauthorized", nil)
+					return
+				}
+				next.ServeHTTP(w, r)
+			})
+		})
+		r.Post("/batch", batch.Batch)
+		r.Put("/users/{id}", func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			utils.WriteSuccessResponse(w, http.StatusOK, "updated", map[string]string{
+				"id":   chi.URLParam(r, "id"),
+				"body": string(body),
+			})
+		})
+		r.Get("/text", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "text/plain")
+			w.Write([]byte("plain text"))
+		})
+	})
+	return r
+}
+
+func postBatch(router http.Handler, body string) *httptest.ResponseRecorder {
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/batch", strings.NewReader(body))
+	request.Header.Set("Authorization", "Bearer caller")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	return recorder
+}
+
+func TestBatchHandler(t *testing.T) {
+	router := setupBatchRouter()
+
+	t.Run("runs sub-requests in order with the caller's credentials", func(t *testing.T) {
+		recorder := postBatch(router, `[
+			{"id": "a", "method": "put", "path": "/api/v1/users/1", "body": {"first_name": "A"}},
+			{"id": "b", "method": "PUT", "path": "/api/v1/users/2", "headers": {"Authorization": "Bearer someone-else"}},
+			{"id": "c", "method": "GET", "path": "/api/v1/text"}
+		]`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+
+		var response struct {
+			Data []struct {
+				ID      string            `json:"id"`
+				Status  int               `json:"status"`
+				Headers map[string]string `json:"headers"`
+				Body    json.RawMessage   `json:"body"`
+			} `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		require.Len(t, response.Data, 3)
+
+		assert.Equal(t, "a", response.Data[0].ID)
+		assert.Equal(t, http.StatusOK, response.Data[0].Status)
+		assert.Contains(t, string(response.Data[0].Body), `"id":"1"`)
+		assert.Contains(t, string(response.Data[0].Body), `first_name`)
+
+		// A per-request Authorization header cannot replace the caller's
+		assert.Equal(t, http.StatusOK, response.Data[1].Status)
+		assert.Contains(t, string(response.Data[1].Body), `"id":"2"`)
+
+		assert.Equal(t, http.StatusOK, response.Data[2].Status)
+		assert.Equal(t, `"plain text"`, string(response.Data[2].Body))
+		assert.Equal(t, "text/plain", response.Data[2].Headers["Content-Type"])
+	})
+
+	t.Run("sub-requests fail independently", func(t *testing.T) {
+		recorder := postBatch(router, `[
+			{"method": "GET", "path": "/api/v1/missing"},
+			{"method": "PUT", "path": "/api/v1/users/3"}
+		]`)
+		require.Equal(t, http.StatusOK, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), `"status":404`)
+		assert.Contains(t, recorder.Body.String(), `"status":200`)
+	})
+
+	t.Run("rejects invalid batches", func(t *testing.T) {
+		for name, body := range map[string]string{
+			"empty":           `[]`,
+			"too many":        "[" + strings.Repeat(`{"method": "GET", "path": "/api/v1/text"},`, 3) + `{"method": "GET", "path": "/api/v1/text"}]`,
+			"not an array":    `{"method": "GET", "path": "/api/v1/text"}`,
+			"bad method":      `[{"method": "TRACE", "path": "/api/v1/text"}]`,
+			"outside the api": `[{"method": "GET", "path": "/health"}]`,
+			"nested":          `[{"method": "POST", "path": "/api/v1/./batch"}]`,
+		} {
+			recorder := postBatch(router, body)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, name)
+		}
+
+		recorder := postBatch(router, `[{"method": "POST", "path": "/api/v1/users/1", "body": "`+string(bytes.Repeat([]byte("x"), 5000))+`"}]`)
+		assert.Equal(t, http.StatusRequestEntityTooLarge, recorder.Code)
+	})
+}
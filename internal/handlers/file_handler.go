@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// FileHandler handles stored file HTTP requests
+type FileHandler struct {
+	fileService services.FileService
+	log         *logger.Logger
+}
+
+// NewFileHandler creates a new file handler
+func NewFileHandler(fileService services.FileService, log *logger.Logger) *FileHandler {
+	return &FileHandler{
+		fileService: fileService,
+		log:         log,
+	}
+}
+
+// ListByUser handles GET /users/{id}/files
+func (h *FileHandler) ListByUser(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	// Users can only list their own files unless they are admin
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	if userID != uint(id) && !isAdmin {
+		utils.WriteErrorResponse(w, http.StatusForbidden, "You can only list your own files", nil)
+		return
+	}
+
+	files, err := h.fileService.ListByUser(r.Context(), uint(id))
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", id).Error("Failed to list files")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve files", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Files retrieved successfully", files)
+}
+
+// Download handles GET /files/{id}/download
+func (h *FileHandler) Download(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid file ID", nil)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	download, err := h.fileService.DownloadURL(r.Context(), userID, isAdmin, uint(id))
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrFileNotFound):
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, services.ErrPresignUnsupported):
+			utils.WriteErrorResponse(w, http.StatusNotImplemented, err.Error(), nil)
+		default:
+			h.log.WithError(err).WithField("file_id", id).Error("Failed to create download URL")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to create download URL", nil)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Download URL created", download)
+}
+
+// Delete handles DELETE /files/{id}
+func (h *FileHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid file ID", nil)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	if err := h.fileService.Delete(r.Context(), userID, isAdmin, uint(id)); err != nil {
+		if errors.Is(err, services.ErrFileNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		h.log.WithError(err).WithField("file_id", id).Error("Failed to delete file")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete file", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "File deleted successfully", nil)
+}
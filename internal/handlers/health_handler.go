@@ -2,24 +2,35 @@ package handlers
 
 import (
 	"net/http"
+	"sync/atomic"
 	"time"
 
 	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/leader"
 	"gbt-be-template/pkg/logger"
 	"gbt-be-template/pkg/utils"
 )
 
+// LeaderStatus reports the leader election status of this instance
+type LeaderStatus interface {
+	Status() leader.Status
+}
+
 // HealthHandler handles health check requests
 type HealthHandler struct {
-	db  *repository.Database
-	log *logger.Logger
+	db       *repository.Database
+	leader   LeaderStatus
+	log      *logger.Logger
+	draining atomic.Bool
+	metrics  map[string]func() interface{}
 }
 
-// NewHealthHandler creates a new health handler
-func NewHealthHandler(db *repository.Database, log *logger.Logger) *HealthHandler {
+// NewHealthHandler creates a new health handler. leader may be nil when leader election is disabled.
+func NewHealthHandler(db *repository.Database, leader LeaderStatus, log *logger.Logger) *HealthHandler {
 	return &HealthHandler{
-		db:  db,
-		log: log,
+		db:     db,
+		leader: leader,
+		log:    log,
 	}
 }
 
@@ -51,6 +62,16 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 			},
 		},
 	}
+	if h.leader != nil {
+		response["leader"] = h.leader.Status()
+	}
+	if len(h.metrics) > 0 {
+		metrics := make(map[string]interface{}, len(h.metrics))
+		for name, fn := range h.metrics {
+			metrics[name] = fn()
+		}
+		response["metrics"] = metrics
+	}
 
 	if status == "healthy" {
 		utils.WriteSuccessResponse(w, statusCode, "Service is healthy", response)
@@ -59,13 +80,32 @@ func (h *HealthHandler) Health(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// AddMetrics reports the value fn returns under metrics.<name> in the health response.
+// It must be called before the handler serves requests.
+func (h *HealthHandler) AddMetrics(name string, fn func() interface{}) {
+	if h.metrics == nil {
+		h.metrics = make(map[string]func() interface{})
+	}
+	h.metrics[name] = fn
+}
+
+// StartDraining makes the readiness check fail from now on, so that load
+// balancers stop routing new requests here before the server shuts down
+func (h *HealthHandler) StartDraining() {
+	h.draining.Store(true)
+}
+
 // Ready handles GET /ready
 func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 	// Check if all dependencies are ready
 	ready := true
 	
-	// Check database connection
-	if err := h.db.Health(); err != nil {
+	// A draining instance is shutting down and must not receive new traffic
+	draining := h.draining.Load()
+	if draining {
+		ready = false
+	} else if err := h.db.Health(); err != nil {
+		// Check database connection
 		h.log.WithError(err).Error("Database readiness check failed")
 		ready = false
 	}
@@ -78,6 +118,7 @@ func (h *HealthHandler) Ready(w http.ResponseWriter, r *http.Request) {
 	} else {
 		utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Service is not ready", map[string]interface{}{
 			"ready":     false,
+			"draining":  draining,
 			"timestamp": time.Now().UTC(),
 		})
 	}
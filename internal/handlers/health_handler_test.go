@@ -0,0 +1,45 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHealthHandler_Ready(t *testing.T) {
+	log := logger.New("error", "text")
+	cfg := &config.Config{Database: config.DatabaseConfig{Driver: "sqlite", Name: filepath.Join(t.TempDir(), "health.db")}}
+	db, err := repository.NewDatabase(cfg, log)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	handler := NewHealthHandler(db, nil, log)
+
+	ready := func() (int, map[string]interface{}) {
+		recorder := httptest.NewRecorder()
+		handler.Ready(recorder, httptest.NewRequest(http.MethodGet, "/ready", nil))
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+		return recorder.Code, body
+	}
+
+	code, body := ready()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, true, body["data"].(map[string]interface{})["ready"])
+
+	// Once draining, the instance reports itself unready although the database is fine
+	handler.StartDraining()
+	code, body = ready()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	details := body["error"].(map[string]interface{})
+	assert.Equal(t, false, details["ready"])
+	assert.Equal(t, true, details["draining"])
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// IdentityHandler handles HTTP requests linking external identities to users
+type IdentityHandler struct {
+	oidcService services.OIDCService
+	log         *logger.Logger
+	validator   *validator.Validate
+}
+
+// NewIdentityHandler creates a new identity handler
+func NewIdentityHandler(oidcService services.OIDCService, log *logger.Logger) *IdentityHandler {
+	return &IdentityHandler{
+		oidcService: oidcService,
+		log:         log,
+		validator:   validator.New(),
+	}
+}
+
+// Link handles POST /auth/profile/identities, linking the identity of a token of the external
+// OpenID Provider to the caller, and merging the account it belongs to when asked
+func (h *IdentityHandler) Link(w http.ResponseWriter, r *http.Request) {
+	var req models.IdentityLinkRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in link identity request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	identity, err := h.oidcService.Link(r.Context(), userID, &req)
+	switch {
+	case errors.Is(err, services.ErrInvalidIdentityToken), errors.Is(err, services.ErrInvalidMerge):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	case errors.Is(err, services.ErrIdentityLinked):
+		utils.WriteErrorResponse(w, http.StatusConflict, "Identity is linked to another account; pass merge to merge that account into yours", nil)
+		return
+	case errors.Is(err, services.ErrUserChanged):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	case err != nil:
+		h.log.WithError(err).WithField("user_id", userID).Error("Failed to link identity")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to link identity", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Identity linked successfully", identity)
+}
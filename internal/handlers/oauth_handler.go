@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// OAuthHandler handles authorization server HTTP requests
+type OAuthHandler struct {
+	oauthService services.OAuthService
+	log          *logger.Logger
+	validator    *validator.Validate
+}
+
+// NewOAuthHandler creates a new OAuth handler
+func NewOAuthHandler(oauthService services.OAuthService, log *logger.Logger) *OAuthHandler {
+	return &OAuthHandler{
+		oauthService: oauthService,
+		log:          log,
+		validator:    validator.New(),
+	}
+}
+
+// RegisterClient handles POST /admin/oauth/clients
+func (h *OAuthHandler) RegisterClient(w http.ResponseWriter, r *http.Request) {
+	var req models.OAuthClientCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in register client request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for register client request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	ownerID, _ := middleware.GetUserIDFromContext(r.Context())
+	client, err := h.oauthService.RegisterClient(r.Context(), ownerID, &req)
+	if err != nil {
+		h.log.WithError(err).Error("Failed to register OAuth client")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to register client", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Client registered successfully", client)
+}
+
+// ListClients handles GET /admin/oauth/clients
+func (h *OAuthHandler) ListClients(w http.ResponseWriter, r *http.Request) {
+	clients, err := h.oauthService.ListClients(r.Context())
+	if err != nil {
+		h.log.WithError(err).Error("Failed to list OAuth clients")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve clients", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Clients retrieved successfully", clients)
+}
+
+// DeleteClient handles DELETE /admin/oauth/clients/{clientId}
+func (h *OAuthHandler) DeleteClient(w http.ResponseWriter, r *http.Request) {
+	clientID := chi.URLParam(r, "clientId")
+	if err := h.oauthService.DeleteClient(r.Context(), clientID); err != nil {
+		h.log.WithError(err).WithField("client_id", clientID).Error("Failed to delete OAuth client")
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Client deleted successfully", nil)
+}
+
+// GetConsent handles GET /oauth/authorize - returns the data needed to render the consent screen
+func (h *OAuthHandler) GetConsent(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := models.OAuthAuthorizeRequest{
+		ResponseType:        query.Get("response_type"),
+		ClientID:            query.Get("client_id"),
+		RedirectURI:         query.Get("redirect_uri"),
+		Scope:               query.Get("scope"),
+		State:               query.Get("state"),
+		CodeChallenge:       query.Get("code_challenge"),
+		CodeChallengeMethod: query.Get("code_challenge_method"),
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	consent, err := h.oauthService.PrepareConsent(r.Context(), userID, &req)
+	if err != nil {
+		h.writeAuthorizeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Consent data retrieved successfully", consent)
+}
+
+// Authorize handles POST /oauth/authorize - records the consent decision
+func (h *OAuthHandler) Authorize(w http.ResponseWriter, r *http.Request) {
+	var req models.OAuthConsentDecision
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in authorize request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	redirectURL, err := h.oauthService.Authorize(r.Context(), userID, &req)
+	if err != nil {
+		h.writeAuthorizeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Authorization processed", map[string]interface{}{
+		"redirect_to": redirectURL,
+	})
+}
+
+// Token handles POST /oauth/token
+func (h *OAuthHandler) Token(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+
+	if err := r.ParseForm(); err != nil {
+		utils.WriteJSONResponse(w, http.StatusBadRequest, &models.OAuthError{Code: "invalid_request"})
+		return
+	}
+
+	req := models.OAuthTokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+	}
+
+	// Prefer HTTP Basic client authentication when present
+	if clientID, clientSecret, ok := r.BasicAuth(); ok {
+		req.ClientID = clientID
+		req.ClientSecret = clientSecret
+	}
+
+	token, err := h.oauthService.Exchange(r.Context(), &req)
+	if err != nil {
+		var oauthErr *models.OAuthError
+		if errors.As(err, &oauthErr) {
+			status := http.StatusBadRequest
+			if oauthErr.Code == "invalid_client" {
+				status = http.StatusUnauthorized
+			}
+			utils.WriteJSONResponse(w, status, oauthErr)
+			return
+		}
+		h.log.WithError(err).Error("Failed to exchange authorization code")
+		utils.WriteJSONResponse(w, http.StatusInternalServerError, &models.OAuthError{Code: "server_error"})
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, token)
+}
+
+// UserInfo handles GET /oauth/userinfo
+func (h *OAuthHandler) UserInfo(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	scope, _ := middleware.GetScopeFromContext(r.Context())
+
+	claims, err := h.oauthService.UserInfo(r.Context(), userID, scope)
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", userID).Warn("Failed to get userinfo")
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		utils.WriteJSONResponse(w, http.StatusUnauthorized, &models.OAuthError{Code: "invalid_token"})
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, claims)
+}
+
+// Discovery handles GET /.well-known/openid-configuration
+func (h *OAuthHandler) Discovery(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSONResponse(w, http.StatusOK, h.oauthService.Discovery())
+}
+
+// writeAuthorizeError writes an error for the authorize endpoints without redirecting
+func (h *OAuthHandler) writeAuthorizeError(w http.ResponseWriter, err error) {
+	var oauthErr *models.OAuthError
+	if errors.As(err, &oauthErr) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, oauthErr.Description, oauthErr)
+		return
+	}
+	h.log.WithError(err).Error("Failed to process authorization request")
+	utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to process authorization request", nil)
+}
@@ -0,0 +1,273 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// OrganizationHandler handles organization and membership HTTP requests
+type OrganizationHandler struct {
+	orgService services.OrganizationService
+	log        *logger.Logger
+	validator  *validator.Validate
+}
+
+// NewOrganizationHandler creates a new organization handler
+func NewOrganizationHandler(orgService services.OrganizationService, log *logger.Logger) *OrganizationHandler {
+	return &OrganizationHandler{
+		orgService: orgService,
+		log:        log,
+		validator:  validator.New(),
+	}
+}
+
+// Create handles POST /orgs, making the caller the owner of the new organization
+func (h *OrganizationHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.OrganizationCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in create organization request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	org, err := h.orgService.Create(r.Context(), actorID, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to create organization")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Organization created successfully", org)
+}
+
+// List handles GET /orgs, listing the organizations the caller is a member of
+func (h *OrganizationHandler) List(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	orgs, err := h.orgService.List(r.Context(), userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve organizations", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organizations retrieved successfully", orgs)
+}
+
+// GetByID handles GET /orgs/{id}
+func (h *OrganizationHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, isAdmin := h.actor(r)
+	org, err := h.orgService.GetByID(r.Context(), actorID, isAdmin, id)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve organization")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization retrieved successfully", org)
+}
+
+// Update handles PUT /orgs/{id}
+func (h *OrganizationHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.OrganizationUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in update organization request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, isAdmin := h.actor(r)
+	org, err := h.orgService.Update(r.Context(), actorID, isAdmin, id, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to update organization")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization updated successfully", org)
+}
+
+// Delete handles DELETE /orgs/{id}
+func (h *OrganizationHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, isAdmin := h.actor(r)
+	if err := h.orgService.Delete(r.Context(), actorID, isAdmin, id); err != nil {
+		h.writeError(w, err, "Failed to delete organization")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization deleted successfully", nil)
+}
+
+// ListMembers handles GET /orgs/{id}/members
+func (h *OrganizationHandler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, isAdmin := h.actor(r)
+	members, err := h.orgService.ListMembers(r.Context(), actorID, isAdmin, id)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve organization members")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization members retrieved successfully", members)
+}
+
+// AddMember handles POST /orgs/{id}/members
+func (h *OrganizationHandler) AddMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MembershipCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in add organization member request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, isAdmin := h.actor(r)
+	member, err := h.orgService.AddMember(r.Context(), actorID, isAdmin, id, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to add organization member")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Organization member added successfully", member)
+}
+
+// UpdateMember handles PUT /orgs/{id}/members/{userId}, changing the member's role
+func (h *OrganizationHandler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.MembershipUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in update organization member request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, isAdmin := h.actor(r)
+	member, err := h.orgService.UpdateMember(r.Context(), actorID, isAdmin, id, userID, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to update organization member")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization member updated successfully", member)
+}
+
+// RemoveMember handles DELETE /orgs/{id}/members/{userId}. Members can remove themselves to leave.
+func (h *OrganizationHandler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.orgID(w, r)
+	if !ok {
+		return
+	}
+	userID, ok := h.memberID(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, isAdmin := h.actor(r)
+	if err := h.orgService.RemoveMember(r.Context(), actorID, isAdmin, id, userID); err != nil {
+		h.writeError(w, err, "Failed to remove organization member")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Organization member removed successfully", nil)
+}
+
+// actor returns the ID of the requesting user and whether they are an admin
+func (h *OrganizationHandler) actor(r *http.Request) (uint, bool) {
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	return actorID, isAdmin
+}
+
+// orgID parses the organization ID in the URL, writing an error response when it is invalid
+func (h *OrganizationHandler) orgID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid organization ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// memberID parses the member's user ID in the URL, writing an error response when it is invalid
+func (h *OrganizationHandler) memberID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "userId"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError maps errors of organization management to HTTP status codes
+func (h *OrganizationHandler) writeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrOrganizationNotFound), errors.Is(err, services.ErrMembershipNotFound),
+		errors.Is(err, services.ErrUserNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrOrgRoleInsufficient):
+		utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, services.ErrAlreadyMember), errors.Is(err, services.ErrLastOwner):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	default:
+		h.log.WithError(err).Error(message)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, message, nil)
+	}
+}
@@ -0,0 +1,157 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// PermissionHandler handles permission management HTTP requests
+type PermissionHandler struct {
+	permissionService services.PermissionService
+	log               *logger.Logger
+	validator         *validator.Validate
+}
+
+// NewPermissionHandler creates a new permission handler
+func NewPermissionHandler(permissionService services.PermissionService, log *logger.Logger) *PermissionHandler {
+	return &PermissionHandler{
+		permissionService: permissionService,
+		log:               log,
+		validator:         validator.New(),
+	}
+}
+
+// Create handles POST /admin/permissions
+func (h *PermissionHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.PermissionCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in create permission request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	permission, err := h.permissionService.Create(r.Context(), actorID, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to create permission")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Permission created successfully", permission)
+}
+
+// List handles GET /admin/permissions
+func (h *PermissionHandler) List(w http.ResponseWriter, r *http.Request) {
+	permissions, err := h.permissionService.List(r.Context())
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve permissions", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Permissions retrieved successfully", permissions)
+}
+
+// Catalog handles GET /admin/permissions/catalog, listing the known resource/action pairs
+// for admin UIs to pick from
+func (h *PermissionHandler) Catalog(w http.ResponseWriter, r *http.Request) {
+	utils.WriteSuccessResponse(w, http.StatusOK, "Permission catalog retrieved successfully", h.permissionService.Catalog())
+}
+
+// GetByID handles GET /admin/permissions/{id}
+func (h *PermissionHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.permissionID(w, r)
+	if !ok {
+		return
+	}
+
+	permission, err := h.permissionService.GetByID(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve permission")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Permission retrieved successfully", permission)
+}
+
+// Update handles PUT /admin/permissions/{id}
+func (h *PermissionHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.permissionID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.PermissionUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in update permission request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	permission, err := h.permissionService.Update(r.Context(), actorID, id, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to update permission")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Permission updated successfully", permission)
+}
+
+// Delete handles DELETE /admin/permissions/{id}
+func (h *PermissionHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.permissionID(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.permissionService.Delete(r.Context(), actorID, id); err != nil {
+		h.writeError(w, err, "Failed to delete permission")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Permission deleted successfully", nil)
+}
+
+// permissionID parses the permission ID in the URL, writing an error response when it is invalid
+func (h *PermissionHandler) permissionID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid permission ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError maps errors of permission management to HTTP status codes
+func (h *PermissionHandler) writeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrPermissionNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrPermissionNameTaken):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, message, nil)
+	}
+}
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// RoleHandler handles role management HTTP requests
+type RoleHandler struct {
+	roleService services.RoleService
+	log         *logger.Logger
+	validator   *validator.Validate
+}
+
+// NewRoleHandler creates a new role handler
+func NewRoleHandler(roleService services.RoleService, log *logger.Logger) *RoleHandler {
+	return &RoleHandler{
+		roleService: roleService,
+		log:         log,
+		validator:   validator.New(),
+	}
+}
+
+// Create handles POST /admin/roles
+func (h *RoleHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.RoleCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in create role request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	role, err := h.roleService.Create(r.Context(), actorID, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to create role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Role created successfully", role)
+}
+
+// List handles GET /admin/roles
+func (h *RoleHandler) List(w http.ResponseWriter, r *http.Request) {
+	roles, err := h.roleService.List(r.Context())
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve roles", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Roles retrieved successfully", roles)
+}
+
+// GetByID handles GET /admin/roles/{id}
+func (h *RoleHandler) GetByID(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.roleID(w, r)
+	if !ok {
+		return
+	}
+
+	role, err := h.roleService.GetByID(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role retrieved successfully", role)
+}
+
+// Update handles PUT /admin/roles/{id}
+func (h *RoleHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.roleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.RoleUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in update role request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	role, err := h.roleService.Update(r.Context(), actorID, id, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to update role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role updated successfully", role)
+}
+
+// Delete handles DELETE /admin/roles/{id}
+func (h *RoleHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.roleID(w, r)
+	if !ok {
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.roleService.Delete(r.Context(), actorID, id); err != nil {
+		h.writeError(w, err, "Failed to delete role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role deleted successfully", nil)
+}
+
+// SetPermissions handles PUT /admin/roles/{id}/permissions, replacing the permissions of the role
+func (h *RoleHandler) SetPermissions(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.roleID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AssignPermissionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in set role permissions request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+	req.RoleID = id
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	role, err := h.roleService.SetPermissions(r.Context(), actorID, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to set role permissions")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role permissions set successfully", role)
+}
+
+// ListForUser handles GET /users/{id}/roles. Users can see their own roles, admins anyone's.
+func (h *RoleHandler) ListForUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	if userID != id && !isAdmin {
+		utils.WriteErrorResponse(w, http.StatusForbidden, "You can only view your own roles", nil)
+		return
+	}
+
+	roles, err := h.roleService.ListForUser(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve user roles")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User roles retrieved successfully", roles)
+}
+
+// AssignToUser handles POST /admin/users/{id}/roles, adding roles to those the user already has
+func (h *RoleHandler) AssignToUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.AssignRoleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in assign roles request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+	req.UserID = id
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	roles, err := h.roleService.AssignToUser(r.Context(), actorID, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to assign roles")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Roles assigned successfully", roles)
+}
+
+// RemoveFromUser handles DELETE /admin/users/{id}/roles/{roleId}
+func (h *RoleHandler) RemoveFromUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	roleID, err := strconv.ParseUint(chi.URLParam(r, "roleId"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid role ID", nil)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.roleService.RemoveFromUser(r.Context(), actorID, id, uint(roleID)); err != nil {
+		h.writeError(w, err, "Failed to remove role")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Role removed successfully", nil)
+}
+
+// roleID parses the role ID in the URL, writing an error response when it is invalid
+func (h *RoleHandler) roleID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid role ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// userID parses the user ID in the URL, writing an error response when it is invalid
+func (h *RoleHandler) userID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError maps errors of role management to HTTP status codes
+func (h *RoleHandler) writeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrRoleNotFound), errors.Is(err, services.ErrPermissionNotFound),
+		errors.Is(err, services.ErrUserNotFound), errors.Is(err, services.ErrRoleNotAssigned),
+		errors.Is(err, services.ErrParentRoleNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrRoleNameTaken), errors.Is(err, services.ErrRoleCycle), errors.Is(err, services.ErrUserChanged):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, message, nil)
+	}
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
+	"github.com/go-playground/validator/v10"
+)
+
+// samlLoginTimeout is how long a user has to sign in at the identity provider
+const samlLoginTimeout = 10 * time.Minute
+
+// SAMLHandler handles SAML single sign-on HTTP requests
+type SAMLHandler struct {
+	tokenCookies
+	samlService services.SAMLService
+	tracker     samlsp.RequestTracker
+	redirectURL string
+	log         *logger.Logger
+	validator   *validator.Validate
+}
+
+// NewSAMLHandler creates a new SAML handler. After sign-on the browser is sent to redirectURL
+// with a login code, or an error, in the query string.
+func NewSAMLHandler(samlService services.SAMLService, redirectURL string, log *logger.Logger) *SAMLHandler {
+	sp := samlService.ServiceProvider()
+
+	// Requests started here are tracked in a signed cookie, so only the browser that
+	// started a login can complete it. The identity provider posts the response
+	// cross-site, which browsers only send such cookies along with when SameSite=None.
+	options := samlsp.Options{URL: sp.MetadataURL, Key: sp.Key, CookieSameSite: http.SameSiteLaxMode}
+	if sp.AcsURL.Scheme == "https" {
+		options.CookieSameSite = http.SameSiteNoneMode
+	}
+	codec := samlsp.DefaultTrackedRequestCodec(options)
+	codec.MaxAge = samlLoginTimeout
+	tracker := samlsp.DefaultRequestTracker(options, sp)
+	tracker.Codec = codec
+	tracker.MaxAge = samlLoginTimeout
+
+	return &SAMLHandler{
+		samlService: samlService,
+		tracker:     tracker,
+		redirectURL: redirectURL,
+		log:         log,
+		validator:   validator.New(),
+	}
+}
+
+// Metadata handles GET /auth/saml/metadata
+func (h *SAMLHandler) Metadata(w http.ResponseWriter, r *http.Request) {
+	metadata, err := xml.MarshalIndent(h.samlService.ServiceProvider().Metadata(), "", "  ")
+	if err != nil {
+		h.log.WithError(err).Error("Failed to render SAML metadata")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to render metadata", nil)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/samlmetadata+xml")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(metadata)
+}
+
+// Login handles GET /auth/saml/login by redirecting to the identity provider
+func (h *SAMLHandler) Login(w http.ResponseWriter, r *http.Request) {
+	sp := h.samlService.ServiceProvider()
+	ssoURL := sp.GetSSOBindingLocation(saml.HTTPRedirectBinding)
+	if ssoURL == "" {
+		h.log.Error("SAML identity provider has no HTTP-Redirect single sign-on endpoint")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Single sign-on is unavailable", nil)
+		return
+	}
+
+	authnRequest, err := sp.MakeAuthenticationRequest(ssoURL, saml.HTTPRedirectBinding, saml.HTTPPostBinding)
+	if err != nil {
+		h.log.WithError(err).Error("Failed to create SAML authentication request")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to start single sign-on", nil)
+		return
+	}
+
+	relayState, err := h.tracker.TrackRequest(w, r, authnRequest.ID)
+	if err != nil {
+		h.log.WithError(err).Error("Failed to track SAML authentication request")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to start single sign-on", nil)
+		return
+	}
+
+	redirect, err := authnRequest.Redirect(relayState, sp)
+	if err != nil {
+		h.log.WithError(err).Error("Failed to encode SAML authentication request")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to start single sign-on", nil)
+		return
+	}
+
+	http.Redirect(w, r, redirect.String(), http.StatusFound)
+}
+
+// ACS handles POST /auth/saml/acs, the assertion consumer service the identity provider posts to
+func (h *SAMLHandler) ACS(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid form", nil)
+		return
+	}
+
+	var requestIDs []string
+	for _, tracked := range h.tracker.GetTrackedRequests(r) {
+		requestIDs = append(requestIDs, tracked.SAMLRequestID)
+	}
+
+	assertion, err := h.samlService.ServiceProvider().ParseResponse(r, requestIDs)
+	if err != nil {
+		// The detailed reason is only logged; the public error is deliberately vague
+		var invalid *saml.InvalidResponseError
+		if errors.As(err, &invalid) {
+			err = invalid.PrivateErr
+		}
+		h.log.WithError(err).Warn("Invalid SAML response")
+		h.redirect(w, r, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	if relayState := r.PostForm.Get("RelayState"); relayState != "" {
+		_ = h.tracker.StopTrackingRequest(w, r, relayState)
+	}
+
+	code, err := h.samlService.Login(r.Context(), assertion)
+	if err != nil {
+		h.log.WithError(err).Warn("SAML login refused")
+		h.redirect(w, r, url.Values{"error": {"access_denied"}})
+		return
+	}
+
+	h.redirect(w, r, url.Values{"code": {code}})
+}
+
+// Token handles POST /auth/saml/token
+func (h *SAMLHandler) Token(w http.ResponseWriter, r *http.Request) {
+	var req models.SAMLCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in SAML token request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for SAML token request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	response, err := h.samlService.Redeem(r.Context(), req.Code, req.DeviceToken)
+	if err != nil {
+		var deactivatedErr *services.AccountDeactivatedError
+		var suspendedErr *services.AccountSuspendedError
+		switch {
+		case errors.Is(err, services.ErrInvalidLoginCode):
+			utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid or expired login code", nil)
+		case errors.As(err, &deactivatedErr):
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), map[string]interface{}{
+				"code":                      "account_deactivated",
+				"self_service_reactivation": deactivatedErr.SelfService,
+			})
+		case errors.As(err, &suspendedErr):
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), map[string]interface{}{
+				"code":            "account_suspended",
+				"suspended_until": suspendedErr.Until,
+			})
+		case errors.Is(err, services.ErrSessionLimitReached):
+			utils.WriteErrorResponse(w, http.StatusForbidden, services.ErrSessionLimitReached.Error(), map[string]interface{}{
+				"code": "session_limit_reached",
+			})
+		default:
+			h.log.WithError(err).Error("Failed to redeem SAML login code")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to log in", nil)
+		}
+		return
+	}
+
+	h.writeLoginResponse(w, h.log, "Login successful", response)
+}
+
+// redirect sends the browser back to the frontend with the given query parameters
+func (h *SAMLHandler) redirect(w http.ResponseWriter, r *http.Request, params url.Values) {
+	target, err := url.Parse(h.redirectURL)
+	if err != nil {
+		h.log.WithError(err).Error("Invalid SAML redirect URL")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Single sign-on is misconfigured", nil)
+		return
+	}
+
+	query := target.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	target.RawQuery = query.Encode()
+
+	w.Header().Set("Cache-Control", "no-store")
+	http.Redirect(w, r, target.String(), http.StatusSeeOther)
+}
@@ -0,0 +1,67 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// SettingsHandler handles user settings HTTP requests
+type SettingsHandler struct {
+	settingsService services.SettingsService
+	log             *logger.Logger
+	validator       *validator.Validate
+}
+
+// NewSettingsHandler creates a new settings handler
+func NewSettingsHandler(settingsService services.SettingsService, log *logger.Logger) *SettingsHandler {
+	return &SettingsHandler{
+		settingsService: settingsService,
+		log:             log,
+		validator:       validator.New(),
+	}
+}
+
+// Get handles GET /auth/profile/settings
+func (h *SettingsHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	settings, err := h.settingsService.Get(r.Context(), userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve settings", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Settings retrieved successfully", settings)
+}
+
+// Update handles PUT /auth/profile/settings
+func (h *SettingsHandler) Update(w http.ResponseWriter, r *http.Request) {
+	var req models.UserSettingsUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in update settings request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for update settings request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	settings, err := h.settingsService.Update(r.Context(), userID, &req)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to update settings", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Settings updated successfully", settings)
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// TagHandler handles HTTP requests for tags and the tags of users
+type TagHandler struct {
+	tagService services.TagService
+	log        *logger.Logger
+	validator  *validator.Validate
+}
+
+// NewTagHandler creates a new tag handler
+func NewTagHandler(tagService services.TagService, log *logger.Logger) *TagHandler {
+	return &TagHandler{
+		tagService: tagService,
+		log:        log,
+		validator:  validator.New(),
+	}
+}
+
+// List handles GET /admin/tags
+func (h *TagHandler) List(w http.ResponseWriter, r *http.Request) {
+	tags, err := h.tagService.List(r.Context())
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve tags", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Tags retrieved successfully", tags)
+}
+
+// Create handles POST /admin/tags
+func (h *TagHandler) Create(w http.ResponseWriter, r *http.Request) {
+	var req models.TagCreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in create tag request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	tag, err := h.tagService.Create(r.Context(), actorID, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to create tag")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Tag created successfully", tag)
+}
+
+// Update handles PUT /admin/tags/{id}
+func (h *TagHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.tagID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	var req models.TagUpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in update tag request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	tag, err := h.tagService.Update(r.Context(), actorID, id, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to update tag")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Tag updated successfully", tag)
+}
+
+// Delete handles DELETE /admin/tags/{id}
+func (h *TagHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.tagID(w, r, "id")
+	if !ok {
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.tagService.Delete(r.Context(), actorID, id); err != nil {
+		h.writeError(w, err, "Failed to delete tag")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Tag deleted successfully", nil)
+}
+
+// ListForUser handles GET /admin/users/{id}/tags
+func (h *TagHandler) ListForUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	tags, err := h.tagService.ListForUser(r.Context(), id)
+	if err != nil {
+		h.writeError(w, err, "Failed to retrieve user tags")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User tags retrieved successfully", tags)
+}
+
+// AssignToUser handles POST /admin/users/{id}/tags, adding tags to those the user already has
+func (h *TagHandler) AssignToUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+
+	var req models.UserTagsRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in tag user request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	tags, err := h.tagService.AssignToUser(r.Context(), actorID, id, &req)
+	if err != nil {
+		h.writeError(w, err, "Failed to tag user")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User tagged successfully", tags)
+}
+
+// RemoveFromUser handles DELETE /admin/users/{id}/tags/{tagId}
+func (h *TagHandler) RemoveFromUser(w http.ResponseWriter, r *http.Request) {
+	id, ok := h.userID(w, r)
+	if !ok {
+		return
+	}
+	tagID, ok := h.tagID(w, r, "tagId")
+	if !ok {
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.tagService.RemoveFromUser(r.Context(), actorID, id, tagID); err != nil {
+		h.writeError(w, err, "Failed to remove tag")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Tag removed successfully", nil)
+}
+
+// tagID parses the tag ID in the URL parameter, writing an error response when it is invalid
+func (h *TagHandler) tagID(w http.ResponseWriter, r *http.Request, param string) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, param), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid tag ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// userID parses the user ID in the URL, writing an error response when it is invalid
+func (h *TagHandler) userID(w http.ResponseWriter, r *http.Request) (uint, bool) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// writeError maps errors of tag management to HTTP status codes
+func (h *TagHandler) writeError(w http.ResponseWriter, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidTagName):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, services.ErrTagNotFound), errors.Is(err, services.ErrUserNotFound),
+		errors.Is(err, services.ErrTagNotAssigned):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrTagNameTaken):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	default:
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, message, nil)
+	}
+}
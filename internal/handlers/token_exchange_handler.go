@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+)
+
+// TokenExchangeHandler handles RFC 8693 token exchange requests
+type TokenExchangeHandler struct {
+	tokenExchangeService services.TokenExchangeService
+	log                  *logger.Logger
+}
+
+// NewTokenExchangeHandler creates a new token exchange handler
+func NewTokenExchangeHandler(tokenExchangeService services.TokenExchangeService, log *logger.Logger) *TokenExchangeHandler {
+	return &TokenExchangeHandler{
+		tokenExchangeService: tokenExchangeService,
+		log:                  log,
+	}
+}
+
+// Exchange handles POST /auth/token
+func (h *TokenExchangeHandler) Exchange(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+
+	if err := r.ParseForm(); err != nil {
+		utils.WriteJSONResponse(w, http.StatusBadRequest, &models.OAuthError{Code: "invalid_request"})
+		return
+	}
+
+	req := models.TokenExchangeRequest{
+		GrantType:          r.PostForm.Get("grant_type"),
+		SubjectToken:       r.PostForm.Get("subject_token"),
+		SubjectTokenType:   r.PostForm.Get("subject_token_type"),
+		RequestedTokenType: r.PostForm.Get("requested_token_type"),
+		Audience:           r.PostForm.Get("audience"),
+	}
+
+	resp, err := h.tokenExchangeService.Exchange(r.Context(), &req)
+	if err != nil {
+		var oauthErr *models.OAuthError
+		if errors.As(err, &oauthErr) {
+			utils.WriteJSONResponse(w, http.StatusBadRequest, oauthErr)
+			return
+		}
+		h.log.WithError(err).Error("Token exchange failed")
+		utils.WriteJSONResponse(w, http.StatusInternalServerError, &models.OAuthError{Code: "server_error"})
+		return
+	}
+
+	utils.WriteJSONResponse(w, http.StatusOK, resp)
+}
@@ -0,0 +1,206 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// UploadHandler handles chunked upload HTTP requests
+type UploadHandler struct {
+	uploadService services.UploadService
+	log           *logger.Logger
+	validator     *validator.Validate
+}
+
+// NewUploadHandler creates a new upload handler
+func NewUploadHandler(uploadService services.UploadService, log *logger.Logger) *UploadHandler {
+	return &UploadHandler{
+		uploadService: uploadService,
+		log:           log,
+		validator:     validator.New(),
+	}
+}
+
+// Initiate handles POST /uploads
+func (h *UploadHandler) Initiate(w http.ResponseWriter, r *http.Request) {
+	var req models.UploadInitiateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in initiate upload request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for initiate upload request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	upload, err := h.uploadService.Initiate(r.Context(), userID, &req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Upload started", upload)
+}
+
+// Presign handles POST /uploads/presign
+func (h *UploadHandler) Presign(w http.ResponseWriter, r *http.Request) {
+	var req models.UploadInitiateRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in presign upload request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for presign upload request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	presigned, err := h.uploadService.Presign(r.Context(), userID, &req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Upload URL created", presigned)
+}
+
+// Get handles GET /uploads/{id}
+func (h *UploadHandler) Get(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	upload, err := h.uploadService.Get(r.Context(), userID, chi.URLParam(r, "id"))
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Upload retrieved successfully", upload)
+}
+
+// UploadPart handles PUT /uploads/{id}/parts/{part} with the raw part bytes as body
+func (h *UploadHandler) UploadPart(w http.ResponseWriter, r *http.Request) {
+	partNumber, err := strconv.Atoi(chi.URLParam(r, "part"))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid part number", nil)
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	upload, err := h.uploadService.UploadPart(r.Context(), userID, chi.URLParam(r, "id"), partNumber, r.Body)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Part uploaded", upload)
+}
+
+// Complete handles POST /uploads/{id}/complete
+func (h *UploadHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	var req models.UploadCompleteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.WithError(err).Warn("Invalid JSON in complete upload request")
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+			return
+		}
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	upload, err := h.uploadService.Complete(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Upload completed", upload)
+}
+
+// Confirm handles POST /uploads/{id}/confirm after a presigned upload finished
+func (h *UploadHandler) Confirm(w http.ResponseWriter, r *http.Request) {
+	var req models.UploadCompleteRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			h.log.WithError(err).Warn("Invalid JSON in confirm upload request")
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+			return
+		}
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	upload, err := h.uploadService.Confirm(r.Context(), userID, chi.URLParam(r, "id"), &req)
+	if err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Upload confirmed", upload)
+}
+
+// Abort handles DELETE /uploads/{id}
+func (h *UploadHandler) Abort(w http.ResponseWriter, r *http.Request) {
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.uploadService.Abort(r.Context(), userID, chi.URLParam(r, "id")); err != nil {
+		h.writeError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Upload aborted", nil)
+}
+
+// writeError maps upload errors to HTTP status codes
+func (h *UploadHandler) writeError(w http.ResponseWriter, err error) {
+	writeUploadError(w, h.log, err)
+}
+
+// writeUploadError maps upload errors to HTTP status codes
+func writeUploadError(w http.ResponseWriter, log *logger.Logger, err error) {
+	switch {
+	case errors.Is(err, services.ErrUploadNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrUploadNotPending):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, services.ErrUploadInvalid):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, services.ErrStorageQuotaExceeded):
+		utils.WriteErrorResponse(w, http.StatusRequestEntityTooLarge, err.Error(), nil)
+	case errors.Is(err, services.ErrUploadContentRejected):
+		utils.WriteErrorResponse(w, http.StatusUnsupportedMediaType, err.Error(), nil)
+	case errors.Is(err, services.ErrUploadInfected):
+		utils.WriteErrorResponse(w, http.StatusUnprocessableEntity, err.Error(), nil)
+	case errors.Is(err, services.ErrScanUnavailable):
+		utils.WriteErrorResponse(w, http.StatusServiceUnavailable, err.Error(), nil)
+	case errors.Is(err, services.ErrPresignUnsupported):
+		utils.WriteErrorResponse(w, http.StatusNotImplemented, err.Error(), nil)
+	default:
+		log.WithError(err).Error("Upload request failed")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Upload failed", nil)
+	}
+}
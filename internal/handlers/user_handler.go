@@ -1,9 +1,15 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"io"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"gbt-be-template/internal/models"
 	"gbt-be-template/internal/services"
@@ -17,6 +23,7 @@ import (
 
 // UserHandler handles user-related HTTP requests
 type UserHandler struct {
+	tokenCookies
 	userService services.UserService
 	log         *logger.Logger
 	validator   *validator.Validate
@@ -50,6 +57,9 @@ func (h *UserHandler) Create(w http.ResponseWriter, r *http.Request) {
 	// Create user
 	user, err := h.userService.Create(r.Context(), &req)
 	if err != nil {
+		if h.writePasswordCheckError(w, err) {
+			return
+		}
 		h.log.WithError(err).Error("Failed to create user")
 		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
 		return
@@ -110,7 +120,15 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Update user
-	user, err := h.userService.Update(r.Context(), uint(id), &req)
+	user, err := h.userService.Update(r.Context(), userID, isAdmin, uint(id), &req)
+	if errors.Is(err, services.ErrUserChanged) {
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if errors.Is(err, services.ErrActiveStatusForbidden) {
+		utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), nil)
+		return
+	}
 	if err != nil {
 		h.log.WithError(err).WithField("user_id", id).Error("Failed to update user")
 		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
@@ -120,6 +138,95 @@ func (h *UserHandler) Update(w http.ResponseWriter, r *http.Request) {
 	utils.WriteSuccessResponse(w, http.StatusOK, "User updated successfully", user)
 }
 
+// maxPatchSize bounds the body of a PATCH request
+const maxPatchSize = 1 << 20
+
+// Patch handles PATCH /users/{id} with a JSON merge patch or JSON patch.
+// Unlike PUT, a patch can clear optional fields by setting them to null or removing them.
+func (h *UserHandler) Patch(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	// Check if user is updating their own profile or is admin
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+
+	if userID != uint(id) && !isAdmin {
+		utils.WriteErrorResponse(w, http.StatusForbidden, "You can only update your own profile", nil)
+		return
+	}
+
+	patch, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxPatchSize))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Failed to read request body", nil)
+		return
+	}
+
+	current, err := h.userService.GetByID(r.Context(), uint(id))
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", id).Error("Failed to get user for patch")
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	doc, err := json.Marshal(models.NewUserPatchDocument(current))
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to patch user", nil)
+		return
+	}
+	patched, err := utils.ApplyPatch(r.Header.Get("Content-Type"), doc, patch)
+	switch {
+	case errors.Is(err, utils.ErrUnsupportedPatchType):
+		w.Header().Set("Accept-Patch", utils.MergePatchContentType+", "+utils.JSONPatchContentType)
+		utils.WriteErrorResponse(w, http.StatusUnsupportedMediaType, "Unsupported patch format", nil)
+		return
+	case errors.Is(err, utils.ErrPatchTestFailed):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	case err != nil:
+		h.log.WithError(err).Warn("Invalid patch in patch user request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid patch", err.Error())
+		return
+	}
+
+	// Members the document does not have, such as is_admin, cannot be patched in
+	var result models.UserPatchDocument
+	decoder := json.NewDecoder(bytes.NewReader(patched))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&result); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid patch", err.Error())
+		return
+	}
+
+	// Validate the patched user
+	if err := h.validator.Struct(&result); err != nil {
+		h.log.WithError(err).Warn("Validation failed for patch user request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	user, err := h.userService.Update(r.Context(), userID, isAdmin, uint(id), result.UpdateRequest(current))
+	if errors.Is(err, services.ErrUserChanged) {
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if errors.Is(err, services.ErrActiveStatusForbidden) {
+		utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).WithField("user_id", id).Error("Failed to patch user")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User updated successfully", user)
+}
+
 // AdminUpdate handles PUT /admin/users/{id} - Admin can update any user including admin status
 func (h *UserHandler) AdminUpdate(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
@@ -144,122 +251,1239 @@ func (h *UserHandler) AdminUpdate(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Admin update user
-	user, err := h.userService.AdminUpdate(r.Context(), uint(id), &req)
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.AdminUpdate(r.Context(), actorID, uint(id), &req)
 	if err != nil {
 		h.log.WithError(err).WithField("user_id", id).Error("Failed to admin update user")
-		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		status := http.StatusBadRequest
+		if errors.Is(err, services.ErrUserNotFound) || errors.Is(err, services.ErrRoleNotFound) {
+			status = http.StatusNotFound
+		} else if errors.Is(err, services.ErrUserChanged) {
+			status = http.StatusConflict
+		}
+		utils.WriteErrorResponse(w, status, err.Error(), nil)
 		return
 	}
 
 	utils.WriteSuccessResponse(w, http.StatusOK, "User updated successfully by admin", user)
 }
 
-// Delete handles DELETE /users/{id}
-func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
-	idStr := chi.URLParam(r, "id")
-	id, err := strconv.ParseUint(idStr, 10, 32)
+// AdminGet handles GET /admin/users/{id}, including moderation details such as suspensions
+func (h *UserHandler) AdminGet(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
 	if err != nil {
 		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
 		return
 	}
 
-	// Check if user is deleting their own profile or is admin
-	userID, _ := middleware.GetUserIDFromContext(r.Context())
-	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	user, err := h.userService.AdminGet(r.Context(), uint(id))
+	if err != nil {
+		h.writeModerationError(w, err)
+		return
+	}
 
-	if userID != uint(id) && !isAdmin {
-		utils.WriteErrorResponse(w, http.StatusForbidden, "You can only delete your own profile", nil)
+	utils.WriteSuccessResponse(w, http.StatusOK, "User retrieved successfully", user)
+}
+
+// Suspend handles POST /admin/users/{id}/suspension
+func (h *UserHandler) Suspend(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
 		return
 	}
 
-	if err := h.userService.Delete(r.Context(), uint(id)); err != nil {
-		h.log.WithError(err).WithField("user_id", id).Error("Failed to delete user")
-		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+	var req models.UserSuspendRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in suspend user request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
 		return
 	}
 
-	utils.WriteSuccessResponse(w, http.StatusOK, "User deleted successfully", nil)
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for suspend user request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.Suspend(r.Context(), actorID, uint(id), &req)
+	if err != nil {
+		h.writeModerationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User suspended", user)
 }
 
-// List handles GET /users
-func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
-	// Parse query parameters
-	pageStr := r.URL.Query().Get("page")
-	limitStr := r.URL.Query().Get("limit")
+// Unsuspend handles DELETE /admin/users/{id}/suspension
+func (h *UserHandler) Unsuspend(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.Unsuspend(r.Context(), actorID, uint(id))
+	if err != nil {
+		h.writeModerationError(w, err)
+		return
+	}
 
-	page := 1
-	limit := 10
+	utils.WriteSuccessResponse(w, http.StatusOK, "User suspension lifted", user)
+}
 
-	if pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+// Reactivate handles POST /admin/users/{id}/activate and its older name, /reactivate
+func (h *UserHandler) Reactivate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.Reactivate(r.Context(), actorID, uint(id))
+	if err != nil {
+		h.writeModerationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User reactivated", user)
+}
+
+// Restore handles POST /admin/users/{id}/restore
+func (h *UserHandler) Restore(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.Restore(r.Context(), actorID, uint(id))
+	if err != nil {
+		h.writeModerationError(w, err)
+		return
 	}
 
-	if limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
+	utils.WriteSuccessResponse(w, http.StatusOK, "User restored", user)
+}
+
+// AdminDelete handles DELETE /admin/users/{id}?hard=true. Without hard the user is soft deleted
+// and can be restored, unless its personal data is scrubbed on deletion.
+func (h *UserHandler) AdminDelete(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+	hard := false
+	if value := r.URL.Query().Get("hard"); value != "" {
+		if hard, err = strconv.ParseBool(value); err != nil {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, "invalid hard", nil)
+			return
 		}
 	}
 
-	users, total, err := h.userService.List(r.Context(), page, limit)
+	if !hard {
+		h.Delete(w, r)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.userService.HardDelete(r.Context(), actorID, uint(id)); err != nil {
+		h.writeModerationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User permanently deleted", nil)
+}
+
+// Deactivate handles POST /admin/users/{id}/deactivate
+func (h *UserHandler) Deactivate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
 	if err != nil {
-		h.log.WithError(err).Error("Failed to list users")
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve users", nil)
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.Deactivate(r.Context(), actorID, uint(id))
+	if err != nil {
+		h.writeModerationError(w, err)
 		return
 	}
 
-	utils.WritePaginatedResponse(w, http.StatusOK, "Users retrieved successfully", users, total, page, limit)
+	utils.WriteSuccessResponse(w, http.StatusOK, "User deactivated", user)
 }
 
-// Login handles POST /auth/login
-func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
-	var req models.UserLoginRequest
+// Merge handles POST /admin/users/{id}/merge, merging the duplicate account in the request into
+// the user
+func (h *UserHandler) Merge(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	var req models.AccountMergeRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.log.WithError(err).Warn("Invalid JSON in login request")
+		h.log.WithError(err).Warn("Invalid JSON in merge accounts request")
 		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
 		return
 	}
 
-	// Validate request
 	if err := h.validator.Struct(&req); err != nil {
-		h.log.WithError(err).Warn("Validation failed for login request")
 		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
 		return
 	}
 
-	// Authenticate user
-	token, user, err := h.userService.Login(r.Context(), &req)
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.MergeAccounts(r.Context(), actorID, uint(id), req.DuplicateID)
+	if err != nil {
+		h.writeModerationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Accounts merged", user)
+}
+
+// LogoutAll handles POST /admin/users/{id}/logout-all
+func (h *UserHandler) LogoutAll(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
 	if err != nil {
-		h.log.WithError(err).WithField("email", req.Email).Warn("Login failed")
-		utils.WriteErrorResponse(w, http.StatusUnauthorized, err.Error(), nil)
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
 		return
 	}
 
-	// Return token and user info
-	response := map[string]interface{}{
-		"access_token": token,
-		"user":         user,
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	if err := h.userService.LogoutAll(r.Context(), actorID, uint(id)); err != nil {
+		h.writeModerationError(w, err)
+		return
 	}
 
-	utils.WriteSuccessResponse(w, http.StatusOK, "Login successful", response)
+	utils.WriteSuccessResponse(w, http.StatusOK, "User signed out of all sessions", nil)
 }
 
-// Logout handles POST /auth/logout
-func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
-	userID, ok := middleware.GetUserIDFromContext(r.Context())
-	if !ok {
-		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+// RequirePasswordChange handles POST /admin/users/{id}/password-change
+func (h *UserHandler) RequirePasswordChange(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
 		return
 	}
 
-	if err := h.userService.Logout(r.Context(), userID); err != nil {
-		h.log.WithError(err).WithField("user_id", userID).Error("Failed to logout user")
-		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Logout failed", nil)
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	user, err := h.userService.RequirePasswordChange(r.Context(), actorID, uint(id))
+	if err != nil {
+		h.writeModerationError(w, err)
 		return
 	}
 
-	utils.WriteSuccessResponse(w, http.StatusOK, "Logout successful", nil)
+	utils.WriteSuccessResponse(w, http.StatusOK, "User must change their password", user)
+}
+
+// Impersonate handles POST /admin/users/{id}/impersonate
+func (h *UserHandler) Impersonate(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	actorID, _ := middleware.GetUserIDFromContext(r.Context())
+	response, err := h.userService.Impersonate(r.Context(), actorID, uint(id))
+	if err != nil {
+		h.writeModerationError(w, err)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Impersonation token issued", response)
+}
+
+// writeModerationError maps errors of admin moderation endpoints to HTTP status codes
+func (h *UserHandler) writeModerationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	case errors.Is(err, services.ErrInvalidSuspension), errors.Is(err, services.ErrInvalidDeactivation), errors.Is(err, services.ErrInvalidImpersonation),
+		errors.Is(err, services.ErrInvalidRestore), errors.Is(err, services.ErrInvalidHardDelete), errors.Is(err, services.ErrInvalidMerge):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+	case errors.Is(err, services.ErrUserChanged):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	default:
+		h.log.WithError(err).Error("User moderation request failed")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Request failed", nil)
+	}
+}
+
+// Delete handles DELETE /users/{id}
+func (h *UserHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	id, err := strconv.ParseUint(idStr, 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	// Check if user is deleting their own profile or is admin
+	userID, _ := middleware.GetUserIDFromContext(r.Context())
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+
+	if userID != uint(id) && !isAdmin {
+		utils.WriteErrorResponse(w, http.StatusForbidden, "You can only delete your own profile", nil)
+		return
+	}
+
+	if err := h.userService.Delete(r.Context(), uint(id)); err != nil {
+		h.log.WithError(err).WithField("user_id", id).Error("Failed to delete user")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "User deleted successfully", nil)
+}
+
+// List handles GET /users?search=&is_active=&is_admin=&created_after=&created_before=&deleted=&tag=&sort=
+func (h *UserHandler) List(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := userFilterParams(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	isAdmin, _ := middleware.GetIsAdminFromContext(r.Context())
+	if filter.Deleted && !isAdmin {
+		utils.WriteErrorResponse(w, http.StatusForbidden, "Only admins can list deleted users", nil)
+		return
+	}
+	if len(filter.Tags) > 0 && !isAdmin {
+		utils.WriteErrorResponse(w, http.StatusForbidden, "Only admins can filter users by tag", nil)
+		return
+	}
+	sort, err := sortParam(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	page, limit := pageParams(r)
+
+	// A cursor parameter, empty for the first page, switches to keyset pagination, which stays
+	// fast deep into large tables
+	if query.Has("cursor") {
+		users, next, prev, err := h.userService.ListByCursor(r.Context(), filter, sort, query.Get("cursor"), limit)
+		if errors.Is(err, services.ErrInvalidSort) || errors.Is(err, services.ErrInvalidCursor) {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if err != nil {
+			h.log.WithError(err).Error("Failed to list users")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve users", nil)
+			return
+		}
+		utils.WriteCursorPaginatedResponse(w, r, http.StatusOK, "Users retrieved successfully", users, limit, next, prev)
+		return
+	}
+
+	users, total, err := h.userService.List(r.Context(), filter, sort, page, limit)
+	if errors.Is(err, services.ErrInvalidSort) {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		h.log.WithError(err).Error("Failed to list users")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve users", nil)
+		return
+	}
+
+	utils.WritePaginatedResponse(w, r, http.StatusOK, "Users retrieved successfully", users, total, page, limit)
+}
+
+// userFilterParams parses the search, deleted, is_active, is_admin, created_after,
+// created_before and tag query parameters that narrow down user lists. Tags can be repeated or
+// comma separated, and users must have all of them.
+func userFilterParams(r *http.Request) (models.UserFilter, error) {
+	query := r.URL.Query()
+	filter := models.UserFilter{Search: query.Get("search")}
+	if value := query.Get("deleted"); value != "" {
+		deleted, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("invalid deleted")
+		}
+		filter.Deleted = deleted
+	}
+	for name, field := range map[string]**bool{"is_active": &filter.IsActive, "is_admin": &filter.IsAdmin} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return filter, errors.New("invalid " + name)
+		}
+		*field = &b
+	}
+	for name, field := range map[string]**time.Time{"created_after": &filter.CreatedAfter, "created_before": &filter.CreatedBefore} {
+		value := query.Get(name)
+		if value == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, value)
+		if err != nil {
+			return filter, errors.New("invalid " + name + ", use RFC 3339")
+		}
+		*field = &t
+	}
+	for _, value := range query["tag"] {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+				filter.Tags = append(filter.Tags, name)
+			}
+		}
+	}
+	return filter, nil
+}
+
+// pageParams parses the page and limit query parameters, falling back to the first page of 10
+func pageParams(r *http.Request) (page, limit int) {
+	page, limit = 1, 10
+	if p, err := strconv.Atoi(r.URL.Query().Get("page")); err == nil && p > 0 {
+		page = p
+	}
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 && l <= 100 {
+		limit = l
+	}
+	return page, limit
+}
+
+// sortParam parses the sort query parameter, a comma-separated list of fields where a leading
+// "-" sorts by the field in descending order. Which fields can be sorted by is up to the service.
+func sortParam(r *http.Request) ([]models.SortField, error) {
+	value := r.URL.Query().Get("sort")
+	if value == "" {
+		return nil, nil
+	}
+	var sort []models.SortField
+	for _, name := range strings.Split(value, ",") {
+		field := models.SortField{Name: strings.TrimSpace(name)}
+		if rest, ok := strings.CutPrefix(field.Name, "-"); ok {
+			field.Name, field.Descending = rest, true
+		}
+		if field.Name == "" {
+			return nil, errors.New("invalid sort: empty field name")
+		}
+		sort = append(sort, field)
+	}
+	return sort, nil
+}
+
+// Export handles GET /admin/users/export?format=ndjson|csv|xlsx&columns= with the filters of List.
+// Columns only apply to the tabular formats; NDJSON always carries whole users.
+func (h *UserHandler) Export(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	format := query.Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	filter, err := userFilterParams(r)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	columns := models.UserCSVHeader
+	if value := query.Get("columns"); value != "" {
+		columns = nil
+		for _, column := range strings.Split(value, ",") {
+			column = strings.TrimSpace(column)
+			if !models.IsUserCSVColumn(column) {
+				utils.WriteErrorResponse(w, http.StatusBadRequest, "Unknown export column", column)
+				return
+			}
+			columns = append(columns, column)
+		}
+	}
+
+	var stream *utils.StreamWriter
+	switch format {
+	case "ndjson":
+		w.Header().Set("Content-Disposition", `attachment; filename="users.ndjson"`)
+		stream = utils.NewNDJSONStreamWriter(w, 0)
+	case "csv":
+		w.Header().Set("Content-Disposition", `attachment; filename="users.csv"`)
+		if stream, err = utils.NewCSVStreamWriter(w, columns, 0); err != nil {
+			h.log.WithError(err).Error("Failed to start user export")
+			return
+		}
+	case "xlsx":
+		w.Header().Set("Content-Disposition", `attachment; filename="users.xlsx"`)
+		if stream, err = utils.NewXLSXStreamWriter(w, columns, 0); err != nil {
+			h.log.WithError(err).Error("Failed to start user export")
+			return
+		}
+	default:
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Unsupported export format", "format must be ndjson, csv or xlsx")
+		return
+	}
+
+	// Headers are already sent, so errors past this point can only end the stream early
+	if err := h.userService.Export(r.Context(), filter, func(user *models.UserResponse) error {
+		if format == "ndjson" {
+			return stream.Write(user)
+		}
+		return stream.Write(models.UserCSVRecord{User: user, Columns: columns})
+	}); err != nil {
+		h.log.WithError(err).WithField("written", stream.Written()).Error("User export aborted")
+		return
+	}
+
+	if err := stream.Close(); err != nil {
+		h.log.WithError(err).Error("Failed to flush user export")
+	}
+}
+
+// maxLoginIdentifierBody caps how much of a login body LoginIdentifier reads
+const maxLoginIdentifierBody = 64 << 10
+
+// LoginIdentifier returns the email or username a login request is for, leaving the body
+// for the handler to read. Failed logins are tracked per account with it.
+func LoginIdentifier(r *http.Request) string {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxLoginIdentifierBody))
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+	if err != nil {
+		return ""
+	}
+
+	var req models.UserLoginRequest
+	if json.Unmarshal(body, &req) != nil {
+		return ""
+	}
+	return req.Login()
+}
+
+// Login handles POST /auth/login
+func (h *UserHandler) Login(w http.ResponseWriter, r *http.Request) {
+	var req models.UserLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in login request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	// Validate request
+	if err := h.validator.Struct(&req); err != nil {
+		h.log.WithError(err).Warn("Validation failed for login request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	// Authenticate user
+	response, err := h.userService.Login(r.Context(), &req)
+	if err != nil {
+		h.log.WithError(err).WithField("login", req.Login()).Warn("Login failed")
+		h.writeLoginError(w, err)
+		return
+	}
+
+	if response.TwoFactorRequired {
+		utils.WriteSuccessResponse(w, http.StatusOK, "Two-factor authentication required", response)
+		return
+	}
+
+	// Return tokens and user info
+	h.writeLoginResponse(w, h.log, "Login successful", response)
+}
+
+// VerifyTwoFactor handles POST /auth/2fa/verify, the second step of a login with two-factor authentication
+func (h *UserHandler) VerifyTwoFactor(w http.ResponseWriter, r *http.Request) {
+	var req models.TwoFactorVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in two-factor verification")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	response, err := h.userService.VerifyTwoFactor(r.Context(), &req)
+	if err != nil {
+		h.log.WithError(err).Warn("Two-factor verification failed")
+		h.writeLoginError(w, err)
+		return
+	}
+
+	h.writeLoginResponse(w, h.log, "Login successful", response)
+}
+
+// VerifyLogin handles POST /auth/login/verify
+func (h *UserHandler) VerifyLogin(w http.ResponseWriter, r *http.Request) {
+	var req models.LoginVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in login verification")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	response, err := h.userService.VerifyLogin(r.Context(), &req)
+	if err != nil {
+		h.log.WithError(err).Warn("Login verification failed")
+		h.writeLoginError(w, err)
+		return
+	}
+
+	h.writeLoginResponse(w, h.log, "Login successful", response)
+}
+
+// RequestLoginOTP handles POST /auth/otp/request
+func (h *UserHandler) RequestLoginOTP(w http.ResponseWriter, r *http.Request) {
+	var req models.OTPRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in OTP request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.userService.RequestLoginOTP(r.Context(), &req); err != nil {
+		var limitErr *services.OTPRateLimitedError
+		if errors.As(err, &limitErr) {
+			retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+			w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+			utils.WriteErrorResponse(w, http.StatusTooManyRequests, err.Error(), map[string]interface{}{
+				"retry_after": retryAfter,
+			})
+			return
+		}
+		h.log.WithError(err).Error("Failed to request login code")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Login code request failed", nil)
+		return
+	}
+
+	// The same answer whether or not a code was sent, so phone numbers can't be enumerated
+	utils.WriteSuccessResponse(w, http.StatusAccepted, "If the number belongs to an account, a login code has been sent to it", nil)
+}
+
+// VerifyLoginOTP handles POST /auth/otp/verify
+func (h *UserHandler) VerifyLoginOTP(w http.ResponseWriter, r *http.Request) {
+	var req models.OTPVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in OTP verification")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	response, err := h.userService.VerifyLoginOTP(r.Context(), &req)
+	if err != nil {
+		h.log.WithError(err).Warn("OTP login failed")
+		h.writeLoginError(w, err)
+		return
+	}
+
+	if response.TwoFactorRequired {
+		utils.WriteSuccessResponse(w, http.StatusOK, "Two-factor authentication required", response)
+		return
+	}
+
+	h.writeLoginResponse(w, h.log, "Login successful", response)
+}
+
+// writeLoginError maps a failed login to the response telling the client whether and when to retry
+func (h *UserHandler) writeLoginError(w http.ResponseWriter, err error) {
+	var lockedErr *services.AccountLockedError
+	if errors.As(err, &lockedErr) {
+		retryAfter := int(math.Ceil(lockedErr.RetryAfter().Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		utils.WriteErrorResponse(w, http.StatusLocked, err.Error(), map[string]interface{}{
+			"retry_after":  retryAfter,
+			"locked_until": lockedErr.Until,
+		})
+		return
+	}
+
+	var deactivatedErr *services.AccountDeactivatedError
+	if errors.As(err, &deactivatedErr) {
+		utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), map[string]interface{}{
+			"code":                      "account_deactivated",
+			"self_service_reactivation": deactivatedErr.SelfService,
+		})
+		return
+	}
+
+	var expiredErr *services.PasswordExpiredError
+	if errors.As(err, &expiredErr) {
+		utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), map[string]interface{}{
+			"code":                "password_expired",
+			"password_expired_at": expiredErr.ExpiredAt,
+		})
+		return
+	}
+
+	var suspendedErr *services.AccountSuspendedError
+	if errors.As(err, &suspendedErr) {
+		utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), map[string]interface{}{
+			"code":            "account_suspended",
+			"suspended_until": suspendedErr.Until,
+		})
+		return
+	}
+
+	if errors.Is(err, services.ErrSessionLimitReached) {
+		utils.WriteErrorResponse(w, http.StatusForbidden, services.ErrSessionLimitReached.Error(), map[string]interface{}{
+			"code": "session_limit_reached",
+		})
+		return
+	}
+
+	if errors.Is(err, services.ErrUserChanged) {
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+
+	utils.WriteErrorResponse(w, http.StatusUnauthorized, err.Error(), nil)
+}
+
+// RequestReactivation handles POST /auth/reactivate
+func (h *UserHandler) RequestReactivation(w http.ResponseWriter, r *http.Request) {
+	var req models.ReactivationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in reactivation request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.userService.RequestReactivation(r.Context(), &req); err != nil {
+		h.log.WithError(err).Error("Failed to request account reactivation")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Reactivation request failed", nil)
+		return
+	}
+
+	// The same answer whether or not a link was sent, so accounts can't be enumerated
+	utils.WriteSuccessResponse(w, http.StatusAccepted, "If the account can be reactivated, a link has been sent to its email address", nil)
+}
+
+// ConfirmReactivation handles POST /auth/reactivate/confirm
+func (h *UserHandler) ConfirmReactivation(w http.ResponseWriter, r *http.Request) {
+	var req models.ReactivationConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in reactivation confirmation")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.userService.ConfirmReactivation(r.Context(), &req); err != nil {
+		if errors.Is(err, services.ErrInvalidEmailToken) {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if errors.Is(err, services.ErrUserChanged) {
+			utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+			return
+		}
+		h.log.WithError(err).Error("Failed to confirm account reactivation")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Reactivation failed", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Account reactivated, you can log in again", nil)
+}
+
+// ForgotPassword handles POST /auth/password/forgot
+func (h *UserHandler) ForgotPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordForgotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in password reset request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.userService.RequestPasswordReset(r.Context(), &req); err != nil {
+		h.log.WithError(err).Error("Failed to request password reset")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Password reset request failed", nil)
+		return
+	}
+
+	// The same answer whether or not a link was sent, so accounts can't be enumerated
+	utils.WriteSuccessResponse(w, http.StatusAccepted, "If an account uses this email, a password reset link has been sent to it", nil)
+}
+
+// ResetPassword handles POST /auth/password/reset
+func (h *UserHandler) ResetPassword(w http.ResponseWriter, r *http.Request) {
+	var req models.PasswordResetRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in password reset")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.userService.ResetPassword(r.Context(), &req); err != nil {
+		if errors.Is(err, services.ErrInvalidEmailToken) {
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+			return
+		}
+		if errors.Is(err, services.ErrUserChanged) {
+			utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+			return
+		}
+		if h.writePasswordCheckError(w, err) {
+			return
+		}
+		h.log.WithError(err).Error("Failed to reset password")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Password reset failed", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Password reset, you can log in with the new password", nil)
+}
+
+// ConfirmEmailChange handles POST /auth/email/confirm with the link emailed to a new address
+func (h *UserHandler) ConfirmEmailChange(w http.ResponseWriter, r *http.Request) {
+	var req models.EmailChangeConfirmRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in email change confirmation")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	user, err := h.userService.ConfirmEmailChange(r.Context(), &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidEmailToken):
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, services.ErrEmailTaken), errors.Is(err, services.ErrUserChanged):
+			utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		default:
+			h.log.WithError(err).Error("Failed to confirm email change")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Email change failed", nil)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Email changed successfully", user)
+}
+
+// ChangePassword handles PUT /auth/password. The other sessions of the user are signed out, and
+// the caller gets new tokens in place of the ones used for the request.
+func (h *UserHandler) ChangePassword(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req models.PasswordChangeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in change password request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	tokenID, expiresAt, _ := middleware.GetTokenFromContext(r.Context())
+	response, err := h.userService.ChangePassword(r.Context(), userID, tokenID, expiresAt, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), nil)
+		case errors.Is(err, services.ErrPasswordUnchanged):
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, services.ErrUserChanged):
+			utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		default:
+			if h.writePasswordCheckError(w, err) {
+				return
+			}
+			h.log.WithError(err).WithField("user_id", userID).Error("Failed to change password")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Password change failed", nil)
+		}
+		return
+	}
+
+	h.writeLoginResponse(w, h.log, "Password changed successfully", response)
+}
+
+// writePasswordCheckError writes the response for a new password refused by the breach check,
+// and reports whether err was one
+func (h *UserHandler) writePasswordCheckError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, services.ErrPasswordBreached):
+		utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), map[string]interface{}{
+			"code": "password_breached",
+		})
+	case errors.Is(err, services.ErrPasswordCheckUnavailable):
+		utils.WriteErrorResponse(w, http.StatusServiceUnavailable, err.Error(), nil)
+	default:
+		return false
+	}
+	return true
+}
+
+// Logout handles POST /auth/logout
+func (h *UserHandler) Logout(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	tokenID, expiresAt, _ := middleware.GetTokenFromContext(r.Context())
+	if err := h.userService.Logout(r.Context(), userID, tokenID, expiresAt); err != nil {
+		h.log.WithError(err).WithField("user_id", userID).Error("Failed to logout user")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Logout failed", nil)
+		return
+	}
+
+	h.cookies.Clear(w)
+	utils.WriteSuccessResponse(w, http.StatusOK, "Logout successful", nil)
+}
+
+// DeleteAccount handles DELETE /auth/account and DELETE /auth/profile
+func (h *UserHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req models.AccountDeleteRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in delete account request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	deletion, err := h.userService.RequestDeletion(r.Context(), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidPassword):
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), nil)
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, services.ErrUserChanged):
+			utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+		default:
+			h.log.WithError(err).WithField("user_id", userID).Error("Failed to schedule account deletion")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Account deletion failed", nil)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusAccepted, "Account scheduled for deletion", deletion)
+}
+
+// EnrollTwoFactor handles POST /auth/2fa/enroll
+func (h *UserHandler) EnrollTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	enrollment, err := h.userService.EnrollTwoFactor(r.Context(), userID)
+	if err != nil {
+		h.writeTwoFactorError(w, userID, err, "Failed to enroll two-factor authentication")
+		return
+	}
+
+	// The secret is shown once, so it must not be cached on the way
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteSuccessResponse(w, http.StatusOK, "Scan the secret with an authenticator app, then confirm a code to enable two-factor authentication", enrollment)
+}
+
+// EnableTwoFactor handles POST /auth/2fa/enable
+func (h *UserHandler) EnableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in enable two-factor request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	recovery, err := h.userService.EnableTwoFactor(r.Context(), userID, &req)
+	if err != nil {
+		h.writeTwoFactorError(w, userID, err, "Failed to enable two-factor authentication")
+		return
+	}
+
+	// Recovery codes are shown once, so they must not be cached on the way
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteSuccessResponse(w, http.StatusOK, "Two-factor authentication enabled, store the recovery codes somewhere safe", recovery)
+}
+
+// DisableTwoFactor handles POST /auth/2fa/disable
+func (h *UserHandler) DisableTwoFactor(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req models.TwoFactorDisableRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in disable two-factor request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	if err := h.userService.DisableTwoFactor(r.Context(), userID, &req); err != nil {
+		h.writeTwoFactorError(w, userID, err, "Failed to disable two-factor authentication")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Two-factor authentication disabled", nil)
+}
+
+// RecoveryCodes handles GET /auth/2fa/recovery-codes
+func (h *UserHandler) RecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	status, err := h.userService.RecoveryCodesStatus(r.Context(), userID)
+	if err != nil {
+		h.writeTwoFactorError(w, userID, err, "Failed to get recovery codes")
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Recovery codes retrieved successfully", status)
+}
+
+// RegenerateRecoveryCodes handles POST /auth/2fa/recovery-codes
+func (h *UserHandler) RegenerateRecoveryCodes(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in regenerate recovery codes request")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	recovery, err := h.userService.RegenerateRecoveryCodes(r.Context(), userID, &req)
+	if err != nil {
+		h.writeTwoFactorError(w, userID, err, "Failed to regenerate recovery codes")
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	utils.WriteSuccessResponse(w, http.StatusOK, "Recovery codes regenerated, the previous ones no longer work", recovery)
+}
+
+// ListDevices handles GET /auth/devices
+func (h *UserHandler) ListDevices(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	devices, err := h.userService.ListDevices(r.Context(), userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve devices", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Devices retrieved successfully", devices)
+}
+
+// DeleteDevice handles DELETE /auth/devices/{id}
+func (h *UserHandler) DeleteDevice(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid device ID", nil)
+		return
+	}
+
+	if err := h.userService.DeleteDevice(r.Context(), userID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrDeviceNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete device", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Device removed", nil)
+}
+
+// LoginHistory handles GET /auth/login-history
+func (h *UserHandler) LoginHistory(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	h.writeLoginHistory(w, r, userID)
+}
+
+// AdminLoginHistory handles GET /admin/users/{id}/login-history
+func (h *UserHandler) AdminLoginHistory(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	h.writeLoginHistory(w, r, uint(id))
+}
+
+func (h *UserHandler) writeLoginHistory(w http.ResponseWriter, r *http.Request, userID uint) {
+	page, limit := pageParams(r)
+
+	events, total, err := h.userService.LoginHistory(r.Context(), userID, page, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		h.log.WithError(err).WithField("user_id", userID).Error("Failed to get login history")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve login history", nil)
+		return
+	}
+
+	utils.WritePaginatedResponse(w, r, http.StatusOK, "Login history retrieved successfully", events, total, page, limit)
+}
+
+// ActivityAudit handles GET /auth/profile/audit, listing actions on the caller's account newest first
+func (h *UserHandler) ActivityAudit(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	h.writeActivityAudit(w, r, userID)
+}
+
+// AdminActivityAudit handles GET /admin/users/{id}/audit, also for deleted users
+func (h *UserHandler) AdminActivityAudit(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	h.writeActivityAudit(w, r, uint(id))
+}
+
+func (h *UserHandler) writeActivityAudit(w http.ResponseWriter, r *http.Request, userID uint) {
+	page, limit := pageParams(r)
+
+	events, total, err := h.userService.ActivityAudit(r.Context(), userID, page, limit)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		h.log.WithError(err).WithField("user_id", userID).Error("Failed to get audit trail")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve audit trail", nil)
+		return
+	}
+
+	utils.WritePaginatedResponse(w, r, http.StatusOK, "Audit trail retrieved successfully", events, total, page, limit)
+}
+
+func (h *UserHandler) writeTwoFactorError(w http.ResponseWriter, userID uint, err error, message string) {
+	switch {
+	case errors.Is(err, services.ErrInvalidPassword), errors.Is(err, services.ErrInvalidTwoFactorCode):
+		utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), nil)
+	case errors.Is(err, services.ErrTwoFactorEnabled), errors.Is(err, services.ErrTwoFactorNotEnabled), errors.Is(err, services.ErrUserChanged):
+		utils.WriteErrorResponse(w, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, services.ErrUserNotFound):
+		utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+	default:
+		h.log.WithError(err).WithField("user_id", userID).Error(message)
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, message, nil)
+	}
 }
 
 // Profile handles GET /auth/profile
@@ -8,14 +8,20 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
+	"gbt-be-template/internal/config"
 	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/internal/services"
 	"gbt-be-template/pkg/logger"
 	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
 
 	"github.com/go-chi/chi/v5"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
 // MockUserService is a mock implementation of UserService
@@ -47,8 +53,8 @@ func (m *MockUserService) GetByEmail(ctx context.Context, email string) (*models
 	return args.Get(0).(*models.UserResponse), args.Error(1)
 }
 
-func (m *MockUserService) Update(ctx context.Context, id uint, req *models.UserUpdateRequest) (*models.UserResponse, error) {
-	args := m.Called(ctx, id, req)
+func (m *MockUserService) Update(ctx context.Context, actorID uint, isAdmin bool, id uint, req *models.UserUpdateRequest) (*models.UserResponse, error) {
+	args := m.Called(ctx, actorID, isAdmin, id, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -60,35 +66,318 @@ func (m *MockUserService) Delete(ctx context.Context, id uint) error {
 	return args.Error(0)
 }
 
-func (m *MockUserService) List(ctx context.Context, page, limit int) ([]*models.UserResponse, int64, error) {
-	args := m.Called(ctx, page, limit)
+func (m *MockUserService) List(ctx context.Context, filter models.UserFilter, sort []models.SortField, page, limit int) ([]*models.UserResponse, int64, error) {
+	args := m.Called(ctx, filter, sort, page, limit)
 	if args.Get(0) == nil {
 		return nil, args.Get(1).(int64), args.Error(2)
 	}
 	return args.Get(0).([]*models.UserResponse), args.Get(1).(int64), args.Error(2)
 }
 
-func (m *MockUserService) Login(ctx context.Context, req *models.UserLoginRequest) (string, *models.UserResponse, error) {
+func (m *MockUserService) ListByCursor(ctx context.Context, filter models.UserFilter, sort []models.SortField, cursor string, limit int) ([]*models.UserResponse, string, string, error) {
+	args := m.Called(ctx, filter, sort, cursor, limit)
+	if args.Get(0) == nil {
+		return nil, args.String(1), args.String(2), args.Error(3)
+	}
+	return args.Get(0).([]*models.UserResponse), args.String(1), args.String(2), args.Error(3)
+}
+
+func (m *MockUserService) Export(ctx context.Context, filter models.UserFilter, fn func(*models.UserResponse) error) error {
+	args := m.Called(ctx, filter, fn)
+	return args.Error(0)
+}
+
+func (m *MockUserService) Login(ctx context.Context, req *models.UserLoginRequest) (*models.LoginResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginResponse), args.Error(1)
+}
+
+func (m *MockUserService) CompleteLogin(ctx context.Context, user *models.User, method, deviceToken string) (*models.LoginResponse, error) {
+	args := m.Called(ctx, user, method, deviceToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginResponse), args.Error(1)
+}
+
+func (m *MockUserService) Logout(ctx context.Context, userID uint, tokenID string, expiresAt time.Time) error {
+	args := m.Called(ctx, userID, tokenID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockUserService) VerifyTwoFactor(ctx context.Context, req *models.TwoFactorVerifyRequest) (*models.LoginResponse, error) {
 	args := m.Called(ctx, req)
-	if args.Get(1) == nil {
-		return args.String(0), nil, args.Error(2)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
 	}
-	return args.String(0), args.Get(1).(*models.UserResponse), args.Error(2)
+	return args.Get(0).(*models.LoginResponse), args.Error(1)
 }
 
-func (m *MockUserService) Logout(ctx context.Context, userID uint) error {
+func (m *MockUserService) EnrollTwoFactor(ctx context.Context, userID uint) (*models.TwoFactorEnrollResponse, error) {
 	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TwoFactorEnrollResponse), args.Error(1)
+}
+
+func (m *MockUserService) EnableTwoFactor(ctx context.Context, userID uint, req *models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecoveryCodesResponse), args.Error(1)
+}
+
+func (m *MockUserService) RecoveryCodesStatus(ctx context.Context, userID uint) (*models.RecoveryCodesStatus, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecoveryCodesStatus), args.Error(1)
+}
+
+func (m *MockUserService) RegenerateRecoveryCodes(ctx context.Context, userID uint, req *models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecoveryCodesResponse), args.Error(1)
+}
+
+func (m *MockUserService) DisableTwoFactor(ctx context.Context, userID uint, req *models.TwoFactorDisableRequest) error {
+	args := m.Called(ctx, userID, req)
 	return args.Error(0)
 }
 
-func (m *MockUserService) AdminUpdate(ctx context.Context, id uint, req *models.AdminUserUpdateRequest) (*models.UserResponse, error) {
-	args := m.Called(ctx, id, req)
+func (m *MockUserService) VerifyLogin(ctx context.Context, req *models.LoginVerifyRequest) (*models.LoginResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginResponse), args.Error(1)
+}
+
+func (m *MockUserService) ListDevices(ctx context.Context, userID uint) ([]*models.Device, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Device), args.Error(1)
+}
+
+func (m *MockUserService) DeleteDevice(ctx context.Context, userID, id uint) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) LoginHistory(ctx context.Context, userID uint, page, limit int) ([]*models.LoginEvent, int64, error) {
+	args := m.Called(ctx, userID, page, limit)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*models.LoginEvent), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserService) ActivityAudit(ctx context.Context, userID uint, page, limit int) ([]*models.AuditEvent, int64, error) {
+	args := m.Called(ctx, userID, page, limit)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*models.AuditEvent), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserService) PurgeLoginHistory(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserService) RequestPasswordReset(ctx context.Context, req *models.PasswordForgotRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ResetPassword(ctx context.Context, req *models.PasswordResetRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ConfirmEmailChange(ctx context.Context, req *models.EmailChangeConfirmRequest) (*models.UserResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.UserResponse), args.Error(1)
+}
+
+func (m *MockUserService) RequestLoginOTP(ctx context.Context, req *models.OTPRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) VerifyLoginOTP(ctx context.Context, req *models.OTPVerifyRequest) (*models.LoginResponse, error) {
+	args := m.Called(ctx, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginResponse), args.Error(1)
+}
+
+func (m *MockUserService) UseAuthorizer(authorizer services.Authorizer) {}
+
+func (m *MockUserService) UseActivityAudit(activityRepo repository.AuditEventRepository) {}
+
+func (m *MockUserService) UseTransactions(tx repository.Transactor) {}
+
+func (m *MockUserService) AdminUpdate(ctx context.Context, actorID, id uint, req *models.AdminUserUpdateRequest) (*models.UserResponse, error) {
+	args := m.Called(ctx, actorID, id, req)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
 	return args.Get(0).(*models.UserResponse), args.Error(1)
 }
 
+func (m *MockUserService) AdminGet(ctx context.Context, id uint) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) Suspend(ctx context.Context, actorID, id uint, req *models.UserSuspendRequest) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, actorID, id, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) Unsuspend(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, actorID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) Deactivate(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, actorID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) MergeAccounts(ctx context.Context, actorID, id, duplicateID uint) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, actorID, id, duplicateID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) LogoutAll(ctx context.Context, actorID, id uint) error {
+	args := m.Called(ctx, actorID, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) Restore(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, actorID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) HardDelete(ctx context.Context, actorID, id uint) error {
+	args := m.Called(ctx, actorID, id)
+	return args.Error(0)
+}
+
+func (m *MockUserService) Reactivate(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, actorID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) Impersonate(ctx context.Context, actorID, id uint) (*models.ImpersonationResponse, error) {
+	args := m.Called(ctx, actorID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.ImpersonationResponse), args.Error(1)
+}
+
+func (m *MockUserService) RequirePasswordChange(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	args := m.Called(ctx, actorID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AdminUserResponse), args.Error(1)
+}
+
+func (m *MockUserService) MustChangePassword(ctx context.Context, userID uint) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserService) ChangePassword(ctx context.Context, userID uint, tokenID string, expiresAt time.Time, req *models.PasswordChangeRequest) (*models.LoginResponse, error) {
+	args := m.Called(ctx, userID, tokenID, expiresAt, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginResponse), args.Error(1)
+}
+
+func (m *MockUserService) RequestReactivation(ctx context.Context, req *models.ReactivationRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) ConfirmReactivation(ctx context.Context, req *models.ReactivationConfirmRequest) error {
+	args := m.Called(ctx, req)
+	return args.Error(0)
+}
+
+func (m *MockUserService) LiftExpiredSuspensions(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserService) RequestDeletion(ctx context.Context, userID uint, req *models.AccountDeleteRequest) (*models.AccountDeletionResponse, error) {
+	args := m.Called(ctx, userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AccountDeletionResponse), args.Error(1)
+}
+
+func (m *MockUserService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserService) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserService) AnonymizeDeletedUsers(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserService) ReencryptUsers(ctx context.Context) (int, error) {
+	args := m.Called(ctx)
+	return args.Int(0), args.Error(1)
+}
+
 func setupUserHandler() (*UserHandler, *MockUserService) {
 	mockService := &MockUserService{}
 	log := logger.New("info", "text")
@@ -116,7 +405,7 @@ func TestUserHandler_Create(t *testing.T) {
 			LastName:  req.LastName,
 		}
 
-		mockService.On("Create", mock.Anything, req).Return(expectedResponse, nil)
+		mockService.On("Create", mock.Anything, req).Return(expectedResponse, nil).Once()
 
 		body, _ := json.Marshal(req)
 		request := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(body))
@@ -163,7 +452,7 @@ func TestUserHandler_Create(t *testing.T) {
 			LastName:  "User",
 		}
 
-		mockService.On("Create", mock.Anything, req).Return(nil, errors.New("email already exists"))
+		mockService.On("Create", mock.Anything, req).Return(nil, errors.New("email already exists")).Once()
 
 		body, _ := json.Marshal(req)
 		request := httptest.NewRequest(http.MethodPost, "/users", bytes.NewBuffer(body))
@@ -187,7 +476,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 			Username: "testuser",
 		}
 
-		mockService.On("GetByID", mock.Anything, uint(1)).Return(expectedResponse, nil)
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(expectedResponse, nil).Once()
 
 		request := httptest.NewRequest(http.MethodGet, "/users/1", nil)
 		recorder := httptest.NewRecorder()
@@ -218,7 +507,7 @@ func TestUserHandler_GetByID(t *testing.T) {
 	})
 
 	t.Run("user not found", func(t *testing.T) {
-		mockService.On("GetByID", mock.Anything, uint(999)).Return(nil, errors.New("user not found"))
+		mockService.On("GetByID", mock.Anything, uint(999)).Return(nil, errors.New("user not found")).Once()
 
 		request := httptest.NewRequest(http.MethodGet, "/users/999", nil)
 		recorder := httptest.NewRecorder()
@@ -235,6 +524,111 @@ func TestUserHandler_GetByID(t *testing.T) {
 	})
 }
 
+func TestUserHandler_Export(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	users := []*models.UserResponse{
+		{ID: 1, Email: "one@example.com", Username: "one"},
+		{ID: 2, Email: "two@example.com", Username: "two"},
+	}
+	streamUsers := func(args mock.Arguments) {
+		fn := args.Get(2).(func(*models.UserResponse) error)
+		for _, user := range users {
+			fn(user)
+		}
+	}
+
+	t.Run("ndjson export", func(t *testing.T) {
+		mockService.On("Export", mock.Anything, models.UserFilter{}, mock.Anything).Return(nil).Run(streamUsers).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/export", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Export(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+		lines := bytes.Split(bytes.TrimSpace(recorder.Body.Bytes()), []byte("\n"))
+		assert.Len(t, lines, 2)
+
+		var first models.UserResponse
+		assert.NoError(t, json.Unmarshal(lines[0], &first))
+		assert.Equal(t, "one@example.com", first.Email)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("csv export", func(t *testing.T) {
+		mockService.On("Export", mock.Anything, models.UserFilter{}, mock.Anything).Return(nil).Run(streamUsers).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/export?format=csv", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Export(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+		lines := bytes.Split(bytes.TrimSpace(recorder.Body.Bytes()), []byte("\n"))
+		assert.Len(t, lines, 3)
+		assert.True(t, bytes.HasPrefix(lines[1], []byte("1,one@example.com,one,")))
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("csv export with filters and columns", func(t *testing.T) {
+		active := true
+		mockService.On("Export", mock.Anything, models.UserFilter{Search: "one", IsActive: &active}, mock.Anything).Return(nil).Run(streamUsers).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/export?format=csv&search=one&is_active=true&columns=username,email", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Export(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "username,email\none,one@example.com\ntwo,two@example.com\n", recorder.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("xlsx export", func(t *testing.T) {
+		mockService.On("Export", mock.Anything, models.UserFilter{}, mock.Anything).Return(nil).Run(streamUsers).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/export?format=xlsx&columns=email", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Export(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", recorder.Header().Get("Content-Type"))
+		assert.Contains(t, recorder.Header().Get("Content-Disposition"), "users.xlsx")
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unknown column", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/export?format=csv&columns=email,password", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Export(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("invalid filter", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/export?is_admin=maybe", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Export(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("unsupported format", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodGet, "/admin/users/export?format=xml", nil)
+		recorder := httptest.NewRecorder()
+
+		handler.Export(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
 func TestUserHandler_Login(t *testing.T) {
 	handler, mockService := setupUserHandler()
 
@@ -249,7 +643,12 @@ func TestUserHandler_Login(t *testing.T) {
 			Email: req.Email,
 		}
 
-		mockService.On("Login", mock.Anything, req).Return("token123", expectedUser, nil)
+		mockService.On("Login", mock.Anything, req).Return(&models.LoginResponse{
+			AccessToken:  "token123",
+			RefreshToken: "refresh123",
+			TokenType:    "Bearer",
+			User:         expectedUser,
+		}, nil).Once()
 
 		body, _ := json.Marshal(req)
 		request := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
@@ -266,6 +665,7 @@ func TestUserHandler_Login(t *testing.T) {
 		
 		data := response["data"].(map[string]interface{})
 		assert.Equal(t, "token123", data["access_token"])
+		assert.Equal(t, "refresh123", data["refresh_token"])
 		
 		mockService.AssertExpectations(t)
 	})
@@ -276,7 +676,7 @@ func TestUserHandler_Login(t *testing.T) {
 			Password: "wrongpassword",
 		}
 
-		mockService.On("Login", mock.Anything, req).Return("", nil, errors.New("invalid credentials"))
+		mockService.On("Login", mock.Anything, req).Return(nil, errors.New("invalid credentials")).Once()
 
 		body, _ := json.Marshal(req)
 		request := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
@@ -288,19 +688,100 @@ func TestUserHandler_Login(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
 		mockService.AssertExpectations(t)
 	})
+
+	t.Run("locked account", func(t *testing.T) {
+		req := &models.UserLoginRequest{
+			Email:    "locked@example.com",
+			Password: "password123",
+		}
+
+		lockedErr := &services.AccountLockedError{Until: time.Now().Add(90 * time.Second)}
+		mockService.On("Login", mock.Anything, req).Return(nil, lockedErr).Once()
+
+		body, _ := json.Marshal(req)
+		request := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+
+		handler.Login(recorder, request)
+
+		assert.Equal(t, http.StatusLocked, recorder.Code)
+		assert.Equal(t, "90", recorder.Header().Get("Retry-After"))
+
+		var response map[string]interface{}
+		json.Unmarshal(recorder.Body.Bytes(), &response)
+		details := response["error"].(map[string]interface{})
+		assert.Equal(t, float64(90), details["retry_after"])
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("missing email and identifier", func(t *testing.T) {
+		body, _ := json.Marshal(map[string]string{"password": "password123"})
+		request := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+
+		handler.Login(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestUserHandler_Login_Cookies(t *testing.T) {
+	handler, mockService := setupUserHandler()
+	handler.UseAuthCookies(middleware.NewAuthCookies(&config.Config{
+		JWT:         config.JWTConfig{RefreshExpiry: time.Hour, RememberMeExpiry: time.Hour},
+		AuthCookies: config.AuthCookieConfig{Enabled: true, Secure: true, SameSite: "lax"},
+	}))
+
+	req := &models.UserLoginRequest{Email: "test@example.com", Password: "password123"}
+	mockService.On("Login", mock.Anything, req).Return(&models.LoginResponse{
+		AccessToken:  "token123",
+		RefreshToken: "refresh123",
+		TokenType:    "Bearer",
+		ExpiresIn:    900,
+	}, nil).Once()
+
+	body, _ := json.Marshal(req)
+	request := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
+	recorder := httptest.NewRecorder()
+	handler.Login(recorder, request)
+	require.Equal(t, http.StatusOK, recorder.Code)
+
+	// Tokens go into HttpOnly cookies instead of the body
+	cookies := map[string]*http.Cookie{}
+	for _, cookie := range recorder.Result().Cookies() {
+		cookies[cookie.Name] = cookie
+	}
+	require.Contains(t, cookies, middleware.AccessTokenCookie)
+	assert.Equal(t, "token123", cookies[middleware.AccessTokenCookie].Value)
+	assert.True(t, cookies[middleware.AccessTokenCookie].HttpOnly)
+	assert.Equal(t, "refresh123", cookies[middleware.RefreshTokenCookie].Value)
+	assert.Contains(t, cookies, middleware.CSRFCookie)
+
+	var response struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.NotContains(t, response.Data, "access_token")
+	assert.NotContains(t, response.Data, "refresh_token")
+	assert.Equal(t, float64(900), response.Data["expires_in"])
 }
 
 func TestUserHandler_Logout(t *testing.T) {
 	handler, mockService := setupUserHandler()
 
 	t.Run("successful logout", func(t *testing.T) {
-		mockService.On("Logout", mock.Anything, uint(1)).Return(nil)
+		expiresAt := time.Now().Add(time.Hour)
+		mockService.On("Logout", mock.Anything, uint(1), "token-id", expiresAt).Return(nil).Once()
 
 		request := httptest.NewRequest(http.MethodPost, "/auth/logout", nil)
 		recorder := httptest.NewRecorder()
 
-		// Add user ID to context (simulating authenticated user)
+		// Add user ID and token to context (simulating authenticated user)
 		ctx := context.WithValue(request.Context(), middleware.UserIDKey, uint(1))
+		ctx = context.WithValue(ctx, middleware.TokenIDKey, "token-id")
+		ctx = context.WithValue(ctx, middleware.TokenExpiresAtKey, expiresAt)
 		request = request.WithContext(ctx)
 
 		handler.Logout(recorder, request)
@@ -318,3 +799,410 @@ func TestUserHandler_Logout(t *testing.T) {
 		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
 	})
 }
+
+func TestUserHandler_DeleteAccount(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	serve := func(body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodDelete, "/auth/account", bytes.NewBufferString(body))
+		request = request.WithContext(context.WithValue(request.Context(), middleware.UserIDKey, uint(1)))
+		recorder := httptest.NewRecorder()
+		handler.DeleteAccount(recorder, request)
+		return recorder
+	}
+
+	t.Run("schedules deletion", func(t *testing.T) {
+		scheduledAt := time.Now().Add(30 * 24 * time.Hour)
+		mockService.On("RequestDeletion", mock.Anything, uint(1), &models.AccountDeleteRequest{Password: "password123"}).
+			Return(&models.AccountDeletionResponse{ScheduledAt: scheduledAt}, nil).Once()
+
+		recorder := serve(`{"password":"password123"}`)
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("wrong password", func(t *testing.T) {
+		mockService.On("RequestDeletion", mock.Anything, uint(1), &models.AccountDeleteRequest{Password: "wrong"}).
+			Return(nil, services.ErrInvalidPassword).Once()
+
+		recorder := serve(`{"password":"wrong"}`)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+
+	t.Run("password required", func(t *testing.T) {
+		recorder := serve(`{}`)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestUserHandler_Reactivation(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	t.Run("request answers the same for any email", func(t *testing.T) {
+		mockService.On("RequestReactivation", mock.Anything, &models.ReactivationRequest{Email: "jane@example.com"}).Return(nil).Once()
+
+		request := httptest.NewRequest(http.MethodPost, "/auth/reactivate", bytes.NewBufferString(`{"email":"jane@example.com"}`))
+		recorder := httptest.NewRecorder()
+		handler.RequestReactivation(recorder, request)
+
+		assert.Equal(t, http.StatusAccepted, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("confirm with an invalid token", func(t *testing.T) {
+		mockService.On("ConfirmReactivation", mock.Anything, &models.ReactivationConfirmRequest{Token: "stale"}).
+			Return(services.ErrInvalidEmailToken).Once()
+
+		request := httptest.NewRequest(http.MethodPost, "/auth/reactivate/confirm", bytes.NewBufferString(`{"token":"stale"}`))
+		recorder := httptest.NewRecorder()
+		handler.ConfirmReactivation(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("deactivated login tells whether self-service reactivation is possible", func(t *testing.T) {
+		loginReq := &models.UserLoginRequest{Email: "jane@example.com", Password: "password123"}
+		mockService.On("Login", mock.Anything, loginReq).Return(nil, &services.AccountDeactivatedError{SelfService: true}).Once()
+
+		body, _ := json.Marshal(loginReq)
+		request := httptest.NewRequest(http.MethodPost, "/auth/login", bytes.NewBuffer(body))
+		recorder := httptest.NewRecorder()
+		handler.Login(recorder, request)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		var response struct {
+			Error map[string]interface{} `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "account_deactivated", response.Error["code"])
+		assert.Equal(t, true, response.Error["self_service_reactivation"])
+	})
+}
+
+func TestUserHandler_LoginOTP(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	t.Run("request is throttled per phone number", func(t *testing.T) {
+		mockService.On("RequestLoginOTP", mock.Anything, &models.OTPRequest{PhoneNumber: "+15551234567"}).
+			Return(&services.OTPRateLimitedError{RetryAfter: 90 * time.Second}).Once()
+
+		request := httptest.NewRequest(http.MethodPost, "/auth/otp/request", bytes.NewBufferString(`{"phone_number":"+15551234567"}`))
+		recorder := httptest.NewRecorder()
+		handler.RequestLoginOTP(recorder, request)
+
+		assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+		assert.Equal(t, "90", recorder.Header().Get("Retry-After"))
+	})
+
+	t.Run("request rejects numbers that aren't E.164", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPost, "/auth/otp/request", bytes.NewBufferString(`{"phone_number":"555-1234"}`))
+		recorder := httptest.NewRecorder()
+		handler.RequestLoginOTP(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("verify with a wrong code", func(t *testing.T) {
+		mockService.On("VerifyLoginOTP", mock.Anything, &models.OTPVerifyRequest{PhoneNumber: "+15551234567", Code: "123456"}).
+			Return(nil, services.ErrInvalidOTP).Once()
+
+		request := httptest.NewRequest(http.MethodPost, "/auth/otp/verify", bytes.NewBufferString(`{"phone_number":"+15551234567","code":"123456"}`))
+		recorder := httptest.NewRecorder()
+		handler.VerifyLoginOTP(recorder, request)
+
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+func TestUserHandler_Patch(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	current := &models.UserResponse{
+		ID:        1,
+		Email:     "test@example.com",
+		Username:  "testuser",
+		FirstName: "Test",
+		LastName:  "User",
+		IsActive:  true,
+		Phone:     "+15550123",
+		Metadata:  models.Metadata{"team": "blue", "role": "dev"},
+		Version:   4,
+	}
+	version := current.Version
+
+	serve := func(contentType, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPatch, "/users/1", bytes.NewBufferString(body))
+		request.Header.Set("Content-Type", contentType)
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "1")
+		ctx := context.WithValue(request.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, uint(1))
+		recorder := httptest.NewRecorder()
+		handler.Patch(recorder, request.WithContext(ctx))
+		return recorder
+	}
+
+	t.Run("merge patch clears with null and leaves absent fields alone", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+		firstName := "Tess"
+		phone := ""
+		expected := &models.UserUpdateRequest{
+			FirstName: &firstName,
+			Phone:     &phone,
+			Metadata:  models.Metadata{"team": "blue"},
+			Version:   &version,
+		}
+		mockService.On("Update", mock.Anything, uint(1), false, uint(1), expected).Return(current, nil).Once()
+
+		recorder := serve("application/merge-patch+json", `{"first_name":"Tess","phone":null,"metadata":{"role":null}}`)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("JSON patch", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+		inactive := false
+		expected := &models.UserUpdateRequest{IsActive: &inactive, Metadata: models.Metadata{}, Version: &version}
+		mockService.On("Update", mock.Anything, uint(1), false, uint(1), expected).Return(current, nil).Once()
+
+		recorder := serve("application/json-patch+json",
+			`[{"op":"test","path":"/username","value":"testuser"},{"op":"replace","path":"/is_active","value":false},{"op":"remove","path":"/metadata"}]`)
+
+		assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("failed test operation", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+
+		recorder := serve("application/json-patch+json", `[{"op":"test","path":"/username","value":"other"}]`)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+	})
+
+	t.Run("patch of an older version", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+		firstName, older := "Tess", uint(3)
+		expected := &models.UserUpdateRequest{FirstName: &firstName, Version: &older}
+		mockService.On("Update", mock.Anything, uint(1), false, uint(1), expected).Return(nil, services.ErrUserChanged).Once()
+
+		recorder := serve("application/merge-patch+json", `{"first_name":"Tess","version":3}`)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("deactivation refused", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+		inactive := false
+		expected := &models.UserUpdateRequest{IsActive: &inactive, Version: &version}
+		mockService.On("Update", mock.Anything, uint(1), false, uint(1), expected).Return(nil, services.ErrActiveStatusForbidden).Once()
+
+		recorder := serve("application/merge-patch+json", `{"is_active":false}`)
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("required field removed", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+
+		recorder := serve("application/merge-patch+json", `{"first_name":null}`)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("unknown field", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+
+		recorder := serve("application/merge-patch+json", `{"is_admin":true}`)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("unsupported content type", func(t *testing.T) {
+		mockService.On("GetByID", mock.Anything, uint(1)).Return(current, nil).Once()
+
+		recorder := serve("application/json", `{"first_name":"Tess"}`)
+
+		assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+		assert.Contains(t, recorder.Header().Get("Accept-Patch"), "application/merge-patch+json")
+	})
+
+	t.Run("other users are forbidden", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPatch, "/users/2", bytes.NewBufferString(`{}`))
+		request.Header.Set("Content-Type", "application/merge-patch+json")
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", "2")
+		ctx := context.WithValue(request.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, uint(1))
+		recorder := httptest.NewRecorder()
+		handler.Patch(recorder, request.WithContext(ctx))
+
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
+
+func TestUserHandler_AdminUpdate(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	serve := func(id, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPut, "/admin/users/"+id, bytes.NewBufferString(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(request.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, uint(1))
+		recorder := httptest.NewRecorder()
+		handler.AdminUpdate(recorder, request.WithContext(ctx))
+		return recorder
+	}
+
+	t.Run("replaces roles and status", func(t *testing.T) {
+		inactive := false
+		req := &models.AdminUserUpdateRequest{IsActive: &inactive, RoleIDs: []uint{3, 4}}
+		mockService.On("AdminUpdate", mock.Anything, uint(1), uint(2), req).Return(&models.UserResponse{ID: 2}, nil).Once()
+
+		recorder := serve("2", `{"is_active":false,"role_ids":[3,4]}`)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unknown role", func(t *testing.T) {
+		req := &models.AdminUserUpdateRequest{RoleIDs: []uint{99}}
+		mockService.On("AdminUpdate", mock.Anything, uint(1), uint(2), req).Return(nil, services.ErrRoleNotFound).Once()
+
+		recorder := serve("2", `{"role_ids":[99]}`)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+
+	t.Run("invalid role ID", func(t *testing.T) {
+		recorder := serve("2", `{"role_ids":[0]}`)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+}
+
+func TestUserHandler_Suspend(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	serve := func(id, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/admin/users/"+id+"/suspension", bytes.NewBufferString(body))
+		rctx := chi.NewRouteContext()
+		rctx.URLParams.Add("id", id)
+		ctx := context.WithValue(request.Context(), chi.RouteCtxKey, rctx)
+		ctx = context.WithValue(ctx, middleware.UserIDKey, uint(1))
+		recorder := httptest.NewRecorder()
+		handler.Suspend(recorder, request.WithContext(ctx))
+		return recorder
+	}
+
+	t.Run("user changed by another request", func(t *testing.T) {
+		req := &models.UserSuspendRequest{Reason: "spamming other users"}
+		mockService.On("Suspend", mock.Anything, uint(1), uint(2), req).Return(nil, services.ErrUserChanged).Once()
+
+		recorder := serve("2", `{"reason":"spamming other users"}`)
+
+		assert.Equal(t, http.StatusConflict, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		req := &models.UserSuspendRequest{Reason: "spamming other users"}
+		mockService.On("Suspend", mock.Anything, uint(1), uint(3), req).Return(nil, services.ErrUserNotFound).Once()
+
+		recorder := serve("3", `{"reason":"spamming other users"}`)
+
+		assert.Equal(t, http.StatusNotFound, recorder.Code)
+	})
+}
+
+func TestUserHandler_List(t *testing.T) {
+	handler, mockService := setupUserHandler()
+
+	t.Run("passes filters and sort to the service", func(t *testing.T) {
+		active := true
+		filter := models.UserFilter{Search: "jane", IsActive: &active}
+		sort := []models.SortField{{Name: "created_at", Descending: true}, {Name: "username"}}
+		mockService.On("List", mock.Anything, filter, sort, 1, 10).Return([]*models.UserResponse{}, int64(0), nil).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/users?search=jane&is_active=true&sort=-created_at,username", nil)
+		recorder := httptest.NewRecorder()
+		handler.List(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("unknown sort field", func(t *testing.T) {
+		sort := []models.SortField{{Name: "email"}}
+		mockService.On("List", mock.Anything, models.UserFilter{}, sort, 1, 10).Return(nil, int64(0), services.ErrInvalidSort).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/users?sort=email", nil)
+		recorder := httptest.NewRecorder()
+		handler.List(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("cursor pagination", func(t *testing.T) {
+		users := []*models.UserResponse{{ID: 4, Username: "dave"}}
+		mockService.On("ListByCursor", mock.Anything, models.UserFilter{}, []models.SortField(nil), "abc", 20).Return(users, "def", "", nil).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/users?cursor=abc&limit=20", nil)
+		recorder := httptest.NewRecorder()
+		handler.List(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		var response struct {
+			Data utils.CursorPaginationResponse `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+		assert.Equal(t, "def", response.Data.NextCursor)
+		assert.Equal(t, 20, response.Data.Limit)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("invalid cursor", func(t *testing.T) {
+		mockService.On("ListByCursor", mock.Anything, models.UserFilter{}, []models.SortField(nil), "garbage", 10).Return(nil, "", "", services.ErrInvalidCursor).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/users?cursor=garbage", nil)
+		recorder := httptest.NewRecorder()
+		handler.List(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("invalid parameters", func(t *testing.T) {
+		for _, query := range []string{"sort=username,", "is_admin=maybe", "created_after=yesterday"} {
+			request := httptest.NewRequest(http.MethodGet, "/users?"+query, nil)
+			recorder := httptest.NewRecorder()
+			handler.List(recorder, request)
+
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, query)
+		}
+	})
+
+	t.Run("tag filter", func(t *testing.T) {
+		filter := models.UserFilter{Tags: []string{"vip", "beta", "early"}}
+		mockService.On("List", mock.Anything, filter, []models.SortField(nil), 1, 10).Return([]*models.UserResponse{}, int64(0), nil).Once()
+
+		request := httptest.NewRequest(http.MethodGet, "/users?tag=VIP,beta&tag=early", nil)
+		request = request.WithContext(context.WithValue(request.Context(), middleware.IsAdminKey, true))
+		recorder := httptest.NewRecorder()
+		handler.List(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		mockService.AssertExpectations(t)
+
+		// Tags aren't shown to users, so only admins can filter by them
+		request = httptest.NewRequest(http.MethodGet, "/users?tag=vip", nil)
+		recorder = httptest.NewRecorder()
+		handler.List(recorder, request)
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+)
+
+// WebAuthnHandler handles passkey registration and login HTTP requests
+type WebAuthnHandler struct {
+	tokenCookies
+	webauthnService services.WebAuthnService
+	log             *logger.Logger
+	validator       *validator.Validate
+}
+
+// NewWebAuthnHandler creates a new WebAuthn handler
+func NewWebAuthnHandler(webauthnService services.WebAuthnService, log *logger.Logger) *WebAuthnHandler {
+	return &WebAuthnHandler{
+		webauthnService: webauthnService,
+		log:             log,
+		validator:       validator.New(),
+	}
+}
+
+// BeginRegistration handles POST /auth/webauthn/register/begin
+func (h *WebAuthnHandler) BeginRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	options, err := h.webauthnService.BeginRegistration(r.Context(), userID)
+	if err != nil {
+		if errors.Is(err, services.ErrUserNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		h.log.WithError(err).WithField("user_id", userID).Error("Failed to begin passkey registration")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to begin passkey registration", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Pass the options to navigator.credentials.create", options)
+}
+
+// FinishRegistration handles POST /auth/webauthn/register/finish
+func (h *WebAuthnHandler) FinishRegistration(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	var req models.WebAuthnRegisterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in passkey registration")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	credential, err := h.webauthnService.FinishRegistration(r.Context(), userID, &req)
+	if err != nil {
+		switch {
+		case errors.Is(err, services.ErrInvalidWebAuthnSession), errors.Is(err, services.ErrInvalidWebAuthnCredential):
+			utils.WriteErrorResponse(w, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, services.ErrUserNotFound):
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+		default:
+			h.log.WithError(err).WithField("user_id", userID).Error("Failed to finish passkey registration")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to register passkey", nil)
+		}
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusCreated, "Passkey registered", credential)
+}
+
+// BeginLogin handles POST /auth/webauthn/login/begin
+func (h *WebAuthnHandler) BeginLogin(w http.ResponseWriter, r *http.Request) {
+	options, err := h.webauthnService.BeginLogin(r.Context())
+	if err != nil {
+		h.log.WithError(err).Error("Failed to begin passkey login")
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to begin passkey login", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Pass the options to navigator.credentials.get", options)
+}
+
+// FinishLogin handles POST /auth/webauthn/login/finish
+func (h *WebAuthnHandler) FinishLogin(w http.ResponseWriter, r *http.Request) {
+	var req models.WebAuthnLoginRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.log.WithError(err).Warn("Invalid JSON in passkey login")
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid JSON", nil)
+		return
+	}
+
+	if err := h.validator.Struct(&req); err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", err.Error())
+		return
+	}
+
+	response, err := h.webauthnService.FinishLogin(r.Context(), &req)
+	if err != nil {
+		var deactivatedErr *services.AccountDeactivatedError
+		var suspendedErr *services.AccountSuspendedError
+		switch {
+		case errors.Is(err, services.ErrInvalidWebAuthnSession), errors.Is(err, services.ErrInvalidWebAuthnCredential):
+			utils.WriteErrorResponse(w, http.StatusUnauthorized, err.Error(), nil)
+		case errors.As(err, &deactivatedErr):
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), map[string]interface{}{
+				"code":                      "account_deactivated",
+				"self_service_reactivation": deactivatedErr.SelfService,
+			})
+		case errors.As(err, &suspendedErr):
+			utils.WriteErrorResponse(w, http.StatusForbidden, err.Error(), map[string]interface{}{
+				"code":            "account_suspended",
+				"suspended_until": suspendedErr.Until,
+			})
+		case errors.Is(err, services.ErrSessionLimitReached):
+			utils.WriteErrorResponse(w, http.StatusForbidden, services.ErrSessionLimitReached.Error(), map[string]interface{}{
+				"code": "session_limit_reached",
+			})
+		default:
+			h.log.WithError(err).Error("Failed to finish passkey login")
+			utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to log in", nil)
+		}
+		return
+	}
+
+	h.writeLoginResponse(w, h.log, "Login successful", response)
+}
+
+// ListCredentials handles GET /auth/webauthn/credentials
+func (h *WebAuthnHandler) ListCredentials(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	credentials, err := h.webauthnService.ListCredentials(r.Context(), userID)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to retrieve passkeys", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Passkeys retrieved successfully", credentials)
+}
+
+// DeleteCredential handles DELETE /auth/webauthn/credentials/{id}
+func (h *WebAuthnHandler) DeleteCredential(w http.ResponseWriter, r *http.Request) {
+	userID, ok := middleware.GetUserIDFromContext(r.Context())
+	if !ok {
+		utils.WriteErrorResponse(w, http.StatusUnauthorized, "User not authenticated", nil)
+		return
+	}
+
+	id, err := strconv.ParseUint(chi.URLParam(r, "id"), 10, 32)
+	if err != nil {
+		utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid credential ID", nil)
+		return
+	}
+
+	if err := h.webauthnService.DeleteCredential(r.Context(), userID, uint(id)); err != nil {
+		if errors.Is(err, services.ErrCredentialNotFound) {
+			utils.WriteErrorResponse(w, http.StatusNotFound, err.Error(), nil)
+			return
+		}
+		utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to delete passkey", nil)
+		return
+	}
+
+	utils.WriteSuccessResponse(w, http.StatusOK, "Passkey deleted", nil)
+}
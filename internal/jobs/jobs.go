@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+)
+
+// maxBackoff caps the delay between retries of a failing job
+const maxBackoff = time.Hour
+
+// ErrPermanent marks a handler error that should not be retried
+var ErrPermanent = errors.New("permanent job failure")
+
+// Handler processes the JSON payload of a job
+type Handler func(ctx context.Context, payload []byte) error
+
+// Enqueuer adds jobs to the queue
+type Enqueuer interface {
+	Enqueue(ctx context.Context, jobType string, payload interface{}) error
+}
+
+// Queue stores jobs in the database for workers to pick up
+type Queue struct {
+	repo        repository.JobRepository
+	maxAttempts int
+}
+
+// NewQueue creates a job queue
+func NewQueue(repo repository.JobRepository, maxAttempts int) *Queue {
+	return &Queue{
+		repo:        repo,
+		maxAttempts: maxAttempts,
+	}
+}
+
+// Enqueue stores a job of the given type to run as soon as a worker is free
+func (q *Queue) Enqueue(ctx context.Context, jobType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode job payload: %w", err)
+	}
+
+	return q.repo.Enqueue(ctx, &models.Job{
+		Type:        jobType,
+		Payload:     string(data),
+		Status:      models.JobStatusPending,
+		MaxAttempts: q.maxAttempts,
+		RunAt:       time.Now(),
+	})
+}
+
+// Worker polls the queue and runs jobs with their registered handlers
+type Worker struct {
+	repo         repository.JobRepository
+	log          *logger.Logger
+	pollInterval time.Duration
+	concurrency  int
+	staleAfter   time.Duration
+	handlers     map[string]Handler
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewWorker creates a worker running up to concurrency jobs at a time
+func NewWorker(repo repository.JobRepository, log *logger.Logger, pollInterval time.Duration, concurrency int, staleAfter time.Duration) *Worker {
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+	return &Worker{
+		repo:         repo,
+		log:          log,
+		pollInterval: pollInterval,
+		concurrency:  concurrency,
+		staleAfter:   staleAfter,
+		handlers:     make(map[string]Handler),
+	}
+}
+
+// Register sets the handler for a job type. Handlers must be registered before Start.
+func (w *Worker) Register(jobType string, handler Handler) {
+	w.handlers[jobType] = handler
+}
+
+// Start launches the polling goroutines
+func (w *Worker) Start(ctx context.Context) {
+	ctx, w.cancel = context.WithCancel(ctx)
+
+	// Recover jobs left running by a previous process before picking up new work
+	if w.staleAfter > 0 {
+		if count, err := w.repo.RequeueStale(ctx, time.Now().Add(-w.staleAfter)); err != nil {
+			w.log.WithError(err).Error("Failed to requeue stale jobs")
+		} else if count > 0 {
+			w.log.WithField("count", count).Warn("Requeued stale jobs")
+		}
+	}
+
+	for i := 0; i < w.concurrency; i++ {
+		w.wg.Add(1)
+		go w.run(ctx)
+	}
+}
+
+// Stop cancels polling and waits for running jobs to finish
+func (w *Worker) Stop() {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+}
+
+// run claims and processes jobs until the context is canceled
+func (w *Worker) run(ctx context.Context) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(w.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		// Drain due jobs before waiting for the next tick
+		for ctx.Err() == nil && w.RunNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunNext claims and processes a single due job. It reports whether a job was run.
+func (w *Worker) RunNext(ctx context.Context) bool {
+	job, err := w.repo.ClaimNext(ctx, time.Now())
+	if err != nil {
+		if ctx.Err() == nil {
+			w.log.WithError(err).Error("Failed to claim job")
+		}
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	w.process(ctx, job)
+	return true
+}
+
+// process runs a claimed job and records the outcome
+func (w *Worker) process(ctx context.Context, job *models.Job) {
+	log := w.log.WithFields(map[string]interface{}{
+		"job_id":   job.ID,
+		"job_type": job.Type,
+		"attempt":  job.Attempts,
+	})
+
+	handler, ok := w.handlers[job.Type]
+	if !ok {
+		log.Error("No handler registered for job type")
+		if err := w.repo.Fail(ctx, job.ID, "no handler registered"); err != nil {
+			log.WithError(err).Error("Failed to mark job as failed")
+		}
+		return
+	}
+
+	start := time.Now()
+	err := w.runHandler(ctx, handler, job)
+
+	// Record the outcome even if shutdown canceled the worker context
+	recordCtx := context.WithoutCancel(ctx)
+
+	if err == nil {
+		if err := w.repo.Complete(recordCtx, job.ID); err != nil {
+			log.WithError(err).Error("Failed to mark job as done")
+		}
+		log.WithField("duration_ms", time.Since(start).Milliseconds()).Debug("Job completed")
+		return
+	}
+
+	if errors.Is(err, ErrPermanent) || job.Attempts >= job.MaxAttempts {
+		log.WithError(err).Error("Job failed")
+		if err := w.repo.Fail(recordCtx, job.ID, err.Error()); err != nil {
+			log.WithError(err).Error("Failed to mark job as failed")
+		}
+		return
+	}
+
+	runAt := time.Now().Add(Backoff(job.Attempts))
+	log.WithError(err).WithField("retry_at", runAt).Warn("Job failed, retrying")
+	if err := w.repo.Retry(recordCtx, job.ID, runAt, err.Error()); err != nil {
+		log.WithError(err).Error("Failed to reschedule job")
+	}
+}
+
+// runHandler calls a handler, converting panics into errors
+func (w *Worker) runHandler(ctx context.Context, handler Handler, job *models.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return handler(ctx, []byte(job.Payload))
+}
+
+// Backoff returns the delay before retrying a job after the given number of attempts
+func Backoff(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	if attempts > 12 {
+		return maxBackoff
+	}
+	delay := time.Duration(1<<uint(attempts-1)) * 10 * time.Second
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupQueue(t *testing.T, maxAttempts int) (*Queue, *Worker, *gorm.DB) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	database := &repository.Database{DB: db}
+	require.NoError(t, database.AutoMigrate())
+
+	repo := repository.NewJobRepository(database)
+	return NewQueue(repo, maxAttempts), NewWorker(repo, logger.New("error", "text"), 10*time.Millisecond, 1, time.Minute), db
+}
+
+func TestWorker_RunsJobs(t *testing.T) {
+	queue, worker, db := setupQueue(t, 3)
+	ctx := context.Background()
+
+	var received []string
+	worker.Register("greet", func(ctx context.Context, payload []byte) error {
+		received = append(received, string(payload))
+		return nil
+	})
+
+	require.NoError(t, queue.Enqueue(ctx, "greet", map[string]string{"name": "ada"}))
+	assert.True(t, worker.RunNext(ctx))
+	assert.False(t, worker.RunNext(ctx))
+	assert.Equal(t, []string{`{"name":"ada"}`}, received)
+
+	var job models.Job
+	require.NoError(t, db.First(&job).Error)
+	assert.Equal(t, models.JobStatusDone, job.Status)
+}
+
+func TestWorker_RetriesAndFails(t *testing.T) {
+	queue, worker, db := setupQueue(t, 2)
+	ctx := context.Background()
+
+	worker.Register("flaky", func(ctx context.Context, payload []byte) error {
+		return errors.New("boom")
+	})
+	require.NoError(t, queue.Enqueue(ctx, "flaky", nil))
+
+	// The first failure schedules a retry after a backoff
+	assert.True(t, worker.RunNext(ctx))
+	var job models.Job
+	require.NoError(t, db.First(&job).Error)
+	assert.Equal(t, models.JobStatusPending, job.Status)
+	assert.Equal(t, "boom", job.LastError)
+	assert.True(t, job.RunAt.After(time.Now()))
+	assert.False(t, worker.RunNext(ctx))
+
+	// Once the attempts are used up the job fails for good
+	require.NoError(t, db.Model(&job).Update("run_at", time.Now().Add(-time.Second)).Error)
+	assert.True(t, worker.RunNext(ctx))
+	var failed models.Job
+	require.NoError(t, db.First(&failed).Error)
+	assert.Equal(t, models.JobStatusFailed, failed.Status)
+	assert.Equal(t, 2, failed.Attempts)
+}
+
+func TestWorker_PermanentErrorsAndPanics(t *testing.T) {
+	queue, worker, db := setupQueue(t, 5)
+	ctx := context.Background()
+
+	worker.Register("bad", func(ctx context.Context, payload []byte) error {
+		return fmt.Errorf("%w: invalid input", ErrPermanent)
+	})
+	worker.Register("panics", func(ctx context.Context, payload []byte) error {
+		panic("unexpected")
+	})
+	require.NoError(t, queue.Enqueue(ctx, "bad", nil))
+	require.NoError(t, queue.Enqueue(ctx, "panics", nil))
+	require.NoError(t, queue.Enqueue(ctx, "unknown", nil))
+
+	for worker.RunNext(ctx) {
+	}
+
+	var jobs []models.Job
+	require.NoError(t, db.Order("id").Find(&jobs).Error)
+	require.Len(t, jobs, 3)
+	assert.Equal(t, models.JobStatusFailed, jobs[0].Status)
+	// A panic is an ordinary failure and is retried
+	assert.Equal(t, models.JobStatusPending, jobs[1].Status)
+	assert.Contains(t, jobs[1].LastError, "panicked")
+	assert.Equal(t, models.JobStatusFailed, jobs[2].Status)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 10*time.Second, Backoff(1))
+	assert.Equal(t, 20*time.Second, Backoff(2))
+	assert.Equal(t, 80*time.Second, Backoff(4))
+	assert.Equal(t, time.Hour, Backoff(20))
+}
@@ -0,0 +1,83 @@
+package models
+
+import "time"
+
+// Effects of an access rule
+const (
+	AccessRuleRequire = "require" // Access is refused unless the condition holds
+	AccessRuleAllow   = "allow"   // Access is granted when the condition holds, even without the permission
+)
+
+// AccessRuleWildcard matches any resource or action
+const AccessRuleWildcard = "*"
+
+// AccessRule is a condition on the attributes of the user, the resource and the environment,
+// evaluated on top of role checks for a resource and action
+type AccessRule struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"uniqueIndex;not null;size:100"`
+	Description string    `json:"description" gorm:"size:255"`
+	Resource    string    `json:"resource" gorm:"index:idx_access_rules_resource_action;not null;size:100"` // "*" for any resource
+	Action      string    `json:"action" gorm:"index:idx_access_rules_resource_action;not null;size:50"`    // "*" for any action
+	Effect      string    `json:"effect" gorm:"not null;size:20"`
+	Condition   string    `json:"condition" gorm:"not null;size:1000"` // Expression such as user.tenant_id == resource.tenant_id
+	IsActive    bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for the AccessRule model
+func (AccessRule) TableName() string {
+	return "access_rules"
+}
+
+// AccessRuleCreateRequest represents the request payload for creating an access rule
+type AccessRuleCreateRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Description string `json:"description" validate:"max=255"`
+	Resource    string `json:"resource" validate:"required,min=1,max=100"`
+	Action      string `json:"action" validate:"required,min=1,max=50"`
+	Effect      string `json:"effect" validate:"required,oneof=require allow"`
+	Condition   string `json:"condition" validate:"required,min=1,max=1000"`
+}
+
+// AccessRuleUpdateRequest represents the request payload for updating an access rule
+type AccessRuleUpdateRequest struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=255"`
+	Resource    *string `json:"resource,omitempty" validate:"omitempty,min=1,max=100"`
+	Action      *string `json:"action,omitempty" validate:"omitempty,min=1,max=50"`
+	Effect      *string `json:"effect,omitempty" validate:"omitempty,oneof=require allow"`
+	Condition   *string `json:"condition,omitempty" validate:"omitempty,min=1,max=1000"`
+	IsActive    *bool   `json:"is_active,omitempty"`
+}
+
+// AccessRuleResponse represents an access rule in API responses
+type AccessRuleResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Resource    string    `json:"resource"`
+	Action      string    `json:"action"`
+	Effect      string    `json:"effect"`
+	Condition   string    `json:"condition"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ToResponse converts an AccessRule to AccessRuleResponse
+func (r *AccessRule) ToResponse() *AccessRuleResponse {
+	return &AccessRuleResponse{
+		ID:          r.ID,
+		Name:        r.Name,
+		Description: r.Description,
+		Resource:    r.Resource,
+		Action:      r.Action,
+		Effect:      r.Effect,
+		Condition:   r.Condition,
+		IsActive:    r.IsActive,
+		CreatedAt:   r.CreatedAt,
+		UpdatedAt:   r.UpdatedAt,
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"strings"
+	"time"
+)
+
+// Scopes an API key can be granted. Each limits the key to a group of routes.
+const (
+	APIKeyScopeUsersRead  = "users:read"
+	APIKeyScopeUsersWrite = "users:write"
+	APIKeyScopeFilesRead  = "files:read"
+	APIKeyScopeFilesWrite = "files:write"
+	APIKeyScopeOrgsRead   = "orgs:read"
+	APIKeyScopeOrgsWrite  = "orgs:write"
+	APIKeyScopeAdmin      = "admin" // Only granted to keys of admins
+)
+
+// APIKey is a long-lived credential a user creates for an integration
+type APIKey struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	Name       string     `json:"name" gorm:"not null;size:100"`
+	Prefix     string     `json:"prefix" gorm:"not null;size:16"` // Start of the key, shown to tell keys apart
+	KeyHash    string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	Scopes     string     `json:"-" gorm:"size:255"` // Space-separated list
+	ExpiresAt  *time.Time `json:"expires_at"`        // Nil for keys that don't expire
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for the APIKey model
+func (APIKey) TableName() string {
+	return "api_keys"
+}
+
+// GetScopes returns the scopes granted to the key
+func (k *APIKey) GetScopes() []string {
+	return strings.Fields(k.Scopes)
+}
+
+// APIKeyCreateRequest represents the request payload for creating an API key
+type APIKeyCreateRequest struct {
+	Name      string     `json:"name" validate:"required,min=1,max=100"`
+	Scopes    []string   `json:"scopes" validate:"required,min=1,dive,oneof=users:read users:write files:read files:write orgs:read orgs:write admin"`
+	ExpiresAt *time.Time `json:"expires_at"`
+}
+
+// APIKeyResponse represents an API key in API responses
+type APIKeyResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Key        string     `json:"key,omitempty"` // Only returned once, on creation
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ToResponse converts an APIKey to APIKeyResponse
+func (k *APIKey) ToResponse() *APIKeyResponse {
+	return &APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     k.GetScopes(),
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
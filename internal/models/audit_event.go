@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Actions recorded in the activity audit trail of users
+const (
+	AuditUserCreated        = "user.created"
+	AuditProfileUpdated     = "user.profile_updated"
+	AuditEmailChanged       = "user.email_changed"
+	AuditPasswordChanged    = "user.password_changed"
+	AuditPasswordReset      = "user.password_reset"
+	AuditTwoFactorEnabled   = "user.two_factor_enabled"
+	AuditTwoFactorDisabled  = "user.two_factor_disabled"
+	AuditSuspended          = "user.suspended"
+	AuditUnsuspended        = "user.unsuspended"
+	AuditDeactivated        = "user.deactivated"
+	AuditReactivated        = "user.reactivated"
+	AuditDeletionRequested  = "user.deletion_requested"
+	AuditDeletionCanceled   = "user.deletion_canceled"
+	AuditDeleted            = "user.deleted"
+	AuditAnonymized         = "user.anonymized" // Personal data scrubbed after the deletion grace period, or after a soft delete
+	AuditRestored           = "user.restored"
+	AuditPermanentlyDeleted = "user.permanently_deleted"
+	AuditAccountMerged      = "user.account_merged" // A duplicate account was merged into this one
+	AuditMergedInto         = "user.merged_into"    // This account was merged into another and deactivated
+)
+
+// AuditEvent is an immutable record of a significant action on a user's account, made by the
+// user, an admin or the application
+type AuditEvent struct {
+	ID             uint                   `json:"id" gorm:"primaryKey"`
+	UserID         uint                   `json:"user_id" gorm:"index;not null"` // Kept once the user is permanently deleted
+	ActorID        uint                   `json:"actor_id" gorm:"index"`         // 0 for actions of signed out users or of the application
+	ImpersonatorID uint                   `json:"impersonator_id,omitempty"`     // Admin acting through an impersonation token
+	Action         string                 `json:"action" gorm:"size:50;not null;index"`
+	Details        map[string]interface{} `json:"details,omitempty" gorm:"serializer:json;type:text"` // Such as the names of the fields updated, never their values
+	IPAddress      string                 `json:"ip_address" gorm:"size:45"`
+	UserAgent      string                 `json:"user_agent" gorm:"size:255"`
+	CreatedAt      time.Time              `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for the AuditEvent model
+func (AuditEvent) TableName() string {
+	return "audit_events"
+}
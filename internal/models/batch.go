@@ -0,0 +1,20 @@
+package models
+
+import "encoding/json"
+
+// BatchRequest is one sub-request of POST /batch
+type BatchRequest struct {
+	ID      string            `json:"id,omitempty" validate:"max=100"` // Echoed back to match responses to requests
+	Method  string            `json:"method" validate:"required,oneof=GET POST PUT PATCH DELETE"`
+	Path    string            `json:"path" validate:"required,startswith=/api/v1/,max=2048"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"`
+}
+
+// BatchResponse is the outcome of one sub-request, in the order the requests were given
+type BatchResponse struct {
+	ID      string            `json:"id,omitempty"`
+	Status  int               `json:"status"`
+	Headers map[string]string `json:"headers,omitempty"`
+	Body    json.RawMessage   `json:"body,omitempty"` // The JSON response, or a JSON string for other content
+}
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// Device is a browser or app a user logged in from. Logins return a device token the client
+// keeps and sends with later logins, so the device is recognized; only its hash is stored.
+type Device struct {
+	ID           uint       `json:"id" gorm:"primaryKey"`
+	UserID       uint       `json:"-" gorm:"index;not null"`
+	TokenHash    string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	UserAgent    string     `json:"user_agent" gorm:"size:255"`
+	IPAddress    string     `json:"ip_address" gorm:"size:45"` // Of the latest login
+	LastSeenAt   time.Time  `json:"last_seen_at"`
+	TrustedUntil *time.Time `json:"trusted_until"` // Two-factor authentication is skipped until then
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for the Device model
+func (Device) TableName() string {
+	return "devices"
+}
+
+// IsTrusted reports whether logins from the device skip two-factor authentication at the given time
+func (d *Device) IsTrusted(now time.Time) bool {
+	return d.TrustedUntil != nil && now.Before(*d.TrustedUntil)
+}
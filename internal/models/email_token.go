@@ -0,0 +1,60 @@
+package models
+
+import "time"
+
+// Purposes of email tokens
+const (
+	EmailTokenReactivation        = "reactivation"
+	EmailTokenPasswordReset       = "password_reset"
+	EmailTokenEmailChange         = "email_change"           // Emailed to the new address of an email change
+	EmailTokenSAMLLogin           = "saml_login"             // Handed to the browser after SAML single sign-on
+	EmailTokenTwoFactor           = "2fa_challenge"          // Returned by a password login awaiting a TOTP code
+	EmailTokenTwoFactorRememberMe = "2fa_challenge_remember" // Same, for a login asking to be remembered
+
+	EmailTokenLoginVerification           = "login_verification"          // Emailed to confirm a suspicious login
+	EmailTokenLoginVerificationRememberMe = "login_verification_remember" // Same, for a login asking to be remembered
+)
+
+// EmailToken is a single-use token emailed to a user to confirm an action.
+// Only the hash is stored; the token itself is only ever in the email, or for
+// SAML login codes, in the redirect to the frontend.
+type EmailToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	Purpose   string     `json:"purpose" gorm:"not null;size:32"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for the EmailToken model
+func (EmailToken) TableName() string {
+	return "email_tokens"
+}
+
+// ReactivationRequest represents the request payload for emailing a reactivation link
+type ReactivationRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// ReactivationConfirmRequest represents the request payload for confirming a reactivation link
+type ReactivationConfirmRequest struct {
+	Token string `json:"token" validate:"required,max=255"`
+}
+
+// EmailChangeConfirmRequest represents the request payload for confirming an email change
+type EmailChangeConfirmRequest struct {
+	Token string `json:"token" validate:"required,max=255"`
+}
+
+// PasswordForgotRequest represents the request payload for emailing a password reset link
+type PasswordForgotRequest struct {
+	Email string `json:"email" validate:"required,email"`
+}
+
+// PasswordResetRequest represents the request payload for setting a new password with a reset link
+type PasswordResetRequest struct {
+	Token    string `json:"token" validate:"required,max=255"`
+	Password string `json:"password" validate:"required,min=6,max=72"` // bcrypt ignores bytes after the 72nd
+}
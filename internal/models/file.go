@@ -0,0 +1,66 @@
+package models
+
+import "time"
+
+// File represents a stored file owned by a user
+type File struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	UserID      uint      `json:"user_id" gorm:"index;not null"`
+	Purpose     string    `json:"purpose" gorm:"not null;size:50"`
+	Filename    string    `json:"filename" gorm:"not null;size:255"`
+	ContentType string    `json:"content_type" gorm:"size:255"`
+	Size        int64     `json:"size" gorm:"not null"`
+	Checksum    string    `json:"checksum" gorm:"size:64"` // SHA-256 hex digest
+	ObjectKey   string    `json:"-" gorm:"uniqueIndex;not null;size:512"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for the File model
+func (File) TableName() string {
+	return "files"
+}
+
+// FileResponse represents the response payload for file metadata
+type FileResponse struct {
+	ID          uint      `json:"id"`
+	UserID      uint      `json:"user_id"`
+	Purpose     string    `json:"purpose"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	Checksum    string    `json:"checksum"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// ToResponse converts File model to FileResponse
+func (f *File) ToResponse() *FileResponse {
+	return &FileResponse{
+		ID:          f.ID,
+		UserID:      f.UserID,
+		Purpose:     f.Purpose,
+		Filename:    f.Filename,
+		ContentType: f.ContentType,
+		Size:        f.Size,
+		Checksum:    f.Checksum,
+		CreatedAt:   f.CreatedAt,
+	}
+}
+
+// StorageUsage reports a user's storage consumption; a zero quota means unlimited
+type StorageUsage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
+
+// FileListResponse represents a user's files with their storage usage
+type FileListResponse struct {
+	Files []*FileResponse `json:"files"`
+	Usage StorageUsage    `json:"usage"`
+}
+
+// FileDownloadResponse carries a short-lived URL for downloading a file directly from storage
+type FileDownloadResponse struct {
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
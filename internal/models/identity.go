@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// UserIdentity links a local user to an identity asserted by an external provider
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"index;not null"`
+	Provider  string    `json:"provider" gorm:"uniqueIndex:idx_user_identities_provider_subject;not null;size:255"` // Issuer URL or provider name
+	Subject   string    `json:"subject" gorm:"uniqueIndex:idx_user_identities_provider_subject;not null;size:255"`
+	Email     string    `json:"email" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for the UserIdentity model
+func (UserIdentity) TableName() string {
+	return "user_identities"
+}
+
+// IdentityLinkRequest represents the request payload for linking an identity of the external
+// OpenID Provider to the current user
+type IdentityLinkRequest struct {
+	Token string `json:"token" validate:"required"` // Access token of the provider
+	Merge bool   `json:"merge"`                     // Merge the account the identity is linked to, if any, into the current user
+}
+
+// SAMLCodeRequest represents the request payload for redeeming a SAML login code
+type SAMLCodeRequest struct {
+	Code        string `json:"code" validate:"required,max=255"`
+	DeviceToken string `json:"device_token,omitempty" validate:"omitempty,max=255"` // Returned by an earlier login on this device
+}
+
+// TokenExchangeRequest represents an RFC 8693 token exchange request
+type TokenExchangeRequest struct {
+	GrantType          string
+	SubjectToken       string
+	SubjectTokenType   string
+	RequestedTokenType string
+	Audience           string
+}
+
+// TokenExchangeResponse represents an RFC 8693 token exchange response
+type TokenExchangeResponse struct {
+	AccessToken     string `json:"access_token"`
+	IssuedTokenType string `json:"issued_token_type"`
+	TokenType       string `json:"token_type"`
+	ExpiresIn       int64  `json:"expires_in"`
+}
+
+// RFC 8693 grant and token type identifiers
+const (
+	GrantTypeTokenExchange = "urn:ietf:params:oauth:grant-type:token-exchange"
+	TokenTypeJWT           = "urn:ietf:params:oauth:token-type:jwt"
+	TokenTypeAccessToken   = "urn:ietf:params:oauth:token-type:access_token"
+	TokenTypeIDToken       = "urn:ietf:params:oauth:token-type:id_token"
+)
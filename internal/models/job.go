@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// Job statuses
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusDone    = "done"
+	JobStatusFailed  = "failed"
+)
+
+// Job is a unit of background work stored in the database queue
+type Job struct {
+	ID          uint       `json:"id" gorm:"primaryKey"`
+	Type        string     `json:"type" gorm:"not null;size:100;index"`
+	Payload     string     `json:"payload" gorm:"type:text"`
+	Status      string     `json:"status" gorm:"not null;size:20;default:pending;index:idx_jobs_status_run_at"`
+	Attempts    int        `json:"attempts" gorm:"not null;default:0"`
+	MaxAttempts int        `json:"max_attempts" gorm:"not null;default:5"`
+	RunAt       time.Time  `json:"run_at" gorm:"index:idx_jobs_status_run_at"`
+	LockedAt    *time.Time `json:"locked_at"`
+	LastError   string     `json:"last_error" gorm:"type:text"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for the Job model
+func (Job) TableName() string {
+	return "jobs"
+}
@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Login methods recorded in the login history
+const (
+	LoginMethodPassword  = "password"
+	LoginMethodOTP       = "otp"
+	LoginMethodTwoFactor = "two_factor" // Password or texted code login completed with a TOTP code
+	LoginMethodPasskey   = "passkey"
+	LoginMethodSAML      = "saml"
+	LoginMethodEmailLink = "email_link" // Suspicious login confirmed with the emailed link
+)
+
+// Reasons recorded for failed logins
+const (
+	LoginFailureInvalidPassword    = "invalid_password"
+	LoginFailureInvalidCode        = "invalid_code"
+	LoginFailureAccountLocked      = "account_locked"
+	LoginFailureAccountDeactivated = "account_deactivated"
+	LoginFailureAccountSuspended   = "account_suspended"
+	LoginFailureUnverified         = "verification_required" // Suspicious login awaiting the emailed link
+	LoginFailurePasswordExpired    = "password_expired"
+)
+
+// LoginEvent is an entry of a user's login history, recording a successful or failed login
+type LoginEvent struct {
+	ID            uint      `json:"id" gorm:"primaryKey"`
+	UserID        uint      `json:"-" gorm:"index;not null"`
+	Method        string    `json:"method" gorm:"size:20;not null"`
+	Success       bool      `json:"success"`
+	FailureReason string    `json:"failure_reason,omitempty" gorm:"size:50"`
+	IPAddress     string    `json:"ip_address" gorm:"size:45"`
+	UserAgent     string    `json:"user_agent" gorm:"size:255"`
+	CreatedAt     time.Time `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for the LoginEvent model
+func (LoginEvent) TableName() string {
+	return "login_events"
+}
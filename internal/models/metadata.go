@@ -0,0 +1,62 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Limits of the metadata users can attach to their account
+const (
+	MetadataMaxSize      = 16 << 10 // Bytes of the JSON encoded metadata
+	MetadataMaxDepth     = 5        // Levels of nested objects and arrays, the metadata itself included
+	MetadataMaxKeys      = 50       // Members of the metadata object, not counting nested ones
+	MetadataMaxKeyLength = 64
+)
+
+// Metadata holds custom attributes that apps built on the template attach to users, as a JSON
+// object whose values may be any JSON value, nested objects and arrays included
+type Metadata map[string]interface{}
+
+// Validate checks the metadata against the size, depth and key limits. Nil metadata is valid.
+func (m Metadata) Validate() error {
+	if len(m) > MetadataMaxKeys {
+		return fmt.Errorf("metadata must have at most %d keys", MetadataMaxKeys)
+	}
+	for key := range m {
+		if key == "" || len(key) > MetadataMaxKeyLength {
+			return fmt.Errorf("metadata keys must have 1 to %d characters", MetadataMaxKeyLength)
+		}
+	}
+	if depth := jsonDepth(map[string]interface{}(m)); depth > MetadataMaxDepth {
+		return fmt.Errorf("metadata must nest at most %d levels deep", MetadataMaxDepth)
+	}
+
+	encoded, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("metadata must be JSON: %w", err)
+	}
+	if len(encoded) > MetadataMaxSize {
+		return fmt.Errorf("metadata must be at most %d bytes of JSON", MetadataMaxSize)
+	}
+	return nil
+}
+
+// jsonDepth returns the number of levels of objects and arrays in a decoded JSON value
+func jsonDepth(value interface{}) int {
+	deepest := 0
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, member := range v {
+			deepest = max(deepest, jsonDepth(member))
+		}
+	case Metadata:
+		return jsonDepth(map[string]interface{}(v))
+	case []interface{}:
+		for _, element := range v {
+			deepest = max(deepest, jsonDepth(element))
+		}
+	default:
+		return 0
+	}
+	return deepest + 1
+}
@@ -0,0 +1,185 @@
+package models
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OAuthClient represents an application registered with the authorization server
+type OAuthClient struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	ClientID     string         `json:"client_id" gorm:"uniqueIndex;not null;size:64"`
+	SecretHash   string         `json:"-" gorm:"size:255"` // Empty for public clients
+	Name         string         `json:"name" gorm:"not null;size:100"`
+	RedirectURIs string         `json:"-" gorm:"type:text;not null"` // Space-separated list
+	Scopes       string         `json:"-" gorm:"size:255"`           // Space-separated list
+	IsPublic     bool           `json:"is_public" gorm:"default:false"`
+	OwnerID      uint           `json:"owner_id" gorm:"index"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
+}
+
+// OAuthAuthorizationCode represents a short-lived authorization code issued to a client
+type OAuthAuthorizationCode struct {
+	ID                  uint       `json:"id" gorm:"primaryKey"`
+	CodeHash            string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	ClientID            string     `json:"client_id" gorm:"index;not null;size:64"`
+	UserID              uint       `json:"user_id" gorm:"index;not null"`
+	RedirectURI         string     `json:"redirect_uri" gorm:"type:text;not null"`
+	Scope               string     `json:"scope" gorm:"size:255"`
+	CodeChallenge       string     `json:"-" gorm:"size:128"`
+	CodeChallengeMethod string     `json:"-" gorm:"size:10"`
+	ExpiresAt           time.Time  `json:"expires_at"`
+	UsedAt              *time.Time `json:"used_at"`
+	CreatedAt           time.Time  `json:"created_at"`
+}
+
+// OAuthConsent records the scopes a user has granted to a client
+type OAuthConsent struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_oauth_consents_user_client;not null"`
+	ClientID  string    `json:"client_id" gorm:"uniqueIndex:idx_oauth_consents_user_client;not null;size:64"`
+	Scope     string    `json:"scope" gorm:"size:255"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for the OAuthClient model
+func (OAuthClient) TableName() string {
+	return "oauth_clients"
+}
+
+// TableName specifies the table name for the OAuthAuthorizationCode model
+func (OAuthAuthorizationCode) TableName() string {
+	return "oauth_authorization_codes"
+}
+
+// TableName specifies the table name for the OAuthConsent model
+func (OAuthConsent) TableName() string {
+	return "oauth_consents"
+}
+
+// GetRedirectURIs returns the registered redirect URIs
+func (c *OAuthClient) GetRedirectURIs() []string {
+	return strings.Fields(c.RedirectURIs)
+}
+
+// GetScopes returns the scopes the client is allowed to request
+func (c *OAuthClient) GetScopes() []string {
+	return strings.Fields(c.Scopes)
+}
+
+// OAuthClientCreateRequest represents the request payload for registering a client
+type OAuthClientCreateRequest struct {
+	Name         string   `json:"name" validate:"required,min=1,max=100"`
+	RedirectURIs []string `json:"redirect_uris" validate:"required,min=1,dive,url"`
+	Scopes       []string `json:"scopes" validate:"omitempty,dive,oneof=openid profile email"`
+	IsPublic     bool     `json:"is_public"`
+}
+
+// OAuthClientResponse represents the response payload for client data
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id"`
+	ClientSecret string    `json:"client_secret,omitempty"` // Only returned once, on registration
+	Name         string    `json:"name"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	Scopes       []string  `json:"scopes"`
+	IsPublic     bool      `json:"is_public"`
+	OwnerID      uint      `json:"owner_id"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ToResponse converts OAuthClient model to OAuthClientResponse
+func (c *OAuthClient) ToResponse() *OAuthClientResponse {
+	return &OAuthClientResponse{
+		ClientID:     c.ClientID,
+		Name:         c.Name,
+		RedirectURIs: c.GetRedirectURIs(),
+		Scopes:       c.GetScopes(),
+		IsPublic:     c.IsPublic,
+		OwnerID:      c.OwnerID,
+		CreatedAt:    c.CreatedAt,
+	}
+}
+
+// OAuthAuthorizeRequest represents the parameters of an authorization request
+type OAuthAuthorizeRequest struct {
+	ResponseType        string `json:"response_type" validate:"required,eq=code"`
+	ClientID            string `json:"client_id" validate:"required"`
+	RedirectURI         string `json:"redirect_uri" validate:"required,url"`
+	Scope               string `json:"scope"`
+	State               string `json:"state"`
+	CodeChallenge       string `json:"code_challenge" validate:"omitempty,min=43,max=128"`
+	CodeChallengeMethod string `json:"code_challenge_method" validate:"omitempty,oneof=S256 plain"`
+}
+
+// OAuthConsentDecision represents the user's answer on the consent screen
+type OAuthConsentDecision struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+// OAuthScopeDescription describes a scope for display on the consent screen
+type OAuthScopeDescription struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+}
+
+// OAuthConsentResponse contains everything a frontend needs to render the consent screen
+type OAuthConsentResponse struct {
+	ClientID        string                  `json:"client_id"`
+	ClientName      string                  `json:"client_name"`
+	RedirectURI     string                  `json:"redirect_uri"`
+	State           string                  `json:"state,omitempty"`
+	Scopes          []OAuthScopeDescription `json:"scopes"`
+	ConsentRequired bool                    `json:"consent_required"` // False when the user already granted these scopes
+}
+
+// OAuthTokenRequest represents a token endpoint request
+type OAuthTokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	ClientID     string
+	ClientSecret string
+	CodeVerifier string
+}
+
+// OAuthTokenResponse represents a successful token endpoint response
+type OAuthTokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// OAuthError represents an RFC 6749 error response
+type OAuthError struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Error implements the error interface
+func (e *OAuthError) Error() string {
+	if e.Description == "" {
+		return e.Code
+	}
+	return e.Code + ": " + e.Description
+}
+
+// Supported OAuth2/OIDC scopes
+const (
+	OAuthScopeOpenID  = "openid"
+	OAuthScopeProfile = "profile"
+	OAuthScopeEmail   = "email"
+)
+
+// OAuthScopeDescriptions maps supported scopes to consent screen descriptions
+var OAuthScopeDescriptions = map[string]string{
+	OAuthScopeOpenID:  "Sign you in with your account",
+	OAuthScopeProfile: "Read your name and username",
+	OAuthScopeEmail:   "Read your email address",
+}
@@ -0,0 +1,123 @@
+package models
+
+import "time"
+
+// Roles a user can hold within an organization, from most to least privileged
+const (
+	OrgRoleOwner  = "owner"  // Manages the organization and its members, including other owners
+	OrgRoleAdmin  = "admin"  // Manages the organization and its members, except owners
+	OrgRoleMember = "member" // Sees the organization and its members
+)
+
+// orgRoleRanks orders the organization roles, higher ranks include the rights of lower ones
+var orgRoleRanks = map[string]int{
+	OrgRoleMember: 1,
+	OrgRoleAdmin:  2,
+	OrgRoleOwner:  3,
+}
+
+// OrgRoleAtLeast reports whether role grants at least the rights of minRole
+func OrgRoleAtLeast(role, minRole string) bool {
+	return orgRoleRanks[role] >= orgRoleRanks[minRole] && orgRoleRanks[role] > 0
+}
+
+// Organization groups users, such as a company or a team, above individual accounts
+type Organization struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TenantID    string    `json:"-" gorm:"index;not null;default:'';size:64"` // Empty for the default tenant
+	Name        string    `json:"name" gorm:"not null;size:100"`
+	Description string    `json:"description" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for the Organization model
+func (Organization) TableName() string {
+	return "organizations"
+}
+
+// Membership is the role of a user in an organization
+type Membership struct {
+	OrganizationID uint      `json:"organization_id" gorm:"primaryKey"`
+	UserID         uint      `json:"user_id" gorm:"primaryKey;index"`
+	Role           string    `json:"role" gorm:"not null;size:20"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+
+	// Relationships
+	Organization *Organization `json:"-" gorm:"foreignKey:OrganizationID"`
+	User         *User         `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// TableName specifies the table name for the Membership model
+func (Membership) TableName() string {
+	return "organization_memberships"
+}
+
+// OrganizationCreateRequest represents the request payload for creating an organization
+type OrganizationCreateRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=100"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+// OrganizationUpdateRequest represents the request payload for updating an organization
+type OrganizationUpdateRequest struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=255"`
+}
+
+// MembershipCreateRequest represents the request payload for adding a user to an organization
+type MembershipCreateRequest struct {
+	UserID uint   `json:"user_id" validate:"required"`
+	Role   string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// MembershipUpdateRequest represents the request payload for changing the role of a member
+type MembershipUpdateRequest struct {
+	Role string `json:"role" validate:"required,oneof=owner admin member"`
+}
+
+// OrganizationResponse represents an organization in API responses
+type OrganizationResponse struct {
+	ID          uint      `json:"id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	Role        string    `json:"role,omitempty"` // Role of the requesting user, empty for admins who aren't members
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// ToResponse converts an Organization to OrganizationResponse, with the role of the requesting user
+func (o *Organization) ToResponse(role string) *OrganizationResponse {
+	return &OrganizationResponse{
+		ID:          o.ID,
+		Name:        o.Name,
+		Description: o.Description,
+		Role:        role,
+		CreatedAt:   o.CreatedAt,
+		UpdatedAt:   o.UpdatedAt,
+	}
+}
+
+// MembershipResponse represents a member of an organization in API responses
+type MembershipResponse struct {
+	UserID    uint          `json:"user_id"`
+	Role      string        `json:"role"`
+	User      *UserResponse `json:"user,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+	UpdatedAt time.Time     `json:"updated_at"`
+}
+
+// ToResponse converts a Membership to MembershipResponse, including the user when loaded
+func (m *Membership) ToResponse() *MembershipResponse {
+	resp := &MembershipResponse{
+		UserID:    m.UserID,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+	if m.User != nil {
+		resp.User = m.User.ToResponse()
+	}
+	return resp
+}
@@ -0,0 +1,31 @@
+package models
+
+import "time"
+
+// OTPCode is a one-time code texted to a user to log in with their phone number.
+// A user has at most one pending code; requesting another replaces it.
+type OTPCode struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex;not null"`
+	CodeHash  string    `json:"-" gorm:"not null;size:64"`
+	Attempts  int       `json:"attempts" gorm:"not null;default:0"` // Wrong codes entered so far
+	ExpiresAt time.Time `json:"expires_at" gorm:"not null"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for the OTPCode model
+func (OTPCode) TableName() string {
+	return "otp_codes"
+}
+
+// OTPRequest represents the request payload for texting a login code
+type OTPRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164"`
+}
+
+// OTPVerifyRequest represents the request payload for logging in with a texted code
+type OTPVerifyRequest struct {
+	PhoneNumber string `json:"phone_number" validate:"required,e164"`
+	Code        string `json:"code" validate:"required,numeric,len=6"`
+	DeviceToken string `json:"device_token,omitempty" validate:"omitempty,max=255"`
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// SortField is a field a list is sorted by, as given in a sort query parameter such as
+// sort=-created_at,username
+type SortField struct {
+	Name       string
+	Descending bool
+}
+
+// FormatSort formats sort fields the way the sort query parameter gives them
+func FormatSort(sort []SortField) string {
+	names := make([]string, len(sort))
+	for i, field := range sort {
+		names[i] = field.Name
+		if field.Descending {
+			names[i] = "-" + field.Name
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// ErrInvalidCursor is returned for cursors that weren't issued for the list they are used with
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor marks a position in a sorted list for keyset pagination. Clients get cursors as opaque
+// strings and pass them back unchanged.
+type Cursor struct {
+	Sort   string   `json:"s"`           // Sort of the list the cursor was issued for, as FormatSort gives it
+	Values []string `json:"v"`           // Sort fields of the row at the position, then its ID
+	Before bool     `json:"b,omitempty"` // Points to the page before the row rather than after it
+}
+
+// Encode returns the cursor as an opaque, URL-safe string
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode
+func DecodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
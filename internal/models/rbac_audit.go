@@ -0,0 +1,52 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the RBAC audit log
+const (
+	RBACAuditRoleCreated        = "role.created"
+	RBACAuditRoleUpdated        = "role.updated"
+	RBACAuditRoleDeleted        = "role.deleted"
+	RBACAuditRolePermissionsSet = "role.permissions_set"
+	RBACAuditPermissionCreated  = "permission.created"
+	RBACAuditPermissionUpdated  = "permission.updated"
+	RBACAuditPermissionDeleted  = "permission.deleted"
+	RBACAuditRolesAssigned      = "user.roles_assigned"
+	RBACAuditRoleRemoved        = "user.role_removed"
+	RBACAuditRolesReplaced      = "user.roles_replaced"
+)
+
+// Types of the targets of RBAC audit events
+const (
+	RBACAuditTargetRole       = "role"
+	RBACAuditTargetPermission = "permission"
+	RBACAuditTargetUser       = "user"
+)
+
+// RBACAuditEvent is an immutable record of a change to roles, permissions or the roles of a user
+type RBACAuditEvent struct {
+	ID         uint            `json:"id" gorm:"primaryKey"`
+	ActorID    uint            `json:"actor_id" gorm:"index"` // 0 for changes made by the application, such as directory group sync
+	Action     string          `json:"action" gorm:"size:50;not null;index"`
+	TargetType string          `json:"target_type" gorm:"size:20;not null;index:idx_rbac_audit_events_target"`
+	TargetID   uint            `json:"target_id" gorm:"not null;index:idx_rbac_audit_events_target"`
+	Before     json.RawMessage `json:"before" gorm:"serializer:json;type:text"` // State of the target before the change, null when created
+	After      json.RawMessage `json:"after" gorm:"serializer:json;type:text"`  // State of the target after the change, null when deleted
+	CreatedAt  time.Time       `json:"created_at" gorm:"index"`
+}
+
+// TableName specifies the table name for the RBACAuditEvent model
+func (RBACAuditEvent) TableName() string {
+	return "rbac_audit_events"
+}
+
+// RBACAuditFilter narrows down the RBAC audit events listed. Zero fields match any event.
+type RBACAuditFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+}
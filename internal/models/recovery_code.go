@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// RecoveryCode is a single-use code that stands in for a TOTP code when the user lost their
+// authenticator app. Codes are handed out once, when two-factor authentication is enabled or the
+// codes are regenerated; only their hashes are stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey"`
+	UserID    uint       `gorm:"index;not null"`
+	CodeHash  string     `gorm:"not null;size:64"`
+	UsedAt    *time.Time // Nil until the code completes a login
+	CreatedAt time.Time
+}
+
+// TableName specifies the table name for the RecoveryCode model
+func (RecoveryCode) TableName() string {
+	return "recovery_codes"
+}
+
+// RecoveryCodesResponse carries a new set of recovery codes, shown to the user this one time
+type RecoveryCodesResponse struct {
+	Codes []string `json:"codes"`
+}
+
+// RecoveryCodesStatus tells the user how many of their recovery codes are left
+type RecoveryCodesStatus struct {
+	Remaining int64 `json:"remaining"`
+}
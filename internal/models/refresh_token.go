@@ -0,0 +1,57 @@
+package models
+
+import "time"
+
+// RefreshToken represents an opaque refresh token.
+// Tokens issued from one login share a FamilyID; each rotation marks the old token used
+// and issues a new one in the same family.
+type RefreshToken struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	UserID    uint       `json:"user_id" gorm:"index;not null"`
+	FamilyID  string     `json:"family_id" gorm:"index;not null;size:64"`
+	TokenHash string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	RevokedAt *time.Time `json:"revoked_at"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for the RefreshToken model
+func (RefreshToken) TableName() string {
+	return "refresh_tokens"
+}
+
+// RefreshTokenRequest represents the request payload for refreshing an access token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LoginResponse represents the tokens returned after authentication. When the user has
+// two-factor authentication enabled, a password login only returns a challenge token
+// to complete with a TOTP code.
+type LoginResponse struct {
+	AccessToken  string        `json:"access_token,omitempty"`
+	RefreshToken string        `json:"refresh_token,omitempty"`
+	TokenType    string        `json:"token_type,omitempty"`
+	ExpiresIn    int64         `json:"expires_in,omitempty"`
+	User         *UserResponse `json:"user,omitempty"`
+
+	TwoFactorRequired bool   `json:"two_factor_required,omitempty"`
+	ChallengeToken    string `json:"challenge_token,omitempty"`
+
+	// Set when a suspicious login has to be confirmed with the link emailed to the user
+	VerificationRequired bool `json:"verification_required,omitempty"`
+
+	// Set when the login came from a new device; send it with later logins from the device
+	DeviceToken string `json:"device_token,omitempty"`
+}
+
+// ImpersonationResponse represents the access token an admin was issued to act as a user.
+// There is no refresh token; the admin impersonates again once it expires.
+type ImpersonationResponse struct {
+	AccessToken    string        `json:"access_token"`
+	TokenType      string        `json:"token_type"`
+	ExpiresIn      int64         `json:"expires_in"`
+	ImpersonatorID uint          `json:"impersonator_id"`
+	User           *UserResponse `json:"user"`
+}
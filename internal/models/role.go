@@ -1,6 +1,7 @@
 package models
 
 import (
+	"strings"
 	"time"
 
 	"gorm.io/gorm"
@@ -8,13 +9,14 @@ import (
 
 // Role represents a role in the system
 type Role struct {
-	ID          uint           `json:"id" gorm:"primaryKey"`
-	Name        string         `json:"name" gorm:"uniqueIndex;not null;size:100"`
-	Description string         `json:"description" gorm:"size:255"`
-	IsActive    bool           `json:"is_active" gorm:"default:true"`
-	CreatedAt   time.Time      `json:"created_at"`
-	UpdatedAt   time.Time      `json:"updated_at"`
-	DeletedAt   gorm.DeletedAt `json:"-" gorm:"index"`
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	Name         string         `json:"name" gorm:"uniqueIndex;not null;size:100"`
+	Description  string         `json:"description" gorm:"size:255"`
+	IsActive     bool           `json:"is_active" gorm:"default:true"`
+	ParentRoleID *uint          `json:"parent_role_id" gorm:"index"` // Role whose permissions this role inherits
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"`
 
 	// Relationships
 	Permissions []Permission `json:"permissions" gorm:"many2many:role_permissions;"`
@@ -82,15 +84,17 @@ func (RolePermission) TableName() string {
 
 // RoleCreateRequest represents the request payload for creating a role
 type RoleCreateRequest struct {
-	Name        string `json:"name" validate:"required,min=1,max=100"`
-	Description string `json:"description" validate:"max=255"`
+	Name         string `json:"name" validate:"required,min=1,max=100"`
+	Description  string `json:"description" validate:"max=255"`
+	ParentRoleID *uint  `json:"parent_role_id,omitempty" validate:"omitempty,min=1"`
 }
 
 // RoleUpdateRequest represents the request payload for updating a role
 type RoleUpdateRequest struct {
-	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
-	Description *string `json:"description,omitempty" validate:"omitempty,max=255"`
-	IsActive    *bool   `json:"is_active,omitempty"`
+	Name         *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Description  *string `json:"description,omitempty" validate:"omitempty,max=255"`
+	IsActive     *bool   `json:"is_active,omitempty"`
+	ParentRoleID *uint   `json:"parent_role_id,omitempty"` // 0 stops inheriting
 }
 
 // PermissionCreateRequest represents the request payload for creating a permission
@@ -123,13 +127,14 @@ type AssignPermissionRequest struct {
 
 // RoleResponse represents the response payload for role data
 type RoleResponse struct {
-	ID          uint                 `json:"id"`
-	Name        string               `json:"name"`
-	Description string               `json:"description"`
-	IsActive    bool                 `json:"is_active"`
-	CreatedAt   time.Time            `json:"created_at"`
-	UpdatedAt   time.Time            `json:"updated_at"`
-	Permissions []PermissionResponse `json:"permissions,omitempty"`
+	ID           uint                 `json:"id"`
+	Name         string               `json:"name"`
+	Description  string               `json:"description"`
+	IsActive     bool                 `json:"is_active"`
+	ParentRoleID *uint                `json:"parent_role_id"`
+	CreatedAt    time.Time            `json:"created_at"`
+	UpdatedAt    time.Time            `json:"updated_at"`
+	Permissions  []PermissionResponse `json:"permissions,omitempty"`
 }
 
 // PermissionResponse represents the response payload for permission data
@@ -146,12 +151,13 @@ type PermissionResponse struct {
 // ToResponse converts Role model to RoleResponse
 func (r *Role) ToResponse() *RoleResponse {
 	resp := &RoleResponse{
-		ID:          r.ID,
-		Name:        r.Name,
-		Description: r.Description,
-		IsActive:    r.IsActive,
-		CreatedAt:   r.CreatedAt,
-		UpdatedAt:   r.UpdatedAt,
+		ID:           r.ID,
+		Name:         r.Name,
+		Description:  r.Description,
+		IsActive:     r.IsActive,
+		ParentRoleID: r.ParentRoleID,
+		CreatedAt:    r.CreatedAt,
+		UpdatedAt:    r.UpdatedAt,
 	}
 
 	// Convert permissions if loaded
@@ -185,23 +191,82 @@ const (
 	PermissionUserRead   = "user.read"
 	PermissionUserUpdate = "user.update"
 	PermissionUserDelete = "user.delete"
+	PermissionUserList   = "user.list"
 
 	// Role permissions
 	PermissionRoleCreate = "role.create"
 	PermissionRoleRead   = "role.read"
 	PermissionRoleUpdate = "role.update"
 	PermissionRoleDelete = "role.delete"
+	PermissionRoleList   = "role.list"
 
 	// Permission permissions
 	PermissionPermissionCreate = "permission.create"
 	PermissionPermissionRead   = "permission.read"
 	PermissionPermissionUpdate = "permission.update"
 	PermissionPermissionDelete = "permission.delete"
+	PermissionPermissionList   = "permission.list"
 )
 
+// KnownPermissions lists the permission constants above. Add new constants here too so that
+// they show up in the permission catalog.
+var KnownPermissions = []string{
+	PermissionUserCreate, PermissionUserRead, PermissionUserUpdate, PermissionUserDelete, PermissionUserList,
+	PermissionRoleCreate, PermissionRoleRead, PermissionRoleUpdate, PermissionRoleDelete, PermissionRoleList,
+	PermissionPermissionCreate, PermissionPermissionRead, PermissionPermissionUpdate, PermissionPermissionDelete, PermissionPermissionList,
+}
+
+// PermissionCatalogEntry is a resource/action pair known to the application
+type PermissionCatalogEntry struct {
+	Name     string `json:"name"`
+	Resource string `json:"resource"`
+	Action   string `json:"action"`
+}
+
+// PermissionCatalog returns the resource/action pairs of the known permissions, which are
+// named resource.action
+func PermissionCatalog() []PermissionCatalogEntry {
+	catalog := make([]PermissionCatalogEntry, len(KnownPermissions))
+	for i, name := range KnownPermissions {
+		resource, action, _ := strings.Cut(name, ".")
+		catalog[i] = PermissionCatalogEntry{Name: name, Resource: resource, Action: action}
+	}
+	return catalog
+}
+
 // Common role constants
 const (
 	RoleAdmin     = "admin"
 	RoleModerator = "moderator"
 	RoleUser      = "user"
 )
+
+// DefaultRole is a role created by the seed command, with the permissions it grants
+type DefaultRole struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// DefaultRoles are the roles the seed command creates, matching the roles migration
+var DefaultRoles = []DefaultRole{
+	{
+		Name:        RoleAdmin,
+		Description: "System administrator with full access",
+		Permissions: KnownPermissions,
+	},
+	{
+		Name:        RoleModerator,
+		Description: "Moderator with limited administrative access",
+		Permissions: []string{
+			PermissionUserRead, PermissionUserUpdate, PermissionUserList,
+			PermissionRoleRead, PermissionRoleList,
+			PermissionPermissionRead, PermissionPermissionList,
+		},
+	},
+	{
+		Name:        RoleUser,
+		Description: "Regular user with basic access",
+		Permissions: []string{PermissionUserRead},
+	},
+}
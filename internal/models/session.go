@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// Session is a signed-in device. It is the family of refresh tokens issued from one login,
+// with where the login came from and when the session last refreshed its tokens.
+type Session struct {
+	ID         uint       `json:"id" gorm:"primaryKey"`
+	UserID     uint       `json:"-" gorm:"index;not null"`
+	FamilyID   string     `json:"-" gorm:"uniqueIndex;not null;size:64"`
+	UserAgent  string     `json:"user_agent" gorm:"size:255"`
+	IPAddress  string     `json:"ip_address" gorm:"size:45"` // Of the latest refresh
+	LastUsedAt time.Time  `json:"last_used_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`                       // When the current refresh token expires
+	RememberMe bool       `json:"remember_me" gorm:"default:false"` // Refresh tokens last JWT_REMEMBER_ME_EXPIRY
+	RevokedAt  *time.Time `json:"-"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// TableName specifies the table name for the Session model
+func (Session) TableName() string {
+	return "sessions"
+}
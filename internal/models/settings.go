@@ -0,0 +1,41 @@
+package models
+
+import "time"
+
+// Defaults of users who haven't saved their settings
+const (
+	DefaultTimezone = "UTC"
+	DefaultLocale   = "en"
+)
+
+// UserSettings holds a user's preferences. Users get the defaults until they first save them.
+type UserSettings struct {
+	UserID        uint            `json:"-" gorm:"primaryKey;autoIncrement:false"`
+	Timezone      string          `json:"timezone" gorm:"not null;size:64"`               // IANA name such as Europe/Berlin
+	Locale        string          `json:"locale" gorm:"not null;size:35"`                 // BCP 47 language tag
+	Notifications map[string]bool `json:"notifications" gorm:"serializer:json;type:text"` // Keyed by notification type, left to the app
+	UpdatedAt     time.Time       `json:"updated_at"`
+}
+
+// TableName specifies the table name for the UserSettings model
+func (UserSettings) TableName() string {
+	return "user_settings"
+}
+
+// DefaultUserSettings returns the settings of a user who hasn't saved any
+func DefaultUserSettings(userID uint) *UserSettings {
+	return &UserSettings{
+		UserID:        userID,
+		Timezone:      DefaultTimezone,
+		Locale:        DefaultLocale,
+		Notifications: map[string]bool{},
+	}
+}
+
+// UserSettingsUpdateRequest represents the request payload for changing settings. Omitted
+// fields keep their value; notifications are merged into the saved ones.
+type UserSettingsUpdateRequest struct {
+	Timezone      *string         `json:"timezone,omitempty" validate:"omitempty,timezone"`
+	Locale        *string         `json:"locale,omitempty" validate:"omitempty,bcp47_language_tag"`
+	Notifications map[string]bool `json:"notifications,omitempty" validate:"omitempty,max=50,dive,keys,min=1,max=64,endkeys"`
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"regexp"
+	"time"
+)
+
+// Tag labels users for segmentation, such as beta testers or VIPs. Tags are managed by admins and
+// aren't shown to the users they label.
+type Tag struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	TenantID    string    `json:"-" gorm:"uniqueIndex:idx_tags_name_tenant,priority:2;not null;default:'';size:64"` // Empty for the default tenant
+	Name        string    `json:"name" gorm:"uniqueIndex:idx_tags_name_tenant,priority:1;not null;size:50"`
+	Description string    `json:"description" gorm:"size:255"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for the Tag model
+func (Tag) TableName() string {
+	return "tags"
+}
+
+// UserTag is the join table between users and tags
+type UserTag struct {
+	UserID    uint      `gorm:"primaryKey"`
+	TagID     uint      `gorm:"primaryKey;index"`
+	CreatedAt time.Time // When the user was tagged
+}
+
+// TableName specifies the table name for the UserTag model
+func (UserTag) TableName() string {
+	return "user_tags"
+}
+
+// tagName matches valid tag names, which can be given as a comma separated list to filter users
+var tagName = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// ValidTagName reports whether name, once lowercased, can be used as a tag name, such as
+// "beta-testers"
+func ValidTagName(name string) bool {
+	return tagName.MatchString(name)
+}
+
+// TagCreateRequest represents the request payload for creating a tag. Names are lowercased.
+type TagCreateRequest struct {
+	Name        string `json:"name" validate:"required,min=1,max=50"`
+	Description string `json:"description" validate:"max=255"`
+}
+
+// TagUpdateRequest represents the request payload for updating a tag
+type TagUpdateRequest struct {
+	Name        *string `json:"name,omitempty" validate:"omitempty,min=1,max=50"`
+	Description *string `json:"description,omitempty" validate:"omitempty,max=255"`
+}
+
+// UserTagsRequest represents the request payload for tagging a user
+type UserTagsRequest struct {
+	TagIDs []uint `json:"tag_ids" validate:"required,min=1,max=50,dive,required"`
+}
@@ -0,0 +1,136 @@
+package models
+
+import "time"
+
+// Upload purposes accepted by the chunked upload API
+const (
+	UploadPurposeAvatar = "avatar"
+	UploadPurposeImport = "import"
+)
+
+// Upload session statuses
+const (
+	UploadStatusPending   = "pending"
+	UploadStatusCompleted = "completed"
+	UploadStatusAborted   = "aborted"
+	UploadStatusExpired   = "expired"
+	// UploadStatusQuarantined marks uploads the malware scanner flagged
+	UploadStatusQuarantined = "quarantined"
+)
+
+// UploadSession tracks a resumable chunked upload.
+// Parts are stored as temporary objects and assembled into ObjectKey on completion.
+// Direct sessions are uploaded by the client straight to ObjectKey with a presigned URL instead.
+type UploadSession struct {
+	ID          string    `json:"id" gorm:"primaryKey;size:64"`
+	UserID      uint      `json:"user_id" gorm:"index;not null"`
+	Purpose     string    `json:"purpose" gorm:"not null;size:50"`
+	Filename    string    `json:"filename" gorm:"not null;size:255"`
+	ContentType string    `json:"content_type" gorm:"size:255"`
+	TotalSize   int64     `json:"total_size" gorm:"not null"`
+	ChunkSize   int64     `json:"chunk_size" gorm:"not null"`
+	Status      string    `json:"status" gorm:"not null;size:20;default:pending"`
+	Direct      bool      `json:"direct" gorm:"not null;default:false"`
+	ObjectKey   string    `json:"object_key" gorm:"size:512"`
+	Checksum    string    `json:"checksum" gorm:"size:64"` // SHA-256 of the assembled file
+	FileID      *uint     `json:"file_id"`                 // File created on completion
+	ExpiresAt   time.Time `json:"expires_at" gorm:"index"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// TableName specifies the table name for the UploadSession model
+func (UploadSession) TableName() string {
+	return "upload_sessions"
+}
+
+// TotalParts returns the number of parts the upload is split into
+func (s *UploadSession) TotalParts() int {
+	return int((s.TotalSize + s.ChunkSize - 1) / s.ChunkSize)
+}
+
+// PartSize returns the expected size of a part; only the last part may be shorter than ChunkSize
+func (s *UploadSession) PartSize(partNumber int) int64 {
+	if partNumber == s.TotalParts() {
+		return s.TotalSize - s.ChunkSize*int64(partNumber-1)
+	}
+	return s.ChunkSize
+}
+
+// UploadPart records a received part of an upload session
+type UploadPart struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	SessionID  string    `json:"session_id" gorm:"uniqueIndex:idx_upload_parts_session_part;not null;size:64"`
+	PartNumber int       `json:"part_number" gorm:"uniqueIndex:idx_upload_parts_session_part;not null"`
+	Size       int64     `json:"size" gorm:"not null"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// TableName specifies the table name for the UploadPart model
+func (UploadPart) TableName() string {
+	return "upload_parts"
+}
+
+// UploadInitiateRequest represents the request payload for starting a chunked upload
+type UploadInitiateRequest struct {
+	Filename    string `json:"filename" validate:"required,max=255"`
+	ContentType string `json:"content_type" validate:"omitempty,max=255"`
+	Size        int64  `json:"size" validate:"required,gt=0"`
+	Purpose     string `json:"purpose" validate:"required,oneof=avatar import"`
+}
+
+// UploadCompleteRequest represents the request payload for completing a chunked upload
+type UploadCompleteRequest struct {
+	Checksum string `json:"checksum,omitempty" validate:"omitempty,len=64,hexadecimal"` // Optional SHA-256 of the whole file
+}
+
+// UploadSessionResponse represents the state of an upload session
+type UploadSessionResponse struct {
+	ID            string    `json:"id"`
+	Purpose       string    `json:"purpose"`
+	Filename      string    `json:"filename"`
+	ContentType   string    `json:"content_type"`
+	TotalSize     int64     `json:"total_size"`
+	ChunkSize     int64     `json:"chunk_size"`
+	TotalParts    int       `json:"total_parts"`
+	ReceivedParts []int     `json:"received_parts"`
+	Status        string    `json:"status"`
+	Direct        bool      `json:"direct"`
+	ObjectKey     string    `json:"object_key,omitempty"`
+	Checksum      string    `json:"checksum,omitempty"`
+	FileID        *uint     `json:"file_id,omitempty"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// ToResponse converts an UploadSession and its received parts to UploadSessionResponse
+func (s *UploadSession) ToResponse(parts []*UploadPart) *UploadSessionResponse {
+	received := make([]int, len(parts))
+	for i, part := range parts {
+		received[i] = part.PartNumber
+	}
+	return &UploadSessionResponse{
+		ID:            s.ID,
+		Purpose:       s.Purpose,
+		Filename:      s.Filename,
+		ContentType:   s.ContentType,
+		TotalSize:     s.TotalSize,
+		ChunkSize:     s.ChunkSize,
+		TotalParts:    s.TotalParts(),
+		ReceivedParts: received,
+		Status:        s.Status,
+		Direct:        s.Direct,
+		ObjectKey:     s.ObjectKey,
+		Checksum:      s.Checksum,
+		FileID:        s.FileID,
+		ExpiresAt:     s.ExpiresAt,
+	}
+}
+
+// UploadPresignResponse carries the presigned URL a client uploads a file to directly
+type UploadPresignResponse struct {
+	Upload    *UploadSessionResponse `json:"upload"`
+	URL       string                 `json:"url"`
+	Method    string                 `json:"method"`
+	Headers   map[string]string      `json:"headers"` // Must be sent exactly as given
+	ExpiresAt time.Time              `json:"expires_at"`
+}
@@ -1,25 +1,64 @@
 package models
 
 import (
+	"reflect"
+	"strconv"
 	"time"
 
+	"gbt-be-template/pkg/fieldcrypt"
+
 	"gorm.io/gorm"
 )
 
 // User represents a user in the system
 type User struct {
 	ID        uint           `json:"id" gorm:"primaryKey"`
-	Email     string         `json:"email" gorm:"uniqueIndex;not null;size:255"`
-	Username  string         `json:"username" gorm:"uniqueIndex;not null;size:100"`
+	Email     string         `json:"email" gorm:"not null;type:text;serializer:encrypted"` // Looked up through EmailIndex
+	Username  string         `json:"username" gorm:"uniqueIndex:idx_users_username_tenant,priority:1;not null;size:100"`
 	Password  string         `json:"-" gorm:"not null;size:255"` // "-" excludes from JSON
 	FirstName string         `json:"first_name" gorm:"size:100"`
 	LastName  string         `json:"last_name" gorm:"size:100"`
 	IsActive  bool           `json:"is_active" gorm:"default:true"`
-	IsAdmin   bool           `json:"is_admin" gorm:"default:false"`
+	IsAdmin   bool           `json:"is_admin" gorm:"default:false"` // Mirrors the admin role
 	LastLogin *time.Time     `json:"last_login"`
-	CreatedAt time.Time      `json:"created_at"`
+	CreatedAt time.Time      `json:"created_at" gorm:"index"`
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"` // Soft delete
+
+	// Incremented by every update, which fails when the user was updated since it was read
+	Version uint `json:"-" gorm:"not null;default:1"`
+
+	// Tenant the user belongs to, empty for the default tenant. Emails and usernames are unique per tenant.
+	TenantID string `json:"-" gorm:"uniqueIndex:idx_users_username_tenant,priority:2;uniqueIndex:idx_users_email_index,priority:2;not null;default:'';size:64"`
+
+	EmailIndex   string   `json:"-" gorm:"uniqueIndex:idx_users_email_index,priority:1;size:64"` // Blind index of Email, kept in sync by the save hooks
+	PendingEmail string   `json:"-" gorm:"type:text;serializer:encrypted"`                       // Replaces Email once confirmed from the new address
+	Phone        string   `json:"-" gorm:"type:text;serializer:encrypted"`                       // Looked up through PhoneIndex for SMS login
+	PhoneIndex   string   `json:"-" gorm:"index;size:64"`                                        // Blind index of Phone, not unique since numbers can be shared
+	Metadata     Metadata `json:"-" gorm:"type:text;serializer:encrypted"`                       // Custom attributes, encrypted so stored as text
+
+	FailedLoginAttempts int        `json:"-" gorm:"default:0"`
+	LockedUntil         *time.Time `json:"-"`
+	MustChangePassword  bool       `json:"-" gorm:"default:false"` // Set by an admin; other API calls are refused until the password is changed
+	PasswordChangedAt   *time.Time `json:"-"`                      // Nil for accounts that never set a local password
+
+	TOTPSecret   string `json:"-" gorm:"type:text;serializer:encrypted"` // Stored on enrollment, required at login once TOTPEnabled
+	TOTPEnabled  bool   `json:"-" gorm:"default:false"`
+	TOTPLastStep int64  `json:"-" gorm:"default:0"` // Time step of the last accepted code, which can't be used again
+
+	AvatarFileID *uint             `json:"-"`                                  // Source image of the current avatar
+	AvatarURLs   map[string]string `json:"-" gorm:"serializer:json;type:text"` // Processed variants keyed by size
+
+	SuspendedAt      *time.Time `json:"-"`
+	SuspendedUntil   *time.Time `json:"-" gorm:"index"` // Nil while suspended means until lifted by an admin
+	SuspendedBy      *uint      `json:"-"`              // Admin who suspended the account
+	SuspensionReason string     `json:"-" gorm:"size:500"`
+
+	DeactivatedAt *time.Time `json:"-"`
+	DeactivatedBy *uint      `json:"-"` // The user themselves for voluntary deactivation, otherwise an admin
+
+	DeletionRequestedAt *time.Time `json:"-"`
+	DeletionScheduledAt *time.Time `json:"-" gorm:"index"` // Anonymized at this time unless the user logs in again
 }
 
 // TableName specifies the table name for the User model
@@ -27,6 +66,55 @@ func (User) TableName() string {
 	return "users"
 }
 
+// IsAnonymized reports whether the account's personal data was erased after a self-service
+// deletion. Every other account has an email and so an email index.
+func (u *User) IsAnonymized() bool {
+	return u.EmailIndex == ""
+}
+
+// SetActive activates or deactivates the account, recording who deactivated it
+func (u *User) SetActive(active bool, actorID uint) {
+	if active == u.IsActive {
+		return
+	}
+	u.IsActive = active
+	if active {
+		u.DeactivatedAt = nil
+		u.DeactivatedBy = nil
+		return
+	}
+	now := time.Now()
+	u.DeactivatedAt = &now
+	u.DeactivatedBy = &actorID
+}
+
+// DeactivatedVoluntarily reports whether the user deactivated the account themselves
+func (u *User) DeactivatedVoluntarily() bool {
+	return !u.IsActive && u.DeactivatedBy != nil && *u.DeactivatedBy == u.ID
+}
+
+// IsSuspended reports whether the account is suspended at the given time
+func (u *User) IsSuspended(now time.Time) bool {
+	return u.SuspendedAt != nil && (u.SuspendedUntil == nil || now.Before(*u.SuspendedUntil))
+}
+
+// PasswordExpired reports whether the password is older than maxAge at the given time. A zero
+// maxAge disables expiry.
+func (u *User) PasswordExpired(now time.Time, maxAge time.Duration) bool {
+	return maxAge > 0 && u.PasswordChangedAt != nil && !now.Before(u.PasswordChangedAt.Add(maxAge))
+}
+
+// UserFilter narrows down the users listed. Zero fields match any user.
+type UserFilter struct {
+	Search        string // Every word starts the username, first or last name, or the whole search is the email
+	Deleted       bool   // Only soft-deleted users, which are left out otherwise
+	IsActive      *bool
+	IsAdmin       *bool
+	CreatedAfter  *time.Time // Inclusive
+	CreatedBefore *time.Time // Exclusive
+	Tags          []string   // Names of tags users must all have
+}
+
 // UserCreateRequest represents the request payload for creating a user
 type UserCreateRequest struct {
 	Email     string `json:"email" validate:"required,email"`
@@ -34,15 +122,23 @@ type UserCreateRequest struct {
 	Password  string `json:"password" validate:"required,min=6"`
 	FirstName string `json:"first_name" validate:"required,min=1,max=100"`
 	LastName  string `json:"last_name" validate:"required,min=1,max=100"`
+
+	Phone    string   `json:"phone,omitempty" validate:"omitempty,e164"`
+	Metadata Metadata `json:"metadata,omitempty"`
 }
 
 // UserUpdateRequest represents the request payload for updating a user
 type UserUpdateRequest struct {
-	Email     *string `json:"email,omitempty" validate:"omitempty,email"`
+	Email     *string `json:"email,omitempty" validate:"omitempty,email"` // Applied once confirmed from the new address
 	Username  *string `json:"username,omitempty" validate:"omitempty,min=3,max=50"`
 	FirstName *string `json:"first_name,omitempty" validate:"omitempty,min=1,max=100"`
 	LastName  *string `json:"last_name,omitempty" validate:"omitempty,min=1,max=100"`
 	IsActive  *bool   `json:"is_active,omitempty"`
+
+	Phone    *string  `json:"phone,omitempty" validate:"omitempty,e164|len=0"` // An empty string removes the phone number
+	Metadata Metadata `json:"metadata,omitempty"`                              // Replaces the stored metadata
+
+	Version *uint `json:"version,omitempty"` // Version of the user the update is based on, refused with a conflict once the user changed
 }
 
 // AdminUserUpdateRequest represents the request payload for admin updating a user
@@ -52,13 +148,98 @@ type AdminUserUpdateRequest struct {
 	FirstName *string `json:"first_name,omitempty" validate:"omitempty,min=1,max=100"`
 	LastName  *string `json:"last_name,omitempty" validate:"omitempty,min=1,max=100"`
 	IsActive  *bool   `json:"is_active,omitempty"`
-	IsAdmin   *bool   `json:"is_admin,omitempty"` // Only admins can modify this
+	IsAdmin   *bool   `json:"is_admin,omitempty"` // Deprecated: assign or remove the admin role instead
+
+	Phone    *string  `json:"phone,omitempty" validate:"omitempty,e164|len=0"`
+	Metadata Metadata `json:"metadata,omitempty"`
+	RoleIDs  []uint   `json:"role_ids,omitempty" validate:"omitempty,max=50,dive,min=1"` // Replaces the user's roles; an empty list removes them all
+
+	Version *uint `json:"version,omitempty"` // As in UserUpdateRequest
+}
+
+// UserPatchDocument is the editable representation of a user that PATCH requests apply to.
+// Every member is present so JSON patches can address it; phone and metadata are cleared by null
+// or removal, the other members are required.
+type UserPatchDocument struct {
+	Email     string   `json:"email" validate:"required,email"`
+	Username  string   `json:"username" validate:"required,min=3,max=50"`
+	FirstName string   `json:"first_name" validate:"required,min=1,max=100"`
+	LastName  string   `json:"last_name" validate:"required,min=1,max=100"`
+	IsActive  *bool    `json:"is_active" validate:"required"`
+	Phone     *string  `json:"phone" validate:"omitempty,e164"`
+	Metadata  Metadata `json:"metadata"`
+	Version   uint     `json:"version"` // Patching it in applies the patch only to that version of the user
+}
+
+// NewUserPatchDocument returns the patch document of a user
+func NewUserPatchDocument(u *UserResponse) *UserPatchDocument {
+	doc := &UserPatchDocument{
+		Email:     u.Email,
+		Username:  u.Username,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		IsActive:  &u.IsActive,
+		Metadata:  u.Metadata,
+		Version:   u.Version,
+	}
+	if u.Phone != "" {
+		doc.Phone = &u.Phone
+	}
+	return doc
+}
+
+// UpdateRequest returns the update turning the user u into the patched document
+func (d *UserPatchDocument) UpdateRequest(u *UserResponse) *UserUpdateRequest {
+	req := &UserUpdateRequest{Version: &d.Version}
+	if d.Email != u.Email {
+		req.Email = &d.Email
+	}
+	if d.Username != u.Username {
+		req.Username = &d.Username
+	}
+	if d.FirstName != u.FirstName {
+		req.FirstName = &d.FirstName
+	}
+	if d.LastName != u.LastName {
+		req.LastName = &d.LastName
+	}
+	if *d.IsActive != u.IsActive {
+		req.IsActive = d.IsActive
+	}
+
+	phone := ""
+	if d.Phone != nil {
+		phone = *d.Phone
+	}
+	if phone != u.Phone {
+		req.Phone = &phone
+	}
+
+	if (len(d.Metadata) > 0 || len(u.Metadata) > 0) && !reflect.DeepEqual(d.Metadata, u.Metadata) {
+		// A non-nil empty map clears the metadata, nil would leave it unchanged
+		req.Metadata = d.Metadata
+		if req.Metadata == nil {
+			req.Metadata = Metadata{}
+		}
+	}
+	return req
 }
 
 // UserLoginRequest represents the request payload for user login
 type UserLoginRequest struct {
-	Email    string `json:"email" validate:"required,email"`
-	Password string `json:"password" validate:"required"`
+	Identifier  string `json:"identifier,omitempty" validate:"required_without=Email,omitempty,max=255"` // Email or username
+	Email       string `json:"email,omitempty" validate:"required_without=Identifier,omitempty,email"`
+	Password    string `json:"password" validate:"required"`
+	RememberMe  bool   `json:"remember_me,omitempty"`                               // Issue a refresh token lasting JWT_REMEMBER_ME_EXPIRY
+	DeviceToken string `json:"device_token,omitempty" validate:"omitempty,max=255"` // Returned by an earlier login on this device
+}
+
+// Login returns the email or username the user is logging in with
+func (r *UserLoginRequest) Login() string {
+	if r.Identifier != "" {
+		return r.Identifier
+	}
+	return r.Email
 }
 
 // UserResponse represents the response payload for user data
@@ -73,11 +254,22 @@ type UserResponse struct {
 	LastLogin *time.Time `json:"last_login"`
 	CreatedAt time.Time  `json:"created_at"`
 	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"` // Only soft-deleted users, which only admins can list
+	Version   uint       `json:"version"`              // Send it back with updates to refuse them once the user changed
+
+	AvatarURL    string            `json:"avatar_url,omitempty"` // Largest of AvatarURLs
+	AvatarURLs   map[string]string `json:"avatar_urls,omitempty"`
+	Phone        string            `json:"phone,omitempty"`
+	Metadata     Metadata          `json:"metadata,omitempty"`
+	PendingEmail string            `json:"pending_email,omitempty"` // Awaiting confirmation, email stays in use until then
+
+	TwoFactorEnabled   bool `json:"two_factor_enabled"`
+	MustChangePassword bool `json:"must_change_password"`
 }
 
 // ToResponse converts User model to UserResponse
 func (u *User) ToResponse() *UserResponse {
-	return &UserResponse{
+	resp := &UserResponse{
 		ID:        u.ID,
 		Email:     u.Email,
 		Username:  u.Username,
@@ -88,17 +280,216 @@ func (u *User) ToResponse() *UserResponse {
 		LastLogin: u.LastLogin,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
+		Version:   u.Version,
+
+		AvatarURL:    largestAvatarURL(u.AvatarURLs),
+		AvatarURLs:   u.AvatarURLs,
+		Phone:        u.Phone,
+		Metadata:     u.Metadata,
+		PendingEmail: u.PendingEmail,
+
+		TwoFactorEnabled:   u.TOTPEnabled,
+		MustChangePassword: u.MustChangePassword,
+	}
+	if u.DeletedAt.Valid {
+		resp.DeletedAt = &u.DeletedAt.Time
+	}
+	return resp
+}
+
+// largestAvatarURL returns the URL of the largest avatar variant, whose keys are sizes in pixels
+func largestAvatarURL(urls map[string]string) string {
+	largest, url := -1, ""
+	for key, value := range urls {
+		if size, err := strconv.Atoi(key); err == nil && size > largest {
+			largest, url = size, value
+		}
 	}
+	return url
+}
+
+// UserSuspendRequest represents the request payload for suspending a user
+type UserSuspendRequest struct {
+	Reason string     `json:"reason" validate:"required,min=3,max=500"`
+	Until  *time.Time `json:"until,omitempty"` // Lifted automatically at this time; omit to suspend until lifted by an admin
+}
+
+// AccountMergeRequest represents the request payload for merging a duplicate account into a user
+type AccountMergeRequest struct {
+	DuplicateID uint `json:"duplicate_id" validate:"required"` // Deactivated once merged
+}
+
+// SuspensionResponse describes an account suspension to admins
+type SuspensionResponse struct {
+	Reason      string     `json:"reason"`
+	SuspendedBy *uint      `json:"suspended_by"`
+	SuspendedAt time.Time  `json:"suspended_at"`
+	Until       *time.Time `json:"until"`
+}
+
+// AdminUserResponse is the user data shown to admins, including moderation state
+type AdminUserResponse struct {
+	*UserResponse
+	Suspension *SuspensionResponse `json:"suspension"`
+
+	DeactivatedAt       *time.Time `json:"deactivated_at"`
+	DeactivatedBy       *uint      `json:"deactivated_by"`
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at"` // Set while a self-service deletion is pending
+}
+
+// ToAdminResponse converts User model to AdminUserResponse
+func (u *User) ToAdminResponse() *AdminUserResponse {
+	resp := &AdminUserResponse{
+		UserResponse:        u.ToResponse(),
+		DeactivatedAt:       u.DeactivatedAt,
+		DeactivatedBy:       u.DeactivatedBy,
+		DeletionScheduledAt: u.DeletionScheduledAt,
+	}
+	if u.IsSuspended(time.Now()) {
+		resp.Suspension = &SuspensionResponse{
+			Reason:      u.SuspensionReason,
+			SuspendedBy: u.SuspendedBy,
+			SuspendedAt: *u.SuspendedAt,
+			Until:       u.SuspendedUntil,
+		}
+	}
+	return resp
+}
+
+// PasswordChangeRequest represents the request payload for changing one's own password
+type PasswordChangeRequest struct {
+	CurrentPassword string `json:"current_password" validate:"required,max=72"`
+	NewPassword     string `json:"new_password" validate:"required,min=6,max=72"` // bcrypt ignores bytes after the 72nd
+}
+
+// AccountDeleteRequest represents the request payload for deleting one's own account
+type AccountDeleteRequest struct {
+	Password string `json:"password" validate:"required"` // Confirms the request comes from the account owner
+}
+
+// TwoFactorEnrollResponse carries a new TOTP secret for the user to add to an authenticator app
+type TwoFactorEnrollResponse struct {
+	Secret          string `json:"secret"`
+	ProvisioningURI string `json:"provisioning_uri"` // otpauth:// URI to render as a QR code
+}
+
+// TwoFactorCodeRequest represents the request payload for confirming a TOTP enrollment
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// TwoFactorVerifyRequest represents the request payload for the second step of a login
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required,max=255"`
+	Code           string `json:"code,omitempty" validate:"required_without=RecoveryCode,omitempty,len=6,numeric"`
+	RecoveryCode   string `json:"recovery_code,omitempty" validate:"omitempty,excluded_with=Code,max=32"` // In place of a code when the authenticator app is lost
+	DeviceToken    string `json:"device_token,omitempty" validate:"omitempty,max=255"`
+	TrustDevice    bool   `json:"trust_device,omitempty"` // Skip the code on this device for TOTP_TRUSTED_DEVICE_TTL
+}
+
+// LoginVerifyRequest represents the request payload for confirming a suspicious login with the emailed link
+type LoginVerifyRequest struct {
+	Token       string `json:"token" validate:"required,max=255"`
+	DeviceToken string `json:"device_token,omitempty" validate:"omitempty,max=255"`
+}
+
+// TwoFactorDisableRequest represents the request payload for turning two-factor authentication off
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" validate:"required"`
+	Code     string `json:"code" validate:"required,len=6,numeric"`
+}
+
+// AccountDeletionResponse tells the user when their account will be deleted
+type AccountDeletionResponse struct {
+	ScheduledAt time.Time `json:"scheduled_at"`
+}
+
+// AvatarSetRequest represents the request payload for setting a user's avatar from an uploaded file
+type AvatarSetRequest struct {
+	FileID uint `json:"file_id" validate:"required"`
+}
+
+// UserCSVHeader is the header row of user CSV exports, listing every column in its default order
+var UserCSVHeader = []string{"id", "email", "username", "first_name", "last_name", "is_active", "is_admin", "last_login", "created_at"}
+
+// userCSVColumns renders each column of UserCSVHeader
+var userCSVColumns = map[string]func(*UserResponse) string{
+	"id":         func(u *UserResponse) string { return strconv.FormatUint(uint64(u.ID), 10) },
+	"email":      func(u *UserResponse) string { return u.Email },
+	"username":   func(u *UserResponse) string { return u.Username },
+	"first_name": func(u *UserResponse) string { return u.FirstName },
+	"last_name":  func(u *UserResponse) string { return u.LastName },
+	"is_active":  func(u *UserResponse) string { return strconv.FormatBool(u.IsActive) },
+	"is_admin":   func(u *UserResponse) string { return strconv.FormatBool(u.IsAdmin) },
+	"last_login": func(u *UserResponse) string {
+		if u.LastLogin == nil {
+			return ""
+		}
+		return u.LastLogin.Format(time.RFC3339)
+	},
+	"created_at": func(u *UserResponse) string { return u.CreatedAt.Format(time.RFC3339) },
+}
+
+// IsUserCSVColumn reports whether name is a column user CSV exports can select
+func IsUserCSVColumn(name string) bool {
+	_, ok := userCSVColumns[name]
+	return ok
+}
+
+// UserCSVRecord is a user reduced to a selection of export columns
+type UserCSVRecord struct {
+	User    *UserResponse
+	Columns []string // Names from UserCSVHeader
+}
+
+// CSVRow returns the user's values for the selected columns
+func (r UserCSVRecord) CSVRow() []string {
+	row := make([]string, len(r.Columns))
+	for i, column := range r.Columns {
+		if value, ok := userCSVColumns[column]; ok {
+			row[i] = value(r.User)
+		}
+	}
+	return row
+}
+
+// CSVRow returns the user as a CSV row matching UserCSVHeader
+func (u *UserResponse) CSVRow() []string {
+	return UserCSVRecord{User: u, Columns: UserCSVHeader}.CSVRow()
 }
 
 // BeforeCreate is a GORM hook that runs before creating a user
 func (u *User) BeforeCreate(tx *gorm.DB) error {
-	// Any pre-creation logic can go here
+	u.setEmailIndex()
+	u.setPhoneIndex()
+	// The version must be known without RETURNING, which MySQL lacks, so it doesn't rely on the column default
+	if u.Version == 0 {
+		u.Version = 1
+	}
 	return nil
 }
 
 // BeforeUpdate is a GORM hook that runs before updating a user
 func (u *User) BeforeUpdate(tx *gorm.DB) error {
-	// Any pre-update logic can go here
+	u.setEmailIndex()
+	u.setPhoneIndex()
 	return nil
 }
+
+// setEmailIndex derives the blind index used to look users up by their encrypted email.
+// Partial updates that do not load the email leave the index alone.
+func (u *User) setEmailIndex() {
+	if u.Email != "" {
+		u.EmailIndex = fieldcrypt.Default().BlindIndex(u.Email)
+	}
+}
+
+// setPhoneIndex derives the blind index used to look users up by their encrypted phone number.
+// Unlike the email, the phone can be removed, so an empty phone clears the index. Partial
+// updates only write the index when they select it or it is non-zero.
+func (u *User) setPhoneIndex() {
+	u.PhoneIndex = ""
+	if u.Phone != "" {
+		u.PhoneIndex = fieldcrypt.Default().BlindIndex(u.Phone)
+	}
+}
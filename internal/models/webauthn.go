@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WebAuthnCredential is a passkey or security key registered by a user
+type WebAuthnCredential struct {
+	ID              uint       `json:"id" gorm:"primaryKey"`
+	UserID          uint       `json:"-" gorm:"index;not null"`
+	CredentialID    []byte     `json:"-" gorm:"uniqueIndex;not null"`
+	PublicKey       []byte     `json:"-" gorm:"not null"` // COSE encoded
+	AttestationType string     `json:"-" gorm:"size:32"`
+	Transports      []string   `json:"-" gorm:"serializer:json"`
+	Flags           uint8      `json:"-" gorm:"default:0"` // Authenticator data flags seen at registration
+	AAGUID          []byte     `json:"-"`
+	SignCount       uint32     `json:"-" gorm:"default:0"`
+	CloneWarning    bool       `json:"-" gorm:"default:false"` // Set when the signature counter went backwards
+	Name            string     `json:"name" gorm:"size:100"`
+	LastUsedAt      *time.Time `json:"last_used_at"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+// TableName specifies the table name for the WebAuthnCredential model
+func (WebAuthnCredential) TableName() string {
+	return "webauthn_credentials"
+}
+
+// WebAuthnCredentialResponse represents a registered credential in API responses
+type WebAuthnCredentialResponse struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	Synced     bool       `json:"synced"` // Backed up by a passkey provider, such as iCloud Keychain
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// ToResponse converts a WebAuthnCredential to WebAuthnCredentialResponse
+func (c *WebAuthnCredential) ToResponse() *WebAuthnCredentialResponse {
+	return &WebAuthnCredentialResponse{
+		ID:         c.ID,
+		Name:       c.Name,
+		Synced:     c.Flags&webAuthnFlagBackupState != 0,
+		LastUsedAt: c.LastUsedAt,
+		CreatedAt:  c.CreatedAt,
+	}
+}
+
+// webAuthnFlagBackupState is the BS bit of the authenticator data flags
+const webAuthnFlagBackupState = 0x10
+
+// WebAuthnBeginResponse carries the options to pass to navigator.credentials
+type WebAuthnBeginResponse struct {
+	SessionID string      `json:"session_id"` // Sent back with the result of the ceremony
+	Options   interface{} `json:"options"`
+}
+
+// WebAuthnRegisterRequest represents the request payload for finishing a passkey registration
+type WebAuthnRegisterRequest struct {
+	SessionID  string          `json:"session_id" validate:"required,max=255"`
+	Name       string          `json:"name" validate:"omitempty,max=100"`
+	Credential json.RawMessage `json:"credential" validate:"required"` // PublicKeyCredential from navigator.credentials.create
+}
+
+// WebAuthnLoginRequest represents the request payload for finishing a passkey login
+type WebAuthnLoginRequest struct {
+	SessionID   string          `json:"session_id" validate:"required,max=255"`
+	Credential  json.RawMessage `json:"credential" validate:"required"`                      // PublicKeyCredential from navigator.credentials.get
+	DeviceToken string          `json:"device_token,omitempty" validate:"omitempty,max=255"` // Returned by an earlier login on this device
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+
+	"gbt-be-template/internal/models"
+)
+
+// accessRuleRepository implements the AccessRuleRepository interface. Create, GetByID, Update
+// and Delete come from the base repository.
+type accessRuleRepository struct {
+	Repository[models.AccessRule]
+}
+
+// NewAccessRuleRepository creates a new access rule repository
+func NewAccessRuleRepository(db *Database) AccessRuleRepository {
+	return &accessRuleRepository{
+		Repository: NewRepository[models.AccessRule](db),
+	}
+}
+
+// GetByName retrieves an access rule by its unique name
+func (r *accessRuleRepository) GetByName(ctx context.Context, name string) (*models.AccessRule, error) {
+	return r.FindOne(ctx, "name = ?", name)
+}
+
+// List retrieves all access rules, ordered by resource, action and name
+func (r *accessRuleRepository) List(ctx context.Context) ([]*models.AccessRule, error) {
+	var rules []*models.AccessRule
+	if err := r.db.DB.WithContext(ctx).Order("resource ASC, action ASC, name ASC").Find(&rules).Error; err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// ListActiveFor retrieves the active access rules applying to a resource and action, including
+// those with a wildcard resource or action
+func (r *accessRuleRepository) ListActiveFor(ctx context.Context, resource, action string) ([]*models.AccessRule, error) {
+	var rules []*models.AccessRule
+	err := r.db.DB.WithContext(ctx).
+		Where("is_active = ?", true).
+		Where("resource IN ?", []string{resource, models.AccessRuleWildcard}).
+		Where("action IN ?", []string{action, models.AccessRuleWildcard}).
+		Order("id ASC").
+		Find(&rules).Error
+	if err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessRuleRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAccessRuleRepository(db)
+	ctx := context.Background()
+
+	tenant := &models.AccessRule{Name: "same-tenant", Resource: "file", Action: "read", Effect: models.AccessRuleRequire, Condition: "user.tenant_id == resource.tenant_id", IsActive: true}
+	hours := &models.AccessRule{Name: "business-hours", Resource: models.AccessRuleWildcard, Action: models.AccessRuleWildcard, Effect: models.AccessRuleRequire, Condition: "env.hour >= 9 && env.hour < 17", IsActive: true}
+	other := &models.AccessRule{Name: "file-delete", Resource: "file", Action: "delete", Effect: models.AccessRuleAllow, Condition: "user.is_admin", IsActive: true}
+	for _, rule := range []*models.AccessRule{tenant, hours, other} {
+		require.NoError(t, repo.Create(ctx, rule))
+	}
+	assert.Error(t, repo.Create(ctx, &models.AccessRule{Name: "same-tenant", Resource: "file", Action: "read", Effect: models.AccessRuleAllow, Condition: "true"}))
+
+	found, err := repo.GetByName(ctx, "business-hours")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, hours.ID, found.ID)
+
+	// Wildcards match any resource and action
+	rules, err := repo.ListActiveFor(ctx, "file", "read")
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+	assert.Equal(t, tenant.ID, rules[0].ID)
+	assert.Equal(t, hours.ID, rules[1].ID)
+
+	// Inactive rules don't apply
+	hours.IsActive = false
+	require.NoError(t, repo.Update(ctx, hours))
+	rules, err = repo.ListActiveFor(ctx, "user", "list")
+	require.NoError(t, err)
+	assert.Empty(t, rules)
+
+	all, err := repo.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, all, 3)
+
+	deleted, err := repo.Delete(ctx, tenant.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, tenant.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	missing, err := repo.GetByID(ctx, tenant.ID)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// apiKeyRepository implements the APIKeyRepository interface
+type apiKeyRepository struct {
+	db *Database
+}
+
+// NewAPIKeyRepository creates a new API key repository
+func NewAPIKeyRepository(db *Database) APIKeyRepository {
+	return &apiKeyRepository{
+		db: db,
+	}
+}
+
+// Create stores a new API key
+func (r *apiKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	return r.db.DB.WithContext(ctx).Create(key).Error
+}
+
+// GetByHash retrieves an API key by the hash of its secret
+func (r *apiKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	var key models.APIKey
+	if err := r.db.DB.WithContext(ctx).Where("key_hash = ?", keyHash).First(&key).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &key, nil
+}
+
+// ListByUser retrieves all API keys of a user
+func (r *apiKeyRepository) ListByUser(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&keys).Error; err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// CountByUser counts the API keys of a user
+func (r *apiKeyRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.DB.WithContext(ctx).Model(&models.APIKey{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// RecordUse sets the last use of a key, skipping the write when it was recorded less than a minute ago
+func (r *apiKeyRepository) RecordUse(ctx context.Context, id uint, at time.Time) error {
+	return r.db.DB.WithContext(ctx).Model(&models.APIKey{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, at.Add(-time.Minute)).
+		Update("last_used_at", at).Error
+}
+
+// Delete removes an API key of a user and reports whether it existed
+func (r *apiKeyRepository) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.APIKey{})
+	return result.RowsAffected == 1, result.Error
+}
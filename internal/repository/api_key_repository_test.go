@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIKeyRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAPIKeyRepository(db)
+	ctx := context.Background()
+
+	key := &models.APIKey{UserID: 1, Name: "CI", Prefix: "gbt_abcd", KeyHash: "hash-1", Scopes: "users:read files:read"}
+	require.NoError(t, repo.Create(ctx, key))
+	require.NoError(t, repo.Create(ctx, &models.APIKey{UserID: 2, Name: "Other", Prefix: "gbt_efgh", KeyHash: "hash-2"}))
+
+	// Hashes are unique across users
+	assert.Error(t, repo.Create(ctx, &models.APIKey{UserID: 2, Name: "Copy", Prefix: "gbt_abcd", KeyHash: "hash-1"}))
+
+	found, err := repo.GetByHash(ctx, "hash-1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, []string{"users:read", "files:read"}, found.GetScopes())
+
+	missing, err := repo.GetByHash(ctx, "unknown")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	count, err := repo.CountByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Uses within a minute of the last recorded one are not written
+	used := time.Now().Truncate(time.Second)
+	require.NoError(t, repo.RecordUse(ctx, key.ID, used))
+	require.NoError(t, repo.RecordUse(ctx, key.ID, used.Add(30*time.Second)))
+	keys, err := repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, keys, 1)
+	require.NotNil(t, keys[0].LastUsedAt)
+	assert.True(t, used.Equal(*keys[0].LastUsedAt))
+
+	// Keys of other users can't be deleted
+	deleted, err := repo.Delete(ctx, 2, key.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = repo.Delete(ctx, 1, key.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+
+	"gbt-be-template/internal/models"
+)
+
+// auditEventRepository implements the AuditEventRepository interface
+type auditEventRepository struct {
+	db *Database
+}
+
+// NewAuditEventRepository creates a new audit event repository
+func NewAuditEventRepository(db *Database) AuditEventRepository {
+	return &auditEventRepository{
+		db: db,
+	}
+}
+
+// Create stores an audit event. Events are never updated or deleted.
+func (r *auditEventRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	return r.db.DB.WithContext(ctx).Create(event).Error
+}
+
+// ListByUser retrieves a page of a user's audit trail, newest first, and the total number of events
+func (r *auditEventRepository) ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.AuditEvent, int64, error) {
+	query := r.db.DB.WithContext(ctx).Model(&models.AuditEvent{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.AuditEvent
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditEventRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewAuditEventRepository(db)
+	ctx := context.Background()
+
+	actions := []string{models.AuditUserCreated, models.AuditProfileUpdated, models.AuditPasswordChanged}
+	for _, action := range actions {
+		require.NoError(t, repo.Create(ctx, &models.AuditEvent{UserID: 1, ActorID: 1, Action: action}))
+	}
+	require.NoError(t, repo.Create(ctx, &models.AuditEvent{
+		UserID:  2,
+		ActorID: 1,
+		Action:  models.AuditProfileUpdated,
+		Details: map[string]interface{}{"fields": []string{"first_name"}},
+	}))
+
+	// Newest first, paged
+	events, total, err := repo.ListByUser(ctx, 1, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.AuditPasswordChanged, events[0].Action)
+	events, _, err = repo.ListByUser(ctx, 1, 2, 2)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.AuditUserCreated, events[0].Action)
+
+	events, _, err = repo.ListByUser(ctx, 2, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	assert.Equal(t, []interface{}{"first_name"}, events[0].Details["fields"])
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository implements the reads and writes every model needs. Repositories of a model embed
+// it and add the queries specific to that model, overriding methods that need more, such as a
+// Delete also removing associations.
+type Repository[T any] struct {
+	db *Database
+}
+
+// NewRepository creates a base repository of the model T
+func NewRepository[T any](db *Database) Repository[T] {
+	return Repository[T]{db: db}
+}
+
+// Create stores a new row
+func (r *Repository[T]) Create(ctx context.Context, entity *T) error {
+	return r.db.DB.WithContext(ctx).Create(entity).Error
+}
+
+// GetByID retrieves a row by ID, or nil when it doesn't exist. It reads from the primary, as
+// the row is usually changed and saved next.
+func (r *Repository[T]) GetByID(ctx context.Context, id uint, opts ...QueryOption) (*T, error) {
+	var entity T
+	if err := r.db.DB.WithContext(ctx).Scopes(scopes(opts)...).First(&entity, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// FindOne retrieves the first row matching a condition, such as "name = ?", or nil when none does
+func (r *Repository[T]) FindOne(ctx context.Context, query interface{}, args ...interface{}) (*T, error) {
+	var entity T
+	if err := r.db.DB.WithContext(ctx).Where(query, args...).First(&entity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &entity, nil
+}
+
+// List retrieves a page of rows ordered by primary key, and the total number of rows. It reads
+// from a replica when any are configured.
+func (r *Repository[T]) List(ctx context.Context, limit, offset int, opts ...QueryOption) ([]*T, int64, error) {
+	var total int64
+	if err := r.db.Reader(ctx).Model(new(T)).Scopes(scopes(opts)...).Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var entities []*T
+	err := r.db.Reader(ctx).
+		Scopes(scopes(opts)...).
+		Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: clause.PrimaryKey}}).
+		Limit(limit).
+		Offset(offset).
+		Find(&entities).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return entities, total, nil
+}
+
+// Update saves every field of a row. Rows of models with a uint Version field are locked
+// optimistically: Update increments the version, and fails with ErrVersionConflict when the row
+// was updated since it was read.
+func (r *Repository[T]) Update(ctx context.Context, entity *T) error {
+	return save(r.db.DB.WithContext(ctx), entity)
+}
+
+// Delete removes a row and reports whether it existed. Rows of models with a DeletedAt field
+// are soft deleted.
+func (r *Repository[T]) Delete(ctx context.Context, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Delete(new(T), id)
+	return result.RowsAffected == 1, result.Error
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepository(t *testing.T) {
+	repo := NewRepository[models.Tag](setupTestDB(t))
+	ctx := context.Background()
+
+	for _, name := range []string{"vip", "beta", "staff"} {
+		require.NoError(t, repo.Create(ctx, &models.Tag{Name: name}))
+	}
+
+	tag, err := repo.FindOne(ctx, "name = ?", "beta")
+	require.NoError(t, err)
+	require.NotNil(t, tag)
+
+	tag.Description = "Beta testers"
+	require.NoError(t, repo.Update(ctx, tag))
+	found, err := repo.GetByID(ctx, tag.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "Beta testers", found.Description)
+
+	tags, total, err := repo.List(ctx, 2, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "beta", tags[0].Name)
+	assert.Equal(t, "staff", tags[1].Name)
+
+	deleted, err := repo.Delete(ctx, tag.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, tag.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	found, err = repo.GetByID(ctx, tag.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found)
+	found, err = repo.FindOne(ctx, "name = ?", "beta")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// errCasbinReadOnly is returned when casbin tries to change the policy it was given
+var errCasbinReadOnly = errors.New("casbin policy is managed through roles and permissions")
+
+// casbinAdapter loads a casbin policy from the role and permission tables
+type casbinAdapter struct {
+	db *Database
+}
+
+// NewCasbinAdapter creates a read-only casbin adapter over the role and permission tables. Every
+// permission of an active role becomes a "p, <role>, <resource>, <action>" rule, and every user
+// holding an active role a "g, <subject>, <role>" rule, with the subject from CasbinSubject. A role
+// inheriting from an active role gets a "g, <role>, <parent>" rule.
+func NewCasbinAdapter(db *Database) persist.Adapter {
+	return &casbinAdapter{
+		db: db,
+	}
+}
+
+// CasbinSubject returns the casbin subject of a user, keeping users apart from role names
+func CasbinSubject(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// LoadPolicy loads the rules of all active roles into the model
+func (a *casbinAdapter) LoadPolicy(m model.Model) error {
+	ctx := context.Background()
+
+	var grants []struct {
+		Role     string
+		Resource string
+		Action   string
+	}
+	err := a.db.DB.WithContext(ctx).Model(&models.Role{}).
+		Select("roles.name AS role, permissions.resource, permissions.action").
+		Joins("JOIN role_permissions ON role_permissions.role_id = roles.id").
+		Joins("JOIN permissions ON permissions.id = role_permissions.permission_id AND permissions.deleted_at IS NULL").
+		Where("roles.is_active = ?", true).
+		Scan(&grants).Error
+	if err != nil {
+		return err
+	}
+	for _, grant := range grants {
+		if err := persist.LoadPolicyArray([]string{"p", grant.Role, grant.Resource, grant.Action}, m); err != nil {
+			return err
+		}
+	}
+
+	var members []struct {
+		UserID uint
+		Role   string
+	}
+	err = a.db.DB.WithContext(ctx).Model(&models.Role{}).
+		Select("user_roles.user_id, roles.name AS role").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("roles.is_active = ?", true).
+		Scan(&members).Error
+	if err != nil {
+		return err
+	}
+	for _, member := range members {
+		if err := persist.LoadPolicyArray([]string{"g", CasbinSubject(member.UserID), member.Role}, m); err != nil {
+			return err
+		}
+	}
+
+	var inheritance []struct {
+		Role   string
+		Parent string
+	}
+	err = a.db.DB.WithContext(ctx).Model(&models.Role{}).
+		Select("roles.name AS role, parents.name AS parent").
+		Joins("JOIN roles parents ON parents.id = roles.parent_role_id AND parents.is_active = ? AND parents.deleted_at IS NULL", true).
+		Scan(&inheritance).Error
+	if err != nil {
+		return err
+	}
+	for _, link := range inheritance {
+		if err := persist.LoadPolicyArray([]string{"g", link.Role, link.Parent}, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SavePolicy is not supported, roles and permissions are changed through their own API
+func (a *casbinAdapter) SavePolicy(m model.Model) error {
+	return errCasbinReadOnly
+}
+
+// AddPolicy is not supported
+func (a *casbinAdapter) AddPolicy(sec string, ptype string, rule []string) error {
+	return errCasbinReadOnly
+}
+
+// RemovePolicy is not supported
+func (a *casbinAdapter) RemovePolicy(sec string, ptype string, rule []string) error {
+	return errCasbinReadOnly
+}
+
+// RemoveFilteredPolicy is not supported
+func (a *casbinAdapter) RemoveFilteredPolicy(sec string, ptype string, fieldIndex int, fieldValues ...string) error {
+	return errCasbinReadOnly
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testCasbinModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+func TestCasbinAdapter(t *testing.T) {
+	db := setupTestDB(t)
+	roles := NewRoleRepository(db)
+	permissions := NewPermissionRepository(db)
+	ctx := context.Background()
+
+	read := &models.Permission{Name: models.PermissionUserRead, Resource: "user", Action: "read"}
+	remove := &models.Permission{Name: models.PermissionUserDelete, Resource: "user", Action: "delete"}
+	require.NoError(t, permissions.Create(ctx, read))
+	require.NoError(t, permissions.Create(ctx, remove))
+
+	moderator := &models.Role{Name: models.RoleModerator, IsActive: true}
+	require.NoError(t, roles.Create(ctx, moderator))
+	require.NoError(t, roles.ReplacePermissions(ctx, moderator.ID, []uint{read.ID}))
+	disabled := &models.Role{Name: "cleaner", IsActive: true}
+	require.NoError(t, roles.Create(ctx, disabled))
+	require.NoError(t, roles.ReplacePermissions(ctx, disabled.ID, []uint{remove.ID}))
+	disabled.IsActive = false
+	require.NoError(t, roles.Update(ctx, disabled))
+	require.NoError(t, roles.AssignToUser(ctx, 1, []uint{moderator.ID, disabled.ID}))
+	// Admins inherit from moderators
+	admin := &models.Role{Name: models.RoleAdmin, IsActive: true, ParentRoleID: &moderator.ID}
+	require.NoError(t, roles.Create(ctx, admin))
+	require.NoError(t, roles.AssignToUser(ctx, 3, []uint{admin.ID}))
+
+	m, err := model.NewModelFromString(testCasbinModel)
+	require.NoError(t, err)
+	enforcer, err := casbin.NewEnforcer(m, NewCasbinAdapter(db))
+	require.NoError(t, err)
+
+	allowed, err := enforcer.Enforce(CasbinSubject(1), "user", "read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = enforcer.Enforce(CasbinSubject(1), "user", "delete")
+	require.NoError(t, err)
+	assert.False(t, allowed, "inactive roles grant nothing")
+	allowed, err = enforcer.Enforce(CasbinSubject(2), "user", "read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = enforcer.Enforce(CasbinSubject(3), "user", "read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// The policy can't be changed through casbin
+	_, err = enforcer.AddPolicy(models.RoleModerator, "user", "delete")
+	assert.ErrorIs(t, err, errCasbinReadOnly)
+}
@@ -1,63 +1,296 @@
 package repository
 
 import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
 	"fmt"
-	"log"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"gbt-be-template/internal/config"
 	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/secrets"
 
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"gorm.io/driver/mysql"
 	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
 )
 
 // Database wraps the GORM database connection
 type Database struct {
 	DB *gorm.DB
+
+	// Read replica state, unset when no replicas are configured
+	reader       *gorm.DB
+	replicaPools []*sql.DB
+
+	// Credential rotation state, unset for databases not opened by NewDatabase
+	sqlDB        *sql.DB
+	connConfig   *pgx.ConnConfig
+	provider     secrets.Provider
+	credentials  atomic.Pointer[secrets.Credentials]
+	maxIdleConns int
+	reloadMu     sync.Mutex
 }
 
-// NewDatabase creates a new database connection
-func NewDatabase(cfg *config.Config) (*Database, error) {
-	// Configure GORM logger based on environment
-	var gormLogger logger.Interface
-	if cfg.IsDevelopment() {
-		gormLogger = logger.Default.LogMode(logger.Info)
-	} else {
-		gormLogger = logger.Default.LogMode(logger.Silent)
+// NewDatabase creates a new database connection, whose GORM logs are written to log
+func NewDatabase(cfg *config.Config, log *logger.Logger) (*Database, error) {
+	d := &Database{
+		maxIdleConns: cfg.Database.MaxIdleConns,
+	}
+
+	var dialector gorm.Dialector
+	switch cfg.Database.Driver {
+	case "mysql":
+		dialector = mysql.Open(cfg.GetDSN())
+	case "sqlite":
+		dialector = sqlite.Open(cfg.GetDSN())
+	default:
+		sqlDB, err := d.openPostgres(cfg)
+		if err != nil {
+			return nil, err
+		}
+		dialector = postgres.New(postgres.Config{Conn: sqlDB})
 	}
 
 	// Open database connection
-	db, err := gorm.Open(postgres.Open(cfg.GetDSN()), &gorm.Config{
-		Logger: gormLogger,
+	db, err := gorm.Open(dialector, &gorm.Config{
+		Logger: newGormLogger(log, cfg.Database),
 	})
 	if err != nil {
+		if d.sqlDB != nil {
+			d.sqlDB.Close()
+		}
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
+	d.DB = db
+	if err := registerTenantScope(db); err != nil {
+		return nil, fmt.Errorf("failed to register tenant scope: %w", err)
+	}
 
-	// Get underlying sql.DB to configure connection pool
+	// Configure connection pool
 	sqlDB, err := db.DB()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get underlying sql.DB: %w", err)
+		return nil, fmt.Errorf("failed to get database connection pool: %w", err)
 	}
-
-	// Configure connection pool
-	sqlDB.SetMaxOpenConns(cfg.Database.MaxOpenConns)
-	sqlDB.SetMaxIdleConns(cfg.Database.MaxIdleConns)
-	sqlDB.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	configurePool(sqlDB, cfg)
 
 	// Test the connection
 	if err := sqlDB.Ping(); err != nil {
+		d.Close()
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Database connection established successfully")
+	if len(cfg.Database.ReplicaDSNs) > 0 {
+		replicas, err := d.openReplicas(cfg)
+		if err != nil {
+			d.Close()
+			return nil, err
+		}
+		if err := d.useReplicas(newDialector(cfg.Database.Driver, sqlDB), replicas); err != nil {
+			d.Close()
+			return nil, fmt.Errorf("failed to configure read replicas: %w", err)
+		}
+	}
+
+	if cfg.Metrics.Enabled {
+		if err := d.useMetrics(cfg.Database.Name); err != nil {
+			d.Close()
+			return nil, fmt.Errorf("failed to register database metrics: %w", err)
+		}
+	}
 
-	return &Database{DB: db}, nil
+	log.WithField("driver", cfg.Database.Driver).Info("Database connection established successfully")
+
+	return d, nil
 }
 
-// Close closes the database connection
+// openPostgres opens the pool of Postgres connections, which log in with the current rotating
+// credentials
+func (d *Database) openPostgres(cfg *config.Config) (*sql.DB, error) {
+	connConfig, err := pgx.ParseConfig(cfg.GetDSN())
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse database config: %w", err)
+	}
+
+	d.connConfig = connConfig
+	d.provider = newCredentialsProvider(cfg)
+
+	creds := secrets.Credentials{Username: cfg.Database.User, Password: cfg.Database.Password}
+	if d.provider != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if creds, err = d.provider.Credentials(ctx); err != nil {
+			return nil, fmt.Errorf("failed to load database credentials: %w", err)
+		}
+	}
+	d.credentials.Store(&creds)
+
+	d.sqlDB = d.openPostgresPool(connConfig)
+	return d.sqlDB, nil
+}
+
+// openPostgresPool opens a pool of connections to a Postgres server. New connections always log
+// in with the current credentials, and pooled connections opened with rotated out credentials
+// are discarded on reuse.
+func (d *Database) openPostgresPool(connConfig *pgx.ConnConfig) *sql.DB {
+	return stdlib.OpenDB(*connConfig,
+		stdlib.OptionBeforeConnect(func(ctx context.Context, cc *pgx.ConnConfig) error {
+			current := d.credentials.Load()
+			cc.User, cc.Password = current.Username, current.Password
+			return nil
+		}),
+		stdlib.OptionResetSession(func(ctx context.Context, conn *pgx.Conn) error {
+			current, used := d.credentials.Load(), conn.Config()
+			if used.User != current.Username || used.Password != current.Password {
+				return driver.ErrBadConn
+			}
+			return nil
+		}),
+	)
+}
+
+// openReplicas opens a pool of connections to each read replica. Postgres replicas log in with
+// the rotating credentials when they are used, and otherwise with the user of their DSN.
+func (d *Database) openReplicas(cfg *config.Config) ([]gorm.Dialector, error) {
+	dialectors := make([]gorm.Dialector, 0, len(cfg.Database.ReplicaDSNs))
+	for _, dsn := range cfg.Database.ReplicaDSNs {
+		var pool *sql.DB
+		if cfg.Database.Driver == "mysql" {
+			var err error
+			if pool, err = sql.Open("mysql", dsn); err != nil {
+				return nil, fmt.Errorf("failed to open read replica: %w", err)
+			}
+		} else {
+			connConfig, err := pgx.ParseConfig(dsn)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse read replica config: %w", err)
+			}
+			if d.provider != nil {
+				pool = d.openPostgresPool(connConfig)
+			} else {
+				pool = stdlib.OpenDB(*connConfig)
+			}
+		}
+		d.replicaPools = append(d.replicaPools, pool)
+
+		configurePool(pool, cfg)
+		if err := pool.Ping(); err != nil {
+			return nil, fmt.Errorf("failed to ping read replica: %w", err)
+		}
+		dialectors = append(dialectors, newDialector(cfg.Database.Driver, pool))
+	}
+	return dialectors, nil
+}
+
+// useReplicas sets up the reader on a second handle of the primary, whose queries dbresolver
+// sends to a random replica. Anything else run through the reader still goes to the primary.
+func (d *Database) useReplicas(primary gorm.Dialector, replicas []gorm.Dialector) error {
+	reader, err := gorm.Open(primary, &gorm.Config{Logger: d.DB.Logger})
+	if err != nil {
+		return err
+	}
+	if err := registerTenantScope(reader); err != nil {
+		return err
+	}
+	resolver := dbresolver.Register(dbresolver.Config{
+		Replicas: replicas,
+		Policy:   dbresolver.RandomPolicy{},
+	})
+	if err := reader.Use(resolver); err != nil {
+		return err
+	}
+	d.reader = reader
+	return nil
+}
+
+// configurePool applies the connection pool settings of cfg to pool
+func configurePool(pool *sql.DB, cfg *config.Config) {
+	pool.SetMaxOpenConns(cfg.Database.MaxOpenConns)
+	pool.SetMaxIdleConns(cfg.Database.MaxIdleConns)
+	pool.SetConnMaxLifetime(cfg.Database.ConnMaxLifetime)
+	pool.SetConnMaxIdleTime(cfg.Database.ConnMaxIdleTime)
+}
+
+// newDialector returns the GORM dialector of the driver on an open connection pool
+func newDialector(driver string, pool gorm.ConnPool) gorm.Dialector {
+	if driver == "mysql" {
+		return mysql.New(mysql.Config{Conn: pool})
+	}
+	return postgres.New(postgres.Config{Conn: pool})
+}
+
+// Reader returns the database for reads that can lag a little behind writes, such as lists,
+// which go to a read replica when any are configured. Reads that must see the latest writes,
+// and reads in transactions, use DB.
+func (d *Database) Reader(ctx context.Context) *gorm.DB {
+	if d.reader == nil {
+		return d.DB.WithContext(ctx)
+	}
+	return d.reader.WithContext(ctx)
+}
+
+// newCredentialsProvider returns the configured source of rotating credentials, or nil when they come from the environment
+func newCredentialsProvider(cfg *config.Config) secrets.Provider {
+	switch cfg.Database.CredentialsSource {
+	case "file":
+		return secrets.NewFileProvider(cfg.Database.CredentialsFile)
+	case "vault":
+		return secrets.NewVaultProvider(cfg.Database.VaultAddr, cfg.Database.VaultToken, cfg.Database.VaultPath, nil)
+	}
+	return nil
+}
+
+// ReloadCredentials fetches the credentials again and, if they changed, switches
+// the connection pool over to them without interrupting queries in flight.
+// It reports whether the credentials changed.
+func (d *Database) ReloadCredentials(ctx context.Context) (bool, error) {
+	if d.provider == nil {
+		return false, nil
+	}
+
+	d.reloadMu.Lock()
+	defer d.reloadMu.Unlock()
+
+	creds, err := d.provider.Credentials(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to load database credentials: %w", err)
+	}
+	if creds == *d.credentials.Load() {
+		return false, nil
+	}
+
+	// Make sure the new credentials work before the pool depends on them
+	probe := d.connConfig.Copy()
+	probe.User, probe.Password = creds.Username, creds.Password
+	conn, err := pgx.ConnectConfig(ctx, probe)
+	if err != nil {
+		return false, fmt.Errorf("failed to connect with new database credentials: %w", err)
+	}
+	conn.Close(ctx)
+
+	d.credentials.Store(&creds)
+
+	// Close idle connections now; busy ones are replaced when next reused
+	for _, pool := range append([]*sql.DB{d.sqlDB}, d.replicaPools...) {
+		pool.SetMaxIdleConns(0)
+		pool.SetMaxIdleConns(d.maxIdleConns)
+	}
+	return true, nil
+}
+
+// Close closes the database connection and those to read replicas
 func (d *Database) Close() error {
+	for _, pool := range d.replicaPools {
+		pool.Close()
+	}
 	sqlDB, err := d.DB.DB()
 	if err != nil {
 		return err
@@ -65,11 +298,45 @@ func (d *Database) Close() error {
 	return sqlDB.Close()
 }
 
+// Models returns every model whose table is managed by AutoMigrate
+func Models() []interface{} {
+	return []interface{}{
+		&models.User{},
+		&models.Role{},
+		&models.Permission{},
+		&models.UserRole{},
+		&models.RolePermission{},
+		&models.AccessRule{},
+		&models.RBACAuditEvent{},
+		&models.OAuthClient{},
+		&models.OAuthAuthorizationCode{},
+		&models.OAuthConsent{},
+		&models.UserIdentity{},
+		&models.WebAuthnCredential{},
+		&models.APIKey{},
+		&models.Device{},
+		&models.LoginEvent{},
+		&models.AuditEvent{},
+		&models.UserSettings{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Tag{},
+		&models.UserTag{},
+		&models.RecoveryCode{},
+		&models.RefreshToken{},
+		&models.Session{},
+		&models.EmailToken{},
+		&models.OTPCode{},
+		&models.UploadSession{},
+		&models.UploadPart{},
+		&models.File{},
+		&models.Job{},
+	}
+}
+
 // AutoMigrate runs auto migration for given models
 func (d *Database) AutoMigrate() error {
-	return d.DB.AutoMigrate(
-		&models.User{},
-	)
+	return d.DB.AutoMigrate(Models()...)
 }
 
 // GetDB returns the GORM database instance
@@ -86,7 +353,7 @@ func (d *Database) Health() error {
 	return sqlDB.Ping()
 }
 
-// GetStats returns database connection statistics
+// GetStats returns database connection statistics, including those of each read replica
 func (d *Database) GetStats() map[string]interface{} {
 	sqlDB, err := d.DB.DB()
 	if err != nil {
@@ -95,7 +362,20 @@ func (d *Database) GetStats() map[string]interface{} {
 		}
 	}
 
-	stats := sqlDB.Stats()
+	stats := poolStats(sqlDB.Stats())
+	stats["max_idle_connections"] = d.maxIdleConns
+	if len(d.replicaPools) > 0 {
+		replicas := make([]map[string]interface{}, len(d.replicaPools))
+		for i, pool := range d.replicaPools {
+			replicas[i] = poolStats(pool.Stats())
+		}
+		stats["replicas"] = replicas
+	}
+	return stats
+}
+
+// poolStats returns the statistics of a connection pool
+func poolStats(stats sql.DBStats) map[string]interface{} {
 	return map[string]interface{}{
 		"max_open_connections": stats.MaxOpenConnections,
 		"open_connections":     stats.OpenConnections,
@@ -0,0 +1,110 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestNewDatabase_SQLite(t *testing.T) {
+	cfg := &config.Config{Database: config.DatabaseConfig{
+		Driver:       "sqlite",
+		Name:         filepath.Join(t.TempDir(), "gbt.db"),
+		MaxOpenConns: 4,
+		MaxIdleConns: 4,
+	}}
+
+	db, err := NewDatabase(cfg, logger.New("error", "text"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.AutoMigrate())
+	require.NoError(t, db.Health())
+	stats := db.GetStats()
+	assert.Equal(t, 4, stats["max_open_connections"])
+	assert.Equal(t, 4, stats["max_idle_connections"])
+	assert.NotContains(t, stats, "replicas")
+
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	require.NoError(t, users.Create(ctx, &models.User{Email: "alice@example.com", Username: "alice", Password: "x"}))
+	found, err := users.GetByEmail(ctx, "alice@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+
+	changed, err := db.ReloadCredentials(ctx)
+	require.NoError(t, err)
+	assert.False(t, changed, "credentials only rotate on Postgres")
+}
+
+func TestDatabase_Reader(t *testing.T) {
+	dir := t.TempDir()
+	open := func(name string) (*Database, *config.Config) {
+		cfg := &config.Config{Database: config.DatabaseConfig{Driver: "sqlite", Name: filepath.Join(dir, name)}}
+		db, err := NewDatabase(cfg, logger.New("error", "text"))
+		require.NoError(t, err)
+		t.Cleanup(func() { db.Close() })
+		require.NoError(t, db.AutoMigrate())
+		return db, cfg
+	}
+	db, primaryCfg := open("primary.db")
+	replica, replicaCfg := open("replica.db")
+
+	ctx := context.Background()
+	require.NoError(t, NewUserRepository(replica).Create(ctx, &models.User{Email: "replica@example.com", Username: "replica", Password: "x"}))
+	require.NoError(t, db.useReplicas(sqlite.Open(primaryCfg.GetDSN()), []gorm.Dialector{sqlite.Open(replicaCfg.GetDSN())}))
+
+	users := NewUserRepository(db)
+	require.NoError(t, users.Create(ctx, &models.User{Email: "primary@example.com", Username: "primary", Password: "x"}))
+
+	t.Run("listing methods read from the replica", func(t *testing.T) {
+		list, total, err := users.List(ctx, models.UserFilter{}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, list, 1)
+		assert.Equal(t, "replica", list[0].Username, "the write hasn't reached the replica")
+
+		count, err := users.Count(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), count)
+	})
+
+	t.Run("other reads and writes use the primary", func(t *testing.T) {
+		found, err := users.GetByUsername(ctx, "primary")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		found, err = users.GetByID(ctx, found.ID)
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.Equal(t, "primary", found.Username)
+		found, err = users.GetByUsername(ctx, "replica")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		// Duplicate checks before creating users must see the latest writes
+		exists, err := users.ExistsByUsername(ctx, "primary")
+		require.NoError(t, err)
+		assert.True(t, exists)
+		exists, err = users.ExistsByEmail(ctx, "replica@example.com")
+		require.NoError(t, err)
+		assert.False(t, exists)
+	})
+
+	t.Run("transactions use the primary", func(t *testing.T) {
+		err := NewRepositories(db).WithTx(ctx, func(txRepos *Repositories) error {
+			exists, err := txRepos.User.ExistsByUsername(ctx, "primary")
+			require.NoError(t, err)
+			assert.True(t, exists)
+			return nil
+		})
+		require.NoError(t, err)
+	})
+}
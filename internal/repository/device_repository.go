@@ -0,0 +1,72 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// deviceRepository implements the DeviceRepository interface
+type deviceRepository struct {
+	db *Database
+}
+
+// NewDeviceRepository creates a new device repository
+func NewDeviceRepository(db *Database) DeviceRepository {
+	return &deviceRepository{
+		db: db,
+	}
+}
+
+// Create stores a new device
+func (r *deviceRepository) Create(ctx context.Context, device *models.Device) error {
+	return r.db.DB.WithContext(ctx).Create(device).Error
+}
+
+// GetByHash retrieves a device of a user by the hash of its token
+func (r *deviceRepository) GetByHash(ctx context.Context, userID uint, tokenHash string) (*models.Device, error) {
+	var device models.Device
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ? AND token_hash = ?", userID, tokenHash).First(&device).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &device, nil
+}
+
+// Update saves the changes to a device
+func (r *deviceRepository) Update(ctx context.Context, device *models.Device) error {
+	return r.db.DB.WithContext(ctx).Save(device).Error
+}
+
+// ListByUser retrieves the devices of a user, most recently seen first
+func (r *deviceRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Device, error) {
+	var devices []*models.Device
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ?", userID).Order("last_seen_at DESC").Find(&devices).Error; err != nil {
+		return nil, err
+	}
+	return devices, nil
+}
+
+// Delete removes a device of a user and reports whether it existed
+func (r *deviceRepository) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.Device{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Prune removes all but the keep most recently seen devices of a user
+func (r *deviceRepository) Prune(ctx context.Context, userID uint, keep int) error {
+	recent := r.db.DB.Model(&models.Device{}).Select("id").Where("user_id = ?", userID).Order("last_seen_at DESC").Limit(keep)
+	return r.db.DB.WithContext(ctx).Where("user_id = ? AND id NOT IN (?)", userID, recent).Delete(&models.Device{}).Error
+}
+
+// RevokeTrust makes every device of a user ask for two-factor authentication again
+func (r *deviceRepository) RevokeTrust(ctx context.Context, userID uint) error {
+	return r.db.DB.WithContext(ctx).Model(&models.Device{}).
+		Where("user_id = ? AND trusted_until IS NOT NULL", userID).
+		Update("trusted_until", nil).Error
+}
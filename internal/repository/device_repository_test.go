@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewDeviceRepository(db)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+	trustedUntil := now.Add(time.Hour)
+
+	for i, hash := range []string{"hash-1", "hash-2", "hash-3"} {
+		device := &models.Device{UserID: 1, TokenHash: hash, LastSeenAt: now.Add(time.Duration(i) * time.Minute), TrustedUntil: &trustedUntil}
+		require.NoError(t, repo.Create(ctx, device))
+	}
+	require.NoError(t, repo.Create(ctx, &models.Device{UserID: 2, TokenHash: "hash-4", LastSeenAt: now}))
+
+	// Tokens only identify devices of their own user
+	found, err := repo.GetByHash(ctx, 1, "hash-1")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	missing, err := repo.GetByHash(ctx, 2, "hash-1")
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// Only the most recently seen devices are kept
+	require.NoError(t, repo.Prune(ctx, 1, 2))
+	devices, err := repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, devices, 2)
+	assert.Equal(t, "hash-3", devices[0].TokenHash)
+	assert.Equal(t, "hash-2", devices[1].TokenHash)
+	others, err := repo.ListByUser(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, others, 1)
+
+	require.NoError(t, repo.RevokeTrust(ctx, 1))
+	devices, err = repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	for _, device := range devices {
+		assert.Nil(t, device.TrustedUntil)
+	}
+
+	// Devices of other users can't be deleted
+	deleted, err := repo.Delete(ctx, 2, devices[0].ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = repo.Delete(ctx, 1, devices[0].ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
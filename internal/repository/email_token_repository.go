@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// emailTokenRepository implements the EmailTokenRepository interface
+type emailTokenRepository struct {
+	db *Database
+}
+
+// NewEmailTokenRepository creates a new email token repository
+func NewEmailTokenRepository(db *Database) EmailTokenRepository {
+	return &emailTokenRepository{
+		db: db,
+	}
+}
+
+// Create stores a new email token
+func (r *emailTokenRepository) Create(ctx context.Context, token *models.EmailToken) error {
+	return r.db.DB.WithContext(ctx).Create(token).Error
+}
+
+// GetByHash retrieves an email token by its hash
+func (r *emailTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.EmailToken, error) {
+	var token models.EmailToken
+	if err := r.db.DB.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed atomically marks a token as used.
+// It returns false if the token had already been used.
+func (r *emailTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).
+		Model(&models.EmailToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// InvalidateForUser marks every unused token of a user for the given purpose as used
+func (r *emailTokenRepository) InvalidateForUser(ctx context.Context, userID uint, purpose string) error {
+	return r.db.DB.WithContext(ctx).
+		Model(&models.EmailToken{}).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Update("used_at", time.Now()).Error
+}
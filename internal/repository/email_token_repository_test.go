@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailTokenRepository_MarkUsed(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewEmailTokenRepository(db)
+	ctx := context.Background()
+
+	token := &models.EmailToken{UserID: 1, Purpose: models.EmailTokenReactivation, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, token))
+
+	marked, err := repo.MarkUsed(ctx, token.ID)
+	assert.NoError(t, err)
+	assert.True(t, marked)
+
+	// Tokens are single-use
+	marked, err = repo.MarkUsed(ctx, token.ID)
+	assert.NoError(t, err)
+	assert.False(t, marked)
+}
+
+func TestEmailTokenRepository_InvalidateForUser(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewEmailTokenRepository(db)
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Hour)
+	old := &models.EmailToken{UserID: 1, Purpose: models.EmailTokenReactivation, TokenHash: "old", ExpiresAt: expires}
+	otherPurpose := &models.EmailToken{UserID: 1, Purpose: "other", TokenHash: "other", ExpiresAt: expires}
+	otherUser := &models.EmailToken{UserID: 2, Purpose: models.EmailTokenReactivation, TokenHash: "user2", ExpiresAt: expires}
+	for _, token := range []*models.EmailToken{old, otherPurpose, otherUser} {
+		require.NoError(t, repo.Create(ctx, token))
+	}
+
+	require.NoError(t, repo.InvalidateForUser(ctx, 1, models.EmailTokenReactivation))
+
+	invalidated, err := repo.GetByHash(ctx, "old")
+	require.NoError(t, err)
+	assert.NotNil(t, invalidated.UsedAt)
+
+	for _, hash := range []string{"other", "user2"} {
+		token, err := repo.GetByHash(ctx, hash)
+		require.NoError(t, err)
+		assert.Nil(t, token.UsedAt, hash)
+	}
+
+	missing, err := repo.GetByHash(ctx, "missing")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
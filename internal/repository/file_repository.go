@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// fileRepository implements the FileRepository interface
+type fileRepository struct {
+	db *Database
+}
+
+// NewFileRepository creates a new file repository
+func NewFileRepository(db *Database) FileRepository {
+	return &fileRepository{
+		db: db,
+	}
+}
+
+// Create stores file metadata
+func (r *fileRepository) Create(ctx context.Context, file *models.File) error {
+	return r.db.DB.WithContext(ctx).Create(file).Error
+}
+
+// GetByID retrieves a file by ID
+func (r *fileRepository) GetByID(ctx context.Context, id uint) (*models.File, error) {
+	var file models.File
+	if err := r.db.DB.WithContext(ctx).First(&file, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &file, nil
+}
+
+// ListByUser returns a user's files, newest first
+func (r *fileRepository) ListByUser(ctx context.Context, userID uint) ([]*models.File, error) {
+	var files []*models.File
+	err := r.db.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&files).Error
+	return files, err
+}
+
+// Delete removes file metadata
+func (r *fileRepository) Delete(ctx context.Context, id uint) error {
+	return r.db.DB.WithContext(ctx).Delete(&models.File{}, id).Error
+}
+
+// TotalSizeByUser returns the combined size of a user's files
+func (r *fileRepository) TotalSizeByUser(ctx context.Context, userID uint) (int64, error) {
+	var total int64
+	err := r.db.DB.WithContext(ctx).Model(&models.File{}).
+		Where("user_id = ?", userID).
+		Select("COALESCE(SUM(size), 0)").
+		Scan(&total).Error
+	return total, err
+}
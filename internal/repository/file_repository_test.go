@@ -0,0 +1,30 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileRepository_TotalSizeByUser(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewFileRepository(db)
+	ctx := context.Background()
+
+	// Users without files have zero usage
+	total, err := repo.TotalSizeByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), total)
+
+	require.NoError(t, repo.Create(ctx, &models.File{UserID: 1, Purpose: "import", Filename: "a", Size: 10, ObjectKey: "a"}))
+	require.NoError(t, repo.Create(ctx, &models.File{UserID: 1, Purpose: "import", Filename: "b", Size: 5, ObjectKey: "b"}))
+	require.NoError(t, repo.Create(ctx, &models.File{UserID: 2, Purpose: "import", Filename: "c", Size: 7, ObjectKey: "c"}))
+
+	total, err = repo.TotalSizeByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(15), total)
+}
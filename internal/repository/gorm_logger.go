@@ -0,0 +1,124 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/pkg/logger"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	gormlogger "gorm.io/gorm/logger"
+)
+
+// statementTable matches the table a SQL statement reads or writes
+var statementTable = regexp.MustCompile("(?i)\\b(?:FROM|INTO|UPDATE)\\s+[\"`]?(\\w+)")
+
+// gormLogger writes the logs of GORM through the application logger, so that they follow
+// LOG_LEVEL and LOG_FORMAT. Statements are logged at debug level, slow ones at warn level and
+// failed ones at error level, together with the ID of the request they ran for.
+type gormLogger struct {
+	log           *logger.Logger
+	level         gormlogger.LogLevel
+	slowThreshold time.Duration
+	logParams     bool
+}
+
+// newGormLogger creates a GORM logger writing to log, with the slow query threshold and
+// parameter logging of cfg
+func newGormLogger(log *logger.Logger, cfg config.DatabaseConfig) gormlogger.Interface {
+	return &gormLogger{
+		log:           log,
+		level:         gormlogger.Info,
+		slowThreshold: cfg.SlowQueryThreshold,
+		logParams:     cfg.LogQueryParams,
+	}
+}
+
+// LogMode returns a copy of the logger with the given GORM log level, as used by db.Debug()
+func (l *gormLogger) LogMode(level gormlogger.LogLevel) gormlogger.Interface {
+	copied := *l
+	copied.level = level
+	return &copied
+}
+
+// Info logs a message of GORM, such as one of auto migration
+func (l *gormLogger) Info(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Info {
+		l.entry(ctx).Infof(msg, data...)
+	}
+}
+
+// Warn logs a warning of GORM
+func (l *gormLogger) Warn(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Warn {
+		l.entry(ctx).Warnf(msg, data...)
+	}
+}
+
+// Error logs an error of GORM
+func (l *gormLogger) Error(ctx context.Context, msg string, data ...interface{}) {
+	if l.level >= gormlogger.Error {
+		l.entry(ctx).Errorf(msg, data...)
+	}
+}
+
+// Trace logs an executed statement. Lookups finding no row aren't errors, since repositories
+// report them as nil results.
+func (l *gormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= gormlogger.Silent {
+		return
+	}
+
+	elapsed := time.Since(begin)
+	switch {
+	case err != nil && l.level >= gormlogger.Error && !errors.Is(err, gorm.ErrRecordNotFound):
+		l.statement(ctx, elapsed, fc).WithError(err).Error("Database query failed")
+	case l.slowThreshold > 0 && elapsed > l.slowThreshold && l.level >= gormlogger.Warn:
+		l.statement(ctx, elapsed, fc).WithField("threshold_ms", l.slowThreshold.Milliseconds()).Warn("Slow database query")
+	case l.level >= gormlogger.Info && l.log.IsLevelEnabled(logrus.DebugLevel):
+		l.statement(ctx, elapsed, fc).Debug("Database query")
+	}
+}
+
+// ParamsFilter leaves the values of parameters out of logged SQL unless DB_LOG_QUERY_PARAMS
+// is set, since they can hold personal data and secrets such as password hashes
+func (l *gormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	if !l.logParams {
+		return sql, nil
+	}
+	return sql, params
+}
+
+// statement returns an entry describing an executed statement
+func (l *gormLogger) statement(ctx context.Context, elapsed time.Duration, fc func() (string, int64)) *logrus.Entry {
+	sql, rows := fc()
+	operation, _, _ := strings.Cut(strings.TrimSpace(sql), " ")
+	table := ""
+	if match := statementTable.FindStringSubmatch(sql); match != nil {
+		table = match[1]
+	}
+
+	entry := l.log.Database(strings.ToUpper(operation), table, elapsed.Milliseconds()).WithFields(map[string]interface{}{
+		"sql":  sql,
+		"rows": rows,
+	})
+	if requestID := chiMiddleware.GetReqID(ctx); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+	return entry
+}
+
+// entry returns a log entry carrying the ID of the request in ctx, if any
+func (l *gormLogger) entry(ctx context.Context) *logrus.Entry {
+	entry := logrus.NewEntry(l.log.Logger)
+	if requestID := chiMiddleware.GetReqID(ctx); requestID != "" {
+		entry = entry.WithField("request_id", requestID)
+	}
+	return entry
+}
@@ -0,0 +1,127 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// logEntries decodes the JSON log entries written to buf
+func logEntries(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var entries []map[string]interface{}
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		if line == "" {
+			continue
+		}
+		var entry map[string]interface{}
+		require.NoError(t, json.Unmarshal([]byte(line), &entry))
+		entries = append(entries, entry)
+	}
+	buf.Reset()
+	return entries
+}
+
+func TestGormLogger(t *testing.T) {
+	db := setupTestDB(t)
+	var buf bytes.Buffer
+	log := logger.New("debug", "json")
+	log.SetOutput(&buf)
+	tx := db.DB.Session(&gorm.Session{Logger: newGormLogger(log, config.DatabaseConfig{LogQueryParams: true})})
+	ctx := context.WithValue(context.Background(), chiMiddleware.RequestIDKey, "req-1")
+
+	t.Run("statements are logged at debug level with the request ID", func(t *testing.T) {
+		var count int64
+		require.NoError(t, tx.WithContext(ctx).Model(&models.User{}).Where("username = ?", "alice").Count(&count).Error)
+
+		entries := logEntries(t, &buf)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "debug", entries[0]["level"])
+		assert.Equal(t, "database", entries[0]["type"])
+		assert.Equal(t, "SELECT", entries[0]["operation"])
+		assert.Equal(t, "users", entries[0]["table"])
+		assert.Equal(t, "req-1", entries[0]["request_id"])
+		assert.Contains(t, entries[0]["sql"], `username = "alice"`)
+	})
+
+	t.Run("failed statements are logged as errors", func(t *testing.T) {
+		require.Error(t, tx.WithContext(ctx).Exec("SELECT * FROM missing").Error)
+
+		entries := logEntries(t, &buf)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "error", entries[0]["level"])
+		assert.Contains(t, entries[0]["error"], "no such table")
+	})
+
+	t.Run("missing rows aren't errors", func(t *testing.T) {
+		var user models.User
+		require.ErrorIs(t, tx.WithContext(ctx).First(&user, 42).Error, gorm.ErrRecordNotFound)
+
+		for _, entry := range logEntries(t, &buf) {
+			assert.Equal(t, "debug", entry["level"])
+		}
+	})
+
+	t.Run("statements follow the log level", func(t *testing.T) {
+		log.SetLevel(logger.New("info", "json").Level)
+		var count int64
+		require.NoError(t, tx.WithContext(ctx).Model(&models.User{}).Count(&count).Error)
+		assert.Empty(t, logEntries(t, &buf))
+	})
+}
+
+func TestGormLogger_SlowQueries(t *testing.T) {
+	db := setupTestDB(t)
+	var buf bytes.Buffer
+	log := logger.New("info", "json")
+	log.SetOutput(&buf)
+	ctx := context.WithValue(context.Background(), chiMiddleware.RequestIDKey, "req-1")
+
+	t.Run("queries over the threshold are logged as warnings without parameters", func(t *testing.T) {
+		tx := db.DB.Session(&gorm.Session{Logger: newGormLogger(log, config.DatabaseConfig{SlowQueryThreshold: time.Nanosecond})})
+		var count int64
+		require.NoError(t, tx.WithContext(ctx).Model(&models.User{}).Where("username = ?", "alice").Count(&count).Error)
+
+		entries := logEntries(t, &buf)
+		require.Len(t, entries, 1)
+		assert.Equal(t, "warning", entries[0]["level"])
+		assert.Equal(t, "Slow database query", entries[0]["msg"])
+		assert.Equal(t, "users", entries[0]["table"])
+		assert.Equal(t, "req-1", entries[0]["request_id"])
+		assert.Contains(t, entries[0]["sql"], "username = ?")
+		assert.NotContains(t, entries[0]["sql"], "alice")
+		assert.Contains(t, entries[0], "rows")
+		assert.Contains(t, entries[0], "duration_ms")
+	})
+
+	t.Run("parameters are logged when enabled", func(t *testing.T) {
+		tx := db.DB.Session(&gorm.Session{Logger: newGormLogger(log, config.DatabaseConfig{
+			SlowQueryThreshold: time.Nanosecond,
+			LogQueryParams:     true,
+		})})
+		var count int64
+		require.NoError(t, tx.WithContext(ctx).Model(&models.User{}).Where("username = ?", "alice").Count(&count).Error)
+
+		entries := logEntries(t, &buf)
+		require.Len(t, entries, 1)
+		assert.Contains(t, entries[0]["sql"], `username = "alice"`)
+	})
+
+	t.Run("fast queries aren't logged", func(t *testing.T) {
+		tx := db.DB.Session(&gorm.Session{Logger: newGormLogger(log, config.DatabaseConfig{SlowQueryThreshold: time.Hour})})
+		var count int64
+		require.NoError(t, tx.WithContext(ctx).Model(&models.User{}).Count(&count).Error)
+		assert.Empty(t, logEntries(t, &buf))
+	})
+}
@@ -0,0 +1,48 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// identityRepository implements the IdentityRepository interface
+type identityRepository struct {
+	db *Database
+}
+
+// NewIdentityRepository creates a new identity repository
+func NewIdentityRepository(db *Database) IdentityRepository {
+	return &identityRepository{
+		db: db,
+	}
+}
+
+// Create links an external identity to a user
+func (r *identityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	return r.db.DB.WithContext(ctx).Create(identity).Error
+}
+
+// GetByProviderSubject retrieves an identity by provider and subject
+func (r *identityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.DB.WithContext(ctx).Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// ListByUser retrieves all identities linked to a user
+func (r *identityRepository) ListByUser(ctx context.Context, userID uint) ([]*models.UserIdentity, error) {
+	var identities []*models.UserIdentity
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
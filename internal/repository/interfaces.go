@@ -2,33 +2,317 @@ package repository
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"gbt-be-template/internal/models"
 )
 
+// ErrUnknownSortField is returned for lists sorted by a field that isn't sortable
+var ErrUnknownSortField = errors.New("unknown sort field")
+
 // UserRepository defines the interface for user repository operations
 type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
-	GetByID(ctx context.Context, id uint) (*models.User, error)
+	GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
+	GetByPhone(ctx context.Context, phone string) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
+	GetByEmailOrUsername(ctx context.Context, login string) (*models.User, error)
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id uint) error
-	List(ctx context.Context, limit, offset int) ([]*models.User, error)
+	Restore(ctx context.Context, id uint) (bool, error)
+	HardDelete(ctx context.Context, id uint) (bool, error)
+	PurgeDeleted(ctx context.Context, cutoff time.Time) ([]uint, error)
+	List(ctx context.Context, filter models.UserFilter, sort []models.SortField, limit, offset int) ([]*models.User, int64, error)
+	ListByCursor(ctx context.Context, filter models.UserFilter, sort []models.SortField, cursor *models.Cursor, limit int) ([]*models.User, *models.Cursor, *models.Cursor, error)
+	ForEach(ctx context.Context, filter models.UserFilter, batchSize int, fn func(*models.User) error) error
 	Count(ctx context.Context) (int64, error)
 	ExistsByEmail(ctx context.Context, email string) (bool, error)
 	ExistsByUsername(ctx context.Context, username string) (bool, error)
 	UpdateLastLogin(ctx context.Context, userID uint) error
+	RecordFailedLogin(ctx context.Context, userID uint) (int, error)
+	LockUntil(ctx context.Context, userID uint, until time.Time) error
+	UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error)
+	MustChangePassword(ctx context.Context, userID uint) (bool, error)
+	LiftExpiredSuspensions(ctx context.Context, now time.Time) ([]uint, error)
+	AnonymizeDeletedAccounts(ctx context.Context, now time.Time) ([]uint, error)
+	AnonymizeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]uint, error)
+	ReencryptUsers(ctx context.Context, prefix string, limit int) (int, error)
+	SetAvatarFile(ctx context.Context, userID, fileID uint) error
+	SetAvatarURLs(ctx context.Context, userID, fileID uint, urls map[string]string) (bool, error)
+	TransferOwnership(ctx context.Context, fromID, toID uint) error
+}
+
+// RoleRepository defines the interface for role persistence
+type RoleRepository interface {
+	Create(ctx context.Context, role *models.Role) error
+	GetByID(ctx context.Context, id uint) (*models.Role, error)
+	GetByName(ctx context.Context, name string) (*models.Role, error)
+	List(ctx context.Context) ([]*models.Role, error)
+	Update(ctx context.Context, role *models.Role) error
+	Delete(ctx context.Context, id uint) (bool, error)
+	ReplacePermissions(ctx context.Context, roleID uint, permissionIDs []uint) error
+	ListByIDs(ctx context.Context, ids []uint) ([]*models.Role, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.Role, error)
+	AssignToUser(ctx context.Context, userID uint, roleIDs []uint) error
+	RemoveFromUser(ctx context.Context, userID, roleID uint) (bool, error)
+	ReplaceUserRoles(ctx context.Context, userID uint, roleIDs []uint) error
+}
+
+// PermissionRepository defines the interface for permission persistence
+type PermissionRepository interface {
+	Create(ctx context.Context, permission *models.Permission) error
+	GetByID(ctx context.Context, id uint) (*models.Permission, error)
+	GetByName(ctx context.Context, name string) (*models.Permission, error)
+	List(ctx context.Context) ([]*models.Permission, error)
+	ListByIDs(ctx context.Context, ids []uint) ([]*models.Permission, error)
+	Update(ctx context.Context, permission *models.Permission) error
+	Delete(ctx context.Context, id uint) (bool, error)
+}
+
+// AccessRuleRepository defines the interface for attribute-based access rule persistence
+type AccessRuleRepository interface {
+	Create(ctx context.Context, rule *models.AccessRule) error
+	GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.AccessRule, error)
+	GetByName(ctx context.Context, name string) (*models.AccessRule, error)
+	List(ctx context.Context) ([]*models.AccessRule, error)
+	ListActiveFor(ctx context.Context, resource, action string) ([]*models.AccessRule, error)
+	Update(ctx context.Context, rule *models.AccessRule) error
+	Delete(ctx context.Context, id uint) (bool, error)
+}
+
+// RBACAuditRepository defines the interface for the append-only audit log of role and permission changes
+type RBACAuditRepository interface {
+	Create(ctx context.Context, event *models.RBACAuditEvent) error
+	List(ctx context.Context, filter models.RBACAuditFilter, limit, offset int) ([]*models.RBACAuditEvent, int64, error)
+}
+
+// OAuthRepository defines the interface for authorization server persistence
+type OAuthRepository interface {
+	CreateClient(ctx context.Context, client *models.OAuthClient) error
+	GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error)
+	ListClients(ctx context.Context) ([]*models.OAuthClient, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error
+	GetAuthorizationCodeByHash(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error)
+	MarkAuthorizationCodeUsed(ctx context.Context, id uint) (bool, error)
+	GetConsent(ctx context.Context, userID uint, clientID string) (*models.OAuthConsent, error)
+	SaveConsent(ctx context.Context, consent *models.OAuthConsent) error
+}
+
+// IdentityRepository defines the interface for external identity links
+type IdentityRepository interface {
+	Create(ctx context.Context, identity *models.UserIdentity) error
+	GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.UserIdentity, error)
+}
+
+// WebAuthnRepository defines the interface for passkey and security key persistence
+type WebAuthnRepository interface {
+	Create(ctx context.Context, credential *models.WebAuthnCredential) error
+	GetByCredentialID(ctx context.Context, credentialID []byte) (*models.WebAuthnCredential, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.WebAuthnCredential, error)
+	RecordUse(ctx context.Context, credential *models.WebAuthnCredential) error
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+}
+
+// APIKeyRepository defines the interface for API key persistence
+type APIKeyRepository interface {
+	Create(ctx context.Context, key *models.APIKey) error
+	GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.APIKey, error)
+	CountByUser(ctx context.Context, userID uint) (int64, error)
+	RecordUse(ctx context.Context, id uint, at time.Time) error
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+}
+
+// RefreshTokenRepository defines the interface for refresh token persistence
+type RefreshTokenRepository interface {
+	Create(ctx context.Context, token *models.RefreshToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	RevokeFamily(ctx context.Context, familyID string) error
+	RevokeAllForUser(ctx context.Context, userID uint) error
+	CreateSession(ctx context.Context, session *models.Session) error
+	GetSessionByFamily(ctx context.Context, familyID string) (*models.Session, error)
+	TouchSession(ctx context.Context, familyID, ipAddress string, at, expiresAt time.Time) error
+	ListActiveSessions(ctx context.Context, userID uint, now time.Time) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, userID, id uint) (bool, error)
+}
+
+// EmailTokenRepository defines the interface for single-use email token persistence
+type EmailTokenRepository interface {
+	Create(ctx context.Context, token *models.EmailToken) error
+	GetByHash(ctx context.Context, tokenHash string) (*models.EmailToken, error)
+	MarkUsed(ctx context.Context, id uint) (bool, error)
+	InvalidateForUser(ctx context.Context, userID uint, purpose string) error
+}
+
+// OTPRepository defines the interface for SMS login code persistence
+type OTPRepository interface {
+	Replace(ctx context.Context, code *models.OTPCode) error
+	GetByUser(ctx context.Context, userID uint) (*models.OTPCode, error)
+	RecordAttempt(ctx context.Context, id uint) error
+	Delete(ctx context.Context, id uint) (bool, error)
+}
+
+// UploadRepository defines the interface for chunked upload operations
+type UploadRepository interface {
+	CreateSession(ctx context.Context, session *models.UploadSession) error
+	GetSession(ctx context.Context, id string) (*models.UploadSession, error)
+	UpdateSession(ctx context.Context, session *models.UploadSession) error
+	SavePart(ctx context.Context, part *models.UploadPart) error
+	ListParts(ctx context.Context, sessionID string) ([]*models.UploadPart, error)
+	DeleteParts(ctx context.Context, sessionID string) error
+	ListExpiredSessions(ctx context.Context, before time.Time, limit int) ([]*models.UploadSession, error)
+}
+
+// FileRepository defines the interface for stored file metadata
+type FileRepository interface {
+	Create(ctx context.Context, file *models.File) error
+	GetByID(ctx context.Context, id uint) (*models.File, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.File, error)
+	Delete(ctx context.Context, id uint) error
+	TotalSizeByUser(ctx context.Context, userID uint) (int64, error)
+}
+
+// JobRepository defines the interface for the background job queue
+type JobRepository interface {
+	Enqueue(ctx context.Context, job *models.Job) error
+	ClaimNext(ctx context.Context, now time.Time) (*models.Job, error)
+	Complete(ctx context.Context, id uint) error
+	Retry(ctx context.Context, id uint, runAt time.Time, lastError string) error
+	Fail(ctx context.Context, id uint, lastError string) error
+	RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error)
+}
+
+// DeviceRepository defines the interface for persisting the devices users log in from
+type DeviceRepository interface {
+	Create(ctx context.Context, device *models.Device) error
+	GetByHash(ctx context.Context, userID uint, tokenHash string) (*models.Device, error)
+	Update(ctx context.Context, device *models.Device) error
+	ListByUser(ctx context.Context, userID uint) ([]*models.Device, error)
+	Delete(ctx context.Context, userID, id uint) (bool, error)
+	Prune(ctx context.Context, userID uint, keep int) error
+	RevokeTrust(ctx context.Context, userID uint) error
+}
+
+// RecoveryCodeRepository defines the interface for persisting two-factor recovery codes
+type RecoveryCodeRepository interface {
+	Replace(ctx context.Context, userID uint, codeHashes []string) error
+	Use(ctx context.Context, userID uint, codeHash string) (bool, error)
+	CountUnused(ctx context.Context, userID uint) (int64, error)
+	DeleteByUser(ctx context.Context, userID uint) error
+}
+
+// LoginEventRepository defines the interface for persisting login history
+type LoginEventRepository interface {
+	Create(ctx context.Context, event *models.LoginEvent) error
+	ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.LoginEvent, int64, error)
+	HasSucceeded(ctx context.Context, userID uint, ip string) (bool, error)
+	DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error)
+}
+
+// AuditEventRepository defines the interface for the append-only activity audit trail of users
+type AuditEventRepository interface {
+	Create(ctx context.Context, event *models.AuditEvent) error
+	ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.AuditEvent, int64, error)
+}
+
+// SettingsRepository defines the interface for persisting user preferences
+type SettingsRepository interface {
+	GetByUser(ctx context.Context, userID uint) (*models.UserSettings, error)
+	Save(ctx context.Context, settings *models.UserSettings) error
+}
+
+// OrganizationRepository defines the interface for persisting organizations and their members
+type OrganizationRepository interface {
+	Create(ctx context.Context, org *models.Organization, ownerID uint) error
+	GetByID(ctx context.Context, id uint) (*models.Organization, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.Membership, error)
+	Update(ctx context.Context, org *models.Organization) error
+	Delete(ctx context.Context, id uint) (bool, error)
+	GetMembership(ctx context.Context, orgID, userID uint) (*models.Membership, error)
+	ListMembers(ctx context.Context, orgID uint) ([]*models.Membership, error)
+	AddMember(ctx context.Context, membership *models.Membership) (bool, error)
+	UpdateMember(ctx context.Context, membership *models.Membership) error
+	RemoveMember(ctx context.Context, orgID, userID uint) (bool, error)
+	CountOwners(ctx context.Context, orgID uint) (int64, error)
+}
+
+// TagRepository defines the interface for persisting tags and the users they label
+type TagRepository interface {
+	Create(ctx context.Context, tag *models.Tag) error
+	GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.Tag, error)
+	GetByName(ctx context.Context, name string) (*models.Tag, error)
+	List(ctx context.Context) ([]*models.Tag, error)
+	Update(ctx context.Context, tag *models.Tag) error
+	Delete(ctx context.Context, id uint) (bool, error)
+	ListByIDs(ctx context.Context, ids []uint) ([]*models.Tag, error)
+	ListByUser(ctx context.Context, userID uint) ([]*models.Tag, error)
+	AssignToUser(ctx context.Context, userID uint, tagIDs []uint) error
+	RemoveFromUser(ctx context.Context, userID, tagID uint) (bool, error)
 }
 
 // Repositories holds all repository interfaces
 type Repositories struct {
-	User UserRepository
+	User         UserRepository
+	Role         RoleRepository
+	Permission   PermissionRepository
+	AccessRule   AccessRuleRepository
+	RBACAudit    RBACAuditRepository
+	OAuth        OAuthRepository
+	Identity     IdentityRepository
+	WebAuthn     WebAuthnRepository
+	APIKey       APIKeyRepository
+	Device       DeviceRepository
+	LoginEvent   LoginEventRepository
+	AuditEvent   AuditEventRepository
+	Settings     SettingsRepository
+	Organization OrganizationRepository
+	Tag          TagRepository
+	RecoveryCode RecoveryCodeRepository
+	RefreshToken RefreshTokenRepository
+	EmailToken   EmailTokenRepository
+	OTP          OTPRepository
+	Upload       UploadRepository
+	File         FileRepository
+	Job          JobRepository
+
+	db *Database
+}
+
+// Transactor runs units of work whose writes are committed together or not at all
+type Transactor interface {
+	WithTx(ctx context.Context, fn func(txRepos *Repositories) error) error
 }
 
 // NewRepositories creates a new instance of all repositories
 func NewRepositories(db *Database) *Repositories {
 	return &Repositories{
-		User: NewUserRepository(db),
+		db:           db,
+		User:         NewUserRepository(db),
+		Role:         NewRoleRepository(db),
+		Permission:   NewPermissionRepository(db),
+		AccessRule:   NewAccessRuleRepository(db),
+		RBACAudit:    NewRBACAuditRepository(db),
+		OAuth:        NewOAuthRepository(db),
+		Identity:     NewIdentityRepository(db),
+		WebAuthn:     NewWebAuthnRepository(db),
+		APIKey:       NewAPIKeyRepository(db),
+		Device:       NewDeviceRepository(db),
+		LoginEvent:   NewLoginEventRepository(db),
+		AuditEvent:   NewAuditEventRepository(db),
+		Settings:     NewSettingsRepository(db),
+		Organization: NewOrganizationRepository(db),
+		Tag:          NewTagRepository(db),
+		RecoveryCode: NewRecoveryCodeRepository(db),
+		RefreshToken: NewRefreshTokenRepository(db),
+		EmailToken:   NewEmailTokenRepository(db),
+		OTP:          NewOTPRepository(db),
+		Upload:       NewUploadRepository(db),
+		File:         NewFileRepository(db),
+		Job:          NewJobRepository(db),
 	}
 }
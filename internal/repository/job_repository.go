@@ -0,0 +1,108 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// claimAttempts bounds how often ClaimNext retries after losing a race to another worker
+const claimAttempts = 3
+
+// jobRepository implements the JobRepository interface
+type jobRepository struct {
+	db *Database
+}
+
+// NewJobRepository creates a new job repository
+func NewJobRepository(db *Database) JobRepository {
+	return &jobRepository{
+		db: db,
+	}
+}
+
+// Enqueue stores a new job
+func (r *jobRepository) Enqueue(ctx context.Context, job *models.Job) error {
+	return r.db.DB.WithContext(ctx).Create(job).Error
+}
+
+// ClaimNext marks the oldest due pending job as running and returns it.
+// It returns nil when no job is due.
+func (r *jobRepository) ClaimNext(ctx context.Context, now time.Time) (*models.Job, error) {
+	for attempt := 0; attempt < claimAttempts; attempt++ {
+		var job models.Job
+		err := r.db.DB.WithContext(ctx).
+			Where("status = ? AND run_at <= ?", models.JobStatusPending, now).
+			Order("run_at ASC, id ASC").
+			First(&job).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// Conditional update so only one worker wins the job
+		result := r.db.DB.WithContext(ctx).Model(&models.Job{}).
+			Where("id = ? AND status = ?", job.ID, models.JobStatusPending).
+			Updates(map[string]interface{}{
+				"status":    models.JobStatusRunning,
+				"locked_at": now,
+				"attempts":  gorm.Expr("attempts + 1"),
+			})
+		if result.Error != nil {
+			return nil, result.Error
+		}
+		if result.RowsAffected == 1 {
+			job.Status = models.JobStatusRunning
+			job.LockedAt = &now
+			job.Attempts++
+			return &job, nil
+		}
+	}
+	return nil, nil
+}
+
+// Complete marks a job as done
+func (r *jobRepository) Complete(ctx context.Context, id uint) error {
+	return r.db.DB.WithContext(ctx).Model(&models.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.JobStatusDone,
+		"locked_at":  nil,
+		"last_error": "",
+	}).Error
+}
+
+// Retry puts a failed job back in the queue to run at the given time
+func (r *jobRepository) Retry(ctx context.Context, id uint, runAt time.Time, lastError string) error {
+	return r.db.DB.WithContext(ctx).Model(&models.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.JobStatusPending,
+		"run_at":     runAt,
+		"locked_at":  nil,
+		"last_error": lastError,
+	}).Error
+}
+
+// Fail marks a job as permanently failed
+func (r *jobRepository) Fail(ctx context.Context, id uint, lastError string) error {
+	return r.db.DB.WithContext(ctx).Model(&models.Job{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"status":     models.JobStatusFailed,
+		"locked_at":  nil,
+		"last_error": lastError,
+	}).Error
+}
+
+// RequeueStale returns jobs stuck in running since before the given time to the queue,
+// recovering work from workers that crashed mid-job
+func (r *jobRepository) RequeueStale(ctx context.Context, lockedBefore time.Time) (int64, error) {
+	result := r.db.DB.WithContext(ctx).Model(&models.Job{}).
+		Where("status = ? AND locked_at < ?", models.JobStatusRunning, lockedBefore).
+		Updates(map[string]interface{}{
+			"status":    models.JobStatusPending,
+			"locked_at": nil,
+		})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepository_Lifecycle(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewJobRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	later := &models.Job{Type: "test", Status: models.JobStatusPending, MaxAttempts: 3, RunAt: now.Add(time.Hour)}
+	due := &models.Job{Type: "test", Payload: `{"n":1}`, Status: models.JobStatusPending, MaxAttempts: 3, RunAt: now.Add(-time.Second)}
+	require.NoError(t, repo.Enqueue(ctx, later))
+	require.NoError(t, repo.Enqueue(ctx, due))
+
+	// Only due jobs are claimed
+	job, err := repo.ClaimNext(ctx, now)
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, due.ID, job.ID)
+	assert.Equal(t, models.JobStatusRunning, job.Status)
+	assert.Equal(t, 1, job.Attempts)
+
+	// A running job isn't claimed twice
+	job, err = repo.ClaimNext(ctx, now)
+	require.NoError(t, err)
+	assert.Nil(t, job)
+
+	// Retried jobs become due again at their new run time
+	require.NoError(t, repo.Retry(ctx, due.ID, now.Add(time.Minute), "boom"))
+	job, err = repo.ClaimNext(ctx, now.Add(2*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, job)
+	assert.Equal(t, due.ID, job.ID)
+	assert.Equal(t, 2, job.Attempts)
+	assert.Equal(t, "boom", job.LastError)
+
+	require.NoError(t, repo.Complete(ctx, due.ID))
+	var stored models.Job
+	require.NoError(t, db.DB.First(&stored, due.ID).Error)
+	assert.Equal(t, models.JobStatusDone, stored.Status)
+
+	require.NoError(t, repo.Fail(ctx, later.ID, "gave up"))
+	var failed models.Job
+	require.NoError(t, db.DB.First(&failed, later.ID).Error)
+	assert.Equal(t, models.JobStatusFailed, failed.Status)
+	assert.Equal(t, "gave up", failed.LastError)
+}
+
+func TestJobRepository_RequeueStale(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewJobRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	job := &models.Job{Type: "test", Status: models.JobStatusPending, MaxAttempts: 3, RunAt: now.Add(-time.Hour)}
+	require.NoError(t, repo.Enqueue(ctx, job))
+	claimed, err := repo.ClaimNext(ctx, now.Add(-30*time.Minute))
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+
+	count, err := repo.RequeueStale(ctx, now.Add(-time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	claimed, err = repo.ClaimNext(ctx, now)
+	require.NoError(t, err)
+	require.NotNil(t, claimed)
+	assert.Equal(t, job.ID, claimed.ID)
+}
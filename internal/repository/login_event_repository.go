@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gbt-be-template/internal/models"
+)
+
+// loginEventRepository implements the LoginEventRepository interface
+type loginEventRepository struct {
+	db *Database
+}
+
+// NewLoginEventRepository creates a new login event repository
+func NewLoginEventRepository(db *Database) LoginEventRepository {
+	return &loginEventRepository{
+		db: db,
+	}
+}
+
+// Create stores a login event
+func (r *loginEventRepository) Create(ctx context.Context, event *models.LoginEvent) error {
+	return r.db.DB.WithContext(ctx).Create(event).Error
+}
+
+// ListByUser retrieves a page of a user's login history, newest first, and the total number of events
+func (r *loginEventRepository) ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.LoginEvent, int64, error) {
+	query := r.db.DB.WithContext(ctx).Model(&models.LoginEvent{}).Where("user_id = ?", userID)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.LoginEvent
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
+
+// HasSucceeded reports whether the user's login history holds a successful login, from the
+// given IP address unless ip is empty
+func (r *loginEventRepository) HasSucceeded(ctx context.Context, userID uint, ip string) (bool, error) {
+	query := r.db.DB.WithContext(ctx).Model(&models.LoginEvent{}).Where("user_id = ? AND success = ?", userID, true)
+	if ip != "" {
+		query = query.Where("ip_address = ?", ip)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// DeleteBefore removes the login events older than cutoff and returns how many were removed
+func (r *loginEventRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	result := r.db.DB.WithContext(ctx).Where("created_at < ?", cutoff).Delete(&models.LoginEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginEventRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewLoginEventRepository(db)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	for i := 0; i < 3; i++ {
+		event := &models.LoginEvent{UserID: 1, Method: models.LoginMethodPassword, Success: i != 1, CreatedAt: now.Add(time.Duration(i-2) * time.Hour)}
+		require.NoError(t, repo.Create(ctx, event))
+	}
+	require.NoError(t, repo.Create(ctx, &models.LoginEvent{UserID: 2, Method: models.LoginMethodOTP, Success: true, CreatedAt: now}))
+
+	// Newest first, paged
+	events, total, err := repo.ListByUser(ctx, 1, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, events, 2)
+	assert.True(t, events[0].CreatedAt.After(events[1].CreatedAt))
+	events, _, err = repo.ListByUser(ctx, 1, 2, 2)
+	require.NoError(t, err)
+	assert.Len(t, events, 1)
+
+	succeeded, err := repo.HasSucceeded(ctx, 1, "")
+	require.NoError(t, err)
+	assert.True(t, succeeded)
+	require.NoError(t, repo.Create(ctx, &models.LoginEvent{UserID: 3, Method: models.LoginMethodPassword, IPAddress: "10.0.0.1", CreatedAt: now}))
+	succeeded, err = repo.HasSucceeded(ctx, 3, "10.0.0.1")
+	require.NoError(t, err)
+	assert.False(t, succeeded, "failed logins don't count")
+
+	deleted, err := repo.DeleteBefore(ctx, now.Add(-90*time.Minute))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+	_, total, err = repo.ListByUser(ctx, 1, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+	gormprometheus "gorm.io/plugin/prometheus"
+)
+
+// metricsStartKey is the statement setting holding the time a statement started
+const metricsStartKey = "metrics:start"
+
+var (
+	queryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "gorm_query_duration_seconds",
+		Help:    "Duration of database statements by operation and table.",
+		Buckets: []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"operation", "table"})
+	queryErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "gorm_query_errors_total",
+		Help: "Failed database statements by operation and table.",
+	}, []string{"operation", "table"})
+
+	registerCollectors sync.Once
+)
+
+// useMetrics exports the connection pool stats of the primary, and the duration and errors
+// of statements per table, to the default Prometheus registry, which is served on
+// METRICS_PATH
+func (d *Database) useMetrics(dbName string) error {
+	registerCollectors.Do(func() {
+		prometheus.MustRegister(queryDuration, queryErrors)
+	})
+
+	err := d.DB.Use(gormprometheus.New(gormprometheus.Config{
+		DBName:          dbName,
+		RefreshInterval: 15,
+	}))
+	if err != nil {
+		return err
+	}
+	if err := registerStatementMetrics(d.DB); err != nil {
+		return err
+	}
+	if d.reader != nil {
+		return registerStatementMetrics(d.reader)
+	}
+	return nil
+}
+
+// registerStatementMetrics adds GORM callbacks timing every statement of db
+func registerStatementMetrics(db *gorm.DB) error {
+	callbacks := db.Callback()
+	return errors.Join(
+		callbacks.Create().Before("gorm:create").Register("metrics:start_create", startStatement),
+		callbacks.Create().After("gorm:create").Register("metrics:observe_create", observeStatement("create")),
+		callbacks.Query().Before("gorm:query").Register("metrics:start_query", startStatement),
+		callbacks.Query().After("gorm:query").Register("metrics:observe_query", observeStatement("query")),
+		callbacks.Update().Before("gorm:update").Register("metrics:start_update", startStatement),
+		callbacks.Update().After("gorm:update").Register("metrics:observe_update", observeStatement("update")),
+		callbacks.Delete().Before("gorm:delete").Register("metrics:start_delete", startStatement),
+		callbacks.Delete().After("gorm:delete").Register("metrics:observe_delete", observeStatement("delete")),
+		callbacks.Row().Before("gorm:row").Register("metrics:start_row", startStatement),
+		callbacks.Row().After("gorm:row").Register("metrics:observe_row", observeStatement("row")),
+		callbacks.Raw().Before("gorm:raw").Register("metrics:start_raw", startStatement),
+		callbacks.Raw().After("gorm:raw").Register("metrics:observe_raw", observeStatement("raw")),
+	)
+}
+
+// startStatement records the time a statement starts
+func startStatement(db *gorm.DB) {
+	db.InstanceSet(metricsStartKey, time.Now())
+}
+
+// observeStatement returns a callback recording the duration of a finished statement, and
+// whether it failed. Lookups finding no row aren't failures. Raw SQL is attributed to the
+// table it names.
+func observeStatement(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		value, ok := db.InstanceGet(metricsStartKey)
+		if !ok {
+			return
+		}
+		start, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+
+		table := db.Statement.Table
+		if table == "" {
+			if match := statementTable.FindStringSubmatch(db.Statement.SQL.String()); match != nil {
+				table = match[1]
+			}
+		}
+		queryDuration.WithLabelValues(operation, table).Observe(time.Since(start).Seconds())
+		if db.Error != nil && !errors.Is(db.Error, gorm.ErrRecordNotFound) {
+			queryErrors.WithLabelValues(operation, table).Inc()
+		}
+	}
+}
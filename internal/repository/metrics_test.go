@@ -0,0 +1,60 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// statementCount returns how many statements of an operation on a table were timed
+func statementCount(t *testing.T, operation, table string) uint64 {
+	var metric dto.Metric
+	require.NoError(t, queryDuration.WithLabelValues(operation, table).(prometheus.Histogram).Write(&metric))
+	return metric.GetHistogram().GetSampleCount()
+}
+
+func TestDatabase_Metrics(t *testing.T) {
+	cfg := &config.Config{
+		Database: config.DatabaseConfig{Driver: "sqlite", Name: filepath.Join(t.TempDir(), "gbt.db")},
+		Metrics:  config.MetricsConfig{Enabled: true},
+	}
+	db, err := NewDatabase(cfg, logger.New("error", "text"))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	require.NoError(t, db.AutoMigrate())
+
+	ctx := context.Background()
+	users := NewUserRepository(db)
+	creates, queries := statementCount(t, "create", "users"), statementCount(t, "query", "users")
+	require.NoError(t, users.Create(ctx, &models.User{Email: "alice@example.com", Username: "alice", Password: "x"}))
+	user, err := users.GetByID(ctx, 42)
+	require.NoError(t, err)
+	require.Nil(t, user)
+
+	assert.Equal(t, creates+1, statementCount(t, "create", "users"))
+	assert.Equal(t, queries+1, statementCount(t, "query", "users"))
+	assert.Zero(t, testutil.ToFloat64(queryErrors.WithLabelValues("query", "users")), "missing rows aren't errors")
+
+	failures := testutil.ToFloat64(queryErrors.WithLabelValues("raw", "missing"))
+	require.Error(t, db.DB.WithContext(ctx).Exec("DELETE FROM missing").Error)
+	assert.Equal(t, failures+1, testutil.ToFloat64(queryErrors.WithLabelValues("raw", "missing")))
+
+	families, err := prometheus.DefaultGatherer.Gather()
+	require.NoError(t, err)
+	names := make([]string, 0, len(families))
+	for _, family := range families {
+		names = append(names, family.GetName())
+	}
+	assert.Contains(t, names, "gorm_dbstats_open_connections")
+	assert.Contains(t, names, "gorm_query_duration_seconds")
+}
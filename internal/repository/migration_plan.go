@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// errPlanRollback aborts the transaction PlanMigration runs AutoMigrate in
+var errPlanRollback = errors.New("rollback migration plan")
+
+// PlanMigration returns the DDL statements AutoMigrate would run to bring the current schema
+// in line with the models, without applying them. AutoMigrate runs in a transaction that is
+// rolled back, which relies on transactional DDL as Postgres provides.
+// Only additive changes are found, because AutoMigrate never drops tables or columns.
+func (d *Database) PlanMigration(ctx context.Context) ([]string, error) {
+	recorder := &ddlRecorder{}
+	err := d.DB.Session(&gorm.Session{Logger: recorder, Context: ctx}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.AutoMigrate(Models()...); err != nil {
+			return err
+		}
+		return errPlanRollback
+	})
+	if !errors.Is(err, errPlanRollback) {
+		return nil, fmt.Errorf("failed to plan migration: %w", err)
+	}
+	return recorder.statements, nil
+}
+
+// ddlRecorder is a GORM logger that records the schema changing statements executed
+type ddlRecorder struct {
+	mu         sync.Mutex
+	statements []string
+}
+
+func (r *ddlRecorder) LogMode(logger.LogLevel) logger.Interface      { return r }
+func (r *ddlRecorder) Info(context.Context, string, ...interface{})  {}
+func (r *ddlRecorder) Warn(context.Context, string, ...interface{})  {}
+func (r *ddlRecorder) Error(context.Context, string, ...interface{}) {}
+
+func (r *ddlRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), err error) {
+	if err != nil {
+		return
+	}
+	sql, _ := fc()
+	sql = strings.TrimSpace(sql)
+	switch strings.ToUpper(strings.SplitN(sql, " ", 2)[0]) {
+	case "CREATE", "ALTER", "DROP", "COMMENT":
+		r.mu.Lock()
+		r.statements = append(r.statements, sql)
+		r.mu.Unlock()
+	}
+}
+
+var (
+	createTablePattern   = regexp.MustCompile(`(?i)^CREATE TABLE (?:IF NOT EXISTS )?("?[\w.]+"?)`)
+	createIndexPattern   = regexp.MustCompile(`(?i)^CREATE (?:UNIQUE )?INDEX (?:IF NOT EXISTS )?("?\w+"?)`)
+	addConstraintPattern = regexp.MustCompile(`(?i)^ALTER TABLE ("?[\w.]+"?) ADD CONSTRAINT ("?\w+"?)`)
+	addColumnPattern     = regexp.MustCompile(`(?i)^ALTER TABLE ("?[\w.]+"?) ADD (?:COLUMN )?("?\w+"?)`)
+)
+
+// ReverseMigration returns statements undoing the given ones, in reverse order.
+// Statements that cannot be reversed mechanically, such as column type changes
+// whose previous type is unknown, become TODO comments to be filled in by hand.
+func ReverseMigration(statements []string) []string {
+	reversed := make([]string, 0, len(statements))
+	for i := len(statements) - 1; i >= 0; i-- {
+		reversed = append(reversed, reverseStatement(statements[i]))
+	}
+	return reversed
+}
+
+// reverseStatement returns the statement undoing stmt
+func reverseStatement(stmt string) string {
+	if m := createTablePattern.FindStringSubmatch(stmt); m != nil {
+		return fmt.Sprintf("DROP TABLE IF EXISTS %s", m[1])
+	}
+	if m := createIndexPattern.FindStringSubmatch(stmt); m != nil {
+		return fmt.Sprintf("DROP INDEX IF EXISTS %s", m[1])
+	}
+	// Checked before columns, which would otherwise match "ADD CONSTRAINT"
+	if m := addConstraintPattern.FindStringSubmatch(stmt); m != nil {
+		return fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s", m[1], m[2])
+	}
+	if m := addColumnPattern.FindStringSubmatch(stmt); m != nil {
+		return fmt.Sprintf("ALTER TABLE %s DROP COLUMN IF EXISTS %s", m[1], m[2])
+	}
+	return "-- TODO: reverse manually: " + strings.Join(strings.Fields(stmt), " ")
+}
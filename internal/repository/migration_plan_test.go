@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestDatabase_PlanMigration(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	database := &Database{DB: db}
+	ctx := context.Background()
+
+	statements, err := database.PlanMigration(ctx)
+	require.NoError(t, err)
+	assert.True(t, containsPrefix(statements, "CREATE TABLE `users`"), statements)
+	assert.True(t, containsPrefix(statements, "CREATE UNIQUE INDEX `idx_users_email_index`"), statements)
+
+	// Planning leaves the schema untouched
+	assert.False(t, db.Migrator().HasTable(&models.User{}))
+
+	require.NoError(t, database.AutoMigrate())
+	statements, err = database.PlanMigration(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, statements)
+}
+
+func TestReverseMigration(t *testing.T) {
+	statements := []string{
+		`CREATE TABLE "notes" ("id" bigserial,"body" text,PRIMARY KEY ("id"))`,
+		`CREATE INDEX IF NOT EXISTS "idx_notes_body" ON "notes" ("body")`,
+		`ALTER TABLE "users" ADD "nickname" varchar(50)`,
+		`ALTER TABLE "notes" ADD CONSTRAINT "fk_notes_user" FOREIGN KEY ("user_id") REFERENCES "users"("id")`,
+		`ALTER TABLE "users" ALTER COLUMN "email" TYPE text`,
+	}
+
+	assert.Equal(t, []string{
+		`-- TODO: reverse manually: ALTER TABLE "users" ALTER COLUMN "email" TYPE text`,
+		`ALTER TABLE "notes" DROP CONSTRAINT IF EXISTS "fk_notes_user"`,
+		`ALTER TABLE "users" DROP COLUMN IF EXISTS "nickname"`,
+		`DROP INDEX IF EXISTS "idx_notes_body"`,
+		`DROP TABLE IF EXISTS "notes"`,
+	}, ReverseMigration(statements))
+}
+
+func containsPrefix(statements []string, prefix string) bool {
+	for _, stmt := range statements {
+		if strings.HasPrefix(stmt, prefix) {
+			return true
+		}
+	}
+	return false
+}
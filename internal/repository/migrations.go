@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gbt-be-template/migrations"
+
+	"github.com/golang-migrate/migrate/v4"
+	migratepgx "github.com/golang-migrate/migrate/v4/database/pgx/v5"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// Migrator applies the SQL migrations embedded from migrations/ with golang-migrate. The
+// applied version is tracked in the schema_migrations table, and an advisory lock keeps
+// instances starting together from migrating at the same time.
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+// Migrator opens a migrator on a connection of its own, which logs in with the current rotating
+// credentials. The SQL is written for Postgres, so other databases can't be migrated with it.
+// Close the migrator when done.
+func (d *Database) Migrator() (*Migrator, error) {
+	if d.connConfig == nil {
+		return nil, fmt.Errorf("SQL migrations require a Postgres database")
+	}
+
+	source, err := iofs.New(migrations.FS(), ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read embedded migrations: %w", err)
+	}
+	pool := d.openPostgresPool(d.connConfig)
+	driver, err := migratepgx.WithInstance(pool, &migratepgx.Config{})
+	if err != nil {
+		source.Close()
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect for migrations: %w", err)
+	}
+	m, err := migrate.NewWithInstance("iofs", source, "pgx", driver)
+	if err != nil {
+		source.Close()
+		driver.Close()
+		return nil, fmt.Errorf("failed to create migrator: %w", err)
+	}
+	return &Migrator{m: m}, nil
+}
+
+// Up applies every migration that hasn't been applied yet
+func (m *Migrator) Up() error {
+	if err := m.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return migrationError(err)
+	}
+	return nil
+}
+
+// Steps applies the next n migrations when n is positive, and rolls back the last -n applied
+// ones when it is negative
+func (m *Migrator) Steps(n int) error {
+	if err := m.m.Steps(n); err != nil {
+		return migrationError(err)
+	}
+	return nil
+}
+
+// Force records version as applied and clean without running any migration, after a failed
+// migration has been repaired by hand. -1 records that no migration is applied.
+func (m *Migrator) Force(version int) error {
+	return m.m.Force(version)
+}
+
+// Version returns the version of the last applied migration, 0 when none has been applied, and
+// whether that migration failed halfway
+func (m *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := m.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	return version, dirty, err
+}
+
+// Close releases the connection of the migrator
+func (m *Migrator) Close() error {
+	sourceErr, dbErr := m.m.Close()
+	return errors.Join(sourceErr, dbErr)
+}
+
+// migrationError explains how to recover from a migration that failed halfway
+func migrationError(err error) error {
+	var dirty migrate.ErrDirty
+	if errors.As(err, &dirty) {
+		return fmt.Errorf("migration %d failed halfway; repair the schema by hand and force the version: %w", dirty.Version, err)
+	}
+	return err
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+
+	"gbt-be-template/migrations"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+	source, err := iofs.New(migrations.FS(), ".")
+	require.NoError(t, err)
+	defer source.Close()
+
+	// Every migration file on disk is embedded, numbered without gaps and reversible
+	files, err := fs.Glob(os.DirFS("../../migrations"), "*.sql")
+	require.NoError(t, err)
+	version, err := source.First()
+	require.NoError(t, err)
+	count := 0
+	for {
+		count++
+		assert.Equal(t, uint(count), version)
+		_, _, err := source.ReadDown(version)
+		assert.NoError(t, err, "migration %d has no down file", version)
+
+		version, err = source.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		require.NoError(t, err)
+	}
+	assert.Equal(t, len(files), 2*count)
+}
+
+func TestDatabase_MigratorRequiresPostgres(t *testing.T) {
+	db := setupTestDB(t)
+	_, err := db.Migrator()
+	assert.ErrorContains(t, err, "Postgres")
+}
@@ -0,0 +1,105 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// oauthRepository implements the OAuthRepository interface
+type oauthRepository struct {
+	db *Database
+}
+
+// NewOAuthRepository creates a new OAuth repository
+func NewOAuthRepository(db *Database) OAuthRepository {
+	return &oauthRepository{
+		db: db,
+	}
+}
+
+// CreateClient registers a new OAuth client
+func (r *oauthRepository) CreateClient(ctx context.Context, client *models.OAuthClient) error {
+	return r.db.DB.WithContext(ctx).Create(client).Error
+}
+
+// GetClientByClientID retrieves a client by its public client ID
+func (r *oauthRepository) GetClientByClientID(ctx context.Context, clientID string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	if err := r.db.DB.WithContext(ctx).Where("client_id = ?", clientID).First(&client).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &client, nil
+}
+
+// ListClients retrieves all registered clients
+func (r *oauthRepository) ListClients(ctx context.Context) ([]*models.OAuthClient, error) {
+	var clients []*models.OAuthClient
+	if err := r.db.DB.WithContext(ctx).Order("created_at DESC").Find(&clients).Error; err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// DeleteClient soft deletes a client
+func (r *oauthRepository) DeleteClient(ctx context.Context, clientID string) error {
+	return r.db.DB.WithContext(ctx).Where("client_id = ?", clientID).Delete(&models.OAuthClient{}).Error
+}
+
+// CreateAuthorizationCode stores a new authorization code
+func (r *oauthRepository) CreateAuthorizationCode(ctx context.Context, code *models.OAuthAuthorizationCode) error {
+	return r.db.DB.WithContext(ctx).Create(code).Error
+}
+
+// GetAuthorizationCodeByHash retrieves an authorization code by its hash
+func (r *oauthRepository) GetAuthorizationCodeByHash(ctx context.Context, codeHash string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	if err := r.db.DB.WithContext(ctx).Where("code_hash = ?", codeHash).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// MarkAuthorizationCodeUsed atomically marks a code as used.
+// It returns false if the code had already been redeemed.
+func (r *oauthRepository) MarkAuthorizationCodeUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).
+		Model(&models.OAuthAuthorizationCode{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// GetConsent retrieves the consent a user has given to a client
+func (r *oauthRepository) GetConsent(ctx context.Context, userID uint, clientID string) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// SaveConsent creates or updates the consent a user has given to a client
+func (r *oauthRepository) SaveConsent(ctx context.Context, consent *models.OAuthConsent) error {
+	return r.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(consent).Error
+}
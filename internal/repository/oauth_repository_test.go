@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthRepository_MarkAuthorizationCodeUsed(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewOAuthRepository(db)
+	ctx := context.Background()
+
+	code := &models.OAuthAuthorizationCode{
+		CodeHash:    "hash",
+		ClientID:    "client",
+		UserID:      1,
+		RedirectURI: "https://app.example.com/callback",
+		ExpiresAt:   time.Now().Add(time.Minute),
+	}
+	require.NoError(t, repo.CreateAuthorizationCode(ctx, code))
+
+	// First redemption succeeds
+	redeemed, err := repo.MarkAuthorizationCodeUsed(ctx, code.ID)
+	assert.NoError(t, err)
+	assert.True(t, redeemed)
+
+	// Replaying the code fails
+	redeemed, err = repo.MarkAuthorizationCodeUsed(ctx, code.ID)
+	assert.NoError(t, err)
+	assert.False(t, redeemed)
+}
+
+func TestOAuthRepository_SaveConsent(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewOAuthRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SaveConsent(ctx, &models.OAuthConsent{UserID: 1, ClientID: "client", Scope: "openid"}))
+	require.NoError(t, repo.SaveConsent(ctx, &models.OAuthConsent{UserID: 1, ClientID: "client", Scope: "openid email"}))
+
+	// Saving again updates the existing consent instead of duplicating it
+	consent, err := repo.GetConsent(ctx, 1, "client")
+	assert.NoError(t, err)
+	assert.NotNil(t, consent)
+	assert.Equal(t, "openid email", consent.Scope)
+
+	missing, err := repo.GetConsent(ctx, 2, "client")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrVersionConflict is returned when saving a row that was changed since it was read
+var ErrVersionConflict = errors.New("row was changed by someone else")
+
+// versionField is the field of models whose rows are locked optimistically
+const versionField = "Version"
+
+// save saves every field of a row, like db.Save. Rows of models with a uint Version field are
+// only saved when their stored version still matches the one they were read with, and the
+// version is incremented. Otherwise the row is left alone and ErrVersionConflict returned.
+func save(db *gorm.DB, entity interface{}) error {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(entity); err != nil {
+		return err
+	}
+	field := stmt.Schema.LookUpField(versionField)
+	if field == nil {
+		return db.Save(entity).Error
+	}
+
+	ctx, value := db.Statement.Context, reflect.Indirect(reflect.ValueOf(entity))
+	current, _ := field.ValueOf(ctx, value)
+	version, ok := current.(uint)
+	if !ok {
+		return fmt.Errorf("%s.%s must be a uint", stmt.Schema.Name, versionField)
+	}
+	if err := field.Set(ctx, value, version+1); err != nil {
+		return err
+	}
+
+	result := db.Model(entity).
+		Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: version}).
+		Select("*").
+		Updates(entity)
+	err := result.Error
+	if err == nil && result.RowsAffected == 0 {
+		err = ErrVersionConflict
+	}
+	if err != nil {
+		// The caller may retry with the row as it was
+		if setErr := field.Set(ctx, value, version); setErr != nil {
+			return errors.Join(err, setErr)
+		}
+		return err
+	}
+	return nil
+}
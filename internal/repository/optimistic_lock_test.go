@@ -0,0 +1,68 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm/clause"
+)
+
+func TestUserRepository_UpdateChecksVersion(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &models.User{Email: "alice@example.com", Username: "alice", Password: "x"}
+	require.NoError(t, repo.Create(ctx, user))
+	assert.Equal(t, uint(1), user.Version)
+
+	stale, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+
+	user.FirstName = "Alice"
+	require.NoError(t, repo.Update(ctx, user))
+	assert.Equal(t, uint(2), user.Version)
+
+	// An update based on the user as it was before is refused and leaves the row alone
+	stale.FirstName = "Mallory"
+	assert.ErrorIs(t, repo.Update(ctx, stale), ErrVersionConflict)
+	assert.Equal(t, uint(1), stale.Version)
+
+	stored, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", stored.FirstName)
+	assert.Equal(t, uint(2), stored.Version)
+	assert.NotEmpty(t, stored.EmailIndex, "save hooks still run")
+
+	// Reloading gives the current version, which can be updated again
+	stored.FirstName = "Alicia"
+	require.NoError(t, repo.Update(ctx, stored))
+	assert.Equal(t, uint(3), stored.Version)
+
+	// Deleted users can't be saved back
+	require.NoError(t, repo.Delete(ctx, user.ID))
+	assert.ErrorIs(t, repo.Update(ctx, stored), ErrVersionConflict)
+}
+
+func TestUserRepository_CreateSetsVersion(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	// Only return the ID, as on databases without RETURNING, so the version can't come from
+	// the column default
+	user := &models.User{Email: "alice@example.com", Username: "alice", Password: "x"}
+	require.NoError(t, db.DB.WithContext(ctx).Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).Create(user).Error)
+	assert.Equal(t, uint(1), user.Version)
+
+	user.FirstName = "Alice"
+	require.NoError(t, repo.Update(ctx, user))
+	stored, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "Alice", stored.FirstName)
+	assert.Equal(t, uint(2), stored.Version)
+}
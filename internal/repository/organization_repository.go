@@ -0,0 +1,132 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// organizationRepository implements the OrganizationRepository interface
+type organizationRepository struct {
+	db *Database
+}
+
+// NewOrganizationRepository creates a new organization repository
+func NewOrganizationRepository(db *Database) OrganizationRepository {
+	return &organizationRepository{
+		db: db,
+	}
+}
+
+// Create stores a new organization with the given user as its owner
+func (r *organizationRepository) Create(ctx context.Context, org *models.Organization, ownerID uint) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		owner := &models.Membership{OrganizationID: org.ID, UserID: ownerID, Role: models.OrgRoleOwner}
+		return tx.Omit(clause.Associations).Create(owner).Error
+	})
+}
+
+// GetByID retrieves an organization by its ID
+func (r *organizationRepository) GetByID(ctx context.Context, id uint) (*models.Organization, error) {
+	var org models.Organization
+	if err := r.db.DB.WithContext(ctx).First(&org, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// ListByUser retrieves the memberships of a user with their organizations, ordered by
+// organization name
+func (r *organizationRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Membership, error) {
+	var memberships []*models.Membership
+	err := r.db.DB.WithContext(ctx).Preload("Organization").
+		Joins("JOIN organizations ON organizations.id = organization_memberships.organization_id").
+		Where("organization_memberships.user_id = ?", userID).
+		Order("organizations.name ASC, organizations.id ASC").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// Update saves the fields of an organization
+func (r *organizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	return r.db.DB.WithContext(ctx).Save(org).Error
+}
+
+// Delete removes an organization along with its memberships and reports whether it existed
+func (r *organizationRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	var deleted bool
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("organization_id = ?", id).Delete(&models.Membership{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Organization{}, id)
+		deleted = result.RowsAffected == 1
+		return result.Error
+	})
+	return deleted, err
+}
+
+// GetMembership retrieves the membership of a user in an organization, or nil when the user
+// isn't a member
+func (r *organizationRepository) GetMembership(ctx context.Context, orgID, userID uint) (*models.Membership, error) {
+	var membership models.Membership
+	if err := r.db.DB.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).First(&membership).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// ListMembers retrieves the members of an organization with their users, in the order they joined
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID uint) ([]*models.Membership, error) {
+	var memberships []*models.Membership
+	err := r.db.DB.WithContext(ctx).Preload("User").
+		Where("organization_id = ?", orgID).
+		Order("created_at ASC, user_id ASC").
+		Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// AddMember stores a new membership and reports false when the user already is a member
+func (r *organizationRepository) AddMember(ctx context.Context, membership *models.Membership) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(membership)
+	return result.RowsAffected == 1, result.Error
+}
+
+// UpdateMember changes the role of a member
+func (r *organizationRepository) UpdateMember(ctx context.Context, membership *models.Membership) error {
+	return r.db.DB.WithContext(ctx).Omit(clause.Associations).Save(membership).Error
+}
+
+// RemoveMember takes a user out of an organization and reports whether the user was a member
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&models.Membership{})
+	return result.RowsAffected == 1, result.Error
+}
+
+// CountOwners returns the number of owners of an organization
+func (r *organizationRepository) CountOwners(ctx context.Context, orgID uint) (int64, error) {
+	var count int64
+	err := r.db.DB.WithContext(ctx).Model(&models.Membership{}).
+		Where("organization_id = ? AND role = ?", orgID, models.OrgRoleOwner).
+		Count(&count).Error
+	return count, err
+}
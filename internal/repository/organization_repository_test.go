@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewOrganizationRepository(db)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	owner := &models.User{Email: "owner@example.com", Username: "owner", Password: "x"}
+	member := &models.User{Email: "member@example.com", Username: "member", Password: "x"}
+	for _, user := range []*models.User{owner, member} {
+		require.NoError(t, users.Create(ctx, user))
+	}
+
+	beta := &models.Organization{Name: "Beta"}
+	acme := &models.Organization{Name: "Acme"}
+	require.NoError(t, repo.Create(ctx, beta, owner.ID))
+	require.NoError(t, repo.Create(ctx, acme, owner.ID))
+
+	// Creators become owners
+	membership, err := repo.GetMembership(ctx, acme.ID, owner.ID)
+	require.NoError(t, err)
+	require.NotNil(t, membership)
+	assert.Equal(t, models.OrgRoleOwner, membership.Role)
+
+	memberships, err := repo.ListByUser(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, memberships, 2)
+	assert.Equal(t, "Acme", memberships[0].Organization.Name)
+	assert.Equal(t, "Beta", memberships[1].Organization.Name)
+
+	added, err := repo.AddMember(ctx, &models.Membership{OrganizationID: acme.ID, UserID: member.ID, Role: models.OrgRoleMember})
+	require.NoError(t, err)
+	assert.True(t, added)
+	added, err = repo.AddMember(ctx, &models.Membership{OrganizationID: acme.ID, UserID: member.ID, Role: models.OrgRoleAdmin})
+	require.NoError(t, err)
+	assert.False(t, added, "users are only added once")
+
+	members, err := repo.ListMembers(ctx, acme.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.NotNil(t, members[1].User)
+	assert.Equal(t, "member", members[1].User.Username)
+	assert.Equal(t, models.OrgRoleMember, members[1].Role)
+
+	members[1].Role = models.OrgRoleOwner
+	require.NoError(t, repo.UpdateMember(ctx, members[1]))
+	owners, err := repo.CountOwners(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), owners)
+
+	removed, err := repo.RemoveMember(ctx, acme.ID, member.ID)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	membership, err = repo.GetMembership(ctx, acme.ID, member.ID)
+	require.NoError(t, err)
+	assert.Nil(t, membership)
+
+	// Deleting an organization removes its memberships
+	deleted, err := repo.Delete(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	org, err := repo.GetByID(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.Nil(t, org)
+	memberships, err = repo.ListByUser(ctx, owner.ID)
+	require.NoError(t, err)
+	require.Len(t, memberships, 1)
+	assert.Equal(t, beta.ID, memberships[0].OrganizationID)
+
+	deleted, err = repo.Delete(ctx, acme.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// otpRepository implements the OTPRepository interface
+type otpRepository struct {
+	db *Database
+}
+
+// NewOTPRepository creates a new SMS login code repository
+func NewOTPRepository(db *Database) OTPRepository {
+	return &otpRepository{
+		db: db,
+	}
+}
+
+// Replace stores a new code for the user, discarding the pending one and its attempts
+func (r *otpRepository) Replace(ctx context.Context, code *models.OTPCode) error {
+	code.Attempts = 0
+	return r.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"code_hash", "attempts", "expires_at", "created_at"}),
+	}).Create(code).Error
+}
+
+// GetByUser retrieves the pending code of a user
+func (r *otpRepository) GetByUser(ctx context.Context, userID uint) (*models.OTPCode, error) {
+	var code models.OTPCode
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ?", userID).First(&code).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &code, nil
+}
+
+// RecordAttempt counts a wrong code entered for a pending code
+func (r *otpRepository) RecordAttempt(ctx context.Context, id uint) error {
+	return r.db.DB.WithContext(ctx).Model(&models.OTPCode{}).
+		Where("id = ?", id).
+		UpdateColumn("attempts", gorm.Expr("attempts + 1")).Error
+}
+
+// Delete removes a code and reports whether it still existed, so a code is only accepted once
+func (r *otpRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Where("id = ?", id).Delete(&models.OTPCode{})
+	return result.RowsAffected == 1, result.Error
+}
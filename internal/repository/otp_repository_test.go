@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOTPRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewOTPRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Replace(ctx, &models.OTPCode{UserID: 1, CodeHash: "first", ExpiresAt: time.Now().Add(time.Minute)}))
+	code, err := repo.GetByUser(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, code)
+	require.NoError(t, repo.RecordAttempt(ctx, code.ID))
+
+	// A new code replaces the pending one and resets its attempts
+	require.NoError(t, repo.Replace(ctx, &models.OTPCode{UserID: 1, CodeHash: "second", ExpiresAt: time.Now().Add(time.Minute)}))
+	code, err = repo.GetByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, "second", code.CodeHash)
+	assert.Equal(t, 0, code.Attempts)
+
+	require.NoError(t, repo.RecordAttempt(ctx, code.ID))
+	code, err = repo.GetByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, code.Attempts)
+
+	deleted, err := repo.Delete(ctx, code.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, code.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+
+	missing, err := repo.GetByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// permissionRepository implements the PermissionRepository interface
+type permissionRepository struct {
+	db *Database
+}
+
+// NewPermissionRepository creates a new permission repository
+func NewPermissionRepository(db *Database) PermissionRepository {
+	return &permissionRepository{
+		db: db,
+	}
+}
+
+// Create stores a new permission
+func (r *permissionRepository) Create(ctx context.Context, permission *models.Permission) error {
+	return r.db.DB.WithContext(ctx).Omit(clause.Associations).Create(permission).Error
+}
+
+// GetByID retrieves a permission by ID
+func (r *permissionRepository) GetByID(ctx context.Context, id uint) (*models.Permission, error) {
+	var permission models.Permission
+	if err := r.db.DB.WithContext(ctx).First(&permission, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &permission, nil
+}
+
+// GetByName retrieves a permission by its unique name
+func (r *permissionRepository) GetByName(ctx context.Context, name string) (*models.Permission, error) {
+	var permission models.Permission
+	if err := r.db.DB.WithContext(ctx).Where("name = ?", name).First(&permission).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &permission, nil
+}
+
+// List retrieves all permissions, ordered by resource and action
+func (r *permissionRepository) List(ctx context.Context) ([]*models.Permission, error) {
+	var permissions []*models.Permission
+	if err := r.db.DB.WithContext(ctx).Order("resource ASC, action ASC").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// ListByIDs retrieves the permissions with the given IDs, skipping unknown ones
+func (r *permissionRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.Permission, error) {
+	var permissions []*models.Permission
+	if len(ids) == 0 {
+		return permissions, nil
+	}
+	if err := r.db.DB.WithContext(ctx).Where("id IN ?", ids).Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// Update saves the fields of a permission
+func (r *permissionRepository) Update(ctx context.Context, permission *models.Permission) error {
+	return r.db.DB.WithContext(ctx).Omit(clause.Associations).Save(permission).Error
+}
+
+// Delete removes a permission and its role assignments, and reports whether it existed.
+// Permissions are deleted for good so their name can be used again.
+func (r *permissionRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	var deleted bool
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("permission_id = ?", id).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.Permission{}, id)
+		deleted = result.RowsAffected == 1
+		return result.Error
+	})
+	return deleted, err
+}
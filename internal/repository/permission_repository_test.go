@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermissionRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewPermissionRepository(db)
+	roles := NewRoleRepository(db)
+	ctx := context.Background()
+
+	update := &models.Permission{Name: models.PermissionUserUpdate, Resource: "user", Action: "update"}
+	read := &models.Permission{Name: models.PermissionUserRead, Resource: "user", Action: "read"}
+	require.NoError(t, repo.Create(ctx, update))
+	require.NoError(t, repo.Create(ctx, read))
+	assert.Error(t, repo.Create(ctx, &models.Permission{Name: models.PermissionUserRead, Resource: "user", Action: "read"}))
+
+	list, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, "read", list[0].Action)
+
+	byIDs, err := repo.ListByIDs(ctx, []uint{read.ID, 999})
+	require.NoError(t, err)
+	require.Len(t, byIDs, 1)
+	assert.Equal(t, read.ID, byIDs[0].ID)
+
+	found, err := repo.GetByName(ctx, models.PermissionUserRead)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	found.Description = "View users"
+	require.NoError(t, repo.Update(ctx, found))
+	found, err = repo.GetByID(ctx, read.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "View users", found.Description)
+
+	// Deleting a permission takes it away from roles
+	role := &models.Role{Name: models.RoleUser, IsActive: true}
+	require.NoError(t, roles.Create(ctx, role))
+	require.NoError(t, roles.ReplacePermissions(ctx, role.ID, []uint{read.ID, update.ID}))
+	deleted, err := repo.Delete(ctx, read.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	withPermissions, err := roles.GetByID(ctx, role.ID)
+	require.NoError(t, err)
+	require.Len(t, withPermissions.Permissions, 1)
+	assert.Equal(t, update.ID, withPermissions.Permissions[0].ID)
+
+	missing, err := repo.GetByID(ctx, read.ID)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+}
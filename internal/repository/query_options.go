@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// QueryOption changes which rows a read considers. Reads leave soft-deleted rows out unless
+// given WithDeleted or OnlyDeleted.
+type QueryOption func(*gorm.DB) *gorm.DB
+
+// WithDeleted makes a read include soft-deleted rows
+func WithDeleted() QueryOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	}
+}
+
+// OnlyDeleted makes a read consider soft-deleted rows only. Models without a DeletedAt field
+// have none, so the read finds nothing.
+func OnlyDeleted() QueryOption {
+	return func(db *gorm.DB) *gorm.DB {
+		model := db.Statement.Model
+		if model == nil {
+			model = db.Statement.Dest
+		}
+		if err := db.Statement.Parse(model); err != nil {
+			_ = db.AddError(err)
+			return db
+		}
+		field := db.Statement.Schema.LookUpField("DeletedAt")
+		if field == nil {
+			return db.Where("1 = 0")
+		}
+		return db.Unscoped().Where(clause.Neq{
+			Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName},
+			Value:  nil,
+		})
+	}
+}
+
+// scopes returns the options as GORM scopes
+func scopes(opts []QueryOption) []func(*gorm.DB) *gorm.DB {
+	scoped := make([]func(*gorm.DB) *gorm.DB, len(opts))
+	for i, opt := range opts {
+		scoped[i] = opt
+	}
+	return scoped
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueryOptions(t *testing.T) {
+	db := setupTestDB(t)
+	users := NewRepository[models.User](db)
+	ctx := context.Background()
+
+	kept := &models.User{Email: "kept@example.com", Username: "kept", Password: "x"}
+	deleted := &models.User{Email: "deleted@example.com", Username: "deleted", Password: "x"}
+	for _, user := range []*models.User{kept, deleted} {
+		require.NoError(t, users.Create(ctx, user))
+	}
+	_, err := users.Delete(ctx, deleted.ID)
+	require.NoError(t, err)
+
+	t.Run("get by ID", func(t *testing.T) {
+		found, err := users.GetByID(ctx, deleted.ID)
+		require.NoError(t, err)
+		assert.Nil(t, found)
+
+		found, err = users.GetByID(ctx, deleted.ID, WithDeleted())
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		assert.True(t, found.DeletedAt.Valid)
+
+		found, err = users.GetByID(ctx, kept.ID, OnlyDeleted())
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("list", func(t *testing.T) {
+		list, total, err := users.List(ctx, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, list, 1)
+		assert.Equal(t, kept.ID, list[0].ID)
+
+		list, total, err = users.List(ctx, 10, 0, WithDeleted())
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, list, 2)
+
+		list, total, err = users.List(ctx, 10, 0, OnlyDeleted())
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, list, 1)
+		assert.Equal(t, deleted.ID, list[0].ID)
+	})
+
+	t.Run("models without soft deletes", func(t *testing.T) {
+		tags := NewRepository[models.Tag](db)
+		tag := &models.Tag{Name: "vip"}
+		require.NoError(t, tags.Create(ctx, tag))
+
+		found, err := tags.GetByID(ctx, tag.ID, WithDeleted())
+		require.NoError(t, err)
+		assert.NotNil(t, found)
+
+		found, err = tags.GetByID(ctx, tag.ID, OnlyDeleted())
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+}
@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+
+	"gbt-be-template/internal/models"
+)
+
+// rbacAuditRepository implements the RBACAuditRepository interface
+type rbacAuditRepository struct {
+	db *Database
+}
+
+// NewRBACAuditRepository creates a new RBAC audit repository
+func NewRBACAuditRepository(db *Database) RBACAuditRepository {
+	return &rbacAuditRepository{
+		db: db,
+	}
+}
+
+// Create stores an audit event. Events are never updated or deleted.
+func (r *rbacAuditRepository) Create(ctx context.Context, event *models.RBACAuditEvent) error {
+	return r.db.DB.WithContext(ctx).Create(event).Error
+}
+
+// List retrieves a page of the audit events matching the filter, newest first, and the total
+// number of matching events
+func (r *rbacAuditRepository) List(ctx context.Context, filter models.RBACAuditFilter, limit, offset int) ([]*models.RBACAuditEvent, int64, error) {
+	query := r.db.DB.WithContext(ctx).Model(&models.RBACAuditEvent{})
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []*models.RBACAuditEvent
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Offset(offset).Find(&events).Error; err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRBACAuditRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRBACAuditRepository(db)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	require.NoError(t, repo.Create(ctx, &models.RBACAuditEvent{
+		ActorID: 1, Action: models.RBACAuditRoleCreated, TargetType: models.RBACAuditTargetRole, TargetID: 5,
+		After: json.RawMessage(`{"name":"moderator"}`), CreatedAt: now.Add(-time.Hour),
+	}))
+	require.NoError(t, repo.Create(ctx, &models.RBACAuditEvent{
+		ActorID: 1, Action: models.RBACAuditRolesAssigned, TargetType: models.RBACAuditTargetUser, TargetID: 7,
+		Before: json.RawMessage(`[]`), After: json.RawMessage(`["moderator"]`), CreatedAt: now,
+	}))
+	require.NoError(t, repo.Create(ctx, &models.RBACAuditEvent{
+		ActorID: 2, Action: models.RBACAuditRoleDeleted, TargetType: models.RBACAuditTargetRole, TargetID: 5,
+		Before: json.RawMessage(`{"name":"moderator"}`), CreatedAt: now,
+	}))
+
+	// Newest first, paged
+	events, total, err := repo.List(ctx, models.RBACAuditFilter{}, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), total)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.RBACAuditRoleDeleted, events[0].Action)
+	assert.JSONEq(t, `{"name":"moderator"}`, string(events[0].Before))
+
+	events, total, err = repo.List(ctx, models.RBACAuditFilter{TargetType: models.RBACAuditTargetRole, TargetID: 5}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	assert.Len(t, events, 2)
+
+	events, total, err = repo.List(ctx, models.RBACAuditFilter{ActorID: 1, Action: models.RBACAuditRolesAssigned}, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, events, 1)
+	assert.JSONEq(t, `["moderator"]`, string(events[0].After))
+}
@@ -0,0 +1,62 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// recoveryCodeRepository implements the RecoveryCodeRepository interface
+type recoveryCodeRepository struct {
+	db *Database
+}
+
+// NewRecoveryCodeRepository creates a new recovery code repository
+func NewRecoveryCodeRepository(db *Database) RecoveryCodeRepository {
+	return &recoveryCodeRepository{
+		db: db,
+	}
+}
+
+// Replace swaps all recovery codes of a user for new ones with the given hashes
+func (r *recoveryCodeRepository) Replace(ctx context.Context, userID uint, codeHashes []string) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		codes := make([]*models.RecoveryCode, len(codeHashes))
+		for i, hash := range codeHashes {
+			codes[i] = &models.RecoveryCode{UserID: userID, CodeHash: hash}
+		}
+		if len(codes) == 0 {
+			return nil
+		}
+		return tx.Create(codes).Error
+	})
+}
+
+// Use marks an unused recovery code of a user as used. It reports false when the user has no
+// unused code with the given hash, so concurrent logins can't both redeem the same code.
+func (r *recoveryCodeRepository) Use(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	return result.RowsAffected > 0, result.Error
+}
+
+// CountUnused returns how many recovery codes of a user are left
+func (r *recoveryCodeRepository) CountUnused(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	err := r.db.DB.WithContext(ctx).Model(&models.RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+// DeleteByUser removes all recovery codes of a user
+func (r *recoveryCodeRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	return r.db.DB.WithContext(ctx).Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
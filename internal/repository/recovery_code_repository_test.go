@@ -0,0 +1,50 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecoveryCodeRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRecoveryCodeRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, repo.Replace(ctx, 1, []string{"hash-1", "hash-2"}))
+	require.NoError(t, repo.Replace(ctx, 2, []string{"hash-3"}))
+
+	// Codes are single-use and only work for their own user
+	used, err := repo.Use(ctx, 2, "hash-1")
+	require.NoError(t, err)
+	assert.False(t, used)
+	used, err = repo.Use(ctx, 1, "hash-1")
+	require.NoError(t, err)
+	assert.True(t, used)
+	used, err = repo.Use(ctx, 1, "hash-1")
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	remaining, err := repo.CountUnused(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), remaining)
+
+	// Regenerating replaces used and unused codes alike
+	require.NoError(t, repo.Replace(ctx, 1, []string{"hash-4", "hash-5", "hash-6"}))
+	remaining, err = repo.CountUnused(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), remaining)
+	used, err = repo.Use(ctx, 1, "hash-2")
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	require.NoError(t, repo.DeleteByUser(ctx, 1))
+	remaining, err = repo.CountUnused(ctx, 1)
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+	remaining, err = repo.CountUnused(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), remaining)
+}
@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// refreshTokenRepository implements the RefreshTokenRepository interface
+type refreshTokenRepository struct {
+	db *Database
+}
+
+// NewRefreshTokenRepository creates a new refresh token repository
+func NewRefreshTokenRepository(db *Database) RefreshTokenRepository {
+	return &refreshTokenRepository{
+		db: db,
+	}
+}
+
+// Create stores a new refresh token
+func (r *refreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	return r.db.DB.WithContext(ctx).Create(token).Error
+}
+
+// GetByHash retrieves a refresh token by its hash
+func (r *refreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.DB.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkUsed atomically marks a token as used.
+// It returns false if the token had already been used or revoked.
+func (r *refreshTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).
+		Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RevokeFamily revokes every token in a family and ends its session
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	return r.revoke(ctx, "family_id = ? AND revoked_at IS NULL", familyID)
+}
+
+// RevokeAllForUser revokes every refresh token belonging to a user and ends all their sessions
+func (r *refreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	return r.revoke(ctx, "user_id = ? AND revoked_at IS NULL", userID)
+}
+
+// CreateSession stores the session of a new token family
+func (r *refreshTokenRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	return r.db.DB.WithContext(ctx).Create(session).Error
+}
+
+// GetSessionByFamily retrieves the session of a token family
+func (r *refreshTokenRepository) GetSessionByFamily(ctx context.Context, familyID string) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.DB.WithContext(ctx).Where("family_id = ?", familyID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession records a refresh of the session of a token family
+func (r *refreshTokenRepository) TouchSession(ctx context.Context, familyID, ipAddress string, at, expiresAt time.Time) error {
+	return r.db.DB.WithContext(ctx).
+		Model(&models.Session{}).
+		Where("family_id = ?", familyID).
+		Updates(map[string]interface{}{
+			"ip_address":   ipAddress,
+			"last_used_at": at,
+			"expires_at":   expiresAt,
+		}).Error
+}
+
+// ListActiveSessions retrieves the sessions of a user that are neither revoked nor expired,
+// most recently used first
+func (r *refreshTokenRepository) ListActiveSessions(ctx context.Context, userID uint, now time.Time) ([]*models.Session, error) {
+	var sessions []*models.Session
+	if err := r.db.DB.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now).
+		Order("last_used_at DESC").
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// RevokeSession ends an active session of a user together with its refresh tokens.
+// It returns false if the session doesn't exist, belongs to another user or already ended.
+func (r *refreshTokenRepository) RevokeSession(ctx context.Context, userID, id uint) (bool, error) {
+	var session models.Session
+	err := r.db.DB.WithContext(ctx).Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).First(&session).Error
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, r.RevokeFamily(ctx, session.FamilyID)
+}
+
+// revoke revokes the refresh tokens and sessions matching a condition in one transaction
+func (r *refreshTokenRepository) revoke(ctx context.Context, query string, arg interface{}) error {
+	now := time.Now()
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.RefreshToken{}).Where(query, arg).Update("revoked_at", now).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Session{}).Where(query, arg).Update("revoked_at", now).Error
+	})
+}
@@ -0,0 +1,115 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefreshTokenRepository_MarkUsed(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	token := &models.RefreshToken{
+		UserID:    1,
+		FamilyID:  "family",
+		TokenHash: "hash",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+	require.NoError(t, repo.Create(ctx, token))
+
+	// First rotation succeeds
+	marked, err := repo.MarkUsed(ctx, token.ID)
+	assert.NoError(t, err)
+	assert.True(t, marked)
+
+	// A second rotation of the same token fails
+	marked, err = repo.MarkUsed(ctx, token.ID)
+	assert.NoError(t, err)
+	assert.False(t, marked)
+}
+
+func TestRefreshTokenRepository_RevokeFamily(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+
+	first := &models.RefreshToken{UserID: 1, FamilyID: "family", TokenHash: "first", ExpiresAt: time.Now().Add(time.Hour)}
+	second := &models.RefreshToken{UserID: 1, FamilyID: "family", TokenHash: "second", ExpiresAt: time.Now().Add(time.Hour)}
+	other := &models.RefreshToken{UserID: 1, FamilyID: "other", TokenHash: "other", ExpiresAt: time.Now().Add(time.Hour)}
+	require.NoError(t, repo.Create(ctx, first))
+	require.NoError(t, repo.Create(ctx, second))
+	require.NoError(t, repo.Create(ctx, other))
+
+	require.NoError(t, repo.RevokeFamily(ctx, "family"))
+
+	revoked, err := repo.GetByHash(ctx, "second")
+	require.NoError(t, err)
+	assert.NotNil(t, revoked.RevokedAt)
+
+	// Revoked tokens can no longer be rotated
+	marked, err := repo.MarkUsed(ctx, second.ID)
+	assert.NoError(t, err)
+	assert.False(t, marked)
+
+	// Other families are untouched
+	untouched, err := repo.GetByHash(ctx, "other")
+	require.NoError(t, err)
+	assert.Nil(t, untouched.RevokedAt)
+}
+
+func TestRefreshTokenRepository_Sessions(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRefreshTokenRepository(db)
+	ctx := context.Background()
+	now := time.Now()
+
+	laptop := &models.Session{UserID: 1, FamilyID: "laptop", UserAgent: "Firefox", IPAddress: "10.0.0.1", LastUsedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)}
+	phone := &models.Session{UserID: 1, FamilyID: "phone", UserAgent: "Safari", IPAddress: "10.0.0.2", LastUsedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Hour)}
+	expired := &models.Session{UserID: 1, FamilyID: "expired", LastUsedAt: now.Add(-48 * time.Hour), ExpiresAt: now.Add(-time.Hour)}
+	other := &models.Session{UserID: 2, FamilyID: "other", LastUsedAt: now, ExpiresAt: now.Add(time.Hour)}
+	for _, session := range []*models.Session{laptop, phone, expired, other} {
+		require.NoError(t, repo.CreateSession(ctx, session))
+	}
+	require.NoError(t, repo.Create(ctx, &models.RefreshToken{UserID: 1, FamilyID: "phone", TokenHash: "phone-token", ExpiresAt: now.Add(time.Hour)}))
+
+	// A refresh moves the session to the top
+	require.NoError(t, repo.TouchSession(ctx, "phone", "10.0.0.3", now, now.Add(2*time.Hour)))
+	sessions, err := repo.ListActiveSessions(ctx, 1, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, phone.ID, sessions[0].ID)
+	assert.Equal(t, "10.0.0.3", sessions[0].IPAddress)
+	assert.Equal(t, laptop.ID, sessions[1].ID)
+
+	// Sessions of other users can't be revoked
+	revoked, err := repo.RevokeSession(ctx, 2, phone.ID)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = repo.RevokeSession(ctx, 1, phone.ID)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	token, err := repo.GetByHash(ctx, "phone-token")
+	require.NoError(t, err)
+	assert.NotNil(t, token.RevokedAt)
+
+	revoked, err = repo.RevokeSession(ctx, 1, phone.ID)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Revoking all of a user's tokens ends their sessions too
+	require.NoError(t, repo.RevokeAllForUser(ctx, 1))
+	sessions, err = repo.ListActiveSessions(ctx, 1, now)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+	sessions, err = repo.ListActiveSessions(ctx, 2, now)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 1)
+}
@@ -0,0 +1,167 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// roleRepository implements the RoleRepository interface
+type roleRepository struct {
+	db *Database
+}
+
+// NewRoleRepository creates a new role repository
+func NewRoleRepository(db *Database) RoleRepository {
+	return &roleRepository{
+		db: db,
+	}
+}
+
+// Create stores a new role
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
+	return r.db.DB.WithContext(ctx).Omit(clause.Associations).Create(role).Error
+}
+
+// GetByID retrieves a role with its permissions
+func (r *roleRepository) GetByID(ctx context.Context, id uint) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.DB.WithContext(ctx).Preload("Permissions").First(&role, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// GetByName retrieves a role by its unique name
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	var role models.Role
+	if err := r.db.DB.WithContext(ctx).Where("name = ?", name).First(&role).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &role, nil
+}
+
+// List retrieves all roles with their permissions, ordered by name
+func (r *roleRepository) List(ctx context.Context) ([]*models.Role, error) {
+	var roles []*models.Role
+	if err := r.db.DB.WithContext(ctx).Preload("Permissions").Order("name ASC").Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// Update saves the fields of a role, leaving its permissions alone
+func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
+	return r.db.DB.WithContext(ctx).Omit(clause.Associations).Save(role).Error
+}
+
+// Delete removes a role along with its permission and user assignments, and reports whether it
+// existed. Roles inheriting from it stop inheriting. Roles are deleted for good so their name can
+// be used again.
+func (r *roleRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	var deleted bool
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("role_id = ?", id).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.Role{}).Where("parent_role_id = ?", id).Update("parent_role_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Delete(&models.Role{}, id)
+		deleted = result.RowsAffected == 1
+		return result.Error
+	})
+	return deleted, err
+}
+
+// ReplacePermissions sets the permissions of a role to exactly the given ones
+func (r *roleRepository) ReplacePermissions(ctx context.Context, roleID uint, permissionIDs []uint) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", roleID).Delete(&models.RolePermission{}).Error; err != nil {
+			return err
+		}
+		if len(permissionIDs) == 0 {
+			return nil
+		}
+		rows := make([]models.RolePermission, len(permissionIDs))
+		for i, permissionID := range permissionIDs {
+			rows[i] = models.RolePermission{RoleID: roleID, PermissionID: permissionID}
+		}
+		return tx.Omit(clause.Associations).Create(&rows).Error
+	})
+}
+
+// ListByIDs retrieves the roles with the given IDs, skipping unknown ones
+func (r *roleRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.Role, error) {
+	var roles []*models.Role
+	if len(ids) == 0 {
+		return roles, nil
+	}
+	if err := r.db.DB.WithContext(ctx).Where("id IN ?", ids).Find(&roles).Error; err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// ListByUser retrieves the roles assigned to a user with their permissions, ordered by name
+func (r *roleRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Role, error) {
+	var roles []*models.Role
+	err := r.db.DB.WithContext(ctx).Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name ASC").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// AssignToUser gives roles to a user. The roles are inserted in a single statement, so either all
+// or none are given. Roles the user already has are kept as they are.
+func (r *roleRepository) AssignToUser(ctx context.Context, userID uint, roleIDs []uint) error {
+	if len(roleIDs) == 0 {
+		return nil
+	}
+	rows := make([]models.UserRole, len(roleIDs))
+	for i, roleID := range roleIDs {
+		rows[i] = models.UserRole{UserID: userID, RoleID: roleID}
+	}
+	return r.db.DB.WithContext(ctx).Omit(clause.Associations).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// ReplaceUserRoles gives a user exactly the given roles, taking away any others
+func (r *roleRepository) ReplaceUserRoles(ctx context.Context, userID uint, roleIDs []uint) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if len(roleIDs) == 0 {
+			return nil
+		}
+		rows := make([]models.UserRole, len(roleIDs))
+		for i, roleID := range roleIDs {
+			rows[i] = models.UserRole{UserID: userID, RoleID: roleID}
+		}
+		return tx.Omit(clause.Associations).Create(&rows).Error
+	})
+}
+
+// RemoveFromUser takes a role away from a user and reports whether the user had it
+func (r *roleRepository) RemoveFromUser(ctx context.Context, userID, roleID uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{})
+	return result.RowsAffected == 1, result.Error
+}
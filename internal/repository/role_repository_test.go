@@ -0,0 +1,128 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRoleRepository(db)
+	permissions := NewPermissionRepository(db)
+	ctx := context.Background()
+
+	read := &models.Permission{Name: models.PermissionUserRead, Resource: "user", Action: "read"}
+	update := &models.Permission{Name: models.PermissionUserUpdate, Resource: "user", Action: "update"}
+	require.NoError(t, permissions.Create(ctx, read))
+	require.NoError(t, permissions.Create(ctx, update))
+
+	role := &models.Role{Name: models.RoleModerator, Description: "Moderates users", IsActive: true}
+	require.NoError(t, repo.Create(ctx, role))
+	require.NoError(t, repo.Create(ctx, &models.Role{Name: models.RoleUser, IsActive: true}))
+
+	// Names are unique
+	assert.Error(t, repo.Create(ctx, &models.Role{Name: models.RoleModerator}))
+
+	found, err := repo.GetByName(ctx, models.RoleModerator)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, role.ID, found.ID)
+	missing, err := repo.GetByID(ctx, 999)
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// Permissions are replaced as a whole
+	require.NoError(t, repo.ReplacePermissions(ctx, role.ID, []uint{read.ID, update.ID}))
+	require.NoError(t, repo.ReplacePermissions(ctx, role.ID, []uint{update.ID}))
+	found, err = repo.GetByID(ctx, role.ID)
+	require.NoError(t, err)
+	require.Len(t, found.Permissions, 1)
+	assert.Equal(t, models.PermissionUserUpdate, found.Permissions[0].Name)
+
+	found.Description = "Moderates users and content"
+	found.IsActive = false
+	require.NoError(t, repo.Update(ctx, found))
+	roles, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	assert.Equal(t, "Moderates users and content", roles[0].Description)
+	assert.False(t, roles[0].IsActive)
+	assert.Len(t, roles[0].Permissions, 1, "updates leave permissions alone")
+
+	child := &models.Role{Name: "trainee", IsActive: true, ParentRoleID: &role.ID}
+	require.NoError(t, repo.Create(ctx, child))
+
+	// Deleted roles free their name and assignments
+	deleted, err := repo.Delete(ctx, role.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	deleted, err = repo.Delete(ctx, role.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	var assignments int64
+	require.NoError(t, db.DB.Model(&models.RolePermission{}).Where("role_id = ?", role.ID).Count(&assignments).Error)
+	assert.Zero(t, assignments)
+	child, err = repo.GetByID(ctx, child.ID)
+	require.NoError(t, err)
+	assert.Nil(t, child.ParentRoleID, "roles stop inheriting from deleted roles")
+	assert.NoError(t, repo.Create(ctx, &models.Role{Name: models.RoleModerator, IsActive: true}))
+}
+
+func TestRoleRepository_UserRoles(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewRoleRepository(db)
+	ctx := context.Background()
+
+	moderator := &models.Role{Name: models.RoleModerator, IsActive: true}
+	user := &models.Role{Name: models.RoleUser, IsActive: true}
+	require.NoError(t, repo.Create(ctx, moderator))
+	require.NoError(t, repo.Create(ctx, user))
+
+	require.NoError(t, repo.AssignToUser(ctx, 1, []uint{user.ID}))
+	// Assigning a role again is a no-op
+	require.NoError(t, repo.AssignToUser(ctx, 1, []uint{moderator.ID, user.ID}))
+	require.NoError(t, repo.AssignToUser(ctx, 2, []uint{user.ID}))
+
+	roles, err := repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, roles, 2)
+	assert.Equal(t, models.RoleModerator, roles[0].Name)
+	assert.Equal(t, models.RoleUser, roles[1].Name)
+
+	byIDs, err := repo.ListByIDs(ctx, []uint{user.ID, 999})
+	require.NoError(t, err)
+	assert.Len(t, byIDs, 1)
+
+	removed, err := repo.RemoveFromUser(ctx, 1, moderator.ID)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = repo.RemoveFromUser(ctx, 1, moderator.ID)
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	roles, err = repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, user.ID, roles[0].ID)
+	roles, err = repo.ListByUser(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, roles, 1, "other users keep their roles")
+
+	require.NoError(t, repo.ReplaceUserRoles(ctx, 1, []uint{moderator.ID}))
+	roles, err = repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, moderator.ID, roles[0].ID)
+	require.NoError(t, repo.ReplaceUserRoles(ctx, 1, nil))
+	roles, err = repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Empty(t, roles)
+	roles, err = repo.ListByUser(ctx, 2)
+	require.NoError(t, err)
+	assert.Len(t, roles, 1, "other users keep their roles")
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// settingsRepository implements the SettingsRepository interface
+type settingsRepository struct {
+	db *Database
+}
+
+// NewSettingsRepository creates a new settings repository
+func NewSettingsRepository(db *Database) SettingsRepository {
+	return &settingsRepository{
+		db: db,
+	}
+}
+
+// GetByUser retrieves the saved settings of a user, or nil when the user has none
+func (r *settingsRepository) GetByUser(ctx context.Context, userID uint) (*models.UserSettings, error) {
+	var settings models.UserSettings
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ?", userID).First(&settings).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Save stores the settings of a user, replacing any saved before
+func (r *settingsRepository) Save(ctx context.Context, settings *models.UserSettings) error {
+	return r.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}},
+		UpdateAll: true,
+	}).Create(settings).Error
+}
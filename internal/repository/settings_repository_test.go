@@ -0,0 +1,40 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewSettingsRepository(db)
+	ctx := context.Background()
+
+	// Users start without saved settings
+	settings, err := repo.GetByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Nil(t, settings)
+
+	require.NoError(t, repo.Save(ctx, &models.UserSettings{UserID: 1, Timezone: "Europe/Berlin", Locale: "de", Notifications: map[string]bool{"newsletter": true}}))
+	require.NoError(t, repo.Save(ctx, &models.UserSettings{UserID: 2, Timezone: "UTC", Locale: "en"}))
+
+	// Saving again replaces the previous settings
+	require.NoError(t, repo.Save(ctx, &models.UserSettings{UserID: 1, Timezone: "Asia/Tokyo", Locale: "ja", Notifications: map[string]bool{"newsletter": false}}))
+
+	settings, err = repo.GetByUser(ctx, 1)
+	require.NoError(t, err)
+	require.NotNil(t, settings)
+	assert.Equal(t, "Asia/Tokyo", settings.Timezone)
+	assert.Equal(t, "ja", settings.Locale)
+	assert.Equal(t, map[string]bool{"newsletter": false}, settings.Notifications)
+
+	others, err := repo.GetByUser(ctx, 2)
+	require.NoError(t, err)
+	require.NotNil(t, others)
+	assert.Equal(t, "UTC", others.Timezone)
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tagRepository implements the TagRepository interface. Create, GetByID and Update come from
+// the base repository.
+type tagRepository struct {
+	Repository[models.Tag]
+}
+
+// NewTagRepository creates a new tag repository
+func NewTagRepository(db *Database) TagRepository {
+	return &tagRepository{
+		Repository: NewRepository[models.Tag](db),
+	}
+}
+
+// GetByName retrieves a tag by its name
+func (r *tagRepository) GetByName(ctx context.Context, name string) (*models.Tag, error) {
+	return r.FindOne(ctx, "name = ?", name)
+}
+
+// List retrieves all tags ordered by name
+func (r *tagRepository) List(ctx context.Context) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	if err := r.db.DB.WithContext(ctx).Order("name ASC").Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// Delete removes a tag, untagging its users, and reports whether it existed
+func (r *tagRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	var deleted bool
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tag_id = ?", id).Delete(&models.UserTag{}).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&models.Tag{}, id)
+		deleted = result.RowsAffected == 1
+		return result.Error
+	})
+	return deleted, err
+}
+
+// ListByIDs retrieves the tags with the given IDs, skipping unknown ones
+func (r *tagRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	if len(ids) == 0 {
+		return tags, nil
+	}
+	if err := r.db.DB.WithContext(ctx).Where("id IN ?", ids).Find(&tags).Error; err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// ListByUser retrieves the tags of a user ordered by name
+func (r *tagRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	err := r.db.DB.WithContext(ctx).
+		Joins("JOIN user_tags ON user_tags.tag_id = tags.id").
+		Where("user_tags.user_id = ?", userID).
+		Order("tags.name ASC").
+		Find(&tags).Error
+	if err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// AssignToUser tags a user. The tags are inserted in a single statement, so either all or none
+// are added. Tags the user already has are kept as they are.
+func (r *tagRepository) AssignToUser(ctx context.Context, userID uint, tagIDs []uint) error {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	rows := make([]models.UserTag, len(tagIDs))
+	for i, tagID := range tagIDs {
+		rows[i] = models.UserTag{UserID: userID, TagID: tagID}
+	}
+	return r.db.DB.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// RemoveFromUser untags a user and reports whether the user had the tag
+func (r *tagRepository) RemoveFromUser(ctx context.Context, userID, tagID uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Where("user_id = ? AND tag_id = ?", userID, tagID).Delete(&models.UserTag{})
+	return result.RowsAffected == 1, result.Error
+}
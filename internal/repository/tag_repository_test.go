@@ -0,0 +1,80 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewTagRepository(db)
+	users := NewUserRepository(db)
+	ctx := context.Background()
+
+	alice := &models.User{Email: "alice@example.com", Username: "alice", Password: "x"}
+	bob := &models.User{Email: "bob@example.com", Username: "bob", Password: "x"}
+	for _, user := range []*models.User{alice, bob} {
+		require.NoError(t, users.Create(ctx, user))
+	}
+
+	vip := &models.Tag{Name: "vip"}
+	beta := &models.Tag{Name: "beta-testers"}
+	require.NoError(t, repo.Create(ctx, vip))
+	require.NoError(t, repo.Create(ctx, beta))
+	assert.Error(t, repo.Create(ctx, &models.Tag{Name: "vip"}), "names are unique")
+
+	found, err := repo.GetByName(ctx, "vip")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, vip.ID, found.ID)
+
+	require.NoError(t, repo.AssignToUser(ctx, alice.ID, []uint{vip.ID, beta.ID}))
+	require.NoError(t, repo.AssignToUser(ctx, alice.ID, []uint{vip.ID}), "tags already given are kept")
+	require.NoError(t, repo.AssignToUser(ctx, bob.ID, []uint{beta.ID}))
+
+	tags, err := repo.ListByUser(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "beta-testers", tags[0].Name)
+	assert.Equal(t, "vip", tags[1].Name)
+
+	t.Run("users are filtered by tags", func(t *testing.T) {
+		listed, total, err := users.List(ctx, models.UserFilter{Tags: []string{"beta-testers"}}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(2), total)
+		assert.Len(t, listed, 2)
+
+		listed, total, err = users.List(ctx, models.UserFilter{Tags: []string{"beta-testers", "vip"}}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+		require.Len(t, listed, 1)
+		assert.Equal(t, alice.ID, listed[0].ID)
+
+		_, total, err = users.List(ctx, models.UserFilter{Tags: []string{"unknown"}}, nil, 10, 0)
+		require.NoError(t, err)
+		assert.Zero(t, total)
+	})
+
+	removed, err := repo.RemoveFromUser(ctx, alice.ID, vip.ID)
+	require.NoError(t, err)
+	assert.True(t, removed)
+	removed, err = repo.RemoveFromUser(ctx, alice.ID, vip.ID)
+	require.NoError(t, err)
+	assert.False(t, removed)
+
+	// Deleting a tag untags its users
+	deleted, err := repo.Delete(ctx, beta.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	tags, err = repo.ListByUser(ctx, bob.ID)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+	deleted, err = repo.Delete(ctx, beta.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
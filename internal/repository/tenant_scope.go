@@ -0,0 +1,76 @@
+package repository
+
+import (
+	"reflect"
+
+	"gbt-be-template/pkg/tenant"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantField is the field of models whose rows belong to a tenant
+const tenantField = "TenantID"
+
+// registerTenantScope adds GORM callbacks isolating tenants. Statements on models with a
+// TenantID field whose context is scoped to a tenant only see and change that tenant's rows,
+// and rows they create are given to it. Statements without a tenant in their context, such as
+// those of background jobs, and raw SQL are not scoped.
+func registerTenantScope(db *gorm.DB) error {
+	callbacks := db.Callback()
+	if err := callbacks.Create().Before("gorm:create").Register("tenant:create", setTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Query().Before("gorm:query").Register("tenant:query", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Update().Before("gorm:update").Register("tenant:update", scopeTenant); err != nil {
+		return err
+	}
+	if err := callbacks.Delete().Before("gorm:delete").Register("tenant:delete", scopeTenant); err != nil {
+		return err
+	}
+	return callbacks.Row().Before("gorm:row").Register("tenant:row", scopeTenant)
+}
+
+// scopeTenant limits a statement to the rows of the tenant of its context
+func scopeTenant(db *gorm.DB) {
+	id, ok := tenant.FromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+	db.Statement.AddClause(clause.Where{Exprs: []clause.Expression{
+		clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: field.DBName}, Value: id},
+	}})
+}
+
+// setTenant gives the rows created by a statement to the tenant of its context
+func setTenant(db *gorm.DB) {
+	id, ok := tenant.FromContext(db.Statement.Context)
+	if !ok || db.Statement.Schema == nil {
+		return
+	}
+	field := db.Statement.Schema.LookUpField(tenantField)
+	if field == nil {
+		return
+	}
+
+	ctx := db.Statement.Context
+	switch value := db.Statement.ReflectValue; value.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < value.Len(); i++ {
+			if err := field.Set(ctx, reflect.Indirect(value.Index(i)), id); err != nil {
+				db.AddError(err)
+				return
+			}
+		}
+	case reflect.Struct:
+		if err := field.Set(ctx, value, id); err != nil {
+			db.AddError(err)
+		}
+	}
+}
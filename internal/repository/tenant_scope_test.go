@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/tenant"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantScope(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, registerTenantScope(db.DB))
+	repo := NewUserRepository(db)
+	acme := tenant.WithID(context.Background(), "acme")
+	globex := tenant.WithID(context.Background(), "globex")
+
+	// The same email and username can be used by each tenant
+	acmeUser := &models.User{Email: "user@example.com", Username: "user", Password: "x", IsActive: true}
+	globexUser := &models.User{Email: "user@example.com", Username: "user", Password: "x", IsActive: true}
+	require.NoError(t, repo.Create(acme, acmeUser))
+	require.NoError(t, repo.Create(globex, globexUser))
+	assert.Equal(t, "acme", acmeUser.TenantID)
+	assert.Equal(t, "globex", globexUser.TenantID)
+	assert.Error(t, repo.Create(acme, &models.User{Email: "user@example.com", Username: "other", Password: "x"}))
+
+	found, err := repo.GetByEmail(acme, "user@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, acmeUser.ID, found.ID)
+
+	found, err = repo.GetByID(acme, globexUser.ID)
+	require.NoError(t, err)
+	assert.Nil(t, found, "rows of other tenants are hidden")
+
+	count, err := repo.Count(globex)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	// Changes don't reach rows of other tenants
+	require.NoError(t, repo.Delete(acme, globexUser.ID))
+	found, err = repo.GetByID(globex, globexUser.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, found)
+
+	// Contexts without a tenant see every tenant
+	count, err = repo.Count(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+}
@@ -0,0 +1,16 @@
+package repository
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// WithTx runs fn in a database transaction, handing it repositories whose statements are part
+// of it. The transaction is committed when fn returns nil and rolled back when it returns an
+// error or panics. Calls of WithTx on txRepos run in a nested transaction, backed by a savepoint.
+func (r *Repositories) WithTx(ctx context.Context, fn func(txRepos *Repositories) error) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return fn(NewRepositories(&Database{DB: tx}))
+	})
+}
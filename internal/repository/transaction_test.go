@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositories_WithTx(t *testing.T) {
+	repos := NewRepositories(setupTestDB(t))
+	ctx := context.Background()
+
+	role := &models.Role{Name: "editor", IsActive: true}
+	require.NoError(t, repos.Role.Create(ctx, role))
+
+	t.Run("writes are committed together", func(t *testing.T) {
+		user := &models.User{Email: "alice@example.com", Username: "alice", Password: "x"}
+		err := repos.WithTx(ctx, func(txRepos *Repositories) error {
+			if err := txRepos.User.Create(ctx, user); err != nil {
+				return err
+			}
+			return txRepos.Role.AssignToUser(ctx, user.ID, []uint{role.ID})
+		})
+		require.NoError(t, err)
+
+		found, err := repos.User.GetByEmail(ctx, "alice@example.com")
+		require.NoError(t, err)
+		require.NotNil(t, found)
+		roles, err := repos.Role.ListByUser(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, roles, 1)
+		assert.Equal(t, "editor", roles[0].Name)
+	})
+
+	t.Run("an error rolls back every write", func(t *testing.T) {
+		failure := errors.New("failure")
+		err := repos.WithTx(ctx, func(txRepos *Repositories) error {
+			user := &models.User{Email: "bob@example.com", Username: "bob", Password: "x"}
+			if err := txRepos.User.Create(ctx, user); err != nil {
+				return err
+			}
+			if err := txRepos.Role.AssignToUser(ctx, user.ID, []uint{role.ID}); err != nil {
+				return err
+			}
+			return failure
+		})
+		assert.ErrorIs(t, err, failure)
+
+		found, err := repos.User.GetByEmail(ctx, "bob@example.com")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+
+	t.Run("nested units of work roll back on their own", func(t *testing.T) {
+		failure := errors.New("failure")
+		err := repos.WithTx(ctx, func(txRepos *Repositories) error {
+			if err := txRepos.User.Create(ctx, &models.User{Email: "carol@example.com", Username: "carol", Password: "x"}); err != nil {
+				return err
+			}
+			nested := txRepos.WithTx(ctx, func(nestedRepos *Repositories) error {
+				if err := nestedRepos.User.Create(ctx, &models.User{Email: "dave@example.com", Username: "dave", Password: "x"}); err != nil {
+					return err
+				}
+				return failure
+			})
+			assert.ErrorIs(t, nested, failure)
+			return nil
+		})
+		require.NoError(t, err)
+
+		found, err := repos.User.GetByEmail(ctx, "carol@example.com")
+		require.NoError(t, err)
+		assert.NotNil(t, found)
+		found, err = repos.User.GetByEmail(ctx, "dave@example.com")
+		require.NoError(t, err)
+		assert.Nil(t, found)
+	})
+}
@@ -0,0 +1,77 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// uploadRepository implements the UploadRepository interface
+type uploadRepository struct {
+	db *Database
+}
+
+// NewUploadRepository creates a new upload repository
+func NewUploadRepository(db *Database) UploadRepository {
+	return &uploadRepository{
+		db: db,
+	}
+}
+
+// CreateSession stores a new upload session
+func (r *uploadRepository) CreateSession(ctx context.Context, session *models.UploadSession) error {
+	return r.db.DB.WithContext(ctx).Create(session).Error
+}
+
+// GetSession retrieves an upload session by ID
+func (r *uploadRepository) GetSession(ctx context.Context, id string) (*models.UploadSession, error) {
+	var session models.UploadSession
+	if err := r.db.DB.WithContext(ctx).Where("id = ?", id).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// UpdateSession saves changes to an upload session
+func (r *uploadRepository) UpdateSession(ctx context.Context, session *models.UploadSession) error {
+	return r.db.DB.WithContext(ctx).Save(session).Error
+}
+
+// SavePart records a received part, replacing an earlier upload of the same part
+func (r *uploadRepository) SavePart(ctx context.Context, part *models.UploadPart) error {
+	return r.db.DB.WithContext(ctx).Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "session_id"}, {Name: "part_number"}},
+		DoUpdates: clause.AssignmentColumns([]string{"size"}),
+	}).Create(part).Error
+}
+
+// ListParts returns the received parts of a session ordered by part number
+func (r *uploadRepository) ListParts(ctx context.Context, sessionID string) ([]*models.UploadPart, error) {
+	var parts []*models.UploadPart
+	err := r.db.DB.WithContext(ctx).Where("session_id = ?", sessionID).Order("part_number ASC").Find(&parts).Error
+	return parts, err
+}
+
+// DeleteParts removes the part records of a session
+func (r *uploadRepository) DeleteParts(ctx context.Context, sessionID string) error {
+	return r.db.DB.WithContext(ctx).Where("session_id = ?", sessionID).Delete(&models.UploadPart{}).Error
+}
+
+// ListExpiredSessions returns pending sessions that expired before the given time
+func (r *uploadRepository) ListExpiredSessions(ctx context.Context, before time.Time, limit int) ([]*models.UploadSession, error) {
+	var sessions []*models.UploadSession
+	err := r.db.DB.WithContext(ctx).
+		Where("status = ? AND expires_at < ?", models.UploadStatusPending, before).
+		Order("expires_at ASC").
+		Limit(limit).
+		Find(&sessions).Error
+	return sessions, err
+}
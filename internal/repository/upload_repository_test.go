@@ -0,0 +1,51 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUploadRepository_SavePart(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUploadRepository(db)
+	ctx := context.Background()
+
+	require.NoError(t, repo.SavePart(ctx, &models.UploadPart{SessionID: "upload", PartNumber: 2, Size: 10}))
+	require.NoError(t, repo.SavePart(ctx, &models.UploadPart{SessionID: "upload", PartNumber: 1, Size: 10}))
+
+	// Re-uploading a part replaces it instead of duplicating it
+	require.NoError(t, repo.SavePart(ctx, &models.UploadPart{SessionID: "upload", PartNumber: 1, Size: 5}))
+
+	parts, err := repo.ListParts(ctx, "upload")
+	require.NoError(t, err)
+	require.Len(t, parts, 2)
+	assert.Equal(t, 1, parts[0].PartNumber)
+	assert.Equal(t, int64(5), parts[0].Size)
+}
+
+func TestUploadRepository_ListExpiredSessions(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUploadRepository(db)
+	ctx := context.Background()
+
+	sessions := []*models.UploadSession{
+		{ID: "expired", Status: models.UploadStatusPending, ExpiresAt: time.Now().Add(-time.Hour)},
+		{ID: "active", Status: models.UploadStatusPending, ExpiresAt: time.Now().Add(time.Hour)},
+		{ID: "completed", Status: models.UploadStatusCompleted, ExpiresAt: time.Now().Add(-time.Hour)},
+	}
+	for _, session := range sessions {
+		session.UserID, session.Purpose, session.Filename, session.TotalSize, session.ChunkSize = 1, models.UploadPurposeImport, "a.csv", 1, 1
+		require.NoError(t, repo.CreateSession(ctx, session))
+	}
+
+	expired, err := repo.ListExpiredSessions(ctx, time.Now(), 10)
+	require.NoError(t, err)
+	require.Len(t, expired, 1)
+	assert.Equal(t, "expired", expired[0].ID)
+}
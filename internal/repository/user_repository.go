@@ -3,11 +3,16 @@ package repository
 import (
 	"context"
 	"errors"
+	"fmt"
+	"strconv"
+	"strings"
 	"time"
 
 	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/fieldcrypt"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 // userRepository implements the UserRepository interface
@@ -30,10 +35,11 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 	return nil
 }
 
-// GetByID retrieves a user by ID
-func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
+// GetByID retrieves a user by ID. Soft-deleted users are only found with WithDeleted or
+// OnlyDeleted. It reads from the primary, as the user is usually changed and saved next.
+func (r *userRepository) GetByID(ctx context.Context, id uint, opts ...QueryOption) (*models.User, error) {
 	var user models.User
-	if err := r.db.DB.WithContext(ctx).First(&user, id).Error; err != nil {
+	if err := r.db.DB.WithContext(ctx).Scopes(scopes(opts)...).First(&user, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -45,7 +51,7 @@ func (r *userRepository) GetByID(ctx context.Context, id uint) (*models.User, er
 // GetByEmail retrieves a user by email
 func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
 	var user models.User
-	if err := r.db.DB.WithContext(ctx).Where("email = ?", email).First(&user).Error; err != nil {
+	if err := r.db.DB.WithContext(ctx).Where("email_index IN ?", fieldcrypt.Default().BlindIndexes(email)).First(&user).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, nil
 		}
@@ -54,6 +60,19 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 	return &user, nil
 }
 
+// GetByPhone retrieves the user with the given phone number. Phone numbers aren't unique,
+// so nil is returned when several users share the number as well as when none has it.
+func (r *userRepository) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
+	var users []*models.User
+	if err := r.db.DB.WithContext(ctx).Where("phone_index IN ?", fieldcrypt.Default().BlindIndexes(phone)).Limit(2).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	if len(users) != 1 || users[0].Phone != phone {
+		return nil, nil
+	}
+	return users[0], nil
+}
+
 // GetByUsername retrieves a user by username
 func (r *userRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	var user models.User
@@ -66,9 +85,28 @@ func (r *userRepository) GetByUsername(ctx context.Context, username string) (*m
 	return &user, nil
 }
 
-// Update updates a user
+// GetByEmailOrUsername retrieves a user by email or username in a single query.
+// An email match wins over a username that happens to equal another user's email.
+func (r *userRepository) GetByEmailOrUsername(ctx context.Context, login string) (*models.User, error) {
+	var users []*models.User
+	if err := r.db.DB.WithContext(ctx).Where("email_index IN ? OR username = ?", fieldcrypt.Default().BlindIndexes(login), login).Limit(2).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range users {
+		if user.Email == login {
+			return user, nil
+		}
+	}
+	if len(users) == 0 {
+		return nil, nil
+	}
+	return users[0], nil
+}
+
+// Update updates a user and increments its version. It fails with ErrVersionConflict when the
+// user was updated since it was read.
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
-	return r.db.DB.WithContext(ctx).Save(user).Error
+	return save(r.db.DB.WithContext(ctx), user)
 }
 
 // Delete soft deletes a user
@@ -76,10 +114,55 @@ func (r *userRepository) Delete(ctx context.Context, id uint) error {
 	return r.db.DB.WithContext(ctx).Delete(&models.User{}, id).Error
 }
 
-// List retrieves a list of users with pagination
-func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models.User, error) {
+// Restore undoes the soft delete of a user and reports whether the user was deleted
+func (r *userRepository) Restore(ctx context.Context, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Unscoped().Model(&models.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		UpdateColumn("deleted_at", nil)
+	return result.RowsAffected == 1, result.Error
+}
+
+// HardDelete permanently removes a user, soft deleted or not, and reports whether the user existed.
+// Rows referencing the user are removed by the foreign keys.
+func (r *userRepository) HardDelete(ctx context.Context, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Unscoped().Delete(&models.User{}, id)
+	return result.RowsAffected == 1, result.Error
+}
+
+// PurgeDeleted permanently removes the users soft deleted before cutoff and returns their IDs
+func (r *userRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.User{}).
+			Where("deleted_at < ?", cutoff).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Unscoped().Where("id IN ? AND deleted_at < ?", ids, cutoff).Delete(&models.User{}).Error
+	})
+	return ids, err
+}
+
+// List retrieves a page of the users matching the filter, newest first, and the total number of
+// matching users
+func (r *userRepository) List(ctx context.Context, filter models.UserFilter, sort []models.SortField, limit, offset int) ([]*models.User, int64, error) {
+	terms, err := userOrderTerms(sort)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	query := r.filtered(r.db.Reader(ctx), filter)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
 	var users []*models.User
-	query := r.db.DB.WithContext(ctx).Order("created_at DESC")
+	query = query.Order(orderClause(terms, false))
 	
 	if limit > 0 {
 		query = query.Limit(limit)
@@ -90,31 +173,281 @@ func (r *userRepository) List(ctx context.Context, limit, offset int) ([]*models
 	}
 	
 	if err := query.Find(&users).Error; err != nil {
-		return nil, err
+		return nil, 0, err
 	}
 	
-	return users, nil
+	return users, total, nil
+}
+
+// ListByCursor retrieves the page of at most limit users matching the filter next to the cursor,
+// or the first page for a nil cursor, with keyset pagination. It returns cursors to the pages
+// after and before it, which are nil at either end of the list.
+func (r *userRepository) ListByCursor(ctx context.Context, filter models.UserFilter, sort []models.SortField, cursor *models.Cursor, limit int) ([]*models.User, *models.Cursor, *models.Cursor, error) {
+	terms, err := userOrderTerms(sort)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for _, term := range terms {
+		if term.column.nullable {
+			return nil, nil, nil, fmt.Errorf("%w: %s can't be paginated with a cursor", ErrUnknownSortField, term.column.name)
+		}
+	}
+
+	key := models.FormatSort(sort)
+	query := r.filtered(r.db.Reader(ctx), filter)
+	before := false
+	if cursor != nil {
+		if cursor.Sort != key || len(cursor.Values) != len(terms) {
+			return nil, nil, nil, models.ErrInvalidCursor
+		}
+		condition, values, err := keysetCondition(terms, cursor)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		query = query.Where(condition, values...)
+		before = cursor.Before
+	}
+
+	// One more user than asked for tells whether there is another page in the same direction
+	var users []*models.User
+	if err := query.Order(orderClause(terms, before)).Limit(limit + 1).Find(&users).Error; err != nil {
+		return nil, nil, nil, err
+	}
+	more := len(users) > limit
+	if more {
+		users = users[:limit]
+	}
+	if before {
+		for i, j := 0, len(users)-1; i < j; i, j = i+1, j-1 {
+			users[i], users[j] = users[j], users[i]
+		}
+	}
+	if len(users) == 0 {
+		return users, nil, nil, nil
+	}
+
+	var next, prev *models.Cursor
+	if more || before {
+		next = &models.Cursor{Sort: key, Values: cursorValues(terms, users[len(users)-1])}
+	}
+	if (before && more) || (!before && cursor != nil) {
+		prev = &models.Cursor{Sort: key, Values: cursorValues(terms, users[0]), Before: true}
+	}
+	return users, next, prev, nil
+}
+
+// filtered returns a query on db for the users matching the filter
+func (r *userRepository) filtered(db *gorm.DB, filter models.UserFilter) *gorm.DB {
+	query := db.Model(&models.User{})
+	if filter.Deleted {
+		query = query.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	if search := strings.TrimSpace(filter.Search); search != "" {
+		// Emails are encrypted, so they can only be matched as a whole through the blind index
+		names := r.db.DB.Session(&gorm.Session{NewDB: true})
+		for _, word := range strings.Fields(strings.ToLower(search)) {
+			pattern := likeEscaper.Replace(word) + "%"
+			names = names.Where(`(LOWER(username) LIKE ? ESCAPE '\' OR LOWER(first_name) LIKE ? ESCAPE '\' OR LOWER(last_name) LIKE ? ESCAPE '\')`, pattern, pattern, pattern)
+		}
+		query = query.Where(names.Or("email_index IN ?", fieldcrypt.Default().BlindIndexes(search)))
+	}
+	if filter.IsActive != nil {
+		query = query.Where("is_active = ?", *filter.IsActive)
+	}
+	if filter.IsAdmin != nil {
+		query = query.Where("is_admin = ?", *filter.IsAdmin)
+	}
+	if filter.CreatedAfter != nil {
+		query = query.Where("created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		query = query.Where("created_at < ?", *filter.CreatedBefore)
+	}
+	for _, name := range filter.Tags {
+		tagged := r.db.DB.Session(&gorm.Session{NewDB: true}).Table("user_tags").
+			Select("user_tags.user_id").
+			Joins("JOIN tags ON tags.id = user_tags.tag_id").
+			Where("tags.name = ?", name)
+		query = query.Where("id IN (?)", tagged)
+	}
+	return query
+}
+
+// userSortColumn is a column users can be sorted by
+type userSortColumn struct {
+	name     string
+	time     bool // Compared as a time in cursors
+	nullable bool // Can't be paginated with cursors, as NULLs don't compare
+}
+
+// userSortColumns are the columns users can be sorted by, keyed by field name. Encrypted fields
+// such as the email can't be sorted.
+var userSortColumns = map[string]userSortColumn{
+	"id":         {name: "id"},
+	"username":   {name: "username"},
+	"first_name": {name: "first_name"},
+	"last_name":  {name: "last_name"},
+	"created_at": {name: "created_at", time: true},
+	"updated_at": {name: "updated_at", time: true},
+	"last_login": {name: "last_login", time: true, nullable: true},
+}
+
+// orderTerm is a column of an ORDER BY clause
+type orderTerm struct {
+	column     userSortColumn
+	descending bool
+}
+
+// userOrderTerms returns the terms for sorting users by the given fields, newest first by default.
+// The ID breaks ties, so pages don't overlap.
+func userOrderTerms(sort []models.SortField) ([]orderTerm, error) {
+	if len(sort) == 0 {
+		return []orderTerm{
+			{column: userSortColumns["created_at"], descending: true},
+			{column: userSortColumns["id"], descending: true},
+		}, nil
+	}
+	terms := make([]orderTerm, 0, len(sort)+1)
+	byID := false
+	for _, field := range sort {
+		column, ok := userSortColumns[field.Name]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownSortField, field.Name)
+		}
+		byID = byID || column.name == "id"
+		terms = append(terms, orderTerm{column: column, descending: field.Descending})
+	}
+	if !byID {
+		terms = append(terms, orderTerm{column: userSortColumns["id"]})
+	}
+	return terms, nil
+}
+
+// orderClause returns the ORDER BY clause for the terms, with every direction flipped when
+// reversed
+func orderClause(terms []orderTerm, reversed bool) string {
+	clauses := make([]string, len(terms))
+	for i, term := range terms {
+		clauses[i] = term.column.name
+		if term.descending != reversed {
+			clauses[i] += " DESC"
+		}
+	}
+	return strings.Join(clauses, ", ")
+}
+
+// keysetCondition returns the condition selecting the rows after the cursor in the order of the
+// terms, or before it for a cursor pointing backwards: the first column is past the cursor's
+// value, or equal with the second column past it, and so on.
+func keysetCondition(terms []orderTerm, cursor *models.Cursor) (string, []interface{}, error) {
+	values := make([]interface{}, len(terms))
+	for i, term := range terms {
+		value, err := parseCursorValue(term.column, cursor.Values[i])
+		if err != nil {
+			return "", nil, err
+		}
+		values[i] = value
+	}
+
+	alternatives := make([]string, len(terms))
+	var args []interface{}
+	for i, term := range terms {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, terms[j].column.name+" = ?")
+			args = append(args, values[j])
+		}
+		operator := " > ?"
+		if term.descending != cursor.Before {
+			operator = " < ?"
+		}
+		parts = append(parts, term.column.name+operator)
+		args = append(args, values[i])
+		alternatives[i] = "(" + strings.Join(parts, " AND ") + ")"
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
+
+// cursorValues returns the values of the sort columns of a user, as cursors hold them
+func cursorValues(terms []orderTerm, user *models.User) []string {
+	values := make([]string, len(terms))
+	for i, term := range terms {
+		switch term.column.name {
+		case "id":
+			values[i] = strconv.FormatUint(uint64(user.ID), 10)
+		case "username":
+			values[i] = user.Username
+		case "first_name":
+			values[i] = user.FirstName
+		case "last_name":
+			values[i] = user.LastName
+		case "created_at":
+			values[i] = user.CreatedAt.Format(time.RFC3339Nano)
+		case "updated_at":
+			values[i] = user.UpdatedAt.Format(time.RFC3339Nano)
+		}
+	}
+	return values
+}
+
+// parseCursorValue parses a value of a cursor for comparing it with the column
+func parseCursorValue(column userSortColumn, value string) (interface{}, error) {
+	switch {
+	case column.name == "id":
+		id, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		return uint(id), nil
+	case column.time:
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, models.ErrInvalidCursor
+		}
+		return t, nil
+	default:
+		return value, nil
+	}
+}
+
+// likeEscaper escapes the wildcards of LIKE patterns, which use \ as the escape character
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// ForEach calls fn for every user matching the filter, loading batchSize users at a time
+// ordered by ID. Iteration stops at the first error returned by fn.
+func (r *userRepository) ForEach(ctx context.Context, filter models.UserFilter, batchSize int, fn func(*models.User) error) error {
+	var batch []*models.User
+	return r.filtered(r.db.DB.WithContext(ctx), filter).FindInBatches(&batch, batchSize, func(tx *gorm.DB, _ int) error {
+		for _, user := range batch {
+			if err := fn(user); err != nil {
+				return err
+			}
+		}
+		return nil
+	}).Error
 }
 
 // Count returns the total number of users
 func (r *userRepository) Count(ctx context.Context) (int64, error) {
 	var count int64
-	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
+	if err := r.db.Reader(ctx).Model(&models.User{}).Count(&count).Error; err != nil {
 		return 0, err
 	}
 	return count, nil
 }
 
-// ExistsByEmail checks if a user exists with the given email
+// ExistsByEmail checks if a user exists with the given email. It reads from the primary, since
+// it guards the creation of users against duplicates.
 func (r *userRepository) ExistsByEmail(ctx context.Context, email string) (bool, error) {
 	var count int64
-	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Where("email = ?", email).Count(&count).Error; err != nil {
+	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Where("email_index IN ?", fieldcrypt.Default().BlindIndexes(email)).Count(&count).Error; err != nil {
 		return false, err
 	}
 	return count > 0, nil
 }
 
-// ExistsByUsername checks if a user exists with the given username
+// ExistsByUsername checks if a user exists with the given username. Like ExistsByEmail, it
+// reads from the primary.
 func (r *userRepository) ExistsByUsername(ctx context.Context, username string) (bool, error) {
 	var count int64
 	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Where("username = ?", username).Count(&count).Error; err != nil {
@@ -123,8 +456,248 @@ func (r *userRepository) ExistsByUsername(ctx context.Context, username string)
 	return count > 0, nil
 }
 
-// UpdateLastLogin updates the last login time for a user
+// UpdateLastLogin updates the last login time for a user and clears failed login attempts
 func (r *userRepository) UpdateLastLogin(ctx context.Context, userID uint) error {
 	now := time.Now()
-	return r.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Update("last_login", now).Error
+	return r.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"last_login":            now,
+		"failed_login_attempts": 0,
+		"locked_until":          nil,
+	}).Error
+}
+
+// RecordFailedLogin increments the failed login counter and returns the new count
+func (r *userRepository) RecordFailedLogin(ctx context.Context, userID uint) (int, error) {
+	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+		UpdateColumn("failed_login_attempts", gorm.Expr("failed_login_attempts + 1")).Error; err != nil {
+		return 0, err
+	}
+
+	var attempts int
+	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+		Select("failed_login_attempts").Scan(&attempts).Error; err != nil {
+		return 0, err
+	}
+	return attempts, nil
+}
+
+// LockUntil locks a user out of password login until the given time and resets the failed login counter
+func (r *userRepository) LockUntil(ctx context.Context, userID uint, until time.Time) error {
+	return r.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).UpdateColumns(map[string]interface{}{
+		"locked_until":          until,
+		"failed_login_attempts": 0,
+	}).Error
+}
+
+// UseTOTPStep records step as the time step of the user's last accepted TOTP code. It reports
+// false when a code of the same or a later step was already accepted, so each code works once.
+func (r *userRepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND totp_last_step < ?", userID, step).
+		UpdateColumn("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+// MustChangePassword reports whether the user has to change their password. Unknown users report false.
+func (r *userRepository) MustChangePassword(ctx context.Context, userID uint) (bool, error) {
+	var flags []bool
+	if err := r.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).
+		Pluck("must_change_password", &flags).Error; err != nil {
+		return false, err
+	}
+	return len(flags) == 1 && flags[0], nil
+}
+
+// LiftExpiredSuspensions clears suspensions whose end time has passed and returns the affected user IDs
+func (r *userRepository) LiftExpiredSuspensions(ctx context.Context, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("suspended_at IS NOT NULL AND suspended_until <= ?", now).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		return tx.Model(&models.User{}).Where("id IN ? AND suspended_until <= ?", ids, now).UpdateColumns(map[string]interface{}{
+			"suspended_at":      nil,
+			"suspended_until":   nil,
+			"suspended_by":      nil,
+			"suspension_reason": "",
+		}).Error
+	})
+	return ids, err
+}
+
+// AnonymizeDeletedAccounts scrubs the personal data of accounts whose deletion grace period ended at now,
+// soft deletes them and returns the affected user IDs. The email and username are replaced with
+// placeholders derived from the ID so they can be registered again.
+func (r *userRepository) AnonymizeDeletedAccounts(ctx context.Context, now time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.User{}).
+			Where("deletion_scheduled_at <= ?", now).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		columns := anonymizedColumns()
+		columns["deleted_at"] = now
+		if err := tx.Model(&models.User{}).Where("id IN ? AND deletion_scheduled_at <= ?", ids, now).UpdateColumns(columns).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.UserIdentity{}).Where("user_id IN ?", ids).UpdateColumn("email", "").Error
+	})
+	return ids, err
+}
+
+// AnonymizeDeletedUsers scrubs the personal data of users soft deleted at or before deletedBefore
+// that still have it, and returns their IDs. Rows are kept, so whatever references the users
+// stays intact.
+func (r *userRepository) AnonymizeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]uint, error) {
+	var ids []uint
+	err := r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&models.User{}).
+			Where("deleted_at IS NOT NULL AND deleted_at <= ? AND email_index IS NOT NULL", deletedBefore).
+			Pluck("id", &ids).Error; err != nil {
+			return err
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+		if err := tx.Unscoped().Model(&models.User{}).Where("id IN ?", ids).UpdateColumns(anonymizedColumns()).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.UserIdentity{}).Where("user_id IN ?", ids).UpdateColumn("email", "").Error
+	})
+	return ids, err
+}
+
+// anonymizedColumns returns the columns of users holding personal data with the values that
+// replace it. The email and username become placeholders derived from the ID, and the email
+// index is cleared, which marks the user as anonymized.
+func anonymizedColumns() map[string]interface{} {
+	return map[string]interface{}{
+		"email":                 gorm.Expr("'deleted-' || CAST(id AS TEXT) || '@deleted.invalid'"),
+		"email_index":           nil,
+		"pending_email":         "",
+		"username":              gorm.Expr("'deleted-' || CAST(id AS TEXT)"),
+		"password":              "",
+		"first_name":            "",
+		"last_name":             "",
+		"phone":                 "",
+		"phone_index":           nil,
+		"metadata":              nil,
+		"is_active":             false,
+		"last_login":            nil,
+		"avatar_file_id":        nil,
+		"avatar_urls":           nil,
+		"suspension_reason":     "",
+		"totp_secret":           "",
+		"totp_enabled":          false,
+		"deletion_scheduled_at": nil,
+	}
+}
+
+// SetAvatarFile records the source file of a user's avatar; the current variants stay until the new ones are processed
+func (r *userRepository) SetAvatarFile(ctx context.Context, userID, fileID uint) error {
+	return r.db.DB.WithContext(ctx).Model(&models.User{}).Where("id = ?", userID).UpdateColumn("avatar_file_id", fileID).Error
+}
+
+// SetAvatarURLs stores processed avatar variants if fileID is still the user's avatar source.
+// It reports false when the avatar was replaced in the meantime.
+func (r *userRepository) SetAvatarURLs(ctx context.Context, userID, fileID uint, urls map[string]string) (bool, error) {
+	// A struct update so the JSON serializer applies to the column
+	result := r.db.DB.WithContext(ctx).Model(&models.User{}).
+		Where("id = ? AND avatar_file_id = ?", userID, fileID).
+		Select("avatar_urls").
+		UpdateColumns(&models.User{AvatarURLs: urls})
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReencryptUsers rewrites the encrypted columns of up to limit users holding values that do not
+// start with prefix, the prefix of the current primary key, and returns how many were rewritten.
+// Saving re-encrypts them with the primary key and recomputes the blind indexes. Users with a
+// phone number but no phone index, encrypted before the index existed, are rewritten as well.
+func (r *userRepository) ReencryptUsers(ctx context.Context, prefix string, limit int) (int, error) {
+	pattern := prefix + "%"
+	var users []*models.User
+	if err := r.db.DB.WithContext(ctx).
+		Where("(email <> '' AND email NOT LIKE ?) OR (phone <> '' AND phone NOT LIKE ?) OR (metadata IS NOT NULL AND metadata NOT LIKE ?) OR (totp_secret <> '' AND totp_secret NOT LIKE ?) OR (pending_email <> '' AND pending_email NOT LIKE ?) OR (phone <> '' AND phone_index IS NULL)", pattern, pattern, pattern, pattern, pattern).
+		Order("id").Limit(limit).Find(&users).Error; err != nil {
+		return 0, err
+	}
+
+	for i, user := range users {
+		if err := r.db.DB.WithContext(ctx).Model(user).Select("email", "email_index", "pending_email", "phone", "phone_index", "metadata", "totp_secret").Updates(user).Error; err != nil {
+			return i, err
+		}
+	}
+	return len(users), nil
+}
+
+// TransferOwnership moves what a user owns to another user in a single transaction: linked
+// external identities, files, organization memberships and tags. Where both users are members
+// of an organization, the higher of their roles is kept.
+func (r *userRepository) TransferOwnership(ctx context.Context, fromID, toID uint) error {
+	return r.db.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.UserIdentity{}, &models.File{}} {
+			if err := tx.Model(model).Where("user_id = ?", fromID).UpdateColumn("user_id", toID).Error; err != nil {
+				return err
+			}
+		}
+
+		var memberships []*models.Membership
+		if err := tx.Where("user_id IN ?", []uint{fromID, toID}).Find(&memberships).Error; err != nil {
+			return err
+		}
+		kept := make(map[uint]*models.Membership)
+		for _, membership := range memberships {
+			if membership.UserID == toID {
+				kept[membership.OrganizationID] = membership
+			}
+		}
+		for _, membership := range memberships {
+			if membership.UserID != fromID {
+				continue
+			}
+			existing, ok := kept[membership.OrganizationID]
+			if !ok {
+				if err := tx.Model(&models.Membership{}).
+					Where("organization_id = ? AND user_id = ?", membership.OrganizationID, fromID).
+					UpdateColumn("user_id", toID).Error; err != nil {
+					return err
+				}
+				continue
+			}
+			if !models.OrgRoleAtLeast(existing.Role, membership.Role) {
+				if err := tx.Model(&models.Membership{}).
+					Where("organization_id = ? AND user_id = ?", membership.OrganizationID, toID).
+					Update("role", membership.Role).Error; err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("organization_id = ? AND user_id = ?", membership.OrganizationID, fromID).Delete(&models.Membership{}).Error; err != nil {
+				return err
+			}
+		}
+
+		var tagIDs []uint
+		if err := tx.Model(&models.UserTag{}).Where("user_id = ?", fromID).Pluck("tag_id", &tagIDs).Error; err != nil {
+			return err
+		}
+		if len(tagIDs) > 0 {
+			rows := make([]models.UserTag, len(tagIDs))
+			for i, tagID := range tagIDs {
+				rows[i] = models.UserTag{UserID: toID, TagID: tagID}
+			}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Where("user_id = ?", fromID).Delete(&models.UserTag{}).Error
+	})
 }
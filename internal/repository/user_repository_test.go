@@ -1,11 +1,16 @@
 package repository
 
 import (
+	"bytes"
 	"context"
+	"errors"
+	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/fieldcrypt"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -110,6 +115,68 @@ func TestUserRepository_GetByEmail(t *testing.T) {
 	assert.Nil(t, notFoundUser)
 }
 
+func TestUserRepository_GetByEmailOrUsername(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	alice := &models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword", IsActive: true}
+	require.NoError(t, repo.Create(ctx, alice))
+	// A username that looks like another user's email
+	mallory := &models.User{Email: "mallory@example.com", Username: "alice@example.com", Password: "hashedpassword", IsActive: true}
+	require.NoError(t, repo.Create(ctx, mallory))
+
+	found, err := repo.GetByEmailOrUsername(ctx, "alice")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, alice.ID, found.ID)
+
+	found, err = repo.GetByEmailOrUsername(ctx, "mallory@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, mallory.ID, found.ID)
+
+	// The email match wins
+	found, err = repo.GetByEmailOrUsername(ctx, "alice@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, alice.ID, found.ID)
+
+	found, err = repo.GetByEmailOrUsername(ctx, "nobody")
+	assert.NoError(t, err)
+	assert.Nil(t, found)
+}
+
+func TestUserRepository_GetByPhone(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	alice := &models.User{Email: "alice@example.com", Username: "alice", Password: "hashedpassword", Phone: "+15550001111", IsActive: true}
+	require.NoError(t, repo.Create(ctx, alice))
+
+	found, err := repo.GetByPhone(ctx, "+15550001111")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, alice.ID, found.ID)
+
+	// Removing the phone number clears the index
+	alice.Phone = ""
+	require.NoError(t, repo.Update(ctx, alice))
+	found, err = repo.GetByPhone(ctx, "+15550001111")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	// A number shared by several users identifies none of them
+	alice.Phone = "+15550002222"
+	require.NoError(t, repo.Update(ctx, alice))
+	bob := &models.User{Email: "bob@example.com", Username: "bob", Password: "hashedpassword", Phone: "+15550002222", IsActive: true}
+	require.NoError(t, repo.Create(ctx, bob))
+	found, err = repo.GetByPhone(ctx, "+15550002222")
+	require.NoError(t, err)
+	assert.Nil(t, found)
+}
+
 func TestUserRepository_Update(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewUserRepository(db)
@@ -169,6 +236,85 @@ func TestUserRepository_Delete(t *testing.T) {
 	assert.Nil(t, deletedUser) // Should be nil due to soft delete
 }
 
+func TestUserRepository_Restore(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	kept := &models.User{Email: "kept@example.com", Username: "kept", Password: "x"}
+	deleted := &models.User{Email: "deleted@example.com", Username: "deleted", Password: "x"}
+	for _, user := range []*models.User{kept, deleted} {
+		require.NoError(t, repo.Create(ctx, user))
+	}
+	require.NoError(t, repo.Delete(ctx, deleted.ID))
+
+	// Soft-deleted users are only visible to lookups with WithDeleted and the deleted filter
+	found, err := repo.GetByID(ctx, deleted.ID, WithDeleted())
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.True(t, found.DeletedAt.Valid)
+
+	users, total, err := repo.List(ctx, models.UserFilter{Deleted: true}, nil, 10, 0)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), total)
+	require.Len(t, users, 1)
+	assert.Equal(t, deleted.ID, users[0].ID)
+
+	restored, err := repo.Restore(ctx, deleted.ID)
+	require.NoError(t, err)
+	assert.True(t, restored)
+
+	found, err = repo.GetByID(ctx, deleted.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "deleted@example.com", found.Email)
+
+	// Restoring a user that isn't deleted is a no-op
+	restored, err = repo.Restore(ctx, kept.ID)
+	require.NoError(t, err)
+	assert.False(t, restored)
+}
+
+func TestUserRepository_HardDelete(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	old := &models.User{Email: "old@example.com", Username: "old", Password: "x"}
+	recent := &models.User{Email: "recent@example.com", Username: "recent", Password: "x"}
+	active := &models.User{Email: "active@example.com", Username: "active", Password: "x"}
+	for _, user := range []*models.User{old, recent, active} {
+		require.NoError(t, repo.Create(ctx, user))
+	}
+	require.NoError(t, db.DB.Model(old).UpdateColumn("deleted_at", now.Add(-48*time.Hour)).Error)
+	require.NoError(t, db.DB.Model(recent).UpdateColumn("deleted_at", now.Add(-time.Hour)).Error)
+
+	// Only users soft deleted before the cutoff are purged
+	ids, err := repo.PurgeDeleted(ctx, now.Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []uint{old.ID}, ids)
+
+	var count int64
+	require.NoError(t, db.DB.Unscoped().Model(&models.User{}).Where("id = ?", old.ID).Count(&count).Error)
+	assert.Zero(t, count)
+	found, err := repo.GetByID(ctx, recent.ID, WithDeleted())
+	require.NoError(t, err)
+	assert.NotNil(t, found)
+
+	// Hard deletes don't need the user to be soft deleted first
+	deleted, err := repo.HardDelete(ctx, active.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+	found, err = repo.GetByID(ctx, active.ID, WithDeleted())
+	require.NoError(t, err)
+	assert.Nil(t, found)
+
+	deleted, err = repo.HardDelete(ctx, active.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+}
+
 func TestUserRepository_ExistsByEmail(t *testing.T) {
 	db := setupTestDB(t)
 	repo := NewUserRepository(db)
@@ -226,3 +372,592 @@ func TestUserRepository_UpdateLastLogin(t *testing.T) {
 	assert.NotNil(t, updatedUser.LastLogin)
 	assert.WithinDuration(t, time.Now(), *updatedUser.LastLogin, time.Minute)
 }
+
+func TestUserRepository_LiftExpiredSuspensions(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	expired := &models.User{Email: "expired@example.com", Username: "expired", Password: "x", IsActive: true,
+		SuspendedAt: &past, SuspendedUntil: &past, SuspensionReason: "spam"}
+	active := &models.User{Email: "active@example.com", Username: "active", Password: "x", IsActive: true,
+		SuspendedAt: &past, SuspendedUntil: &future, SuspensionReason: "spam"}
+	indefinite := &models.User{Email: "indefinite@example.com", Username: "indefinite", Password: "x", IsActive: true,
+		SuspendedAt: &past, SuspensionReason: "abuse"}
+	for _, user := range []*models.User{expired, active, indefinite} {
+		require.NoError(t, repo.Create(ctx, user))
+	}
+
+	ids, err := repo.LiftExpiredSuspensions(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{expired.ID}, ids)
+
+	lifted, err := repo.GetByID(ctx, expired.ID)
+	require.NoError(t, err)
+	assert.Nil(t, lifted.SuspendedAt)
+	assert.Nil(t, lifted.SuspendedUntil)
+	assert.Empty(t, lifted.SuspensionReason)
+
+	for _, user := range []*models.User{active, indefinite} {
+		stillSuspended, err := repo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.True(t, stillSuspended.IsSuspended(now))
+	}
+
+	ids, err = repo.LiftExpiredSuspensions(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestUserRepository_AnonymizeDeletedAccounts(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	now := time.Now()
+	past, future := now.Add(-time.Minute), now.Add(time.Hour)
+	due := &models.User{Email: "due@example.com", Username: "due", Password: "x", FirstName: "Due", LastName: "User",
+		DeletionRequestedAt: &past, DeletionScheduledAt: &past}
+	pending := &models.User{Email: "pending@example.com", Username: "pending", Password: "x",
+		DeletionRequestedAt: &past, DeletionScheduledAt: &future}
+	for _, user := range []*models.User{due, pending} {
+		require.NoError(t, repo.Create(ctx, user))
+	}
+
+	ids, err := repo.AnonymizeDeletedAccounts(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, []uint{due.ID}, ids)
+
+	// Anonymized accounts are soft deleted and free their email and username
+	deleted, err := repo.GetByID(ctx, due.ID)
+	require.NoError(t, err)
+	assert.Nil(t, deleted)
+	exists, err := repo.ExistsByEmail(ctx, "due@example.com")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	var scrubbed models.User
+	require.NoError(t, db.DB.Unscoped().First(&scrubbed, due.ID).Error)
+	assert.Equal(t, fmt.Sprintf("deleted-%d@deleted.invalid", due.ID), scrubbed.Email)
+	assert.Equal(t, fmt.Sprintf("deleted-%d", due.ID), scrubbed.Username)
+	assert.Empty(t, scrubbed.Password)
+	assert.Empty(t, scrubbed.FirstName)
+	assert.Nil(t, scrubbed.DeletionScheduledAt)
+
+	stillPending, err := repo.GetByID(ctx, pending.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "pending@example.com", stillPending.Email)
+
+	ids, err = repo.AnonymizeDeletedAccounts(ctx, now)
+	require.NoError(t, err)
+	assert.Empty(t, ids)
+}
+
+func TestUserRepository_AnonymizeDeletedUsers(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	old := &models.User{Email: "old@example.com", Username: "old", Password: "x", FirstName: "Old", Phone: "+15550100"}
+	recent := &models.User{Email: "recent@example.com", Username: "recent", Password: "x"}
+	active := &models.User{Email: "active@example.com", Username: "active", Password: "x"}
+	for _, user := range []*models.User{old, recent, active} {
+		require.NoError(t, repo.Create(ctx, user))
+	}
+	require.NoError(t, NewIdentityRepository(db).Create(ctx, &models.UserIdentity{UserID: old.ID, Provider: "https://idp.example.com", Subject: "old", Email: "old@example.com"}))
+	require.NoError(t, repo.Delete(ctx, old.ID))
+	require.NoError(t, repo.Delete(ctx, recent.ID))
+	require.NoError(t, db.DB.Unscoped().Model(&models.User{}).Where("id = ?", old.ID).Update("deleted_at", time.Now().Add(-48*time.Hour)).Error)
+
+	ids, err := repo.AnonymizeDeletedUsers(ctx, time.Now().Add(-24*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, []uint{old.ID}, ids)
+
+	// The row stays, soft deleted, without personal data
+	scrubbed, err := repo.GetByID(ctx, old.ID, WithDeleted())
+	require.NoError(t, err)
+	require.NotNil(t, scrubbed)
+	assert.True(t, scrubbed.IsAnonymized())
+	assert.True(t, scrubbed.DeletedAt.Valid)
+	assert.Equal(t, fmt.Sprintf("deleted-%d", old.ID), scrubbed.Username)
+	assert.Empty(t, scrubbed.FirstName)
+	assert.Empty(t, scrubbed.Phone)
+	identities, err := NewIdentityRepository(db).ListByUser(ctx, old.ID)
+	require.NoError(t, err)
+	require.Len(t, identities, 1)
+	assert.Empty(t, identities[0].Email)
+
+	kept, err := repo.GetByID(ctx, recent.ID, WithDeleted())
+	require.NoError(t, err)
+	assert.Equal(t, "recent@example.com", kept.Email)
+
+	// Anonymized users aren't scrubbed again, and users who aren't deleted never are
+	ids, err = repo.AnonymizeDeletedUsers(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, []uint{recent.ID}, ids)
+	kept, err = repo.GetByID(ctx, active.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "active@example.com", kept.Email)
+}
+
+func TestUserRepository_FailedLogins(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &models.User{
+		Email:     "test@example.com",
+		Username:  "testuser",
+		Password:  "hashedpassword",
+		FirstName: "Test",
+		LastName:  "User",
+		IsActive:  true,
+	}
+	require.NoError(t, repo.Create(ctx, user))
+
+	attempts, err := repo.RecordFailedLogin(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempts)
+
+	attempts, err = repo.RecordFailedLogin(ctx, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	// Locking resets the counter
+	require.NoError(t, repo.LockUntil(ctx, user.ID, time.Now().Add(time.Minute)))
+	locked, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.NotNil(t, locked.LockedUntil)
+	assert.Equal(t, 0, locked.FailedLoginAttempts)
+
+	// A successful login clears the lock
+	require.NoError(t, repo.UpdateLastLogin(ctx, user.ID))
+	unlocked, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Nil(t, unlocked.LockedUntil)
+}
+
+func TestUserRepository_UseTOTPStep(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &models.User{
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "hashedpassword",
+		IsActive: true,
+	}
+	require.NoError(t, repo.Create(ctx, user))
+
+	used, err := repo.UseTOTPStep(ctx, user.ID, 100)
+	require.NoError(t, err)
+	assert.True(t, used)
+
+	// The same step, or an earlier one, can't be used again
+	used, err = repo.UseTOTPStep(ctx, user.ID, 100)
+	require.NoError(t, err)
+	assert.False(t, used)
+	used, err = repo.UseTOTPStep(ctx, user.ID, 99)
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	used, err = repo.UseTOTPStep(ctx, user.ID, 101)
+	require.NoError(t, err)
+	assert.True(t, used)
+}
+
+func TestUserRepository_MustChangePassword(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &models.User{
+		Email:    "test@example.com",
+		Username: "testuser",
+		Password: "hashedpassword",
+		IsActive: true,
+	}
+	require.NoError(t, repo.Create(ctx, user))
+
+	mustChange, err := repo.MustChangePassword(ctx, user.ID)
+	require.NoError(t, err)
+	assert.False(t, mustChange)
+
+	user.MustChangePassword = true
+	require.NoError(t, repo.Update(ctx, user))
+	mustChange, err = repo.MustChangePassword(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, mustChange)
+
+	mustChange, err = repo.MustChangePassword(ctx, 999)
+	require.NoError(t, err)
+	assert.False(t, mustChange)
+}
+
+func TestUserRepository_ForEach(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		user := &models.User{
+			Email:     fmt.Sprintf("user%d@example.com", i),
+			Username:  fmt.Sprintf("user%d", i),
+			Password:  "hashedpassword",
+			FirstName: "Test",
+			LastName:  "User",
+		}
+		require.NoError(t, repo.Create(ctx, user))
+	}
+
+	// All users are visited in ID order across batches
+	var ids []uint
+	err := repo.ForEach(ctx, models.UserFilter{}, 2, func(user *models.User) error {
+		ids = append(ids, user.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 2, 3, 4, 5}, ids)
+
+	// Only users matching the filter are visited
+	ids = nil
+	err = repo.ForEach(ctx, models.UserFilter{Search: "user3"}, 2, func(user *models.User) error {
+		ids = append(ids, user.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{4}, ids)
+
+	// An error from the callback stops the iteration
+	stop := errors.New("stop")
+	visited := 0
+	err = repo.ForEach(ctx, models.UserFilter{}, 2, func(user *models.User) error {
+		visited++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, visited)
+}
+
+func TestUserRepository_List(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, u := range []struct{ username, first, last string }{
+		{"jdoe", "Jane", "Doe"},
+		{"jsmith", "John", "Smith"},
+		{"max_power", "Max", "Power"},
+		{"maxwell", "Maxwell", "Doe"},
+	} {
+		user := &models.User{
+			Email:     u.username + "@example.com",
+			Username:  u.username,
+			Password:  "hashedpassword",
+			FirstName: u.first,
+			LastName:  u.last,
+			IsActive:  true,
+			IsAdmin:   i == 3,
+			CreatedAt: start.AddDate(0, 0, i),
+		}
+		require.NoError(t, repo.Create(ctx, user))
+	}
+	// Create would apply the is_active default to a false value
+	require.NoError(t, db.DB.Model(&models.User{}).Where("username = ?", "jsmith").Update("is_active", false).Error)
+
+	usernames := func(filter models.UserFilter) []string {
+		users, total, err := repo.List(ctx, filter, nil, 10, 0)
+		require.NoError(t, err)
+		names := make([]string, len(users))
+		for i, user := range users {
+			names[i] = user.Username
+		}
+		assert.Equal(t, int64(len(users)), total)
+		return names
+	}
+
+	assert.Equal(t, []string{"maxwell", "max_power", "jsmith", "jdoe"}, usernames(models.UserFilter{}), "newest first")
+	assert.Equal(t, []string{"maxwell", "jdoe"}, usernames(models.UserFilter{Search: "DOE"}))
+	assert.Equal(t, []string{"jdoe"}, usernames(models.UserFilter{Search: "ja do"}), "every word must match")
+	assert.Equal(t, []string{"max_power"}, usernames(models.UserFilter{Search: "max_"}), "wildcards are escaped")
+	assert.Equal(t, []string{"jsmith"}, usernames(models.UserFilter{Search: "jsmith@example.com"}))
+	assert.Empty(t, usernames(models.UserFilter{Search: "example.com"}), "emails only match as a whole")
+
+	active, admin := false, true
+	assert.Equal(t, []string{"jsmith"}, usernames(models.UserFilter{IsActive: &active}))
+	assert.Equal(t, []string{"maxwell"}, usernames(models.UserFilter{IsAdmin: &admin}))
+	after, before := start.AddDate(0, 0, 1), start.AddDate(0, 0, 3)
+	assert.Equal(t, []string{"max_power", "jsmith"}, usernames(models.UserFilter{CreatedAfter: &after, CreatedBefore: &before}))
+
+	// The total counts every match, not just the page
+	users, total, err := repo.List(ctx, models.UserFilter{Search: "m"}, nil, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, users, 1)
+	assert.Equal(t, "max_power", users[0].Username)
+	assert.Equal(t, int64(2), total)
+
+	// Sorting by whitelisted fields only
+	users, _, err = repo.List(ctx, models.UserFilter{}, []models.SortField{{Name: "last_name", Descending: true}, {Name: "username"}}, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, users, 4)
+	assert.Equal(t, []string{"jsmith", "max_power", "jdoe", "maxwell"}, []string{users[0].Username, users[1].Username, users[2].Username, users[3].Username})
+	_, _, err = repo.List(ctx, models.UserFilter{}, []models.SortField{{Name: "email"}}, 10, 0)
+	assert.ErrorIs(t, err, ErrUnknownSortField)
+	_, _, err = repo.List(ctx, models.UserFilter{}, []models.SortField{{Name: "id; DROP TABLE users"}}, 10, 0)
+	assert.ErrorIs(t, err, ErrUnknownSortField)
+}
+
+func TestUserRepository_ListByCursor(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, u := range []struct{ username, last string }{
+		{"alice", "Doe"},
+		{"bob", "Smith"},
+		{"carol", "Doe"},
+		{"dave", "Doe"},
+		{"erin", "Adams"},
+	} {
+		require.NoError(t, repo.Create(ctx, &models.User{
+			Email:     u.username + "@example.com",
+			Username:  u.username,
+			Password:  "hashedpassword",
+			LastName:  u.last,
+			IsActive:  true,
+			CreatedAt: start.AddDate(0, 0, i),
+		}))
+	}
+
+	page := func(sort []models.SortField, cursor *models.Cursor) ([]string, *models.Cursor, *models.Cursor) {
+		users, next, prev, err := repo.ListByCursor(ctx, models.UserFilter{}, sort, cursor, 2)
+		require.NoError(t, err)
+		names := make([]string, len(users))
+		for i, user := range users {
+			names[i] = user.Username
+		}
+		return names, next, prev
+	}
+
+	// Forwards through the list, newest first
+	names, next, prev := page(nil, nil)
+	assert.Equal(t, []string{"erin", "dave"}, names)
+	assert.Nil(t, prev, "the first page has no previous page")
+	require.NotNil(t, next)
+	names, next, prev = page(nil, next)
+	assert.Equal(t, []string{"carol", "bob"}, names)
+	require.NotNil(t, prev)
+	names, last, _ := page(nil, next)
+	assert.Equal(t, []string{"alice"}, names)
+	assert.Nil(t, last, "the last page has no next page")
+
+	// And back again
+	names, _, prev = page(nil, prev)
+	assert.Equal(t, []string{"erin", "dave"}, names)
+	assert.Nil(t, prev)
+
+	// The ID breaks ties between equal sort values
+	sort := []models.SortField{{Name: "last_name"}}
+	names, next, _ = page(sort, nil)
+	assert.Equal(t, []string{"erin", "alice"}, names)
+	names, next, _ = page(sort, next)
+	assert.Equal(t, []string{"carol", "dave"}, names)
+	names, _, _ = page(sort, next)
+	assert.Equal(t, []string{"bob"}, names)
+
+	// Cursors only work with the sort they were issued for
+	_, _, _, err := repo.ListByCursor(ctx, models.UserFilter{}, nil, next, 2)
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+	_, _, _, err = repo.ListByCursor(ctx, models.UserFilter{}, nil, &models.Cursor{Sort: "", Values: []string{"yesterday", "1"}}, 2)
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+	_, _, _, err = repo.ListByCursor(ctx, models.UserFilter{}, []models.SortField{{Name: "last_login"}}, nil, 2)
+	assert.ErrorIs(t, err, ErrUnknownSortField, "nullable columns can't be paginated with cursors")
+}
+
+func TestUserRepository_Avatar(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	user := &models.User{
+		Email:     "test@example.com",
+		Username:  "testuser",
+		Password:  "hashedpassword",
+		FirstName: "Test",
+		LastName:  "User",
+	}
+	require.NoError(t, repo.Create(ctx, user))
+	require.NoError(t, repo.SetAvatarFile(ctx, user.ID, 7))
+
+	urls := map[string]string{"64": "http://localhost/avatars/1/7/64.jpg"}
+	updated, err := repo.SetAvatarURLs(ctx, user.ID, 7, urls)
+	require.NoError(t, err)
+	assert.True(t, updated)
+
+	found, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, found.AvatarFileID)
+	assert.Equal(t, uint(7), *found.AvatarFileID)
+	assert.Equal(t, urls, found.AvatarURLs)
+
+	// Variants of a replaced avatar are discarded
+	require.NoError(t, repo.SetAvatarFile(ctx, user.ID, 8))
+	updated, err = repo.SetAvatarURLs(ctx, user.ID, 7, map[string]string{"64": "stale"})
+	require.NoError(t, err)
+	assert.False(t, updated)
+
+	found, err = repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, urls, found.AvatarURLs)
+}
+
+func TestUserRepository_FieldEncryption(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	ctx := context.Background()
+
+	// A row written before encryption was enabled
+	legacy := &models.User{Email: "legacy@example.com", Username: "legacy", Password: "x", Phone: "+15550100"}
+	require.NoError(t, repo.Create(ctx, legacy))
+
+	indexKey := bytes.Repeat([]byte{9}, 32)
+	oldKeys, err := fieldcrypt.NewKeyring(map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)}, "k1", indexKey)
+	require.NoError(t, err)
+	fieldcrypt.SetDefault(oldKeys)
+	t.Cleanup(func() { fieldcrypt.SetDefault(nil) })
+
+	user := &models.User{
+		Email:    "secret@example.com",
+		Username: "secret",
+		Password: "x",
+		Phone:    "+15550123",
+		Metadata: models.Metadata{"team": "blue"},
+	}
+	require.NoError(t, repo.Create(ctx, user))
+
+	var raw struct{ Email, EmailIndex, Phone, Metadata string }
+	require.NoError(t, db.DB.Table("users").Where("id = ?", user.ID).Scan(&raw).Error)
+	for _, value := range []string{raw.Email, raw.Phone, raw.Metadata} {
+		assert.True(t, strings.HasPrefix(value, "enc:k1:"), value)
+	}
+	assert.NotContains(t, raw.Metadata, "blue")
+	assert.Equal(t, oldKeys.BlindIndex("secret@example.com"), raw.EmailIndex)
+
+	// Lookups go through the blind index, including rows not re-encrypted yet
+	found, err := repo.GetByEmail(ctx, "secret@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, "+15550123", found.Phone)
+	assert.Equal(t, models.Metadata{"team": "blue"}, found.Metadata)
+
+	found, err = repo.GetByEmailOrUsername(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, legacy.ID, found.ID)
+	assert.Equal(t, "+15550100", found.Phone)
+
+	exists, err := repo.ExistsByEmail(ctx, "legacy@example.com")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	// After rotating, both rows are rewritten with the new primary key
+	newKeys, err := fieldcrypt.NewKeyring(map[string][]byte{
+		"k1": bytes.Repeat([]byte{1}, 32),
+		"k2": bytes.Repeat([]byte{2}, 32),
+	}, "k2", indexKey)
+	require.NoError(t, err)
+	fieldcrypt.SetDefault(newKeys)
+
+	n, err := repo.ReencryptUsers(ctx, newKeys.Prefix(), 1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = repo.ReencryptUsers(ctx, newKeys.Prefix(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	n, err = repo.ReencryptUsers(ctx, newKeys.Prefix(), 10)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	require.NoError(t, db.DB.Table("users").Where("id = ?", legacy.ID).Scan(&raw).Error)
+	assert.True(t, strings.HasPrefix(raw.Email, "enc:k2:"), raw.Email)
+	assert.True(t, strings.HasPrefix(raw.Phone, "enc:k2:"), raw.Phone)
+	assert.Equal(t, newKeys.BlindIndex("legacy@example.com"), raw.EmailIndex)
+
+	// Rows encrypted before the phone index existed get one
+	require.NoError(t, db.DB.Table("users").Where("id = ?", user.ID).Update("phone_index", nil).Error)
+	n, err = repo.ReencryptUsers(ctx, newKeys.Prefix(), 10)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	found, err = repo.GetByPhone(ctx, "+15550123")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, user.ID, found.ID)
+
+	found, err = repo.GetByEmail(ctx, "secret@example.com")
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, models.Metadata{"team": "blue"}, found.Metadata)
+}
+
+func TestUserRepository_TransferOwnership(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewUserRepository(db)
+	orgs := NewOrganizationRepository(db)
+	tags := NewTagRepository(db)
+	ctx := context.Background()
+
+	user := &models.User{Email: "jane@example.com", Username: "jane", Password: "x"}
+	duplicate := &models.User{Email: "jane.doe@example.com", Username: "janedoe", Password: "x"}
+	for _, u := range []*models.User{user, duplicate} {
+		require.NoError(t, repo.Create(ctx, u))
+	}
+
+	require.NoError(t, NewIdentityRepository(db).Create(ctx, &models.UserIdentity{UserID: duplicate.ID, Provider: "https://idp.example.com", Subject: "jane"}))
+	require.NoError(t, db.DB.Create(&models.File{UserID: duplicate.ID, Purpose: "document", ObjectKey: "files/a", Filename: "a.txt"}).Error)
+
+	// The duplicate owns one organization the user is a member of, and another one alone
+	shared := &models.Organization{Name: "Shared"}
+	own := &models.Organization{Name: "Own"}
+	require.NoError(t, orgs.Create(ctx, shared, duplicate.ID))
+	require.NoError(t, orgs.Create(ctx, own, duplicate.ID))
+	_, err := orgs.AddMember(ctx, &models.Membership{OrganizationID: shared.ID, UserID: user.ID, Role: models.OrgRoleMember})
+	require.NoError(t, err)
+
+	vip := &models.Tag{Name: "vip"}
+	beta := &models.Tag{Name: "beta"}
+	require.NoError(t, tags.Create(ctx, vip))
+	require.NoError(t, tags.Create(ctx, beta))
+	require.NoError(t, tags.AssignToUser(ctx, user.ID, []uint{vip.ID}))
+	require.NoError(t, tags.AssignToUser(ctx, duplicate.ID, []uint{vip.ID, beta.ID}))
+
+	require.NoError(t, repo.TransferOwnership(ctx, duplicate.ID, user.ID))
+
+	identities, err := NewIdentityRepository(db).ListByUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, identities, 1)
+	var files int64
+	require.NoError(t, db.DB.Model(&models.File{}).Where("user_id = ?", user.ID).Count(&files).Error)
+	assert.Equal(t, int64(1), files)
+
+	memberships, err := orgs.ListByUser(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, memberships, 2)
+	for _, membership := range memberships {
+		assert.Equal(t, models.OrgRoleOwner, membership.Role, "the higher role is kept")
+	}
+	memberships, err = orgs.ListByUser(ctx, duplicate.ID)
+	require.NoError(t, err)
+	assert.Empty(t, memberships)
+
+	userTags, err := tags.ListByUser(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Len(t, userTags, 2)
+	userTags, err = tags.ListByUser(ctx, duplicate.ID)
+	require.NoError(t, err)
+	assert.Empty(t, userTags)
+}
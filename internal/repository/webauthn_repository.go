@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// webAuthnRepository implements the WebAuthnRepository interface
+type webAuthnRepository struct {
+	db *Database
+}
+
+// NewWebAuthnRepository creates a new WebAuthn credential repository
+func NewWebAuthnRepository(db *Database) WebAuthnRepository {
+	return &webAuthnRepository{
+		db: db,
+	}
+}
+
+// Create stores a newly registered credential
+func (r *webAuthnRepository) Create(ctx context.Context, credential *models.WebAuthnCredential) error {
+	return r.db.DB.WithContext(ctx).Create(credential).Error
+}
+
+// GetByCredentialID retrieves a credential by the ID the authenticator assigned to it
+func (r *webAuthnRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*models.WebAuthnCredential, error) {
+	var credential models.WebAuthnCredential
+	if err := r.db.DB.WithContext(ctx).Where("credential_id = ?", credentialID).First(&credential).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return &credential, nil
+}
+
+// ListByUser retrieves all credentials registered by a user
+func (r *webAuthnRepository) ListByUser(ctx context.Context, userID uint) ([]*models.WebAuthnCredential, error) {
+	var credentials []*models.WebAuthnCredential
+	if err := r.db.DB.WithContext(ctx).Where("user_id = ?", userID).Order("created_at ASC").Find(&credentials).Error; err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+// RecordUse stores the signature counter and flags of a successful login
+func (r *webAuthnRepository) RecordUse(ctx context.Context, credential *models.WebAuthnCredential) error {
+	return r.db.DB.WithContext(ctx).Model(credential).
+		Select("sign_count", "clone_warning", "flags", "last_used_at").
+		Updates(credential).Error
+}
+
+// Delete removes a credential of a user and reports whether it existed
+func (r *webAuthnRepository) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	result := r.db.DB.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	return result.RowsAffected == 1, result.Error
+}
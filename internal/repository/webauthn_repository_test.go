@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebAuthnRepository(t *testing.T) {
+	db := setupTestDB(t)
+	repo := NewWebAuthnRepository(db)
+	ctx := context.Background()
+
+	credential := &models.WebAuthnCredential{
+		UserID:       1,
+		CredentialID: []byte{1, 2, 3},
+		PublicKey:    []byte{4, 5, 6},
+		Transports:   []string{"internal", "hybrid"},
+		Name:         "Laptop",
+	}
+	require.NoError(t, repo.Create(ctx, credential))
+	require.NoError(t, repo.Create(ctx, &models.WebAuthnCredential{UserID: 2, CredentialID: []byte{7}, PublicKey: []byte{8}}))
+
+	// Credential IDs are unique across users
+	assert.Error(t, repo.Create(ctx, &models.WebAuthnCredential{UserID: 2, CredentialID: []byte{1, 2, 3}, PublicKey: []byte{9}}))
+
+	found, err := repo.GetByCredentialID(ctx, []byte{1, 2, 3})
+	require.NoError(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, []string{"internal", "hybrid"}, found.Transports)
+
+	missing, err := repo.GetByCredentialID(ctx, []byte{0})
+	require.NoError(t, err)
+	assert.Nil(t, missing)
+
+	now := time.Now()
+	found.SignCount = 3
+	found.LastUsedAt = &now
+	require.NoError(t, repo.RecordUse(ctx, found))
+	credentials, err := repo.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+	assert.Equal(t, uint32(3), credentials[0].SignCount)
+	assert.NotNil(t, credentials[0].LastUsedAt)
+
+	// Only the owner can delete a credential
+	deleted, err := repo.Delete(ctx, 2, credential.ID)
+	require.NoError(t, err)
+	assert.False(t, deleted)
+	deleted, err = repo.Delete(ctx, 1, credential.ID)
+	require.NoError(t, err)
+	assert.True(t, deleted)
+}
@@ -1,15 +1,25 @@
 package routes
 
 import (
+	"context"
+	"net/http"
+	"os"
+
 	"gbt-be-template/internal/config"
 	"gbt-be-template/internal/handlers"
+	"gbt-be-template/internal/models"
 	"gbt-be-template/internal/repository"
 	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/backoff"
 	"gbt-be-template/pkg/logger"
 	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/ratelimit"
+	"gbt-be-template/pkg/static"
+	"gbt-be-template/web"
 
 	"github.com/go-chi/chi/v5"
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 // Router holds all dependencies for routing
@@ -19,19 +29,158 @@ type Router struct {
 	db       *repository.Database
 	repos    *repository.Repositories
 	services *services.Services
+	leader   handlers.LeaderStatus
+	limiter  ratelimit.Limiter
+	health   *handlers.HealthHandler
+	openapi  *middleware.OpenAPIValidator
+
+	// Failed login trackers, nil when login backoff is disabled
+	loginsByIP      *backoff.Tracker
+	loginsByAccount *backoff.Tracker
+
+	// Auth cookie settings, nil when tokens are only returned in response bodies
+	authCookies *middleware.AuthCookies
 }
 
-// NewRouter creates a new router instance
-func NewRouter(cfg *config.Config, log *logger.Logger, db *repository.Database, repos *repository.Repositories, services *services.Services) *Router {
-	return &Router{
+// NewRouter creates a new router instance. leader may be nil when leader election is disabled.
+func NewRouter(cfg *config.Config, log *logger.Logger, db *repository.Database, repos *repository.Repositories, services *services.Services, leader handlers.LeaderStatus) *Router {
+	rt := &Router{
 		cfg:      cfg,
 		log:      log,
 		db:       db,
 		repos:    repos,
 		services: services,
+		leader:   leader,
+		limiter:  ratelimit.NewMemoryLimiter(),
+	}
+	rt.authCookies = middleware.NewAuthCookies(cfg)
+	if b := cfg.LoginBackoff; b.Enabled {
+		rt.loginsByIP = backoff.New(backoff.Policy{Threshold: b.IPThreshold, BaseDelay: b.BaseDelay, MaxDelay: b.MaxDelay, ResetAfter: b.ResetAfter})
+		rt.loginsByAccount = backoff.New(backoff.Policy{Threshold: b.AccountThreshold, BaseDelay: b.BaseDelay, MaxDelay: b.MaxDelay, ResetAfter: b.ResetAfter})
+	}
+	return rt
+}
+
+// concurrency returns a load shedding middleware for a route group.
+// Call it once per group so all routes of the group share the same limiter.
+func (rt *Router) concurrency(group string) func(http.Handler) http.Handler {
+	cfg := rt.cfg.Concurrency
+	maxInFlight := cfg.MaxInFlight
+	if group != "global" {
+		maxInFlight = cfg.GroupLimits[group]
+	}
+
+	var limiter *middleware.ConcurrencyLimiter
+	if maxInFlight > 0 {
+		limiter = middleware.NewConcurrencyLimiter(maxInFlight, cfg.QueueSize, cfg.QueueTimeout)
+	}
+	return middleware.ConcurrencyLimit(rt.log, group, limiter)
+}
+
+// throttle returns the middleware for a named rate limit policy
+func (rt *Router) throttle(policy string) func(http.Handler) http.Handler {
+	return middleware.Throttle(rt.log, rt.limiter, rt.cfg.RateLimit.Policy(policy))
+}
+
+// loginBackoff returns the middleware slowing down repeated failed logins
+func (rt *Router) loginBackoff() func(http.Handler) http.Handler {
+	if rt.loginsByIP == nil {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	return middleware.LoginBackoff(rt.log, rt.loginsByIP, rt.loginsByAccount, handlers.LoginIdentifier)
+}
+
+// authenticate returns the middleware for routes that require a signed-in user. Tokens of
+// the OIDC provider and API keys, when enabled, are accepted next to locally issued ones.
+// With cookie auth, browsers may send the access token in a cookie instead. With
+// AUTHZ_ADMIN_FROM_ROLES, admin status is decided by the admin role.
+func (rt *Router) authenticate() func(http.Handler) http.Handler {
+	var external middleware.ExternalAuth
+	if rt.services.OIDC != nil || rt.services.APIKey != nil {
+		external = func(ctx context.Context, token string) (*middleware.Principal, bool, error) {
+			if rt.services.APIKey != nil && rt.services.APIKey.Handles(token) {
+				user, scopes, err := rt.services.APIKey.Authenticate(ctx, token)
+				if err != nil {
+					return nil, true, err
+				}
+				if scopes == nil {
+					scopes = []string{}
+				}
+				return &middleware.Principal{UserID: user.ID, Email: user.Email, IsAdmin: user.IsAdmin, Scopes: scopes}, true, nil
+			}
+			if rt.services.OIDC == nil || !rt.services.OIDC.Handles(token) {
+				return nil, false, nil
+			}
+			user, isAdmin, err := rt.services.OIDC.Authenticate(ctx, token)
+			if err != nil {
+				return nil, true, err
+			}
+			return &middleware.Principal{UserID: user.ID, Email: user.Email, IsAdmin: isAdmin}, true, nil
+		}
+	}
+	auth := middleware.JWTAuth(rt.log, rt.cfg.JWT.Keys(), rt.services.Auth.IsAccessTokenRevoked, external, rt.authCookies)
+	if rt.cfg.Tenancy.Enabled {
+		jwtAuth, tenantFromClaim := auth, middleware.TenantFromClaim(rt.log, rt.cfg.Tenancy.Claim)
+		auth = func(next http.Handler) http.Handler {
+			return jwtAuth(tenantFromClaim(next))
+		}
+	}
+	if !rt.cfg.Authz.AdminFromRoles {
+		return auth
+	}
+	resolveAdmin := middleware.ResolveAdmin(rt.log, rt.services.Role.IsAdmin)
+	return func(next http.Handler) http.Handler {
+		return auth(resolveAdmin(next))
 	}
 }
 
+// passwordChanged returns the middleware refusing requests of users an admin asked to change their password
+func (rt *Router) passwordChanged() func(http.Handler) http.Handler {
+	return middleware.RequirePasswordChanged(rt.log, rt.services.User.MustChangePassword)
+}
+
+// scope returns the middleware limiting API keys to routes of one of their scopes
+func (rt *Router) scope(scope string) func(http.Handler) http.Handler {
+	return middleware.RequireScope(rt.log, scope)
+}
+
+// routePermissions lists the permission each guarded route requires, on top of the checks of its
+// route group. Patterns are full chi patterns; routes of a group declared with r.Route end in "/".
+// SetupRoutes warns about entries that don't match a route.
+var routePermissions = []middleware.RoutePermission{
+	{Method: http.MethodGet, Pattern: "/api/v1/admin/roles/", Resource: "role", Action: "list"},
+	{Method: http.MethodPost, Pattern: "/api/v1/admin/roles/", Resource: "role", Action: "create"},
+	{Method: http.MethodGet, Pattern: "/api/v1/admin/roles/{id}", Resource: "role", Action: "read"},
+	{Method: http.MethodPut, Pattern: "/api/v1/admin/roles/{id}", Resource: "role", Action: "update"},
+	{Method: http.MethodDelete, Pattern: "/api/v1/admin/roles/{id}", Resource: "role", Action: "delete"},
+	{Method: http.MethodPut, Pattern: "/api/v1/admin/roles/{id}/permissions", Resource: "role", Action: "update"},
+	{Method: http.MethodPost, Pattern: "/api/v1/admin/users/{id}/roles", Resource: "role", Action: "update"},
+	{Method: http.MethodDelete, Pattern: "/api/v1/admin/users/{id}/roles/{roleId}", Resource: "role", Action: "update"},
+	{Method: http.MethodGet, Pattern: "/api/v1/admin/permissions/", Resource: "permission", Action: "list"},
+	{Method: http.MethodPost, Pattern: "/api/v1/admin/permissions/", Resource: "permission", Action: "create"},
+	{Method: http.MethodGet, Pattern: "/api/v1/admin/permissions/catalog", Resource: "permission", Action: "list"},
+	{Method: http.MethodGet, Pattern: "/api/v1/admin/permissions/{id}", Resource: "permission", Action: "read"},
+	{Method: http.MethodPut, Pattern: "/api/v1/admin/permissions/{id}", Resource: "permission", Action: "update"},
+	{Method: http.MethodDelete, Pattern: "/api/v1/admin/permissions/{id}", Resource: "permission", Action: "delete"},
+}
+
+// permissions returns the middleware applying routePermissions
+func (rt *Router) permissions() func(http.Handler) http.Handler {
+	return middleware.RequireRoutePermissions(rt.log, rt.services.Authorizer.Authorize, routePermissions)
+}
+
+// UseOpenAPIValidator enforces the OpenAPI spec on API requests. It must be called before SetupRoutes.
+func (rt *Router) UseOpenAPIValidator(validator *middleware.OpenAPIValidator) {
+	rt.openapi = validator
+}
+
+// StartDraining fails the readiness check ahead of shutdown. It must be called after SetupRoutes.
+func (rt *Router) StartDraining() {
+	rt.health.StartDraining()
+}
+
 // SetupRoutes configures all routes and middleware
 func (rt *Router) SetupRoutes() *chi.Mux {
 	r := chi.NewRouter()
@@ -39,14 +188,37 @@ func (rt *Router) SetupRoutes() *chi.Mux {
 	// Global middleware
 	r.Use(chiMiddleware.RequestID)
 	r.Use(chiMiddleware.RealIP)
+	r.Use(middleware.ClientInfo)
 	r.Use(middleware.Logging(rt.log))
 	r.Use(middleware.Recovery(rt.log))
 	r.Use(middleware.CORS(rt.cfg))
 	r.Use(chiMiddleware.Timeout(rt.cfg.Server.GetTimeout()))
+	if rt.cfg.Tenancy.Enabled {
+		r.Use(middleware.ResolveTenant(rt.log, rt.cfg.Tenancy.Header, rt.cfg.Tenancy.BaseDomain))
+	}
 
 	// Initialize handlers
 	userHandler := handlers.NewUserHandler(rt.services.User, rt.log)
-	healthHandler := handlers.NewHealthHandler(rt.db, rt.log)
+	authHandler := handlers.NewAuthHandler(rt.services.Auth, rt.log)
+	userHandler.UseAuthCookies(rt.authCookies)
+	authHandler.UseAuthCookies(rt.authCookies)
+	uploadHandler := handlers.NewUploadHandler(rt.services.Upload, rt.log)
+	fileHandler := handlers.NewFileHandler(rt.services.File, rt.log)
+	settingsHandler := handlers.NewSettingsHandler(rt.services.Settings, rt.log)
+	avatarHandler := handlers.NewAvatarHandler(rt.services.Avatar, rt.services.Upload, rt.cfg.Upload.Avatar.MaxSize, rt.log)
+	roleHandler := handlers.NewRoleHandler(rt.services.Role, rt.log)
+	orgHandler := handlers.NewOrganizationHandler(rt.services.Organization, rt.log)
+	batchHandler := handlers.NewBatchHandler(r, rt.cfg.Batch.MaxRequests, rt.cfg.Batch.MaxBodySize, rt.log)
+	healthHandler := handlers.NewHealthHandler(rt.db, rt.leader, rt.log)
+	rt.health = healthHandler
+	if rt.loginsByIP != nil {
+		healthHandler.AddMetrics("login_backoff", func() interface{} {
+			return map[string]backoff.Stats{
+				"ip":      rt.loginsByIP.Stats(),
+				"account": rt.loginsByAccount.Stats(),
+			}
+		})
+	}
 
 	// Health check routes (no auth required)
 	r.Route("/health", func(r chi.Router) {
@@ -55,40 +227,351 @@ func (rt *Router) SetupRoutes() *chi.Mux {
 		r.Get("/live", healthHandler.Live)
 	})
 
+	// Prometheus metrics (optional), guarded by METRICS_TOKEN when set
+	if rt.cfg.Metrics.Enabled {
+		r.With(middleware.RequireStaticToken(rt.log, rt.cfg.Metrics.Token)).Handle(rt.cfg.Metrics.Path, promhttp.Handler())
+	}
+
+	// OAuth2/OIDC authorization server (optional)
+	var oauthHandler *handlers.OAuthHandler
+	if rt.services.OAuth != nil {
+		oauthHandler = handlers.NewOAuthHandler(rt.services.OAuth, rt.log)
+		r.Get("/.well-known/openid-configuration", oauthHandler.Discovery)
+	}
+
 	// API routes
 	r.Route("/api/v1", func(r chi.Router) {
+		// Cap in-flight API requests to protect the database pool during spikes
+		r.Use(rt.concurrency("global"))
+
+		// Reject requests that do not match the OpenAPI spec before they reach a handler
+		if rt.openapi != nil {
+			r.Use(middleware.OpenAPIValidation(rt.log, rt.openapi))
+		}
+
+		if oauthHandler != nil {
+			r.Route("/oauth", func(r chi.Router) {
+				// Token endpoint authenticates clients itself
+				r.With(rt.throttle("auth")).Post("/token", oauthHandler.Token)
+
+				// Userinfo accepts only tokens delegated to OAuth clients
+				r.With(middleware.OAuthBearer(rt.log, rt.cfg.JWT.Keys()), rt.throttle("read")).Get("/userinfo", oauthHandler.UserInfo)
+
+				// Consent screen API used by the first-party frontend on behalf of the signed-in user
+				r.Group(func(r chi.Router) {
+					r.Use(rt.authenticate())
+					r.Use(rt.passwordChanged())
+					r.Use(middleware.RequireSession(rt.log))
+					r.With(rt.throttle("read")).Get("/authorize", oauthHandler.GetConsent)
+					r.With(rt.throttle("write")).Post("/authorize", oauthHandler.Authorize)
+				})
+			})
+		}
+
+		// Public media such as rendered avatars, referenced directly from clients
+		r.With(rt.throttle("read")).Get("/media/avatars/*", avatarHandler.ServeVariant)
+
 		// Public auth routes (no auth required)
-		r.Post("/auth/login", userHandler.Login)
-		r.Post("/auth/register", userHandler.Create)
+		r.Group(func(r chi.Router) {
+			r.Use(rt.throttle("auth"))
+			r.Use(rt.concurrency("auth"))
+
+			r.With(rt.loginBackoff()).Post("/auth/login", userHandler.Login)
+			r.Post("/auth/2fa/verify", userHandler.VerifyTwoFactor)
+			r.Post("/auth/login/verify", userHandler.VerifyLogin)
+			r.Post("/auth/register", userHandler.Create)
+			r.With(middleware.CSRF(rt.log, rt.authCookies)).Post("/auth/refresh", authHandler.Refresh)
+			r.Post("/auth/reactivate", userHandler.RequestReactivation)
+			r.Post("/auth/reactivate/confirm", userHandler.ConfirmReactivation)
+			r.Post("/auth/email/confirm", userHandler.ConfirmEmailChange)
+
+			// Passwords live in the directory when logins go through LDAP
+			if !rt.cfg.LDAP.Enabled {
+				r.Post("/auth/password/forgot", userHandler.ForgotPassword)
+				r.Post("/auth/password/reset", userHandler.ResetPassword)
+			}
+
+			// Passwordless login with a code texted to the user's phone (optional)
+			if rt.cfg.OTP.Enabled {
+				r.Post("/auth/otp/request", userHandler.RequestLoginOTP)
+				r.Post("/auth/otp/verify", userHandler.VerifyLoginOTP)
+			}
+
+			// Exchange external IdP tokens for local access tokens (RFC 8693)
+			if rt.services.TokenExchange != nil {
+				tokenExchangeHandler := handlers.NewTokenExchangeHandler(rt.services.TokenExchange, rt.log)
+				r.Post("/auth/token", tokenExchangeHandler.Exchange)
+			}
+
+			// SAML single sign-on (optional)
+			if rt.services.SAML != nil {
+				samlHandler := handlers.NewSAMLHandler(rt.services.SAML, rt.cfg.SAML.RedirectURL, rt.log)
+				samlHandler.UseAuthCookies(rt.authCookies)
+				r.Get("/auth/saml/metadata", samlHandler.Metadata)
+				r.Get("/auth/saml/login", samlHandler.Login)
+				r.Post("/auth/saml/acs", samlHandler.ACS)
+				r.Post("/auth/saml/token", samlHandler.Token)
+			}
+
+			// Passwordless login with passkeys (optional)
+			if rt.services.WebAuthn != nil {
+				webauthnHandler := handlers.NewWebAuthnHandler(rt.services.WebAuthn, rt.log)
+				webauthnHandler.UseAuthCookies(rt.authCookies)
+				r.Post("/auth/webauthn/login/begin", webauthnHandler.BeginLogin)
+				r.Post("/auth/webauthn/login/finish", webauthnHandler.FinishLogin)
+			}
+		})
+
+		// Account routes left to users who have to change their password before anything else
+		r.Group(func(r chi.Router) {
+			r.Use(rt.authenticate())
+			r.Use(middleware.RequireSession(rt.log))
+
+			r.With(rt.throttle("write")).Post("/auth/logout", userHandler.Logout)
+			r.With(rt.throttle("read")).Get("/auth/profile", userHandler.Profile)
+
+			// Passwords live in the directory when logins go through LDAP
+			if !rt.cfg.LDAP.Enabled {
+				r.With(rt.throttle("auth")).Put("/auth/password", userHandler.ChangePassword)
+			}
+		})
 
 		// Protected routes (auth required)
 		r.Group(func(r chi.Router) {
-			r.Use(middleware.JWTAuth(rt.log, rt.cfg.JWT.Secret))
+			r.Use(rt.authenticate())
+			r.Use(rt.passwordChanged())
+			r.Use(rt.permissions())
+
+			// Protected auth routes, which API keys can't reach
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireSession(rt.log))
+
+				r.With(rt.throttle("auth")).Delete("/auth/account", userHandler.DeleteAccount)
+				r.With(rt.throttle("auth")).Delete("/auth/profile", userHandler.DeleteAccount) // Same as /auth/account
+				r.With(rt.throttle("read")).Get("/auth/profile/settings", settingsHandler.Get)
+				r.With(rt.throttle("write")).Put("/auth/profile/settings", settingsHandler.Update)
+				r.With(rt.throttle("read")).Get("/auth/sessions", authHandler.ListSessions)
+				r.With(rt.throttle("write")).Delete("/auth/sessions/{id}", authHandler.RevokeSession)
+				r.With(rt.throttle("read")).Get("/auth/devices", userHandler.ListDevices)
+				r.With(rt.throttle("write")).Delete("/auth/devices/{id}", userHandler.DeleteDevice)
+				r.With(rt.throttle("read")).Get("/auth/login-history", userHandler.LoginHistory)
+				r.With(rt.throttle("read")).Get("/auth/profile/audit", userHandler.ActivityAudit)
+				r.With(rt.throttle("auth")).Post("/auth/2fa/enroll", userHandler.EnrollTwoFactor)
+				r.With(rt.throttle("auth")).Post("/auth/2fa/enable", userHandler.EnableTwoFactor)
+				r.With(rt.throttle("auth")).Post("/auth/2fa/disable", userHandler.DisableTwoFactor)
+				r.With(rt.throttle("read")).Get("/auth/2fa/recovery-codes", userHandler.RecoveryCodes)
+				r.With(rt.throttle("auth")).Post("/auth/2fa/recovery-codes", userHandler.RegenerateRecoveryCodes)
+
+				// Passkey management
+				if rt.services.WebAuthn != nil {
+					webauthnHandler := handlers.NewWebAuthnHandler(rt.services.WebAuthn, rt.log)
+					r.With(rt.throttle("auth")).Post("/auth/webauthn/register/begin", webauthnHandler.BeginRegistration)
+					r.With(rt.throttle("auth")).Post("/auth/webauthn/register/finish", webauthnHandler.FinishRegistration)
+					r.With(rt.throttle("read")).Get("/auth/webauthn/credentials", webauthnHandler.ListCredentials)
+					r.With(rt.throttle("write")).Delete("/auth/webauthn/credentials/{id}", webauthnHandler.DeleteCredential)
+				}
+
+				// Signing in with the external OpenID Provider as well, merging a duplicate account
+				if rt.services.OIDC != nil {
+					identityHandler := handlers.NewIdentityHandler(rt.services.OIDC, rt.log)
+					r.With(rt.throttle("auth")).Post("/auth/profile/identities", identityHandler.Link)
+				}
 
-			// Protected auth routes
-			r.Post("/auth/logout", userHandler.Logout)
-			r.Get("/auth/profile", userHandler.Profile)
+				// API key management
+				if rt.services.APIKey != nil {
+					apiKeyHandler := handlers.NewAPIKeyHandler(rt.services.APIKey, rt.log)
+					r.With(rt.throttle("auth")).Post("/auth/api-keys", apiKeyHandler.Create)
+					r.With(rt.throttle("read")).Get("/auth/api-keys", apiKeyHandler.List)
+					r.With(rt.throttle("write")).Delete("/auth/api-keys/{id}", apiKeyHandler.Delete)
+				}
+			})
+
+			// Several API calls in one round trip, each authorized and throttled on its own
+			r.With(rt.throttle("write")).Post("/batch", batchHandler.Batch)
 
 			// User routes
 			r.Route("/users", func(r chi.Router) {
-				r.Get("/", userHandler.List)
-				r.Get("/{id}", userHandler.GetByID)
-				r.Put("/{id}", userHandler.Update)
-				r.Delete("/{id}", userHandler.Delete)
+				r.Use(rt.concurrency("users"))
+				r.With(rt.scope(models.APIKeyScopeUsersRead), rt.throttle("read")).Get("/", userHandler.List)
+				r.With(rt.scope(models.APIKeyScopeUsersRead), rt.throttle("read")).Get("/{id}", userHandler.GetByID)
+				r.With(rt.scope(models.APIKeyScopeUsersWrite), rt.throttle("write")).Put("/{id}", userHandler.Update)
+				r.With(rt.scope(models.APIKeyScopeUsersWrite), rt.throttle("write")).Patch("/{id}", userHandler.Patch) // Merge patch or JSON patch
+				r.With(rt.scope(models.APIKeyScopeUsersWrite), rt.throttle("write")).Delete("/{id}", userHandler.Delete)
+				r.With(rt.scope(models.APIKeyScopeFilesRead), rt.throttle("read")).Get("/{id}/files", fileHandler.ListByUser)
+				r.With(rt.scope(models.APIKeyScopeUsersWrite), rt.throttle("write")).Post("/{id}/avatar", avatarHandler.Upload) // Multipart image upload
+				r.With(rt.scope(models.APIKeyScopeUsersWrite), rt.throttle("write")).Put("/{id}/avatar", avatarHandler.Set)
+				r.With(rt.scope(models.APIKeyScopeUsersRead), rt.throttle("read")).Get("/{id}/roles", roleHandler.ListForUser)
+			})
+
+			// Organization routes; access depends on the caller's role in the organization
+			r.Route("/orgs", func(r chi.Router) {
+				r.With(rt.scope(models.APIKeyScopeOrgsRead), rt.throttle("read")).Get("/", orgHandler.List) // Organizations the caller is a member of
+				r.With(rt.scope(models.APIKeyScopeOrgsWrite), rt.throttle("write")).Post("/", orgHandler.Create)
+				r.With(rt.scope(models.APIKeyScopeOrgsRead), rt.throttle("read")).Get("/{id}", orgHandler.GetByID)
+				r.With(rt.scope(models.APIKeyScopeOrgsWrite), rt.throttle("write")).Put("/{id}", orgHandler.Update)
+				r.With(rt.scope(models.APIKeyScopeOrgsWrite), rt.throttle("write")).Delete("/{id}", orgHandler.Delete) // Owners only
+				r.With(rt.scope(models.APIKeyScopeOrgsRead), rt.throttle("read")).Get("/{id}/members", orgHandler.ListMembers)
+				r.With(rt.scope(models.APIKeyScopeOrgsWrite), rt.throttle("write")).Post("/{id}/members", orgHandler.AddMember)
+				r.With(rt.scope(models.APIKeyScopeOrgsWrite), rt.throttle("write")).Put("/{id}/members/{userId}", orgHandler.UpdateMember)
+				r.With(rt.scope(models.APIKeyScopeOrgsWrite), rt.throttle("write")).Delete("/{id}/members/{userId}", orgHandler.RemoveMember) // Members can remove themselves
+			})
+
+			// File routes
+			r.With(rt.scope(models.APIKeyScopeFilesRead), rt.throttle("read")).Get("/files/{id}/download", fileHandler.Download)
+			r.With(rt.scope(models.APIKeyScopeFilesWrite), rt.throttle("write")).Delete("/files/{id}", fileHandler.Delete)
+
+			// Chunked upload routes
+			r.Route("/uploads", func(r chi.Router) {
+				r.Use(rt.scope(models.APIKeyScopeFilesWrite))
+				r.Use(rt.throttle("write"))
+				r.Post("/", uploadHandler.Initiate)
+				r.Post("/presign", uploadHandler.Presign)
+				r.Get("/{id}", uploadHandler.Get)
+				r.Put("/{id}/parts/{part}", uploadHandler.UploadPart)
+				r.Post("/{id}/complete", uploadHandler.Complete)
+				r.Post("/{id}/confirm", uploadHandler.Confirm)
+				r.Delete("/{id}", uploadHandler.Abort)
 			})
 
 			// Admin only routes
 			r.Group(func(r chi.Router) {
 				r.Use(middleware.RequireAdmin(rt.log))
+				r.Use(rt.scope(models.APIKeyScopeAdmin))
+				r.Use(rt.throttle("admin"))
+				r.Use(rt.concurrency("admin"))
 
 				// Admin user management
+				tagHandler := handlers.NewTagHandler(rt.services.Tag, rt.log)
 				r.Route("/admin/users", func(r chi.Router) {
-					r.Post("/", userHandler.Create)         // Admin can create users
-					r.Put("/{id}", userHandler.AdminUpdate) // Admin can update any user including admin status
+					r.Post("/", userHandler.Create)            // Admin can create users
+					r.Get("/export", userHandler.Export)       // Streams all users as NDJSON, CSV or XLSX
+					r.Get("/{id}", userHandler.AdminGet)       // Includes suspension details
+					r.Put("/{id}", userHandler.AdminUpdate)    // Admin can update any user; is_admin is deprecated
+					r.Delete("/{id}", userHandler.AdminDelete) // Permanent with ?hard=true, otherwise restorable
+					r.Post("/{id}/suspension", userHandler.Suspend)
+					r.Delete("/{id}/suspension", userHandler.Unsuspend)
+					r.Post("/{id}/deactivate", userHandler.Deactivate)   // Signs the user out; only an admin can reactivate
+					r.Post("/{id}/activate", userHandler.Reactivate)     // Audit logged, whoever deactivated the account
+					r.Post("/{id}/reactivate", userHandler.Reactivate)   // Older name of /activate
+					r.Post("/{id}/restore", userHandler.Restore)         // Undoes a deletion, unless the user was anonymized
+					r.Post("/{id}/logout-all", userHandler.LogoutAll)    // Revokes refresh tokens; access tokens run out
+					r.Post("/{id}/merge", userHandler.Merge)             // Moves what the duplicate owns, then deactivates it
+					r.Post("/{id}/impersonate", userHandler.Impersonate) // Audit logged, as is every request made with the token
+					if !rt.cfg.LDAP.Enabled {
+						r.Post("/{id}/password-change", userHandler.RequirePasswordChange) // Users reset it with the forgot password flow
+					}
+					r.Get("/{id}/login-history", userHandler.AdminLoginHistory)
+					r.Get("/{id}/audit", userHandler.AdminActivityAudit) // Also for deleted users
+					r.Post("/{id}/roles", roleHandler.AssignToUser)      // Adds to the roles the user already has
+					r.Delete("/{id}/roles/{roleId}", roleHandler.RemoveFromUser)
+					r.Get("/{id}/tags", tagHandler.ListForUser)
+					r.Post("/{id}/tags", tagHandler.AssignToUser) // Adds to the tags the user already has
+					r.Delete("/{id}/tags/{tagId}", tagHandler.RemoveFromUser)
 				})
+
+				// Tags segmenting users, which lists of users can be filtered by
+				r.Route("/admin/tags", func(r chi.Router) {
+					r.Get("/", tagHandler.List)
+					r.Post("/", tagHandler.Create)
+					r.Put("/{id}", tagHandler.Update)
+					r.Delete("/{id}", tagHandler.Delete) // Also untags its users
+				})
+
+				// Roles and the permissions they grant
+				r.Route("/admin/roles", func(r chi.Router) {
+					r.Get("/", roleHandler.List)
+					r.Post("/", roleHandler.Create)
+					r.Get("/{id}", roleHandler.GetByID)
+					r.Put("/{id}", roleHandler.Update)
+					r.Delete("/{id}", roleHandler.Delete) // Also takes the role away from its users
+					r.Put("/{id}/permissions", roleHandler.SetPermissions)
+				})
+				permissionHandler := handlers.NewPermissionHandler(rt.services.Permission, rt.log)
+				r.Route("/admin/permissions", func(r chi.Router) {
+					r.Get("/", permissionHandler.List)
+					r.Post("/", permissionHandler.Create)
+					r.Get("/catalog", permissionHandler.Catalog) // Resource/action pairs known to the application
+					r.Get("/{id}", permissionHandler.GetByID)
+					r.Put("/{id}", permissionHandler.Update)
+					r.Delete("/{id}", permissionHandler.Delete) // Also takes the permission away from roles
+				})
+				if rt.services.AccessRule != nil {
+					accessRuleHandler := handlers.NewAccessRuleHandler(rt.services.AccessRule, rt.log)
+					r.Route("/admin/access-rules", func(r chi.Router) {
+						r.Get("/", accessRuleHandler.List)
+						r.Post("/", accessRuleHandler.Create)
+						r.Get("/{id}", accessRuleHandler.GetByID)
+						r.Put("/{id}", accessRuleHandler.Update)
+						r.Delete("/{id}", accessRuleHandler.Delete)
+					})
+				}
+
+				// Audit logs, which can't be changed through the API
+				auditHandler := handlers.NewAuditHandler(rt.services.RBACAudit, rt.log)
+				r.Get("/admin/audit/rbac", auditHandler.ListRBAC) // Role and permission changes
+
+				// OAuth client registration
+				if oauthHandler != nil {
+					r.Route("/admin/oauth/clients", func(r chi.Router) {
+						r.Get("/", oauthHandler.ListClients)
+						r.Post("/", oauthHandler.RegisterClient)
+						r.Delete("/{clientId}", oauthHandler.DeleteClient)
+					})
+				}
 			})
 		})
 	})
 
+	// Frontend assets and client-side routes, matched after all API routes
+	if rt.cfg.Static.Enabled {
+		rt.mountStatic(r)
+	}
+
+	for _, permission := range middleware.UnroutedPermissions(r, routePermissions) {
+		rt.log.WithFields(map[string]interface{}{
+			"method":  permission.Method,
+			"pattern": permission.Pattern,
+		}).Warn("Route permission doesn't match a route")
+	}
+
 	return r
 }
+
+// SetupServiceRoutes configures the service-to-service routes served on the mTLS listener.
+// Callers are identified by their client certificate instead of a user's token.
+func (rt *Router) SetupServiceRoutes() *chi.Mux {
+	r := chi.NewRouter()
+
+	r.Use(chiMiddleware.RequestID)
+	r.Use(middleware.Logging(rt.log))
+	r.Use(middleware.Recovery(rt.log))
+	r.Use(chiMiddleware.Timeout(rt.cfg.Server.GetTimeout()))
+	r.Use(middleware.ClientCertAuth(rt.log, rt.cfg.MTLS.AllowedServices))
+
+	userHandler := handlers.NewUserHandler(rt.services.User, rt.log)
+
+	r.Route("/internal/v1", func(r chi.Router) {
+		r.Use(rt.concurrency("service"))
+
+		r.Get("/users/{id}", userHandler.AdminGet) // Includes suspension details
+	})
+
+	return r
+}
+
+// mountStatic serves the frontend from the configured directory or the embedded build
+func (rt *Router) mountStatic(r chi.Router) {
+	fsys := web.Dist()
+	if rt.cfg.Static.Dir != "" {
+		fsys = os.DirFS(rt.cfg.Static.Dir)
+	}
+
+	handler := static.Handler(fsys, static.Options{
+		SPAFallback:    rt.cfg.Static.SPAFallback,
+		ImmutablePaths: rt.cfg.Static.ImmutablePaths,
+		MaxAge:         rt.cfg.Static.MaxAge,
+	})
+	compressed := chiMiddleware.Compress(5)(handler)
+	r.Get("/*", compressed.ServeHTTP)
+	r.Head("/*", compressed.ServeHTTP)
+}
@@ -0,0 +1,76 @@
+package routes_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/server"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer creates the application with all of its routes on a SQLite database
+func newTestServer(t *testing.T, env map[string]string) http.Handler {
+	dir := t.TempDir()
+	t.Setenv("DB_DRIVER", "sqlite")
+	t.Setenv("DB_NAME", filepath.Join(dir, "app.db"))
+	t.Setenv("STORAGE_DRIVER", "local")
+	t.Setenv("STORAGE_LOCAL_PATH", filepath.Join(dir, "storage"))
+	for key, value := range env {
+		t.Setenv(key, value)
+	}
+
+	cfg, err := config.Load()
+	require.NoError(t, err)
+	srv, err := server.New(cfg, logger.New("error", "text"))
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = srv.Shutdown() })
+	return srv.GetRouter()
+}
+
+func TestRoutes_DeleteProfile(t *testing.T) {
+	// Registering and logging in use up two requests of the auth policy
+	router := newTestServer(t, map[string]string{"RATE_LIMIT_POLICIES": "auth=4/1m:ip,read=unlimited,write=unlimited"})
+
+	serve := func(method, path, token, body string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		request.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			request.Header.Set("Authorization", "Bearer "+token)
+		}
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	recorder := serve(http.MethodPost, "/api/v1/auth/register", "", `{"email":"alice@example.com","username":"alice","password":"Password123!","first_name":"Alice","last_name":"Doe"}`)
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	recorder = serve(http.MethodPost, "/api/v1/auth/login", "", `{"email":"alice@example.com","password":"Password123!"}`)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var login struct {
+		Data struct {
+			AccessToken string `json:"access_token"`
+		} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &login))
+	token := login.Data.AccessToken
+	require.NotEmpty(t, token)
+
+	// Both paths require a signed-in user
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodDelete, "/api/v1/auth/profile", "", `{"password":"wrong"}`).Code)
+
+	// Both reach DeleteAccount, which checks the password
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/api/v1/auth/account", token, `{"password":"wrong"}`).Code)
+	assert.Equal(t, http.StatusForbidden, serve(http.MethodDelete, "/api/v1/auth/profile", token, `{"password":"wrong"}`).Code)
+
+	// and share the auth rate limit
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodDelete, "/api/v1/auth/profile", token, `{"password":"wrong"}`).Code)
+	assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodDelete, "/api/v1/auth/account", token, `{"password":"wrong"}`).Code)
+}
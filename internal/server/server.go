@@ -2,67 +2,301 @@ package server
 
 import (
 	"context"
+	"crypto"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
 	"fmt"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
+	"strings"
 	"syscall"
 	"time"
 
+	"gbt-be-template/api"
 	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/handlers"
+	"gbt-be-template/internal/jobs"
+	"gbt-be-template/internal/models"
 	"gbt-be-template/internal/repository"
 	"gbt-be-template/internal/routes"
 	"gbt-be-template/internal/services"
+	"gbt-be-template/pkg/backoff"
+	"gbt-be-template/pkg/challenge"
+	"gbt-be-template/pkg/fieldcrypt"
+	"gbt-be-template/pkg/leader"
+	"gbt-be-template/pkg/lock"
 	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/mailer"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/revocation"
+	"gbt-be-template/pkg/scanner"
+	"gbt-be-template/pkg/scheduler"
+	"gbt-be-template/pkg/sms"
+	"gbt-be-template/pkg/storage"
 
+	"github.com/crewjam/saml"
+	"github.com/crewjam/saml/samlsp"
 	"github.com/go-chi/chi/v5"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/redis/go-redis/v9"
 )
 
 // Server represents the HTTP server
 type Server struct {
-	cfg    *config.Config
-	log    *logger.Logger
-	db     *repository.Database
-	router *chi.Mux
-	server *http.Server
+	cfg       *config.Config
+	log       *logger.Logger
+	db        *repository.Database
+	router    *chi.Mux
+	routes    *routes.Router
+	server    *http.Server
+	services  *http.Server // mTLS listener for service-to-service routes, nil when disabled
+	scheduler *scheduler.Scheduler
+	elector   *leader.Elector
+	worker    *jobs.Worker
+	redis     []*redis.Client
 }
 
 // New creates a new server instance
 func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
-	// Initialize database
-	db, err := repository.NewDatabase(cfg)
+	// Sensitive user columns are encrypted by a GORM serializer using the default keyring
+	keyring, err := newKeyring(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize field encryption: %w", err)
+	}
+	fieldcrypt.SetDefault(keyring)
+
+	// Initialize database, waiting for it while it is still starting, such as in a new container
+	db, err := connectDatabase(cfg, log)
 	if err != nil {
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
 
-	// Run auto migration only in development mode when not using Docker
-	// In Docker, we use proper migrations via migrate container
+	// Apply the embedded SQL migrations when asked to, so that deploys need no migrate container
+	if cfg.Database.MigrateOnStart {
+		if err := migrateDatabase(db, log); err != nil {
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
+	}
+
+	// Run auto migration only in development mode unless skipped, such as
+	// when the SQL migrations are applied instead. The SQL migrations are
+	// written for Postgres, so other databases are always auto migrated.
 	skipAutoMigrate := os.Getenv("SKIP_AUTO_MIGRATE")
 	log.Info("Auto migration check", "skip_auto_migrate", skipAutoMigrate, "is_development", cfg.IsDevelopment())
 
-	if cfg.IsDevelopment() && skipAutoMigrate != "true" {
+	if (cfg.IsDevelopment() || cfg.Database.Driver != "postgres") && skipAutoMigrate != "true" {
 		log.Info("Running auto migration")
 		if err := db.AutoMigrate(); err != nil {
 			return nil, fmt.Errorf("failed to run auto migration: %w", err)
 		}
 	} else {
-		log.Info("Skipping auto migration", "reason", "skip_auto_migrate=true or not development")
+		log.Info("Skipping auto migration", "reason", "skip_auto_migrate=true or not development on postgres")
 	}
 
 	// Initialize repositories
 	repos := repository.NewRepositories(db)
 
+	// Initialize storage
+	store, err := newStorage(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize storage: %w", err)
+	}
+
+	// Background job queue
+	queue := jobs.NewQueue(repos.Job, cfg.Jobs.MaxAttempts)
+	worker := jobs.NewWorker(repos.Job, log, cfg.Jobs.PollInterval, cfg.Jobs.Concurrency, cfg.Jobs.StaleAfter)
+
+	// Emails and text messages are sent from jobs so delivery failures are retried
+	mail, err := newMailer(cfg, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize mailer: %w", err)
+	}
+	worker.Register(services.JobSendEmail, services.NewEmailJobHandler(mail))
+	worker.Register(services.JobSendSMS, services.NewSMSJobHandler(newSMSSender(cfg, log)))
+
 	// Initialize services
-	authService := services.NewAuthService(repos.User, cfg, log)
-	userService := services.NewUserService(repos.User, authService, cfg, log)
+	revocations, revocationRedis := newRevocationStore(cfg)
+	authService := services.NewAuthService(repos.User, repos.RefreshToken, revocations, cfg, log)
+	if cfg.Authz.RolesInToken || cfg.Authz.PermissionsInToken {
+		authService.UseRoles(repos.Role)
+	}
+	if cfg.Tenancy.Enabled {
+		claim := cfg.Tenancy.Claim
+		authService.UseClaimsEnricher(func(user *models.User) map[string]interface{} {
+			return map[string]interface{}{claim: user.TenantID}
+		})
+	}
+	authBackend := services.NewLocalAuthBackend()
+	if cfg.LDAP.Enabled {
+		authBackend = services.NewLDAPAuthBackend(repos.User, repos.Identity, repos.Role, repos.RBACAudit, cfg, log)
+	}
+	userService := services.NewUserService(repos.User, repos.EmailToken, repos.OTP, repos.Device, repos.LoginEvent, repos.RecoveryCode, repos.Role, repos.RBACAudit, authService, authBackend, queue, cfg, log)
+	userService.UseActivityAudit(repos.AuditEvent)
+	userService.UseTransactions(repos)
+
+	// The authorization server is optional and only wired up when enabled
+	var oauthService services.OAuthService
+	if cfg.OAuth2.Enabled {
+		oauthService = services.NewOAuthService(repos.OAuth, repos.User, cfg, log)
+	}
+
+	var tokenExchangeService services.TokenExchangeService
+	if cfg.TokenExchange.Enabled {
+		tokenExchangeService = services.NewTokenExchangeService(repos.User, repos.Identity, authService, cfg, log)
+	}
+
+	var oidcService services.OIDCService
+	if cfg.OIDC.Enabled {
+		oidcService = services.NewOIDCService(repos.User, repos.Identity, cfg, log)
+		oidcService.UseAccountMerger(userService)
+	}
+
+	var samlService services.SAMLService
+	if cfg.SAML.Enabled {
+		sp, err := newSAMLServiceProvider(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize SAML service provider: %w", err)
+		}
+		samlService = services.NewSAMLService(sp, repos.User, repos.Identity, repos.Role, repos.RBACAudit, repos.EmailToken, userService, cfg, log)
+	}
+
+	var webauthnService services.WebAuthnService
+	var challengeRedis *redis.Client
+	if cfg.WebAuthn.Enabled {
+		wa, err := newWebAuthn(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize WebAuthn: %w", err)
+		}
+		var challenges challenge.Store
+		challenges, challengeRedis = newChallengeStore(cfg)
+		webauthnService = services.NewWebAuthnService(wa, repos.User, repos.WebAuthn, challenges, userService, cfg, log)
+	}
+
+	var apiKeyService services.APIKeyService
+	if cfg.APIKeys.Enabled {
+		apiKeyService = services.NewAPIKeyService(repos.APIKey, repos.User, cfg, log)
+	}
+
+	roleService := services.NewRoleService(repos.Role, repos.Permission, repos.User, repos.RBACAudit, log)
+	permissionService := services.NewPermissionService(repos.Permission, repos.RBACAudit, log)
+	authorizer, err := newAuthorizer(cfg, db, repos, log)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create authorizer: %w", err)
+	}
+	roleService.UseAuthorizer(authorizer)
+	userService.UseAuthorizer(authorizer)
+	permissionService.UseAuthorizer(authorizer)
+	var accessRuleService services.AccessRuleService
+	if cfg.Authz.RulesEnabled {
+		accessRuleService = services.NewAccessRuleService(repos.AccessRule, log)
+	}
+
+	fileService := services.NewFileService(repos.File, store, cfg, log)
+	uploadService := services.NewUploadService(repos.Upload, fileService, store, newScanner(cfg), cfg, log)
+	avatarService := services.NewAvatarService(repos.User, repos.File, store, queue, cfg, log)
+	worker.Register(services.JobProcessAvatar, services.NewAvatarJobHandler(avatarService))
 
 	services := &services.Services{
-		User: userService,
-		Auth: authService,
+		User:          userService,
+		Auth:          authService,
+		OAuth:         oauthService,
+		TokenExchange: tokenExchangeService,
+		OIDC:          oidcService,
+		SAML:          samlService,
+		WebAuthn:      webauthnService,
+		APIKey:        apiKeyService,
+		Role:          roleService,
+		Permission:    permissionService,
+		AccessRule:    accessRuleService,
+		RBACAudit:     services.NewRBACAuditService(repos.RBACAudit, log),
+		Authorizer:    authorizer,
+		Upload:        uploadService,
+		File:          fileService,
+		Avatar:        avatarService,
+		Settings:      services.NewSettingsService(repos.Settings, log),
+		Organization:  services.NewOrganizationService(repos.Organization, repos.User, log),
+		Tag:           services.NewTagService(repos.Tag, repos.User, log),
+	}
+
+	// Background maintenance tasks run on one instance at a time
+	locker, redisClients, err := newLocker(cfg, db)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize locker: %w", err)
+	}
+	if revocationRedis != nil {
+		redisClients = append(redisClients, revocationRedis)
+	}
+	if challengeRedis != nil {
+		redisClients = append(redisClients, challengeRedis)
+	}
+	sched := scheduler.New(log)
+	sched.UseLocker(locker)
+	sched.Every("upload_cleanup", cfg.Upload.CleanupInterval, func(ctx context.Context) error {
+		_, err := uploadService.CleanupExpired(ctx)
+		return err
+	})
+	sched.Every("suspension_lift", cfg.Suspension.LiftInterval, func(ctx context.Context) error {
+		_, err := userService.LiftExpiredSuspensions(ctx)
+		return err
+	})
+	sched.Every("account_deletion", cfg.Deletion.PurgeInterval, func(ctx context.Context) error {
+		_, err := userService.PurgeDeletedAccounts(ctx)
+		return err
+	})
+	if cfg.Deletion.Anonymize {
+		sched.Every("deleted_user_anonymization", cfg.Deletion.PurgeInterval, func(ctx context.Context) error {
+			_, err := userService.AnonymizeDeletedUsers(ctx)
+			return err
+		})
+	}
+	if cfg.Deletion.HardDeleteAfter > 0 {
+		sched.Every("deleted_user_purge", cfg.Deletion.PurgeInterval, func(ctx context.Context) error {
+			_, err := userService.PurgeDeletedUsers(ctx)
+			return err
+		})
+	}
+	if cfg.LoginHistory.Retention > 0 {
+		sched.Every("login_history_purge", cfg.LoginHistory.PurgeInterval, func(ctx context.Context) error {
+			_, err := userService.PurgeLoginHistory(ctx)
+			return err
+		})
+	}
+	if keyring != nil {
+		sched.Every("field_reencryption", cfg.Encryption.RotationInterval, func(ctx context.Context) error {
+			_, err := userService.ReencryptUsers(ctx)
+			return err
+		})
+	}
+
+	// Only the elected instance runs the scheduler; the others take over if it goes away
+	var elector *leader.Elector
+	var leaderStatus handlers.LeaderStatus
+	if cfg.Leader.Enabled {
+		elector = leader.New(locker, leader.Config{
+			Name:          "leader",
+			LeaseTTL:      cfg.Leader.LeaseTTL,
+			RetryInterval: cfg.Leader.RetryInterval,
+		}, log)
+		elector.Go(func(ctx context.Context) {
+			sched.Start(ctx)
+			<-ctx.Done()
+			sched.Stop()
+		})
+		leaderStatus = elector
 	}
 
 	// Initialize router
-	router := routes.NewRouter(cfg, log, db, repos, services)
+	router := routes.NewRouter(cfg, log, db, repos, services, leaderStatus)
+	if cfg.OpenAPI.Validate {
+		validator, err := newOpenAPIValidator(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load OpenAPI spec: %w", err)
+		}
+		router.UseOpenAPIValidator(validator)
+	}
 	mux := router.SetupRoutes()
 
 	// Create HTTP server
@@ -74,21 +308,388 @@ func New(cfg *config.Config, log *logger.Logger) (*Server, error) {
 		IdleTimeout:  60 * time.Second,
 	}
 
+	var serviceServer *http.Server
+	if cfg.MTLS.Enabled {
+		serviceServer, err = newServiceServer(cfg, router.SetupServiceRoutes())
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize mTLS listener: %w", err)
+		}
+	}
+
 	return &Server{
-		cfg:    cfg,
-		log:    log,
-		db:     db,
-		router: mux,
-		server: server,
+		cfg:       cfg,
+		log:       log,
+		db:        db,
+		router:    mux,
+		routes:    router,
+		server:    server,
+		services:  serviceServer,
+		scheduler: sched,
+		elector:   elector,
+		worker:    worker,
+		redis:     redisClients,
+	}, nil
+}
+
+// newServiceServer creates the listener for service-to-service routes, which only completes
+// TLS handshakes with clients presenting a certificate signed by the client CA
+func newServiceServer(cfg *config.Config, handler http.Handler) (*http.Server, error) {
+	caPEM, err := os.ReadFile(cfg.MTLS.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read client CA: %w", err)
+	}
+	clientCAs := x509.NewCertPool()
+	if !clientCAs.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificates found in client CA file")
+	}
+	certificate, err := tls.LoadX509KeyPair(cfg.MTLS.CertFile, cfg.MTLS.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key pair: %w", err)
+	}
+
+	return &http.Server{
+		Addr:    fmt.Sprintf("%s:%s", cfg.Server.Host, cfg.MTLS.Port),
+		Handler: handler,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			ClientAuth:   tls.RequireAndVerifyClientCert,
+			ClientCAs:    clientCAs,
+			MinVersion:   tls.VersionTLS12,
+		},
+		ReadTimeout:  15 * time.Second,
+		WriteTimeout: 15 * time.Second,
+		IdleTimeout:  60 * time.Second,
+	}, nil
+}
+
+// newOpenAPIValidator loads the configured spec, falling back to the embedded one
+func newOpenAPIValidator(cfg *config.Config) (*middleware.OpenAPIValidator, error) {
+	spec := api.Spec()
+	if cfg.OpenAPI.SpecFile != "" {
+		data, err := os.ReadFile(cfg.OpenAPI.SpecFile)
+		if err != nil {
+			return nil, err
+		}
+		spec = data
+	}
+	return middleware.NewOpenAPIValidator(spec)
+}
+
+// migrateDatabase applies the embedded SQL migrations that haven't been applied yet
+func migrateDatabase(db *repository.Database, log *logger.Logger) error {
+	migrator, err := db.Migrator()
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	from, _, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	if err := migrator.Up(); err != nil {
+		return err
+	}
+	to, _, err := migrator.Version()
+	if err != nil {
+		return err
+	}
+	log.WithFields(map[string]interface{}{
+		"from_version": from,
+		"to_version":   to,
+	}).Info("Database migrations applied")
+	return nil
+}
+
+// connectDatabase opens the database, retrying with exponential backoff while it can't be
+// reached, and logs each failed attempt
+func connectDatabase(cfg *config.Config, log *logger.Logger) (*repository.Database, error) {
+	policy := backoff.RetryPolicy{
+		Retries:   cfg.Database.ConnectRetries,
+		BaseDelay: cfg.Database.ConnectRetryDelay,
+		MaxWait:   cfg.Database.ConnectMaxWait,
+	}
+	var db *repository.Database
+	err := backoff.Retry(context.Background(), policy, func() error {
+		var err error
+		db, err = repository.NewDatabase(cfg, log)
+		return err
+	}, func(attempt int, err error, delay time.Duration) {
+		log.WithError(err).WithFields(map[string]interface{}{
+			"attempt":  attempt,
+			"retry_in": delay.String(),
+		}).Warn("Failed to connect to database, retrying")
+	})
+	return db, err
+}
+
+// newKeyring creates the field encryption keyring, nil when no keys are configured
+func newKeyring(cfg *config.Config) (*fieldcrypt.Keyring, error) {
+	if cfg.Encryption.Keys == "" {
+		return nil, nil
+	}
+	keys, primary, err := fieldcrypt.ParseKeys(cfg.Encryption.Keys)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Encryption.PrimaryKeyID != "" {
+		primary = cfg.Encryption.PrimaryKeyID
+	}
+	indexKey, err := base64.StdEncoding.DecodeString(cfg.Encryption.IndexKey)
+	if err != nil {
+		return nil, fmt.Errorf("blind index key is not valid base64: %w", err)
+	}
+	return fieldcrypt.NewKeyring(keys, primary, indexKey)
+}
+
+// newSAMLServiceProvider creates the SAML service provider from its key pair and the identity provider's metadata
+func newSAMLServiceProvider(cfg *config.Config) (*saml.ServiceProvider, error) {
+	keyPair, err := tls.LoadX509KeyPair(cfg.SAML.CertFile, cfg.SAML.KeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load key pair: %w", err)
+	}
+	certificate, err := x509.ParseCertificate(keyPair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %w", err)
+	}
+	key, ok := keyPair.PrivateKey.(crypto.Signer)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", keyPair.PrivateKey)
+	}
+
+	var idpMetadata *saml.EntityDescriptor
+	if cfg.SAML.IDPMetadataFile != "" {
+		data, err := os.ReadFile(cfg.SAML.IDPMetadataFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read identity provider metadata: %w", err)
+		}
+		idpMetadata, err = samlsp.ParseMetadata(data)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse identity provider metadata: %w", err)
+		}
+	} else {
+		metadataURL, err := url.Parse(cfg.SAML.IDPMetadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid identity provider metadata URL: %w", err)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		idpMetadata, err = samlsp.FetchMetadata(ctx, http.DefaultClient, *metadataURL)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch identity provider metadata: %w", err)
+		}
+	}
+
+	baseURL, err := url.Parse(strings.TrimRight(cfg.SAML.BaseURL, "/") + "/api/v1/auth/saml/")
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAML base URL: %w", err)
+	}
+	return &saml.ServiceProvider{
+		EntityID:          cfg.SAML.EntityID,
+		Key:               key,
+		Certificate:       certificate,
+		MetadataURL:       *baseURL.JoinPath("metadata"),
+		AcsURL:            *baseURL.JoinPath("acs"),
+		IDPMetadata:       idpMetadata,
+		AllowIDPInitiated: cfg.SAML.AllowIDPInitiated,
 	}, nil
 }
 
+// newMailer creates the configured email sender
+func newMailer(cfg *config.Config, log *logger.Logger) (mailer.Mailer, error) {
+	if cfg.Mail.Driver == "smtp" {
+		return mailer.NewSMTP(cfg.Mail.SMTPHost, cfg.Mail.SMTPPort, cfg.Mail.SMTPUsername, cfg.Mail.SMTPPassword, cfg.Mail.From, cfg.Mail.Timeout)
+	}
+	return mailer.NewLog(log), nil
+}
+
+// newSMSSender creates the configured text message sender
+func newSMSSender(cfg *config.Config, log *logger.Logger) sms.Sender {
+	if cfg.SMS.Driver == "twilio" {
+		return sms.NewTwilio(cfg.SMS.TwilioAccountSID, cfg.SMS.TwilioAuthToken, cfg.SMS.From, cfg.SMS.Timeout)
+	}
+	return sms.NewLog(log)
+}
+
+// newStorage creates the configured object store
+func newStorage(cfg *config.Config) (storage.Storage, error) {
+	switch cfg.Storage.Driver {
+	case "s3":
+		return newS3Storage(cfg)
+	case "gcs":
+		var credentials []byte
+		if cfg.Storage.GCS.CredentialsFile != "" {
+			data, err := os.ReadFile(cfg.Storage.GCS.CredentialsFile)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read GCS credentials: %w", err)
+			}
+			credentials = data
+		}
+		return storage.NewGCSStorage(storage.GCSConfig{
+			Bucket:          cfg.Storage.GCS.Bucket,
+			CredentialsJSON: credentials,
+			Endpoint:        cfg.Storage.GCS.Endpoint,
+		}, nil)
+	case "azure":
+		return storage.NewAzureStorage(storage.AzureConfig{
+			AccountName: cfg.Storage.Azure.AccountName,
+			AccountKey:  cfg.Storage.Azure.AccountKey,
+			Container:   cfg.Storage.Azure.Container,
+			Endpoint:    cfg.Storage.Azure.Endpoint,
+		}, nil)
+	default:
+		return storage.NewLocalStorage(cfg.Storage.LocalPath)
+	}
+}
+
+// newS3Storage creates an S3 store, creating the bucket first if configured to
+func newS3Storage(cfg *config.Config) (storage.Storage, error) {
+
+	store, err := storage.NewS3Storage(storage.S3Config{
+		Endpoint:        cfg.Storage.S3.Endpoint,
+		Region:          cfg.Storage.S3.Region,
+		Bucket:          cfg.Storage.S3.Bucket,
+		AccessKeyID:     cfg.Storage.S3.AccessKeyID,
+		SecretAccessKey: cfg.Storage.S3.SecretAccessKey,
+		SessionToken:    cfg.Storage.S3.SessionToken,
+		PathStyle:       cfg.Storage.S3.PathStyle,
+	}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Storage.S3.CreateBucket {
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if err := store.EnsureBucket(ctx); err != nil {
+			return nil, err
+		}
+	}
+	return store, nil
+}
+
+// newLocker creates the configured distributed locker along with any Redis clients it opened
+func newLocker(cfg *config.Config, db *repository.Database) (lock.Locker, []*redis.Client, error) {
+	switch cfg.Lock.Driver {
+	case "redis":
+		addrs := cfg.Lock.RedisAddrs
+		if len(addrs) == 0 {
+			addrs = []string{cfg.Redis.Addr}
+		}
+		clients := make([]*redis.Client, 0, len(addrs))
+		nodes := make([]redis.Cmdable, 0, len(addrs))
+		for _, addr := range addrs {
+			client := redis.NewClient(&redis.Options{
+				Addr:     addr,
+				Password: cfg.Redis.Password,
+				DB:       cfg.Redis.DB,
+			})
+			clients = append(clients, client)
+			nodes = append(nodes, client)
+		}
+		return lock.NewRedisLocker(nodes...), clients, nil
+	case "local":
+		return lock.NewLocalLocker(), nil, nil
+	default:
+		sqlDB, err := db.DB.DB()
+		if err != nil {
+			return nil, nil, err
+		}
+		return lock.NewPostgresLocker(sqlDB), nil, nil
+	}
+}
+
+// newRevocationStore creates the configured access token revocation store and the Redis client it uses, if any
+func newRevocationStore(cfg *config.Config) (revocation.Store, *redis.Client) {
+	if cfg.Revocation.Driver == "redis" {
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		return revocation.NewRedisStore(client), client
+	}
+	return revocation.NewMemoryStore(), nil
+}
+
+// newWebAuthn creates the WebAuthn relying party, enforcing the ceremony timeout on the server too
+func newWebAuthn(cfg *config.Config) (*webauthn.WebAuthn, error) {
+	timeout := webauthn.TimeoutConfig{Enforce: true, Timeout: cfg.WebAuthn.Timeout, TimeoutUVD: cfg.WebAuthn.Timeout}
+	return webauthn.New(&webauthn.Config{
+		RPID:          cfg.WebAuthn.RPID,
+		RPDisplayName: cfg.WebAuthn.RPDisplayName,
+		RPOrigins:     cfg.WebAuthn.RPOrigins,
+		Timeouts:      webauthn.TimeoutsConfig{Login: timeout, Registration: timeout},
+	})
+}
+
+// newChallengeStore creates the configured WebAuthn challenge store and the Redis client it uses, if any
+func newChallengeStore(cfg *config.Config) (challenge.Store, *redis.Client) {
+	if cfg.WebAuthn.ChallengeDriver == "redis" {
+		client := redis.NewClient(&redis.Options{
+			Addr:     cfg.Redis.Addr,
+			Password: cfg.Redis.Password,
+			DB:       cfg.Redis.DB,
+		})
+		return challenge.NewRedisStore(client), client
+	}
+	return challenge.NewMemoryStore(), nil
+}
+
+// newScanner creates the configured malware scanner for uploads
+func newScanner(cfg *config.Config) scanner.Scanner {
+	switch cfg.Scanner.Driver {
+	case "clamav":
+		return scanner.NewClamAV(cfg.Scanner.Address, cfg.Scanner.Timeout)
+	case "icap":
+		return scanner.NewICAP(cfg.Scanner.Address, cfg.Scanner.ICAPService, cfg.Scanner.Timeout)
+	}
+	return scanner.Noop{}
+}
+
+// newAuthorizer creates the configured authorizer for routes guarded by a permission, applying
+// access rules on top of it when they are enabled
+func newAuthorizer(cfg *config.Config, db *repository.Database, repos *repository.Repositories, log *logger.Logger) (services.Authorizer, error) {
+	var authorizer services.Authorizer = services.NewRBACAuthorizer(repos.Role)
+	if cfg.Authz.Driver == "casbin" {
+		casbinAuthorizer, err := services.NewCasbinAuthorizer(repository.NewCasbinAdapter(db), cfg.Authz.CasbinModelFile, cfg.Authz.CasbinReloadInterval, log)
+		if err != nil {
+			return nil, err
+		}
+		authorizer = casbinAuthorizer
+	}
+	if !cfg.Authz.RulesEnabled {
+		return authorizer, nil
+	}
+
+	location, err := time.LoadLocation(cfg.Authz.RulesTimezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid access rules timezone: %w", err)
+	}
+	// Pass a services.UserAttributes to expose more user attributes, such as a tenant ID, to conditions
+	return services.NewRuleAuthorizer(authorizer, repos.AccessRule, repos.User, nil, location, log), nil
+}
+
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	// Create a channel to listen for interrupt signals
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 
+	// Reload database credentials on SIGHUP and on a timer
+	reload := make(chan os.Signal, 1)
+	signal.Notify(reload, syscall.SIGHUP)
+	ctx, stopReload := context.WithCancel(context.Background())
+	go s.watchCredentials(ctx, reload)
+
+	// Start background tasks
+	if s.elector != nil {
+		s.elector.Start(context.Background())
+	} else {
+		s.scheduler.Start(context.Background())
+	}
+	s.worker.Start(context.Background())
+
 	// Start server in a goroutine
 	go func() {
 		s.log.WithFields(map[string]interface{}{
@@ -101,16 +702,77 @@ func (s *Server) Start() error {
 		}
 	}()
 
+	if s.services != nil {
+		go func() {
+			s.log.WithField("addr", s.services.Addr).Info("Starting mTLS server for service routes")
+
+			// The certificate is already loaded into the TLS config
+			if err := s.services.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+				s.log.WithError(err).Fatal("Failed to start mTLS server")
+			}
+		}()
+	}
+
 	// Wait for interrupt signal
 	<-quit
 	s.log.Info("Shutting down server...")
+	stopReload()
 
 	// Graceful shutdown
 	return s.Shutdown()
 }
 
+// watchCredentials reloads rotating database credentials until ctx is canceled
+func (s *Server) watchCredentials(ctx context.Context, reload <-chan os.Signal) {
+	if s.cfg.Database.CredentialsSource == "env" {
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-reload:
+				s.log.Warn("Ignoring SIGHUP, database credentials come from the environment")
+			}
+		}
+	}
+
+	var tick <-chan time.Time
+	if s.cfg.Database.CredentialsRefresh > 0 {
+		ticker := time.NewTicker(s.cfg.Database.CredentialsRefresh)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-reload:
+		case <-tick:
+		}
+
+		reloadCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
+		changed, err := s.db.ReloadCredentials(reloadCtx)
+		cancel()
+		if err != nil {
+			s.log.WithError(err).Error("Failed to reload database credentials, keeping the current ones")
+			continue
+		}
+		if changed {
+			s.log.Info("Database credentials rotated")
+		}
+	}
+}
+
 // Shutdown gracefully shuts down the server
 func (s *Server) Shutdown() error {
+	// Fail readiness first and keep serving while load balancers stop routing here
+	s.routes.StartDraining()
+	if s.cfg.Server.DrainPeriod > 0 {
+		s.server.SetKeepAlivesEnabled(false)
+		s.log.WithField("drain_period", s.cfg.Server.DrainPeriod.String()).Info("Draining before shutdown")
+		time.Sleep(s.cfg.Server.DrainPeriod)
+	}
+
 	// Create a context with timeout for shutdown
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
@@ -120,6 +782,25 @@ func (s *Server) Shutdown() error {
 		s.log.WithError(err).Error("Failed to shutdown server gracefully")
 		return err
 	}
+	if s.services != nil {
+		if err := s.services.Shutdown(ctx); err != nil {
+			s.log.WithError(err).Error("Failed to shutdown mTLS server gracefully")
+			return err
+		}
+	}
+
+	// Stop background tasks before closing the database they use
+	if s.elector != nil {
+		s.elector.Stop()
+	}
+	s.scheduler.Stop()
+	s.worker.Stop()
+
+	for _, client := range s.redis {
+		if err := client.Close(); err != nil {
+			s.log.WithError(err).Warn("Failed to close Redis connection")
+		}
+	}
 
 	// Close database connection
 	if err := s.db.Close(); err != nil {
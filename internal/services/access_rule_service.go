@@ -0,0 +1,345 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/casbin/govaluate"
+)
+
+// accessRuleService implements the AccessRuleService interface
+type accessRuleService struct {
+	ruleRepo repository.AccessRuleRepository
+	log      *logger.Logger
+}
+
+// NewAccessRuleService creates a new access rule service
+func NewAccessRuleService(ruleRepo repository.AccessRuleRepository, log *logger.Logger) AccessRuleService {
+	return &accessRuleService{
+		ruleRepo: ruleRepo,
+		log:      log,
+	}
+}
+
+// Create creates a new, active access rule
+func (s *accessRuleService) Create(ctx context.Context, req *models.AccessRuleCreateRequest) (*models.AccessRuleResponse, error) {
+	if err := s.checkNameAvailable(ctx, req.Name, 0); err != nil {
+		return nil, err
+	}
+	if err := checkCondition(req.Condition); err != nil {
+		return nil, err
+	}
+
+	rule := &models.AccessRule{
+		Name:        req.Name,
+		Description: req.Description,
+		Resource:    req.Resource,
+		Action:      req.Action,
+		Effect:      req.Effect,
+		Condition:   req.Condition,
+		IsActive:    true,
+	}
+	if err := s.ruleRepo.Create(ctx, rule); err != nil {
+		s.log.WithError(err).WithField("name", req.Name).Error("Failed to create access rule")
+		return nil, fmt.Errorf("failed to create access rule: %w", err)
+	}
+
+	s.log.WithField("access_rule_id", rule.ID).Info("Access rule created")
+	return rule.ToResponse(), nil
+}
+
+// GetByID returns an access rule
+func (s *accessRuleService) GetByID(ctx context.Context, id uint) (*models.AccessRuleResponse, error) {
+	rule, err := s.getRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return rule.ToResponse(), nil
+}
+
+// List returns all access rules
+func (s *accessRuleService) List(ctx context.Context) ([]*models.AccessRuleResponse, error) {
+	rules, err := s.ruleRepo.List(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to list access rules")
+		return nil, fmt.Errorf("failed to list access rules: %w", err)
+	}
+
+	responses := make([]*models.AccessRuleResponse, len(rules))
+	for i, rule := range rules {
+		responses[i] = rule.ToResponse()
+	}
+	return responses, nil
+}
+
+// Update changes the fields of an access rule given in the request
+func (s *accessRuleService) Update(ctx context.Context, id uint, req *models.AccessRuleUpdateRequest) (*models.AccessRuleResponse, error) {
+	rule, err := s.getRule(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil && *req.Name != rule.Name {
+		if err := s.checkNameAvailable(ctx, *req.Name, rule.ID); err != nil {
+			return nil, err
+		}
+		rule.Name = *req.Name
+	}
+	if req.Condition != nil {
+		if err := checkCondition(*req.Condition); err != nil {
+			return nil, err
+		}
+		rule.Condition = *req.Condition
+	}
+	if req.Description != nil {
+		rule.Description = *req.Description
+	}
+	if req.Resource != nil {
+		rule.Resource = *req.Resource
+	}
+	if req.Action != nil {
+		rule.Action = *req.Action
+	}
+	if req.Effect != nil {
+		rule.Effect = *req.Effect
+	}
+	if req.IsActive != nil {
+		rule.IsActive = *req.IsActive
+	}
+
+	if err := s.ruleRepo.Update(ctx, rule); err != nil {
+		s.log.WithError(err).WithField("access_rule_id", id).Error("Failed to update access rule")
+		return nil, fmt.Errorf("failed to update access rule: %w", err)
+	}
+
+	s.log.WithField("access_rule_id", id).Info("Access rule updated")
+	return rule.ToResponse(), nil
+}
+
+// Delete deletes an access rule
+func (s *accessRuleService) Delete(ctx context.Context, id uint) error {
+	deleted, err := s.ruleRepo.Delete(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("access_rule_id", id).Error("Failed to delete access rule")
+		return fmt.Errorf("failed to delete access rule: %w", err)
+	}
+	if !deleted {
+		return ErrAccessRuleNotFound
+	}
+
+	s.log.WithField("access_rule_id", id).Info("Access rule deleted")
+	return nil
+}
+
+// getRule returns an access rule, or ErrAccessRuleNotFound
+func (s *accessRuleService) getRule(ctx context.Context, id uint) (*models.AccessRule, error) {
+	rule, err := s.ruleRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("access_rule_id", id).Error("Failed to get access rule")
+		return nil, fmt.Errorf("failed to get access rule: %w", err)
+	}
+	if rule == nil {
+		return nil, ErrAccessRuleNotFound
+	}
+	return rule, nil
+}
+
+// checkNameAvailable returns ErrAccessRuleNameTaken when a rule other than exceptID has the name
+func (s *accessRuleService) checkNameAvailable(ctx context.Context, name string, exceptID uint) error {
+	existing, err := s.ruleRepo.GetByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check access rule name: %w", err)
+	}
+	if existing != nil && existing.ID != exceptID {
+		return ErrAccessRuleNameTaken
+	}
+	return nil
+}
+
+// checkCondition returns ErrInvalidCondition for conditions that can't be parsed
+func checkCondition(condition string) error {
+	if _, err := govaluate.NewEvaluableExpression(condition); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCondition, err)
+	}
+	return nil
+}
+
+// UserAttributes adds attributes of a user, such as a tenant ID, to those access rules see as user
+type UserAttributes func(user *models.User) map[string]interface{}
+
+// resourceAttributesKey is the context key for the attributes of the resource being accessed
+type resourceAttributesKey struct{}
+
+// WithResourceAttributes returns a context carrying the attributes access rules see as resource.
+// Handlers set them before authorizing access to a resource they loaded.
+func WithResourceAttributes(ctx context.Context, attributes map[string]interface{}) context.Context {
+	return context.WithValue(ctx, resourceAttributesKey{}, attributes)
+}
+
+// ruleAuthorizer evaluates access rules on top of another authorizer
+type ruleAuthorizer struct {
+	next       Authorizer
+	ruleRepo   repository.AccessRuleRepository
+	userRepo   repository.UserRepository
+	attributes UserAttributes
+	location   *time.Location
+	log        *logger.Logger
+	clock      func() time.Time
+}
+
+// NewRuleAuthorizer creates an authorizer applying the active access rules for a resource and
+// action to the decision of next. Access is refused when the condition of a require rule doesn't
+// hold or can't be evaluated. Otherwise it is granted when next grants it, or when the condition
+// of an allow rule holds. Conditions see the user, the resource attributes set with
+// WithResourceAttributes, and the time as env in location. attributes may be nil.
+func NewRuleAuthorizer(next Authorizer, ruleRepo repository.AccessRuleRepository, userRepo repository.UserRepository, attributes UserAttributes, location *time.Location, log *logger.Logger) Authorizer {
+	return &ruleAuthorizer{
+		next:       next,
+		ruleRepo:   ruleRepo,
+		userRepo:   userRepo,
+		attributes: attributes,
+		location:   location,
+		log:        log,
+		clock:      time.Now,
+	}
+}
+
+// Authorize combines the decision of the wrapped authorizer with the access rules
+func (a *ruleAuthorizer) Authorize(ctx context.Context, userID uint, resource, action string) (bool, error) {
+	rules, err := a.ruleRepo.ListActiveFor(ctx, resource, action)
+	if err != nil {
+		return false, fmt.Errorf("failed to get access rules: %w", err)
+	}
+	if len(rules) == 0 {
+		return a.next.Authorize(ctx, userID, resource, action)
+	}
+
+	parameters, err := a.parameters(ctx, userID)
+	if err != nil {
+		return false, err
+	}
+
+	for _, rule := range rules {
+		if rule.Effect == models.AccessRuleRequire && !a.holds(rule, parameters) {
+			a.log.WithFields(map[string]interface{}{
+				"user_id":     userID,
+				"resource":    resource,
+				"action":      action,
+				"access_rule": rule.Name,
+			}).Warn("Access refused by access rule")
+			return false, nil
+		}
+	}
+
+	allowed, err := a.next.Authorize(ctx, userID, resource, action)
+	if err != nil || allowed {
+		return allowed, err
+	}
+	for _, rule := range rules {
+		if rule.Effect == models.AccessRuleAllow && a.holds(rule, parameters) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Reload reloads the wrapped authorizer; access rules are read on every call
+func (a *ruleAuthorizer) Reload(ctx context.Context) error {
+	return a.next.Reload(ctx)
+}
+
+// holds reports whether the condition of a rule evaluates to true. Conditions that fail, for
+// example on a missing attribute, don't hold.
+func (a *ruleAuthorizer) holds(rule *models.AccessRule, parameters map[string]interface{}) bool {
+	held, err := evaluateCondition(rule.Condition, parameters)
+	if err != nil {
+		a.log.WithError(err).WithField("access_rule", rule.Name).Warn("Failed to evaluate access rule")
+		return false
+	}
+	return held
+}
+
+// evaluateCondition evaluates a condition, which must result in a boolean
+func evaluateCondition(condition string, parameters map[string]interface{}) (bool, error) {
+	expression, err := govaluate.NewEvaluableExpression(condition)
+	if err != nil {
+		return false, err
+	}
+	result, err := expression.Evaluate(parameters)
+	if err != nil {
+		return false, err
+	}
+	held, ok := result.(bool)
+	if !ok {
+		return false, fmt.Errorf("condition evaluated to %v instead of a boolean", result)
+	}
+	return held, nil
+}
+
+// parameters returns the attributes conditions are evaluated against
+func (a *ruleAuthorizer) parameters(ctx context.Context, userID uint) (map[string]interface{}, error) {
+	user, err := a.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	userAttributes := map[string]interface{}{"id": userID}
+	if user != nil {
+		if a.attributes != nil {
+			for name, value := range a.attributes(user) {
+				userAttributes[name] = value
+			}
+		}
+		userAttributes["id"] = user.ID
+		userAttributes["email"] = user.Email
+		userAttributes["username"] = user.Username
+		userAttributes["is_admin"] = user.IsAdmin
+	}
+
+	resourceAttributes, _ := ctx.Value(resourceAttributesKey{}).(map[string]interface{})
+	if resourceAttributes == nil {
+		resourceAttributes = map[string]interface{}{}
+	}
+
+	now := a.clock().In(a.location)
+	return map[string]interface{}{
+		"user":     normalizeAttributes(userAttributes),
+		"resource": normalizeAttributes(resourceAttributes),
+		"env": normalizeAttributes(map[string]interface{}{
+			"hour":    now.Hour(),
+			"minute":  now.Minute(),
+			"weekday": int(now.Weekday()), // 0 is Sunday
+			"date":    now.Format("2006-01-02"),
+		}),
+	}, nil
+}
+
+// normalizeAttributes converts numbers to float64, the type of numbers in conditions, so that
+// they compare equal to literals and to each other
+func normalizeAttributes(attributes map[string]interface{}) map[string]interface{} {
+	normalized := make(map[string]interface{}, len(attributes))
+	for name, value := range attributes {
+		switch v := value.(type) {
+		case int:
+			value = float64(v)
+		case int32:
+			value = float64(v)
+		case int64:
+			value = float64(v)
+		case uint:
+			value = float64(v)
+		case uint32:
+			value = float64(v)
+		case uint64:
+			value = float64(v)
+		case float32:
+			value = float64(v)
+		}
+		normalized[name] = value
+	}
+	return normalized
+}
@@ -0,0 +1,189 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAccessRuleRepository keeps access rules in memory
+type fakeAccessRuleRepository struct {
+	rules  map[uint]*models.AccessRule
+	nextID uint
+}
+
+func (r *fakeAccessRuleRepository) Create(ctx context.Context, rule *models.AccessRule) error {
+	if r.rules == nil {
+		r.rules = make(map[uint]*models.AccessRule)
+	}
+	r.nextID++
+	rule.ID = r.nextID
+	copied := *rule
+	r.rules[rule.ID] = &copied
+	return nil
+}
+
+func (r *fakeAccessRuleRepository) GetByID(ctx context.Context, id uint, opts ...repository.QueryOption) (*models.AccessRule, error) {
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *rule
+	return &copied, nil
+}
+
+func (r *fakeAccessRuleRepository) GetByName(ctx context.Context, name string) (*models.AccessRule, error) {
+	for _, rule := range r.rules {
+		if rule.Name == name {
+			copied := *rule
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAccessRuleRepository) List(ctx context.Context) ([]*models.AccessRule, error) {
+	var rules []*models.AccessRule
+	for _, rule := range r.rules {
+		copied := *rule
+		rules = append(rules, &copied)
+	}
+	sort.Slice(rules, func(i, j int) bool { return rules[i].ID < rules[j].ID })
+	return rules, nil
+}
+
+func (r *fakeAccessRuleRepository) ListActiveFor(ctx context.Context, resource, action string) ([]*models.AccessRule, error) {
+	all, _ := r.List(ctx)
+	var rules []*models.AccessRule
+	for _, rule := range all {
+		if rule.IsActive &&
+			(rule.Resource == resource || rule.Resource == models.AccessRuleWildcard) &&
+			(rule.Action == action || rule.Action == models.AccessRuleWildcard) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules, nil
+}
+
+func (r *fakeAccessRuleRepository) Update(ctx context.Context, rule *models.AccessRule) error {
+	copied := *rule
+	r.rules[rule.ID] = &copied
+	return nil
+}
+
+func (r *fakeAccessRuleRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	if _, ok := r.rules[id]; !ok {
+		return false, nil
+	}
+	delete(r.rules, id)
+	return true, nil
+}
+
+// staticAuthorizer grants the resource:action pairs it holds
+type staticAuthorizer map[string]bool
+
+func (a staticAuthorizer) Authorize(ctx context.Context, userID uint, resource, action string) (bool, error) {
+	return a[resource+":"+action], nil
+}
+
+func (a staticAuthorizer) Reload(ctx context.Context) error {
+	return nil
+}
+
+func TestAccessRuleService(t *testing.T) {
+	ctx := context.Background()
+	rules := NewAccessRuleService(&fakeAccessRuleRepository{}, logger.New("error", "text"))
+
+	rule, err := rules.Create(ctx, &models.AccessRuleCreateRequest{
+		Name:      "same-tenant",
+		Resource:  "user",
+		Action:    models.AccessRuleWildcard,
+		Effect:    models.AccessRuleRequire,
+		Condition: "user.tenant_id == resource.tenant_id",
+	})
+	require.NoError(t, err)
+	assert.True(t, rule.IsActive)
+
+	_, err = rules.Create(ctx, &models.AccessRuleCreateRequest{Name: "same-tenant", Resource: "user", Action: "read", Effect: models.AccessRuleAllow, Condition: "true"})
+	assert.ErrorIs(t, err, ErrAccessRuleNameTaken)
+	_, err = rules.Create(ctx, &models.AccessRuleCreateRequest{Name: "broken", Resource: "user", Action: "read", Effect: models.AccessRuleAllow, Condition: "user.id =="})
+	assert.ErrorIs(t, err, ErrInvalidCondition)
+
+	broken := "(env.hour"
+	_, err = rules.Update(ctx, rule.ID, &models.AccessRuleUpdateRequest{Condition: &broken})
+	assert.ErrorIs(t, err, ErrInvalidCondition)
+	inactive := false
+	updated, err := rules.Update(ctx, rule.ID, &models.AccessRuleUpdateRequest{IsActive: &inactive})
+	require.NoError(t, err)
+	assert.False(t, updated.IsActive)
+	assert.Equal(t, "user.tenant_id == resource.tenant_id", updated.Condition)
+
+	list, err := rules.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	require.NoError(t, rules.Delete(ctx, rule.ID))
+	assert.ErrorIs(t, rules.Delete(ctx, rule.ID), ErrAccessRuleNotFound)
+	_, err = rules.GetByID(ctx, rule.ID)
+	assert.ErrorIs(t, err, ErrAccessRuleNotFound)
+}
+
+func TestRuleAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	ruleRepo := &fakeAccessRuleRepository{}
+	userRepo := &MockUserRepository{}
+	userRepo.On("GetByID", mock.Anything, uint(1)).Return(&models.User{ID: 1}, nil)
+	userRepo.On("GetByID", mock.Anything, mock.Anything).Return(nil, nil)
+	tenants := func(user *models.User) map[string]interface{} {
+		return map[string]interface{}{"tenant_id": uint(7)}
+	}
+	next := staticAuthorizer{"user:read": true}
+
+	authorizer := NewRuleAuthorizer(next, ruleRepo, userRepo, tenants, time.UTC, log).(*ruleAuthorizer)
+	authorizer.clock = func() time.Time { return time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC) } // Monday
+
+	// Without rules, the wrapped authorizer decides
+	allowed, err := authorizer.Authorize(ctx, 1, "user", "read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	require.NoError(t, ruleRepo.Create(ctx, &models.AccessRule{
+		Name: "same-tenant", Resource: "user", Action: models.AccessRuleWildcard, Effect: models.AccessRuleRequire,
+		Condition: "user.tenant_id == resource.tenant_id", IsActive: true,
+	}))
+	allowed, err = authorizer.Authorize(WithResourceAttributes(ctx, map[string]interface{}{"tenant_id": 7}), 1, "user", "read")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = authorizer.Authorize(WithResourceAttributes(ctx, map[string]interface{}{"tenant_id": 8}), 1, "user", "read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = authorizer.Authorize(ctx, 1, "user", "read")
+	require.NoError(t, err)
+	assert.False(t, allowed, "require rules fail closed on missing attributes")
+
+	// Allow rules grant access the wrapped authorizer refuses, within require rules
+	require.NoError(t, ruleRepo.Create(ctx, &models.AccessRule{
+		Name: "business-hours", Resource: "file", Action: "upload", Effect: models.AccessRuleAllow,
+		Condition: "env.weekday >= 1 && env.weekday <= 5 && env.hour >= 9 && env.hour < 17", IsActive: true,
+	}))
+	allowed, err = authorizer.Authorize(ctx, 1, "file", "upload")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	authorizer.clock = func() time.Time { return time.Date(2026, 3, 2, 20, 0, 0, 0, time.UTC) }
+	allowed, err = authorizer.Authorize(ctx, 1, "file", "upload")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = authorizer.Authorize(ctx, 1, "user", "update")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+)
+
+// MergeAccounts moves the linked identities, files, organization memberships and tags of a
+// duplicate account to the user with the given ID, then deactivates the duplicate and signs it
+// out. Roles aren't moved, so merging can't grant privileges. Only an admin can reactivate the
+// duplicate, which keeps its profile.
+func (s *userService) MergeAccounts(ctx context.Context, actorID, id, duplicateID uint) (*models.AdminUserResponse, error) {
+	if id == duplicateID {
+		return nil, fmt.Errorf("%w: an account can't be merged into itself", ErrInvalidMerge)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for merge")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	duplicate, err := s.userRepo.GetByID(ctx, duplicateID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", duplicateID).Error("Failed to get duplicate user for merge")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if duplicate == nil {
+		return nil, ErrUserNotFound
+	}
+
+	duplicate.SetActive(false, actorID)
+	err = s.withTx(ctx, func(userRepo repository.UserRepository, _ repository.RoleRepository) error {
+		if err := userRepo.TransferOwnership(ctx, duplicateID, id); err != nil {
+			s.log.WithError(err).WithFields(map[string]interface{}{
+				"user_id":      id,
+				"duplicate_id": duplicateID,
+			}).Error("Failed to transfer ownership of merged account")
+			return fmt.Errorf("failed to merge accounts: %w", err)
+		}
+		if err := updateUser(ctx, userRepo, duplicate); err != nil {
+			s.log.WithError(err).WithField("user_id", duplicateID).Error("Failed to deactivate merged account")
+			return fmt.Errorf("failed to deactivate merged account: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := s.authSvc.RevokeRefreshTokens(ctx, duplicateID); err != nil {
+		s.log.WithError(err).WithField("user_id", duplicateID).Error("Failed to revoke refresh tokens of merged account")
+	}
+
+	s.log.Security("accounts_merged", id).WithFields(map[string]interface{}{
+		"actor_id":     actorID,
+		"duplicate_id": duplicateID,
+	}).Warn("Duplicate account merged")
+	s.recordActivity(ctx, id, models.AuditAccountMerged, map[string]interface{}{"duplicate_id": duplicateID})
+	s.recordActivity(ctx, duplicateID, models.AuditMergedInto, map[string]interface{}{"user_id": id})
+	return user.ToAdminResponse(), nil
+}
@@ -0,0 +1,66 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/middleware"
+)
+
+// UseActivityAudit records significant actions on user accounts, such as profile updates, password
+// changes and deletions, in the activity audit trail. Nothing is recorded until it is called.
+func (s *userService) UseActivityAudit(activityRepo repository.AuditEventRepository) {
+	s.activityRepo = activityRepo
+}
+
+// recordActivity adds an action on a user's account to their audit trail. The actor and client are
+// those of the request in ctx; actions of signed out users and of the application have no actor.
+// details must not hold secrets or the values of personal data. The action has already been
+// taken, so a failure is logged rather than returned.
+func (s *userService) recordActivity(ctx context.Context, userID uint, action string, details map[string]interface{}) {
+	if s.activityRepo == nil {
+		return
+	}
+
+	ip, userAgent := middleware.GetClientFromContext(ctx)
+	event := &models.AuditEvent{
+		UserID:    userID,
+		Action:    action,
+		Details:   details,
+		IPAddress: ip,
+		UserAgent: truncate(userAgent, 255),
+	}
+	event.ActorID, _ = middleware.GetUserIDFromContext(ctx)
+	event.ImpersonatorID, _ = middleware.GetImpersonatorFromContext(ctx)
+	if err := s.activityRepo.Create(ctx, event); err != nil {
+		s.log.WithError(err).WithFields(map[string]interface{}{
+			"user_id": userID,
+			"action":  action,
+		}).Error("Failed to record audit event")
+	}
+}
+
+// ActivityAudit returns a page of a user's audit trail, newest first, and the total number of
+// events. The trail of deleted users stays available until they are permanently deleted.
+func (s *userService) ActivityAudit(ctx context.Context, userID uint, page, limit int) ([]*models.AuditEvent, int64, error) {
+	user, err := s.userRepo.GetByID(ctx, userID, repository.WithDeleted())
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for audit trail")
+		return nil, 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, 0, ErrUserNotFound
+	}
+	if s.activityRepo == nil {
+		return []*models.AuditEvent{}, 0, nil
+	}
+
+	events, total, err := s.activityRepo.ListByUser(ctx, userID, limit, (page-1)*limit)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list audit events")
+		return nil, 0, fmt.Errorf("failed to list audit trail: %w", err)
+	}
+	return events, total, nil
+}
@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAuditEventRepository keeps audit events in memory
+type fakeAuditEventRepository struct {
+	events []*models.AuditEvent
+}
+
+func (r *fakeAuditEventRepository) Create(ctx context.Context, event *models.AuditEvent) error {
+	event.ID = uint(len(r.events) + 1)
+	event.CreatedAt = time.Now()
+	copied := *event
+	r.events = append(r.events, &copied)
+	return nil
+}
+
+func (r *fakeAuditEventRepository) ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.AuditEvent, int64, error) {
+	var events []*models.AuditEvent
+	for _, event := range r.events {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+	total := int64(len(events))
+	if offset >= len(events) {
+		return nil, total, nil
+	}
+	return events[offset:min(offset+limit, len(events))], total, nil
+}
+
+func TestUserService_ActivityAudit(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	activityRepo := &fakeAuditEventRepository{}
+	service.UseActivityAudit(activityRepo)
+
+	user := &models.User{ID: 1, Email: "test@example.com", Username: "test", IsActive: true}
+	mockRepo.On("GetByID", mock.Anything, user.ID).Return(user, nil)
+	mockRepo.On("GetByID", mock.Anything, user.ID, mock.Anything).Return(user, nil)
+	mockRepo.On("GetByID", mock.Anything, uint(2), mock.Anything).Return(nil, nil)
+	mockRepo.On("Update", mock.Anything, user).Return(nil)
+	mockRepo.On("Delete", mock.Anything, user.ID).Return(nil)
+
+	// An admin updates the user's name, then deletes the user
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, uint(9))
+	ctx = context.WithValue(ctx, middleware.ClientIPKey, "10.0.0.1")
+	firstName, lastName := "New", "Name"
+	_, err := service.Update(ctx, 9, true, user.ID, &models.UserUpdateRequest{FirstName: &firstName, LastName: &lastName})
+	require.NoError(t, err)
+	require.NoError(t, service.Delete(ctx, user.ID))
+
+	events, total, err := service.ActivityAudit(context.Background(), user.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, events, 2)
+	assert.Equal(t, models.AuditDeleted, events[0].Action)
+	assert.Equal(t, models.AuditProfileUpdated, events[1].Action)
+	assert.Equal(t, uint(9), events[1].ActorID)
+	assert.Equal(t, "10.0.0.1", events[1].IPAddress)
+	assert.Equal(t, []string{"first_name", "last_name"}, events[1].Details["fields"])
+
+	// Updates changing nothing aren't recorded
+	_, err = service.Update(ctx, 9, true, user.ID, &models.UserUpdateRequest{})
+	require.NoError(t, err)
+	assert.Len(t, activityRepo.events, 2)
+
+	_, _, err = service.ActivityAudit(context.Background(), 2, 1, 10)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+}
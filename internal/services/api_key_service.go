@@ -0,0 +1,161 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+)
+
+// apiKeyPrefix starts every API key, telling them apart from JWTs in the Authorization header
+const apiKeyPrefix = "gbt_"
+
+// apiKeyService implements the APIKeyService interface
+type apiKeyService struct {
+	keyRepo  repository.APIKeyRepository
+	userRepo repository.UserRepository
+	cfg      *config.Config
+	log      *logger.Logger
+}
+
+// NewAPIKeyService creates a new API key service
+func NewAPIKeyService(keyRepo repository.APIKeyRepository, userRepo repository.UserRepository, cfg *config.Config, log *logger.Logger) APIKeyService {
+	return &apiKeyService{
+		keyRepo:  keyRepo,
+		userRepo: userRepo,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+// Create issues a new API key for the user. The key itself is only part of this response.
+func (s *apiKeyService) Create(ctx context.Context, userID uint, req *models.APIKeyCreateRequest) (*models.APIKeyResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for API key")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if slices.Contains(req.Scopes, models.APIKeyScopeAdmin) && !user.IsAdmin {
+		return nil, ErrAPIKeyScopeNotAllowed
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return nil, ErrAPIKeyExpiryInvalid
+	}
+
+	count, err := s.keyRepo.CountByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count API keys: %w", err)
+	}
+	if count >= int64(s.cfg.APIKeys.MaxPerUser) {
+		return nil, ErrAPIKeyLimitReached
+	}
+
+	secret, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	raw := apiKeyPrefix + secret
+	scopes := slices.Compact(slices.Sorted(slices.Values(req.Scopes)))
+	key := &models.APIKey{
+		UserID:    userID,
+		Name:      req.Name,
+		Prefix:    raw[:len(apiKeyPrefix)+8],
+		KeyHash:   utils.HashToken(raw),
+		Scopes:    strings.Join(scopes, " "),
+		ExpiresAt: req.ExpiresAt,
+	}
+	if err := s.keyRepo.Create(ctx, key); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to store API key")
+		return nil, fmt.Errorf("failed to store API key: %w", err)
+	}
+
+	s.log.Security("api_key_created", userID).WithFields(map[string]interface{}{
+		"api_key_id": key.ID,
+		"scopes":     key.Scopes,
+	}).Info("API key created")
+
+	response := key.ToResponse()
+	response.Key = raw
+	return response, nil
+}
+
+// List returns the user's API keys without their secrets
+func (s *apiKeyService) List(ctx context.Context, userID uint) ([]*models.APIKeyResponse, error) {
+	keys, err := s.keyRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list API keys")
+		return nil, fmt.Errorf("failed to list API keys: %w", err)
+	}
+
+	responses := make([]*models.APIKeyResponse, len(keys))
+	for i, key := range keys {
+		responses[i] = key.ToResponse()
+	}
+	return responses, nil
+}
+
+// Delete revokes one of the user's API keys
+func (s *apiKeyService) Delete(ctx context.Context, userID, id uint) error {
+	deleted, err := s.keyRepo.Delete(ctx, userID, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to delete API key")
+		return fmt.Errorf("failed to delete API key: %w", err)
+	}
+	if !deleted {
+		return ErrAPIKeyNotFound
+	}
+
+	s.log.Security("api_key_deleted", userID).WithField("api_key_id", id).Info("API key deleted")
+	return nil
+}
+
+// Handles reports whether a bearer token is an API key rather than a JWT
+func (s *apiKeyService) Handles(rawToken string) bool {
+	return strings.HasPrefix(rawToken, apiKeyPrefix)
+}
+
+// Authenticate returns the owner of an API key and the scopes the key grants. Keys of
+// deactivated or suspended users are refused, and the admin scope only counts while the
+// owner is still an admin.
+func (s *apiKeyService) Authenticate(ctx context.Context, rawKey string) (*models.User, []string, error) {
+	key, err := s.keyRepo.GetByHash(ctx, utils.HashToken(rawKey))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+	now := time.Now()
+	if key == nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		return nil, nil, errors.New("unknown or expired API key")
+	}
+
+	user, err := s.userRepo.GetByID(ctx, key.UserID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.IsActive {
+		return nil, nil, errors.New("user account is deactivated")
+	}
+	if user.IsSuspended(now) {
+		return nil, nil, &AccountSuspendedError{Until: user.SuspendedUntil}
+	}
+
+	if err := s.keyRepo.RecordUse(ctx, key.ID, now); err != nil {
+		s.log.WithError(err).WithField("api_key_id", key.ID).Warn("Failed to record API key use")
+	}
+
+	scopes := key.GetScopes()
+	if !user.IsAdmin {
+		scopes = slices.DeleteFunc(scopes, func(scope string) bool { return scope == models.APIKeyScopeAdmin })
+	}
+	return user, scopes, nil
+}
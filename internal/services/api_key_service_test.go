@@ -0,0 +1,164 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeAPIKeyRepository keeps API keys in memory
+type fakeAPIKeyRepository struct {
+	keys []*models.APIKey
+}
+
+func (r *fakeAPIKeyRepository) Create(ctx context.Context, key *models.APIKey) error {
+	key.ID = uint(len(r.keys) + 1)
+	key.CreatedAt = time.Now()
+	copied := *key
+	r.keys = append(r.keys, &copied)
+	return nil
+}
+
+func (r *fakeAPIKeyRepository) GetByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	for _, key := range r.keys {
+		if key.KeyHash == keyHash {
+			copied := *key
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeAPIKeyRepository) ListByUser(ctx context.Context, userID uint) ([]*models.APIKey, error) {
+	var keys []*models.APIKey
+	for _, key := range r.keys {
+		if key.UserID == userID {
+			copied := *key
+			keys = append(keys, &copied)
+		}
+	}
+	return keys, nil
+}
+
+func (r *fakeAPIKeyRepository) CountByUser(ctx context.Context, userID uint) (int64, error) {
+	keys, _ := r.ListByUser(ctx, userID)
+	return int64(len(keys)), nil
+}
+
+func (r *fakeAPIKeyRepository) RecordUse(ctx context.Context, id uint, at time.Time) error {
+	for _, key := range r.keys {
+		if key.ID == id {
+			key.LastUsedAt = &at
+		}
+	}
+	return nil
+}
+
+func (r *fakeAPIKeyRepository) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	for i, key := range r.keys {
+		if key.ID == id && key.UserID == userID {
+			r.keys = append(r.keys[:i], r.keys[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func setupAPIKeyService() (*apiKeyService, *MockUserRepository, *fakeAPIKeyRepository) {
+	cfg := &config.Config{APIKeys: config.APIKeyConfig{Enabled: true, MaxPerUser: 2}}
+	mockRepo := &MockUserRepository{}
+	keyRepo := &fakeAPIKeyRepository{}
+	service := NewAPIKeyService(keyRepo, mockRepo, cfg, logger.New("error", "text")).(*apiKeyService)
+	return service, mockRepo, keyRepo
+}
+
+func TestAPIKeyService_Create(t *testing.T) {
+	service, mockRepo, keyRepo := setupAPIKeyService()
+	ctx := context.Background()
+
+	user := &models.User{ID: 1, Email: "test@example.com", IsActive: true}
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+
+	key, err := service.Create(ctx, user.ID, &models.APIKeyCreateRequest{Name: "CI", Scopes: []string{"users:write", "users:read", "users:read"}})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(key.Key, key.Prefix))
+	assert.True(t, service.Handles(key.Key))
+	assert.Equal(t, []string{"users:read", "users:write"}, key.Scopes)
+
+	// Only the hash is stored
+	require.Len(t, keyRepo.keys, 1)
+	assert.NotContains(t, keyRepo.keys[0].KeyHash, key.Key)
+	listed, err := service.List(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Empty(t, listed[0].Key)
+
+	_, err = service.Create(ctx, user.ID, &models.APIKeyCreateRequest{Name: "Admin", Scopes: []string{"admin"}})
+	assert.ErrorIs(t, err, ErrAPIKeyScopeNotAllowed)
+
+	past := time.Now().Add(-time.Minute)
+	_, err = service.Create(ctx, user.ID, &models.APIKeyCreateRequest{Name: "Old", Scopes: []string{"users:read"}, ExpiresAt: &past})
+	assert.ErrorIs(t, err, ErrAPIKeyExpiryInvalid)
+
+	_, err = service.Create(ctx, user.ID, &models.APIKeyCreateRequest{Name: "Second", Scopes: []string{"files:read"}})
+	require.NoError(t, err)
+	_, err = service.Create(ctx, user.ID, &models.APIKeyCreateRequest{Name: "Third", Scopes: []string{"files:read"}})
+	assert.ErrorIs(t, err, ErrAPIKeyLimitReached)
+
+	assert.ErrorIs(t, service.Delete(ctx, 2, key.ID), ErrAPIKeyNotFound)
+	require.NoError(t, service.Delete(ctx, user.ID, key.ID))
+}
+
+func TestAPIKeyService_Authenticate(t *testing.T) {
+	service, mockRepo, keyRepo := setupAPIKeyService()
+	ctx := context.Background()
+
+	user := &models.User{ID: 1, Email: "admin@example.com", IsActive: true, IsAdmin: true}
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+
+	key, err := service.Create(ctx, user.ID, &models.APIKeyCreateRequest{Name: "Ops", Scopes: []string{"admin", "users:read"}})
+	require.NoError(t, err)
+
+	authenticated, scopes, err := service.Authenticate(ctx, key.Key)
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, authenticated.ID)
+	assert.Equal(t, []string{"admin", "users:read"}, scopes)
+	assert.NotNil(t, keyRepo.keys[0].LastUsedAt)
+
+	_, _, err = service.Authenticate(ctx, key.Key+"x")
+	assert.Error(t, err)
+
+	// The admin scope lapses with the owner's admin rights
+	user.IsAdmin = false
+	_, scopes, err = service.Authenticate(ctx, key.Key)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"users:read"}, scopes)
+
+	// Keys stop working with the account
+	suspendedUntil := time.Now().Add(time.Hour)
+	user.SuspendedAt = &suspendedUntil
+	user.SuspendedUntil = &suspendedUntil
+	_, _, err = service.Authenticate(ctx, key.Key)
+	var suspended *AccountSuspendedError
+	assert.ErrorAs(t, err, &suspended)
+
+	user.SuspendedAt, user.SuspendedUntil = nil, nil
+	user.IsActive = false
+	_, _, err = service.Authenticate(ctx, key.Key)
+	assert.Error(t, err)
+
+	// Expired keys are refused
+	user.IsActive = true
+	expired := time.Now().Add(-time.Second)
+	keyRepo.keys[0].ExpiresAt = &expired
+	_, _, err = service.Authenticate(ctx, key.Key)
+	assert.Error(t, err)
+}
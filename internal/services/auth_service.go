@@ -1,34 +1,73 @@
 package services
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"slices"
+	"time"
+	"unicode/utf8"
 
 	"gbt-be-template/internal/config"
 	"gbt-be-template/internal/models"
 	"gbt-be-template/internal/repository"
 	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/revocation"
 	"gbt-be-template/pkg/utils"
 )
 
 // authService implements the AuthService interface
 type authService struct {
-	userRepo repository.UserRepository
-	cfg      *config.Config
-	log      *logger.Logger
+	userRepo         repository.UserRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	revocations      revocation.Store
+	cfg              *config.Config
+	log              *logger.Logger
+	enricher         ClaimsEnricher
+	roleRepo         repository.RoleRepository // Nil unless roles go into access tokens
 }
 
+// ClaimsEnricher returns extra claims to put in the access tokens of a user, such as a tenant ID,
+// roles or feature flags. Claims the template sets itself can't be overridden.
+type ClaimsEnricher func(user *models.User) map[string]interface{}
+
 // NewAuthService creates a new auth service
-func NewAuthService(userRepo repository.UserRepository, cfg *config.Config, log *logger.Logger) AuthService {
+func NewAuthService(userRepo repository.UserRepository, refreshTokenRepo repository.RefreshTokenRepository, revocations revocation.Store, cfg *config.Config, log *logger.Logger) AuthService {
 	return &authService{
-		userRepo: userRepo,
-		cfg:      cfg,
-		log:      log,
+		userRepo:         userRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		revocations:      revocations,
+		cfg:              cfg,
+		log:              log,
 	}
 }
 
+// UseClaimsEnricher adds the claims returned by enricher to every access token issued from now on.
+// enricher may be nil to issue tokens with the built-in claims only.
+func (s *authService) UseClaimsEnricher(enricher ClaimsEnricher) {
+	s.enricher = enricher
+}
+
+// UseRoles puts the user's roles, and with AUTHZ_PERMISSIONS_IN_TOKEN their permissions, in every
+// access token issued from now on
+func (s *authService) UseRoles(roleRepo repository.RoleRepository) {
+	s.roleRepo = roleRepo
+}
+
 // GenerateToken generates a JWT token for a user
 func (s *authService) GenerateToken(userID uint, email string, isAdmin bool) (string, error) {
-	token, err := utils.GenerateJWT(userID, email, isAdmin, s.cfg.JWT.Secret, s.cfg.JWT.Expiry)
+	extra, err := s.extraClaims(userID)
+	if err != nil {
+		return "", err
+	}
+
+	claims := utils.JWTClaims{UserID: userID, Email: email, IsAdmin: isAdmin, Extra: extra}
+	if err := s.roleClaims(&claims); err != nil {
+		return "", err
+	}
+
+	token, err := utils.GenerateJWTFromClaims(claims, s.cfg.JWT.Keys().Current, s.cfg.JWT.Expiry)
 	if err != nil {
 		s.log.WithError(err).WithField("user_id", userID).Error("Failed to generate JWT token")
 		return "", fmt.Errorf("failed to generate token: %w", err)
@@ -38,9 +77,73 @@ func (s *authService) GenerateToken(userID uint, email string, isAdmin bool) (st
 	return token, nil
 }
 
+// extraClaims asks the claims enricher, if any, for the extra claims of a user's access token
+func (s *authService) extraClaims(userID uint) (map[string]interface{}, error) {
+	if s.enricher == nil {
+		return nil, nil
+	}
+
+	user, err := s.userRepo.GetByID(context.Background(), userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for token claims")
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	if user == nil {
+		return nil, fmt.Errorf("failed to generate token: user not found")
+	}
+
+	extra := make(map[string]interface{})
+	for name, value := range s.enricher(user) {
+		if utils.IsReservedClaim(name) {
+			s.log.WithField("claim", name).Warn("Ignoring extra token claim that overrides a built-in claim")
+			continue
+		}
+		extra[name] = value
+	}
+	return extra, nil
+}
+
+// roleClaims adds the user's active roles, including those inherited, and when configured the
+// permissions they grant to the claims of an access token
+func (s *authService) roleClaims(claims *utils.JWTClaims) error {
+	if s.roleRepo == nil {
+		return nil
+	}
+
+	roles, err := grantingRoles(context.Background(), s.roleRepo, claims.UserID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", claims.UserID).Error("Failed to get roles for token claims")
+		return fmt.Errorf("failed to generate token: %w", err)
+	}
+	for _, role := range roles {
+		if s.cfg.Authz.RolesInToken {
+			claims.Roles = append(claims.Roles, role.Name)
+		}
+		if s.cfg.Authz.PermissionsInToken {
+			for _, permission := range role.Permissions {
+				claims.Permissions = append(claims.Permissions, utils.PermissionClaim(permission.Resource, permission.Action))
+			}
+		}
+	}
+	slices.Sort(claims.Roles)
+	slices.Sort(claims.Permissions)
+	claims.Permissions = slices.Compact(claims.Permissions)
+	return nil
+}
+
+// GenerateImpersonationToken generates a short-lived access token letting an admin act as a user
+func (s *authService) GenerateImpersonationToken(user *models.User, impersonatorID uint) (string, error) {
+	token, err := utils.GenerateImpersonationJWT(user.ID, user.Email, impersonatorID, s.cfg.JWT.Keys().Current, s.cfg.JWT.ImpersonationExpiry)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to generate impersonation token")
+		return "", fmt.Errorf("failed to generate token: %w", err)
+	}
+	return token, nil
+}
+
 // ValidateToken validates a JWT token and returns the user
 func (s *authService) ValidateToken(token string) (*models.User, error) {
-	claims, err := utils.ValidateJWT(token, s.cfg.JWT.Secret)
+	claims, err := utils.ValidateJWT(token, s.cfg.JWT.Keys())
 	if err != nil {
 		s.log.WithError(err).Warn("Failed to validate JWT token")
 		return nil, fmt.Errorf("invalid token: %w", err)
@@ -63,12 +166,24 @@ func (s *authService) ValidateToken(token string) (*models.User, error) {
 		return nil, fmt.Errorf("user account is deactivated")
 	}
 
+	if user.IsSuspended(time.Now()) {
+		s.log.WithField("user_id", claims.UserID).Warn("Token validation failed: user is suspended")
+		return nil, &AccountSuspendedError{Until: user.SuspendedUntil}
+	}
+
 	return user, nil
 }
 
 // RefreshToken generates a new token with extended expiry
 func (s *authService) RefreshToken(token string) (string, error) {
-	newToken, err := utils.RefreshJWT(token, s.cfg.JWT.Secret, s.cfg.JWT.Expiry)
+	// A revoked token must not be traded for a fresh one
+	if claims, err := utils.ValidateJWT(token, s.cfg.JWT.Keys()); err == nil {
+		if revoked, _ := s.IsAccessTokenRevoked(context.Background(), claims); revoked {
+			return "", fmt.Errorf("failed to refresh token: token has been revoked")
+		}
+	}
+
+	newToken, err := utils.RefreshJWT(token, s.cfg.JWT.Keys(), s.cfg.JWT.Expiry)
 	if err != nil {
 		s.log.WithError(err).Warn("Failed to refresh JWT token")
 		return "", fmt.Errorf("failed to refresh token: %w", err)
@@ -77,3 +192,273 @@ func (s *authService) RefreshToken(token string) (string, error) {
 	s.log.Info("JWT token refreshed successfully")
 	return newToken, nil
 }
+
+// IssueRefreshToken issues a refresh token starting a new token family, and records it as
+// a session of the client making the request. Sessions remembered at login get refresh tokens
+// lasting JWT_REMEMBER_ME_EXPIRY instead of JWT_REFRESH_EXPIRY; access tokens stay as short.
+func (s *authService) IssueRefreshToken(ctx context.Context, userID uint, rememberMe bool) (string, error) {
+	familyID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate token family: %w", err)
+	}
+
+	if err := s.enforceSessionLimit(ctx, userID); err != nil {
+		return "", err
+	}
+
+	ip, userAgent := middleware.GetClientFromContext(ctx)
+	ttl := s.refreshTTL(rememberMe)
+	now := time.Now()
+	if err := s.refreshTokenRepo.CreateSession(ctx, &models.Session{
+		UserID:     userID,
+		FamilyID:   familyID,
+		UserAgent:  truncate(userAgent, 255),
+		IPAddress:  ip,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(ttl),
+		RememberMe: rememberMe,
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to store session")
+		return "", fmt.Errorf("failed to store session: %w", err)
+	}
+
+	return s.createRefreshToken(ctx, userID, familyID, ttl)
+}
+
+// RotateRefreshToken exchanges a refresh token for a new access token and refresh token.
+// Presenting a token that was already rotated means it was copied, so the whole family is revoked.
+func (s *authService) RotateRefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+	stored, err := s.refreshTokenRepo.GetByHash(ctx, utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+	if stored == nil || stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return nil, ErrInvalidRefreshToken
+	}
+
+	if stored.UsedAt != nil {
+		return nil, s.handleRefreshTokenReuse(ctx, stored)
+	}
+
+	// Mark used atomically; losing the race to a concurrent rotation is also reuse
+	marked, err := s.refreshTokenRepo.MarkUsed(ctx, stored.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to rotate refresh token: %w", err)
+	}
+	if !marked {
+		return nil, s.handleRefreshTokenReuse(ctx, stored)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, stored.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.IsActive || user.IsSuspended(time.Now()) {
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, stored.FamilyID); err != nil {
+			s.log.WithError(err).WithField("user_id", stored.UserID).Error("Failed to revoke refresh token family")
+		}
+		return nil, ErrInvalidRefreshToken
+	}
+
+	// Rotated tokens keep the lifetime the session was given at login
+	session, err := s.refreshTokenRepo.GetSessionByFamily(ctx, stored.FamilyID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+	ttl := s.refreshTTL(session != nil && session.RememberMe)
+
+	newRefreshToken, err := s.createRefreshToken(ctx, user.ID, stored.FamilyID, ttl)
+	if err != nil {
+		return nil, err
+	}
+
+	ip, _ := middleware.GetClientFromContext(ctx)
+	now := time.Now()
+	if err := s.refreshTokenRepo.TouchSession(ctx, stored.FamilyID, ip, now, now.Add(ttl)); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to record session activity")
+	}
+
+	accessToken, err := s.GenerateToken(user.ID, user.Email, user.IsAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.WithField("user_id", user.ID).Info("Refresh token rotated successfully")
+	return &models.LoginResponse{
+		AccessToken:  accessToken,
+		RefreshToken: newRefreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.cfg.JWT.Expiry.Seconds()),
+	}, nil
+}
+
+// RevokeRefreshTokens revokes every refresh token of a user
+func (s *authService) RevokeRefreshTokens(ctx context.Context, userID uint) error {
+	if err := s.refreshTokenRepo.RevokeAllForUser(ctx, userID); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke refresh tokens")
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	return nil
+}
+
+// ListSessions returns the user's signed-in devices, most recently used first
+func (s *authService) ListSessions(ctx context.Context, userID uint) ([]*models.Session, error) {
+	sessions, err := s.refreshTokenRepo.ListActiveSessions(ctx, userID, time.Now())
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list sessions")
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+	return sessions, nil
+}
+
+// RevokeSession signs out one of the user's devices by revoking its refresh tokens. Access
+// tokens already issued to the device stay valid until they expire.
+func (s *authService) RevokeSession(ctx context.Context, userID, id uint) error {
+	revoked, err := s.refreshTokenRepo.RevokeSession(ctx, userID, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke session")
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+	if !revoked {
+		return ErrSessionNotFound
+	}
+
+	s.log.Security("session_revoked", userID).WithField("session_id", id).Info("Session revoked")
+	return nil
+}
+
+// RevokeAccessToken rejects an access token from now until it expires
+func (s *authService) RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	if err := s.revocations.Revoke(ctx, tokenID, expiresAt); err != nil {
+		s.log.WithError(err).Error("Failed to revoke access token")
+		return fmt.Errorf("failed to revoke access token: %w", err)
+	}
+	return nil
+}
+
+// RevokeUserAccessTokens rejects every access token issued to the user so far, from now until
+// the last of them expires
+func (s *authService) RevokeUserAccessTokens(ctx context.Context, userID uint) error {
+	now := time.Now()
+	if err := s.revocations.RevokeUser(ctx, userID, now, now.Add(s.accessTokenTTL())); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke access tokens")
+		return fmt.Errorf("failed to revoke access tokens: %w", err)
+	}
+	return nil
+}
+
+// IsAccessTokenRevoked reports whether an access token was revoked, by itself or together with
+// every token of its user
+func (s *authService) IsAccessTokenRevoked(ctx context.Context, claims *utils.JWTClaims) (bool, error) {
+	// Tokens issued before token IDs were introduced cannot be revoked by themselves
+	if claims.ID != "" {
+		if revoked, err := s.revocations.IsRevoked(ctx, claims.ID); revoked || err != nil {
+			return revoked, err
+		}
+	}
+
+	before, err := s.revocations.RevokedBefore(ctx, claims.UserID)
+	if err != nil || before.IsZero() {
+		return false, err
+	}
+	// Issue times are whole seconds, so a token issued in the same second as the revocation
+	// counts as issued before it
+	return claims.IssuedAt == nil || !claims.IssuedAt.Time.After(before.Truncate(time.Second)), nil
+}
+
+// accessTokenTTL returns the longest lifetime of the access tokens the service issues
+func (s *authService) accessTokenTTL() time.Duration {
+	ttl := s.cfg.JWT.Expiry
+	for _, expiry := range []time.Duration{s.cfg.JWT.ImpersonationExpiry, s.cfg.OAuth2.AccessTokenExpiry} {
+		if expiry > ttl {
+			ttl = expiry
+		}
+	}
+	return ttl
+}
+
+// refreshTTL returns how long refresh tokens of a session last
+func (s *authService) refreshTTL(rememberMe bool) time.Duration {
+	if rememberMe {
+		return s.cfg.JWT.RememberMeExpiry
+	}
+	return s.cfg.JWT.RefreshExpiry
+}
+
+// createRefreshToken stores a new refresh token in the given family, valid for ttl
+func (s *authService) createRefreshToken(ctx context.Context, userID uint, familyID string, ttl time.Duration) (string, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+
+	if err := s.refreshTokenRepo.Create(ctx, &models.RefreshToken{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(ttl),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to store refresh token")
+		return "", fmt.Errorf("failed to store refresh token: %w", err)
+	}
+
+	return token, nil
+}
+
+// enforceSessionLimit makes room for a new session when the user is at the session limit,
+// by ending their oldest sessions or refusing the login, depending on the policy
+func (s *authService) enforceSessionLimit(ctx context.Context, userID uint) error {
+	limit := s.cfg.Session.MaxPerUser
+	if limit <= 0 {
+		return nil
+	}
+
+	sessions, err := s.refreshTokenRepo.ListActiveSessions(ctx, userID, time.Now())
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list sessions")
+		return fmt.Errorf("failed to list sessions: %w", err)
+	}
+	if len(sessions) < limit {
+		return nil
+	}
+	if s.cfg.Session.LimitPolicy == config.SessionLimitReject {
+		s.log.Security("session_limit_reached", userID).Warn("Login refused, too many active sessions")
+		return ErrSessionLimitReached
+	}
+
+	slices.SortFunc(sessions, func(a, b *models.Session) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+	for _, session := range sessions[:len(sessions)-limit+1] {
+		if err := s.refreshTokenRepo.RevokeFamily(ctx, session.FamilyID); err != nil {
+			s.log.WithError(err).WithField("user_id", userID).Error("Failed to evict session")
+			return fmt.Errorf("failed to evict session: %w", err)
+		}
+		s.log.Security("session_evicted", userID).WithField("session_id", session.ID).Info("Oldest session ended to stay within the session limit")
+	}
+	return nil
+}
+
+// handleRefreshTokenReuse revokes the token family and raises a security event
+func (s *authService) handleRefreshTokenReuse(ctx context.Context, token *models.RefreshToken) error {
+	s.log.Security("refresh_token_reuse", token.UserID).
+		WithField("family_id", token.FamilyID).
+		Warn("Refresh token reuse detected, revoking token family")
+
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, token.FamilyID); err != nil {
+		s.log.WithError(err).WithField("user_id", token.UserID).Error("Failed to revoke refresh token family")
+		return errors.Join(ErrRefreshTokenReuse, err)
+	}
+	return ErrRefreshTokenReuse
+}
+
+// truncate shortens s to at most n bytes without splitting a UTF-8 character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
@@ -0,0 +1,299 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/revocation"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRefreshTokenRepository keeps refresh tokens and sessions in memory
+type fakeRefreshTokenRepository struct {
+	tokens   []*models.RefreshToken
+	sessions []*models.Session
+}
+
+func (r *fakeRefreshTokenRepository) Create(ctx context.Context, token *models.RefreshToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *fakeRefreshTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.RefreshToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRefreshTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil && token.RevokedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeRefreshTokenRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	now := time.Now()
+	for _, token := range r.tokens {
+		if token.FamilyID == familyID {
+			token.RevokedAt = &now
+		}
+	}
+	for _, session := range r.sessions {
+		if session.FamilyID == familyID {
+			session.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *fakeRefreshTokenRepository) RevokeAllForUser(ctx context.Context, userID uint) error {
+	for _, session := range r.sessions {
+		if session.UserID == userID {
+			r.RevokeFamily(ctx, session.FamilyID)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRefreshTokenRepository) CreateSession(ctx context.Context, session *models.Session) error {
+	session.ID = uint(len(r.sessions) + 1)
+	session.CreatedAt = time.Now().Add(time.Duration(session.ID) * time.Second)
+	r.sessions = append(r.sessions, session)
+	return nil
+}
+
+func (r *fakeRefreshTokenRepository) GetSessionByFamily(ctx context.Context, familyID string) (*models.Session, error) {
+	for _, session := range r.sessions {
+		if session.FamilyID == familyID {
+			copied := *session
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRefreshTokenRepository) TouchSession(ctx context.Context, familyID, ipAddress string, at, expiresAt time.Time) error {
+	for _, session := range r.sessions {
+		if session.FamilyID == familyID {
+			session.IPAddress, session.LastUsedAt, session.ExpiresAt = ipAddress, at, expiresAt
+		}
+	}
+	return nil
+}
+
+func (r *fakeRefreshTokenRepository) ListActiveSessions(ctx context.Context, userID uint, now time.Time) ([]*models.Session, error) {
+	var sessions []*models.Session
+	for _, session := range r.sessions {
+		if session.UserID == userID && session.RevokedAt == nil && session.ExpiresAt.After(now) {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *fakeRefreshTokenRepository) RevokeSession(ctx context.Context, userID, id uint) (bool, error) {
+	for _, session := range r.sessions {
+		if session.ID == id && session.UserID == userID && session.RevokedAt == nil {
+			return true, r.RevokeFamily(ctx, session.FamilyID)
+		}
+	}
+	return false, nil
+}
+
+func setupAuthService(session config.SessionConfig) (*authService, *fakeRefreshTokenRepository) {
+	cfg := &config.Config{
+		JWT:     config.JWTConfig{Secret: "test-secret", KeyID: "v1", Expiry: time.Minute, RefreshExpiry: time.Hour, RememberMeExpiry: 30 * 24 * time.Hour},
+		Session: session,
+	}
+	tokenRepo := &fakeRefreshTokenRepository{}
+	service := NewAuthService(&MockUserRepository{}, tokenRepo, revocation.NewMemoryStore(), cfg, logger.New("error", "text")).(*authService)
+	return service, tokenRepo
+}
+
+// activeSessionIDs returns the IDs of the user's sessions that weren't revoked
+func activeSessionIDs(t *testing.T, service *authService, userID uint) []uint {
+	sessions, err := service.ListSessions(context.Background(), userID)
+	require.NoError(t, err)
+	var ids []uint
+	for _, session := range sessions {
+		ids = append(ids, session.ID)
+	}
+	return ids
+}
+
+func TestAuthService_SessionLimit(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("unlimited by default", func(t *testing.T) {
+		service, _ := setupAuthService(config.SessionConfig{LimitPolicy: config.SessionLimitEvictOldest})
+		for i := 0; i < 5; i++ {
+			_, err := service.IssueRefreshToken(ctx, 1, false)
+			require.NoError(t, err)
+		}
+		assert.Len(t, activeSessionIDs(t, service, 1), 5)
+	})
+
+	t.Run("oldest sessions are evicted", func(t *testing.T) {
+		service, tokenRepo := setupAuthService(config.SessionConfig{MaxPerUser: 2, LimitPolicy: config.SessionLimitEvictOldest})
+		for i := 0; i < 3; i++ {
+			_, err := service.IssueRefreshToken(ctx, 1, false)
+			require.NoError(t, err)
+		}
+		_, err := service.IssueRefreshToken(ctx, 2, false)
+		require.NoError(t, err)
+
+		assert.ElementsMatch(t, []uint{2, 3}, activeSessionIDs(t, service, 1))
+		assert.NotNil(t, tokenRepo.tokens[0].RevokedAt)
+		assert.Len(t, activeSessionIDs(t, service, 2), 1)
+	})
+
+	t.Run("logins are rejected", func(t *testing.T) {
+		service, _ := setupAuthService(config.SessionConfig{MaxPerUser: 2, LimitPolicy: config.SessionLimitReject})
+		for i := 0; i < 2; i++ {
+			_, err := service.IssueRefreshToken(ctx, 1, false)
+			require.NoError(t, err)
+		}
+		_, err := service.IssueRefreshToken(ctx, 1, false)
+		assert.ErrorIs(t, err, ErrSessionLimitReached)
+
+		// Signing out a device makes room
+		require.NoError(t, service.RevokeSession(ctx, 1, 1))
+		_, err = service.IssueRefreshToken(ctx, 1, false)
+		assert.NoError(t, err)
+		assert.ErrorIs(t, service.RevokeSession(ctx, 1, 1), ErrSessionNotFound)
+	})
+}
+
+func TestAuthService_RememberMe(t *testing.T) {
+	ctx := context.Background()
+	service, tokenRepo := setupAuthService(config.SessionConfig{})
+	user := &models.User{ID: 1, Email: "test@example.com", IsActive: true}
+	service.userRepo.(*MockUserRepository).On("GetByID", ctx, user.ID).Return(user, nil)
+
+	_, err := service.IssueRefreshToken(ctx, user.ID, false)
+	require.NoError(t, err)
+	remembered, err := service.IssueRefreshToken(ctx, user.ID, true)
+	require.NoError(t, err)
+
+	assert.WithinDuration(t, time.Now().Add(time.Hour), tokenRepo.tokens[0].ExpiresAt, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), tokenRepo.tokens[1].ExpiresAt, time.Minute)
+	assert.False(t, tokenRepo.sessions[0].RememberMe)
+	assert.True(t, tokenRepo.sessions[1].RememberMe)
+
+	// Rotation keeps the long lifetime while access tokens stay short
+	resp, err := service.RotateRefreshToken(ctx, remembered)
+	require.NoError(t, err)
+	assert.Equal(t, int64(60), resp.ExpiresIn)
+	require.Len(t, tokenRepo.tokens, 3)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), tokenRepo.tokens[2].ExpiresAt, time.Minute)
+	assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), tokenRepo.sessions[1].ExpiresAt, time.Minute)
+}
+
+func TestAuthService_RevokeUserAccessTokens(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupAuthService(config.SessionConfig{})
+	issued := func(userID uint, at time.Time) *utils.JWTClaims {
+		claims := &utils.JWTClaims{UserID: userID}
+		claims.IssuedAt = jwt.NewNumericDate(at)
+		return claims
+	}
+
+	token, err := service.GenerateToken(1, "test@example.com", false)
+	require.NoError(t, err)
+	earlier := issued(1, time.Now().Add(-time.Minute))
+	revoked, err := service.IsAccessTokenRevoked(ctx, earlier)
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	require.NoError(t, service.RevokeUserAccessTokens(ctx, 1))
+
+	revoked, err = service.IsAccessTokenRevoked(ctx, earlier)
+	require.NoError(t, err)
+	assert.True(t, revoked)
+	_, err = service.RefreshToken(token)
+	assert.Error(t, err, "a revoked token can't be refreshed")
+
+	// Tokens of other users and tokens issued later are left alone
+	revoked, err = service.IsAccessTokenRevoked(ctx, issued(2, time.Now().Add(-time.Minute)))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+	revoked, err = service.IsAccessTokenRevoked(ctx, issued(1, time.Now().Add(2*time.Second)))
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestAuthService_ClaimsEnricher(t *testing.T) {
+	service, _ := setupAuthService(config.SessionConfig{})
+	user := &models.User{ID: 1, Email: "test@example.com", IsActive: true}
+	service.userRepo.(*MockUserRepository).On("GetByID", context.Background(), user.ID).Return(user, nil)
+	service.UseClaimsEnricher(func(user *models.User) map[string]interface{} {
+		return map[string]interface{}{"tenant_id": "acme", "is_admin": true}
+	})
+
+	token, err := service.GenerateToken(user.ID, user.Email, false)
+	require.NoError(t, err)
+	claims, err := utils.ValidateJWT(token, service.cfg.JWT.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.Extra["tenant_id"])
+	// Built-in claims can't be overridden
+	assert.False(t, claims.IsAdmin)
+	assert.NotContains(t, claims.Extra, "is_admin")
+}
+
+func TestAuthService_RoleClaims(t *testing.T) {
+	ctx := context.Background()
+	service, _ := setupAuthService(config.SessionConfig{})
+	roles, permissions := setupRoleServices()
+	list, err := permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserList, Resource: "user", Action: "list"})
+	require.NoError(t, err)
+	parent, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: models.RoleModerator})
+	require.NoError(t, err)
+	_, err = roles.SetPermissions(ctx, 2, &models.AssignPermissionRequest{RoleID: parent.ID, PermissionIDs: []uint{list.ID}})
+	require.NoError(t, err)
+	role, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: "support", ParentRoleID: &parent.ID})
+	require.NoError(t, err)
+	_, err = roles.AssignToUser(ctx, 2, &models.AssignRoleRequest{UserID: 1, RoleIDs: []uint{role.ID}})
+	require.NoError(t, err)
+
+	// Without UseRoles, tokens carry no roles
+	token, err := service.GenerateToken(1, "test@example.com", false)
+	require.NoError(t, err)
+	claims, err := utils.ValidateJWT(token, service.cfg.JWT.Keys())
+	require.NoError(t, err)
+	assert.Nil(t, claims.Roles)
+
+	service.UseRoles(roles.roleRepo)
+	service.cfg.Authz.RolesInToken = true
+	token, err = service.GenerateToken(1, "test@example.com", false)
+	require.NoError(t, err)
+	claims, err = utils.ValidateJWT(token, service.cfg.JWT.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.RoleModerator, "support"}, claims.Roles)
+	assert.Nil(t, claims.Permissions)
+
+	service.cfg.Authz.PermissionsInToken = true
+	token, err = service.GenerateToken(1, "test@example.com", false)
+	require.NoError(t, err)
+	claims, err = utils.ValidateJWT(token, service.cfg.JWT.Keys())
+	require.NoError(t, err)
+	assert.Equal(t, []string{"user:list"}, claims.Permissions)
+}
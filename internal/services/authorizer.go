@@ -0,0 +1,188 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/casbin/casbin/v2"
+	"github.com/casbin/casbin/v2/model"
+	"github.com/casbin/casbin/v2/persist"
+)
+
+// DefaultCasbinModel is the casbin model used when no model file is configured. It grants users
+// the resource/action pairs of their roles, like the RBAC authorizer.
+const DefaultCasbinModel = `
+[request_definition]
+r = sub, obj, act
+
+[policy_definition]
+p = sub, obj, act
+
+[role_definition]
+g = _, _
+
+[policy_effect]
+e = some(where (p.eft == allow))
+
+[matchers]
+m = g(r.sub, p.sub) && r.obj == p.obj && r.act == p.act
+`
+
+// rbacAuthorizer grants users the permissions of their active roles and the roles they inherit from
+type rbacAuthorizer struct {
+	roleRepo repository.RoleRepository
+}
+
+// NewRBACAuthorizer creates an authorizer checking the permissions of the user's roles on every call
+func NewRBACAuthorizer(roleRepo repository.RoleRepository) Authorizer {
+	return &rbacAuthorizer{
+		roleRepo: roleRepo,
+	}
+}
+
+// Authorize reports whether an active role of the user has a permission for the resource and
+// action, itself or through the roles it inherits from. Inheritance stops at an inactive role.
+func (a *rbacAuthorizer) Authorize(ctx context.Context, userID uint, resource, action string) (bool, error) {
+	roles, err := grantingRoles(ctx, a.roleRepo, userID)
+	if err != nil {
+		return false, err
+	}
+	for _, role := range roles {
+		for _, permission := range role.Permissions {
+			if permission.Resource == resource && permission.Action == action {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// grantingRoles returns the active roles of a user and the active roles they inherit from.
+// Inheritance stops at an inactive role. Roles inherited more than once are listed once.
+func grantingRoles(ctx context.Context, roleRepo repository.RoleRepository, userID uint) ([]*models.Role, error) {
+	roles, err := roleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	var granting []*models.Role
+	seen := make(map[uint]bool)
+	for _, role := range roles {
+		if !role.IsActive {
+			continue
+		}
+		ancestors, err := roleAncestors(ctx, roleRepo, role)
+		if err != nil {
+			return nil, err
+		}
+		for _, inherited := range append([]*models.Role{role}, ancestors...) {
+			if !inherited.IsActive {
+				break
+			}
+			if !seen[inherited.ID] {
+				seen[inherited.ID] = true
+				granting = append(granting, inherited)
+			}
+		}
+	}
+	return granting, nil
+}
+
+// Reload does nothing, roles are read on every call
+func (a *rbacAuthorizer) Reload(ctx context.Context) error {
+	return nil
+}
+
+// casbinAuthorizer evaluates a casbin model over a policy loaded from an adapter
+type casbinAuthorizer struct {
+	enforcer       *casbin.SyncedEnforcer
+	reloadInterval time.Duration
+	log            *logger.Logger
+
+	mu       sync.Mutex
+	loadedAt time.Time
+}
+
+// NewCasbinAuthorizer creates an authorizer enforcing the casbin model in modelFile, or
+// DefaultCasbinModel when it is empty. Users are the subjects returned by
+// repository.CasbinSubject. The policy is loaded from adapter right away, and again on the first
+// call after reloadInterval.
+func NewCasbinAuthorizer(adapter persist.Adapter, modelFile string, reloadInterval time.Duration, log *logger.Logger) (Authorizer, error) {
+	var m model.Model
+	var err error
+	if modelFile == "" {
+		m, err = model.NewModelFromString(DefaultCasbinModel)
+	} else {
+		m, err = model.NewModelFromFile(modelFile)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load casbin model: %w", err)
+	}
+
+	enforcer, err := casbin.NewSyncedEnforcer(m, adapter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create casbin enforcer: %w", err)
+	}
+
+	return &casbinAuthorizer{
+		enforcer:       enforcer,
+		reloadInterval: reloadInterval,
+		log:            log,
+		loadedAt:       time.Now(),
+	}, nil
+}
+
+// Authorize reports whether the casbin model allows the user the action on the resource. When the
+// policy can't be reloaded, the previous one is used.
+func (a *casbinAuthorizer) Authorize(ctx context.Context, userID uint, resource, action string) (bool, error) {
+	if a.claimReload() {
+		if err := a.Reload(ctx); err != nil {
+			a.log.WithError(err).Warn("Failed to reload casbin policy, using the previous one")
+		}
+	}
+
+	allowed, err := a.enforcer.Enforce(repository.CasbinSubject(userID), resource, action)
+	if err != nil {
+		return false, fmt.Errorf("failed to enforce casbin policy: %w", err)
+	}
+	return allowed, nil
+}
+
+// Reload loads the policy from the adapter again
+func (a *casbinAuthorizer) Reload(ctx context.Context) error {
+	if err := a.enforcer.LoadPolicy(); err != nil {
+		return fmt.Errorf("failed to load casbin policy: %w", err)
+	}
+	a.mu.Lock()
+	a.loadedAt = time.Now()
+	a.mu.Unlock()
+	return nil
+}
+
+// claimReload reports whether the policy is due for a reload, so that only one caller reloads it
+func (a *casbinAuthorizer) claimReload() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if time.Since(a.loadedAt) < a.reloadInterval {
+		return false
+	}
+	a.loadedAt = time.Now()
+	return true
+}
+
+// reloadAuthorizer makes the authorizer pick up a change to roles or permissions. A failure is only
+// logged: the change is stored, and the authorizer retries on its own.
+func reloadAuthorizer(ctx context.Context, authorizer Authorizer, log *logger.Logger) {
+	if authorizer == nil {
+		return
+	}
+	if err := authorizer.Reload(ctx); err != nil {
+		log.WithError(err).Warn("Failed to reload authorizer")
+	}
+}
@@ -0,0 +1,95 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	stringadapter "github.com/casbin/casbin/v2/persist/string-adapter"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRBACAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	roles, permissions := setupRoleServices()
+	authorizer := NewRBACAuthorizer(roles.roleRepo)
+	roles.UseAuthorizer(authorizer)
+
+	list, err := permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserList, Resource: "user", Action: "list"})
+	require.NoError(t, err)
+	role, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: models.RoleModerator})
+	require.NoError(t, err)
+	_, err = roles.SetPermissions(ctx, 2, &models.AssignPermissionRequest{RoleID: role.ID, PermissionIDs: []uint{list.ID}})
+	require.NoError(t, err)
+	_, err = roles.AssignToUser(ctx, 2, &models.AssignRoleRequest{UserID: 1, RoleIDs: []uint{role.ID}})
+	require.NoError(t, err)
+
+	allowed, err := authorizer.Authorize(ctx, 1, "user", "list")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = authorizer.Authorize(ctx, 1, "user", "delete")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Permissions are inherited from parent roles
+	remove, err := permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserDelete, Resource: "user", Action: "delete"})
+	require.NoError(t, err)
+	parent, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: "cleaner"})
+	require.NoError(t, err)
+	_, err = roles.SetPermissions(ctx, 2, &models.AssignPermissionRequest{RoleID: parent.ID, PermissionIDs: []uint{remove.ID}})
+	require.NoError(t, err)
+	_, err = roles.Update(ctx, 2, role.ID, &models.RoleUpdateRequest{ParentRoleID: &parent.ID})
+	require.NoError(t, err)
+	allowed, err = authorizer.Authorize(ctx, 1, "user", "delete")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	// Inactive roles grant nothing, and aren't inherited from
+	inactive := false
+	_, err = roles.Update(ctx, 2, parent.ID, &models.RoleUpdateRequest{IsActive: &inactive})
+	require.NoError(t, err)
+	allowed, err = authorizer.Authorize(ctx, 1, "user", "delete")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	_, err = roles.Update(ctx, 2, role.ID, &models.RoleUpdateRequest{IsActive: &inactive})
+	require.NoError(t, err)
+	allowed, err = authorizer.Authorize(ctx, 1, "user", "list")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+}
+
+func TestCasbinAuthorizer(t *testing.T) {
+	ctx := context.Background()
+	log := logger.New("error", "text")
+	adapter := stringadapter.NewAdapter("p, moderator, user, list\ng, user:1, moderator")
+
+	authorizer, err := NewCasbinAuthorizer(adapter, "", time.Hour, log)
+	require.NoError(t, err)
+
+	allowed, err := authorizer.Authorize(ctx, 1, "user", "list")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	allowed, err = authorizer.Authorize(ctx, 2, "user", "list")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	allowed, err = authorizer.Authorize(ctx, 1, "user", "delete")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+
+	// Changes are picked up on reload
+	adapter.Line = "p, moderator, user, list\ng, user:2, moderator"
+	allowed, err = authorizer.Authorize(ctx, 2, "user", "list")
+	require.NoError(t, err)
+	assert.False(t, allowed, "the policy is cached until the reload interval")
+	require.NoError(t, authorizer.Reload(ctx))
+	allowed, err = authorizer.Authorize(ctx, 2, "user", "list")
+	require.NoError(t, err)
+	assert.True(t, allowed)
+
+	_, err = NewCasbinAuthorizer(adapter, "/nonexistent/model.conf", time.Hour, log)
+	assert.Error(t, err)
+}
@@ -0,0 +1,279 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/jobs"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/filetype"
+	"gbt-be-template/pkg/imaging"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/storage"
+)
+
+// JobProcessAvatar is the job type that renders avatar variants
+const JobProcessAvatar = "avatar.process"
+
+// avatarJobPayload is the payload of an avatar processing job
+type avatarJobPayload struct {
+	UserID uint `json:"user_id"`
+	FileID uint `json:"file_id"`
+}
+
+// avatarService implements the AvatarService interface
+type avatarService struct {
+	userRepo repository.UserRepository
+	fileRepo repository.FileRepository
+	storage  storage.Storage
+	queue    jobs.Enqueuer
+	cfg      *config.Config
+	log      *logger.Logger
+}
+
+// NewAvatarService creates a new avatar service
+func NewAvatarService(userRepo repository.UserRepository, fileRepo repository.FileRepository, store storage.Storage, queue jobs.Enqueuer, cfg *config.Config, log *logger.Logger) AvatarService {
+	return &avatarService{
+		userRepo: userRepo,
+		fileRepo: fileRepo,
+		storage:  store,
+		queue:    queue,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+// NewAvatarJobHandler returns the job handler that processes queued avatars
+func NewAvatarJobHandler(avatarService AvatarService) jobs.Handler {
+	return func(ctx context.Context, payload []byte) error {
+		var p avatarJobPayload
+		if err := json.Unmarshal(payload, &p); err != nil {
+			return fmt.Errorf("%w: invalid payload: %v", jobs.ErrPermanent, err)
+		}
+		return avatarService.Process(ctx, p.UserID, p.FileID)
+	}
+}
+
+// Set makes an uploaded file the user's avatar and queues rendering of its variants
+func (s *avatarService) Set(ctx context.Context, userID, fileID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return errors.New("user not found")
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to get file: %w", err)
+	}
+	if file == nil || file.UserID != userID {
+		return ErrFileNotFound
+	}
+	if file.Purpose != models.UploadPurposeAvatar {
+		return ErrAvatarInvalid
+	}
+
+	if err := s.userRepo.SetAvatarFile(ctx, userID, fileID); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to set avatar")
+		return fmt.Errorf("failed to set avatar: %w", err)
+	}
+
+	if err := s.queue.Enqueue(ctx, JobProcessAvatar, avatarJobPayload{UserID: userID, FileID: fileID}); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to queue avatar processing")
+		return fmt.Errorf("failed to queue avatar processing: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id": userID,
+		"file_id": fileID,
+	}).Info("Avatar processing queued")
+	return nil
+}
+
+// Process renders the configured square variants of an avatar as JPEG and publishes them on the user.
+// Re-encoding drops EXIF and other metadata from the original upload. SVG avatars scale without
+// rendering, so every variant points at the sanitized upload.
+func (s *avatarService) Process(ctx context.Context, userID, fileID uint) error {
+	log := s.log.WithFields(map[string]interface{}{
+		"user_id": userID,
+		"file_id": fileID,
+	})
+
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return fmt.Errorf("%w: user not found", jobs.ErrPermanent)
+	}
+	// Nothing to do if the avatar was replaced before this job ran
+	if user.AvatarFileID == nil || *user.AvatarFileID != fileID {
+		log.Debug("Skipping superseded avatar")
+		return nil
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, fileID)
+	if err != nil {
+		return fmt.Errorf("failed to get file: %w", err)
+	}
+	if file == nil {
+		return fmt.Errorf("%w: file not found", jobs.ErrPermanent)
+	}
+
+	var keys []string
+	var urls map[string]string
+	if file.ContentType == filetype.SVG {
+		keys, urls, err = s.publishSVG(ctx, userID, file)
+	} else {
+		keys, urls, err = s.renderVariants(ctx, userID, file)
+	}
+	if err != nil {
+		if errors.Is(err, jobs.ErrPermanent) {
+			log.WithError(err).Warn("Rejected avatar image")
+		}
+		return err
+	}
+
+	updated, err := s.userRepo.SetAvatarURLs(ctx, userID, fileID, urls)
+	if err != nil {
+		return fmt.Errorf("failed to update avatar: %w", err)
+	}
+	if !updated {
+		// Replaced while we were rendering; the newer job publishes its own variants
+		s.deleteObjects(ctx, keys)
+		log.Debug("Discarded variants of superseded avatar")
+		return nil
+	}
+
+	// Remove the variants of the previous avatar
+	var stale []string
+	for _, url := range user.AvatarURLs {
+		if key, ok := s.objectKey(url); ok && !strings.HasPrefix(key, path.Dir(keys[0])+"/") {
+			stale = append(stale, key)
+		}
+	}
+	s.deleteObjects(ctx, stale)
+
+	log.WithField("variants", len(urls)).Info("Avatar processed")
+	return nil
+}
+
+// renderVariants stores a JPEG thumbnail of the avatar for every configured size
+func (s *avatarService) renderVariants(ctx context.Context, userID uint, file *models.File) ([]string, map[string]string, error) {
+	source, err := s.openSource(ctx, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	img, _, err := imaging.Decode(source, s.cfg.Avatar.MaxPixels)
+	source.Close()
+	if err != nil {
+		if errors.Is(err, imaging.ErrUnsupportedFormat) || errors.Is(err, imaging.ErrTooLarge) {
+			return nil, nil, fmt.Errorf("%w: %v", jobs.ErrPermanent, err)
+		}
+		return nil, nil, err
+	}
+
+	urls := make(map[string]string, len(s.cfg.Avatar.Variants))
+	keys := make([]string, 0, len(s.cfg.Avatar.Variants))
+	for _, size := range s.cfg.Avatar.Variants {
+		var buf bytes.Buffer
+		if err := imaging.EncodeJPEG(&buf, imaging.Thumbnail(img, size), s.cfg.Avatar.JPEGQuality); err != nil {
+			return nil, nil, fmt.Errorf("failed to encode avatar variant: %w", err)
+		}
+
+		key := s.variantKey(userID, file.ID, strconv.Itoa(size)+".jpg")
+		if err := s.storage.Put(ctx, key, &buf, int64(buf.Len()), "image/jpeg"); err != nil {
+			return nil, nil, fmt.Errorf("failed to store avatar variant: %w", err)
+		}
+		keys = append(keys, key)
+		urls[strconv.Itoa(size)] = s.publicURL(key)
+	}
+	return keys, urls, nil
+}
+
+// publishSVG copies an SVG avatar, sanitized on upload, next to the variants and uses it for every size
+func (s *avatarService) publishSVG(ctx context.Context, userID uint, file *models.File) ([]string, map[string]string, error) {
+	source, err := s.openSource(ctx, file)
+	if err != nil {
+		return nil, nil, err
+	}
+	key := s.variantKey(userID, file.ID, "avatar.svg")
+	err = s.storage.Put(ctx, key, source, file.Size, filetype.SVG)
+	source.Close()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to store avatar: %w", err)
+	}
+
+	urls := make(map[string]string, len(s.cfg.Avatar.Variants))
+	for _, size := range s.cfg.Avatar.Variants {
+		urls[strconv.Itoa(size)] = s.publicURL(key)
+	}
+	return []string{key}, urls, nil
+}
+
+// openSource opens the uploaded avatar file
+func (s *avatarService) openSource(ctx context.Context, file *models.File) (io.ReadCloser, error) {
+	source, err := s.storage.Get(ctx, file.ObjectKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%w: %v", jobs.ErrPermanent, err)
+		}
+		return nil, fmt.Errorf("failed to open avatar: %w", err)
+	}
+	return source, nil
+}
+
+// variantKey returns the storage key of a rendered avatar file
+func (s *avatarService) variantKey(userID, fileID uint, name string) string {
+	return path.Join("avatars", strconv.FormatUint(uint64(userID), 10), strconv.FormatUint(uint64(fileID), 10), name)
+}
+
+// OpenVariant opens a rendered avatar variant for serving. Only keys under avatars/ are served.
+func (s *avatarService) OpenVariant(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !strings.HasPrefix(key, "avatars/") || strings.Contains(key, "..") {
+		return nil, ErrFileNotFound
+	}
+
+	reader, err := s.storage.Get(ctx, key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, ErrFileNotFound
+		}
+		return nil, fmt.Errorf("failed to open avatar variant: %w", err)
+	}
+	return reader, nil
+}
+
+// publicURL returns the URL an object is served from
+func (s *avatarService) publicURL(key string) string {
+	return strings.TrimRight(s.cfg.Storage.PublicBaseURL, "/") + "/" + key
+}
+
+// objectKey recovers the object key from a URL built by publicURL
+func (s *avatarService) objectKey(url string) (string, bool) {
+	prefix := strings.TrimRight(s.cfg.Storage.PublicBaseURL, "/") + "/"
+	if !strings.HasPrefix(url, prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(url, prefix), true
+}
+
+// deleteObjects removes stored objects, logging failures since leftovers are harmless
+func (s *avatarService) deleteObjects(ctx context.Context, keys []string) {
+	for _, key := range keys {
+		if err := s.storage.Delete(ctx, key); err != nil {
+			s.log.WithError(err).WithField("key", key).Warn("Failed to delete avatar variant")
+		}
+	}
+}
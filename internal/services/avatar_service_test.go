@@ -0,0 +1,172 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"testing"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/jobs"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeQueue records enqueued jobs
+type fakeQueue struct {
+	jobs []string
+}
+
+func (q *fakeQueue) Enqueue(ctx context.Context, jobType string, payload interface{}) error {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	q.jobs = append(q.jobs, jobType+" "+string(data))
+	return nil
+}
+
+func setupAvatarService(t *testing.T) (*avatarService, *MockUserRepository, *fakeFileRepository, storage.Storage, *fakeQueue) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	userRepo := new(MockUserRepository)
+	fileRepo := newFakeFileRepository()
+	queue := &fakeQueue{}
+	cfg := &config.Config{
+		Storage: config.StorageConfig{PublicBaseURL: "http://cdn.test/media"},
+		Avatar:  config.AvatarConfig{Variants: []int{32, 64}, JPEGQuality: 80, MaxPixels: 1_000_000},
+	}
+	service := NewAvatarService(userRepo, fileRepo, store, queue, cfg, logger.New("error", "text")).(*avatarService)
+	return service, userRepo, fileRepo, store, queue
+}
+
+func TestAvatarService_Set(t *testing.T) {
+	service, userRepo, fileRepo, _, queue := setupAvatarService(t)
+	ctx := context.Background()
+
+	require.NoError(t, fileRepo.Create(ctx, &models.File{UserID: 1, Purpose: models.UploadPurposeAvatar, ObjectKey: "a"}))
+	require.NoError(t, fileRepo.Create(ctx, &models.File{UserID: 1, Purpose: models.UploadPurposeImport, ObjectKey: "b"}))
+	require.NoError(t, fileRepo.Create(ctx, &models.File{UserID: 2, Purpose: models.UploadPurposeAvatar, ObjectKey: "c"}))
+
+	userRepo.On("GetByID", ctx, uint(1)).Return(&models.User{ID: 1}, nil)
+	userRepo.On("SetAvatarFile", ctx, uint(1), uint(1)).Return(nil).Once()
+
+	require.NoError(t, service.Set(ctx, 1, 1))
+	assert.Equal(t, []string{`avatar.process {"user_id":1,"file_id":1}`}, queue.jobs)
+
+	// Only the user's own avatar uploads qualify
+	assert.ErrorIs(t, service.Set(ctx, 1, 2), ErrAvatarInvalid)
+	assert.ErrorIs(t, service.Set(ctx, 1, 3), ErrFileNotFound)
+	assert.ErrorIs(t, service.Set(ctx, 1, 99), ErrFileNotFound)
+
+	userRepo.AssertExpectations(t)
+}
+
+func TestAvatarService_Process(t *testing.T) {
+	service, userRepo, fileRepo, store, _ := setupAvatarService(t)
+	ctx := context.Background()
+
+	var source bytes.Buffer
+	require.NoError(t, png.Encode(&source, image.NewRGBA(image.Rect(0, 0, 120, 80))))
+	require.NoError(t, store.Put(ctx, "uploads/avatar/1/x/me.png", bytes.NewReader(source.Bytes()), int64(source.Len()), "image/png"))
+	require.NoError(t, store.Put(ctx, "avatars/1/5/32.jpg", bytes.NewReader([]byte("old")), 3, "image/jpeg"))
+	require.NoError(t, fileRepo.Create(ctx, &models.File{UserID: 1, Purpose: models.UploadPurposeAvatar, ObjectKey: "uploads/avatar/1/x/me.png"}))
+
+	fileID := uint(1)
+	userRepo.On("GetByID", ctx, uint(1)).Return(&models.User{
+		ID:           1,
+		AvatarFileID: &fileID,
+		AvatarURLs:   map[string]string{"32": "http://cdn.test/media/avatars/1/5/32.jpg"},
+	}, nil)
+	expected := map[string]string{
+		"32": "http://cdn.test/media/avatars/1/1/32.jpg",
+		"64": "http://cdn.test/media/avatars/1/1/64.jpg",
+	}
+	userRepo.On("SetAvatarURLs", ctx, uint(1), uint(1), expected).Return(true, nil).Once()
+
+	require.NoError(t, service.Process(ctx, 1, 1))
+
+	reader, err := service.OpenVariant(ctx, "avatars/1/1/64.jpg")
+	require.NoError(t, err)
+	data, err := io.ReadAll(reader)
+	reader.Close()
+	require.NoError(t, err)
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(data))
+	require.NoError(t, err)
+	assert.Equal(t, 64, cfg.Width)
+	assert.Equal(t, 64, cfg.Height)
+
+	// The previous avatar's variants are removed
+	exists, err := store.Exists(ctx, "avatars/1/5/32.jpg")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Only avatar variants are served
+	_, err = service.OpenVariant(ctx, "uploads/avatar/1/x/me.png")
+	assert.ErrorIs(t, err, ErrFileNotFound)
+
+	userRepo.AssertExpectations(t)
+}
+
+func TestAvatarService_ProcessSVG(t *testing.T) {
+	service, userRepo, fileRepo, store, _ := setupAvatarService(t)
+	ctx := context.Background()
+
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg"><circle r="1"></circle></svg>`)
+	require.NoError(t, store.Put(ctx, "uploads/avatar/1/x/me.svg", bytes.NewReader(svg), int64(len(svg)), "image/svg+xml"))
+	require.NoError(t, fileRepo.Create(ctx, &models.File{UserID: 1, Purpose: models.UploadPurposeAvatar, ContentType: "image/svg+xml", Size: int64(len(svg)), ObjectKey: "uploads/avatar/1/x/me.svg"}))
+
+	fileID := uint(1)
+	userRepo.On("GetByID", ctx, uint(1)).Return(&models.User{ID: 1, AvatarFileID: &fileID}, nil)
+	expected := map[string]string{
+		"32": "http://cdn.test/media/avatars/1/1/avatar.svg",
+		"64": "http://cdn.test/media/avatars/1/1/avatar.svg",
+	}
+	userRepo.On("SetAvatarURLs", ctx, uint(1), uint(1), expected).Return(true, nil).Once()
+
+	require.NoError(t, service.Process(ctx, 1, 1))
+
+	reader, err := service.OpenVariant(ctx, "avatars/1/1/avatar.svg")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, svg, data)
+
+	userRepo.AssertExpectations(t)
+}
+
+func TestAvatarService_ProcessSkipsSuperseded(t *testing.T) {
+	service, userRepo, _, _, _ := setupAvatarService(t)
+	ctx := context.Background()
+
+	newer := uint(2)
+	userRepo.On("GetByID", ctx, uint(1)).Return(&models.User{ID: 1, AvatarFileID: &newer}, nil)
+
+	assert.NoError(t, service.Process(ctx, 1, 1))
+	userRepo.AssertNotCalled(t, "SetAvatarURLs", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestAvatarService_ProcessRejectsNonImages(t *testing.T) {
+	service, userRepo, fileRepo, store, _ := setupAvatarService(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "uploads/avatar/1/x/me.png", bytes.NewReader([]byte("not an image")), 12, "image/png"))
+	require.NoError(t, fileRepo.Create(ctx, &models.File{UserID: 1, Purpose: models.UploadPurposeAvatar, ObjectKey: "uploads/avatar/1/x/me.png"}))
+
+	fileID := uint(1)
+	userRepo.On("GetByID", ctx, uint(1)).Return(&models.User{ID: 1, AvatarFileID: &fileID}, nil)
+
+	// Undecodable images fail permanently instead of being retried
+	err := NewAvatarJobHandler(service)(ctx, []byte(`{"user_id":1,"file_id":1}`))
+	assert.ErrorIs(t, err, jobs.ErrPermanent)
+}
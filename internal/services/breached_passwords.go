@@ -0,0 +1,24 @@
+package services
+
+import "context"
+
+// checkBreachedPassword refuses a new password that appears in known data breaches. When the
+// breach check can't be reached the password is accepted, unless failing open is turned off.
+func (s *userService) checkBreachedPassword(ctx context.Context, password string) error {
+	if s.pwned == nil {
+		return nil
+	}
+
+	count, err := s.pwned.Count(ctx, password)
+	if err != nil {
+		s.log.WithError(err).Warn("Failed to check password against known breaches")
+		if s.cfg.Pwned.FailOpen {
+			return nil
+		}
+		return ErrPasswordCheckUnavailable
+	}
+	if count > 0 {
+		return ErrPasswordBreached
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePwnedChecker knows a fixed set of breached passwords, or fails every check
+type fakePwnedChecker struct {
+	breached map[string]int
+	err      error
+}
+
+func (c *fakePwnedChecker) Count(ctx context.Context, password string) (int, error) {
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.breached[password], nil
+}
+
+func TestUserService_BreachedPasswords(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	checker := &fakePwnedChecker{breached: map[string]int{"password123": 250000}}
+	service.pwned = checker
+	ctx := context.Background()
+
+	create := func(password string) error {
+		req := &models.UserCreateRequest{Email: "test@example.com", Username: "testuser", Password: password}
+		mockRepo.On("ExistsByEmail", ctx, req.Email).Return(false, nil).Once()
+		mockRepo.On("ExistsByUsername", ctx, req.Username).Return(false, nil).Once()
+		_, err := service.Create(ctx, req)
+		return err
+	}
+
+	assert.ErrorIs(t, create("password123"), ErrPasswordBreached)
+
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil).Once()
+	require.NoError(t, create("a much less common password"))
+
+	t.Run("unreachable check fails open unless told otherwise", func(t *testing.T) {
+		checker.err = errors.New("timeout")
+
+		service.cfg.Pwned.FailOpen = false
+		assert.ErrorIs(t, create("password123"), ErrPasswordCheckUnavailable)
+
+		service.cfg.Pwned.FailOpen = true
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil).Once()
+		require.NoError(t, create("password123"))
+		mockRepo.AssertExpectations(t)
+	})
+}
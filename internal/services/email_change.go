@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/mailer"
+	"gbt-be-template/pkg/utils"
+)
+
+// sendEmailChangeLink emails a link confirming the pending email of a user to the new address.
+// Only the most recent link works.
+func (s *userService) sendEmailChangeLink(ctx context.Context, user *models.User) error {
+	if err := s.tokenRepo.InvalidateForUser(ctx, user.ID, models.EmailTokenEmailChange); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to invalidate email change tokens")
+		return fmt.Errorf("failed to invalidate email change tokens: %w", err)
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate email change token: %w", err)
+	}
+	if err := s.tokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   models.EmailTokenEmailChange,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.EmailChange.TokenTTL),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to store email change token")
+		return fmt.Errorf("failed to store email change token: %w", err)
+	}
+
+	msg := &mailer.Message{
+		To:      user.PendingEmail,
+		Subject: "Confirm your new email address",
+		Body: fmt.Sprintf("Hi %s,\n\nOpen this link to use this address for your account:\n\n%s/confirm-email?token=%s\n\nThe link expires in %s. Until then, your account keeps using %s. If you didn't ask for this, you can ignore this email.\n",
+			user.FirstName, s.cfg.Mail.LinkBaseURL, token, s.cfg.EmailChange.TokenTTL, user.Email),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to queue email change confirmation")
+		return fmt.Errorf("failed to queue email change confirmation: %w", err)
+	}
+
+	s.log.Security("email_change_requested", user.ID).Info("Email change confirmation sent")
+	return nil
+}
+
+// ConfirmEmailChange switches a user to their pending email with the link emailed to it. The old
+// address is told about the change.
+func (s *userService) ConfirmEmailChange(ctx context.Context, req *models.EmailChangeConfirmRequest) (*models.UserResponse, error) {
+	token, err := s.tokenRepo.GetByHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get email change token")
+		return nil, fmt.Errorf("failed to get email change token: %w", err)
+	}
+	if token == nil || token.Purpose != models.EmailTokenEmailChange || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidEmailToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", token.UserID).Error("Failed to get user for email change")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.PendingEmail == "" {
+		return nil, ErrInvalidEmailToken
+	}
+
+	// Another account may have taken the address since the change was requested
+	exists, err := s.userRepo.ExistsByEmail(ctx, user.PendingEmail)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check email availability: %w", err)
+	}
+	if exists {
+		return nil, ErrEmailTaken
+	}
+
+	marked, err := s.tokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use email change token: %w", err)
+	}
+	if !marked {
+		return nil, ErrInvalidEmailToken
+	}
+
+	oldEmail := user.Email
+	user.Email = user.PendingEmail
+	user.PendingEmail = ""
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to change email")
+		return nil, fmt.Errorf("failed to change email: %w", err)
+	}
+
+	msg := &mailer.Message{
+		To:      oldEmail,
+		Subject: "Your email address was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account now uses %s instead of this address. If this wasn't you, contact support right away.\n",
+			user.FirstName, user.Email),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to queue email changed notice")
+	}
+
+	s.log.Security("email_changed", user.ID).Warn("Email changed after confirmation from the new address")
+	s.recordActivity(ctx, user.ID, models.AuditEmailChanged, nil)
+	return user.ToResponse(), nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserService_EmailChange(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	service.cfg.EmailChange.TokenTTL = time.Hour
+	service.cfg.Mail.LinkBaseURL = "https://app.example.com"
+	queue := service.queue.(*fakeQueue)
+	ctx := context.Background()
+
+	user := &models.User{ID: 1, Email: "jane@example.com", FirstName: "Jane", IsActive: true}
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("Update", ctx, user).Return(nil)
+
+	requestChange := func(email string) string {
+		current := user.Email
+		mockRepo.On("ExistsByEmail", ctx, email).Return(false, nil).Once()
+		response, err := service.Update(ctx, user.ID, false, user.ID, &models.UserUpdateRequest{Email: &email})
+		require.NoError(t, err)
+		assert.Equal(t, current, response.Email, "the email stays until confirmed")
+		assert.Equal(t, email, response.PendingEmail)
+
+		job := queue.jobs[len(queue.jobs)-1]
+		require.True(t, strings.HasPrefix(job, JobSendEmail+" "))
+		var msg struct{ To, Body string }
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(job, JobSendEmail+" ")), &msg))
+		assert.Equal(t, email, msg.To)
+		match := regexp.MustCompile(`https://app\.example\.com/confirm-email\?token=(\S+)`).FindStringSubmatch(msg.Body)
+		require.Len(t, match, 2)
+		return match[1]
+	}
+
+	t.Run("only the latest link works, once", func(t *testing.T) {
+		stale := requestChange("old-pick@example.com")
+		token := requestChange("jane.doe@example.com")
+
+		_, err := service.ConfirmEmailChange(ctx, &models.EmailChangeConfirmRequest{Token: stale})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+
+		mockRepo.On("ExistsByEmail", ctx, "jane.doe@example.com").Return(false, nil).Once()
+		response, err := service.ConfirmEmailChange(ctx, &models.EmailChangeConfirmRequest{Token: token})
+		require.NoError(t, err)
+		assert.Equal(t, "jane.doe@example.com", response.Email)
+		assert.Empty(t, response.PendingEmail)
+		assert.Contains(t, queue.jobs[len(queue.jobs)-1], `"to":"jane@example.com"`)
+		assert.Contains(t, queue.jobs[len(queue.jobs)-1], "Your email address was changed")
+
+		_, err = service.ConfirmEmailChange(ctx, &models.EmailChangeConfirmRequest{Token: token})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+	})
+
+	t.Run("asking for the current email cancels the change", func(t *testing.T) {
+		token := requestChange("jane.new@example.com")
+		current := user.Email
+		response, err := service.Update(ctx, user.ID, false, user.ID, &models.UserUpdateRequest{Email: &current})
+		require.NoError(t, err)
+		assert.Empty(t, response.PendingEmail)
+
+		_, err = service.ConfirmEmailChange(ctx, &models.EmailChangeConfirmRequest{Token: token})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+	})
+
+	t.Run("addresses taken in the meantime are refused", func(t *testing.T) {
+		token := requestChange("taken@example.com")
+		mockRepo.On("ExistsByEmail", ctx, "taken@example.com").Return(true, nil).Once()
+		_, err := service.ConfirmEmailChange(ctx, &models.EmailChangeConfirmRequest{Token: token})
+		assert.ErrorIs(t, err, ErrEmailTaken)
+		assert.Equal(t, "jane.doe@example.com", user.Email)
+	})
+}
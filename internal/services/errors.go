@@ -0,0 +1,217 @@
+package services
+
+import (
+	"errors"
+	"time"
+)
+
+// Sentinel errors that handlers map to specific HTTP status codes
+var (
+	// ErrInvalidRefreshToken is returned for unknown, expired or revoked refresh tokens
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	// ErrRefreshTokenReuse is returned when an already rotated refresh token is presented again
+	ErrRefreshTokenReuse = errors.New("refresh token reuse detected")
+	// ErrSessionNotFound is returned for unknown or ended sessions and sessions of another user
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrSessionLimitReached is returned for logins of a user who is signed in on too many devices
+	ErrSessionLimitReached = errors.New("too many active sessions, sign out on another device first")
+	// ErrDeviceNotFound is returned for unknown devices and devices of another user
+	ErrDeviceNotFound = errors.New("device not found")
+
+	// ErrUserNotFound is returned for unknown users
+	ErrUserNotFound = errors.New("user not found")
+	// ErrUserChanged is returned for updates of a user that was updated since the version they were
+	// based on
+	ErrUserChanged = errors.New("user was changed by another request, reload it and try again")
+	// ErrActiveStatusForbidden is returned when a user who isn't an admin activates or deactivates
+	// an account, other than undoing their own deactivation
+	ErrActiveStatusForbidden = errors.New("only admins can activate or deactivate accounts")
+	// ErrInvalidCredentials is returned by authentication backends for an unknown login or wrong password
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	// ErrPasswordBreached is returned for new passwords that appear in known data breaches
+	ErrPasswordBreached = errors.New("password appears in a known data breach, choose a different one")
+	// ErrPasswordCheckUnavailable is returned when breached passwords can't be checked and unchecked passwords are refused
+	ErrPasswordCheckUnavailable = errors.New("password check unavailable, try again later")
+	// ErrPasswordUnchanged is returned when a new password is the same as the current one
+	ErrPasswordUnchanged = errors.New("new password must differ from the current password")
+	// ErrEmailTaken is returned when a pending email change is confirmed for an address another account uses
+	ErrEmailTaken = errors.New("email is already taken")
+	// ErrInvalidSort is returned for lists sorted by a field that isn't sortable
+	ErrInvalidSort = errors.New("invalid sort")
+	// ErrInvalidCursor is returned for pagination cursors that are malformed or were issued for
+	// another sort
+	ErrInvalidCursor = errors.New("invalid cursor")
+	// ErrInvalidPassword is returned when the password confirming a sensitive action is wrong
+	ErrInvalidPassword = errors.New("invalid password")
+	// ErrInvalidEmailToken is returned for unknown, expired or already used emailed links
+	ErrInvalidEmailToken = errors.New("invalid or expired token")
+	// ErrInvalidLoginCode is returned for unknown, expired or already redeemed single sign-on codes
+	ErrInvalidLoginCode = errors.New("invalid or expired login code")
+	// ErrInvalidTwoFactorCode is returned for a wrong, expired or already used TOTP code
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor code")
+	// ErrInvalidChallenge is returned for unknown, expired or already completed two-factor login challenges
+	ErrInvalidChallenge = errors.New("invalid or expired two-factor challenge")
+	// ErrTwoFactorEnabled is returned when enrolling a user who already has two-factor authentication on
+	ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+	// ErrTwoFactorNotEnabled is returned when confirming or disabling two-factor authentication that isn't set up
+	ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+	// ErrInvalidOTP is returned for a wrong, expired or already used texted login code
+	ErrInvalidOTP = errors.New("invalid or expired code")
+	// ErrInvalidWebAuthnSession is returned for unknown, expired or already finished passkey ceremonies
+	ErrInvalidWebAuthnSession = errors.New("invalid or expired WebAuthn session")
+	// ErrInvalidWebAuthnCredential is returned when a passkey registration or assertion fails verification
+	ErrInvalidWebAuthnCredential = errors.New("passkey verification failed")
+	// ErrCredentialNotFound is returned for unknown passkeys or passkeys of another user
+	ErrCredentialNotFound = errors.New("credential not found")
+	// ErrAPIKeyNotFound is returned for unknown API keys or keys of another user
+	ErrAPIKeyNotFound = errors.New("API key not found")
+	// ErrAPIKeyLimitReached is returned when a user already holds the maximum number of API keys
+	ErrAPIKeyLimitReached = errors.New("API key limit reached")
+	// ErrAPIKeyScopeNotAllowed is returned when a user requests a scope beyond their own permissions
+	ErrAPIKeyScopeNotAllowed = errors.New("scope not allowed for this user")
+	// ErrAPIKeyExpiryInvalid is returned for API keys that would expire in the past
+	ErrAPIKeyExpiryInvalid = errors.New("expiry must be in the future")
+	// ErrInvalidSuspension is returned for suspensions that end in the past or target the acting admin
+	ErrInvalidSuspension = errors.New("invalid suspension")
+	// ErrInvalidDeactivation is returned when an admin tries to deactivate themselves
+	ErrInvalidDeactivation = errors.New("user can't be deactivated")
+	// ErrInvalidMerge is returned when a duplicate account can't be merged into a user
+	ErrInvalidMerge = errors.New("accounts can't be merged")
+	// ErrInvalidIdentityToken is returned for provider tokens that fail verification
+	ErrInvalidIdentityToken = errors.New("invalid identity provider token")
+	// ErrIdentityLinked is returned when linking an external identity that belongs to another account
+	ErrIdentityLinked = errors.New("identity is linked to another account")
+	// ErrInvalidImpersonation is returned when an admin tries to impersonate themselves, another admin
+	// or an account that can't sign in
+	ErrInvalidImpersonation = errors.New("user can't be impersonated")
+	// ErrInvalidHardDelete is returned when an admin tries to permanently delete themselves
+	ErrInvalidHardDelete = errors.New("user can't be permanently deleted")
+	// ErrInvalidRestore is returned when an admin tries to restore an account that was anonymized
+	ErrInvalidRestore = errors.New("anonymized users can't be restored")
+	// ErrInvalidMetadata is returned when user metadata exceeds the size, depth or key limits
+	ErrInvalidMetadata = errors.New("invalid metadata")
+
+	// ErrRoleNotFound is returned for unknown roles
+	ErrRoleNotFound = errors.New("role not found")
+	// ErrRoleNameTaken is returned when a role is created or renamed to the name of another role
+	ErrRoleNameTaken = errors.New("role name already exists")
+	// ErrParentRoleNotFound is returned when a role would inherit from an unknown role
+	ErrParentRoleNotFound = errors.New("parent role not found")
+	// ErrRoleCycle is returned when a role would end up inheriting from itself
+	ErrRoleCycle = errors.New("role can't inherit from itself or a role inheriting from it")
+	// ErrAccessRuleNotFound is returned for unknown access rules
+	ErrAccessRuleNotFound = errors.New("access rule not found")
+	// ErrAccessRuleNameTaken is returned when an access rule is created or renamed to the name of another access rule
+	ErrAccessRuleNameTaken = errors.New("access rule name already exists")
+	// ErrInvalidCondition is returned for access rule conditions that can't be parsed
+	ErrInvalidCondition = errors.New("invalid access rule condition")
+	// ErrRoleNotAssigned is returned when taking away a role the user doesn't have
+	ErrRoleNotAssigned = errors.New("user doesn't have this role")
+	// ErrPermissionNotFound is returned for unknown permissions
+	ErrPermissionNotFound = errors.New("permission not found")
+	// ErrPermissionNameTaken is returned when a permission is created or renamed to the name of another permission
+	ErrPermissionNameTaken = errors.New("permission name already exists")
+
+	// ErrUploadNotFound is returned for unknown upload sessions or sessions owned by another user
+	ErrUploadNotFound = errors.New("upload not found")
+	// ErrUploadInvalid is returned for requests that violate the upload protocol
+	ErrUploadInvalid = errors.New("invalid upload request")
+	// ErrUploadNotPending is returned when a completed, aborted or expired upload is modified
+	ErrUploadNotPending = errors.New("upload is no longer pending")
+	// ErrUploadContentRejected is returned for files whose type, extension or dimensions are not allowed
+	ErrUploadContentRejected = errors.New("file type not allowed")
+	// ErrUploadInfected is returned when the malware scanner flags an upload
+	ErrUploadInfected = errors.New("upload rejected by malware scan")
+	// ErrScanUnavailable is returned when an upload cannot be scanned and unscanned uploads are refused
+	ErrScanUnavailable = errors.New("malware scanner unavailable")
+
+	// ErrFileNotFound is returned for unknown files or files the caller may not access
+	ErrFileNotFound = errors.New("file not found")
+	// ErrStorageQuotaExceeded is returned when a file would take a user over their storage quota
+	ErrStorageQuotaExceeded = errors.New("storage quota exceeded")
+
+	// ErrPresignUnsupported is returned for presigned URL requests when the storage backend can't issue them
+	ErrPresignUnsupported = errors.New("storage backend does not support presigned URLs")
+
+	// ErrAvatarInvalid is returned when a file that wasn't uploaded as an avatar is set as one
+	ErrAvatarInvalid = errors.New("file is not an avatar upload")
+
+	// ErrOrganizationNotFound is returned for unknown organizations and organizations the caller isn't a member of
+	ErrOrganizationNotFound = errors.New("organization not found")
+	// ErrMembershipNotFound is returned for users who aren't members of the organization
+	ErrMembershipNotFound = errors.New("user is not a member of this organization")
+	// ErrAlreadyMember is returned when adding a user who already is a member of the organization
+	ErrAlreadyMember = errors.New("user is already a member of this organization")
+	// ErrOrgRoleInsufficient is returned when the caller's role in an organization doesn't allow the change
+	ErrOrgRoleInsufficient = errors.New("your role in this organization doesn't allow this")
+	// ErrLastOwner is returned when a change would leave an organization without an owner
+	ErrLastOwner = errors.New("organization must keep at least one owner")
+
+	// ErrTagNotFound is returned for unknown tags
+	ErrTagNotFound = errors.New("tag not found")
+	// ErrTagNameTaken is returned when a tag is created or renamed to the name of another tag
+	ErrTagNameTaken = errors.New("tag name already exists")
+	// ErrInvalidTagName is returned for tag names other than lowercase letters, digits, dashes and underscores
+	ErrInvalidTagName = errors.New("tag names may only contain letters, digits, dashes and underscores")
+	// ErrTagNotAssigned is returned when removing a tag the user doesn't have
+	ErrTagNotAssigned = errors.New("user doesn't have this tag")
+)
+
+// AccountLockedError is returned when a login is refused because the account is locked out
+type AccountLockedError struct {
+	Until time.Time
+}
+
+// Error implements the error interface
+func (e *AccountLockedError) Error() string {
+	return "account is temporarily locked due to too many failed login attempts"
+}
+
+// RetryAfter returns the time left until the lockout ends
+func (e *AccountLockedError) RetryAfter() time.Duration {
+	if remaining := time.Until(e.Until); remaining > 0 {
+		return remaining
+	}
+	return 0
+}
+
+// OTPRateLimitedError is returned when too many login codes were requested for a phone number
+type OTPRateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+// Error implements the error interface
+func (e *OTPRateLimitedError) Error() string {
+	return "too many codes requested for this phone number"
+}
+
+// AccountSuspendedError is returned when a login is refused because an admin suspended the account
+type AccountSuspendedError struct {
+	Until *time.Time // Nil when the suspension has no end date
+}
+
+// Error implements the error interface
+func (e *AccountSuspendedError) Error() string {
+	return "account is suspended"
+}
+
+// PasswordExpiredError is returned when a login is refused because the password is older than
+// the maximum password age
+type PasswordExpiredError struct {
+	ExpiredAt time.Time
+}
+
+// Error implements the error interface
+func (e *PasswordExpiredError) Error() string {
+	return "password has expired and must be reset"
+}
+
+// AccountDeactivatedError is returned when a login is refused because the account is deactivated
+type AccountDeactivatedError struct {
+	SelfService bool // The user deactivated the account and can reactivate it by email
+}
+
+// Error implements the error interface
+func (e *AccountDeactivatedError) Error() string {
+	return "account is deactivated"
+}
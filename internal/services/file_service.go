@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/storage"
+)
+
+// fileService implements the FileService interface
+type fileService struct {
+	fileRepo repository.FileRepository
+	storage  storage.Storage
+	cfg      *config.Config
+	log      *logger.Logger
+}
+
+// NewFileService creates a new file service
+func NewFileService(fileRepo repository.FileRepository, store storage.Storage, cfg *config.Config, log *logger.Logger) FileService {
+	return &fileService{
+		fileRepo: fileRepo,
+		storage:  store,
+		cfg:      cfg,
+		log:      log,
+	}
+}
+
+// Create records a stored object as a file, enforcing the owner's storage quota
+func (s *fileService) Create(ctx context.Context, file *models.File) (*models.FileResponse, error) {
+	if err := s.CheckQuota(ctx, file.UserID, file.Size); err != nil {
+		return nil, err
+	}
+
+	if err := s.fileRepo.Create(ctx, file); err != nil {
+		s.log.WithError(err).WithField("user_id", file.UserID).Error("Failed to create file")
+		return nil, fmt.Errorf("failed to create file: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id": file.UserID,
+		"file_id": file.ID,
+		"size":    file.Size,
+	}).Info("File stored")
+
+	return file.ToResponse(), nil
+}
+
+// ListByUser returns a user's files along with their storage usage
+func (s *fileService) ListByUser(ctx context.Context, userID uint) (*models.FileListResponse, error) {
+	files, err := s.fileRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list files")
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+
+	response := &models.FileListResponse{
+		Files: make([]*models.FileResponse, len(files)),
+		Usage: models.StorageUsage{Quota: s.cfg.Upload.UserQuota},
+	}
+	for i, file := range files {
+		response.Files[i] = file.ToResponse()
+		response.Usage.Used += file.Size
+	}
+
+	return response, nil
+}
+
+// Delete removes a file and its stored object. Only the owner or an admin may delete a file.
+func (s *fileService) Delete(ctx context.Context, actorID uint, isAdmin bool, id uint) error {
+	file, err := s.fileRepo.GetByID(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to get file: %w", err)
+	}
+	// Report files of other users as missing so IDs can't be probed
+	if file == nil || (file.UserID != actorID && !isAdmin) {
+		return ErrFileNotFound
+	}
+
+	if err := s.storage.Delete(ctx, file.ObjectKey); err != nil {
+		s.log.WithError(err).WithField("file_id", id).Error("Failed to delete stored object")
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	if err := s.fileRepo.Delete(ctx, id); err != nil {
+		s.log.WithError(err).WithField("file_id", id).Error("Failed to delete file")
+		return fmt.Errorf("failed to delete file: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"file_id":  id,
+		"actor_id": actorID,
+	}).Info("File deleted")
+	return nil
+}
+
+// DownloadURL returns a presigned URL for downloading a file directly from storage.
+// Only the owner or an admin may download a file.
+func (s *fileService) DownloadURL(ctx context.Context, actorID uint, isAdmin bool, id uint) (*models.FileDownloadResponse, error) {
+	presigner, ok := s.storage.(storage.Presigner)
+	if !ok {
+		return nil, ErrPresignUnsupported
+	}
+
+	file, err := s.fileRepo.GetByID(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get file: %w", err)
+	}
+	if file == nil || (file.UserID != actorID && !isAdmin) {
+		return nil, ErrFileNotFound
+	}
+
+	expiresAt := time.Now().Add(s.cfg.Upload.PresignExpiry)
+	url, err := presigner.PresignGet(ctx, file.ObjectKey, s.cfg.Upload.PresignExpiry)
+	if err != nil {
+		s.log.WithError(err).WithField("file_id", id).Error("Failed to presign download")
+		return nil, fmt.Errorf("failed to presign download: %w", err)
+	}
+
+	return &models.FileDownloadResponse{URL: url, ExpiresAt: expiresAt}, nil
+}
+
+// Usage returns a user's storage consumption
+func (s *fileService) Usage(ctx context.Context, userID uint) (*models.StorageUsage, error) {
+	used, err := s.fileRepo.TotalSizeByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get storage usage: %w", err)
+	}
+	return &models.StorageUsage{Used: used, Quota: s.cfg.Upload.UserQuota}, nil
+}
+
+// CheckQuota returns ErrStorageQuotaExceeded if storing additional bytes would exceed the user's quota
+func (s *fileService) CheckQuota(ctx context.Context, userID uint, additional int64) error {
+	if s.cfg.Upload.UserQuota <= 0 {
+		return nil
+	}
+
+	usage, err := s.Usage(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if usage.Used+additional > usage.Quota {
+		return fmt.Errorf("%w: %d of %d bytes used", ErrStorageQuotaExceeded, usage.Used, usage.Quota)
+	}
+	return nil
+}
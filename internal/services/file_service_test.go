@@ -0,0 +1,115 @@
+package services
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeFileRepository is an in-memory FileRepository
+type fakeFileRepository struct {
+	files  map[uint]*models.File
+	nextID uint
+}
+
+func newFakeFileRepository() *fakeFileRepository {
+	return &fakeFileRepository{files: make(map[uint]*models.File)}
+}
+
+func (f *fakeFileRepository) Create(ctx context.Context, file *models.File) error {
+	f.nextID++
+	file.ID = f.nextID
+	f.files[file.ID] = file
+	return nil
+}
+
+func (f *fakeFileRepository) GetByID(ctx context.Context, id uint) (*models.File, error) {
+	return f.files[id], nil
+}
+
+func (f *fakeFileRepository) ListByUser(ctx context.Context, userID uint) ([]*models.File, error) {
+	var files []*models.File
+	for _, file := range f.files {
+		if file.UserID == userID {
+			files = append(files, file)
+		}
+	}
+	return files, nil
+}
+
+func (f *fakeFileRepository) Delete(ctx context.Context, id uint) error {
+	delete(f.files, id)
+	return nil
+}
+
+func (f *fakeFileRepository) TotalSizeByUser(ctx context.Context, userID uint) (int64, error) {
+	var total int64
+	for _, file := range f.files {
+		if file.UserID == userID {
+			total += file.Size
+		}
+	}
+	return total, nil
+}
+
+func setupFileService(t *testing.T, quota int64) (*fileService, *fakeFileRepository, storage.Storage) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	repo := newFakeFileRepository()
+	cfg := &config.Config{Upload: config.UploadConfig{UserQuota: quota}}
+	return NewFileService(repo, store, cfg, logger.New("error", "text")).(*fileService), repo, store
+}
+
+func TestFileService_Quota(t *testing.T) {
+	service, _, _ := setupFileService(t, 10)
+	ctx := context.Background()
+
+	_, err := service.Create(ctx, &models.File{UserID: 1, Filename: "a", Size: 6, ObjectKey: "a"})
+	require.NoError(t, err)
+
+	// The second file would take the user over quota
+	_, err = service.Create(ctx, &models.File{UserID: 1, Filename: "b", Size: 5, ObjectKey: "b"})
+	assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+
+	// Quotas are per user
+	_, err = service.Create(ctx, &models.File{UserID: 2, Filename: "c", Size: 5, ObjectKey: "c"})
+	assert.NoError(t, err)
+
+	files, err := service.ListByUser(ctx, 1)
+	require.NoError(t, err)
+	assert.Len(t, files.Files, 1)
+	assert.Equal(t, models.StorageUsage{Used: 6, Quota: 10}, files.Usage)
+}
+
+func TestFileService_Delete(t *testing.T) {
+	service, repo, store := setupFileService(t, 0)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "users/1/a", strings.NewReader("abc"), 3, "text/plain"))
+	file, err := service.Create(ctx, &models.File{UserID: 1, Filename: "a", Size: 3, ObjectKey: "users/1/a"})
+	require.NoError(t, err)
+
+	t.Run("other users can't delete", func(t *testing.T) {
+		assert.ErrorIs(t, service.Delete(ctx, 2, false, file.ID), ErrFileNotFound)
+	})
+
+	t.Run("owner deletes file and object", func(t *testing.T) {
+		require.NoError(t, service.Delete(ctx, 1, false, file.ID))
+		assert.Empty(t, repo.files)
+		exists, _ := store.Exists(ctx, "users/1/a")
+		assert.False(t, exists)
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		assert.ErrorIs(t, service.Delete(ctx, 1, true, file.ID), ErrFileNotFound)
+	})
+}
@@ -0,0 +1,166 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// externalIdentity is an identity asserted by an external provider (IdP token, SAML assertion, directory)
+type externalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Username      string
+	FirstName     string
+	LastName      string
+}
+
+// identityResolver maps external identities onto local users
+type identityResolver struct {
+	userRepo      repository.UserRepository
+	identityRepo  repository.IdentityRepository
+	autoProvision bool
+	log           *logger.Logger
+}
+
+var usernameInvalidChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// Resolve returns the local user for an external identity.
+// Lookup order: existing identity link, then verified email match, then JIT provisioning if enabled.
+func (r *identityResolver) Resolve(ctx context.Context, ext *externalIdentity) (*models.User, error) {
+	identity, err := r.identityRepo.GetByProviderSubject(ctx, ext.Provider, ext.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	if identity != nil {
+		user, err := r.userRepo.GetByID(ctx, identity.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user: %w", err)
+		}
+		if user == nil {
+			return nil, errors.New("linked user not found")
+		}
+		return user, nil
+	}
+
+	// Only link by email when the provider vouches for it, otherwise anyone could claim an address
+	var user *models.User
+	if ext.Email != "" && ext.EmailVerified {
+		user, err = r.userRepo.GetByEmail(ctx, ext.Email)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user by email: %w", err)
+		}
+	}
+
+	if user == nil {
+		if !r.autoProvision || ext.Email == "" {
+			return nil, errors.New("no local account for external identity")
+		}
+		if user, err = r.provision(ctx, ext); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := r.identityRepo.Create(ctx, &models.UserIdentity{
+		UserID:   user.ID,
+		Provider: ext.Provider,
+		Subject:  ext.Subject,
+		Email:    ext.Email,
+	}); err != nil {
+		return nil, fmt.Errorf("failed to link identity: %w", err)
+	}
+
+	r.log.WithFields(map[string]interface{}{
+		"user_id":  user.ID,
+		"provider": ext.Provider,
+	}).Info("External identity linked to user")
+
+	return user, nil
+}
+
+// provision creates a local user for an external identity
+func (r *identityResolver) provision(ctx context.Context, ext *externalIdentity) (*models.User, error) {
+	exists, err := r.userRepo.ExistsByEmail(ctx, ext.Email)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check user existence: %w", err)
+	}
+	if exists {
+		return nil, errors.New("an account with this email already exists")
+	}
+
+	username, err := r.availableUsername(ctx, ext)
+	if err != nil {
+		return nil, err
+	}
+
+	// Provisioned users sign in through their provider; the random password is never disclosed
+	randomPassword, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate password: %w", err)
+	}
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(randomPassword), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	user := &models.User{
+		Email:     ext.Email,
+		Username:  username,
+		Password:  string(hashedPassword),
+		FirstName: ext.FirstName,
+		LastName:  ext.LastName,
+		IsActive:  true,
+	}
+	if err := r.userRepo.Create(ctx, user); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	r.log.WithFields(map[string]interface{}{
+		"user_id":  user.ID,
+		"provider": ext.Provider,
+	}).Info("User provisioned from external identity")
+
+	return user, nil
+}
+
+// availableUsername derives a unique username from the external identity
+func (r *identityResolver) availableUsername(ctx context.Context, ext *externalIdentity) (string, error) {
+	base := ext.Username
+	if base == "" {
+		base = strings.SplitN(ext.Email, "@", 2)[0]
+	}
+	base = utils.TruncateString(usernameInvalidChars.ReplaceAllString(base, "_"), 40)
+	for len(base) < 3 {
+		base += "_"
+	}
+
+	candidate := base
+	for attempt := 0; attempt < 5; attempt++ {
+		exists, err := r.userRepo.ExistsByUsername(ctx, candidate)
+		if err != nil {
+			return "", fmt.Errorf("failed to check username availability: %w", err)
+		}
+		if !exists {
+			return candidate, nil
+		}
+
+		suffix, err := utils.GenerateRandomToken(3)
+		if err != nil {
+			return "", err
+		}
+		candidate = base + "_" + usernameInvalidChars.ReplaceAllString(suffix, "")
+	}
+
+	return "", errors.New("could not find an available username")
+}
@@ -2,32 +2,280 @@ package services
 
 import (
 	"context"
+	"io"
+	"time"
 
 	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/crewjam/saml"
 )
 
 // UserService defines the interface for user business logic
 type UserService interface {
+	UseAuthorizer(authorizer Authorizer)
+	UseActivityAudit(activityRepo repository.AuditEventRepository)
+	UseTransactions(tx repository.Transactor)
 	Create(ctx context.Context, req *models.UserCreateRequest) (*models.UserResponse, error)
 	GetByID(ctx context.Context, id uint) (*models.UserResponse, error)
 	GetByEmail(ctx context.Context, email string) (*models.UserResponse, error)
-	Update(ctx context.Context, id uint, req *models.UserUpdateRequest) (*models.UserResponse, error)
-	AdminUpdate(ctx context.Context, id uint, req *models.AdminUserUpdateRequest) (*models.UserResponse, error)
+	Update(ctx context.Context, actorID uint, isAdmin bool, id uint, req *models.UserUpdateRequest) (*models.UserResponse, error)
+	AdminUpdate(ctx context.Context, actorID, id uint, req *models.AdminUserUpdateRequest) (*models.UserResponse, error)
+	AdminGet(ctx context.Context, id uint) (*models.AdminUserResponse, error)
+	Suspend(ctx context.Context, actorID, id uint, req *models.UserSuspendRequest) (*models.AdminUserResponse, error)
+	Unsuspend(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error)
+	Reactivate(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error)
+	Restore(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error)
+	HardDelete(ctx context.Context, actorID, id uint) error
+	Deactivate(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error)
+	MergeAccounts(ctx context.Context, actorID, id, duplicateID uint) (*models.AdminUserResponse, error)
+	LogoutAll(ctx context.Context, actorID, id uint) error
+	Impersonate(ctx context.Context, actorID, id uint) (*models.ImpersonationResponse, error)
+	RequirePasswordChange(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error)
+	MustChangePassword(ctx context.Context, userID uint) (bool, error)
+	ChangePassword(ctx context.Context, userID uint, tokenID string, expiresAt time.Time, req *models.PasswordChangeRequest) (*models.LoginResponse, error)
+	RequestReactivation(ctx context.Context, req *models.ReactivationRequest) error
+	ConfirmReactivation(ctx context.Context, req *models.ReactivationConfirmRequest) error
+	RequestPasswordReset(ctx context.Context, req *models.PasswordForgotRequest) error
+	ResetPassword(ctx context.Context, req *models.PasswordResetRequest) error
+	ConfirmEmailChange(ctx context.Context, req *models.EmailChangeConfirmRequest) (*models.UserResponse, error)
+	LiftExpiredSuspensions(ctx context.Context) (int, error)
 	Delete(ctx context.Context, id uint) error
-	List(ctx context.Context, page, limit int) ([]*models.UserResponse, int64, error)
-	Login(ctx context.Context, req *models.UserLoginRequest) (string, *models.UserResponse, error)
-	Logout(ctx context.Context, userID uint) error
+	List(ctx context.Context, filter models.UserFilter, sort []models.SortField, page, limit int) ([]*models.UserResponse, int64, error)
+	ListByCursor(ctx context.Context, filter models.UserFilter, sort []models.SortField, cursor string, limit int) ([]*models.UserResponse, string, string, error)
+	Export(ctx context.Context, filter models.UserFilter, fn func(*models.UserResponse) error) error
+	Login(ctx context.Context, req *models.UserLoginRequest) (*models.LoginResponse, error)
+	CompleteLogin(ctx context.Context, user *models.User, method, deviceToken string) (*models.LoginResponse, error)
+	Logout(ctx context.Context, userID uint, tokenID string, expiresAt time.Time) error
+	VerifyTwoFactor(ctx context.Context, req *models.TwoFactorVerifyRequest) (*models.LoginResponse, error)
+	VerifyLogin(ctx context.Context, req *models.LoginVerifyRequest) (*models.LoginResponse, error)
+	EnrollTwoFactor(ctx context.Context, userID uint) (*models.TwoFactorEnrollResponse, error)
+	EnableTwoFactor(ctx context.Context, userID uint, req *models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error)
+	DisableTwoFactor(ctx context.Context, userID uint, req *models.TwoFactorDisableRequest) error
+	RecoveryCodesStatus(ctx context.Context, userID uint) (*models.RecoveryCodesStatus, error)
+	RegenerateRecoveryCodes(ctx context.Context, userID uint, req *models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error)
+	ListDevices(ctx context.Context, userID uint) ([]*models.Device, error)
+	DeleteDevice(ctx context.Context, userID, id uint) error
+	LoginHistory(ctx context.Context, userID uint, page, limit int) ([]*models.LoginEvent, int64, error)
+	ActivityAudit(ctx context.Context, userID uint, page, limit int) ([]*models.AuditEvent, int64, error)
+	PurgeLoginHistory(ctx context.Context) (int, error)
+	RequestLoginOTP(ctx context.Context, req *models.OTPRequest) error
+	VerifyLoginOTP(ctx context.Context, req *models.OTPVerifyRequest) (*models.LoginResponse, error)
+	RequestDeletion(ctx context.Context, userID uint, req *models.AccountDeleteRequest) (*models.AccountDeletionResponse, error)
+	PurgeDeletedAccounts(ctx context.Context) (int, error)
+	PurgeDeletedUsers(ctx context.Context) (int, error)
+	AnonymizeDeletedUsers(ctx context.Context) (int, error)
+	ReencryptUsers(ctx context.Context) (int, error)
 }
 
 // AuthService defines the interface for authentication operations
 type AuthService interface {
+	UseClaimsEnricher(enricher ClaimsEnricher)
+	UseRoles(roleRepo repository.RoleRepository)
 	GenerateToken(userID uint, email string, isAdmin bool) (string, error)
+	GenerateImpersonationToken(user *models.User, impersonatorID uint) (string, error)
 	ValidateToken(token string) (*models.User, error)
 	RefreshToken(token string) (string, error)
+	IssueRefreshToken(ctx context.Context, userID uint, rememberMe bool) (string, error)
+	RotateRefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error)
+	RevokeRefreshTokens(ctx context.Context, userID uint) error
+	ListSessions(ctx context.Context, userID uint) ([]*models.Session, error)
+	RevokeSession(ctx context.Context, userID, id uint) error
+	RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error
+	RevokeUserAccessTokens(ctx context.Context, userID uint) error
+	IsAccessTokenRevoked(ctx context.Context, claims *utils.JWTClaims) (bool, error)
+}
+
+// OAuthService defines the interface for the OAuth2/OIDC authorization server
+type OAuthService interface {
+	RegisterClient(ctx context.Context, ownerID uint, req *models.OAuthClientCreateRequest) (*models.OAuthClientResponse, error)
+	ListClients(ctx context.Context) ([]*models.OAuthClientResponse, error)
+	DeleteClient(ctx context.Context, clientID string) error
+	PrepareConsent(ctx context.Context, userID uint, req *models.OAuthAuthorizeRequest) (*models.OAuthConsentResponse, error)
+	Authorize(ctx context.Context, userID uint, decision *models.OAuthConsentDecision) (string, error)
+	Exchange(ctx context.Context, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error)
+	UserInfo(ctx context.Context, userID uint, scope string) (map[string]interface{}, error)
+	Discovery() map[string]interface{}
+}
+
+// TokenExchangeService defines the interface for RFC 8693 token exchange
+type TokenExchangeService interface {
+	Exchange(ctx context.Context, req *models.TokenExchangeRequest) (*models.TokenExchangeResponse, error)
+}
+
+// AccountMerger merges duplicate accounts, such as one signed up with an email and password and
+// one provisioned from an external provider for the same person
+type AccountMerger interface {
+	MergeAccounts(ctx context.Context, actorID, id, duplicateID uint) (*models.AdminUserResponse, error)
+}
+
+// LoginCompleter finishes a login once another service proved who the user is, so that every
+// login method checks the account and records the login the same way
+type LoginCompleter interface {
+	CompleteLogin(ctx context.Context, user *models.User, method, deviceToken string) (*models.LoginResponse, error)
+}
+
+// OIDCService defines the interface for accepting access tokens of an external OpenID Provider
+type OIDCService interface {
+	UseAccountMerger(merger AccountMerger)
+	Handles(rawToken string) bool
+	Authenticate(ctx context.Context, rawToken string) (*models.User, bool, error)
+	Link(ctx context.Context, userID uint, req *models.IdentityLinkRequest) (*models.UserIdentity, error)
+}
+
+// SAMLService defines the interface for single sign-on through a SAML identity provider
+type SAMLService interface {
+	ServiceProvider() *saml.ServiceProvider
+	Login(ctx context.Context, assertion *saml.Assertion) (string, error)
+	Redeem(ctx context.Context, code, deviceToken string) (*models.LoginResponse, error)
+}
+
+// WebAuthnService defines the interface for passkey registration and passwordless login
+type WebAuthnService interface {
+	BeginRegistration(ctx context.Context, userID uint) (*models.WebAuthnBeginResponse, error)
+	FinishRegistration(ctx context.Context, userID uint, req *models.WebAuthnRegisterRequest) (*models.WebAuthnCredentialResponse, error)
+	BeginLogin(ctx context.Context) (*models.WebAuthnBeginResponse, error)
+	FinishLogin(ctx context.Context, req *models.WebAuthnLoginRequest) (*models.LoginResponse, error)
+	ListCredentials(ctx context.Context, userID uint) ([]*models.WebAuthnCredentialResponse, error)
+	DeleteCredential(ctx context.Context, userID, id uint) error
+}
+
+// APIKeyService defines the interface for API keys used by integrations
+type APIKeyService interface {
+	Create(ctx context.Context, userID uint, req *models.APIKeyCreateRequest) (*models.APIKeyResponse, error)
+	List(ctx context.Context, userID uint) ([]*models.APIKeyResponse, error)
+	Delete(ctx context.Context, userID, id uint) error
+	Handles(rawToken string) bool
+	Authenticate(ctx context.Context, rawKey string) (*models.User, []string, error)
+}
+
+// Authorizer decides whether users may perform actions on resources, based on their roles
+type Authorizer interface {
+	Authorize(ctx context.Context, userID uint, resource, action string) (bool, error)
+	// Reload picks up changed roles and permissions, for authorizers that cache them
+	Reload(ctx context.Context) error
+}
+
+// RoleService defines the interface for role management
+type RoleService interface {
+	UseAuthorizer(authorizer Authorizer)
+	Create(ctx context.Context, actorID uint, req *models.RoleCreateRequest) (*models.RoleResponse, error)
+	GetByID(ctx context.Context, id uint) (*models.RoleResponse, error)
+	List(ctx context.Context) ([]*models.RoleResponse, error)
+	Update(ctx context.Context, actorID, id uint, req *models.RoleUpdateRequest) (*models.RoleResponse, error)
+	Delete(ctx context.Context, actorID, id uint) error
+	SetPermissions(ctx context.Context, actorID uint, req *models.AssignPermissionRequest) (*models.RoleResponse, error)
+	ListForUser(ctx context.Context, userID uint) ([]*models.RoleResponse, error)
+	AssignToUser(ctx context.Context, actorID uint, req *models.AssignRoleRequest) ([]*models.RoleResponse, error)
+	RemoveFromUser(ctx context.Context, actorID, userID, roleID uint) error
+	IsAdmin(ctx context.Context, userID uint) (bool, error)
+}
+
+// PermissionService defines the interface for permission management
+type PermissionService interface {
+	UseAuthorizer(authorizer Authorizer)
+	Create(ctx context.Context, actorID uint, req *models.PermissionCreateRequest) (*models.PermissionResponse, error)
+	GetByID(ctx context.Context, id uint) (*models.PermissionResponse, error)
+	List(ctx context.Context) ([]*models.PermissionResponse, error)
+	Update(ctx context.Context, actorID, id uint, req *models.PermissionUpdateRequest) (*models.PermissionResponse, error)
+	Delete(ctx context.Context, actorID, id uint) error
+	Catalog() []models.PermissionCatalogEntry
+}
+
+// RBACAuditService defines the interface for reading the audit log of role and permission changes
+type RBACAuditService interface {
+	List(ctx context.Context, filter models.RBACAuditFilter, page, limit int) ([]*models.RBACAuditEvent, int64, error)
+}
+
+// AccessRuleService defines the interface for managing attribute-based access rules
+type AccessRuleService interface {
+	Create(ctx context.Context, req *models.AccessRuleCreateRequest) (*models.AccessRuleResponse, error)
+	GetByID(ctx context.Context, id uint) (*models.AccessRuleResponse, error)
+	List(ctx context.Context) ([]*models.AccessRuleResponse, error)
+	Update(ctx context.Context, id uint, req *models.AccessRuleUpdateRequest) (*models.AccessRuleResponse, error)
+	Delete(ctx context.Context, id uint) error
+}
+
+// UploadService defines the interface for resumable chunked uploads
+type UploadService interface {
+	Initiate(ctx context.Context, userID uint, req *models.UploadInitiateRequest) (*models.UploadSessionResponse, error)
+	UploadPart(ctx context.Context, userID uint, id string, partNumber int, body io.Reader) (*models.UploadSessionResponse, error)
+	Get(ctx context.Context, userID uint, id string) (*models.UploadSessionResponse, error)
+	Complete(ctx context.Context, userID uint, id string, req *models.UploadCompleteRequest) (*models.UploadSessionResponse, error)
+	Abort(ctx context.Context, userID uint, id string) error
+	Presign(ctx context.Context, userID uint, req *models.UploadInitiateRequest) (*models.UploadPresignResponse, error)
+	Confirm(ctx context.Context, userID uint, id string, req *models.UploadCompleteRequest) (*models.UploadSessionResponse, error)
+	Store(ctx context.Context, userID uint, req *models.UploadInitiateRequest, body io.Reader) (*models.UploadSessionResponse, error)
+	CleanupExpired(ctx context.Context) (int, error)
+}
+
+// FileService defines the interface for stored file management
+type FileService interface {
+	Create(ctx context.Context, file *models.File) (*models.FileResponse, error)
+	ListByUser(ctx context.Context, userID uint) (*models.FileListResponse, error)
+	Delete(ctx context.Context, actorID uint, isAdmin bool, id uint) error
+	DownloadURL(ctx context.Context, actorID uint, isAdmin bool, id uint) (*models.FileDownloadResponse, error)
+	Usage(ctx context.Context, userID uint) (*models.StorageUsage, error)
+	CheckQuota(ctx context.Context, userID uint, additional int64) error
+}
+
+// AvatarService defines the interface for user avatar management
+type AvatarService interface {
+	Set(ctx context.Context, userID, fileID uint) error
+	Process(ctx context.Context, userID, fileID uint) error
+	OpenVariant(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// SettingsService defines the interface for user preferences
+type SettingsService interface {
+	Get(ctx context.Context, userID uint) (*models.UserSettings, error)
+	Update(ctx context.Context, userID uint, req *models.UserSettingsUpdateRequest) (*models.UserSettings, error)
+}
+
+// OrganizationService defines the interface for organizations and their members
+type OrganizationService interface {
+	Create(ctx context.Context, actorID uint, req *models.OrganizationCreateRequest) (*models.OrganizationResponse, error)
+	List(ctx context.Context, userID uint) ([]*models.OrganizationResponse, error)
+	GetByID(ctx context.Context, actorID uint, isAdmin bool, id uint) (*models.OrganizationResponse, error)
+	Update(ctx context.Context, actorID uint, isAdmin bool, id uint, req *models.OrganizationUpdateRequest) (*models.OrganizationResponse, error)
+	Delete(ctx context.Context, actorID uint, isAdmin bool, id uint) error
+	ListMembers(ctx context.Context, actorID uint, isAdmin bool, id uint) ([]*models.MembershipResponse, error)
+	AddMember(ctx context.Context, actorID uint, isAdmin bool, id uint, req *models.MembershipCreateRequest) (*models.MembershipResponse, error)
+	UpdateMember(ctx context.Context, actorID uint, isAdmin bool, id, userID uint, req *models.MembershipUpdateRequest) (*models.MembershipResponse, error)
+	RemoveMember(ctx context.Context, actorID uint, isAdmin bool, id, userID uint) error
+}
+
+// TagService defines the interface for tagging users
+type TagService interface {
+	List(ctx context.Context) ([]*models.Tag, error)
+	Create(ctx context.Context, actorID uint, req *models.TagCreateRequest) (*models.Tag, error)
+	Update(ctx context.Context, actorID, id uint, req *models.TagUpdateRequest) (*models.Tag, error)
+	Delete(ctx context.Context, actorID, id uint) error
+	ListForUser(ctx context.Context, userID uint) ([]*models.Tag, error)
+	AssignToUser(ctx context.Context, actorID, userID uint, req *models.UserTagsRequest) ([]*models.Tag, error)
+	RemoveFromUser(ctx context.Context, actorID, userID, tagID uint) error
 }
 
 // Services holds all service interfaces
 type Services struct {
-	User UserService
-	Auth AuthService
+	User          UserService
+	Auth          AuthService
+	OAuth         OAuthService
+	TokenExchange TokenExchangeService
+	OIDC          OIDCService
+	SAML          SAMLService
+	WebAuthn      WebAuthnService
+	APIKey        APIKeyService
+	Role          RoleService
+	Permission    PermissionService
+	AccessRule    AccessRuleService
+	RBACAudit     RBACAuditService
+	Authorizer    Authorizer
+	Upload        UploadService
+	File          FileService
+	Avatar        AvatarService
+	Settings      SettingsService
+	Organization  OrganizationService
+	Tag           TagService
 }
@@ -0,0 +1,193 @@
+package services
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/hex"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// ldapAuthBackend checks passwords by binding to an LDAP directory as the user.
+// Directory users signing in for the first time are linked to, or provisioned as, local users.
+type ldapAuthBackend struct {
+	resolver  *identityResolver
+	userRepo  repository.UserRepository
+	roleRepo  repository.RoleRepository
+	auditRepo repository.RBACAuditRepository
+	cfg       config.LDAPConfig
+	log       *logger.Logger
+}
+
+// NewLDAPAuthBackend creates the backend checking passwords against the configured directory
+func NewLDAPAuthBackend(userRepo repository.UserRepository, identityRepo repository.IdentityRepository, roleRepo repository.RoleRepository, auditRepo repository.RBACAuditRepository, cfg *config.Config, log *logger.Logger) AuthBackend {
+	return &ldapAuthBackend{
+		resolver: &identityResolver{
+			userRepo:      userRepo,
+			identityRepo:  identityRepo,
+			autoProvision: cfg.LDAP.AutoProvision,
+			log:           log,
+		},
+		userRepo:  userRepo,
+		roleRepo:  roleRepo,
+		auditRepo: auditRepo,
+		cfg:       cfg.LDAP,
+		log:       log,
+	}
+}
+
+// Authenticate finds the directory entry for the login, binds as it and returns the linked local user
+func (b *ldapAuthBackend) Authenticate(ctx context.Context, login, password string, user *models.User) (*models.User, error) {
+	entry, err := b.bind(strings.ReplaceAll(b.cfg.UserFilter, "{login}", ldap.EscapeFilter(login)), password)
+	if err != nil {
+		return nil, err
+	}
+
+	// The directory vouches for the email address, so it may link an existing local account
+	user, err = b.resolver.Resolve(ctx, &externalIdentity{
+		Provider:      b.cfg.URL,
+		Subject:       ldapSubject(entry, b.cfg.IDAttribute),
+		Email:         entry.GetAttributeValue(b.cfg.EmailAttribute),
+		EmailVerified: true,
+		Username:      entry.GetAttributeValue(b.cfg.UsernameAttribute),
+		FirstName:     entry.GetAttributeValue(b.cfg.FirstNameAttribute),
+		LastName:      entry.GetAttributeValue(b.cfg.LastNameAttribute),
+	})
+	if err != nil {
+		b.log.WithError(err).WithField("dn", entry.DN).Warn("Failed to map directory user")
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := b.syncAttributes(ctx, user, entry); err != nil {
+		return nil, err
+	}
+	return user, nil
+}
+
+// VerifyPassword binds as the directory entry with the user's email address
+func (b *ldapAuthBackend) VerifyPassword(ctx context.Context, user *models.User, password string) error {
+	filter := fmt.Sprintf("(%s=%s)", b.cfg.EmailAttribute, ldap.EscapeFilter(user.Email))
+	_, err := b.bind(filter, password)
+	return err
+}
+
+// bind searches for exactly one entry matching the filter and binds as it with the password.
+// It returns ErrInvalidCredentials when there is no such entry or the password is wrong.
+func (b *ldapAuthBackend) bind(filter, password string) (*ldap.Entry, error) {
+	// An empty password would make an unauthenticated bind, which servers accept for any DN
+	if password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := ldap.DialURL(b.cfg.URL, ldap.DialWithDialer(&net.Dialer{Timeout: b.cfg.Timeout}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to LDAP server: %w", err)
+	}
+	defer conn.Close()
+	conn.SetTimeout(b.cfg.Timeout)
+
+	if b.cfg.StartTLS {
+		server, err := url.Parse(b.cfg.URL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid LDAP URL: %w", err)
+		}
+		if err := conn.StartTLS(&tls.Config{ServerName: server.Hostname()}); err != nil {
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+
+	if b.cfg.BindDN != "" {
+		if err := conn.Bind(b.cfg.BindDN, b.cfg.BindPassword); err != nil {
+			return nil, fmt.Errorf("failed to bind service account: %w", err)
+		}
+	}
+
+	attributes := []string{b.cfg.IDAttribute, b.cfg.EmailAttribute, b.cfg.UsernameAttribute, b.cfg.FirstNameAttribute, b.cfg.LastNameAttribute, b.cfg.GroupsAttribute}
+	result, err := conn.Search(ldap.NewSearchRequest(
+		b.cfg.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, int(b.cfg.Timeout.Seconds()), false,
+		filter, attributes, nil,
+	))
+	if err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to search directory: %w", err)
+	}
+	// An ambiguous login must not sign in as whichever entry comes first
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+
+	entry := result.Entries[0]
+	if err := conn.Bind(entry.DN, password); err != nil {
+		if ldap.IsErrorWithCode(err, ldap.LDAPResultInvalidCredentials) {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, fmt.Errorf("failed to bind as user: %w", err)
+	}
+	return entry, nil
+}
+
+// syncAttributes updates the user's name and, when an admin group is configured, admin
+// status from the directory, so changes made there carry over
+func (b *ldapAuthBackend) syncAttributes(ctx context.Context, user *models.User, entry *ldap.Entry) error {
+	changed, adminChanged := false, false
+	if firstName := entry.GetAttributeValue(b.cfg.FirstNameAttribute); firstName != "" && firstName != user.FirstName {
+		user.FirstName = firstName
+		changed = true
+	}
+	if lastName := entry.GetAttributeValue(b.cfg.LastNameAttribute); lastName != "" && lastName != user.LastName {
+		user.LastName = lastName
+		changed = true
+	}
+	if group := b.cfg.AdminGroup; group != "" {
+		isAdmin := false
+		for _, member := range entry.GetAttributeValues(b.cfg.GroupsAttribute) {
+			// Group DNs compare case-insensitively
+			if strings.EqualFold(member, group) {
+				isAdmin = true
+				break
+			}
+		}
+		if isAdmin != user.IsAdmin {
+			b.log.Security("ldap_admin_changed", user.ID).WithField("is_admin", isAdmin).Info("Admin status changed by directory group membership")
+			user.IsAdmin = isAdmin
+			changed, adminChanged = true, true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := updateUser(ctx, b.userRepo, user); err != nil {
+		b.log.WithError(err).WithField("user_id", user.ID).Error("Failed to update user from directory")
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if adminChanged {
+		return setAdminRole(ctx, b.roleRepo, b.auditRepo, b.log, 0, user.ID, user.IsAdmin)
+	}
+	return nil
+}
+
+// ldapSubject returns the stable identifier of an entry. Binary identifiers such as
+// Active Directory's objectGUID are hex encoded; entries without one fall back to the DN.
+func ldapSubject(entry *ldap.Entry, attribute string) string {
+	raw := entry.GetRawAttributeValue(attribute)
+	switch {
+	case len(raw) == 0:
+		return entry.DN
+	case utf8.Valid(raw):
+		return string(raw)
+	}
+	return hex.EncodeToString(raw)
+}
@@ -0,0 +1,65 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/middleware"
+)
+
+// recordLoginEvent adds a login attempt to a user's login history. An empty failureReason records
+// a successful login. Failing to record the event doesn't fail the login.
+func recordLoginEvent(ctx context.Context, repo repository.LoginEventRepository, log *logger.Logger, userID uint, method, failureReason string) {
+	ip, userAgent := middleware.GetClientFromContext(ctx)
+	event := &models.LoginEvent{
+		UserID:        userID,
+		Method:        method,
+		Success:       failureReason == "",
+		FailureReason: failureReason,
+		IPAddress:     ip,
+		UserAgent:     truncate(userAgent, 255),
+	}
+	if err := repo.Create(ctx, event); err != nil {
+		log.WithError(err).WithField("user_id", userID).Warn("Failed to record login event")
+	}
+}
+
+// LoginHistory returns a page of a user's login history, newest first, and the total number of events
+func (s *userService) LoginHistory(ctx context.Context, userID uint, page, limit int) ([]*models.LoginEvent, int64, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for login history")
+		return nil, 0, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, 0, ErrUserNotFound
+	}
+
+	events, total, err := s.eventRepo.ListByUser(ctx, userID, limit, (page-1)*limit)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list login events")
+		return nil, 0, fmt.Errorf("failed to list login history: %w", err)
+	}
+	return events, total, nil
+}
+
+// PurgeLoginHistory removes login events older than the configured retention
+func (s *userService) PurgeLoginHistory(ctx context.Context) (int, error) {
+	if s.cfg.LoginHistory.Retention <= 0 {
+		return 0, nil
+	}
+
+	deleted, err := s.eventRepo.DeleteBefore(ctx, time.Now().Add(-s.cfg.LoginHistory.Retention))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to purge login history")
+		return 0, fmt.Errorf("failed to purge login history: %w", err)
+	}
+	if deleted > 0 {
+		s.log.WithField("count", deleted).Info("Purged old login events")
+	}
+	return int(deleted), nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeLoginEventRepository keeps login events in memory
+type fakeLoginEventRepository struct {
+	events []*models.LoginEvent
+}
+
+func (r *fakeLoginEventRepository) Create(ctx context.Context, event *models.LoginEvent) error {
+	event.ID = uint(len(r.events) + 1)
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	copied := *event
+	r.events = append(r.events, &copied)
+	return nil
+}
+
+func (r *fakeLoginEventRepository) ListByUser(ctx context.Context, userID uint, limit, offset int) ([]*models.LoginEvent, int64, error) {
+	var events []*models.LoginEvent
+	for _, event := range r.events {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].ID > events[j].ID })
+	total := int64(len(events))
+	if offset >= len(events) {
+		return nil, total, nil
+	}
+	return events[offset:min(offset+limit, len(events))], total, nil
+}
+
+func (r *fakeLoginEventRepository) HasSucceeded(ctx context.Context, userID uint, ip string) (bool, error) {
+	for _, event := range r.events {
+		if event.UserID == userID && event.Success && (ip == "" || event.IPAddress == ip) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeLoginEventRepository) DeleteBefore(ctx context.Context, cutoff time.Time) (int64, error) {
+	var kept []*models.LoginEvent
+	for _, event := range r.events {
+		if !event.CreatedAt.Before(cutoff) {
+			kept = append(kept, event)
+		}
+	}
+	deleted := int64(len(r.events) - len(kept))
+	r.events = kept
+	return deleted, nil
+}
+
+func TestUserService_LoginHistory(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.Lockout.MaxFailedAttempts = 5
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), IsActive: true}
+	mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("GetByID", ctx, uint(2)).Return(nil, nil)
+	mockRepo.On("RecordFailedLogin", ctx, user.ID).Return(1, nil)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil)
+	mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil)
+
+	_, err := service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "wrong"})
+	require.Error(t, err)
+	_, err = service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "password123"})
+	require.NoError(t, err)
+
+	events, total, err := service.LoginHistory(ctx, user.ID, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, events, 2)
+	assert.True(t, events[0].Success)
+	assert.Equal(t, models.LoginMethodPassword, events[0].Method)
+	assert.False(t, events[1].Success)
+	assert.Equal(t, models.LoginFailureInvalidPassword, events[1].FailureReason)
+
+	_, _, err = service.LoginHistory(ctx, 2, 1, 10)
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	t.Run("old events are purged", func(t *testing.T) {
+		service.cfg.LoginHistory.Retention = time.Hour
+		repo := service.eventRepo.(*fakeLoginEventRepository)
+		repo.events[0].CreatedAt = time.Now().Add(-2 * time.Hour)
+
+		purged, err := service.PurgeLoginHistory(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 1, purged)
+		_, total, err := service.LoginHistory(ctx, user.ID, 1, 10)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), total)
+	})
+}
@@ -0,0 +1,24 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"gbt-be-template/internal/jobs"
+	"gbt-be-template/pkg/mailer"
+)
+
+// JobSendEmail is the job type that delivers an email, so SMTP outages are retried instead of failing requests
+const JobSendEmail = "email.send"
+
+// NewEmailJobHandler returns the job handler that sends queued emails
+func NewEmailJobHandler(m mailer.Mailer) jobs.Handler {
+	return func(ctx context.Context, payload []byte) error {
+		var msg mailer.Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("%w: invalid payload: %v", jobs.ErrPermanent, err)
+		}
+		return m.Send(ctx, &msg)
+	}
+}
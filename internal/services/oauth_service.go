@@ -0,0 +1,432 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// oauthService implements the OAuthService interface
+type oauthService struct {
+	oauthRepo repository.OAuthRepository
+	userRepo  repository.UserRepository
+	cfg       *config.Config
+	log       *logger.Logger
+}
+
+// NewOAuthService creates a new OAuth service
+func NewOAuthService(oauthRepo repository.OAuthRepository, userRepo repository.UserRepository, cfg *config.Config, log *logger.Logger) OAuthService {
+	return &oauthService{
+		oauthRepo: oauthRepo,
+		userRepo:  userRepo,
+		cfg:       cfg,
+		log:       log,
+	}
+}
+
+// RegisterClient registers a new OAuth client and returns its credentials
+func (s *oauthService) RegisterClient(ctx context.Context, ownerID uint, req *models.OAuthClientCreateRequest) (*models.OAuthClientResponse, error) {
+	clientID, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate client ID: %w", err)
+	}
+
+	scopes := req.Scopes
+	if len(scopes) == 0 {
+		scopes = []string{models.OAuthScopeOpenID, models.OAuthScopeProfile, models.OAuthScopeEmail}
+	}
+
+	client := &models.OAuthClient{
+		ClientID:     clientID,
+		Name:         req.Name,
+		RedirectURIs: strings.Join(req.RedirectURIs, " "),
+		Scopes:       strings.Join(scopes, " "),
+		IsPublic:     req.IsPublic,
+		OwnerID:      ownerID,
+	}
+
+	// Confidential clients authenticate at the token endpoint with a secret
+	var secret string
+	if !req.IsPublic {
+		secret, err = utils.GenerateRandomToken(32)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate client secret: %w", err)
+		}
+		hash, err := bcrypt.GenerateFromPassword([]byte(secret), bcrypt.DefaultCost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to hash client secret: %w", err)
+		}
+		client.SecretHash = string(hash)
+	}
+
+	if err := s.oauthRepo.CreateClient(ctx, client); err != nil {
+		s.log.WithError(err).Error("Failed to create OAuth client")
+		return nil, fmt.Errorf("failed to create client: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"client_id": client.ClientID,
+		"owner_id":  ownerID,
+	}).Info("OAuth client registered successfully")
+
+	resp := client.ToResponse()
+	resp.ClientSecret = secret
+	return resp, nil
+}
+
+// ListClients retrieves all registered clients
+func (s *oauthService) ListClients(ctx context.Context) ([]*models.OAuthClientResponse, error) {
+	clients, err := s.oauthRepo.ListClients(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to list OAuth clients")
+		return nil, fmt.Errorf("failed to list clients: %w", err)
+	}
+
+	responses := make([]*models.OAuthClientResponse, len(clients))
+	for i, client := range clients {
+		responses[i] = client.ToResponse()
+	}
+	return responses, nil
+}
+
+// DeleteClient removes a registered client
+func (s *oauthService) DeleteClient(ctx context.Context, clientID string) error {
+	client, err := s.oauthRepo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return fmt.Errorf("failed to get client: %w", err)
+	}
+	if client == nil {
+		return errors.New("client not found")
+	}
+
+	if err := s.oauthRepo.DeleteClient(ctx, clientID); err != nil {
+		s.log.WithError(err).WithField("client_id", clientID).Error("Failed to delete OAuth client")
+		return fmt.Errorf("failed to delete client: %w", err)
+	}
+
+	s.log.WithField("client_id", clientID).Info("OAuth client deleted successfully")
+	return nil
+}
+
+// PrepareConsent validates an authorization request and returns the data for the consent screen
+func (s *oauthService) PrepareConsent(ctx context.Context, userID uint, req *models.OAuthAuthorizeRequest) (*models.OAuthConsentResponse, error) {
+	client, scopes, err := s.validateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	descriptions := make([]models.OAuthScopeDescription, len(scopes))
+	for i, scope := range scopes {
+		descriptions[i] = models.OAuthScopeDescription{
+			Name:        scope,
+			Description: models.OAuthScopeDescriptions[scope],
+		}
+	}
+
+	consentRequired := true
+	consent, err := s.oauthRepo.GetConsent(ctx, userID, client.ClientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get consent: %w", err)
+	}
+	if consent != nil && containsAll(strings.Fields(consent.Scope), scopes) {
+		consentRequired = false
+	}
+
+	return &models.OAuthConsentResponse{
+		ClientID:        client.ClientID,
+		ClientName:      client.Name,
+		RedirectURI:     req.RedirectURI,
+		State:           req.State,
+		Scopes:          descriptions,
+		ConsentRequired: consentRequired,
+	}, nil
+}
+
+// Authorize records the user's consent decision and returns the URL to redirect the user agent to
+func (s *oauthService) Authorize(ctx context.Context, userID uint, decision *models.OAuthConsentDecision) (string, error) {
+	req := &decision.OAuthAuthorizeRequest
+	client, scopes, err := s.validateAuthorizeRequest(ctx, req)
+	if err != nil {
+		return "", err
+	}
+
+	if !decision.Approve {
+		s.log.WithFields(map[string]interface{}{
+			"client_id": client.ClientID,
+			"user_id":   userID,
+		}).Info("OAuth authorization denied by user")
+		return buildRedirectURL(req.RedirectURI, map[string]string{
+			"error": "access_denied",
+			"state": req.State,
+		})
+	}
+
+	scope := strings.Join(scopes, " ")
+	if err := s.oauthRepo.SaveConsent(ctx, &models.OAuthConsent{
+		UserID:   userID,
+		ClientID: client.ClientID,
+		Scope:    scope,
+	}); err != nil {
+		s.log.WithError(err).Error("Failed to save OAuth consent")
+		return "", fmt.Errorf("failed to save consent: %w", err)
+	}
+
+	code, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate authorization code: %w", err)
+	}
+
+	method := req.CodeChallengeMethod
+	if req.CodeChallenge != "" && method == "" {
+		method = "plain"
+	}
+
+	authCode := &models.OAuthAuthorizationCode{
+		CodeHash:            utils.HashToken(code),
+		ClientID:            client.ClientID,
+		UserID:              userID,
+		RedirectURI:         req.RedirectURI,
+		Scope:               scope,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: method,
+		ExpiresAt:           time.Now().Add(s.cfg.OAuth2.AuthCodeExpiry),
+	}
+	if err := s.oauthRepo.CreateAuthorizationCode(ctx, authCode); err != nil {
+		s.log.WithError(err).Error("Failed to store authorization code")
+		return "", fmt.Errorf("failed to create authorization code: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"client_id": client.ClientID,
+		"user_id":   userID,
+		"scope":     scope,
+	}).Info("OAuth authorization code issued")
+
+	return buildRedirectURL(req.RedirectURI, map[string]string{
+		"code":  code,
+		"state": req.State,
+	})
+}
+
+// Exchange redeems an authorization code for an access token
+func (s *oauthService) Exchange(ctx context.Context, req *models.OAuthTokenRequest) (*models.OAuthTokenResponse, error) {
+	if req.GrantType != "authorization_code" {
+		return nil, &models.OAuthError{Code: "unsupported_grant_type"}
+	}
+
+	client, err := s.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	code, err := s.oauthRepo.GetAuthorizationCodeByHash(ctx, utils.HashToken(req.Code))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get authorization code: %w", err)
+	}
+	if code == nil || code.ClientID != client.ClientID || code.UsedAt != nil || time.Now().After(code.ExpiresAt) {
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "authorization code is invalid or expired"}
+	}
+	if code.RedirectURI != req.RedirectURI {
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "redirect_uri does not match"}
+	}
+	if !verifyCodeChallenge(code.CodeChallenge, code.CodeChallengeMethod, req.CodeVerifier) {
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "PKCE verification failed"}
+	}
+
+	// Redeem the code atomically so concurrent exchanges can't both succeed
+	redeemed, err := s.oauthRepo.MarkAuthorizationCodeUsed(ctx, code.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to redeem authorization code: %w", err)
+	}
+	if !redeemed {
+		s.log.WithField("client_id", client.ClientID).Warn("Authorization code replay detected")
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "authorization code is invalid or expired"}
+	}
+
+	user, err := s.userRepo.GetByID(ctx, code.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.IsActive || user.IsSuspended(time.Now()) {
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "user is not active"}
+	}
+
+	expiry := s.cfg.OAuth2.AccessTokenExpiry
+	token, err := utils.GenerateClientJWT(user.ID, user.Email, client.ClientID, code.Scope, s.cfg.OAuth2.Issuer, s.cfg.JWT.Keys().Current, expiry)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to generate OAuth access token")
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"client_id": client.ClientID,
+		"user_id":   user.ID,
+	}).Info("OAuth access token issued")
+
+	return &models.OAuthTokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(expiry.Seconds()),
+		Scope:       code.Scope,
+	}, nil
+}
+
+// UserInfo returns the OIDC claims about a user permitted by the granted scope
+func (s *oauthService) UserInfo(ctx context.Context, userID uint, scope string) (map[string]interface{}, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, errors.New("user not found")
+	}
+
+	claims := map[string]interface{}{
+		"sub": strconv.FormatUint(uint64(user.ID), 10),
+	}
+
+	scopes := strings.Fields(scope)
+	if containsAll(scopes, []string{models.OAuthScopeProfile}) {
+		claims["name"] = strings.TrimSpace(user.FirstName + " " + user.LastName)
+		claims["given_name"] = user.FirstName
+		claims["family_name"] = user.LastName
+		claims["preferred_username"] = user.Username
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+	if containsAll(scopes, []string{models.OAuthScopeEmail}) {
+		claims["email"] = user.Email
+	}
+
+	return claims, nil
+}
+
+// Discovery returns the OpenID Provider metadata document
+func (s *oauthService) Discovery() map[string]interface{} {
+	issuer := strings.TrimRight(s.cfg.OAuth2.Issuer, "/")
+	return map[string]interface{}{
+		"issuer":                                issuer,
+		"authorization_endpoint":                s.cfg.OAuth2.ConsentURL,
+		"token_endpoint":                        issuer + "/api/v1/oauth/token",
+		"userinfo_endpoint":                     issuer + "/api/v1/oauth/userinfo",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code"},
+		"subject_types_supported":               []string{"public"},
+		"scopes_supported":                      []string{models.OAuthScopeOpenID, models.OAuthScopeProfile, models.OAuthScopeEmail},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post", "none"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+	}
+}
+
+// validateAuthorizeRequest checks the client, redirect URI, PKCE parameters and scopes
+func (s *oauthService) validateAuthorizeRequest(ctx context.Context, req *models.OAuthAuthorizeRequest) (*models.OAuthClient, []string, error) {
+	client, err := s.oauthRepo.GetClientByClientID(ctx, req.ClientID)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	if client == nil {
+		return nil, nil, &models.OAuthError{Code: "invalid_client", Description: "unknown client"}
+	}
+
+	// Redirect URIs must match a registered URI exactly
+	if !containsAll(client.GetRedirectURIs(), []string{req.RedirectURI}) {
+		return nil, nil, &models.OAuthError{Code: "invalid_request", Description: "redirect_uri is not registered for this client"}
+	}
+
+	// Public clients can't keep a secret, so PKCE is mandatory for them
+	if client.IsPublic && req.CodeChallenge == "" {
+		return nil, nil, &models.OAuthError{Code: "invalid_request", Description: "code_challenge is required for public clients"}
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		scopes = client.GetScopes()
+	}
+	if !containsAll(client.GetScopes(), scopes) {
+		return nil, nil, &models.OAuthError{Code: "invalid_scope", Description: "requested scope is not allowed for this client"}
+	}
+
+	return client, scopes, nil
+}
+
+// authenticateClient authenticates a client at the token endpoint
+func (s *oauthService) authenticateClient(ctx context.Context, clientID, clientSecret string) (*models.OAuthClient, error) {
+	client, err := s.oauthRepo.GetClientByClientID(ctx, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get client: %w", err)
+	}
+	if client == nil {
+		return nil, &models.OAuthError{Code: "invalid_client"}
+	}
+
+	if !client.IsPublic {
+		if clientSecret == "" || bcrypt.CompareHashAndPassword([]byte(client.SecretHash), []byte(clientSecret)) != nil {
+			s.log.WithField("client_id", clientID).Warn("OAuth client authentication failed")
+			return nil, &models.OAuthError{Code: "invalid_client"}
+		}
+	}
+
+	return client, nil
+}
+
+// verifyCodeChallenge checks a PKCE code verifier against the stored challenge
+func verifyCodeChallenge(challenge, method, verifier string) bool {
+	if challenge == "" {
+		return true
+	}
+	if verifier == "" {
+		return false
+	}
+
+	expected := verifier
+	if method == "S256" {
+		sum := sha256.Sum256([]byte(verifier))
+		expected = base64.RawURLEncoding.EncodeToString(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(expected), []byte(challenge)) == 1
+}
+
+// buildRedirectURL appends non-empty query parameters to a redirect URI
+func buildRedirectURL(redirectURI string, params map[string]string) (string, error) {
+	u, err := url.Parse(redirectURI)
+	if err != nil {
+		return "", fmt.Errorf("invalid redirect URI: %w", err)
+	}
+
+	query := u.Query()
+	for key, value := range params {
+		if value != "" {
+			query.Set(key, value)
+		}
+	}
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// containsAll reports whether every element of subset is present in set
+func containsAll(set, subset []string) bool {
+	lookup := make(map[string]struct{}, len(set))
+	for _, item := range set {
+		lookup[item] = struct{}{}
+	}
+	for _, item := range subset {
+		if _, ok := lookup[item]; !ok {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/oidc"
+)
+
+// oidcService implements the OIDCService interface
+type oidcService struct {
+	verifier *oidc.Verifier
+	resolver *identityResolver
+	merger   AccountMerger
+	cfg      *config.Config
+	log      *logger.Logger
+}
+
+// NewOIDCService creates a new service accepting access tokens of the configured OpenID Provider
+func NewOIDCService(userRepo repository.UserRepository, identityRepo repository.IdentityRepository, cfg *config.Config, log *logger.Logger) OIDCService {
+	return &oidcService{
+		verifier: oidc.NewVerifier(cfg.OIDC.Issuer, cfg.OIDC.Audience, nil),
+		resolver: &identityResolver{
+			userRepo:      userRepo,
+			identityRepo:  identityRepo,
+			autoProvision: cfg.OIDC.AutoProvision,
+			log:           log,
+		},
+		cfg: cfg,
+		log: log,
+	}
+}
+
+// UseAccountMerger lets users linking an identity of another account merge that account into
+// theirs. Linking such identities is refused until it is called.
+func (s *oidcService) UseAccountMerger(merger AccountMerger) {
+	s.merger = merger
+}
+
+// Handles reports whether a token claims to be issued by the configured provider.
+// It does not verify the token, Authenticate does.
+func (s *oidcService) Handles(rawToken string) bool {
+	issuer, err := oidc.UnverifiedIssuer(rawToken)
+	return err == nil && issuer == s.verifier.Issuer()
+}
+
+// Authenticate verifies a provider token and returns the mapped local user and whether they act as an admin
+func (s *oidcService) Authenticate(ctx context.Context, rawToken string) (*models.User, bool, error) {
+	claims, err := s.verifier.Verify(ctx, rawToken)
+	if err != nil {
+		return nil, false, fmt.Errorf("invalid token: %w", err)
+	}
+
+	user, err := s.resolver.Resolve(ctx, &externalIdentity{
+		Provider:      s.verifier.Issuer(),
+		Subject:       claims.String("sub"),
+		Email:         claims.String("email"),
+		EmailVerified: claims.Bool("email_verified"),
+		Username:      claims.String("preferred_username"),
+		FirstName:     claims.String("given_name"),
+		LastName:      claims.String("family_name"),
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to map external identity: %w", err)
+	}
+	if !user.IsActive {
+		return nil, false, errors.New("user account is deactivated")
+	}
+	if user.IsSuspended(time.Now()) {
+		return nil, false, &AccountSuspendedError{Until: user.SuspendedUntil}
+	}
+
+	// Admin status comes from the local account or membership of the configured IdP group
+	isAdmin := user.IsAdmin
+	if group := s.cfg.OIDC.AdminGroup; group != "" {
+		isAdmin = isAdmin || containsAll(claims.Strings(s.cfg.OIDC.GroupsClaim), []string{group})
+	}
+
+	return user, isAdmin, nil
+}
+
+// Link links the identity a provider token was issued for to a user, so that the user can sign
+// in with the provider. An identity of another account is only linked when the request asks to
+// merge that account into the user's; the token proves the user can sign in as that account
+// anyway, unless it is deactivated or suspended.
+func (s *oidcService) Link(ctx context.Context, userID uint, req *models.IdentityLinkRequest) (*models.UserIdentity, error) {
+	claims, err := s.verifier.Verify(ctx, req.Token)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Warn("Invalid token for identity link")
+		return nil, ErrInvalidIdentityToken
+	}
+
+	provider, subject := s.verifier.Issuer(), claims.String("sub")
+	identity, err := s.resolver.identityRepo.GetByProviderSubject(ctx, provider, subject)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get identity: %w", err)
+	}
+	if identity == nil {
+		identity = &models.UserIdentity{
+			UserID:   userID,
+			Provider: provider,
+			Subject:  subject,
+			Email:    claims.String("email"),
+		}
+		if err := s.resolver.identityRepo.Create(ctx, identity); err != nil {
+			return nil, fmt.Errorf("failed to link identity: %w", err)
+		}
+		s.log.Security("identity_linked", userID).WithField("provider", provider).Info("External identity linked to user")
+		return identity, nil
+	}
+	if identity.UserID == userID {
+		return identity, nil
+	}
+	if !req.Merge || s.merger == nil {
+		return nil, ErrIdentityLinked
+	}
+
+	duplicate, err := s.resolver.userRepo.GetByID(ctx, identity.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if duplicate == nil || !duplicate.IsActive || duplicate.IsSuspended(time.Now()) {
+		return nil, fmt.Errorf("%w: the account of this identity can't sign in", ErrInvalidMerge)
+	}
+	if _, err := s.merger.MergeAccounts(ctx, userID, userID, duplicate.ID); err != nil {
+		return nil, err
+	}
+	identity.UserID = userID
+	return identity, nil
+}
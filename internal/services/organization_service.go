@@ -0,0 +1,311 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+)
+
+// organizationService implements the OrganizationService interface
+type organizationService struct {
+	orgRepo  repository.OrganizationRepository
+	userRepo repository.UserRepository
+	log      *logger.Logger
+}
+
+// NewOrganizationService creates a new organization service. Admins may act on every organization
+// as if they were its owner.
+func NewOrganizationService(orgRepo repository.OrganizationRepository, userRepo repository.UserRepository, log *logger.Logger) OrganizationService {
+	return &organizationService{
+		orgRepo:  orgRepo,
+		userRepo: userRepo,
+		log:      log,
+	}
+}
+
+// Create creates an organization owned by the acting user
+func (s *organizationService) Create(ctx context.Context, actorID uint, req *models.OrganizationCreateRequest) (*models.OrganizationResponse, error) {
+	org := &models.Organization{
+		Name:        req.Name,
+		Description: req.Description,
+	}
+	if err := s.orgRepo.Create(ctx, org, actorID); err != nil {
+		s.log.WithError(err).WithField("user_id", actorID).Error("Failed to create organization")
+		return nil, fmt.Errorf("failed to create organization: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"organization_id": org.ID,
+		"user_id":         actorID,
+	}).Info("Organization created")
+	return org.ToResponse(models.OrgRoleOwner), nil
+}
+
+// List returns the organizations a user is a member of, with the user's role in each
+func (s *organizationService) List(ctx context.Context, userID uint) ([]*models.OrganizationResponse, error) {
+	memberships, err := s.orgRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list organizations")
+		return nil, fmt.Errorf("failed to list organizations: %w", err)
+	}
+
+	responses := make([]*models.OrganizationResponse, 0, len(memberships))
+	for _, membership := range memberships {
+		if membership.Organization != nil {
+			responses = append(responses, membership.Organization.ToResponse(membership.Role))
+		}
+	}
+	return responses, nil
+}
+
+// GetByID returns an organization the acting user is a member of
+func (s *organizationService) GetByID(ctx context.Context, actorID uint, isAdmin bool, id uint) (*models.OrganizationResponse, error) {
+	org, role, err := s.access(ctx, actorID, isAdmin, id, models.OrgRoleMember)
+	if err != nil {
+		return nil, err
+	}
+	return org.ToResponse(role), nil
+}
+
+// Update changes the fields of an organization given in the request. It takes an owner or admin.
+func (s *organizationService) Update(ctx context.Context, actorID uint, isAdmin bool, id uint, req *models.OrganizationUpdateRequest) (*models.OrganizationResponse, error) {
+	org, role, err := s.access(ctx, actorID, isAdmin, id, models.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		org.Name = *req.Name
+	}
+	if req.Description != nil {
+		org.Description = *req.Description
+	}
+	if err := s.orgRepo.Update(ctx, org); err != nil {
+		s.log.WithError(err).WithField("organization_id", id).Error("Failed to update organization")
+		return nil, fmt.Errorf("failed to update organization: %w", err)
+	}
+
+	s.log.WithField("organization_id", id).Info("Organization updated")
+	return org.ToResponse(role), nil
+}
+
+// Delete deletes an organization along with its memberships. It takes an owner.
+func (s *organizationService) Delete(ctx context.Context, actorID uint, isAdmin bool, id uint) error {
+	if _, _, err := s.access(ctx, actorID, isAdmin, id, models.OrgRoleOwner); err != nil {
+		return err
+	}
+
+	deleted, err := s.orgRepo.Delete(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("organization_id", id).Error("Failed to delete organization")
+		return fmt.Errorf("failed to delete organization: %w", err)
+	}
+	if !deleted {
+		return ErrOrganizationNotFound
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"organization_id": id,
+		"actor_id":        actorID,
+	}).Info("Organization deleted")
+	return nil
+}
+
+// ListMembers returns the members of an organization the acting user is a member of
+func (s *organizationService) ListMembers(ctx context.Context, actorID uint, isAdmin bool, id uint) ([]*models.MembershipResponse, error) {
+	if _, _, err := s.access(ctx, actorID, isAdmin, id, models.OrgRoleMember); err != nil {
+		return nil, err
+	}
+
+	memberships, err := s.orgRepo.ListMembers(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("organization_id", id).Error("Failed to list organization members")
+		return nil, fmt.Errorf("failed to list organization members: %w", err)
+	}
+
+	responses := make([]*models.MembershipResponse, len(memberships))
+	for i, membership := range memberships {
+		responses[i] = membership.ToResponse()
+	}
+	return responses, nil
+}
+
+// AddMember adds a user to an organization. It takes an owner or admin, and only owners can add
+// other owners.
+func (s *organizationService) AddMember(ctx context.Context, actorID uint, isAdmin bool, id uint, req *models.MembershipCreateRequest) (*models.MembershipResponse, error) {
+	_, role, err := s.access(ctx, actorID, isAdmin, id, models.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if req.Role == models.OrgRoleOwner && !isAdmin && role != models.OrgRoleOwner {
+		return nil, ErrOrgRoleInsufficient
+	}
+
+	user, err := s.userRepo.GetByID(ctx, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	membership := &models.Membership{OrganizationID: id, UserID: req.UserID, Role: req.Role}
+	added, err := s.orgRepo.AddMember(ctx, membership)
+	if err != nil {
+		s.log.WithError(err).WithField("organization_id", id).Error("Failed to add organization member")
+		return nil, fmt.Errorf("failed to add organization member: %w", err)
+	}
+	if !added {
+		return nil, ErrAlreadyMember
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"organization_id": id,
+		"user_id":         req.UserID,
+		"role":            req.Role,
+		"actor_id":        actorID,
+	}).Info("Organization member added")
+	membership.User = user
+	return membership.ToResponse(), nil
+}
+
+// UpdateMember changes the role of a member. It takes an owner or admin, and only owners can
+// promote members to owner or change the role of owners. The last owner can't step down.
+func (s *organizationService) UpdateMember(ctx context.Context, actorID uint, isAdmin bool, id, userID uint, req *models.MembershipUpdateRequest) (*models.MembershipResponse, error) {
+	_, role, err := s.access(ctx, actorID, isAdmin, id, models.OrgRoleAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	membership, err := s.getMembership(ctx, id, userID)
+	if err != nil {
+		return nil, err
+	}
+	if membership.Role == req.Role {
+		return membership.ToResponse(), nil
+	}
+	if (req.Role == models.OrgRoleOwner || membership.Role == models.OrgRoleOwner) && !isAdmin && role != models.OrgRoleOwner {
+		return nil, ErrOrgRoleInsufficient
+	}
+	if membership.Role == models.OrgRoleOwner {
+		if err := s.checkNotLastOwner(ctx, id); err != nil {
+			return nil, err
+		}
+	}
+
+	membership.Role = req.Role
+	if err := s.orgRepo.UpdateMember(ctx, membership); err != nil {
+		s.log.WithError(err).WithField("organization_id", id).Error("Failed to update organization member")
+		return nil, fmt.Errorf("failed to update organization member: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"organization_id": id,
+		"user_id":         userID,
+		"role":            req.Role,
+		"actor_id":        actorID,
+	}).Info("Organization member role changed")
+	return membership.ToResponse(), nil
+}
+
+// RemoveMember takes a user out of an organization. Members can leave on their own; removing
+// others takes an owner or admin, and only owners can remove owners. The last owner can't leave.
+func (s *organizationService) RemoveMember(ctx context.Context, actorID uint, isAdmin bool, id, userID uint) error {
+	minRole := models.OrgRoleAdmin
+	if userID == actorID {
+		minRole = models.OrgRoleMember
+	}
+	_, role, err := s.access(ctx, actorID, isAdmin, id, minRole)
+	if err != nil {
+		return err
+	}
+
+	membership, err := s.getMembership(ctx, id, userID)
+	if err != nil {
+		return err
+	}
+	if membership.Role == models.OrgRoleOwner {
+		if userID != actorID && !isAdmin && role != models.OrgRoleOwner {
+			return ErrOrgRoleInsufficient
+		}
+		if err := s.checkNotLastOwner(ctx, id); err != nil {
+			return err
+		}
+	}
+
+	removed, err := s.orgRepo.RemoveMember(ctx, id, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("organization_id", id).Error("Failed to remove organization member")
+		return fmt.Errorf("failed to remove organization member: %w", err)
+	}
+	if !removed {
+		return ErrMembershipNotFound
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"organization_id": id,
+		"user_id":         userID,
+		"actor_id":        actorID,
+	}).Info("Organization member removed")
+	return nil
+}
+
+// access returns an organization and the acting user's role in it, or ErrOrganizationNotFound
+// when the user isn't a member. ErrOrgRoleInsufficient is returned when the role is below minRole.
+// Admins pass every check, with an empty role unless they are members.
+func (s *organizationService) access(ctx context.Context, actorID uint, isAdmin bool, id uint, minRole string) (*models.Organization, string, error) {
+	org, err := s.orgRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("organization_id", id).Error("Failed to get organization")
+		return nil, "", fmt.Errorf("failed to get organization: %w", err)
+	}
+	if org == nil {
+		return nil, "", ErrOrganizationNotFound
+	}
+
+	membership, err := s.orgRepo.GetMembership(ctx, id, actorID)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to get membership: %w", err)
+	}
+	role := ""
+	if membership != nil {
+		role = membership.Role
+	}
+	if isAdmin {
+		return org, role, nil
+	}
+	if membership == nil {
+		return nil, "", ErrOrganizationNotFound
+	}
+	if !models.OrgRoleAtLeast(role, minRole) {
+		return nil, "", ErrOrgRoleInsufficient
+	}
+	return org, role, nil
+}
+
+// getMembership returns the membership of a user, or ErrMembershipNotFound
+func (s *organizationService) getMembership(ctx context.Context, id, userID uint) (*models.Membership, error) {
+	membership, err := s.orgRepo.GetMembership(ctx, id, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get membership: %w", err)
+	}
+	if membership == nil {
+		return nil, ErrMembershipNotFound
+	}
+	return membership, nil
+}
+
+// checkNotLastOwner returns ErrLastOwner when an organization has a single owner left
+func (s *organizationService) checkNotLastOwner(ctx context.Context, id uint) error {
+	owners, err := s.orgRepo.CountOwners(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to count owners: %w", err)
+	}
+	if owners <= 1 {
+		return ErrLastOwner
+	}
+	return nil
+}
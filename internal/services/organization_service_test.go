@@ -0,0 +1,226 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOrganizationRepository keeps organizations and memberships in memory
+type fakeOrganizationRepository struct {
+	orgs        map[uint]*models.Organization
+	memberships map[[2]uint]*models.Membership // Keyed by organization and user ID
+}
+
+func newFakeOrganizationRepository() *fakeOrganizationRepository {
+	return &fakeOrganizationRepository{
+		orgs:        map[uint]*models.Organization{},
+		memberships: map[[2]uint]*models.Membership{},
+	}
+}
+
+func (r *fakeOrganizationRepository) Create(ctx context.Context, org *models.Organization, ownerID uint) error {
+	org.ID = uint(len(r.orgs) + 1)
+	r.orgs[org.ID] = org
+	r.memberships[[2]uint{org.ID, ownerID}] = &models.Membership{OrganizationID: org.ID, UserID: ownerID, Role: models.OrgRoleOwner}
+	return nil
+}
+
+func (r *fakeOrganizationRepository) GetByID(ctx context.Context, id uint) (*models.Organization, error) {
+	org, ok := r.orgs[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *org
+	return &copied, nil
+}
+
+func (r *fakeOrganizationRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Membership, error) {
+	var memberships []*models.Membership
+	for key, membership := range r.memberships {
+		if key[1] == userID {
+			copied := *membership
+			copied.Organization = r.orgs[key[0]]
+			memberships = append(memberships, &copied)
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool { return memberships[i].OrganizationID < memberships[j].OrganizationID })
+	return memberships, nil
+}
+
+func (r *fakeOrganizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	r.orgs[org.ID] = org
+	return nil
+}
+
+func (r *fakeOrganizationRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	if _, ok := r.orgs[id]; !ok {
+		return false, nil
+	}
+	delete(r.orgs, id)
+	for key := range r.memberships {
+		if key[0] == id {
+			delete(r.memberships, key)
+		}
+	}
+	return true, nil
+}
+
+func (r *fakeOrganizationRepository) GetMembership(ctx context.Context, orgID, userID uint) (*models.Membership, error) {
+	membership, ok := r.memberships[[2]uint{orgID, userID}]
+	if !ok {
+		return nil, nil
+	}
+	copied := *membership
+	return &copied, nil
+}
+
+func (r *fakeOrganizationRepository) ListMembers(ctx context.Context, orgID uint) ([]*models.Membership, error) {
+	var memberships []*models.Membership
+	for key, membership := range r.memberships {
+		if key[0] == orgID {
+			copied := *membership
+			memberships = append(memberships, &copied)
+		}
+	}
+	sort.Slice(memberships, func(i, j int) bool { return memberships[i].UserID < memberships[j].UserID })
+	return memberships, nil
+}
+
+func (r *fakeOrganizationRepository) AddMember(ctx context.Context, membership *models.Membership) (bool, error) {
+	key := [2]uint{membership.OrganizationID, membership.UserID}
+	if _, ok := r.memberships[key]; ok {
+		return false, nil
+	}
+	copied := *membership
+	r.memberships[key] = &copied
+	return true, nil
+}
+
+func (r *fakeOrganizationRepository) UpdateMember(ctx context.Context, membership *models.Membership) error {
+	copied := *membership
+	r.memberships[[2]uint{membership.OrganizationID, membership.UserID}] = &copied
+	return nil
+}
+
+func (r *fakeOrganizationRepository) RemoveMember(ctx context.Context, orgID, userID uint) (bool, error) {
+	key := [2]uint{orgID, userID}
+	if _, ok := r.memberships[key]; !ok {
+		return false, nil
+	}
+	delete(r.memberships, key)
+	return true, nil
+}
+
+func (r *fakeOrganizationRepository) CountOwners(ctx context.Context, orgID uint) (int64, error) {
+	var count int64
+	for key, membership := range r.memberships {
+		if key[0] == orgID && membership.Role == models.OrgRoleOwner {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func setupOrganizationService() (*organizationService, *fakeOrganizationRepository, *MockUserRepository) {
+	orgRepo := newFakeOrganizationRepository()
+	userRepo := &MockUserRepository{}
+	userRepo.On("GetByID", mock.Anything, mock.Anything).Return(&models.User{IsActive: true}, nil)
+	return NewOrganizationService(orgRepo, userRepo, logger.New("error", "text")).(*organizationService), orgRepo, userRepo
+}
+
+func TestOrganizationService_Create(t *testing.T) {
+	service, _, _ := setupOrganizationService()
+	ctx := context.Background()
+
+	org, err := service.Create(ctx, 1, &models.OrganizationCreateRequest{Name: "Acme"})
+	require.NoError(t, err)
+	assert.Equal(t, models.OrgRoleOwner, org.Role)
+
+	orgs, err := service.List(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, orgs, 1)
+	assert.Equal(t, "Acme", orgs[0].Name)
+
+	// Organizations are hidden from users who aren't members, but not from admins
+	_, err = service.GetByID(ctx, 2, false, org.ID)
+	assert.ErrorIs(t, err, ErrOrganizationNotFound)
+	got, err := service.GetByID(ctx, 2, true, org.ID)
+	require.NoError(t, err)
+	assert.Empty(t, got.Role)
+
+	orgs, err = service.List(ctx, 2)
+	require.NoError(t, err)
+	assert.Empty(t, orgs)
+}
+
+func TestOrganizationService_Roles(t *testing.T) {
+	service, orgRepo, _ := setupOrganizationService()
+	ctx := context.Background()
+
+	org, err := service.Create(ctx, 1, &models.OrganizationCreateRequest{Name: "Acme"})
+	require.NoError(t, err)
+	_, err = service.AddMember(ctx, 1, false, org.ID, &models.MembershipCreateRequest{UserID: 2, Role: models.OrgRoleAdmin})
+	require.NoError(t, err)
+	_, err = service.AddMember(ctx, 1, false, org.ID, &models.MembershipCreateRequest{UserID: 3, Role: models.OrgRoleMember})
+	require.NoError(t, err)
+
+	t.Run("members can't manage the organization", func(t *testing.T) {
+		name := "Renamed"
+		_, err := service.Update(ctx, 3, false, org.ID, &models.OrganizationUpdateRequest{Name: &name})
+		assert.ErrorIs(t, err, ErrOrgRoleInsufficient)
+		_, err = service.AddMember(ctx, 3, false, org.ID, &models.MembershipCreateRequest{UserID: 4, Role: models.OrgRoleMember})
+		assert.ErrorIs(t, err, ErrOrgRoleInsufficient)
+
+		members, err := service.ListMembers(ctx, 3, false, org.ID)
+		require.NoError(t, err)
+		assert.Len(t, members, 3)
+	})
+
+	t.Run("admins manage members but not owners", func(t *testing.T) {
+		name := "Acme Inc"
+		updated, err := service.Update(ctx, 2, false, org.ID, &models.OrganizationUpdateRequest{Name: &name})
+		require.NoError(t, err)
+		assert.Equal(t, "Acme Inc", updated.Name)
+
+		_, err = service.AddMember(ctx, 2, false, org.ID, &models.MembershipCreateRequest{UserID: 4, Role: models.OrgRoleOwner})
+		assert.ErrorIs(t, err, ErrOrgRoleInsufficient)
+		_, err = service.UpdateMember(ctx, 2, false, org.ID, 3, &models.MembershipUpdateRequest{Role: models.OrgRoleOwner})
+		assert.ErrorIs(t, err, ErrOrgRoleInsufficient)
+		assert.ErrorIs(t, service.RemoveMember(ctx, 2, false, org.ID, 1), ErrOrgRoleInsufficient)
+		assert.ErrorIs(t, service.Delete(ctx, 2, false, org.ID), ErrOrgRoleInsufficient)
+
+		_, err = service.AddMember(ctx, 2, false, org.ID, &models.MembershipCreateRequest{UserID: 3, Role: models.OrgRoleMember})
+		assert.ErrorIs(t, err, ErrAlreadyMember)
+	})
+
+	t.Run("the last owner stays", func(t *testing.T) {
+		_, err := service.UpdateMember(ctx, 1, false, org.ID, 1, &models.MembershipUpdateRequest{Role: models.OrgRoleMember})
+		assert.ErrorIs(t, err, ErrLastOwner)
+		assert.ErrorIs(t, service.RemoveMember(ctx, 1, false, org.ID, 1), ErrLastOwner)
+
+		member, err := service.UpdateMember(ctx, 1, false, org.ID, 2, &models.MembershipUpdateRequest{Role: models.OrgRoleOwner})
+		require.NoError(t, err)
+		assert.Equal(t, models.OrgRoleOwner, member.Role)
+		require.NoError(t, service.RemoveMember(ctx, 1, false, org.ID, 1), "another owner is left")
+	})
+
+	t.Run("members can leave", func(t *testing.T) {
+		require.NoError(t, service.RemoveMember(ctx, 3, false, org.ID, 3))
+		assert.ErrorIs(t, service.RemoveMember(ctx, 2, false, org.ID, 3), ErrMembershipNotFound)
+	})
+
+	t.Run("owners delete the organization", func(t *testing.T) {
+		require.NoError(t, service.Delete(ctx, 2, false, org.ID))
+		assert.Empty(t, orgRepo.memberships)
+		_, err := service.GetByID(ctx, 2, false, org.ID)
+		assert.ErrorIs(t, err, ErrOrganizationNotFound)
+	})
+}
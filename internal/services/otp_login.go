@@ -0,0 +1,123 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"math/big"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/sms"
+	"gbt-be-template/pkg/utils"
+)
+
+// RequestLoginOTP texts a login code to the user with the given phone number. It succeeds
+// without sending anything for unknown numbers and locked accounts, so the response doesn't
+// reveal which numbers belong to an account. Requests are limited per number whether or not
+// it is known, which caps both SMS costs and guesses at codes.
+func (s *userService) RequestLoginOTP(ctx context.Context, req *models.OTPRequest) error {
+	result, err := s.otpLimiter.Allow(ctx, "otp:"+req.PhoneNumber, s.cfg.OTP.MaxRequests, s.cfg.OTP.RequestWindow)
+	if err != nil {
+		return fmt.Errorf("failed to check OTP rate limit: %w", err)
+	}
+	if !result.Allowed {
+		return &OTPRateLimitedError{RetryAfter: result.Reset}
+	}
+
+	user, err := s.userRepo.GetByPhone(ctx, req.PhoneNumber)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get user for OTP request")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || (user.LockedUntil != nil && time.Now().Before(*user.LockedUntil)) {
+		return nil
+	}
+
+	code, err := generateOTPCode()
+	if err != nil {
+		return fmt.Errorf("failed to generate code: %w", err)
+	}
+	// Only the most recent code works
+	if err := s.otpRepo.Replace(ctx, &models.OTPCode{
+		UserID:    user.ID,
+		CodeHash:  utils.HashToken(code),
+		ExpiresAt: time.Now().Add(s.cfg.OTP.CodeTTL),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to store OTP code")
+		return fmt.Errorf("failed to store code: %w", err)
+	}
+
+	msg := &sms.Message{
+		To:   req.PhoneNumber,
+		Body: fmt.Sprintf("Your login code is %s. It expires in %s. Don't share it with anyone.", code, s.cfg.OTP.CodeTTL),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendSMS, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to queue OTP message")
+		return fmt.Errorf("failed to queue OTP message: %w", err)
+	}
+
+	s.log.Security("otp_login_requested", user.ID).Info("Login code texted")
+	return nil
+}
+
+// VerifyLoginOTP logs in with a texted code. A code is discarded after too many wrong guesses.
+// Users with two-factor authentication still need their TOTP code, as after a password login.
+func (s *userService) VerifyLoginOTP(ctx context.Context, req *models.OTPVerifyRequest) (*models.LoginResponse, error) {
+	user, err := s.userRepo.GetByPhone(ctx, req.PhoneNumber)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get user for OTP login")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidOTP
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, models.LoginMethodOTP, models.LoginFailureAccountLocked)
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+
+	code, err := s.otpRepo.GetByUser(ctx, user.ID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to get OTP code")
+		return nil, fmt.Errorf("failed to get code: %w", err)
+	}
+	if code == nil || time.Now().After(code.ExpiresAt) || code.Attempts >= s.cfg.OTP.MaxAttempts {
+		return nil, ErrInvalidOTP
+	}
+
+	if subtle.ConstantTimeCompare([]byte(code.CodeHash), []byte(utils.HashToken(req.Code))) != 1 {
+		s.log.WithField("user_id", user.ID).Warn("Invalid OTP login attempt")
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, models.LoginMethodOTP, models.LoginFailureInvalidCode)
+		if err := s.otpRepo.RecordAttempt(ctx, code.ID); err != nil {
+			s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to record OTP attempt")
+		}
+		if code.Attempts+1 >= s.cfg.OTP.MaxAttempts {
+			s.log.Security("otp_code_discarded", user.ID).Warn("Login code discarded after too many wrong attempts")
+		}
+		return nil, ErrInvalidOTP
+	}
+
+	deleted, err := s.otpRepo.Delete(ctx, code.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use code: %w", err)
+	}
+	if !deleted {
+		return nil, ErrInvalidOTP
+	}
+
+	if user.TOTPEnabled && !s.isTrustedDevice(ctx, user.ID, req.DeviceToken) {
+		return s.startTwoFactorChallenge(ctx, user, false)
+	}
+	return s.completeLogin(ctx, user, loginOptions{method: models.LoginMethodOTP, deviceToken: req.DeviceToken})
+}
+
+// generateOTPCode returns a random six digit code
+func generateOTPCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,170 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeOTPRepository keeps login codes in memory
+type fakeOTPRepository struct {
+	codes  map[uint]*models.OTPCode
+	nextID uint
+}
+
+func (r *fakeOTPRepository) Replace(ctx context.Context, code *models.OTPCode) error {
+	if r.codes == nil {
+		r.codes = make(map[uint]*models.OTPCode)
+	}
+	r.nextID++
+	code.ID = r.nextID
+	code.Attempts = 0
+	copied := *code
+	r.codes[code.UserID] = &copied
+	return nil
+}
+
+func (r *fakeOTPRepository) GetByUser(ctx context.Context, userID uint) (*models.OTPCode, error) {
+	code, ok := r.codes[userID]
+	if !ok {
+		return nil, nil
+	}
+	copied := *code
+	return &copied, nil
+}
+
+func (r *fakeOTPRepository) RecordAttempt(ctx context.Context, id uint) error {
+	for _, code := range r.codes {
+		if code.ID == id {
+			code.Attempts++
+		}
+	}
+	return nil
+}
+
+func (r *fakeOTPRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	for userID, code := range r.codes {
+		if code.ID == id {
+			delete(r.codes, userID)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func setupOTPLogin(t *testing.T) (*userService, *MockUserRepository, *MockAuthService, *fakeQueue) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.OTP.CodeTTL = time.Minute
+	service.cfg.OTP.MaxAttempts = 3
+	service.cfg.OTP.MaxRequests = 2
+	service.cfg.OTP.RequestWindow = time.Minute
+	return service, mockRepo, mockAuth, service.queue.(*fakeQueue)
+}
+
+// textedCode returns the code of the last queued text message
+func textedCode(t *testing.T, queue *fakeQueue, phone string) string {
+	require.NotEmpty(t, queue.jobs)
+	job := queue.jobs[len(queue.jobs)-1]
+	require.True(t, strings.HasPrefix(job, JobSendSMS+" "))
+	var msg struct{ To, Body string }
+	require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(job, JobSendSMS+" ")), &msg))
+	assert.Equal(t, phone, msg.To)
+	code := regexp.MustCompile(`\d{6}`).FindString(msg.Body)
+	require.NotEmpty(t, code)
+	return code
+}
+
+func TestUserService_RequestLoginOTP(t *testing.T) {
+	service, mockRepo, _, queue := setupOTPLogin(t)
+	ctx := context.Background()
+
+	// Unknown numbers get the same answer without a message being sent
+	mockRepo.On("GetByPhone", ctx, "+15550000000").Return(nil, nil)
+	require.NoError(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: "+15550000000"}))
+	assert.Empty(t, queue.jobs)
+
+	user := &models.User{ID: 1, Email: "test@example.com", Phone: "+15551234567", IsActive: true}
+	mockRepo.On("GetByPhone", ctx, user.Phone).Return(user, nil)
+	require.NoError(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: user.Phone}))
+	textedCode(t, queue, user.Phone)
+
+	// Requests are limited per number, known or not
+	require.NoError(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: user.Phone}))
+	var limitErr *OTPRateLimitedError
+	require.ErrorAs(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: user.Phone}), &limitErr)
+	assert.Positive(t, limitErr.RetryAfter)
+	assert.Len(t, queue.jobs, 2)
+
+	require.NoError(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: "+15550000000"}))
+	assert.ErrorAs(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: "+15550000000"}), &limitErr)
+}
+
+func TestUserService_VerifyLoginOTP(t *testing.T) {
+	service, mockRepo, mockAuth, queue := setupOTPLogin(t)
+	ctx := context.Background()
+
+	user := &models.User{ID: 1, Email: "test@example.com", Phone: "+15551234567", IsActive: true}
+	mockRepo.On("GetByPhone", ctx, user.Phone).Return(user, nil)
+	require.NoError(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: user.Phone}))
+	code := textedCode(t, queue, user.Phone)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	_, err := service.VerifyLoginOTP(ctx, &models.OTPVerifyRequest{PhoneNumber: user.Phone, Code: wrong})
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+
+	mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil).Once()
+	mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil).Once()
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+	resp, err := service.VerifyLoginOTP(ctx, &models.OTPVerifyRequest{PhoneNumber: user.Phone, Code: code})
+	require.NoError(t, err)
+	assert.Equal(t, "token123", resp.AccessToken)
+	assert.Equal(t, "refresh123", resp.RefreshToken)
+	mockAuth.AssertExpectations(t)
+
+	// Codes are single use
+	_, err = service.VerifyLoginOTP(ctx, &models.OTPVerifyRequest{PhoneNumber: user.Phone, Code: code})
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+
+	// Too many wrong guesses discard the code
+	require.NoError(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: user.Phone}))
+	code = textedCode(t, queue, user.Phone)
+	wrong = "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < service.cfg.OTP.MaxAttempts; i++ {
+		_, err = service.VerifyLoginOTP(ctx, &models.OTPVerifyRequest{PhoneNumber: user.Phone, Code: wrong})
+		assert.ErrorIs(t, err, ErrInvalidOTP)
+	}
+	_, err = service.VerifyLoginOTP(ctx, &models.OTPVerifyRequest{PhoneNumber: user.Phone, Code: code})
+	assert.ErrorIs(t, err, ErrInvalidOTP)
+	mockRepo.AssertNumberOfCalls(t, "UpdateLastLogin", 1)
+}
+
+func TestUserService_VerifyLoginOTP_TwoFactor(t *testing.T) {
+	service, mockRepo, _, queue := setupOTPLogin(t)
+	service.cfg.TwoFactor.ChallengeTTL = time.Minute
+	ctx := context.Background()
+
+	user := &models.User{ID: 1, Email: "test@example.com", Phone: "+15551234567", IsActive: true, TOTPSecret: "secret", TOTPEnabled: true}
+	mockRepo.On("GetByPhone", ctx, user.Phone).Return(user, nil)
+	require.NoError(t, service.RequestLoginOTP(ctx, &models.OTPRequest{PhoneNumber: user.Phone}))
+
+	// The texted code replaces the password, not the second factor
+	resp, err := service.VerifyLoginOTP(ctx, &models.OTPVerifyRequest{PhoneNumber: user.Phone, Code: textedCode(t, queue, user.Phone)})
+	require.NoError(t, err)
+	assert.True(t, resp.TwoFactorRequired)
+	assert.NotEmpty(t, resp.ChallengeToken)
+	assert.Empty(t, resp.AccessToken)
+}
@@ -0,0 +1,133 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/mailer"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RequirePasswordChange makes a user change their password before they can use the API again,
+// such as after their credentials leaked. Every session holding a refresh token is signed out,
+// and the flag is cleared once the password is reset.
+func (s *userService) RequirePasswordChange(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for password change requirement")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.MustChangePassword = true
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to require password change")
+		return nil, fmt.Errorf("failed to require password change: %w", err)
+	}
+
+	if err := s.authSvc.RevokeRefreshTokens(ctx, id); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to revoke refresh tokens of user required to change password")
+	}
+
+	s.log.Security("password_change_required", id).WithField("actor_id", actorID).Warn("Password change required by admin")
+	return user.ToAdminResponse(), nil
+}
+
+// ChangePassword sets a new password for a signed-in user who confirmed their current one, and
+// clears a password change required by an admin. Every session is signed out: refresh tokens are
+// revoked, as is the access token identified by tokenID, and the caller gets a new token pair.
+// Access tokens of other sessions remain valid until they expire.
+func (s *userService) ChangePassword(ctx context.Context, userID uint, tokenID string, expiresAt time.Time, req *models.PasswordChangeRequest) (*models.LoginResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for password change")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.backend.VerifyPassword(ctx, user, req.CurrentPassword); err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) {
+			s.log.WithError(err).WithField("user_id", userID).Error("Failed to check password")
+			return nil, fmt.Errorf("failed to check password: %w", err)
+		}
+		s.log.Security("password_change_failed", userID).Warn("Wrong current password given to change password")
+		return nil, ErrInvalidPassword
+	}
+	if req.NewPassword == req.CurrentPassword {
+		return nil, ErrPasswordUnchanged
+	}
+	if err := s.checkBreachedPassword(ctx, req.NewPassword); err != nil {
+		return nil, err
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.NewPassword), bcrypt.DefaultCost)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to hash password")
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	now := time.Now()
+	user.Password = string(hashedPassword)
+	user.PasswordChangedAt = &now
+	user.MustChangePassword = false
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to change password")
+		return nil, fmt.Errorf("failed to change password: %w", err)
+	}
+
+	if err := s.authSvc.RevokeRefreshTokens(ctx, userID); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke refresh tokens after password change")
+	}
+	if tokenID != "" {
+		if err := s.authSvc.RevokeAccessToken(ctx, tokenID, expiresAt); err != nil {
+			s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke access token after password change")
+		}
+	}
+
+	token, err := s.authSvc.GenerateToken(user.ID, user.Email, user.IsAdmin)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to generate token")
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+	refreshToken, err := s.authSvc.IssueRefreshToken(ctx, user.ID, false)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to issue refresh token")
+		return nil, fmt.Errorf("failed to issue refresh token: %w", err)
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password of your account was just changed, and your other devices were signed out. If this wasn't you, reset your password right away and contact support.\n",
+			user.FirstName),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Warn("Failed to queue password changed email")
+	}
+
+	s.log.Security("password_changed", userID).Warn("Password changed by the user")
+	s.recordActivity(ctx, userID, models.AuditPasswordChanged, nil)
+	return &models.LoginResponse{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.cfg.JWT.Expiry.Seconds()),
+		User:         user.ToResponse(),
+	}, nil
+}
+
+// MustChangePassword reports whether a user has to change their password before using the API
+func (s *userService) MustChangePassword(ctx context.Context, userID uint) (bool, error) {
+	mustChange, err := s.userRepo.MustChangePassword(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check password change requirement: %w", err)
+	}
+	return mustChange, nil
+}
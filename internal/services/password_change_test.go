@@ -0,0 +1,82 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_RequirePasswordChange(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	ctx := context.Background()
+	admin := uint(9)
+
+	t.Run("flags the user and signs them out", func(t *testing.T) {
+		user := &models.User{ID: 2, Email: "user@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, uint(2)).Return(nil).Once()
+
+		result, err := service.RequirePasswordChange(ctx, admin, 2)
+
+		require.NoError(t, err)
+		assert.True(t, result.MustChangePassword)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(3)).Return(nil, nil).Once()
+
+		_, err := service.RequirePasswordChange(ctx, admin, 3)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestUserService_ChangePassword(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	ctx := context.Background()
+	hash, err := bcrypt.GenerateFromPassword([]byte("old-password"), bcrypt.MinCost)
+	require.NoError(t, err)
+	user := &models.User{ID: 2, Email: "user@example.com", Password: string(hash), IsActive: true, MustChangePassword: true}
+	expiresAt := time.Now().Add(time.Hour)
+
+	t.Run("wrong current password", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+
+		_, err := service.ChangePassword(ctx, 2, "token-id", expiresAt, &models.PasswordChangeRequest{CurrentPassword: "wrong", NewPassword: "new-password"})
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+	})
+
+	t.Run("same password", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+
+		_, err := service.ChangePassword(ctx, 2, "token-id", expiresAt, &models.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "old-password"})
+		assert.ErrorIs(t, err, ErrPasswordUnchanged)
+	})
+
+	t.Run("changes the password and signs out other sessions", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, uint(2)).Return(nil).Once()
+		mockAuth.On("RevokeAccessToken", ctx, "token-id", expiresAt).Return(nil).Once()
+		mockAuth.On("GenerateToken", uint(2), user.Email, false).Return("access-token", nil).Once()
+		mockAuth.On("IssueRefreshToken", ctx, uint(2), false).Return("refresh-token", nil).Once()
+
+		response, err := service.ChangePassword(ctx, 2, "token-id", expiresAt, &models.PasswordChangeRequest{CurrentPassword: "old-password", NewPassword: "new-password"})
+		require.NoError(t, err)
+		assert.Equal(t, "access-token", response.AccessToken)
+		assert.Equal(t, "refresh-token", response.RefreshToken)
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("new-password")))
+		assert.False(t, user.MustChangePassword)
+		assert.NotNil(t, user.PasswordChangedAt)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+}
@@ -0,0 +1,134 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/mailer"
+	"gbt-be-template/pkg/utils"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// RequestPasswordReset emails a password reset link. It succeeds without sending anything for
+// unknown emails, so the response doesn't reveal which accounts exist.
+func (s *userService) RequestPasswordReset(ctx context.Context, req *models.PasswordForgotRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get user for password reset request")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil
+	}
+
+	// Only the most recent link works
+	if err := s.tokenRepo.InvalidateForUser(ctx, user.ID, models.EmailTokenPasswordReset); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to invalidate password reset tokens")
+		return fmt.Errorf("failed to invalidate password reset tokens: %w", err)
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate password reset token: %w", err)
+	}
+	if err := s.tokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   models.EmailTokenPasswordReset,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.PasswordReset.TokenTTL),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to store password reset token")
+		return fmt.Errorf("failed to store password reset token: %w", err)
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your password",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reset your password. Open this link to choose a new one:\n\n%s/reset-password?token=%s\n\nThe link expires in %s. If you didn't ask for this, you can ignore this email and your password stays the same.\n",
+			user.FirstName, s.cfg.Mail.LinkBaseURL, token, s.cfg.PasswordReset.TokenTTL),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to queue password reset email")
+		return fmt.Errorf("failed to queue password reset email: %w", err)
+	}
+
+	s.log.Security("password_reset_requested", user.ID).Info("Password reset link sent")
+	return nil
+}
+
+// ResetPassword sets a new password with an emailed reset link. It also lifts a lockout, since
+// the user proved they own the email, clears a password change required by an admin, and
+// signs out every session holding a refresh token.
+func (s *userService) ResetPassword(ctx context.Context, req *models.PasswordResetRequest) error {
+	token, err := s.tokenRepo.GetByHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get password reset token")
+		return fmt.Errorf("failed to get password reset token: %w", err)
+	}
+	if token == nil || token.Purpose != models.EmailTokenPasswordReset || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return ErrInvalidEmailToken
+	}
+
+	// Checked before using up the link, so the user can pick another password with it
+	if err := s.checkBreachedPassword(ctx, req.Password); err != nil {
+		return err
+	}
+
+	marked, err := s.tokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return fmt.Errorf("failed to use password reset token: %w", err)
+	}
+	if !marked {
+		return ErrInvalidEmailToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", token.UserID).Error("Failed to get user for password reset")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrInvalidEmailToken
+	}
+
+	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to hash password")
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+	now := time.Now()
+	user.Password = string(hashedPassword)
+	user.PasswordChangedAt = &now
+	user.FailedLoginAttempts = 0
+	user.LockedUntil = nil
+	user.MustChangePassword = false
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to reset password")
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	// Links requested before this one must not change the password again
+	if err := s.tokenRepo.InvalidateForUser(ctx, user.ID, models.EmailTokenPasswordReset); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to invalidate password reset tokens")
+	}
+	if err := s.authSvc.RevokeRefreshTokens(ctx, user.ID); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to revoke refresh tokens after password reset")
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Your password was changed",
+		Body: fmt.Sprintf("Hi %s,\n\nThe password of your account was just reset. If this wasn't you, reset it again right away and contact support.\n",
+			user.FirstName),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to queue password changed email")
+	}
+
+	s.log.Security("password_reset", user.ID).Warn("Password reset with an emailed link")
+	s.recordActivity(ctx, user.ID, models.AuditPasswordReset, nil)
+	return nil
+}
@@ -0,0 +1,83 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_PasswordReset(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.PasswordReset.TokenTTL = time.Hour
+	service.cfg.Mail.LinkBaseURL = "https://app.example.com"
+	queue := service.queue.(*fakeQueue)
+	ctx := context.Background()
+
+	lockedUntil := time.Now().Add(time.Hour)
+	user := &models.User{ID: 1, Email: "jane@example.com", FirstName: "Jane", Password: "old-hash", IsActive: true, FailedLoginAttempts: 5, LockedUntil: &lockedUntil}
+
+	requestLink := func() string {
+		mockRepo.On("GetByEmail", ctx, user.Email).Return(user, nil).Once()
+		require.NoError(t, service.RequestPasswordReset(ctx, &models.PasswordForgotRequest{Email: user.Email}))
+		require.NotEmpty(t, queue.jobs)
+		job := queue.jobs[len(queue.jobs)-1]
+		require.True(t, strings.HasPrefix(job, JobSendEmail+" "))
+		var msg struct{ Body string }
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(job, JobSendEmail+" ")), &msg))
+		match := regexp.MustCompile(`https://app\.example\.com/reset-password\?token=(\S+)`).FindStringSubmatch(msg.Body)
+		require.Len(t, match, 2)
+		return match[1]
+	}
+
+	t.Run("unknown emails get no link", func(t *testing.T) {
+		mockRepo.On("GetByEmail", ctx, "unknown@example.com").Return(nil, nil).Once()
+		require.NoError(t, service.RequestPasswordReset(ctx, &models.PasswordForgotRequest{Email: "unknown@example.com"}))
+		assert.Empty(t, queue.jobs)
+	})
+
+	t.Run("only the latest link works, once", func(t *testing.T) {
+		stale := requestLink()
+		token := requestLink()
+
+		err := service.ResetPassword(ctx, &models.PasswordResetRequest{Token: stale, Password: "new-password"})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, user.ID).Return(nil).Once()
+		require.NoError(t, service.ResetPassword(ctx, &models.PasswordResetRequest{Token: token, Password: "new-password"}))
+
+		assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(user.Password), []byte("new-password")))
+		assert.Zero(t, user.FailedLoginAttempts)
+		assert.Nil(t, user.LockedUntil)
+		assert.True(t, strings.HasPrefix(queue.jobs[len(queue.jobs)-1], JobSendEmail+" "))
+		assert.Contains(t, queue.jobs[len(queue.jobs)-1], "Your password was changed")
+
+		err = service.ResetPassword(ctx, &models.PasswordResetRequest{Token: token, Password: "another-password"})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("tokens for other purposes are refused", func(t *testing.T) {
+		require.NoError(t, service.tokenRepo.Create(ctx, &models.EmailToken{
+			UserID:    user.ID,
+			Purpose:   models.EmailTokenReactivation,
+			TokenHash: utils.HashToken("reactivation-token"),
+			ExpiresAt: time.Now().Add(time.Hour),
+		}))
+
+		err := service.ResetPassword(ctx, &models.PasswordResetRequest{Token: "reactivation-token", Password: "new-password"})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+	})
+}
@@ -0,0 +1,169 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+)
+
+// permissionService implements the PermissionService interface
+type permissionService struct {
+	permissionRepo repository.PermissionRepository
+	auditRepo      repository.RBACAuditRepository
+	authorizer     Authorizer
+	log            *logger.Logger
+}
+
+// NewPermissionService creates a new permission service. Changes to permissions are recorded in
+// auditRepo.
+func NewPermissionService(permissionRepo repository.PermissionRepository, auditRepo repository.RBACAuditRepository, log *logger.Logger) PermissionService {
+	return &permissionService{
+		permissionRepo: permissionRepo,
+		auditRepo:      auditRepo,
+		log:            log,
+	}
+}
+
+// UseAuthorizer reloads the authorizer whenever the permissions granted to users change
+func (s *permissionService) UseAuthorizer(authorizer Authorizer) {
+	s.authorizer = authorizer
+}
+
+// Create creates a new permission
+func (s *permissionService) Create(ctx context.Context, actorID uint, req *models.PermissionCreateRequest) (*models.PermissionResponse, error) {
+	if err := s.checkNameAvailable(ctx, req.Name, 0); err != nil {
+		return nil, err
+	}
+
+	permission := &models.Permission{
+		Name:        req.Name,
+		Description: req.Description,
+		Resource:    req.Resource,
+		Action:      req.Action,
+	}
+	if err := s.permissionRepo.Create(ctx, permission); err != nil {
+		s.log.WithError(err).WithField("name", req.Name).Error("Failed to create permission")
+		return nil, fmt.Errorf("failed to create permission: %w", err)
+	}
+
+	s.log.WithField("permission_id", permission.ID).Info("Permission created")
+	response := permission.ToResponse()
+	recordRBACAudit(ctx, s.auditRepo, s.log, actorID, models.RBACAuditPermissionCreated, models.RBACAuditTargetPermission, permission.ID, nil, response)
+	return response, nil
+}
+
+// GetByID returns a permission
+func (s *permissionService) GetByID(ctx context.Context, id uint) (*models.PermissionResponse, error) {
+	permission, err := s.getPermission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return permission.ToResponse(), nil
+}
+
+// List returns all permissions
+func (s *permissionService) List(ctx context.Context) ([]*models.PermissionResponse, error) {
+	permissions, err := s.permissionRepo.List(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to list permissions")
+		return nil, fmt.Errorf("failed to list permissions: %w", err)
+	}
+
+	responses := make([]*models.PermissionResponse, len(permissions))
+	for i, permission := range permissions {
+		responses[i] = permission.ToResponse()
+	}
+	return responses, nil
+}
+
+// Update changes the fields of a permission given in the request
+func (s *permissionService) Update(ctx context.Context, actorID, id uint, req *models.PermissionUpdateRequest) (*models.PermissionResponse, error) {
+	permission, err := s.getPermission(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := permission.ToResponse()
+
+	if req.Name != nil && *req.Name != permission.Name {
+		if err := s.checkNameAvailable(ctx, *req.Name, permission.ID); err != nil {
+			return nil, err
+		}
+		permission.Name = *req.Name
+	}
+	if req.Description != nil {
+		permission.Description = *req.Description
+	}
+	if req.Resource != nil {
+		permission.Resource = *req.Resource
+	}
+	if req.Action != nil {
+		permission.Action = *req.Action
+	}
+
+	if err := s.permissionRepo.Update(ctx, permission); err != nil {
+		s.log.WithError(err).WithField("permission_id", id).Error("Failed to update permission")
+		return nil, fmt.Errorf("failed to update permission: %w", err)
+	}
+
+	s.log.WithField("permission_id", id).Info("Permission updated")
+	response := permission.ToResponse()
+	recordRBACAudit(ctx, s.auditRepo, s.log, actorID, models.RBACAuditPermissionUpdated, models.RBACAuditTargetPermission, id, before, response)
+	reloadAuthorizer(ctx, s.authorizer, s.log)
+	return response, nil
+}
+
+// Delete deletes a permission, taking it away from the roles that had it
+func (s *permissionService) Delete(ctx context.Context, actorID, id uint) error {
+	permission, err := s.getPermission(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.permissionRepo.Delete(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("permission_id", id).Error("Failed to delete permission")
+		return fmt.Errorf("failed to delete permission: %w", err)
+	}
+	if !deleted {
+		return ErrPermissionNotFound
+	}
+
+	s.log.WithField("permission_id", id).Info("Permission deleted")
+	recordRBACAudit(ctx, s.auditRepo, s.log, actorID, models.RBACAuditPermissionDeleted, models.RBACAuditTargetPermission, id, permission.ToResponse(), nil)
+	reloadAuthorizer(ctx, s.authorizer, s.log)
+	return nil
+}
+
+// Catalog returns the resource/action pairs the application knows about, whether or not a
+// permission was created for them
+func (s *permissionService) Catalog() []models.PermissionCatalogEntry {
+	return models.PermissionCatalog()
+}
+
+// getPermission returns a permission, or ErrPermissionNotFound
+func (s *permissionService) getPermission(ctx context.Context, id uint) (*models.Permission, error) {
+	permission, err := s.permissionRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("permission_id", id).Error("Failed to get permission")
+		return nil, fmt.Errorf("failed to get permission: %w", err)
+	}
+	if permission == nil {
+		return nil, ErrPermissionNotFound
+	}
+	return permission, nil
+}
+
+// checkNameAvailable returns ErrPermissionNameTaken when a permission other than exceptID has the name
+func (s *permissionService) checkNameAvailable(ctx context.Context, name string, exceptID uint) error {
+	existing, err := s.permissionRepo.GetByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check permission name: %w", err)
+	}
+	if existing != nil && existing.ID != exceptID {
+		return ErrPermissionNameTaken
+	}
+	return nil
+}
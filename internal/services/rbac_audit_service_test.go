@@ -0,0 +1,101 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeRBACAuditRepository keeps audit events in memory, oldest first
+type fakeRBACAuditRepository struct {
+	events []*models.RBACAuditEvent
+}
+
+func (r *fakeRBACAuditRepository) Create(ctx context.Context, event *models.RBACAuditEvent) error {
+	event.ID = uint(len(r.events) + 1)
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *fakeRBACAuditRepository) List(ctx context.Context, filter models.RBACAuditFilter, limit, offset int) ([]*models.RBACAuditEvent, int64, error) {
+	var events []*models.RBACAuditEvent
+	for i := len(r.events) - 1; i >= 0; i-- {
+		event := r.events[i]
+		if (filter.ActorID == 0 || event.ActorID == filter.ActorID) &&
+			(filter.Action == "" || event.Action == filter.Action) &&
+			(filter.TargetType == "" || event.TargetType == filter.TargetType) &&
+			(filter.TargetID == 0 || event.TargetID == filter.TargetID) {
+			events = append(events, event)
+		}
+	}
+	total := int64(len(events))
+	events = events[min(offset, len(events)):]
+	return events[:min(limit, len(events))], total, nil
+}
+
+func TestRBACAudit(t *testing.T) {
+	ctx := context.Background()
+	auditRepo := &fakeRBACAuditRepository{}
+	roles, permissions := setupRoleServices()
+	roles.auditRepo, permissions.auditRepo = auditRepo, auditRepo
+	audit := NewRBACAuditService(auditRepo, logger.New("error", "text"))
+
+	read, err := permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserRead, Resource: "user", Action: "read"})
+	require.NoError(t, err)
+	role, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: models.RoleModerator})
+	require.NoError(t, err)
+	_, err = roles.SetPermissions(ctx, 2, &models.AssignPermissionRequest{RoleID: role.ID, PermissionIDs: []uint{read.ID}})
+	require.NoError(t, err)
+	_, err = roles.AssignToUser(ctx, 3, &models.AssignRoleRequest{UserID: 1, RoleIDs: []uint{role.ID}})
+	require.NoError(t, err)
+	_, err = roles.AssignToUser(ctx, 3, &models.AssignRoleRequest{UserID: 1, RoleIDs: []uint{role.ID}})
+	require.NoError(t, err)
+	require.NoError(t, roles.RemoveFromUser(ctx, 3, 1, role.ID))
+	require.NoError(t, roles.Delete(ctx, 2, role.ID))
+
+	// Failed changes aren't recorded
+	_, err = roles.Create(ctx, 2, &models.RoleCreateRequest{Name: models.RoleModerator, ParentRoleID: &role.ID})
+	assert.ErrorIs(t, err, ErrParentRoleNotFound)
+
+	events, total, err := audit.List(ctx, models.RBACAuditFilter{}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(6), total, "assigning a role the user already has changes nothing")
+	actions := make([]string, len(events))
+	for i, event := range events {
+		actions[i] = event.Action
+	}
+	assert.Equal(t, []string{
+		models.RBACAuditRoleDeleted,
+		models.RBACAuditRoleRemoved,
+		models.RBACAuditRolesAssigned,
+		models.RBACAuditRolePermissionsSet,
+		models.RBACAuditRoleCreated,
+		models.RBACAuditPermissionCreated,
+	}, actions)
+
+	setPermissions := events[3]
+	assert.Equal(t, uint(2), setPermissions.ActorID)
+	assert.Equal(t, role.ID, setPermissions.TargetID)
+	assert.NotContains(t, string(setPermissions.Before), models.PermissionUserRead)
+	assert.Contains(t, string(setPermissions.After), models.PermissionUserRead)
+
+	assigned := events[2]
+	assert.Equal(t, models.RBACAuditTargetUser, assigned.TargetType)
+	assert.Equal(t, uint(1), assigned.TargetID)
+	assert.JSONEq(t, `[]`, string(assigned.Before))
+	assert.JSONEq(t, `["moderator"]`, string(assigned.After))
+
+	assert.Nil(t, events[0].After)
+	assert.Nil(t, events[4].Before)
+
+	events, total, err = audit.List(ctx, models.RBACAuditFilter{ActorID: 3}, 1, 1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), total)
+	require.Len(t, events, 1)
+	assert.Equal(t, models.RBACAuditRoleRemoved, events[0].Action)
+}
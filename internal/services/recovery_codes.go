@@ -0,0 +1,136 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/mailer"
+	"gbt-be-template/pkg/utils"
+)
+
+const (
+	// recoveryCodeCount is how many recovery codes a user gets at a time
+	recoveryCodeCount = 10
+	// recoveryCodeAlphabet leaves out characters that are easily mistaken for one another
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+	// recoveryCodeLength is the number of characters of a code, shown in two dash-separated halves
+	recoveryCodeLength = 10
+)
+
+// issueRecoveryCodes replaces the recovery codes of a user with a new set and returns the codes
+func (s *userService) issueRecoveryCodes(ctx context.Context, userID uint) ([]string, error) {
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		code, err := generateRecoveryCode()
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %w", err)
+		}
+		codes[i] = code
+		hashes[i] = utils.HashToken(normalizeRecoveryCode(code))
+	}
+
+	if err := s.recoveryRepo.Replace(ctx, userID, hashes); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to store recovery codes")
+		return nil, fmt.Errorf("failed to store recovery codes: %w", err)
+	}
+	return codes, nil
+}
+
+// useRecoveryCode redeems a recovery code in place of a TOTP code. The user is emailed, since a
+// recovery code in the wrong hands gets past two-factor authentication.
+func (s *userService) useRecoveryCode(ctx context.Context, user *models.User, code string) error {
+	used, err := s.recoveryRepo.Use(ctx, user.ID, utils.HashToken(normalizeRecoveryCode(code)))
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to use recovery code")
+		return fmt.Errorf("failed to check recovery code: %w", err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+
+	remaining, err := s.recoveryRepo.CountUnused(ctx, user.ID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to count recovery codes")
+	}
+	s.log.Security("recovery_code_used", user.ID).WithField("remaining", remaining).Warn("Recovery code used to log in")
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "A recovery code was used to log in",
+		Body: fmt.Sprintf("Hi %s,\n\nA recovery code was just used to log in to your account. You have %d recovery codes left.\n\nIf this was you, consider setting up your authenticator app again and generating new codes. If not, reset your password right away.\n",
+			user.FirstName, remaining),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to queue recovery code email")
+	}
+	return nil
+}
+
+// RecoveryCodesStatus returns how many recovery codes a user has left
+func (s *userService) RecoveryCodesStatus(ctx context.Context, userID uint) (*models.RecoveryCodesStatus, error) {
+	user, err := s.getUserForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	remaining, err := s.recoveryRepo.CountUnused(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to count recovery codes")
+		return nil, fmt.Errorf("failed to count recovery codes: %w", err)
+	}
+	return &models.RecoveryCodesStatus{Remaining: remaining}, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user, used up or not. A current TOTP
+// code is required, so a stolen session alone can't obtain codes.
+func (s *userService) RegenerateRecoveryCodes(ctx context.Context, userID uint, req *models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error) {
+	user, err := s.getUserForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !user.TOTPEnabled {
+		return nil, ErrTwoFactorNotEnabled
+	}
+	if err := s.checkTwoFactorCode(ctx, user, req.Code); err != nil {
+		return nil, err
+	}
+
+	codes, err := s.issueRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Security("recovery_codes_regenerated", userID).Info("Recovery codes regenerated")
+	return &models.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// generateRecoveryCode returns a random code such as "k7m2p-x9qrt"
+func generateRecoveryCode() (string, error) {
+	alphabetSize := big.NewInt(int64(len(recoveryCodeAlphabet)))
+	var b strings.Builder
+	for i := 0; i < recoveryCodeLength; i++ {
+		if i == recoveryCodeLength/2 {
+			b.WriteByte('-')
+		}
+		n, err := rand.Int(rand.Reader, alphabetSize)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(recoveryCodeAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
+
+// normalizeRecoveryCode ignores case, dashes and spaces, so codes can be typed as the user likes
+func normalizeRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
@@ -0,0 +1,135 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/mailer"
+	"gbt-be-template/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeRecoveryCodeRepository keeps recovery codes in memory, keyed by user and hash
+type fakeRecoveryCodeRepository struct {
+	codes map[uint]map[string]bool // Whether each code was used
+}
+
+func (r *fakeRecoveryCodeRepository) Replace(ctx context.Context, userID uint, codeHashes []string) error {
+	if r.codes == nil {
+		r.codes = make(map[uint]map[string]bool)
+	}
+	r.codes[userID] = make(map[string]bool)
+	for _, hash := range codeHashes {
+		r.codes[userID][hash] = false
+	}
+	return nil
+}
+
+func (r *fakeRecoveryCodeRepository) Use(ctx context.Context, userID uint, codeHash string) (bool, error) {
+	used, ok := r.codes[userID][codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	r.codes[userID][codeHash] = true
+	return true, nil
+}
+
+func (r *fakeRecoveryCodeRepository) CountUnused(ctx context.Context, userID uint) (int64, error) {
+	var count int64
+	for _, used := range r.codes[userID] {
+		if !used {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *fakeRecoveryCodeRepository) DeleteByUser(ctx context.Context, userID uint) error {
+	delete(r.codes, userID)
+	return nil
+}
+
+func TestUserService_RecoveryCodes(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.TwoFactor.ChallengeTTL = time.Minute
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{
+		ID:          1,
+		Email:       "test@example.com",
+		Password:    string(hashedPassword),
+		IsActive:    true,
+		TOTPSecret:  secret,
+		TOTPEnabled: true,
+	}
+	mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("UseTOTPStep", ctx, user.ID, mock.AnythingOfType("int64")).Return(true, nil)
+	mockRepo.On("RecordFailedLogin", ctx, user.ID).Return(1, nil)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil)
+	mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil)
+
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	recovery, err := service.RegenerateRecoveryCodes(ctx, user.ID, &models.TwoFactorCodeRequest{Code: code})
+	require.NoError(t, err)
+	require.Len(t, recovery.Codes, recoveryCodeCount)
+	assert.Regexp(t, `^[a-z2-9]{5}-[a-z2-9]{5}$`, recovery.Codes[0])
+
+	verify := func(recoveryCode string) (*models.LoginResponse, error) {
+		challenge, err := service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "password123"})
+		require.NoError(t, err)
+		require.True(t, challenge.TwoFactorRequired)
+		return service.VerifyTwoFactor(ctx, &models.TwoFactorVerifyRequest{ChallengeToken: challenge.ChallengeToken, RecoveryCode: recoveryCode})
+	}
+
+	t.Run("recovery code completes the login once", func(t *testing.T) {
+		// Codes may be typed in upper case and with a space for the dash
+		typed := strings.ToUpper(recovery.Codes[0][:5] + " " + recovery.Codes[0][6:])
+		resp, err := verify(typed)
+		require.NoError(t, err)
+		assert.Equal(t, "token123", resp.AccessToken)
+
+		_, err = verify(recovery.Codes[0])
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	})
+
+	t.Run("the user is emailed about it", func(t *testing.T) {
+		queue := service.queue.(*fakeQueue)
+		require.NotEmpty(t, queue.jobs)
+		var msg mailer.Message
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(queue.jobs[0], JobSendEmail+" ")), &msg))
+		assert.Equal(t, user.Email, msg.To)
+		assert.Contains(t, msg.Body, "9 recovery codes left")
+	})
+
+	t.Run("remaining codes are counted", func(t *testing.T) {
+		status, err := service.RecoveryCodesStatus(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(recoveryCodeCount-1), status.Remaining)
+	})
+
+	t.Run("regenerating replaces the old codes", func(t *testing.T) {
+		old := recovery.Codes[1]
+		_, err := service.RegenerateRecoveryCodes(ctx, user.ID, &models.TwoFactorCodeRequest{Code: code})
+		require.NoError(t, err)
+		_, err = verify(old)
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+
+		status, err := service.RecoveryCodesStatus(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(recoveryCodeCount), status.Remaining)
+	})
+}
@@ -0,0 +1,445 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+)
+
+// roleService implements the RoleService interface
+type roleService struct {
+	roleRepo       repository.RoleRepository
+	permissionRepo repository.PermissionRepository
+	userRepo       repository.UserRepository
+	auditRepo      repository.RBACAuditRepository
+	authorizer     Authorizer
+	log            *logger.Logger
+}
+
+// NewRoleService creates a new role service. Changes to roles and to the roles of users are
+// recorded in auditRepo.
+func NewRoleService(roleRepo repository.RoleRepository, permissionRepo repository.PermissionRepository, userRepo repository.UserRepository, auditRepo repository.RBACAuditRepository, log *logger.Logger) RoleService {
+	return &roleService{
+		roleRepo:       roleRepo,
+		permissionRepo: permissionRepo,
+		userRepo:       userRepo,
+		auditRepo:      auditRepo,
+		log:            log,
+	}
+}
+
+// UseAuthorizer reloads the authorizer whenever the permissions granted to users change
+func (s *roleService) UseAuthorizer(authorizer Authorizer) {
+	s.authorizer = authorizer
+}
+
+// Create creates a new, active role without permissions of its own
+func (s *roleService) Create(ctx context.Context, actorID uint, req *models.RoleCreateRequest) (*models.RoleResponse, error) {
+	if err := s.checkNameAvailable(ctx, req.Name, 0); err != nil {
+		return nil, err
+	}
+
+	if req.ParentRoleID != nil {
+		if err := s.checkParent(ctx, 0, *req.ParentRoleID); err != nil {
+			return nil, err
+		}
+	}
+
+	role := &models.Role{
+		Name:         req.Name,
+		Description:  req.Description,
+		IsActive:     true,
+		ParentRoleID: req.ParentRoleID,
+	}
+	if err := s.roleRepo.Create(ctx, role); err != nil {
+		s.log.WithError(err).WithField("name", req.Name).Error("Failed to create role")
+		return nil, fmt.Errorf("failed to create role: %w", err)
+	}
+
+	s.log.WithField("role_id", role.ID).Info("Role created")
+	response := role.ToResponse()
+	recordRBACAudit(ctx, s.auditRepo, s.log, actorID, models.RBACAuditRoleCreated, models.RBACAuditTargetRole, role.ID, nil, response)
+	return response, nil
+}
+
+// GetByID returns a role with its permissions
+func (s *roleService) GetByID(ctx context.Context, id uint) (*models.RoleResponse, error) {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return role.ToResponse(), nil
+}
+
+// List returns all roles with their permissions
+func (s *roleService) List(ctx context.Context) ([]*models.RoleResponse, error) {
+	roles, err := s.roleRepo.List(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to list roles")
+		return nil, fmt.Errorf("failed to list roles: %w", err)
+	}
+
+	responses := make([]*models.RoleResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = role.ToResponse()
+	}
+	return responses, nil
+}
+
+// Update changes the fields of a role given in the request
+func (s *roleService) Update(ctx context.Context, actorID, id uint, req *models.RoleUpdateRequest) (*models.RoleResponse, error) {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	before := role.ToResponse()
+
+	if req.Name != nil && *req.Name != role.Name {
+		if err := s.checkNameAvailable(ctx, *req.Name, role.ID); err != nil {
+			return nil, err
+		}
+		role.Name = *req.Name
+	}
+	if req.Description != nil {
+		role.Description = *req.Description
+	}
+	if req.IsActive != nil {
+		role.IsActive = *req.IsActive
+	}
+	if req.ParentRoleID != nil {
+		if *req.ParentRoleID == 0 {
+			role.ParentRoleID = nil
+		} else {
+			if err := s.checkParent(ctx, role.ID, *req.ParentRoleID); err != nil {
+				return nil, err
+			}
+			role.ParentRoleID = req.ParentRoleID
+		}
+	}
+
+	if err := s.roleRepo.Update(ctx, role); err != nil {
+		s.log.WithError(err).WithField("role_id", id).Error("Failed to update role")
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	s.log.WithField("role_id", id).Info("Role updated")
+	response := role.ToResponse()
+	recordRBACAudit(ctx, s.auditRepo, s.log, actorID, models.RBACAuditRoleUpdated, models.RBACAuditTargetRole, id, before, response)
+	reloadAuthorizer(ctx, s.authorizer, s.log)
+	return response, nil
+}
+
+// Delete deletes a role, taking it away from the users it was assigned to
+func (s *roleService) Delete(ctx context.Context, actorID, id uint) error {
+	role, err := s.getRole(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.roleRepo.Delete(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("role_id", id).Error("Failed to delete role")
+		return fmt.Errorf("failed to delete role: %w", err)
+	}
+	if !deleted {
+		return ErrRoleNotFound
+	}
+
+	s.log.WithField("role_id", id).Info("Role deleted")
+	recordRBACAudit(ctx, s.auditRepo, s.log, actorID, models.RBACAuditRoleDeleted, models.RBACAuditTargetRole, id, role.ToResponse(), nil)
+	reloadAuthorizer(ctx, s.authorizer, s.log)
+	return nil
+}
+
+// SetPermissions replaces the permissions of a role with the requested ones
+func (s *roleService) SetPermissions(ctx context.Context, actorID uint, req *models.AssignPermissionRequest) (*models.RoleResponse, error) {
+	role, err := s.getRole(ctx, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := slices.Clone(req.PermissionIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	permissions, err := s.permissionRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get permissions: %w", err)
+	}
+	if len(permissions) != len(ids) {
+		return nil, ErrPermissionNotFound
+	}
+
+	if err := s.roleRepo.ReplacePermissions(ctx, req.RoleID, ids); err != nil {
+		s.log.WithError(err).WithField("role_id", req.RoleID).Error("Failed to set role permissions")
+		return nil, fmt.Errorf("failed to set role permissions: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"role_id":        req.RoleID,
+		"permission_ids": ids,
+	}).Info("Role permissions set")
+	reloadAuthorizer(ctx, s.authorizer, s.log)
+	response, err := s.GetByID(ctx, req.RoleID)
+	if err != nil {
+		return nil, err
+	}
+	recordRBACAudit(ctx, s.auditRepo, s.log, actorID, models.RBACAuditRolePermissionsSet, models.RBACAuditTargetRole, req.RoleID, role.ToResponse(), response)
+	return response, nil
+}
+
+// ListForUser returns the roles assigned to a user with their permissions
+func (s *roleService) ListForUser(ctx context.Context, userID uint) ([]*models.RoleResponse, error) {
+	if err := s.checkUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	roles, err := s.roleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list user roles")
+		return nil, fmt.Errorf("failed to list user roles: %w", err)
+	}
+
+	responses := make([]*models.RoleResponse, len(roles))
+	for i, role := range roles {
+		responses[i] = role.ToResponse()
+	}
+	return responses, nil
+}
+
+// AssignToUser gives the requested roles to a user, on top of the roles they already have. It
+// returns all roles of the user.
+func (s *roleService) AssignToUser(ctx context.Context, actorID uint, req *models.AssignRoleRequest) ([]*models.RoleResponse, error) {
+	if err := s.checkUserExists(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+
+	ids := slices.Clone(req.RoleIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	roles, err := s.roleRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	if len(roles) != len(ids) {
+		return nil, ErrRoleNotFound
+	}
+	before, err := userRoleNames(ctx, s.roleRepo, req.UserID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.AssignToUser(ctx, req.UserID, ids); err != nil {
+		s.log.WithError(err).WithField("user_id", req.UserID).Error("Failed to assign roles")
+		return nil, fmt.Errorf("failed to assign roles: %w", err)
+	}
+
+	s.log.Security("roles_assigned", req.UserID).WithFields(map[string]interface{}{
+		"actor_id": actorID,
+		"role_ids": ids,
+	}).Info("Roles assigned to user")
+	recordUserRoles(ctx, s.roleRepo, s.auditRepo, s.log, actorID, models.RBACAuditRolesAssigned, req.UserID, before)
+	reloadAuthorizer(ctx, s.authorizer, s.log)
+	if err := s.syncAdminFlag(ctx, req.UserID); err != nil {
+		return nil, err
+	}
+	return s.ListForUser(ctx, req.UserID)
+}
+
+// RemoveFromUser takes a role away from a user
+func (s *roleService) RemoveFromUser(ctx context.Context, actorID, userID, roleID uint) error {
+	if err := s.checkUserExists(ctx, userID); err != nil {
+		return err
+	}
+
+	before, err := userRoleNames(ctx, s.roleRepo, userID)
+	if err != nil {
+		return err
+	}
+
+	removed, err := s.roleRepo.RemoveFromUser(ctx, userID, roleID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to remove role")
+		return fmt.Errorf("failed to remove role: %w", err)
+	}
+	if !removed {
+		return ErrRoleNotAssigned
+	}
+
+	s.log.Security("role_removed", userID).WithFields(map[string]interface{}{
+		"actor_id": actorID,
+		"role_id":  roleID,
+	}).Info("Role removed from user")
+	recordUserRoles(ctx, s.roleRepo, s.auditRepo, s.log, actorID, models.RBACAuditRoleRemoved, userID, before)
+	reloadAuthorizer(ctx, s.authorizer, s.log)
+	return s.syncAdminFlag(ctx, userID)
+}
+
+// recordUserRoles records a change to the roles of a user, given their role names before it.
+// Nothing is recorded when the roles didn't change.
+func recordUserRoles(ctx context.Context, roleRepo repository.RoleRepository, auditRepo repository.RBACAuditRepository, log *logger.Logger, actorID uint, action string, userID uint, before []string) {
+	after, err := userRoleNames(ctx, roleRepo, userID)
+	if err != nil {
+		log.WithError(err).WithField("user_id", userID).Error("Failed to record RBAC audit event")
+		return
+	}
+	if slices.Equal(before, after) {
+		return
+	}
+	recordRBACAudit(ctx, auditRepo, log, actorID, action, models.RBACAuditTargetUser, userID, before, after)
+}
+
+// IsAdmin reports whether a user holds the active admin role, which backs the IsAdmin flag
+func (s *roleService) IsAdmin(ctx context.Context, userID uint) (bool, error) {
+	return holdsAdminRole(ctx, s.roleRepo, userID)
+}
+
+// syncAdminFlag mirrors the admin role onto the IsAdmin flag of a user, which still goes into
+// access tokens and responses
+func (s *roleService) syncAdminFlag(ctx context.Context, userID uint) error {
+	isAdmin, err := holdsAdminRole(ctx, s.roleRepo, userID)
+	if err != nil {
+		return err
+	}
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || user.IsAdmin == isAdmin {
+		return nil
+	}
+
+	user.IsAdmin = isAdmin
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to update admin flag")
+		return fmt.Errorf("failed to update admin flag: %w", err)
+	}
+	s.log.Security("admin_changed", userID).WithField("is_admin", isAdmin).Info("Admin status changed by the admin role")
+	return nil
+}
+
+// holdsAdminRole reports whether a user holds the active admin role
+func holdsAdminRole(ctx context.Context, roleRepo repository.RoleRepository, userID uint) (bool, error) {
+	roles, err := roleRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to list user roles: %w", err)
+	}
+	return slices.ContainsFunc(roles, func(role *models.Role) bool {
+		return role.Name == models.RoleAdmin && role.IsActive
+	}), nil
+}
+
+// setAdminRole gives the admin role to a user or takes it away, for writes of the deprecated
+// IsAdmin flag, and records the change in auditRepo. Nothing happens when roleRepo is nil or the
+// admin role doesn't exist.
+func setAdminRole(ctx context.Context, roleRepo repository.RoleRepository, auditRepo repository.RBACAuditRepository, log *logger.Logger, actorID, userID uint, isAdmin bool) error {
+	if roleRepo == nil {
+		return nil
+	}
+	role, err := roleRepo.GetByName(ctx, models.RoleAdmin)
+	if err != nil {
+		return fmt.Errorf("failed to get admin role: %w", err)
+	}
+	if role == nil {
+		return nil
+	}
+	before, err := userRoleNames(ctx, roleRepo, userID)
+	if err != nil {
+		return err
+	}
+
+	action := models.RBACAuditRolesAssigned
+	if isAdmin {
+		err = roleRepo.AssignToUser(ctx, userID, []uint{role.ID})
+	} else {
+		action = models.RBACAuditRoleRemoved
+		_, err = roleRepo.RemoveFromUser(ctx, userID, role.ID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update admin role: %w", err)
+	}
+	recordUserRoles(ctx, roleRepo, auditRepo, log, actorID, action, userID, before)
+	return nil
+}
+
+// checkUserExists returns ErrUserNotFound for unknown users
+func (s *roleService) checkUserExists(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for roles")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// getRole returns a role with its permissions, or ErrRoleNotFound
+func (s *roleService) getRole(ctx context.Context, id uint) (*models.Role, error) {
+	role, err := s.roleRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("role_id", id).Error("Failed to get role")
+		return nil, fmt.Errorf("failed to get role: %w", err)
+	}
+	if role == nil {
+		return nil, ErrRoleNotFound
+	}
+	return role, nil
+}
+
+// checkParent returns ErrParentRoleNotFound for unknown parents, and ErrRoleCycle when the role
+// with roleID would end up inheriting from itself
+func (s *roleService) checkParent(ctx context.Context, roleID, parentID uint) error {
+	parent, err := s.roleRepo.GetByID(ctx, parentID)
+	if err != nil {
+		return fmt.Errorf("failed to get parent role: %w", err)
+	}
+	if parent == nil {
+		return ErrParentRoleNotFound
+	}
+
+	ancestors, err := roleAncestors(ctx, s.roleRepo, parent)
+	if err != nil {
+		return err
+	}
+	if parent.ID == roleID || slices.ContainsFunc(ancestors, func(ancestor *models.Role) bool { return ancestor.ID == roleID }) {
+		return ErrRoleCycle
+	}
+	return nil
+}
+
+// roleAncestors returns the roles a role inherits from, nearest first. It stops at a role it has
+// already seen, so a cycle in stored data can't loop forever.
+func roleAncestors(ctx context.Context, roleRepo repository.RoleRepository, role *models.Role) ([]*models.Role, error) {
+	var ancestors []*models.Role
+	seen := map[uint]bool{role.ID: true}
+	for role.ParentRoleID != nil && !seen[*role.ParentRoleID] {
+		parent, err := roleRepo.GetByID(ctx, *role.ParentRoleID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get parent role: %w", err)
+		}
+		if parent == nil {
+			break
+		}
+		seen[parent.ID] = true
+		ancestors = append(ancestors, parent)
+		role = parent
+	}
+	return ancestors, nil
+}
+
+// checkNameAvailable returns ErrRoleNameTaken when a role other than exceptID has the name
+func (s *roleService) checkNameAvailable(ctx context.Context, name string, exceptID uint) error {
+	existing, err := s.roleRepo.GetByName(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to check role name: %w", err)
+	}
+	if existing != nil && existing.ID != exceptID {
+		return ErrRoleNameTaken
+	}
+	return nil
+}
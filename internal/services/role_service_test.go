@@ -0,0 +1,387 @@
+package services
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"testing"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakePermissionRepository keeps permissions in memory
+type fakePermissionRepository struct {
+	permissions map[uint]*models.Permission
+	nextID      uint
+}
+
+func (r *fakePermissionRepository) Create(ctx context.Context, permission *models.Permission) error {
+	if r.permissions == nil {
+		r.permissions = make(map[uint]*models.Permission)
+	}
+	r.nextID++
+	permission.ID = r.nextID
+	copied := *permission
+	r.permissions[permission.ID] = &copied
+	return nil
+}
+
+func (r *fakePermissionRepository) GetByID(ctx context.Context, id uint) (*models.Permission, error) {
+	permission, ok := r.permissions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *permission
+	return &copied, nil
+}
+
+func (r *fakePermissionRepository) GetByName(ctx context.Context, name string) (*models.Permission, error) {
+	for _, permission := range r.permissions {
+		if permission.Name == name {
+			copied := *permission
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakePermissionRepository) List(ctx context.Context) ([]*models.Permission, error) {
+	var permissions []*models.Permission
+	for _, permission := range r.permissions {
+		copied := *permission
+		permissions = append(permissions, &copied)
+	}
+	sort.Slice(permissions, func(i, j int) bool { return permissions[i].ID < permissions[j].ID })
+	return permissions, nil
+}
+
+func (r *fakePermissionRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.Permission, error) {
+	var permissions []*models.Permission
+	for _, id := range ids {
+		if permission, ok := r.permissions[id]; ok {
+			copied := *permission
+			permissions = append(permissions, &copied)
+		}
+	}
+	return permissions, nil
+}
+
+func (r *fakePermissionRepository) Update(ctx context.Context, permission *models.Permission) error {
+	copied := *permission
+	r.permissions[permission.ID] = &copied
+	return nil
+}
+
+func (r *fakePermissionRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	if _, ok := r.permissions[id]; !ok {
+		return false, nil
+	}
+	delete(r.permissions, id)
+	return true, nil
+}
+
+// fakeRoleRepository keeps roles in memory, loading permissions from a fakePermissionRepository
+type fakeRoleRepository struct {
+	roles         map[uint]*models.Role
+	permissionIDs map[uint][]uint
+	userRoleIDs   map[uint][]uint
+	permissions   *fakePermissionRepository
+	nextID        uint
+}
+
+func (r *fakeRoleRepository) Create(ctx context.Context, role *models.Role) error {
+	if r.roles == nil {
+		r.roles = make(map[uint]*models.Role)
+		r.permissionIDs = make(map[uint][]uint)
+	}
+	r.nextID++
+	role.ID = r.nextID
+	copied := *role
+	r.roles[role.ID] = &copied
+	return nil
+}
+
+func (r *fakeRoleRepository) GetByID(ctx context.Context, id uint) (*models.Role, error) {
+	role, ok := r.roles[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *role
+	copied.Permissions = nil
+	permissions, _ := r.permissions.ListByIDs(ctx, r.permissionIDs[id])
+	for _, permission := range permissions {
+		copied.Permissions = append(copied.Permissions, *permission)
+	}
+	return &copied, nil
+}
+
+func (r *fakeRoleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	for _, role := range r.roles {
+		if role.Name == name {
+			return r.GetByID(ctx, role.ID)
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeRoleRepository) List(ctx context.Context) ([]*models.Role, error) {
+	var roles []*models.Role
+	for id := range r.roles {
+		role, _ := r.GetByID(ctx, id)
+		roles = append(roles, role)
+	}
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (r *fakeRoleRepository) Update(ctx context.Context, role *models.Role) error {
+	copied := *role
+	r.roles[role.ID] = &copied
+	return nil
+}
+
+func (r *fakeRoleRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	if _, ok := r.roles[id]; !ok {
+		return false, nil
+	}
+	delete(r.roles, id)
+	delete(r.permissionIDs, id)
+	for _, role := range r.roles {
+		if role.ParentRoleID != nil && *role.ParentRoleID == id {
+			role.ParentRoleID = nil
+		}
+	}
+	return true, nil
+}
+
+func (r *fakeRoleRepository) ReplacePermissions(ctx context.Context, roleID uint, permissionIDs []uint) error {
+	r.permissionIDs[roleID] = append([]uint(nil), permissionIDs...)
+	return nil
+}
+
+func (r *fakeRoleRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.Role, error) {
+	var roles []*models.Role
+	for _, id := range ids {
+		if role, _ := r.GetByID(ctx, id); role != nil {
+			roles = append(roles, role)
+		}
+	}
+	return roles, nil
+}
+
+func (r *fakeRoleRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Role, error) {
+	roles, _ := r.ListByIDs(ctx, r.userRoleIDs[userID])
+	sort.Slice(roles, func(i, j int) bool { return roles[i].Name < roles[j].Name })
+	return roles, nil
+}
+
+func (r *fakeRoleRepository) AssignToUser(ctx context.Context, userID uint, roleIDs []uint) error {
+	if r.userRoleIDs == nil {
+		r.userRoleIDs = make(map[uint][]uint)
+	}
+	for _, roleID := range roleIDs {
+		if !slices.Contains(r.userRoleIDs[userID], roleID) {
+			r.userRoleIDs[userID] = append(r.userRoleIDs[userID], roleID)
+		}
+	}
+	return nil
+}
+
+func (r *fakeRoleRepository) ReplaceUserRoles(ctx context.Context, userID uint, roleIDs []uint) error {
+	if r.userRoleIDs == nil {
+		r.userRoleIDs = make(map[uint][]uint)
+	}
+	r.userRoleIDs[userID] = append([]uint(nil), roleIDs...)
+	return nil
+}
+
+func (r *fakeRoleRepository) RemoveFromUser(ctx context.Context, userID, roleID uint) (bool, error) {
+	i := slices.Index(r.userRoleIDs[userID], roleID)
+	if i < 0 {
+		return false, nil
+	}
+	r.userRoleIDs[userID] = slices.Delete(r.userRoleIDs[userID], i, i+1)
+	return true, nil
+}
+
+func setupRoleServices() (*roleService, *permissionService) {
+	log := logger.New("error", "text")
+	permissionRepo := &fakePermissionRepository{}
+	roleRepo := &fakeRoleRepository{permissions: permissionRepo}
+	userRepo := &MockUserRepository{}
+	userRepo.On("GetByID", mock.Anything, uint(1)).Return(&models.User{ID: 1}, nil)
+	userRepo.On("GetByID", mock.Anything, mock.Anything).Return(nil, nil)
+	return NewRoleService(roleRepo, permissionRepo, userRepo, nil, log).(*roleService), NewPermissionService(permissionRepo, nil, log).(*permissionService)
+}
+
+func TestRoleService(t *testing.T) {
+	ctx := context.Background()
+	roles, permissions := setupRoleServices()
+
+	read, err := permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserRead, Resource: "user", Action: "read"})
+	require.NoError(t, err)
+	update, err := permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserUpdate, Resource: "user", Action: "update"})
+	require.NoError(t, err)
+
+	role, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: models.RoleModerator})
+	require.NoError(t, err)
+	assert.True(t, role.IsActive)
+	_, err = roles.Create(ctx, 2, &models.RoleCreateRequest{Name: models.RoleModerator})
+	assert.ErrorIs(t, err, ErrRoleNameTaken)
+
+	t.Run("permissions are replaced", func(t *testing.T) {
+		role, err := roles.SetPermissions(ctx, 2, &models.AssignPermissionRequest{RoleID: role.ID, PermissionIDs: []uint{read.ID, update.ID, read.ID}})
+		require.NoError(t, err)
+		assert.Len(t, role.Permissions, 2)
+
+		_, err = roles.SetPermissions(ctx, 2, &models.AssignPermissionRequest{RoleID: role.ID, PermissionIDs: []uint{read.ID, 999}})
+		assert.ErrorIs(t, err, ErrPermissionNotFound)
+		_, err = roles.SetPermissions(ctx, 2, &models.AssignPermissionRequest{RoleID: 999, PermissionIDs: []uint{read.ID}})
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+
+	t.Run("roles are updated", func(t *testing.T) {
+		other, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: "editor"})
+		require.NoError(t, err)
+		taken := models.RoleModerator
+		_, err = roles.Update(ctx, 2, other.ID, &models.RoleUpdateRequest{Name: &taken})
+		assert.ErrorIs(t, err, ErrRoleNameTaken)
+
+		inactive := false
+		description := "Edits content"
+		updated, err := roles.Update(ctx, 2, other.ID, &models.RoleUpdateRequest{Description: &description, IsActive: &inactive})
+		require.NoError(t, err)
+		assert.Equal(t, "editor", updated.Name)
+		assert.Equal(t, description, updated.Description)
+		assert.False(t, updated.IsActive)
+	})
+
+	t.Run("roles are assigned to users", func(t *testing.T) {
+		editor, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: "writer"})
+		require.NoError(t, err)
+
+		assigned, err := roles.AssignToUser(ctx, 2, &models.AssignRoleRequest{UserID: 1, RoleIDs: []uint{role.ID, editor.ID, role.ID}})
+		require.NoError(t, err)
+		require.Len(t, assigned, 2)
+		assert.Equal(t, models.RoleModerator, assigned[0].Name)
+		assert.Len(t, assigned[0].Permissions, 2)
+
+		_, err = roles.AssignToUser(ctx, 2, &models.AssignRoleRequest{UserID: 1, RoleIDs: []uint{999}})
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+		_, err = roles.AssignToUser(ctx, 2, &models.AssignRoleRequest{UserID: 42, RoleIDs: []uint{role.ID}})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		_, err = roles.ListForUser(ctx, 42)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+
+		require.NoError(t, roles.RemoveFromUser(ctx, 2, 1, editor.ID))
+		assert.ErrorIs(t, roles.RemoveFromUser(ctx, 2, 1, editor.ID), ErrRoleNotAssigned)
+		list, err := roles.ListForUser(ctx, 1)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, role.ID, list[0].ID)
+	})
+
+	t.Run("roles inherit from other roles", func(t *testing.T) {
+		parent, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: "reviewer"})
+		require.NoError(t, err)
+		child, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: "lead", ParentRoleID: &parent.ID})
+		require.NoError(t, err)
+		require.NotNil(t, child.ParentRoleID)
+		assert.Equal(t, parent.ID, *child.ParentRoleID)
+
+		unknown := uint(999)
+		_, err = roles.Create(ctx, 2, &models.RoleCreateRequest{Name: "orphan", ParentRoleID: &unknown})
+		assert.ErrorIs(t, err, ErrParentRoleNotFound)
+		_, err = roles.Update(ctx, 2, parent.ID, &models.RoleUpdateRequest{ParentRoleID: &child.ID})
+		assert.ErrorIs(t, err, ErrRoleCycle)
+		_, err = roles.Update(ctx, 2, parent.ID, &models.RoleUpdateRequest{ParentRoleID: &parent.ID})
+		assert.ErrorIs(t, err, ErrRoleCycle)
+
+		none := uint(0)
+		updated, err := roles.Update(ctx, 2, child.ID, &models.RoleUpdateRequest{ParentRoleID: &none})
+		require.NoError(t, err)
+		assert.Nil(t, updated.ParentRoleID)
+	})
+
+	t.Run("roles are deleted", func(t *testing.T) {
+		require.NoError(t, roles.Delete(ctx, 2, role.ID))
+		assert.ErrorIs(t, roles.Delete(ctx, 2, role.ID), ErrRoleNotFound)
+		_, err := roles.GetByID(ctx, role.ID)
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+	})
+}
+
+func TestAdminRole(t *testing.T) {
+	ctx := context.Background()
+	roleRepo := &fakeRoleRepository{permissions: &fakePermissionRepository{}}
+	user := &models.User{ID: 1}
+	userRepo := &MockUserRepository{}
+	userRepo.On("GetByID", mock.Anything, uint(1)).Return(user, nil)
+	userRepo.On("Update", mock.Anything, user).Return(nil)
+	roles := NewRoleService(roleRepo, roleRepo.permissions, userRepo, nil, logger.New("error", "text"))
+
+	admin, err := roles.Create(ctx, 2, &models.RoleCreateRequest{Name: models.RoleAdmin})
+	require.NoError(t, err)
+
+	// The IsAdmin flag follows the admin role
+	_, err = roles.AssignToUser(ctx, 2, &models.AssignRoleRequest{UserID: 1, RoleIDs: []uint{admin.ID}})
+	require.NoError(t, err)
+	assert.True(t, user.IsAdmin)
+	isAdmin, err := roles.IsAdmin(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, isAdmin)
+
+	require.NoError(t, roles.RemoveFromUser(ctx, 2, 1, admin.ID))
+	assert.False(t, user.IsAdmin)
+	isAdmin, err = roles.IsAdmin(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, isAdmin)
+
+	// Writes of the deprecated flag grant and take away the role
+	require.NoError(t, setAdminRole(ctx, roleRepo, nil, roles.(*roleService).log, 2, 1, true))
+	isAdmin, err = roles.IsAdmin(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, isAdmin)
+	require.NoError(t, setAdminRole(ctx, roleRepo, nil, roles.(*roleService).log, 2, 1, false))
+	isAdmin, err = roles.IsAdmin(ctx, 1)
+	require.NoError(t, err)
+	assert.False(t, isAdmin)
+	require.NoError(t, setAdminRole(ctx, nil, nil, roles.(*roleService).log, 2, 1, true))
+}
+
+func TestPermissionService(t *testing.T) {
+	ctx := context.Background()
+	_, permissions := setupRoleServices()
+
+	read, err := permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserRead, Resource: "user", Action: "read"})
+	require.NoError(t, err)
+	_, err = permissions.Create(ctx, 2, &models.PermissionCreateRequest{Name: models.PermissionUserRead, Resource: "user", Action: "read"})
+	assert.ErrorIs(t, err, ErrPermissionNameTaken)
+
+	action := "list"
+	name := "user.list"
+	updated, err := permissions.Update(ctx, 2, read.ID, &models.PermissionUpdateRequest{Name: &name, Action: &action})
+	require.NoError(t, err)
+	assert.Equal(t, "user.list", updated.Name)
+	assert.Equal(t, "user", updated.Resource)
+	assert.Equal(t, "list", updated.Action)
+
+	list, err := permissions.List(ctx)
+	require.NoError(t, err)
+	assert.Len(t, list, 1)
+
+	// The catalog lists known pairs, whether or not they were created
+	catalog := permissions.Catalog()
+	assert.Len(t, catalog, len(models.KnownPermissions))
+	assert.Contains(t, catalog, models.PermissionCatalogEntry{Name: models.PermissionRoleDelete, Resource: "role", Action: "delete"})
+
+	require.NoError(t, permissions.Delete(ctx, 2, read.ID))
+	assert.ErrorIs(t, permissions.Delete(ctx, 2, read.ID), ErrPermissionNotFound)
+	_, err = permissions.Update(ctx, 2, read.ID, &models.PermissionUpdateRequest{Name: &name})
+	assert.ErrorIs(t, err, ErrPermissionNotFound)
+}
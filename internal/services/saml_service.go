@@ -0,0 +1,192 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/crewjam/saml"
+)
+
+// samlService implements the SAMLService interface
+type samlService struct {
+	sp        *saml.ServiceProvider
+	resolver  *identityResolver
+	userRepo  repository.UserRepository
+	roleRepo  repository.RoleRepository
+	auditRepo repository.RBACAuditRepository
+	tokenRepo repository.EmailTokenRepository
+	logins    LoginCompleter
+	cfg       *config.Config
+	log       *logger.Logger
+}
+
+// NewSAMLService creates a new SAML single sign-on service for a configured service provider
+func NewSAMLService(sp *saml.ServiceProvider, userRepo repository.UserRepository, identityRepo repository.IdentityRepository, roleRepo repository.RoleRepository, auditRepo repository.RBACAuditRepository, tokenRepo repository.EmailTokenRepository, logins LoginCompleter, cfg *config.Config, log *logger.Logger) SAMLService {
+	return &samlService{
+		sp: sp,
+		resolver: &identityResolver{
+			userRepo:      userRepo,
+			identityRepo:  identityRepo,
+			autoProvision: cfg.SAML.AutoProvision,
+			log:           log,
+		},
+		userRepo:  userRepo,
+		roleRepo:  roleRepo,
+		auditRepo: auditRepo,
+		tokenRepo: tokenRepo,
+		logins:    logins,
+		cfg:       cfg,
+		log:       log,
+	}
+}
+
+// ServiceProvider returns the SAML service provider validating assertions
+func (s *samlService) ServiceProvider() *saml.ServiceProvider {
+	return s.sp
+}
+
+// Login maps a validated assertion onto a local user and returns a single-use code,
+// which the frontend redeems for tokens so they never appear in a URL
+func (s *samlService) Login(ctx context.Context, assertion *saml.Assertion) (string, error) {
+	if assertion.Issuer.Value == "" || assertion.Subject == nil || assertion.Subject.NameID == nil || assertion.Subject.NameID.Value == "" {
+		return "", fmt.Errorf("assertion has no issuer or subject")
+	}
+
+	attributes := samlAttributes(assertion)
+	first := func(name string) string {
+		if values := attributes[name]; len(values) > 0 {
+			return values[0]
+		}
+		return ""
+	}
+
+	user, err := s.resolver.Resolve(ctx, &externalIdentity{
+		Provider:      assertion.Issuer.Value,
+		Subject:       assertion.Subject.NameID.Value,
+		Email:         first(s.cfg.SAML.EmailAttribute),
+		EmailVerified: s.cfg.SAML.TrustEmail,
+		Username:      first(s.cfg.SAML.UsernameAttribute),
+		FirstName:     first(s.cfg.SAML.FirstNameAttribute),
+		LastName:      first(s.cfg.SAML.LastNameAttribute),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to map external identity: %w", err)
+	}
+	if !user.IsActive {
+		return "", &AccountDeactivatedError{SelfService: user.DeactivatedVoluntarily()}
+	}
+	if user.IsSuspended(time.Now()) {
+		return "", &AccountSuspendedError{Until: user.SuspendedUntil}
+	}
+
+	if err := s.syncAttributes(ctx, user, first, attributes[s.cfg.SAML.GroupsAttribute]); err != nil {
+		return "", err
+	}
+
+	code, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate login code: %w", err)
+	}
+	if err := s.tokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   models.EmailTokenSAMLLogin,
+		TokenHash: utils.HashToken(code),
+		ExpiresAt: time.Now().Add(s.cfg.SAML.CodeTTL),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to store SAML login code")
+		return "", fmt.Errorf("failed to store login code: %w", err)
+	}
+
+	s.log.Security("saml_login", user.ID).WithField("provider", assertion.Issuer.Value).Info("SAML assertion accepted")
+	return code, nil
+}
+
+// Redeem exchanges a single-use login code for an access token and refresh token. deviceToken
+// is the one an earlier login handed out on the same device, if any.
+func (s *samlService) Redeem(ctx context.Context, code, deviceToken string) (*models.LoginResponse, error) {
+	token, err := s.tokenRepo.GetByHash(ctx, utils.HashToken(code))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get SAML login code")
+		return nil, fmt.Errorf("failed to get login code: %w", err)
+	}
+	if token == nil || token.Purpose != models.EmailTokenSAMLLogin || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidLoginCode
+	}
+
+	marked, err := s.tokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use login code: %w", err)
+	}
+	if !marked {
+		return nil, ErrInvalidLoginCode
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", token.UserID).Error("Failed to get user for SAML login")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidLoginCode
+	}
+
+	return s.logins.CompleteLogin(ctx, user, models.LoginMethodSAML, deviceToken)
+}
+
+// syncAttributes updates the user's name and, when an admin group is configured, admin
+// status from the assertion, so changes made at the identity provider carry over
+func (s *samlService) syncAttributes(ctx context.Context, user *models.User, first func(string) string, groups []string) error {
+	changed, adminChanged := false, false
+	if firstName := first(s.cfg.SAML.FirstNameAttribute); firstName != "" && firstName != user.FirstName {
+		user.FirstName = firstName
+		changed = true
+	}
+	if lastName := first(s.cfg.SAML.LastNameAttribute); lastName != "" && lastName != user.LastName {
+		user.LastName = lastName
+		changed = true
+	}
+	if group := s.cfg.SAML.AdminGroup; group != "" {
+		if isAdmin := containsAll(groups, []string{group}); isAdmin != user.IsAdmin {
+			s.log.Security("saml_admin_changed", user.ID).WithField("is_admin", isAdmin).Info("Admin status changed by SAML group membership")
+			user.IsAdmin = isAdmin
+			changed, adminChanged = true, true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to update user from SAML attributes")
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+	if adminChanged {
+		return setAdminRole(ctx, s.roleRepo, s.auditRepo, s.log, 0, user.ID, user.IsAdmin)
+	}
+	return nil
+}
+
+// samlAttributes collects the values of the assertion's attributes by name and friendly name
+func samlAttributes(assertion *saml.Assertion) map[string][]string {
+	attributes := make(map[string][]string)
+	for _, statement := range assertion.AttributeStatements {
+		for _, attribute := range statement.Attributes {
+			var values []string
+			for _, value := range attribute.Values {
+				values = append(values, value.Value)
+			}
+			attributes[attribute.Name] = append(attributes[attribute.Name], values...)
+			if attribute.FriendlyName != "" && attribute.FriendlyName != attribute.Name {
+				attributes[attribute.FriendlyName] = append(attributes[attribute.FriendlyName], values...)
+			}
+		}
+	}
+	return attributes
+}
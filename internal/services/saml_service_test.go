@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/crewjam/saml"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeIdentityRepository keeps identity links in memory
+type fakeIdentityRepository struct {
+	identities []*models.UserIdentity
+}
+
+func (r *fakeIdentityRepository) Create(ctx context.Context, identity *models.UserIdentity) error {
+	identity.ID = uint(len(r.identities) + 1)
+	r.identities = append(r.identities, identity)
+	return nil
+}
+
+func (r *fakeIdentityRepository) GetByProviderSubject(ctx context.Context, provider, subject string) (*models.UserIdentity, error) {
+	for _, identity := range r.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return identity, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeIdentityRepository) ListByUser(ctx context.Context, userID uint) ([]*models.UserIdentity, error) {
+	var result []*models.UserIdentity
+	for _, identity := range r.identities {
+		if identity.UserID == userID {
+			result = append(result, identity)
+		}
+	}
+	return result, nil
+}
+
+func samlAssertion(subject string, attributes map[string][]string) *saml.Assertion {
+	statement := saml.AttributeStatement{}
+	for name, values := range attributes {
+		attribute := saml.Attribute{Name: name}
+		for _, value := range values {
+			attribute.Values = append(attribute.Values, saml.AttributeValue{Value: value})
+		}
+		statement.Attributes = append(statement.Attributes, attribute)
+	}
+	return &saml.Assertion{
+		Issuer:              saml.Issuer{Value: "https://idp.example.com"},
+		Subject:             &saml.Subject{NameID: &saml.NameID{Value: subject}},
+		AttributeStatements: []saml.AttributeStatement{statement},
+	}
+}
+
+func TestSAMLService_LoginAndRedeem(t *testing.T) {
+	ctx := context.Background()
+	logins, mockRepo, mockAuth := setupUserService()
+	identities := &fakeIdentityRepository{}
+	cfg := &config.Config{
+		JWT: config.JWTConfig{Expiry: time.Hour},
+		SAML: config.SAMLConfig{
+			CodeTTL:            time.Minute,
+			TrustEmail:         true,
+			EmailAttribute:     "email",
+			FirstNameAttribute: "firstName",
+			LastNameAttribute:  "lastName",
+			GroupsAttribute:    "groups",
+			AdminGroup:         "app-admins",
+		},
+	}
+	service := NewSAMLService(&saml.ServiceProvider{}, mockRepo, identities, nil, nil, newFakeEmailTokenRepository(), logins, cfg, logger.New("error", "text"))
+
+	user := &models.User{ID: 1, Email: "jane@example.com", FirstName: "Jane", LastName: "Old", IsActive: true}
+	mockRepo.On("GetByEmail", ctx, "jane@example.com").Return(user, nil).Once()
+	mockRepo.On("Update", ctx, mock.MatchedBy(func(u *models.User) bool {
+		return u.LastName == "Doe" && u.IsAdmin
+	})).Return(nil).Once()
+
+	code, err := service.Login(ctx, samlAssertion("jane", map[string][]string{
+		"email":    {"jane@example.com"},
+		"lastName": {"Doe"},
+		"groups":   {"staff", "app-admins"},
+	}))
+	require.NoError(t, err)
+	require.NotEmpty(t, code)
+	require.Len(t, identities.identities, 1)
+	assert.Equal(t, "jane", identities.identities[0].Subject)
+
+	mockRepo.On("GetByID", ctx, uint(1)).Return(user, nil).Once()
+	mockRepo.On("UpdateLastLogin", ctx, uint(1)).Return(nil).Once()
+	mockAuth.On("GenerateToken", uint(1), "jane@example.com", true).Return("access", nil).Once()
+	mockAuth.On("IssueRefreshToken", ctx, uint(1), false).Return("refresh", nil).Once()
+
+	response, err := service.Redeem(ctx, code, "")
+	require.NoError(t, err)
+	assert.Equal(t, "access", response.AccessToken)
+	assert.Equal(t, "refresh", response.RefreshToken)
+	assert.NotEmpty(t, response.DeviceToken)
+
+	// Codes are single-use
+	_, err = service.Redeem(ctx, code, "")
+	assert.ErrorIs(t, err, ErrInvalidLoginCode)
+
+	mockRepo.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
+
+func TestSAMLService_LoginRefusesUnknownUsers(t *testing.T) {
+	ctx := context.Background()
+	mockRepo := &MockUserRepository{}
+	cfg := &config.Config{SAML: config.SAMLConfig{CodeTTL: time.Minute, EmailAttribute: "email"}}
+	service := NewSAMLService(&saml.ServiceProvider{}, mockRepo, &fakeIdentityRepository{}, nil, nil, newFakeEmailTokenRepository(), nil, cfg, logger.New("error", "text"))
+
+	// Without TrustEmail the asserted address is not used to find an account
+	_, err := service.Login(ctx, samlAssertion("jane", map[string][]string{"email": {"jane@example.com"}}))
+	assert.Error(t, err)
+
+	_, err = service.Login(ctx, samlAssertion("", nil))
+	assert.Error(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestSAMLService_RedeemChecksTheAccount(t *testing.T) {
+	ctx := context.Background()
+	logins, mockRepo, mockAuth := setupUserService()
+	tokens := newFakeEmailTokenRepository()
+	events := &fakeLoginEventRepository{}
+	logins.eventRepo = events
+	cfg := &config.Config{SAML: config.SAMLConfig{CodeTTL: time.Minute}}
+	service := NewSAMLService(&saml.ServiceProvider{}, mockRepo, &fakeIdentityRepository{}, nil, nil, tokens, logins, cfg, logger.New("error", "text"))
+
+	adminID := uint(9)
+	user := &models.User{ID: 1, Email: "jane@example.com", IsActive: true}
+	user.SetActive(false, adminID)
+	require.NoError(t, tokens.Create(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   models.EmailTokenSAMLLogin,
+		TokenHash: utils.HashToken("code"),
+		ExpiresAt: time.Now().Add(time.Minute),
+	}))
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil).Once()
+
+	// A SAML login goes through the same account checks and login history as a password login
+	_, err := service.Redeem(ctx, "code", "")
+	var deactivatedErr *AccountDeactivatedError
+	require.ErrorAs(t, err, &deactivatedErr)
+	assert.False(t, deactivatedErr.SelfService)
+	require.Len(t, events.events, 1)
+	assert.Equal(t, models.LoginMethodSAML, events.events[0].Method)
+	assert.Equal(t, models.LoginFailureAccountDeactivated, events.events[0].FailureReason)
+	mockAuth.AssertNotCalled(t, "GenerateToken", mock.Anything, mock.Anything, mock.Anything)
+}
@@ -0,0 +1,64 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+)
+
+// settingsService implements the SettingsService interface
+type settingsService struct {
+	settingsRepo repository.SettingsRepository
+	log          *logger.Logger
+}
+
+// NewSettingsService creates a new settings service
+func NewSettingsService(settingsRepo repository.SettingsRepository, log *logger.Logger) SettingsService {
+	return &settingsService{
+		settingsRepo: settingsRepo,
+		log:          log,
+	}
+}
+
+// Get returns the settings of a user, or the defaults when the user hasn't saved any
+func (s *settingsService) Get(ctx context.Context, userID uint) (*models.UserSettings, error) {
+	settings, err := s.settingsRepo.GetByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get settings")
+		return nil, fmt.Errorf("failed to get settings: %w", err)
+	}
+	if settings == nil {
+		return models.DefaultUserSettings(userID), nil
+	}
+	if settings.Notifications == nil {
+		settings.Notifications = map[string]bool{}
+	}
+	return settings, nil
+}
+
+// Update changes the settings given in the request and keeps the others
+func (s *settingsService) Update(ctx context.Context, userID uint, req *models.UserSettingsUpdateRequest) (*models.UserSettings, error) {
+	settings, err := s.Get(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Timezone != nil {
+		settings.Timezone = *req.Timezone
+	}
+	if req.Locale != nil {
+		settings.Locale = *req.Locale
+	}
+	for name, enabled := range req.Notifications {
+		settings.Notifications[name] = enabled
+	}
+
+	if err := s.settingsRepo.Save(ctx, settings); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to save settings")
+		return nil, fmt.Errorf("failed to save settings: %w", err)
+	}
+	return settings, nil
+}
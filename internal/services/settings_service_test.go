@@ -0,0 +1,72 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSettingsRepository keeps settings in memory
+type fakeSettingsRepository struct {
+	settings map[uint]models.UserSettings
+}
+
+func (r *fakeSettingsRepository) GetByUser(ctx context.Context, userID uint) (*models.UserSettings, error) {
+	settings, ok := r.settings[userID]
+	if !ok {
+		return nil, nil
+	}
+	return &settings, nil
+}
+
+func (r *fakeSettingsRepository) Save(ctx context.Context, settings *models.UserSettings) error {
+	r.settings[settings.UserID] = *settings
+	return nil
+}
+
+func TestSettingsService(t *testing.T) {
+	ctx := context.Background()
+	repo := &fakeSettingsRepository{settings: map[uint]models.UserSettings{}}
+	service := NewSettingsService(repo, logger.New("error", "text"))
+
+	// Users who never saved settings get the defaults
+	settings, err := service.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultTimezone, settings.Timezone)
+	assert.Equal(t, models.DefaultLocale, settings.Locale)
+	assert.Empty(t, settings.Notifications)
+
+	timezone := "Europe/Berlin"
+	settings, err = service.Update(ctx, 1, &models.UserSettingsUpdateRequest{
+		Timezone:      &timezone,
+		Notifications: map[string]bool{"newsletter": true, "security": true},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", settings.Timezone)
+	assert.Equal(t, models.DefaultLocale, settings.Locale)
+
+	// Omitted fields are kept and notifications are merged
+	locale := "de-DE"
+	settings, err = service.Update(ctx, 1, &models.UserSettingsUpdateRequest{
+		Locale:        &locale,
+		Notifications: map[string]bool{"newsletter": false},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Berlin", settings.Timezone)
+	assert.Equal(t, "de-DE", settings.Locale)
+	assert.Equal(t, map[string]bool{"newsletter": false, "security": true}, settings.Notifications)
+
+	saved, err := service.Get(ctx, 1)
+	require.NoError(t, err)
+	assert.Equal(t, settings, saved)
+
+	// Other users are unaffected
+	other, err := service.Get(ctx, 2)
+	require.NoError(t, err)
+	assert.Equal(t, models.DefaultTimezone, other.Timezone)
+}
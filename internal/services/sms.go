@@ -0,0 +1,31 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"gbt-be-template/internal/jobs"
+	"gbt-be-template/pkg/sms"
+)
+
+// JobSendSMS is the job type that delivers a text message, so provider outages are retried instead of failing requests
+const JobSendSMS = "sms.send"
+
+// NewSMSJobHandler returns the job handler that sends queued text messages
+func NewSMSJobHandler(sender sms.Sender) jobs.Handler {
+	return func(ctx context.Context, payload []byte) error {
+		var msg sms.Message
+		if err := json.Unmarshal(payload, &msg); err != nil {
+			return fmt.Errorf("%w: invalid payload: %v", jobs.ErrPermanent, err)
+		}
+		if err := sender.Send(ctx, &msg); err != nil {
+			if errors.Is(err, sms.ErrRejected) {
+				return fmt.Errorf("%w: %v", jobs.ErrPermanent, err)
+			}
+			return err
+		}
+		return nil
+	}
+}
@@ -0,0 +1,150 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/mailer"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+)
+
+// checkSuspiciousLogin looks out for a login from an IP address and a device the user never logged in
+// from before. The user is emailed about it or, when verification is required, the login is held until
+// the user confirms it with an emailed link. A non-nil response then takes the place of the tokens.
+func (s *userService) checkSuspiciousLogin(ctx context.Context, user *models.User, opts loginOptions) (*models.LoginResponse, error) {
+	if !s.isSuspiciousLogin(ctx, user.ID, opts) {
+		return nil, nil
+	}
+
+	ip, userAgent := middleware.GetClientFromContext(ctx)
+	s.log.Security("suspicious_login", user.ID).WithFields(map[string]interface{}{
+		"ip":     ip,
+		"method": opts.method,
+	}).Warn("Login from an unfamiliar IP address and device")
+
+	// A TOTP code already proved the login is the user's
+	if s.cfg.Suspicious.RequireVerification && opts.method != models.LoginMethodTwoFactor {
+		return s.startLoginVerification(ctx, user, opts, ip, userAgent)
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "New login to your account",
+		Body: fmt.Sprintf("Hi %s,\n\nYour account was just logged in to from a new place:\n\nIP address: %s\nDevice: %s\nTime: %s\n\nIf this was you, there's nothing to do. If not, reset your password right away and sign out the sessions you don't recognize.\n",
+			user.FirstName, ip, userAgent, time.Now().UTC().Format(time.RFC1123)),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to queue suspicious login email")
+	}
+	return nil, nil
+}
+
+// isSuspiciousLogin reports whether a login comes from both an IP address and a device the user's
+// login history doesn't know. The first login of a user has nothing to compare with and passes.
+func (s *userService) isSuspiciousLogin(ctx context.Context, userID uint, opts loginOptions) bool {
+	if !s.cfg.Suspicious.Enabled || opts.method == models.LoginMethodEmailLink {
+		return false
+	}
+	ip, _ := middleware.GetClientFromContext(ctx)
+	if ip == "" {
+		return false
+	}
+
+	if opts.deviceToken != "" {
+		device, err := s.deviceRepo.GetByHash(ctx, userID, utils.HashToken(opts.deviceToken))
+		if err != nil {
+			s.log.WithError(err).WithField("user_id", userID).Error("Failed to get login device")
+			return false
+		}
+		if device != nil {
+			return false
+		}
+	}
+
+	// Errors let the login through, as failing to look at the history shouldn't lock users out
+	hasHistory, err := s.eventRepo.HasSucceeded(ctx, userID, "")
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to check login history")
+		return false
+	}
+	if !hasHistory {
+		return false
+	}
+	seen, err := s.eventRepo.HasSucceeded(ctx, userID, ip)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to check login history")
+		return false
+	}
+	return !seen
+}
+
+// startLoginVerification holds a suspicious login and emails the user a link that completes it
+func (s *userService) startLoginVerification(ctx context.Context, user *models.User, opts loginOptions, ip, userAgent string) (*models.LoginResponse, error) {
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate login verification token: %w", err)
+	}
+	purpose := models.EmailTokenLoginVerification
+	if opts.rememberMe {
+		purpose = models.EmailTokenLoginVerificationRememberMe
+	}
+	if err := s.tokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.Suspicious.VerificationTTL),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to store login verification token")
+		return nil, fmt.Errorf("failed to store login verification token: %w", err)
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Confirm your login",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone is logging in to your account from a new place:\n\nIP address: %s\nDevice: %s\n\nIf this is you, open this link to finish logging in:\n\n%s/verify-login?token=%s\n\nThe link expires in %s. If it isn't you, don't open the link and reset your password right away.\n",
+			user.FirstName, ip, userAgent, s.cfg.Mail.LinkBaseURL, token, s.cfg.Suspicious.VerificationTTL),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to queue login verification email")
+		return nil, fmt.Errorf("failed to queue login verification email: %w", err)
+	}
+
+	recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, opts.method, models.LoginFailureUnverified)
+	return &models.LoginResponse{VerificationRequired: true}, nil
+}
+
+// VerifyLogin completes a suspicious login with the link emailed to the user
+func (s *userService) VerifyLogin(ctx context.Context, req *models.LoginVerifyRequest) (*models.LoginResponse, error) {
+	token, err := s.tokenRepo.GetByHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get login verification token")
+		return nil, fmt.Errorf("failed to get login verification token: %w", err)
+	}
+	rememberMe := token != nil && token.Purpose == models.EmailTokenLoginVerificationRememberMe
+	if token == nil || (token.Purpose != models.EmailTokenLoginVerification && !rememberMe) || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidEmailToken
+	}
+
+	marked, err := s.tokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use login verification token: %w", err)
+	}
+	if !marked {
+		return nil, ErrInvalidEmailToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", token.UserID).Error("Failed to get user for login verification")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrInvalidEmailToken
+	}
+
+	s.log.Security("login_verified", user.ID).Info("Suspicious login confirmed by email")
+	return s.completeLogin(ctx, user, loginOptions{method: models.LoginMethodEmailLink, rememberMe: rememberMe, deviceToken: req.DeviceToken})
+}
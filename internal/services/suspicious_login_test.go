@@ -0,0 +1,98 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/middleware"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_SuspiciousLogin(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.Suspicious.Enabled = true
+	service.cfg.Suspicious.VerificationTTL = time.Minute
+	service.cfg.Mail.LinkBaseURL = "https://app.example.com"
+	queue := service.queue.(*fakeQueue)
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), IsActive: true}
+	mockRepo.On("GetByEmailOrUsername", mock.Anything, user.Email).Return(user, nil)
+	mockRepo.On("GetByID", mock.Anything, user.ID).Return(user, nil)
+	mockRepo.On("UpdateLastLogin", mock.Anything, user.ID).Return(nil)
+	mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil)
+	mockAuth.On("IssueRefreshToken", mock.Anything, user.ID, false).Return("refresh123", nil)
+
+	login := func(ip, deviceToken string) *models.LoginResponse {
+		ctx := context.WithValue(context.Background(), middleware.ClientIPKey, ip)
+		resp, err := service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "password123", DeviceToken: deviceToken})
+		require.NoError(t, err)
+		return resp
+	}
+	emails := func() int {
+		count := 0
+		for _, job := range queue.jobs {
+			if strings.HasPrefix(job, JobSendEmail) {
+				count++
+			}
+		}
+		return count
+	}
+
+	// The first login has no history to compare with
+	first := login("10.0.0.1", "")
+	require.NotEmpty(t, first.AccessToken)
+	assert.Equal(t, 0, emails())
+
+	t.Run("known IP address passes", func(t *testing.T) {
+		login("10.0.0.1", "")
+		assert.Equal(t, 0, emails())
+	})
+
+	t.Run("known device passes", func(t *testing.T) {
+		login("10.0.0.2", first.DeviceToken)
+		assert.Equal(t, 0, emails())
+	})
+
+	t.Run("new IP address and device is emailed about", func(t *testing.T) {
+		resp := login("10.0.0.3", "")
+		assert.NotEmpty(t, resp.AccessToken)
+		assert.Equal(t, 1, emails())
+	})
+
+	t.Run("verification holds the login until the emailed link is used", func(t *testing.T) {
+		service.cfg.Suspicious.RequireVerification = true
+		defer func() { service.cfg.Suspicious.RequireVerification = false }()
+
+		resp := login("10.0.0.4", "")
+		assert.True(t, resp.VerificationRequired)
+		assert.Empty(t, resp.AccessToken)
+		require.Equal(t, 2, emails())
+
+		var msg struct{ Body string }
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(queue.jobs[len(queue.jobs)-1], JobSendEmail+" ")), &msg))
+		match := regexp.MustCompile(`https://app\.example\.com/verify-login\?token=(\S+)`).FindStringSubmatch(msg.Body)
+		require.Len(t, match, 2)
+		token := match[1]
+
+		ctx := context.WithValue(context.Background(), middleware.ClientIPKey, "10.0.0.4")
+		verified, err := service.VerifyLogin(ctx, &models.LoginVerifyRequest{Token: token})
+		require.NoError(t, err)
+		assert.Equal(t, "token123", verified.AccessToken)
+
+		_, err = service.VerifyLogin(ctx, &models.LoginVerifyRequest{Token: token})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+
+		// The confirmed IP address is known from now on
+		assert.NotEmpty(t, login("10.0.0.4", "").AccessToken)
+	})
+}
@@ -0,0 +1,217 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+)
+
+// tagService implements the TagService interface
+type tagService struct {
+	tagRepo  repository.TagRepository
+	userRepo repository.UserRepository
+	log      *logger.Logger
+}
+
+// NewTagService creates a new tag service
+func NewTagService(tagRepo repository.TagRepository, userRepo repository.UserRepository, log *logger.Logger) TagService {
+	return &tagService{
+		tagRepo:  tagRepo,
+		userRepo: userRepo,
+		log:      log,
+	}
+}
+
+// List returns all tags ordered by name
+func (s *tagService) List(ctx context.Context) ([]*models.Tag, error) {
+	tags, err := s.tagRepo.List(ctx)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to list tags")
+		return nil, fmt.Errorf("failed to list tags: %w", err)
+	}
+	return tags, nil
+}
+
+// Create creates a tag. Its name is lowercased.
+func (s *tagService) Create(ctx context.Context, actorID uint, req *models.TagCreateRequest) (*models.Tag, error) {
+	name, err := s.checkName(ctx, req.Name, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	tag := &models.Tag{Name: name, Description: req.Description}
+	if err := s.tagRepo.Create(ctx, tag); err != nil {
+		s.log.WithError(err).WithField("name", name).Error("Failed to create tag")
+		return nil, fmt.Errorf("failed to create tag: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"tag_id":   tag.ID,
+		"actor_id": actorID,
+	}).Info("Tag created")
+	return tag, nil
+}
+
+// Update changes the fields of a tag given in the request
+func (s *tagService) Update(ctx context.Context, actorID, id uint, req *models.TagUpdateRequest) (*models.Tag, error) {
+	tag, err := s.getTag(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Name != nil {
+		name, err := s.checkName(ctx, *req.Name, id)
+		if err != nil {
+			return nil, err
+		}
+		tag.Name = name
+	}
+	if req.Description != nil {
+		tag.Description = *req.Description
+	}
+	if err := s.tagRepo.Update(ctx, tag); err != nil {
+		s.log.WithError(err).WithField("tag_id", id).Error("Failed to update tag")
+		return nil, fmt.Errorf("failed to update tag: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"tag_id":   id,
+		"actor_id": actorID,
+	}).Info("Tag updated")
+	return tag, nil
+}
+
+// Delete deletes a tag, untagging its users
+func (s *tagService) Delete(ctx context.Context, actorID, id uint) error {
+	deleted, err := s.tagRepo.Delete(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("tag_id", id).Error("Failed to delete tag")
+		return fmt.Errorf("failed to delete tag: %w", err)
+	}
+	if !deleted {
+		return ErrTagNotFound
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"tag_id":   id,
+		"actor_id": actorID,
+	}).Info("Tag deleted")
+	return nil
+}
+
+// ListForUser returns the tags of a user ordered by name
+func (s *tagService) ListForUser(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	if err := s.checkUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	tags, err := s.tagRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list user tags")
+		return nil, fmt.Errorf("failed to list user tags: %w", err)
+	}
+	return tags, nil
+}
+
+// AssignToUser adds tags to a user, keeping the tags the user already has, and returns all of
+// the user's tags
+func (s *tagService) AssignToUser(ctx context.Context, actorID, userID uint, req *models.UserTagsRequest) ([]*models.Tag, error) {
+	if err := s.checkUserExists(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	ids := slices.Clone(req.TagIDs)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	tags, err := s.tagRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+	if len(tags) != len(ids) {
+		return nil, ErrTagNotFound
+	}
+
+	if err := s.tagRepo.AssignToUser(ctx, userID, ids); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to tag user")
+		return nil, fmt.Errorf("failed to tag user: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id":  userID,
+		"actor_id": actorID,
+		"tag_ids":  ids,
+	}).Info("User tagged")
+	return s.ListForUser(ctx, userID)
+}
+
+// RemoveFromUser takes a tag away from a user
+func (s *tagService) RemoveFromUser(ctx context.Context, actorID, userID, tagID uint) error {
+	if err := s.checkUserExists(ctx, userID); err != nil {
+		return err
+	}
+
+	removed, err := s.tagRepo.RemoveFromUser(ctx, userID, tagID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to untag user")
+		return fmt.Errorf("failed to untag user: %w", err)
+	}
+	if !removed {
+		return ErrTagNotAssigned
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id":  userID,
+		"actor_id": actorID,
+		"tag_id":   tagID,
+	}).Info("User untagged")
+	return nil
+}
+
+// getTag returns a tag, or ErrTagNotFound
+func (s *tagService) getTag(ctx context.Context, id uint) (*models.Tag, error) {
+	tag, err := s.tagRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("tag_id", id).Error("Failed to get tag")
+		return nil, fmt.Errorf("failed to get tag: %w", err)
+	}
+	if tag == nil {
+		return nil, ErrTagNotFound
+	}
+	return tag, nil
+}
+
+// checkName lowercases a tag name and checks that it is valid and not used by another tag than
+// exceptID
+func (s *tagService) checkName(ctx context.Context, name string, exceptID uint) (string, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if !models.ValidTagName(name) {
+		return "", ErrInvalidTagName
+	}
+
+	existing, err := s.tagRepo.GetByName(ctx, name)
+	if err != nil {
+		return "", fmt.Errorf("failed to check tag name: %w", err)
+	}
+	if existing != nil && existing.ID != exceptID {
+		return "", ErrTagNameTaken
+	}
+	return name, nil
+}
+
+// checkUserExists returns ErrUserNotFound for unknown users
+func (s *tagService) checkUserExists(ctx context.Context, userID uint) error {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for tags")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,184 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTagRepository keeps tags and the tags of users in memory
+type fakeTagRepository struct {
+	tags     map[uint]*models.Tag
+	userTags map[[2]uint]bool // Keyed by user and tag ID
+}
+
+func newFakeTagRepository() *fakeTagRepository {
+	return &fakeTagRepository{
+		tags:     map[uint]*models.Tag{},
+		userTags: map[[2]uint]bool{},
+	}
+}
+
+func (r *fakeTagRepository) Create(ctx context.Context, tag *models.Tag) error {
+	tag.ID = uint(len(r.tags) + 1)
+	copied := *tag
+	r.tags[tag.ID] = &copied
+	return nil
+}
+
+func (r *fakeTagRepository) GetByID(ctx context.Context, id uint, opts ...repository.QueryOption) (*models.Tag, error) {
+	tag, ok := r.tags[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *tag
+	return &copied, nil
+}
+
+func (r *fakeTagRepository) GetByName(ctx context.Context, name string) (*models.Tag, error) {
+	for _, tag := range r.tags {
+		if tag.Name == name {
+			copied := *tag
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeTagRepository) List(ctx context.Context) ([]*models.Tag, error) {
+	return r.sorted(func(*models.Tag) bool { return true }), nil
+}
+
+func (r *fakeTagRepository) Update(ctx context.Context, tag *models.Tag) error {
+	copied := *tag
+	r.tags[tag.ID] = &copied
+	return nil
+}
+
+func (r *fakeTagRepository) Delete(ctx context.Context, id uint) (bool, error) {
+	if _, ok := r.tags[id]; !ok {
+		return false, nil
+	}
+	delete(r.tags, id)
+	for key := range r.userTags {
+		if key[1] == id {
+			delete(r.userTags, key)
+		}
+	}
+	return true, nil
+}
+
+func (r *fakeTagRepository) ListByIDs(ctx context.Context, ids []uint) ([]*models.Tag, error) {
+	var tags []*models.Tag
+	for _, id := range ids {
+		if tag, ok := r.tags[id]; ok {
+			tags = append(tags, tag)
+		}
+	}
+	return tags, nil
+}
+
+func (r *fakeTagRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Tag, error) {
+	return r.sorted(func(tag *models.Tag) bool { return r.userTags[[2]uint{userID, tag.ID}] }), nil
+}
+
+func (r *fakeTagRepository) AssignToUser(ctx context.Context, userID uint, tagIDs []uint) error {
+	for _, tagID := range tagIDs {
+		r.userTags[[2]uint{userID, tagID}] = true
+	}
+	return nil
+}
+
+func (r *fakeTagRepository) RemoveFromUser(ctx context.Context, userID, tagID uint) (bool, error) {
+	key := [2]uint{userID, tagID}
+	if !r.userTags[key] {
+		return false, nil
+	}
+	delete(r.userTags, key)
+	return true, nil
+}
+
+// sorted returns the tags matching keep ordered by name
+func (r *fakeTagRepository) sorted(keep func(*models.Tag) bool) []*models.Tag {
+	tags := []*models.Tag{}
+	for _, tag := range r.tags {
+		if keep(tag) {
+			copied := *tag
+			tags = append(tags, &copied)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].Name < tags[j].Name })
+	return tags
+}
+
+func setupTagService() (*tagService, *fakeTagRepository, *MockUserRepository) {
+	tagRepo := newFakeTagRepository()
+	userRepo := &MockUserRepository{}
+	return NewTagService(tagRepo, userRepo, logger.New("error", "text")).(*tagService), tagRepo, userRepo
+}
+
+func TestTagService_Create(t *testing.T) {
+	service, _, _ := setupTagService()
+	ctx := context.Background()
+
+	tag, err := service.Create(ctx, 1, &models.TagCreateRequest{Name: " Beta-Testers "})
+	require.NoError(t, err)
+	assert.Equal(t, "beta-testers", tag.Name)
+
+	_, err = service.Create(ctx, 1, &models.TagCreateRequest{Name: "BETA-testers"})
+	assert.ErrorIs(t, err, ErrTagNameTaken)
+	_, err = service.Create(ctx, 1, &models.TagCreateRequest{Name: "beta,vip"})
+	assert.ErrorIs(t, err, ErrInvalidTagName)
+
+	vip, err := service.Create(ctx, 1, &models.TagCreateRequest{Name: "vip"})
+	require.NoError(t, err)
+	name := "beta-testers"
+	_, err = service.Update(ctx, 1, vip.ID, &models.TagUpdateRequest{Name: &name})
+	assert.ErrorIs(t, err, ErrTagNameTaken)
+	name = "vips"
+	updated, err := service.Update(ctx, 1, vip.ID, &models.TagUpdateRequest{Name: &name})
+	require.NoError(t, err)
+	assert.Equal(t, "vips", updated.Name)
+
+	tags, err := service.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "beta-testers", tags[0].Name)
+
+	assert.NoError(t, service.Delete(ctx, 1, vip.ID))
+	assert.ErrorIs(t, service.Delete(ctx, 1, vip.ID), ErrTagNotFound)
+}
+
+func TestTagService_AssignToUser(t *testing.T) {
+	service, tagRepo, userRepo := setupTagService()
+	ctx := context.Background()
+	userRepo.On("GetByID", mock.Anything, uint(1)).Return(&models.User{ID: 1}, nil)
+	userRepo.On("GetByID", mock.Anything, uint(2)).Return(nil, nil)
+
+	vip, err := service.Create(ctx, 1, &models.TagCreateRequest{Name: "vip"})
+	require.NoError(t, err)
+	beta, err := service.Create(ctx, 1, &models.TagCreateRequest{Name: "beta"})
+	require.NoError(t, err)
+
+	tags, err := service.AssignToUser(ctx, 9, 1, &models.UserTagsRequest{TagIDs: []uint{vip.ID, beta.ID, vip.ID}})
+	require.NoError(t, err)
+	require.Len(t, tags, 2)
+	assert.Equal(t, "beta", tags[0].Name)
+
+	_, err = service.AssignToUser(ctx, 9, 1, &models.UserTagsRequest{TagIDs: []uint{vip.ID, 42}})
+	assert.ErrorIs(t, err, ErrTagNotFound)
+	_, err = service.AssignToUser(ctx, 9, 2, &models.UserTagsRequest{TagIDs: []uint{vip.ID}})
+	assert.ErrorIs(t, err, ErrUserNotFound)
+
+	require.NoError(t, service.RemoveFromUser(ctx, 9, 1, vip.ID))
+	assert.ErrorIs(t, service.RemoveFromUser(ctx, 9, 1, vip.ID), ErrTagNotAssigned)
+	assert.Len(t, tagRepo.userTags, 1)
+}
@@ -0,0 +1,124 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/oidc"
+)
+
+// tokenExchangeService implements the TokenExchangeService interface
+type tokenExchangeService struct {
+	verifiers map[string]*oidc.Verifier
+	resolver  *identityResolver
+	authSvc   AuthService
+	cfg       *config.Config
+	log       *logger.Logger
+}
+
+// NewTokenExchangeService creates a new token exchange service trusting the configured issuers
+func NewTokenExchangeService(userRepo repository.UserRepository, identityRepo repository.IdentityRepository, authSvc AuthService, cfg *config.Config, log *logger.Logger) TokenExchangeService {
+	verifiers := make(map[string]*oidc.Verifier, len(cfg.TokenExchange.Issuers))
+	for _, issuer := range cfg.TokenExchange.Issuers {
+		issuer = strings.TrimSpace(issuer)
+		verifiers[issuer] = oidc.NewVerifier(issuer, cfg.TokenExchange.Audience, nil)
+	}
+
+	return &tokenExchangeService{
+		verifiers: verifiers,
+		resolver: &identityResolver{
+			userRepo:      userRepo,
+			identityRepo:  identityRepo,
+			autoProvision: cfg.TokenExchange.AutoProvision,
+			log:           log,
+		},
+		authSvc: authSvc,
+		cfg:     cfg,
+		log:     log,
+	}
+}
+
+// Exchange validates an external IdP token and issues a local access token for the mapped user
+func (s *tokenExchangeService) Exchange(ctx context.Context, req *models.TokenExchangeRequest) (*models.TokenExchangeResponse, error) {
+	if req.GrantType != models.GrantTypeTokenExchange {
+		return nil, &models.OAuthError{Code: "unsupported_grant_type"}
+	}
+	if req.SubjectToken == "" {
+		return nil, &models.OAuthError{Code: "invalid_request", Description: "subject_token is required"}
+	}
+	switch req.SubjectTokenType {
+	case models.TokenTypeJWT, models.TokenTypeAccessToken, models.TokenTypeIDToken:
+	default:
+		return nil, &models.OAuthError{Code: "invalid_request", Description: "unsupported subject_token_type"}
+	}
+	if req.RequestedTokenType != "" && req.RequestedTokenType != models.TokenTypeAccessToken {
+		return nil, &models.OAuthError{Code: "invalid_target", Description: "only access tokens can be issued"}
+	}
+
+	// Pick the verifier by the token's claimed issuer; the verifier then checks it properly
+	issuer, err := oidc.UnverifiedIssuer(req.SubjectToken)
+	if err != nil {
+		return nil, &models.OAuthError{Code: "invalid_request", Description: "subject_token is malformed"}
+	}
+	verifier, ok := s.verifiers[issuer]
+	if !ok {
+		s.log.WithField("issuer", issuer).Warn("Token exchange attempted with untrusted issuer")
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "issuer is not trusted"}
+	}
+
+	claims, err := verifier.Verify(ctx, req.SubjectToken)
+	if err != nil {
+		s.log.WithError(err).WithField("issuer", issuer).Warn("Subject token verification failed")
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "subject_token is invalid"}
+	}
+
+	user, err := s.resolver.Resolve(ctx, &externalIdentity{
+		Provider:      issuer,
+		Subject:       claims.String("sub"),
+		Email:         claims.String("email"),
+		EmailVerified: claims.Bool("email_verified"),
+		Username:      claims.String("preferred_username"),
+		FirstName:     claims.String("given_name"),
+		LastName:      claims.String("family_name"),
+	})
+	if err != nil {
+		// The reason can carry database errors, so it is only logged
+		s.log.WithError(err).WithField("issuer", issuer).Warn("Failed to map external identity")
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "subject_token does not map to an account"}
+	}
+	if !user.IsActive {
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "account is deactivated"}
+	}
+	if user.IsSuspended(time.Now()) {
+		return nil, &models.OAuthError{Code: "invalid_grant", Description: "account is suspended"}
+	}
+
+	// Admin status comes from the local account or membership of the configured IdP group
+	isAdmin := user.IsAdmin
+	if group := s.cfg.TokenExchange.AdminGroup; group != "" {
+		isAdmin = isAdmin || containsAll(claims.Strings(s.cfg.TokenExchange.GroupsClaim), []string{group})
+	}
+
+	token, err := s.authSvc.GenerateToken(user.ID, user.Email, isAdmin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id": user.ID,
+		"issuer":  issuer,
+	}).Info("External token exchanged successfully")
+
+	return &models.TokenExchangeResponse{
+		AccessToken:     token,
+		IssuedTokenType: models.TokenTypeAccessToken,
+		TokenType:       "Bearer",
+		ExpiresIn:       int64(s.cfg.JWT.Expiry.Seconds()),
+	}, nil
+}
@@ -0,0 +1,111 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/middleware"
+	"gbt-be-template/pkg/utils"
+)
+
+// maxDevicesPerUser bounds the devices remembered per user; the least recently seen are forgotten
+const maxDevicesPerUser = 20
+
+// loginOptions carries how a login was made and what the client asked for when completing it
+type loginOptions struct {
+	method      string // Recorded in the login history
+	rememberMe  bool   // Issue a long-lived refresh token
+	deviceToken string // Device token handed out by an earlier login, if any
+	trustDevice bool   // Skip two-factor authentication on this device from now on
+}
+
+// isTrustedDevice reports whether a login comes from a device the user trusts to skip
+// two-factor authentication
+func (s *userService) isTrustedDevice(ctx context.Context, userID uint, deviceToken string) bool {
+	if deviceToken == "" || s.cfg.TwoFactor.TrustedDeviceTTL <= 0 {
+		return false
+	}
+
+	device, err := s.deviceRepo.GetByHash(ctx, userID, utils.HashToken(deviceToken))
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get login device")
+		return false
+	}
+	return device != nil && device.IsTrusted(time.Now())
+}
+
+// recordDevice records the device a login came from and trusts it when asked to. It returns a
+// new device token when the login didn't present a known one.
+func (s *userService) recordDevice(ctx context.Context, user *models.User, opts loginOptions) (string, error) {
+	var device *models.Device
+	if opts.deviceToken != "" {
+		var err error
+		device, err = s.deviceRepo.GetByHash(ctx, user.ID, utils.HashToken(opts.deviceToken))
+		if err != nil {
+			return "", fmt.Errorf("failed to get device: %w", err)
+		}
+	}
+
+	var newToken string
+	if device == nil {
+		var err error
+		newToken, err = utils.GenerateRandomToken(32)
+		if err != nil {
+			return "", fmt.Errorf("failed to generate device token: %w", err)
+		}
+		device = &models.Device{UserID: user.ID, TokenHash: utils.HashToken(newToken)}
+	}
+
+	now := time.Now()
+	ip, userAgent := middleware.GetClientFromContext(ctx)
+	device.IPAddress = ip
+	device.UserAgent = truncate(userAgent, 255)
+	device.LastSeenAt = now
+	if opts.trustDevice && user.TOTPEnabled && s.cfg.TwoFactor.TrustedDeviceTTL > 0 {
+		until := now.Add(s.cfg.TwoFactor.TrustedDeviceTTL)
+		device.TrustedUntil = &until
+		s.log.Security("device_trusted", user.ID).WithField("ip", ip).Info("Device trusted to skip two-factor authentication")
+	}
+
+	if device.ID != 0 {
+		if err := s.deviceRepo.Update(ctx, device); err != nil {
+			return "", fmt.Errorf("failed to update device: %w", err)
+		}
+		return "", nil
+	}
+
+	if err := s.deviceRepo.Create(ctx, device); err != nil {
+		return "", fmt.Errorf("failed to create device: %w", err)
+	}
+	if err := s.deviceRepo.Prune(ctx, user.ID, maxDevicesPerUser); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to prune devices")
+	}
+	return newToken, nil
+}
+
+// ListDevices returns the devices a user logged in from, most recently seen first
+func (s *userService) ListDevices(ctx context.Context, userID uint) ([]*models.Device, error) {
+	devices, err := s.deviceRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list devices")
+		return nil, fmt.Errorf("failed to list devices: %w", err)
+	}
+	return devices, nil
+}
+
+// DeleteDevice forgets a device of a user, so it has to complete two-factor authentication again
+func (s *userService) DeleteDevice(ctx context.Context, userID, id uint) error {
+	deleted, err := s.deviceRepo.Delete(ctx, userID, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to delete device")
+		return fmt.Errorf("failed to delete device: %w", err)
+	}
+	if !deleted {
+		return ErrDeviceNotFound
+	}
+
+	s.log.Security("device_removed", userID).WithField("device_id", id).Info("Device removed")
+	return nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"context"
+	"sort"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeDeviceRepository keeps devices in memory
+type fakeDeviceRepository struct {
+	devices map[uint]*models.Device
+	nextID  uint
+}
+
+func (r *fakeDeviceRepository) Create(ctx context.Context, device *models.Device) error {
+	if r.devices == nil {
+		r.devices = make(map[uint]*models.Device)
+	}
+	r.nextID++
+	device.ID = r.nextID
+	device.CreatedAt = time.Now()
+	copied := *device
+	r.devices[device.ID] = &copied
+	return nil
+}
+
+func (r *fakeDeviceRepository) GetByHash(ctx context.Context, userID uint, tokenHash string) (*models.Device, error) {
+	for _, device := range r.devices {
+		if device.UserID == userID && device.TokenHash == tokenHash {
+			copied := *device
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeDeviceRepository) Update(ctx context.Context, device *models.Device) error {
+	copied := *device
+	r.devices[device.ID] = &copied
+	return nil
+}
+
+func (r *fakeDeviceRepository) ListByUser(ctx context.Context, userID uint) ([]*models.Device, error) {
+	var devices []*models.Device
+	for _, device := range r.devices {
+		if device.UserID == userID {
+			copied := *device
+			devices = append(devices, &copied)
+		}
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].LastSeenAt.After(devices[j].LastSeenAt) })
+	return devices, nil
+}
+
+func (r *fakeDeviceRepository) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	device, ok := r.devices[id]
+	if !ok || device.UserID != userID {
+		return false, nil
+	}
+	delete(r.devices, id)
+	return true, nil
+}
+
+func (r *fakeDeviceRepository) Prune(ctx context.Context, userID uint, keep int) error {
+	devices, _ := r.ListByUser(ctx, userID)
+	for i := keep; i < len(devices); i++ {
+		delete(r.devices, devices[i].ID)
+	}
+	return nil
+}
+
+func (r *fakeDeviceRepository) RevokeTrust(ctx context.Context, userID uint) error {
+	for _, device := range r.devices {
+		if device.UserID == userID {
+			device.TrustedUntil = nil
+		}
+	}
+	return nil
+}
+
+func TestUserService_TrustedDevices(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.TwoFactor.ChallengeTTL = time.Minute
+	service.cfg.TwoFactor.TrustedDeviceTTL = time.Hour
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{
+		ID:          1,
+		Email:       "test@example.com",
+		Password:    string(hashedPassword),
+		IsActive:    true,
+		TOTPSecret:  secret,
+		TOTPEnabled: true,
+	}
+	mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil)
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("UseTOTPStep", ctx, user.ID, mock.AnythingOfType("int64")).Return(true, nil)
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+	mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil)
+	mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil)
+
+	login := func(deviceToken string) *models.LoginResponse {
+		resp, err := service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "password123", DeviceToken: deviceToken})
+		require.NoError(t, err)
+		return resp
+	}
+
+	// A new device completes two-factor authentication and asks to be trusted
+	challenge := login("")
+	require.True(t, challenge.TwoFactorRequired)
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	resp, err := service.VerifyTwoFactor(ctx, &models.TwoFactorVerifyRequest{
+		ChallengeToken: challenge.ChallengeToken,
+		Code:           code,
+		TrustDevice:    true,
+	})
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.DeviceToken)
+	deviceToken := resp.DeviceToken
+
+	t.Run("trusted device skips the challenge", func(t *testing.T) {
+		resp := login(deviceToken)
+		assert.False(t, resp.TwoFactorRequired)
+		assert.Equal(t, "token123", resp.AccessToken)
+		// Known devices keep their token
+		assert.Empty(t, resp.DeviceToken)
+	})
+
+	t.Run("unknown device token is challenged", func(t *testing.T) {
+		assert.True(t, login("unknown").TwoFactorRequired)
+	})
+
+	t.Run("revoked trust is challenged again", func(t *testing.T) {
+		require.NoError(t, service.deviceRepo.RevokeTrust(ctx, user.ID))
+		assert.True(t, login(deviceToken).TwoFactorRequired)
+	})
+
+	t.Run("devices can be listed and removed", func(t *testing.T) {
+		devices, err := service.ListDevices(ctx, user.ID)
+		require.NoError(t, err)
+		require.Len(t, devices, 1)
+
+		assert.ErrorIs(t, service.DeleteDevice(ctx, 2, devices[0].ID), ErrDeviceNotFound)
+		require.NoError(t, service.DeleteDevice(ctx, user.ID, devices[0].ID))
+		devices, err = service.ListDevices(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Empty(t, devices)
+	})
+}
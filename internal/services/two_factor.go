@@ -0,0 +1,229 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/totp"
+	"gbt-be-template/pkg/utils"
+)
+
+// EnrollTwoFactor generates a new TOTP secret for the user. Two-factor authentication stays off
+// until EnableTwoFactor confirms a code from the authenticator app, and enrolling again replaces
+// a secret that was never confirmed.
+func (s *userService) EnrollTwoFactor(ctx context.Context, userID uint) (*models.TwoFactorEnrollResponse, error) {
+	user, err := s.getUserForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate secret: %w", err)
+	}
+	user.TOTPSecret = secret
+	user.TOTPLastStep = 0
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to store TOTP secret")
+		return nil, fmt.Errorf("failed to enroll two-factor authentication: %w", err)
+	}
+
+	return &models.TwoFactorEnrollResponse{
+		Secret:          secret,
+		ProvisioningURI: totp.ProvisioningURI(secret, s.cfg.TwoFactor.Issuer, user.Email),
+	}, nil
+}
+
+// EnableTwoFactor turns two-factor authentication on once the user proves their authenticator
+// app produces codes for the enrolled secret. It returns the recovery codes to log in with
+// should the app be lost.
+func (s *userService) EnableTwoFactor(ctx context.Context, userID uint, req *models.TwoFactorCodeRequest) (*models.RecoveryCodesResponse, error) {
+	user, err := s.getUserForTwoFactor(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user.TOTPEnabled {
+		return nil, ErrTwoFactorEnabled
+	}
+	if user.TOTPSecret == "" {
+		return nil, ErrTwoFactorNotEnabled
+	}
+
+	if err := s.checkTwoFactorCode(ctx, user, req.Code); err != nil {
+		return nil, err
+	}
+	codes, err := s.issueRecoveryCodes(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	user.TOTPEnabled = true
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to enable two-factor authentication")
+		return nil, fmt.Errorf("failed to enable two-factor authentication: %w", err)
+	}
+
+	s.log.Security("two_factor_enabled", userID).Info("Two-factor authentication enabled")
+	s.recordActivity(ctx, userID, models.AuditTwoFactorEnabled, nil)
+	return &models.RecoveryCodesResponse{Codes: codes}, nil
+}
+
+// DisableTwoFactor turns two-factor authentication off and forgets the secret. Both the password
+// and a current code are required, so a stolen session alone can't remove the second factor.
+func (s *userService) DisableTwoFactor(ctx context.Context, userID uint, req *models.TwoFactorDisableRequest) error {
+	user, err := s.getUserForTwoFactor(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !user.TOTPEnabled {
+		return ErrTwoFactorNotEnabled
+	}
+
+	if err := s.backend.VerifyPassword(ctx, user, req.Password); err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) {
+			s.log.WithError(err).WithField("user_id", userID).Error("Failed to check password")
+			return fmt.Errorf("failed to check password: %w", err)
+		}
+		s.log.Security("two_factor_disable_denied", userID).Warn("Disabling two-factor authentication with invalid password")
+		return ErrInvalidPassword
+	}
+	if err := s.checkTwoFactorCode(ctx, user, req.Code); err != nil {
+		return err
+	}
+
+	user.TOTPEnabled = false
+	user.TOTPSecret = ""
+	user.TOTPLastStep = 0
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to disable two-factor authentication")
+		return fmt.Errorf("failed to disable two-factor authentication: %w", err)
+	}
+
+	// Trust only ever meant skipping the second factor; turning it back on later must ask everywhere
+	if err := s.deviceRepo.RevokeTrust(ctx, userID); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke trusted devices")
+	}
+	if err := s.recoveryRepo.DeleteByUser(ctx, userID); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to delete recovery codes")
+	}
+
+	s.log.Security("two_factor_disabled", userID).Warn("Two-factor authentication disabled")
+	s.recordActivity(ctx, userID, models.AuditTwoFactorDisabled, nil)
+	return nil
+}
+
+// VerifyTwoFactor completes a password login with the challenge token it returned and a TOTP code,
+// or one of the user's recovery codes. Wrong codes count as failed logins, so guessing codes ends
+// in a lockout.
+func (s *userService) VerifyTwoFactor(ctx context.Context, req *models.TwoFactorVerifyRequest) (*models.LoginResponse, error) {
+	token, err := s.tokenRepo.GetByHash(ctx, utils.HashToken(req.ChallengeToken))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get two-factor challenge")
+		return nil, fmt.Errorf("failed to get challenge: %w", err)
+	}
+	rememberMe := token != nil && token.Purpose == models.EmailTokenTwoFactorRememberMe
+	if token == nil || (token.Purpose != models.EmailTokenTwoFactor && !rememberMe) || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidChallenge
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", token.UserID).Error("Failed to get user for two-factor login")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.TOTPEnabled {
+		return nil, ErrInvalidChallenge
+	}
+	if user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, models.LoginMethodTwoFactor, models.LoginFailureAccountLocked)
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+
+	if req.RecoveryCode != "" {
+		err = s.useRecoveryCode(ctx, user, req.RecoveryCode)
+	} else {
+		err = s.checkTwoFactorCode(ctx, user, req.Code)
+	}
+	if err != nil {
+		if !errors.Is(err, ErrInvalidTwoFactorCode) {
+			return nil, err
+		}
+		s.log.WithField("user_id", user.ID).Warn("Invalid two-factor code attempt")
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, models.LoginMethodTwoFactor, models.LoginFailureInvalidCode)
+		var lockedErr *AccountLockedError
+		if errors.As(s.recordFailedLogin(ctx, user), &lockedErr) {
+			return nil, lockedErr
+		}
+		return nil, ErrInvalidTwoFactorCode
+	}
+
+	marked, err := s.tokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use challenge: %w", err)
+	}
+	if !marked {
+		return nil, ErrInvalidChallenge
+	}
+
+	return s.completeLogin(ctx, user, loginOptions{method: models.LoginMethodTwoFactor, rememberMe: rememberMe, deviceToken: req.DeviceToken, trustDevice: req.TrustDevice})
+}
+
+// startTwoFactorChallenge stores a short-lived challenge for a user whose password was accepted.
+// The challenge's purpose carries whether the login asked to be remembered.
+func (s *userService) startTwoFactorChallenge(ctx context.Context, user *models.User, rememberMe bool) (*models.LoginResponse, error) {
+	challenge, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	purpose := models.EmailTokenTwoFactor
+	if rememberMe {
+		purpose = models.EmailTokenTwoFactorRememberMe
+	}
+	if err := s.tokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   purpose,
+		TokenHash: utils.HashToken(challenge),
+		ExpiresAt: time.Now().Add(s.cfg.TwoFactor.ChallengeTTL),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to store two-factor challenge")
+		return nil, fmt.Errorf("failed to store challenge: %w", err)
+	}
+
+	return &models.LoginResponse{TwoFactorRequired: true, ChallengeToken: challenge}, nil
+}
+
+// checkTwoFactorCode validates a code against the user's secret and consumes its time step
+func (s *userService) checkTwoFactorCode(ctx context.Context, user *models.User, code string) error {
+	step, ok := totp.Validate(user.TOTPSecret, code, time.Now())
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	used, err := s.userRepo.UseTOTPStep(ctx, user.ID, step)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to record TOTP step")
+		return fmt.Errorf("failed to check two-factor code: %w", err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	user.TOTPLastStep = step
+	return nil
+}
+
+func (s *userService) getUserForTwoFactor(ctx context.Context, userID uint) (*models.User, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for two-factor authentication")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user, nil
+}
@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/totp"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserService_TwoFactorLogin(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.TwoFactor.ChallengeTTL = time.Minute
+	service.cfg.Lockout.MaxFailedAttempts = 5
+	ctx := context.Background()
+
+	secret, err := totp.GenerateSecret()
+	require.NoError(t, err)
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{
+		ID:          1,
+		Email:       "test@example.com",
+		Password:    string(hashedPassword),
+		IsActive:    true,
+		TOTPSecret:  secret,
+		TOTPEnabled: true,
+	}
+	req := &models.UserLoginRequest{Email: user.Email, Password: "password123", RememberMe: true}
+
+	// The password alone only earns a challenge
+	mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil).Once()
+	challenge, err := service.Login(ctx, req)
+	require.NoError(t, err)
+	assert.True(t, challenge.TwoFactorRequired)
+	assert.NotEmpty(t, challenge.ChallengeToken)
+	assert.Empty(t, challenge.AccessToken)
+
+	code, err := totp.Code(secret, time.Now())
+	require.NoError(t, err)
+	wrong := "000000"
+	if code == wrong {
+		wrong = "111111"
+	}
+
+	t.Run("wrong code counts as a failed login", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil).Once()
+		mockRepo.On("RecordFailedLogin", ctx, user.ID).Return(1, nil).Once()
+
+		resp, err := service.VerifyTwoFactor(ctx, &models.TwoFactorVerifyRequest{ChallengeToken: challenge.ChallengeToken, Code: wrong})
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		assert.Nil(t, resp)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("already used code is refused", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil).Once()
+		mockRepo.On("UseTOTPStep", ctx, user.ID, mock.AnythingOfType("int64")).Return(false, nil).Once()
+		mockRepo.On("RecordFailedLogin", ctx, user.ID).Return(2, nil).Once()
+
+		_, err := service.VerifyTwoFactor(ctx, &models.TwoFactorVerifyRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("valid code completes the login", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil).Once()
+		mockRepo.On("UseTOTPStep", ctx, user.ID, mock.AnythingOfType("int64")).Return(true, nil).Once()
+		mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil).Once()
+		// Asking to be remembered carries over from the password step
+		mockAuth.On("IssueRefreshToken", ctx, user.ID, true).Return("refresh123", nil).Once()
+		mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+
+		resp, err := service.VerifyTwoFactor(ctx, &models.TwoFactorVerifyRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		require.NoError(t, err)
+		assert.Equal(t, "token123", resp.AccessToken)
+		assert.Equal(t, "refresh123", resp.RefreshToken)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("challenge can't be used twice", func(t *testing.T) {
+		_, err := service.VerifyTwoFactor(ctx, &models.TwoFactorVerifyRequest{ChallengeToken: challenge.ChallengeToken, Code: code})
+		assert.ErrorIs(t, err, ErrInvalidChallenge)
+	})
+}
+
+func TestUserService_TwoFactorEnrollment(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	service.cfg.TwoFactor.Issuer = "gbt-be-template"
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), IsActive: true}
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("Update", ctx, user).Return(nil)
+	mockRepo.On("UseTOTPStep", ctx, user.ID, mock.AnythingOfType("int64")).Return(true, nil)
+
+	enrollment, err := service.EnrollTwoFactor(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, enrollment.Secret, user.TOTPSecret)
+	assert.Contains(t, enrollment.ProvisioningURI, "otpauth://totp/")
+	assert.False(t, user.TOTPEnabled)
+
+	// A code from a different secret doesn't confirm the enrollment
+	other, _ := totp.GenerateSecret()
+	otherCode, _ := totp.Code(other, time.Now())
+	_, err = service.EnableTwoFactor(ctx, user.ID, &models.TwoFactorCodeRequest{Code: otherCode})
+	assert.ErrorIs(t, err, ErrInvalidTwoFactorCode)
+	assert.False(t, user.TOTPEnabled)
+
+	code, _ := totp.Code(user.TOTPSecret, time.Now())
+	recovery, err := service.EnableTwoFactor(ctx, user.ID, &models.TwoFactorCodeRequest{Code: code})
+	require.NoError(t, err)
+	assert.Len(t, recovery.Codes, recoveryCodeCount)
+	assert.True(t, user.TOTPEnabled)
+	assert.True(t, user.ToResponse().TwoFactorEnabled)
+
+	_, err = service.EnrollTwoFactor(ctx, user.ID)
+	assert.ErrorIs(t, err, ErrTwoFactorEnabled)
+
+	// Disabling needs the password as well as a code
+	err = service.DisableTwoFactor(ctx, user.ID, &models.TwoFactorDisableRequest{Password: "wrong", Code: code})
+	assert.ErrorIs(t, err, ErrInvalidPassword)
+	assert.True(t, user.TOTPEnabled)
+
+	require.NoError(t, service.DisableTwoFactor(ctx, user.ID, &models.TwoFactorDisableRequest{Password: "password123", Code: code}))
+	assert.False(t, user.TOTPEnabled)
+	assert.Empty(t, user.TOTPSecret)
+	remaining, _ := service.recoveryRepo.CountUnused(ctx, user.ID)
+	assert.Zero(t, remaining)
+}
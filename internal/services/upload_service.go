@@ -0,0 +1,575 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/scanner"
+	"gbt-be-template/pkg/storage"
+	"gbt-be-template/pkg/utils"
+)
+
+// uploadCleanupBatchSize limits how many expired sessions one cleanup run handles
+const uploadCleanupBatchSize = 100
+
+var unsafeFilenameChars = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+
+// uploadService implements the UploadService interface
+type uploadService struct {
+	uploadRepo  repository.UploadRepository
+	fileService FileService
+	storage     storage.Storage
+	scanner     scanner.Scanner
+	cfg         *config.Config
+	log         *logger.Logger
+}
+
+// NewUploadService creates a new upload service
+func NewUploadService(uploadRepo repository.UploadRepository, fileService FileService, store storage.Storage, scan scanner.Scanner, cfg *config.Config, log *logger.Logger) UploadService {
+	return &uploadService{
+		uploadRepo:  uploadRepo,
+		fileService: fileService,
+		storage:     store,
+		scanner:     scan,
+		cfg:         cfg,
+		log:         log,
+	}
+}
+
+// Initiate starts a new chunked upload session
+func (s *uploadService) Initiate(ctx context.Context, userID uint, req *models.UploadInitiateRequest) (*models.UploadSessionResponse, error) {
+	session, err := s.createSession(ctx, userID, req, false)
+	if err != nil {
+		return nil, err
+	}
+	return session.ToResponse(nil), nil
+}
+
+// Presign starts an upload the client sends straight to object storage with a presigned PUT URL.
+// The client calls Confirm once the upload finished.
+func (s *uploadService) Presign(ctx context.Context, userID uint, req *models.UploadInitiateRequest) (*models.UploadPresignResponse, error) {
+	presigner, ok := s.storage.(storage.Presigner)
+	if !ok {
+		return nil, ErrPresignUnsupported
+	}
+
+	session, err := s.createSession(ctx, userID, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	url, headers, err := presigner.PresignPut(ctx, session.ObjectKey, session.TotalSize, session.ContentType, s.cfg.Upload.PresignExpiry)
+	if err != nil {
+		s.log.WithError(err).WithField("upload_id", session.ID).Error("Failed to presign upload")
+		return nil, fmt.Errorf("failed to presign upload: %w", err)
+	}
+
+	return &models.UploadPresignResponse{
+		Upload:    session.ToResponse(nil),
+		URL:       url,
+		Method:    "PUT",
+		Headers:   headers,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// createSession validates an upload request and records a new pending session
+func (s *uploadService) createSession(ctx context.Context, userID uint, req *models.UploadInitiateRequest, direct bool) (*models.UploadSession, error) {
+	if err := s.checkPolicy(req); err != nil {
+		return nil, err
+	}
+
+	// Fail early rather than after the whole file was transferred
+	if err := s.fileService.CheckQuota(ctx, userID, req.Size); err != nil {
+		return nil, err
+	}
+
+	id, err := utils.GenerateRandomToken(16)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate upload id: %w", err)
+	}
+
+	session := &models.UploadSession{
+		ID:          id,
+		UserID:      userID,
+		Purpose:     req.Purpose,
+		Filename:    req.Filename,
+		ContentType: req.ContentType,
+		TotalSize:   req.Size,
+		ChunkSize:   s.cfg.Upload.ChunkSize,
+		Status:      models.UploadStatusPending,
+		ExpiresAt:   time.Now().Add(s.cfg.Upload.SessionExpiry),
+	}
+	if direct {
+		// Direct uploads are a single PUT to the final location
+		session.Direct = true
+		session.ChunkSize = req.Size
+		session.ObjectKey = s.objectKey(session)
+		session.ExpiresAt = time.Now().Add(s.cfg.Upload.PresignExpiry)
+	}
+
+	if err := s.uploadRepo.CreateSession(ctx, session); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to create upload session")
+		return nil, fmt.Errorf("failed to create upload session: %w", err)
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id":   userID,
+		"upload_id": id,
+		"purpose":   req.Purpose,
+		"size":      req.Size,
+		"direct":    direct,
+	}).Info("Upload session started")
+
+	return session, nil
+}
+
+// UploadPart stores one part of an upload. Re-uploading a part replaces it, so clients can retry safely.
+func (s *uploadService) UploadPart(ctx context.Context, userID uint, id string, partNumber int, body io.Reader) (*models.UploadSessionResponse, error) {
+	session, err := s.chunkedSession(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if partNumber < 1 || partNumber > session.TotalParts() {
+		return nil, fmt.Errorf("%w: part number must be between 1 and %d", ErrUploadInvalid, session.TotalParts())
+	}
+
+	expected := session.PartSize(partNumber)
+	counter := &countingReader{r: io.LimitReader(body, expected+1)}
+	key := partKey(session.ID, partNumber)
+	if err := s.storage.Put(ctx, key, counter, expected, "application/octet-stream"); err != nil {
+		s.log.WithError(err).WithField("upload_id", id).Error("Failed to store upload part")
+		return nil, fmt.Errorf("failed to store upload part: %w", err)
+	}
+	if counter.n != expected {
+		_ = s.storage.Delete(ctx, key)
+		return nil, fmt.Errorf("%w: part %d must be %d bytes, got %d", ErrUploadInvalid, partNumber, expected, counter.n)
+	}
+
+	if err := s.uploadRepo.SavePart(ctx, &models.UploadPart{SessionID: session.ID, PartNumber: partNumber, Size: expected}); err != nil {
+		return nil, fmt.Errorf("failed to record upload part: %w", err)
+	}
+
+	return s.response(ctx, session)
+}
+
+// Get returns the state of an upload so interrupted clients can resume with the missing parts
+func (s *uploadService) Get(ctx context.Context, userID uint, id string) (*models.UploadSessionResponse, error) {
+	session, err := s.session(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	return s.response(ctx, session)
+}
+
+// Complete assembles the parts into the final object and removes the temporary parts.
+// The content is scanned for malware while it is assembled; flagged uploads are quarantined.
+func (s *uploadService) Complete(ctx context.Context, userID uint, id string, req *models.UploadCompleteRequest) (*models.UploadSessionResponse, error) {
+	session, err := s.chunkedSession(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+
+	parts, err := s.uploadRepo.ListParts(ctx, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upload parts: %w", err)
+	}
+	if len(parts) != session.TotalParts() {
+		return nil, fmt.Errorf("%w: received %d of %d parts", ErrUploadInvalid, len(parts), session.TotalParts())
+	}
+
+	keys := make([]string, len(parts))
+	for i, part := range parts {
+		keys[i] = partKey(session.ID, part.PartNumber)
+	}
+
+	objectKey := s.objectKey(session)
+	hash := sha256.New()
+	scan := scanner.NewStream(ctx, s.scanner)
+	assembled := io.TeeReader(&partsReader{ctx: ctx, storage: s.storage, keys: keys}, io.MultiWriter(hash, scan))
+	if err := s.storage.Put(ctx, objectKey, assembled, session.TotalSize, session.ContentType); err != nil {
+		scan.Abort(err)
+		s.log.WithError(err).WithField("upload_id", id).Error("Failed to assemble upload")
+		return nil, fmt.Errorf("failed to assemble upload: %w", err)
+	}
+	verdict, scanErr := scan.Finish()
+
+	if err := s.finalize(ctx, session, objectKey, hex.EncodeToString(hash.Sum(nil)), req.Checksum, verdict, scanErr); err != nil {
+		return nil, err
+	}
+	s.removeParts(ctx, session)
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id":    userID,
+		"upload_id":  id,
+		"object_key": objectKey,
+	}).Info("Upload completed")
+
+	return session.ToResponse(nil), nil
+}
+
+// Confirm records a presigned upload once the client finished sending it to storage.
+// The stored object is read back to verify its size and checksum and to scan it for malware.
+func (s *uploadService) Confirm(ctx context.Context, userID uint, id string, req *models.UploadCompleteRequest) (*models.UploadSessionResponse, error) {
+	session, err := s.pendingSession(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if !session.Direct {
+		return nil, fmt.Errorf("%w: not a presigned upload", ErrUploadInvalid)
+	}
+
+	reader, err := s.storage.Get(ctx, session.ObjectKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("%w: the file has not been uploaded yet", ErrUploadInvalid)
+		}
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	hash := sha256.New()
+	scan := scanner.NewStream(ctx, s.scanner)
+	counter := &countingReader{r: reader}
+	_, err = io.Copy(io.MultiWriter(hash, scan), counter)
+	reader.Close()
+	if err != nil {
+		scan.Abort(err)
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	verdict, scanErr := scan.Finish()
+
+	if counter.n != session.TotalSize {
+		_ = s.storage.Delete(ctx, session.ObjectKey)
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrUploadInvalid, session.TotalSize, counter.n)
+	}
+
+	if err := s.finalize(ctx, session, session.ObjectKey, hex.EncodeToString(hash.Sum(nil)), req.Checksum, verdict, scanErr); err != nil {
+		return nil, err
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id":    userID,
+		"upload_id":  id,
+		"object_key": session.ObjectKey,
+	}).Info("Presigned upload confirmed")
+
+	return session.ToResponse(nil), nil
+}
+
+// finalize checks a stored upload's checksum, scan verdict and content, then records it as a file.
+// The object is removed or quarantined when any check fails.
+func (s *uploadService) finalize(ctx context.Context, session *models.UploadSession, objectKey, checksum, expectedChecksum string, verdict *scanner.Result, scanErr error) error {
+	if expectedChecksum != "" && expectedChecksum != checksum {
+		_ = s.storage.Delete(ctx, objectKey)
+		return fmt.Errorf("%w: checksum mismatch", ErrUploadInvalid)
+	}
+
+	if scanErr != nil {
+		if !s.cfg.Scanner.FailOpen {
+			_ = s.storage.Delete(ctx, objectKey)
+			s.log.WithError(scanErr).WithField("upload_id", session.ID).Error("Malware scan failed")
+			return ErrScanUnavailable
+		}
+		s.log.WithError(scanErr).WithField("upload_id", session.ID).Warn("Malware scan failed, accepting upload unscanned")
+	} else if verdict.Infected {
+		return s.quarantine(ctx, session, objectKey, verdict.Signature)
+	}
+
+	content, err := s.inspect(ctx, session, objectKey, checksum)
+	if err != nil {
+		_ = s.storage.Delete(ctx, objectKey)
+		if errors.Is(err, ErrUploadContentRejected) {
+			s.log.WithError(err).WithFields(map[string]interface{}{
+				"upload_id": session.ID,
+				"filename":  session.Filename,
+			}).Warn("Upload content rejected")
+		}
+		return err
+	}
+
+	file, err := s.fileService.Create(ctx, &models.File{
+		UserID:      session.UserID,
+		Purpose:     session.Purpose,
+		Filename:    session.Filename,
+		ContentType: content.contentType,
+		Size:        content.size,
+		Checksum:    content.checksum,
+		ObjectKey:   objectKey,
+	})
+	if err != nil {
+		_ = s.storage.Delete(ctx, objectKey)
+		return err
+	}
+
+	session.Status = models.UploadStatusCompleted
+	session.ObjectKey = objectKey
+	session.ContentType = content.contentType
+	session.Checksum = content.checksum
+	session.FileID = &file.ID
+	if err := s.uploadRepo.UpdateSession(ctx, session); err != nil {
+		return fmt.Errorf("failed to complete upload: %w", err)
+	}
+	return nil
+}
+
+// quarantine moves a flagged upload out of reach of file listings and downloads, keeping it for review
+func (s *uploadService) quarantine(ctx context.Context, session *models.UploadSession, objectKey, signature string) error {
+	quarantineKey := path.Join("quarantine", fmt.Sprint(session.UserID), session.ID, safeFilename(session.Filename))
+	if err := s.moveObject(ctx, objectKey, quarantineKey, session.TotalSize); err != nil {
+		s.log.WithError(err).WithField("upload_id", session.ID).Error("Failed to quarantine upload")
+		// Never leave infected content at its regular location
+		_ = s.storage.Delete(ctx, objectKey)
+		quarantineKey = ""
+	}
+
+	if !session.Direct {
+		s.removeParts(ctx, session)
+	}
+	session.Status = models.UploadStatusQuarantined
+	session.ObjectKey = quarantineKey
+	if err := s.uploadRepo.UpdateSession(ctx, session); err != nil {
+		s.log.WithError(err).WithField("upload_id", session.ID).Error("Failed to mark upload as quarantined")
+	}
+
+	s.log.Security("upload_quarantined", session.UserID).WithFields(map[string]interface{}{
+		"upload_id":  session.ID,
+		"filename":   session.Filename,
+		"signature":  signature,
+		"object_key": quarantineKey,
+	}).Warn("Malware detected in upload")
+
+	return ErrUploadInfected
+}
+
+// moveObject copies an object to a new key and deletes the original
+func (s *uploadService) moveObject(ctx context.Context, from, to string, size int64) error {
+	reader, err := s.storage.Get(ctx, from)
+	if err != nil {
+		return err
+	}
+	err = s.storage.Put(ctx, to, reader, size, "application/octet-stream")
+	reader.Close()
+	if err != nil {
+		return err
+	}
+	return s.storage.Delete(ctx, from)
+}
+
+// Store uploads a whole file sent in a single request, for small files such as avatars. The file
+// is validated, scanned and recorded exactly like a completed chunked upload.
+func (s *uploadService) Store(ctx context.Context, userID uint, req *models.UploadInitiateRequest, body io.Reader) (*models.UploadSessionResponse, error) {
+	// The session is written straight to its final location, like a presigned upload
+	session, err := s.createSession(ctx, userID, req, true)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := sha256.New()
+	scan := scanner.NewStream(ctx, s.scanner)
+	counter := &countingReader{r: io.LimitReader(body, session.TotalSize+1)}
+	if err := s.storage.Put(ctx, session.ObjectKey, io.TeeReader(counter, io.MultiWriter(hash, scan)), session.TotalSize, session.ContentType); err != nil {
+		scan.Abort(err)
+		s.log.WithError(err).WithField("upload_id", session.ID).Error("Failed to store upload")
+		return nil, fmt.Errorf("failed to store upload: %w", err)
+	}
+	verdict, scanErr := scan.Finish()
+
+	if counter.n != session.TotalSize {
+		_ = s.storage.Delete(ctx, session.ObjectKey)
+		return nil, fmt.Errorf("%w: expected %d bytes, got %d", ErrUploadInvalid, session.TotalSize, counter.n)
+	}
+
+	if err := s.finalize(ctx, session, session.ObjectKey, hex.EncodeToString(hash.Sum(nil)), "", verdict, scanErr); err != nil {
+		return nil, err
+	}
+
+	s.log.WithFields(map[string]interface{}{
+		"user_id":    userID,
+		"upload_id":  session.ID,
+		"object_key": session.ObjectKey,
+	}).Info("Upload stored")
+
+	return session.ToResponse(nil), nil
+}
+
+// Abort cancels an upload and removes its temporary parts
+func (s *uploadService) Abort(ctx context.Context, userID uint, id string) error {
+	session, err := s.pendingSession(ctx, userID, id)
+	if err != nil {
+		return err
+	}
+
+	session.Status = models.UploadStatusAborted
+	if err := s.uploadRepo.UpdateSession(ctx, session); err != nil {
+		return fmt.Errorf("failed to abort upload: %w", err)
+	}
+
+	s.removeParts(ctx, session)
+	return nil
+}
+
+// CleanupExpired removes the temporary parts of uploads that were never completed
+func (s *uploadService) CleanupExpired(ctx context.Context) (int, error) {
+	sessions, err := s.uploadRepo.ListExpiredSessions(ctx, time.Now(), uploadCleanupBatchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list expired uploads: %w", err)
+	}
+
+	for _, session := range sessions {
+		session.Status = models.UploadStatusExpired
+		if err := s.uploadRepo.UpdateSession(ctx, session); err != nil {
+			return 0, fmt.Errorf("failed to expire upload: %w", err)
+		}
+		s.removeParts(ctx, session)
+	}
+
+	if len(sessions) > 0 {
+		s.log.WithField("count", len(sessions)).Info("Expired uploads cleaned up")
+	}
+	return len(sessions), nil
+}
+
+// session loads an upload owned by the user
+func (s *uploadService) session(ctx context.Context, userID uint, id string) (*models.UploadSession, error) {
+	session, err := s.uploadRepo.GetSession(ctx, id)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get upload: %w", err)
+	}
+	if session == nil || session.UserID != userID {
+		return nil, ErrUploadNotFound
+	}
+	return session, nil
+}
+
+// chunkedSession loads a pending chunked upload owned by the user
+func (s *uploadService) chunkedSession(ctx context.Context, userID uint, id string) (*models.UploadSession, error) {
+	session, err := s.pendingSession(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Direct {
+		return nil, fmt.Errorf("%w: presigned uploads are confirmed, not sent in parts", ErrUploadInvalid)
+	}
+	return session, nil
+}
+
+// pendingSession loads an upload owned by the user that still accepts changes
+func (s *uploadService) pendingSession(ctx context.Context, userID uint, id string) (*models.UploadSession, error) {
+	session, err := s.session(ctx, userID, id)
+	if err != nil {
+		return nil, err
+	}
+	if session.Status != models.UploadStatusPending || time.Now().After(session.ExpiresAt) {
+		return nil, ErrUploadNotPending
+	}
+	return session, nil
+}
+
+// response builds the session response including received parts
+func (s *uploadService) response(ctx context.Context, session *models.UploadSession) (*models.UploadSessionResponse, error) {
+	parts, err := s.uploadRepo.ListParts(ctx, session.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list upload parts: %w", err)
+	}
+	return session.ToResponse(parts), nil
+}
+
+// removeParts deletes temporary part objects and records; failures are logged and left for the next cleanup.
+// For unfinished presigned uploads the partially or fully uploaded object is removed instead.
+func (s *uploadService) removeParts(ctx context.Context, session *models.UploadSession) {
+	if session.Direct {
+		if err := s.storage.Delete(ctx, session.ObjectKey); err != nil {
+			s.log.WithError(err).WithField("upload_id", session.ID).Warn("Failed to delete presigned upload")
+		}
+		return
+	}
+	for partNumber := 1; partNumber <= session.TotalParts(); partNumber++ {
+		if err := s.storage.Delete(ctx, partKey(session.ID, partNumber)); err != nil {
+			s.log.WithError(err).WithField("upload_id", session.ID).Warn("Failed to delete upload part")
+		}
+	}
+	if err := s.uploadRepo.DeleteParts(ctx, session.ID); err != nil {
+		s.log.WithError(err).WithField("upload_id", session.ID).Warn("Failed to delete upload part records")
+	}
+}
+
+// objectKey returns the final storage key of an upload
+func (s *uploadService) objectKey(session *models.UploadSession) string {
+	return path.Join("uploads", session.Purpose, fmt.Sprint(session.UserID), session.ID, safeFilename(session.Filename))
+}
+
+// partKey returns the temporary storage key of a part
+func partKey(sessionID string, partNumber int) string {
+	return fmt.Sprintf("tmp/uploads/%s/%d", sessionID, partNumber)
+}
+
+// safeFilename strips directories and unusual characters from a client supplied filename
+func safeFilename(filename string) string {
+	name := unsafeFilenameChars.ReplaceAllString(path.Base(filename), "_")
+	if name == "" || name == "." || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// partsReader reads stored parts back to back, opening each one only when it is reached
+type partsReader struct {
+	ctx     context.Context
+	storage storage.Storage
+	keys    []string
+	current io.ReadCloser
+}
+
+func (p *partsReader) Read(b []byte) (int, error) {
+	for {
+		if p.current == nil {
+			if len(p.keys) == 0 {
+				return 0, io.EOF
+			}
+			reader, err := p.storage.Get(p.ctx, p.keys[0])
+			if err != nil {
+				return 0, err
+			}
+			p.current, p.keys = reader, p.keys[1:]
+		}
+
+		n, err := p.current.Read(b)
+		if err == io.EOF {
+			p.current.Close()
+			p.current = nil
+			if n > 0 {
+				return n, nil
+			}
+			continue
+		}
+		if err != nil {
+			p.current.Close()
+			p.current = nil
+		}
+		return n, err
+	}
+}
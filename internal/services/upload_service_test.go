@@ -0,0 +1,493 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/scanner"
+	"gbt-be-template/pkg/storage"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeUploadRepository is an in-memory UploadRepository
+type fakeUploadRepository struct {
+	sessions map[string]*models.UploadSession
+	parts    map[string]map[int]*models.UploadPart
+}
+
+func newFakeUploadRepository() *fakeUploadRepository {
+	return &fakeUploadRepository{
+		sessions: make(map[string]*models.UploadSession),
+		parts:    make(map[string]map[int]*models.UploadPart),
+	}
+}
+
+func (f *fakeUploadRepository) CreateSession(ctx context.Context, session *models.UploadSession) error {
+	copied := *session
+	f.sessions[session.ID] = &copied
+	return nil
+}
+
+func (f *fakeUploadRepository) GetSession(ctx context.Context, id string) (*models.UploadSession, error) {
+	session, ok := f.sessions[id]
+	if !ok {
+		return nil, nil
+	}
+	copied := *session
+	return &copied, nil
+}
+
+func (f *fakeUploadRepository) UpdateSession(ctx context.Context, session *models.UploadSession) error {
+	return f.CreateSession(ctx, session)
+}
+
+func (f *fakeUploadRepository) SavePart(ctx context.Context, part *models.UploadPart) error {
+	if f.parts[part.SessionID] == nil {
+		f.parts[part.SessionID] = make(map[int]*models.UploadPart)
+	}
+	f.parts[part.SessionID][part.PartNumber] = part
+	return nil
+}
+
+func (f *fakeUploadRepository) ListParts(ctx context.Context, sessionID string) ([]*models.UploadPart, error) {
+	parts := make([]*models.UploadPart, 0, len(f.parts[sessionID]))
+	for _, part := range f.parts[sessionID] {
+		parts = append(parts, part)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	return parts, nil
+}
+
+func (f *fakeUploadRepository) DeleteParts(ctx context.Context, sessionID string) error {
+	delete(f.parts, sessionID)
+	return nil
+}
+
+func (f *fakeUploadRepository) ListExpiredSessions(ctx context.Context, before time.Time, limit int) ([]*models.UploadSession, error) {
+	var sessions []*models.UploadSession
+	for _, session := range f.sessions {
+		if session.Status == models.UploadStatusPending && session.ExpiresAt.Before(before) {
+			copied := *session
+			sessions = append(sessions, &copied)
+		}
+	}
+	return sessions, nil
+}
+
+func setupUploadService(t *testing.T) (*uploadService, *fakeUploadRepository, storage.Storage) {
+	store, err := storage.NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	repo := newFakeUploadRepository()
+	cfg := &config.Config{Upload: config.UploadConfig{
+		ChunkSize:     4,
+		SessionExpiry: time.Hour,
+		PresignExpiry: time.Minute,
+		UserQuota:     20,
+		Avatar:        config.UploadPolicy{MaxSize: 8},
+		Import:        config.UploadPolicy{MaxSize: 100},
+	}}
+	log := logger.New("error", "text")
+	fileSvc := NewFileService(newFakeFileRepository(), store, cfg, log)
+	service := NewUploadService(repo, fileSvc, store, scanner.Noop{}, cfg, log).(*uploadService)
+	return service, repo, store
+}
+
+func TestUploadService_ChunkedUpload(t *testing.T) {
+	service, repo, store := setupUploadService(t)
+	ctx := context.Background()
+	content := []byte("hello, world")
+	sum := sha256.Sum256(content)
+
+	upload, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "../data.csv", Size: int64(len(content)), Purpose: models.UploadPurposeImport})
+	require.NoError(t, err)
+	assert.Equal(t, 3, upload.TotalParts)
+
+	// Parts may arrive out of order and be retried
+	for _, part := range []int{3, 1, 1, 2} {
+		start := (part - 1) * 4
+		_, err := service.UploadPart(ctx, 1, upload.ID, part, bytes.NewReader(content[start:start+4]))
+		require.NoError(t, err)
+	}
+
+	status, err := service.Get(ctx, 1, upload.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 2, 3}, status.ReceivedParts)
+
+	completed, err := service.Complete(ctx, 1, upload.ID, &models.UploadCompleteRequest{Checksum: hex.EncodeToString(sum[:])})
+	require.NoError(t, err)
+	assert.Equal(t, models.UploadStatusCompleted, completed.Status)
+	assert.Equal(t, "uploads/import/1/"+upload.ID+"/data.csv", completed.ObjectKey)
+	require.NotNil(t, completed.FileID)
+
+	reader, err := store.Get(ctx, completed.ObjectKey)
+	require.NoError(t, err)
+	assembled, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, content, assembled)
+
+	// Temporary parts are removed
+	assert.Empty(t, repo.parts[upload.ID])
+	exists, _ := store.Exists(ctx, partKey(upload.ID, 1))
+	assert.False(t, exists)
+
+	// Completed uploads can't be changed
+	_, err = service.UploadPart(ctx, 1, upload.ID, 1, bytes.NewReader(content[:4]))
+	assert.ErrorIs(t, err, ErrUploadNotPending)
+}
+
+// fakeScanner flags content containing a marker string, or fails when err is set
+type fakeScanner struct {
+	err error
+}
+
+func (f *fakeScanner) Scan(ctx context.Context, r io.Reader) (*scanner.Result, error) {
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	if bytes.Contains(content, []byte("EVIL")) {
+		return &scanner.Result{Infected: true, Signature: "Test.Evil"}, nil
+	}
+	return &scanner.Result{}, nil
+}
+
+func TestUploadService_MalwareScan(t *testing.T) {
+	ctx := context.Background()
+
+	upload := func(t *testing.T, service *uploadService, content string) (*models.UploadSessionResponse, error) {
+		session, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "a.csv", Size: int64(len(content)), Purpose: models.UploadPurposeImport})
+		require.NoError(t, err)
+		_, err = service.UploadPart(ctx, 1, session.ID, 1, strings.NewReader(content))
+		require.NoError(t, err)
+		return service.Complete(ctx, 1, session.ID, &models.UploadCompleteRequest{})
+	}
+
+	t.Run("infected uploads are quarantined", func(t *testing.T) {
+		service, repo, store := setupUploadService(t)
+		service.scanner = &fakeScanner{}
+
+		_, err := upload(t, service, "EVIL")
+		assert.ErrorIs(t, err, ErrUploadInfected)
+
+		var session *models.UploadSession
+		for _, s := range repo.sessions {
+			session = s
+		}
+		require.NotNil(t, session)
+		assert.Equal(t, models.UploadStatusQuarantined, session.Status)
+		assert.Equal(t, "quarantine/1/"+session.ID+"/a.csv", session.ObjectKey)
+		assert.Nil(t, session.FileID)
+
+		exists, _ := store.Exists(ctx, session.ObjectKey)
+		assert.True(t, exists)
+		exists, _ = store.Exists(ctx, "uploads/import/1/"+session.ID+"/a.csv")
+		assert.False(t, exists)
+	})
+
+	t.Run("clean uploads are accepted", func(t *testing.T) {
+		service, _, _ := setupUploadService(t)
+		service.scanner = &fakeScanner{}
+
+		completed, err := upload(t, service, "good")
+		require.NoError(t, err)
+		assert.Equal(t, models.UploadStatusCompleted, completed.Status)
+	})
+
+	t.Run("scanner outage fails closed", func(t *testing.T) {
+		service, _, _ := setupUploadService(t)
+		service.scanner = &fakeScanner{err: errors.New("connection refused")}
+
+		_, err := upload(t, service, "good")
+		assert.ErrorIs(t, err, ErrScanUnavailable)
+	})
+
+	t.Run("scanner outage can fail open", func(t *testing.T) {
+		service, _, _ := setupUploadService(t)
+		service.scanner = &fakeScanner{err: errors.New("connection refused")}
+		service.cfg.Scanner.FailOpen = true
+
+		completed, err := upload(t, service, "good")
+		require.NoError(t, err)
+		assert.Equal(t, models.UploadStatusCompleted, completed.Status)
+	})
+}
+
+func TestUploadService_Validation(t *testing.T) {
+	service, _, _ := setupUploadService(t)
+	ctx := context.Background()
+
+	t.Run("size over purpose limit", func(t *testing.T) {
+		_, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "a.png", Size: 9, Purpose: models.UploadPurposeAvatar})
+		assert.ErrorIs(t, err, ErrUploadInvalid)
+	})
+
+	upload, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "a.png", Size: 6, Purpose: models.UploadPurposeAvatar})
+	require.NoError(t, err)
+
+	t.Run("size over storage quota", func(t *testing.T) {
+		_, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "a.csv", Size: 21, Purpose: models.UploadPurposeImport})
+		assert.ErrorIs(t, err, ErrStorageQuotaExceeded)
+	})
+
+	t.Run("wrong part size", func(t *testing.T) {
+		_, err := service.UploadPart(ctx, 1, upload.ID, 1, bytes.NewReader([]byte("abc")))
+		assert.ErrorIs(t, err, ErrUploadInvalid)
+		_, err = service.UploadPart(ctx, 1, upload.ID, 2, bytes.NewReader([]byte("abc")))
+		assert.ErrorIs(t, err, ErrUploadInvalid)
+	})
+
+	t.Run("part out of range", func(t *testing.T) {
+		_, err := service.UploadPart(ctx, 1, upload.ID, 3, bytes.NewReader([]byte("ab")))
+		assert.ErrorIs(t, err, ErrUploadInvalid)
+	})
+
+	t.Run("other users can't see the upload", func(t *testing.T) {
+		_, err := service.Get(ctx, 2, upload.ID)
+		assert.ErrorIs(t, err, ErrUploadNotFound)
+	})
+
+	t.Run("incomplete upload can't be completed", func(t *testing.T) {
+		_, err := service.Complete(ctx, 1, upload.ID, &models.UploadCompleteRequest{})
+		assert.ErrorIs(t, err, ErrUploadInvalid)
+	})
+}
+
+func TestUploadService_ContentValidation(t *testing.T) {
+	ctx := context.Background()
+
+	setup := func(t *testing.T) (*uploadService, storage.Storage) {
+		service, _, store := setupUploadService(t)
+		service.cfg.Upload.ChunkSize = 1024
+		service.cfg.Upload.UserQuota = 0
+		service.cfg.Upload.Avatar = config.UploadPolicy{
+			MaxSize:      4096,
+			Extensions:   []string{".png", ".svg"},
+			ContentTypes: []string{"image/png", "image/svg+xml"},
+			MaxWidth:     4,
+			MaxHeight:    4,
+		}
+		return service, store
+	}
+
+	upload := func(t *testing.T, service *uploadService, filename string, content []byte) (*models.UploadSessionResponse, error) {
+		session, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: filename, Size: int64(len(content)), Purpose: models.UploadPurposeAvatar})
+		require.NoError(t, err)
+		_, err = service.UploadPart(ctx, 1, session.ID, 1, bytes.NewReader(content))
+		require.NoError(t, err)
+		return service.Complete(ctx, 1, session.ID, &models.UploadCompleteRequest{})
+	}
+
+	encodePNG := func(t *testing.T, width, height int) []byte {
+		var buf bytes.Buffer
+		require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))))
+		return buf.Bytes()
+	}
+
+	t.Run("disallowed extension is rejected up front", func(t *testing.T) {
+		service, _ := setup(t)
+		_, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "a.exe", Size: 10, Purpose: models.UploadPurposeAvatar})
+		assert.ErrorIs(t, err, ErrUploadContentRejected)
+	})
+
+	t.Run("disallowed declared content type is rejected up front", func(t *testing.T) {
+		service, _ := setup(t)
+		_, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "a.png", ContentType: "text/html", Size: 10, Purpose: models.UploadPurposeAvatar})
+		assert.ErrorIs(t, err, ErrUploadContentRejected)
+	})
+
+	t.Run("content type is detected from the content", func(t *testing.T) {
+		service, _ := setup(t)
+		completed, err := upload(t, service, "a.png", encodePNG(t, 4, 4))
+		require.NoError(t, err)
+		assert.Equal(t, "image/png", completed.ContentType)
+	})
+
+	t.Run("content must match the extension", func(t *testing.T) {
+		service, _ := setup(t)
+		_, err := upload(t, service, "a.png", []byte("<html><script>alert(1)</script></html>"))
+		assert.ErrorIs(t, err, ErrUploadContentRejected)
+
+		files, err := service.fileService.ListByUser(ctx, 1)
+		require.NoError(t, err)
+		assert.Empty(t, files.Files)
+	})
+
+	t.Run("oversized images are rejected", func(t *testing.T) {
+		service, _ := setup(t)
+		_, err := upload(t, service, "a.png", encodePNG(t, 5, 2))
+		assert.ErrorIs(t, err, ErrUploadContentRejected)
+	})
+
+	t.Run("SVG is sanitized", func(t *testing.T) {
+		service, store := setup(t)
+		svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" onload="alert(1)"><script>alert(2)</script><circle r="1"/></svg>`)
+
+		completed, err := upload(t, service, "a.svg", svg)
+		require.NoError(t, err)
+		assert.Equal(t, "image/svg+xml", completed.ContentType)
+
+		reader, err := store.Get(ctx, completed.ObjectKey)
+		require.NoError(t, err)
+		stored, _ := io.ReadAll(reader)
+		reader.Close()
+		assert.NotContains(t, string(stored), "alert")
+		assert.Contains(t, string(stored), "<circle")
+
+		sum := sha256.Sum256(stored)
+		assert.Equal(t, hex.EncodeToString(sum[:]), completed.Checksum)
+	})
+}
+
+func TestUploadService_AbortAndCleanup(t *testing.T) {
+	service, repo, store := setupUploadService(t)
+	ctx := context.Background()
+
+	aborted, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "a.csv", Size: 4, Purpose: models.UploadPurposeImport})
+	require.NoError(t, err)
+	_, err = service.UploadPart(ctx, 1, aborted.ID, 1, bytes.NewReader([]byte("abcd")))
+	require.NoError(t, err)
+
+	require.NoError(t, service.Abort(ctx, 1, aborted.ID))
+	assert.Equal(t, models.UploadStatusAborted, repo.sessions[aborted.ID].Status)
+	exists, _ := store.Exists(ctx, partKey(aborted.ID, 1))
+	assert.False(t, exists)
+
+	expired, err := service.Initiate(ctx, 1, &models.UploadInitiateRequest{Filename: "b.csv", Size: 4, Purpose: models.UploadPurposeImport})
+	require.NoError(t, err)
+	_, err = service.UploadPart(ctx, 1, expired.ID, 1, bytes.NewReader([]byte("abcd")))
+	require.NoError(t, err)
+	repo.sessions[expired.ID].ExpiresAt = time.Now().Add(-time.Minute)
+
+	cleaned, err := service.CleanupExpired(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, cleaned)
+	assert.Equal(t, models.UploadStatusExpired, repo.sessions[expired.ID].Status)
+	exists, _ = store.Exists(ctx, partKey(expired.ID, 1))
+	assert.False(t, exists)
+}
+
+// presigningStorage adds fake presigned URLs to a local store
+type presigningStorage struct {
+	storage.Storage
+}
+
+func (p *presigningStorage) PresignPut(ctx context.Context, key string, size int64, contentType string, expires time.Duration) (string, map[string]string, error) {
+	return "https://bucket.test/" + key + "?op=put", map[string]string{"Content-Length": fmt.Sprint(size), "Content-Type": contentType}, nil
+}
+
+func (p *presigningStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return "https://bucket.test/" + key + "?op=get", nil
+}
+
+func TestUploadService_Presigned(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("requires a presigning store", func(t *testing.T) {
+		service, _, _ := setupUploadService(t)
+		_, err := service.Presign(ctx, 1, &models.UploadInitiateRequest{Filename: "a.csv", Size: 5, Purpose: models.UploadPurposeImport})
+		assert.ErrorIs(t, err, ErrPresignUnsupported)
+	})
+
+	service, _, store := setupUploadService(t)
+	service.storage = &presigningStorage{Storage: store}
+	service.fileService.(*fileService).storage = service.storage
+
+	presigned, err := service.Presign(ctx, 1, &models.UploadInitiateRequest{Filename: "a.csv", ContentType: "text/csv", Size: 5, Purpose: models.UploadPurposeImport})
+	require.NoError(t, err)
+	objectKey := "uploads/import/1/" + presigned.Upload.ID + "/a.csv"
+	assert.Equal(t, "https://bucket.test/"+objectKey+"?op=put", presigned.URL)
+	assert.Equal(t, map[string]string{"Content-Length": "5", "Content-Type": "text/csv"}, presigned.Headers)
+	assert.True(t, presigned.Upload.Direct)
+
+	// Presigned uploads don't accept parts
+	_, err = service.UploadPart(ctx, 1, presigned.Upload.ID, 1, strings.NewReader("hello"))
+	assert.ErrorIs(t, err, ErrUploadInvalid)
+
+	// Confirming before the client uploaded fails
+	_, err = service.Confirm(ctx, 1, presigned.Upload.ID, &models.UploadCompleteRequest{})
+	assert.ErrorIs(t, err, ErrUploadInvalid)
+
+	// The client uploads straight to storage
+	require.NoError(t, store.Put(ctx, objectKey, strings.NewReader("hello"), 5, "text/csv"))
+
+	_, err = service.Confirm(ctx, 2, presigned.Upload.ID, &models.UploadCompleteRequest{})
+	assert.ErrorIs(t, err, ErrUploadNotFound)
+
+	confirmed, err := service.Confirm(ctx, 1, presigned.Upload.ID, &models.UploadCompleteRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, models.UploadStatusCompleted, confirmed.Status)
+	require.NotNil(t, confirmed.FileID)
+
+	download, err := service.fileService.DownloadURL(ctx, 1, false, *confirmed.FileID)
+	require.NoError(t, err)
+	assert.Equal(t, "https://bucket.test/"+objectKey+"?op=get", download.URL)
+
+	// Other users can't get a download URL
+	_, err = service.fileService.DownloadURL(ctx, 2, false, *confirmed.FileID)
+	assert.ErrorIs(t, err, ErrFileNotFound)
+}
+
+func TestUploadService_PresignedSizeMismatch(t *testing.T) {
+	ctx := context.Background()
+	service, _, store := setupUploadService(t)
+	service.storage = &presigningStorage{Storage: store}
+
+	presigned, err := service.Presign(ctx, 1, &models.UploadInitiateRequest{Filename: "a.csv", Size: 5, Purpose: models.UploadPurposeImport})
+	require.NoError(t, err)
+	objectKey := "uploads/import/1/" + presigned.Upload.ID + "/a.csv"
+	require.NoError(t, store.Put(ctx, objectKey, strings.NewReader("hello world"), 11, "text/csv"))
+
+	_, err = service.Confirm(ctx, 1, presigned.Upload.ID, &models.UploadCompleteRequest{})
+	assert.ErrorIs(t, err, ErrUploadInvalid)
+
+	exists, _ := store.Exists(ctx, objectKey)
+	assert.False(t, exists)
+}
+
+func TestUploadService_Store(t *testing.T) {
+	ctx := context.Background()
+	service, _, store := setupUploadService(t)
+
+	stored, err := service.Store(ctx, 1, &models.UploadInitiateRequest{Filename: "a.csv", Size: 5, Purpose: models.UploadPurposeImport}, strings.NewReader("hello"))
+	require.NoError(t, err)
+	assert.Equal(t, models.UploadStatusCompleted, stored.Status)
+	assert.Equal(t, "uploads/import/1/"+stored.ID+"/a.csv", stored.ObjectKey)
+	require.NotNil(t, stored.FileID)
+
+	reader, err := store.Get(ctx, stored.ObjectKey)
+	require.NoError(t, err)
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "hello", string(content))
+
+	t.Run("size over purpose limit", func(t *testing.T) {
+		_, err := service.Store(ctx, 1, &models.UploadInitiateRequest{Filename: "a.png", Size: 9, Purpose: models.UploadPurposeAvatar}, strings.NewReader("123456789"))
+		assert.ErrorIs(t, err, ErrUploadInvalid)
+	})
+
+	t.Run("body longer than declared", func(t *testing.T) {
+		upload, err := service.Store(ctx, 1, &models.UploadInitiateRequest{Filename: "b.csv", Size: 5, Purpose: models.UploadPurposeImport}, strings.NewReader("hello world"))
+		assert.ErrorIs(t, err, ErrUploadInvalid)
+		assert.Nil(t, upload)
+	})
+}
@@ -0,0 +1,130 @@
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/filetype"
+	"gbt-be-template/pkg/imaging"
+)
+
+// inspectedContent describes a stored upload after its content was validated
+type inspectedContent struct {
+	contentType string // Detected from the content, never taken from the client
+	size        int64
+	checksum    string
+}
+
+// checkPolicy rejects uploads the purpose's policy disallows before any content is transferred
+func (s *uploadService) checkPolicy(req *models.UploadInitiateRequest) error {
+	policy := s.cfg.Upload.Policy(req.Purpose)
+	if req.Size > policy.MaxSize {
+		return fmt.Errorf("%w: file exceeds the maximum size of %d bytes", ErrUploadInvalid, policy.MaxSize)
+	}
+
+	if ext := filetype.Extension(req.Filename); !allowed(policy.Extensions, ext) {
+		return fmt.Errorf("%w: extension %q is not allowed for %s uploads", ErrUploadContentRejected, ext, req.Purpose)
+	}
+
+	// The declared type is only a hint, the content is checked again once uploaded
+	if req.ContentType != "" && !allowed(policy.ContentTypes, filetype.Normalize(req.ContentType)) {
+		return fmt.Errorf("%w: content type %q is not allowed for %s uploads", ErrUploadContentRejected, req.ContentType, req.Purpose)
+	}
+	return nil
+}
+
+// inspect validates a stored upload against its purpose's policy using the content's magic bytes.
+// SVG images are sanitized in place, which changes their size and checksum.
+func (s *uploadService) inspect(ctx context.Context, session *models.UploadSession, objectKey, checksum string) (*inspectedContent, error) {
+	policy := s.cfg.Upload.Policy(session.Purpose)
+
+	reader, err := s.storage.Get(ctx, objectKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+	defer reader.Close()
+
+	buffered := bufio.NewReaderSize(reader, filetype.SniffLen)
+	head, err := buffered.Peek(filetype.SniffLen)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("failed to read upload: %w", err)
+	}
+
+	detected := filetype.Detect(head)
+	if !filetype.MatchesExtension(session.Filename, detected) {
+		return nil, fmt.Errorf("%w: content is %s, which does not match the file extension", ErrUploadContentRejected, detected)
+	}
+	content := &inspectedContent{
+		contentType: filetype.CanonicalType(session.Filename, detected),
+		size:        session.TotalSize,
+		checksum:    checksum,
+	}
+	if !allowed(policy.ContentTypes, content.contentType) {
+		return nil, fmt.Errorf("%w: %s is not allowed for %s uploads", ErrUploadContentRejected, content.contentType, session.Purpose)
+	}
+
+	switch {
+	case content.contentType == filetype.SVG:
+		data, err := io.ReadAll(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read upload: %w", err)
+		}
+		reader.Close()
+		return content, s.sanitizeSVG(ctx, objectKey, data, content)
+	case filetype.IsRasterImage(content.contentType):
+		width, height, err := imaging.Dimensions(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("%w: image could not be read", ErrUploadContentRejected)
+		}
+		if exceedsDimensions(policy, width, height) {
+			return nil, fmt.Errorf("%w: image is %dx%d, the maximum is %dx%d", ErrUploadContentRejected, width, height, policy.MaxWidth, policy.MaxHeight)
+		}
+	}
+	return content, nil
+}
+
+// sanitizeSVG replaces a stored SVG with a copy stripped of scripts and external references
+func (s *uploadService) sanitizeSVG(ctx context.Context, objectKey string, data []byte, content *inspectedContent) error {
+	clean, err := filetype.SanitizeSVG(data)
+	if err != nil {
+		if errors.Is(err, filetype.ErrInvalidSVG) {
+			return fmt.Errorf("%w: %v", ErrUploadContentRejected, err)
+		}
+		return err
+	}
+
+	if err := s.storage.Put(ctx, objectKey, bytes.NewReader(clean), int64(len(clean)), filetype.SVG); err != nil {
+		return fmt.Errorf("failed to store sanitized SVG: %w", err)
+	}
+
+	sum := sha256.Sum256(clean)
+	content.size = int64(len(clean))
+	content.checksum = hex.EncodeToString(sum[:])
+	return nil
+}
+
+// exceedsDimensions reports whether an image is wider or taller than a policy allows
+func exceedsDimensions(policy config.UploadPolicy, width, height int) bool {
+	return (policy.MaxWidth > 0 && width > policy.MaxWidth) || (policy.MaxHeight > 0 && height > policy.MaxHeight)
+}
+
+// allowed reports whether value is in an allowlist; an empty allowlist allows everything
+func allowed(allowlist []string, value string) bool {
+	if len(allowlist) == 0 {
+		return true
+	}
+	for _, entry := range allowlist {
+		if entry == value {
+			return true
+		}
+	}
+	return false
+}
@@ -4,35 +4,111 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"slices"
+	"sync"
+	"time"
 
 	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/jobs"
 	"gbt-be-template/internal/models"
 	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/fieldcrypt"
 	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/mailer"
+	"gbt-be-template/pkg/pwned"
+	"gbt-be-template/pkg/ratelimit"
+	"gbt-be-template/pkg/utils"
 
 	"golang.org/x/crypto/bcrypt"
 )
 
 // userService implements the UserService interface
 type userService struct {
-	userRepo repository.UserRepository
-	authSvc  AuthService
-	cfg      *config.Config
-	log      *logger.Logger
+	userRepo     repository.UserRepository
+	tokenRepo    repository.EmailTokenRepository
+	otpRepo      repository.OTPRepository
+	deviceRepo   repository.DeviceRepository
+	eventRepo    repository.LoginEventRepository
+	recoveryRepo repository.RecoveryCodeRepository
+	roleRepo     repository.RoleRepository // Backs the deprecated IsAdmin flag with the admin role
+	auditRepo    repository.RBACAuditRepository
+	activityRepo repository.AuditEventRepository // Nil unless actions on accounts are audited
+	tx           repository.Transactor           // Nil unless compound writes run in transactions
+	authorizer   Authorizer                      // Reloaded when an admin changes the roles of a user
+	authSvc      AuthService
+	backend      AuthBackend
+	queue        jobs.Enqueuer
+	otpLimiter   ratelimit.Limiter // Counts login codes texted per phone number
+	pwned        pwned.Checker     // Nil unless breached passwords are refused
+	cfg          *config.Config
+	log          *logger.Logger
 }
 
-// NewUserService creates a new user service
-func NewUserService(userRepo repository.UserRepository, authSvc AuthService, cfg *config.Config, log *logger.Logger) UserService {
-	return &userService{
-		userRepo: userRepo,
-		authSvc:  authSvc,
-		cfg:      cfg,
-		log:      log,
+// NewUserService creates a new user service checking passwords with the given backend
+func NewUserService(userRepo repository.UserRepository, tokenRepo repository.EmailTokenRepository, otpRepo repository.OTPRepository, deviceRepo repository.DeviceRepository, eventRepo repository.LoginEventRepository, recoveryRepo repository.RecoveryCodeRepository, roleRepo repository.RoleRepository, auditRepo repository.RBACAuditRepository, authSvc AuthService, backend AuthBackend, queue jobs.Enqueuer, cfg *config.Config, log *logger.Logger) UserService {
+	s := &userService{
+		userRepo:     userRepo,
+		tokenRepo:    tokenRepo,
+		otpRepo:      otpRepo,
+		deviceRepo:   deviceRepo,
+		eventRepo:    eventRepo,
+		recoveryRepo: recoveryRepo,
+		roleRepo:     roleRepo,
+		auditRepo:    auditRepo,
+		authSvc:      authSvc,
+		backend:      backend,
+		queue:        queue,
+		otpLimiter:   ratelimit.NewMemoryLimiter(),
+		cfg:          cfg,
+		log:          log,
 	}
+	if cfg.Pwned.Enabled {
+		s.pwned = pwned.NewClient(cfg.Pwned.Timeout)
+	}
+	return s
+}
+
+// UseAuthorizer reloads the authorizer whenever an admin update changes the roles of a user
+func (s *userService) UseAuthorizer(authorizer Authorizer) {
+	s.authorizer = authorizer
+}
+
+// UseTransactions runs compound writes, such as creating a user with its default roles, in a
+// single transaction. Until it is called they run one after the other, so a failure can leave
+// the earlier writes in place.
+func (s *userService) UseTransactions(tx repository.Transactor) {
+	s.tx = tx
+}
+
+// withTx runs fn with user and role repositories whose writes are committed together, or with
+// the service's own repositories when transactions aren't used
+func (s *userService) withTx(ctx context.Context, fn func(userRepo repository.UserRepository, roleRepo repository.RoleRepository) error) error {
+	if s.tx == nil {
+		return fn(s.userRepo, s.roleRepo)
+	}
+	return s.tx.WithTx(ctx, func(txRepos *repository.Repositories) error {
+		return fn(txRepos.User, txRepos.Role)
+	})
+}
+
+// updateUser saves a user with userRepo. A save losing the race against another update of the
+// user fails with ErrUserChanged, which handlers answer with 409.
+func updateUser(ctx context.Context, userRepo repository.UserRepository, user *models.User) error {
+	if err := userRepo.Update(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrVersionConflict) {
+			return ErrUserChanged
+		}
+		return err
+	}
+	return nil
 }
 
 // Create creates a new user
 func (s *userService) Create(ctx context.Context, req *models.UserCreateRequest) (*models.UserResponse, error) {
+	if err := req.Metadata.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
 	// Check if user already exists by email
 	exists, err := s.userRepo.ExistsByEmail(ctx, req.Email)
 	if err != nil {
@@ -53,6 +129,10 @@ func (s *userService) Create(ctx context.Context, req *models.UserCreateRequest)
 		return nil, errors.New("username is already taken")
 	}
 
+	if err := s.checkBreachedPassword(ctx, req.Password); err != nil {
+		return nil, err
+	}
+
 	// Hash password
 	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
 	if err != nil {
@@ -61,6 +141,7 @@ func (s *userService) Create(ctx context.Context, req *models.UserCreateRequest)
 	}
 
 	// Create user model
+	now := time.Now()
 	user := &models.User{
 		Email:     req.Email,
 		Username:  req.Username,
@@ -69,18 +150,65 @@ func (s *userService) Create(ctx context.Context, req *models.UserCreateRequest)
 		LastName:  req.LastName,
 		IsActive:  true,
 		IsAdmin:   false,
+		Phone:     req.Phone,
+		Metadata:  req.Metadata,
+
+		PasswordChangedAt: &now,
 	}
 
-	// Save user to database
-	if err := s.userRepo.Create(ctx, user); err != nil {
-		s.log.WithError(err).Error("Failed to create user")
-		return nil, fmt.Errorf("failed to create user: %w", err)
+	// Save the user together with its default roles
+	var assigned bool
+	err = s.withTx(ctx, func(userRepo repository.UserRepository, roleRepo repository.RoleRepository) error {
+		if err := userRepo.Create(ctx, user); err != nil {
+			s.log.WithError(err).Error("Failed to create user")
+			return fmt.Errorf("failed to create user: %w", err)
+		}
+		assigned, err = s.assignDefaultRoles(ctx, roleRepo, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	s.log.WithField("user_id", user.ID).Info("User created successfully")
+	s.recordActivity(ctx, user.ID, models.AuditUserCreated, nil)
+	if assigned {
+		recordUserRoles(ctx, s.roleRepo, s.auditRepo, s.log, 0, models.RBACAuditRolesAssigned, user.ID, []string{})
+	}
 	return user.ToResponse(), nil
 }
 
+// assignDefaultRoles gives a new user the roles of AUTH_DEFAULT_ROLES and reports whether any
+// were given. Roles that don't exist are skipped.
+func (s *userService) assignDefaultRoles(ctx context.Context, roleRepo repository.RoleRepository, userID uint) (bool, error) {
+	if roleRepo == nil || len(s.cfg.Authz.DefaultRoles) == 0 {
+		return false, nil
+	}
+
+	var ids []uint
+	for _, name := range s.cfg.Authz.DefaultRoles {
+		role, err := roleRepo.GetByName(ctx, name)
+		if err != nil {
+			s.log.WithError(err).WithField("role", name).Error("Failed to get default role")
+			return false, fmt.Errorf("failed to get default role: %w", err)
+		}
+		if role == nil {
+			s.log.WithField("role", name).Error("Default role doesn't exist")
+			continue
+		}
+		ids = append(ids, role.ID)
+	}
+	if len(ids) == 0 {
+		return false, nil
+	}
+
+	if err := roleRepo.AssignToUser(ctx, userID, ids); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to assign default roles")
+		return false, fmt.Errorf("failed to assign default roles: %w", err)
+	}
+	return true, nil
+}
+
 // GetByID retrieves a user by ID
 func (s *userService) GetByID(ctx context.Context, id uint) (*models.UserResponse, error) {
 	user, err := s.userRepo.GetByID(ctx, id)
@@ -109,8 +237,13 @@ func (s *userService) GetByEmail(ctx context.Context, email string) (*models.Use
 	return user.ToResponse(), nil
 }
 
-// Update updates a user
-func (s *userService) Update(ctx context.Context, id uint, req *models.UserUpdateRequest) (*models.UserResponse, error) {
+// Update updates a user. Only admins can activate or deactivate accounts, except that users
+// can reactivate an account they deactivated themselves.
+func (s *userService) Update(ctx context.Context, actorID uint, isAdmin bool, id uint, req *models.UserUpdateRequest) (*models.UserResponse, error) {
+	if err := req.Metadata.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
 	// Get existing user
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -120,8 +253,14 @@ func (s *userService) Update(ctx context.Context, id uint, req *models.UserUpdat
 	if user == nil {
 		return nil, errors.New("user not found")
 	}
+	if req.Version != nil && *req.Version != user.Version {
+		return nil, ErrUserChanged
+	}
 
-	// Update fields if provided
+	// Update fields if provided. A new email only replaces the current one once it is confirmed
+	// with the link sent to it; asking for the current email again cancels a pending change.
+	var fields []string
+	emailChanged := false
 	if req.Email != nil && *req.Email != user.Email {
 		// Check if new email is already taken
 		exists, err := s.userRepo.ExistsByEmail(ctx, *req.Email)
@@ -131,7 +270,16 @@ func (s *userService) Update(ctx context.Context, id uint, req *models.UserUpdat
 		if exists {
 			return nil, errors.New("email is already taken")
 		}
-		user.Email = *req.Email
+		user.PendingEmail = *req.Email
+		emailChanged = true
+		fields = append(fields, "pending_email")
+	} else if req.Email != nil && user.PendingEmail != "" {
+		user.PendingEmail = ""
+		fields = append(fields, "pending_email")
+		if err := s.tokenRepo.InvalidateForUser(ctx, user.ID, models.EmailTokenEmailChange); err != nil {
+			s.log.WithError(err).WithField("user_id", id).Error("Failed to invalidate email change tokens")
+			return nil, fmt.Errorf("failed to invalidate email change tokens: %w", err)
+		}
 	}
 
 	if req.Username != nil && *req.Username != user.Username {
@@ -144,32 +292,65 @@ func (s *userService) Update(ctx context.Context, id uint, req *models.UserUpdat
 			return nil, errors.New("username is already taken")
 		}
 		user.Username = *req.Username
+		fields = append(fields, "username")
 	}
 
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
+		fields = append(fields, "first_name")
 	}
 
 	if req.LastName != nil {
 		user.LastName = *req.LastName
+		fields = append(fields, "last_name")
 	}
 
-	if req.IsActive != nil {
-		user.IsActive = *req.IsActive
+	if req.IsActive != nil && *req.IsActive != user.IsActive {
+		undoesOwnDeactivation := *req.IsActive && actorID == user.ID && user.DeactivatedVoluntarily()
+		if !isAdmin && !undoesOwnDeactivation {
+			return nil, ErrActiveStatusForbidden
+		}
+		user.SetActive(*req.IsActive, actorID)
+		fields = append(fields, "is_active")
+	}
+
+	if req.Phone != nil {
+		user.Phone = *req.Phone
+		fields = append(fields, "phone")
+	}
+
+	if req.Metadata != nil {
+		user.Metadata = req.Metadata
+		fields = append(fields, "metadata")
 	}
 
 	// Save updated user
-	if err := s.userRepo.Update(ctx, user); err != nil {
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		if errors.Is(err, ErrUserChanged) {
+			return nil, err
+		}
 		s.log.WithError(err).WithField("user_id", id).Error("Failed to update user")
 		return nil, fmt.Errorf("failed to update user: %w", err)
 	}
+	if emailChanged {
+		if err := s.sendEmailChangeLink(ctx, user); err != nil {
+			return nil, err
+		}
+	}
 
 	s.log.WithField("user_id", id).Info("User updated successfully")
+	if len(fields) > 0 {
+		s.recordActivity(ctx, id, models.AuditProfileUpdated, map[string]interface{}{"fields": fields})
+	}
 	return user.ToResponse(), nil
 }
 
 // AdminUpdate updates a user with admin privileges (can modify admin status)
-func (s *userService) AdminUpdate(ctx context.Context, id uint, req *models.AdminUserUpdateRequest) (*models.UserResponse, error) {
+func (s *userService) AdminUpdate(ctx context.Context, actorID, id uint, req *models.AdminUserUpdateRequest) (*models.UserResponse, error) {
+	if err := req.Metadata.Validate(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidMetadata, err)
+	}
+
 	// Get existing user
 	user, err := s.userRepo.GetByID(ctx, id)
 	if err != nil {
@@ -177,10 +358,14 @@ func (s *userService) AdminUpdate(ctx context.Context, id uint, req *models.Admi
 		return nil, fmt.Errorf("failed to get user: %w", err)
 	}
 	if user == nil {
-		return nil, errors.New("user not found")
+		return nil, ErrUserNotFound
+	}
+	if req.Version != nil && *req.Version != user.Version {
+		return nil, ErrUserChanged
 	}
 
 	// Update fields if provided
+	var fields []string
 	if req.Email != nil && *req.Email != user.Email {
 		// Check if new email is already taken
 		exists, err := s.userRepo.ExistsByEmail(ctx, *req.Email)
@@ -191,6 +376,16 @@ func (s *userService) AdminUpdate(ctx context.Context, id uint, req *models.Admi
 			return nil, errors.New("email is already taken")
 		}
 		user.Email = *req.Email
+		fields = append(fields, "email")
+	}
+	// An email set by an admin replaces any change the user has yet to confirm
+	if req.Email != nil && user.PendingEmail != "" {
+		user.PendingEmail = ""
+		fields = append(fields, "pending_email")
+		if err := s.tokenRepo.InvalidateForUser(ctx, user.ID, models.EmailTokenEmailChange); err != nil {
+			s.log.WithError(err).WithField("user_id", id).Error("Failed to invalidate email change tokens")
+			return nil, fmt.Errorf("failed to invalidate email change tokens: %w", err)
+		}
 	}
 
 	if req.Username != nil && *req.Username != user.Username {
@@ -203,35 +398,509 @@ func (s *userService) AdminUpdate(ctx context.Context, id uint, req *models.Admi
 			return nil, errors.New("username is already taken")
 		}
 		user.Username = *req.Username
+		fields = append(fields, "username")
 	}
 
 	if req.FirstName != nil {
 		user.FirstName = *req.FirstName
+		fields = append(fields, "first_name")
 	}
 
 	if req.LastName != nil {
 		user.LastName = *req.LastName
+		fields = append(fields, "last_name")
 	}
 
 	if req.IsActive != nil {
-		user.IsActive = *req.IsActive
+		user.SetActive(*req.IsActive, actorID)
+		fields = append(fields, "is_active")
+	}
+
+	if req.Phone != nil {
+		user.Phone = *req.Phone
+		fields = append(fields, "phone")
 	}
 
-	// Admin-only field: can modify admin status
+	if req.Metadata != nil {
+		user.Metadata = req.Metadata
+		fields = append(fields, "metadata")
+	}
+
+	// Admin-only fields: the roles of the user, replaced as a whole, and admin status. IsAdmin
+	// mirrors the admin role; the deprecated flag wins over role_ids when both are given.
+	var roleIDs []uint
+	if req.RoleIDs != nil {
+		roles, err := s.adminUpdateRoles(ctx, req.RoleIDs)
+		if err != nil {
+			return nil, err
+		}
+		roleIDs = make([]uint, len(roles))
+		for i, role := range roles {
+			roleIDs[i] = role.ID
+		}
+		user.IsAdmin = slices.ContainsFunc(roles, func(role *models.Role) bool {
+			return role.Name == models.RoleAdmin && role.IsActive
+		})
+	}
 	if req.IsAdmin != nil {
+		s.log.WithField("user_id", id).Warn("is_admin is deprecated, assign or remove the admin role instead")
 		user.IsAdmin = *req.IsAdmin
 	}
+	if req.RoleIDs != nil || req.IsAdmin != nil {
+		fields = append(fields, "roles")
+	}
 
-	// Save updated user
-	if err := s.userRepo.Update(ctx, user); err != nil {
-		s.log.WithError(err).WithField("user_id", id).Error("Failed to admin update user")
-		return nil, fmt.Errorf("failed to update user: %w", err)
+	var before []string
+	if req.RoleIDs != nil {
+		if before, err = userRoleNames(ctx, s.roleRepo, id); err != nil {
+			return nil, err
+		}
+	}
+
+	// Save the updated user together with its roles
+	err = s.withTx(ctx, func(userRepo repository.UserRepository, roleRepo repository.RoleRepository) error {
+		if err := updateUser(ctx, userRepo, user); err != nil {
+			if errors.Is(err, ErrUserChanged) {
+				return err
+			}
+			s.log.WithError(err).WithField("user_id", id).Error("Failed to admin update user")
+			return fmt.Errorf("failed to update user: %w", err)
+		}
+		if req.RoleIDs != nil {
+			if err := roleRepo.ReplaceUserRoles(ctx, id, roleIDs); err != nil {
+				s.log.WithError(err).WithField("user_id", id).Error("Failed to replace user roles")
+				return fmt.Errorf("failed to replace user roles: %w", err)
+			}
+		}
+		if req.IsAdmin != nil {
+			if err := setAdminRole(ctx, roleRepo, s.auditRepo, s.log, actorID, id, *req.IsAdmin); err != nil {
+				s.log.WithError(err).WithField("user_id", id).Error("Failed to update admin role")
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if req.RoleIDs != nil {
+		s.log.Security("roles_replaced", id).WithFields(map[string]interface{}{
+			"actor_id": actorID,
+			"role_ids": roleIDs,
+		}).Info("Roles of user replaced")
+		recordUserRoles(ctx, s.roleRepo, s.auditRepo, s.log, actorID, models.RBACAuditRolesReplaced, id, before)
+	}
+	if req.RoleIDs != nil || req.IsAdmin != nil {
+		reloadAuthorizer(ctx, s.authorizer, s.log)
 	}
 
 	s.log.WithField("user_id", id).Info("User admin updated successfully")
+	if len(fields) > 0 {
+		s.recordActivity(ctx, id, models.AuditProfileUpdated, map[string]interface{}{"fields": fields})
+	}
 	return user.ToResponse(), nil
 }
 
+// AdminGet retrieves a user together with moderation details only admins may see
+func (s *userService) AdminGet(ctx context.Context, id uint) (*models.AdminUserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	return user.ToAdminResponse(), nil
+}
+
+// Suspend blocks a user from logging in until the suspension ends or is lifted,
+// and signs them out of every session
+func (s *userService) Suspend(ctx context.Context, actorID, id uint, req *models.UserSuspendRequest) (*models.AdminUserResponse, error) {
+	if actorID == id {
+		return nil, fmt.Errorf("%w: admins cannot suspend themselves", ErrInvalidSuspension)
+	}
+	now := time.Now()
+	if req.Until != nil && !req.Until.After(now) {
+		return nil, fmt.Errorf("%w: end time must be in the future", ErrInvalidSuspension)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for suspension")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.SuspendedAt = &now
+	user.SuspendedUntil = req.Until
+	user.SuspendedBy = &actorID
+	user.SuspensionReason = req.Reason
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to suspend user")
+		return nil, fmt.Errorf("failed to suspend user: %w", err)
+	}
+
+	if err := s.authSvc.RevokeRefreshTokens(ctx, id); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to revoke refresh tokens of suspended user")
+	}
+	if err := s.authSvc.RevokeUserAccessTokens(ctx, id); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to revoke access tokens of suspended user")
+	}
+
+	s.log.Security("account_suspended", id).
+		WithField("actor_id", actorID).
+		WithField("reason", req.Reason).
+		Warn("Account suspended")
+	s.recordActivity(ctx, id, models.AuditSuspended, map[string]interface{}{"until": req.Until})
+	return user.ToAdminResponse(), nil
+}
+
+// Unsuspend lifts a user's suspension
+func (s *userService) Unsuspend(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for unsuspension")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	user.SuspendedAt = nil
+	user.SuspendedUntil = nil
+	user.SuspendedBy = nil
+	user.SuspensionReason = ""
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to lift suspension")
+		return nil, fmt.Errorf("failed to lift suspension: %w", err)
+	}
+
+	s.log.Security("account_unsuspended", id).WithField("actor_id", actorID).Info("Account suspension lifted")
+	s.recordActivity(ctx, id, models.AuditUnsuspended, nil)
+	return user.ToAdminResponse(), nil
+}
+
+// Reactivate lets an admin reactivate a deactivated account, whoever deactivated it.
+// A pending self-service deletion is canceled as well.
+func (s *userService) Reactivate(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for reactivation")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.IsActive {
+		return user.ToAdminResponse(), nil
+	}
+
+	deactivatedBy := user.DeactivatedBy
+	if err := s.reactivate(ctx, user, actorID); err != nil {
+		return nil, err
+	}
+
+	s.log.Security("account_reactivated", id).
+		WithField("actor_id", actorID).
+		WithField("deactivated_by", deactivatedBy).
+		Warn("Account reactivated by admin")
+	s.recordActivity(ctx, id, models.AuditReactivated, nil)
+	return user.ToAdminResponse(), nil
+}
+
+// Restore lets an admin undo the deletion of a user. Accounts anonymized after a self-service
+// deletion have lost their data and can't be restored.
+func (s *userService) Restore(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, id, repository.WithDeleted())
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for restore")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.DeletedAt.Valid {
+		return user.ToAdminResponse(), nil
+	}
+	if user.IsAnonymized() {
+		return nil, ErrInvalidRestore
+	}
+
+	if _, err := s.userRepo.Restore(ctx, id); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to restore user")
+		return nil, fmt.Errorf("failed to restore user: %w", err)
+	}
+	user.DeletedAt.Valid = false
+
+	s.log.Security("account_restored", id).
+		WithField("actor_id", actorID).
+		Warn("Deleted account restored by admin")
+	s.recordActivity(ctx, id, models.AuditRestored, nil)
+	return user.ToAdminResponse(), nil
+}
+
+// HardDelete permanently removes a user, soft deleted or not, along with everything the user owns
+func (s *userService) HardDelete(ctx context.Context, actorID, id uint) error {
+	if actorID == id {
+		return ErrInvalidHardDelete
+	}
+
+	deleted, err := s.userRepo.HardDelete(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to permanently delete user")
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+	if !deleted {
+		return ErrUserNotFound
+	}
+
+	s.log.Security("account_purged", id).
+		WithField("actor_id", actorID).
+		Warn("Account permanently deleted by admin")
+	s.recordActivity(ctx, id, models.AuditPermanentlyDeleted, nil)
+	return nil
+}
+
+// adminUpdateRoles returns the roles with the given IDs, or ErrRoleNotFound when one is unknown
+func (s *userService) adminUpdateRoles(ctx context.Context, ids []uint) ([]*models.Role, error) {
+	if s.roleRepo == nil {
+		return nil, ErrRoleNotFound
+	}
+	ids = slices.Clone(ids)
+	slices.Sort(ids)
+	ids = slices.Compact(ids)
+	roles, err := s.roleRepo.ListByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get roles: %w", err)
+	}
+	if len(roles) != len(ids) {
+		return nil, ErrRoleNotFound
+	}
+	return roles, nil
+}
+
+// Deactivate lets an admin deactivate an account and signs the user out of every session. Only an
+// admin can reactivate it.
+func (s *userService) Deactivate(ctx context.Context, actorID, id uint) (*models.AdminUserResponse, error) {
+	if actorID == id {
+		return nil, fmt.Errorf("%w: admins cannot deactivate themselves", ErrInvalidDeactivation)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for deactivation")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if !user.IsActive && !user.DeactivatedVoluntarily() {
+		return user.ToAdminResponse(), nil
+	}
+
+	user.SetActive(false, actorID)
+	// An account the user deactivated themselves is taken over, so they can no longer undo it
+	user.DeactivatedBy = &actorID
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to deactivate user")
+		return nil, fmt.Errorf("failed to deactivate user: %w", err)
+	}
+
+	if err := s.authSvc.RevokeRefreshTokens(ctx, id); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to revoke refresh tokens of deactivated user")
+	}
+	if err := s.authSvc.RevokeUserAccessTokens(ctx, id); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to revoke access tokens of deactivated user")
+	}
+
+	s.log.Security("account_deactivated", id).WithField("actor_id", actorID).Warn("Account deactivated by admin")
+	s.recordActivity(ctx, id, models.AuditDeactivated, nil)
+	return user.ToAdminResponse(), nil
+}
+
+// LogoutAll lets an admin sign a user out of every session by revoking their refresh tokens and
+// the access tokens issued so far
+func (s *userService) LogoutAll(ctx context.Context, actorID, id uint) error {
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for logout")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return ErrUserNotFound
+	}
+
+	if err := s.authSvc.RevokeRefreshTokens(ctx, id); err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to revoke refresh tokens")
+		return fmt.Errorf("failed to revoke refresh tokens: %w", err)
+	}
+	if err := s.authSvc.RevokeUserAccessTokens(ctx, id); err != nil {
+		return err
+	}
+
+	s.log.Security("sessions_revoked", id).WithField("actor_id", actorID).Warn("User signed out of every session by admin")
+	return nil
+}
+
+// Impersonate issues an admin a short-lived access token to act as a user, such as to reproduce
+// a problem they reported. Admins can't be impersonated, so impersonation never grants admin rights.
+func (s *userService) Impersonate(ctx context.Context, actorID, id uint) (*models.ImpersonationResponse, error) {
+	if actorID == id {
+		return nil, fmt.Errorf("%w: admins cannot impersonate themselves", ErrInvalidImpersonation)
+	}
+
+	user, err := s.userRepo.GetByID(ctx, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", id).Error("Failed to get user for impersonation")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+	if user.IsAdmin {
+		return nil, fmt.Errorf("%w: admins cannot be impersonated", ErrInvalidImpersonation)
+	}
+	if !user.IsActive || user.IsSuspended(time.Now()) {
+		return nil, fmt.Errorf("%w: the account is deactivated or suspended", ErrInvalidImpersonation)
+	}
+
+	token, err := s.authSvc.GenerateImpersonationToken(user, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Security("impersonation_started", id).
+		WithField("actor_id", actorID).
+		Warn("Admin started impersonating user")
+	return &models.ImpersonationResponse{
+		AccessToken:    token,
+		TokenType:      "Bearer",
+		ExpiresIn:      int64(s.cfg.JWT.ImpersonationExpiry.Seconds()),
+		ImpersonatorID: actorID,
+		User:           user.ToResponse(),
+	}, nil
+}
+
+// RequestReactivation emails a reactivation link to a user who deactivated their own account.
+// It succeeds without sending anything for unknown emails and accounts an admin deactivated,
+// so the response doesn't reveal which accounts exist.
+func (s *userService) RequestReactivation(ctx context.Context, req *models.ReactivationRequest) error {
+	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get user for reactivation request")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil || !user.DeactivatedVoluntarily() {
+		return nil
+	}
+
+	// Only the most recent link works
+	if err := s.tokenRepo.InvalidateForUser(ctx, user.ID, models.EmailTokenReactivation); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to invalidate reactivation tokens")
+		return fmt.Errorf("failed to invalidate reactivation tokens: %w", err)
+	}
+
+	token, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate reactivation token: %w", err)
+	}
+	if err := s.tokenRepo.Create(ctx, &models.EmailToken{
+		UserID:    user.ID,
+		Purpose:   models.EmailTokenReactivation,
+		TokenHash: utils.HashToken(token),
+		ExpiresAt: time.Now().Add(s.cfg.Reactivation.TokenTTL),
+	}); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to store reactivation token")
+		return fmt.Errorf("failed to store reactivation token: %w", err)
+	}
+
+	msg := &mailer.Message{
+		To:      user.Email,
+		Subject: "Reactivate your account",
+		Body: fmt.Sprintf("Hi %s,\n\nWe received a request to reactivate your account. Open this link to reactivate it:\n\n%s/account/reactivate?token=%s\n\nThe link expires in %s. If you didn't ask for this, you can ignore this email.\n",
+			user.FirstName, s.cfg.Mail.LinkBaseURL, token, s.cfg.Reactivation.TokenTTL),
+	}
+	if err := s.queue.Enqueue(ctx, JobSendEmail, msg); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to queue reactivation email")
+		return fmt.Errorf("failed to queue reactivation email: %w", err)
+	}
+
+	s.log.Security("account_reactivation_requested", user.ID).Info("Reactivation link sent")
+	return nil
+}
+
+// ConfirmReactivation reactivates the account of an emailed reactivation link
+func (s *userService) ConfirmReactivation(ctx context.Context, req *models.ReactivationConfirmRequest) error {
+	token, err := s.tokenRepo.GetByHash(ctx, utils.HashToken(req.Token))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get reactivation token")
+		return fmt.Errorf("failed to get reactivation token: %w", err)
+	}
+	if token == nil || token.Purpose != models.EmailTokenReactivation || token.UsedAt != nil || time.Now().After(token.ExpiresAt) {
+		return ErrInvalidEmailToken
+	}
+
+	marked, err := s.tokenRepo.MarkUsed(ctx, token.ID)
+	if err != nil {
+		return fmt.Errorf("failed to use reactivation token: %w", err)
+	}
+	if !marked {
+		return ErrInvalidEmailToken
+	}
+
+	user, err := s.userRepo.GetByID(ctx, token.UserID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", token.UserID).Error("Failed to get user for reactivation")
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	// An admin may have deactivated the account since the link was sent
+	if user == nil || !user.DeactivatedVoluntarily() {
+		return ErrInvalidEmailToken
+	}
+
+	if err := s.reactivate(ctx, user, user.ID); err != nil {
+		return err
+	}
+
+	s.log.Security("account_reactivated", user.ID).Info("Account reactivated by email confirmation")
+	s.recordActivity(ctx, user.ID, models.AuditReactivated, nil)
+	return nil
+}
+
+// reactivate activates an account and cancels any pending deletion
+func (s *userService) reactivate(ctx context.Context, user *models.User, actorID uint) error {
+	user.SetActive(true, actorID)
+	user.DeletionRequestedAt = nil
+	user.DeletionScheduledAt = nil
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to reactivate user")
+		return fmt.Errorf("failed to reactivate user: %w", err)
+	}
+
+	if err := s.tokenRepo.InvalidateForUser(ctx, user.ID, models.EmailTokenReactivation); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to invalidate reactivation tokens")
+	}
+	return nil
+}
+
+// LiftExpiredSuspensions clears suspensions whose end time has passed. Logins
+// already ignore them; this keeps the stored state and admin views accurate.
+func (s *userService) LiftExpiredSuspensions(ctx context.Context) (int, error) {
+	ids, err := s.userRepo.LiftExpiredSuspensions(ctx, time.Now())
+	if err != nil {
+		s.log.WithError(err).Error("Failed to lift expired suspensions")
+		return 0, fmt.Errorf("failed to lift expired suspensions: %w", err)
+	}
+	for _, id := range ids {
+		s.log.Security("account_unsuspended", id).Info("Account suspension expired")
+		s.recordActivity(ctx, id, models.AuditUnsuspended, map[string]interface{}{"expired": true})
+	}
+	return len(ids), nil
+}
+
 // Delete deletes a user
 func (s *userService) Delete(ctx context.Context, id uint) error {
 	// Check if user exists
@@ -251,65 +920,189 @@ func (s *userService) Delete(ctx context.Context, id uint) error {
 	}
 
 	s.log.WithField("user_id", id).Info("User deleted successfully")
+	s.recordActivity(ctx, id, models.AuditDeleted, nil)
+
+	// Without a delay, personal data is scrubbed right away; should that fail, the scheduled run
+	// catches up
+	if s.cfg.Deletion.Anonymize && s.cfg.Deletion.AnonymizeAfter == 0 {
+		_, _ = s.AnonymizeDeletedUsers(ctx)
+	}
 	return nil
 }
 
 // List retrieves a paginated list of users
-func (s *userService) List(ctx context.Context, page, limit int) ([]*models.UserResponse, int64, error) {
+func (s *userService) List(ctx context.Context, filter models.UserFilter, sort []models.SortField, page, limit int) ([]*models.UserResponse, int64, error) {
 	// Calculate offset
 	offset := (page - 1) * limit
 
-	// Get users
-	users, err := s.userRepo.List(ctx, limit, offset)
+	// Get the matching users and their total count
+	users, total, err := s.userRepo.List(ctx, filter, sort, limit, offset)
+	if errors.Is(err, repository.ErrUnknownSortField) {
+		return nil, 0, fmt.Errorf("%w: %v", ErrInvalidSort, err)
+	}
 	if err != nil {
 		s.log.WithError(err).Error("Failed to list users")
 		return nil, 0, fmt.Errorf("failed to list users: %w", err)
 	}
 
-	// Get total count
-	total, err := s.userRepo.Count(ctx)
+	// Convert to response format
+	responses := make([]*models.UserResponse, len(users))
+	for i, user := range users {
+		responses[i] = user.ToResponse()
+	}
+
+	return responses, total, nil
+}
+
+// ListByCursor returns the page of users next to an encoded cursor, or the first page for an
+// empty one, and the encoded cursors to the next and previous pages, empty at either end
+func (s *userService) ListByCursor(ctx context.Context, filter models.UserFilter, sort []models.SortField, cursor string, limit int) ([]*models.UserResponse, string, string, error) {
+	var position *models.Cursor
+	if cursor != "" {
+		decoded, err := models.DecodeCursor(cursor)
+		if err != nil {
+			return nil, "", "", ErrInvalidCursor
+		}
+		position = decoded
+	}
+
+	users, next, prev, err := s.userRepo.ListByCursor(ctx, filter, sort, position, limit)
+	if errors.Is(err, repository.ErrUnknownSortField) {
+		return nil, "", "", fmt.Errorf("%w: %v", ErrInvalidSort, err)
+	}
+	if errors.Is(err, models.ErrInvalidCursor) {
+		return nil, "", "", ErrInvalidCursor
+	}
 	if err != nil {
-		s.log.WithError(err).Error("Failed to count users")
-		return nil, 0, fmt.Errorf("failed to count users: %w", err)
+		s.log.WithError(err).Error("Failed to list users")
+		return nil, "", "", fmt.Errorf("failed to list users: %w", err)
 	}
 
-	// Convert to response format
 	responses := make([]*models.UserResponse, len(users))
 	for i, user := range users {
 		responses[i] = user.ToResponse()
 	}
 
-	return responses, total, nil
+	var nextCursor, prevCursor string
+	if next != nil {
+		nextCursor = next.Encode()
+	}
+	if prev != nil {
+		prevCursor = prev.Encode()
+	}
+	return responses, nextCursor, prevCursor, nil
 }
 
-// Login authenticates a user and returns a JWT token
-func (s *userService) Login(ctx context.Context, req *models.UserLoginRequest) (string, *models.UserResponse, error) {
-	// Get user by email
-	user, err := s.userRepo.GetByEmail(ctx, req.Email)
+// exportBatchSize is how many users are loaded per query while exporting
+const exportBatchSize = 500
+
+// Export streams every user matching the filter to fn without loading the full result set into memory
+func (s *userService) Export(ctx context.Context, filter models.UserFilter, fn func(*models.UserResponse) error) error {
+	if err := s.userRepo.ForEach(ctx, filter, exportBatchSize, func(user *models.User) error {
+		return fn(user.ToResponse())
+	}); err != nil {
+		s.log.WithError(err).Error("Failed to export users")
+		return fmt.Errorf("failed to export users: %w", err)
+	}
+	return nil
+}
+
+// Login authenticates a user by email or username and returns an access token and refresh token
+func (s *userService) Login(ctx context.Context, req *models.UserLoginRequest) (*models.LoginResponse, error) {
+	login := req.Login()
+	user, err := s.userRepo.GetByEmailOrUsername(ctx, login)
 	if err != nil {
-		s.log.WithError(err).WithField("email", req.Email).Error("Failed to get user for login")
-		return "", nil, fmt.Errorf("failed to authenticate: %w", err)
+		s.log.WithError(err).WithField("login", login).Error("Failed to get user for login")
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
 	}
-	if user == nil {
-		return "", nil, errors.New("invalid credentials")
+
+	// Refuse locked accounts before checking the password so a lockout can't be used as an oracle
+	if user != nil && user.LockedUntil != nil && time.Now().Before(*user.LockedUntil) {
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, models.LoginMethodPassword, models.LoginFailureAccountLocked)
+		return nil, &AccountLockedError{Until: *user.LockedUntil}
+	}
+
+	// Verify password
+	authenticated, err := s.backend.Authenticate(ctx, login, req.Password, user)
+	if errors.Is(err, ErrInvalidCredentials) {
+		if user == nil {
+			return nil, ErrInvalidCredentials
+		}
+		s.log.WithField("user_id", user.ID).Warn("Invalid password attempt")
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, models.LoginMethodPassword, models.LoginFailureInvalidPassword)
+		return nil, s.recordFailedLogin(ctx, user)
+	}
+	if err != nil {
+		s.log.WithError(err).WithField("login", login).Error("Failed to check password")
+		return nil, fmt.Errorf("failed to authenticate: %w", err)
+	}
+
+	// A directory login may belong to a different local account than the one the login matched
+	if (user == nil || authenticated.ID != user.ID) && authenticated.LockedUntil != nil && time.Now().Before(*authenticated.LockedUntil) {
+		recordLoginEvent(ctx, s.eventRepo, s.log, authenticated.ID, models.LoginMethodPassword, models.LoginFailureAccountLocked)
+		return nil, &AccountLockedError{Until: *authenticated.LockedUntil}
 	}
+	user = authenticated
+
+	// Expired passwords are only revealed to someone who knows them, and directory passwords expire
+	// under the directory's own policy
+	if !s.cfg.LDAP.Enabled && user.PasswordExpired(time.Now(), s.cfg.Password.MaxAge) {
+		s.log.Security("password_expired", user.ID).Info("Login refused with an expired password")
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, models.LoginMethodPassword, models.LoginFailurePasswordExpired)
+		return nil, &PasswordExpiredError{ExpiredAt: user.PasswordChangedAt.Add(s.cfg.Password.MaxAge)}
+	}
+
+	// With two-factor authentication the password only earns a challenge to complete with a TOTP code,
+	// unless the login comes from a device the user trusts
+	if user.TOTPEnabled && !s.isTrustedDevice(ctx, user.ID, req.DeviceToken) {
+		return s.startTwoFactorChallenge(ctx, user, req.RememberMe)
+	}
+
+	return s.completeLogin(ctx, user, loginOptions{method: models.LoginMethodPassword, rememberMe: req.RememberMe, deviceToken: req.DeviceToken})
+}
 
-	// Check if user is active
+// CompleteLogin finishes a login made through another service, such as a passkey or SAML login
+func (s *userService) CompleteLogin(ctx context.Context, user *models.User, method, deviceToken string) (*models.LoginResponse, error) {
+	return s.completeLogin(ctx, user, loginOptions{method: method, deviceToken: deviceToken})
+}
+
+// completeLogin finishes a login once the user proved who they are, checking the account status
+// and issuing tokens. The device the login came from is recorded along the way.
+func (s *userService) completeLogin(ctx context.Context, user *models.User, opts loginOptions) (*models.LoginResponse, error) {
+	// Logging in during the grace period cancels a pending self-service deletion, unless an admin
+	// has deactivated the account since
+	if user.DeletionScheduledAt != nil && user.DeactivatedVoluntarily() {
+		if err := s.cancelDeletion(ctx, user); err != nil {
+			return nil, err
+		}
+	}
+
+	// Only reveal that an account is deactivated or suspended to someone who knows its password
 	if !user.IsActive {
-		return "", nil, errors.New("account is deactivated")
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, opts.method, models.LoginFailureAccountDeactivated)
+		return nil, &AccountDeactivatedError{SelfService: user.DeactivatedVoluntarily()}
+	}
+	if user.IsSuspended(time.Now()) {
+		recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, opts.method, models.LoginFailureAccountSuspended)
+		return nil, &AccountSuspendedError{Until: user.SuspendedUntil}
 	}
 
-	// Verify password
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
-		s.log.WithField("email", req.Email).Warn("Invalid password attempt")
-		return "", nil, errors.New("invalid credentials")
+	if response, err := s.checkSuspiciousLogin(ctx, user, opts); response != nil || err != nil {
+		return response, err
 	}
 
 	// Generate JWT token
 	token, err := s.authSvc.GenerateToken(user.ID, user.Email, user.IsAdmin)
 	if err != nil {
 		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to generate token")
-		return "", nil, fmt.Errorf("failed to generate token: %w", err)
+		return nil, fmt.Errorf("failed to generate token: %w", err)
+	}
+
+	// Issue a refresh token starting a new rotation family
+	refreshToken, err := s.authSvc.IssueRefreshToken(ctx, user.ID, opts.rememberMe)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to issue refresh token")
+		return nil, fmt.Errorf("failed to issue refresh token: %w", err)
 	}
 
 	// Update last login
@@ -317,17 +1110,218 @@ func (s *userService) Login(ctx context.Context, req *models.UserLoginRequest) (
 		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to update last login")
 	}
 
+	// A device that can't be recorded shouldn't stop the login
+	deviceToken, err := s.recordDevice(ctx, user, opts)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to record login device")
+	}
+
+	recordLoginEvent(ctx, s.eventRepo, s.log, user.ID, opts.method, "")
 	s.log.WithField("user_id", user.ID).Info("User logged in successfully")
-	return token, user.ToResponse(), nil
+	return &models.LoginResponse{
+		AccessToken:  token,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int64(s.cfg.JWT.Expiry.Seconds()),
+		DeviceToken:  deviceToken,
+		User:         user.ToResponse(),
+	}, nil
 }
 
-// Logout logs out a user (in this implementation, it's just a placeholder)
-func (s *userService) Logout(ctx context.Context, userID uint) error {
-	// In a more sophisticated implementation, you might:
-	// - Add the token to a blacklist
-	// - Store logout time in database
-	// - Invalidate refresh tokens
+// Logout logs out a user by revoking their refresh tokens and the access token of the session.
+// Other access tokens of the user remain valid until they expire. An empty tokenID, from tokens
+// issued before access tokens carried an ID, only revokes the refresh tokens.
+func (s *userService) Logout(ctx context.Context, userID uint, tokenID string, expiresAt time.Time) error {
+	if err := s.authSvc.RevokeRefreshTokens(ctx, userID); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke refresh tokens on logout")
+		return fmt.Errorf("failed to logout: %w", err)
+	}
+
+	if tokenID != "" {
+		if err := s.authSvc.RevokeAccessToken(ctx, tokenID, expiresAt); err != nil {
+			return fmt.Errorf("failed to logout: %w", err)
+		}
+	}
 
 	s.log.WithField("user_id", userID).Info("User logged out successfully")
 	return nil
 }
+
+// RequestDeletion deactivates the user's own account and schedules its anonymization after the
+// grace period. The password must be confirmed; logging in again before then cancels the deletion.
+func (s *userService) RequestDeletion(ctx context.Context, userID uint, req *models.AccountDeleteRequest) (*models.AccountDeletionResponse, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for deletion request")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	if err := s.backend.VerifyPassword(ctx, user, req.Password); err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) {
+			s.log.WithError(err).WithField("user_id", userID).Error("Failed to check password")
+			return nil, fmt.Errorf("failed to check password: %w", err)
+		}
+		s.log.Security("account_deletion_denied", userID).Warn("Account deletion with invalid password")
+		return nil, ErrInvalidPassword
+	}
+
+	now := time.Now()
+	scheduledAt := now.Add(s.cfg.Deletion.GracePeriod)
+	user.SetActive(false, userID)
+	user.DeletionRequestedAt = &now
+	user.DeletionScheduledAt = &scheduledAt
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to schedule account deletion")
+		return nil, fmt.Errorf("failed to schedule account deletion: %w", err)
+	}
+
+	if err := s.authSvc.RevokeRefreshTokens(ctx, userID); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to revoke refresh tokens of deleted account")
+	}
+
+	s.log.Security("account_deletion_requested", userID).
+		WithField("scheduled_at", scheduledAt).
+		Warn("Account deletion requested")
+	s.recordActivity(ctx, userID, models.AuditDeletionRequested, map[string]interface{}{"scheduled_at": scheduledAt})
+	return &models.AccountDeletionResponse{ScheduledAt: scheduledAt}, nil
+}
+
+// cancelDeletion reactivates an account whose owner logged in during the deletion grace period
+func (s *userService) cancelDeletion(ctx context.Context, user *models.User) error {
+	user.SetActive(true, user.ID)
+	user.DeletionRequestedAt = nil
+	user.DeletionScheduledAt = nil
+	if err := updateUser(ctx, s.userRepo, user); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to cancel account deletion")
+		return fmt.Errorf("failed to cancel account deletion: %w", err)
+	}
+
+	s.log.Security("account_deletion_canceled", user.ID).Info("Account deletion canceled by login")
+	s.recordActivity(ctx, user.ID, models.AuditDeletionCanceled, nil)
+	return nil
+}
+
+// PurgeDeletedAccounts anonymizes accounts whose deletion grace period has ended
+func (s *userService) PurgeDeletedAccounts(ctx context.Context) (int, error) {
+	ids, err := s.userRepo.AnonymizeDeletedAccounts(ctx, time.Now())
+	if err != nil {
+		s.log.WithError(err).Error("Failed to anonymize deleted accounts")
+		return 0, fmt.Errorf("failed to anonymize deleted accounts: %w", err)
+	}
+	for _, id := range ids {
+		s.log.Security("account_deleted", id).Info("Account anonymized after deletion grace period")
+		s.recordActivity(ctx, id, models.AuditAnonymized, nil)
+	}
+	return len(ids), nil
+}
+
+// AnonymizeDeletedUsers scrubs the personal data of users soft deleted longer than the configured
+// anonymization delay ago, when anonymization is enabled
+func (s *userService) AnonymizeDeletedUsers(ctx context.Context) (int, error) {
+	if !s.cfg.Deletion.Anonymize {
+		return 0, nil
+	}
+
+	ids, err := s.userRepo.AnonymizeDeletedUsers(ctx, time.Now().Add(-s.cfg.Deletion.AnonymizeAfter))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to anonymize deleted users")
+		return 0, fmt.Errorf("failed to anonymize deleted users: %w", err)
+	}
+	for _, id := range ids {
+		s.log.Security("account_anonymized", id).Info("Personal data of deleted account scrubbed")
+		s.recordActivity(ctx, id, models.AuditAnonymized, nil)
+	}
+	return len(ids), nil
+}
+
+// PurgeDeletedUsers permanently removes users soft deleted longer than the configured hard delete
+// period ago
+func (s *userService) PurgeDeletedUsers(ctx context.Context) (int, error) {
+	if s.cfg.Deletion.HardDeleteAfter <= 0 {
+		return 0, nil
+	}
+
+	ids, err := s.userRepo.PurgeDeleted(ctx, time.Now().Add(-s.cfg.Deletion.HardDeleteAfter))
+	if err != nil {
+		s.log.WithError(err).Error("Failed to purge deleted users")
+		return 0, fmt.Errorf("failed to purge deleted users: %w", err)
+	}
+	for _, id := range ids {
+		s.log.Security("account_purged", id).Info("Deleted account permanently removed")
+		s.recordActivity(ctx, id, models.AuditPermanentlyDeleted, nil)
+	}
+	return len(ids), nil
+}
+
+// reencryptBatchSize is the number of users rewritten per query when rotating encryption keys
+const reencryptBatchSize = 100
+
+// ReencryptUsers rewrites users whose encrypted fields are not sealed with the primary key,
+// after a key rotation or once encryption is enabled for existing data
+func (s *userService) ReencryptUsers(ctx context.Context) (int, error) {
+	keyring := fieldcrypt.Default()
+	if keyring == nil {
+		return 0, nil
+	}
+
+	total := 0
+	for {
+		n, err := s.userRepo.ReencryptUsers(ctx, keyring.Prefix(), reencryptBatchSize)
+		total += n
+		if err != nil {
+			s.log.WithError(err).WithField("reencrypted", total).Error("Failed to re-encrypt users")
+			return total, fmt.Errorf("failed to re-encrypt users: %w", err)
+		}
+		if n < reencryptBatchSize {
+			break
+		}
+	}
+	if total > 0 {
+		s.log.WithField("count", total).Info("Re-encrypted users with the primary key")
+	}
+	return total, nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     []byte
+)
+
+// dummyPasswordHash returns a bcrypt hash with the same cost as real password hashes
+func dummyPasswordHash() []byte {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = bcrypt.GenerateFromPassword([]byte("not-a-real-password"), bcrypt.DefaultCost)
+	})
+	return dummyHash
+}
+
+// recordFailedLogin counts a failed password attempt and locks the account once the limit is reached.
+// It returns the error to report to the caller.
+func (s *userService) recordFailedLogin(ctx context.Context, user *models.User) error {
+	if s.cfg.Lockout.MaxFailedAttempts <= 0 {
+		return errors.New("invalid credentials")
+	}
+
+	attempts, err := s.userRepo.RecordFailedLogin(ctx, user.ID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to record failed login")
+		return errors.New("invalid credentials")
+	}
+	if attempts < s.cfg.Lockout.MaxFailedAttempts {
+		return errors.New("invalid credentials")
+	}
+
+	until := time.Now().Add(s.cfg.Lockout.Duration)
+	if err := s.userRepo.LockUntil(ctx, user.ID, until); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Error("Failed to lock account")
+		return errors.New("invalid credentials")
+	}
+
+	s.log.Security("account_locked", user.ID).
+		WithField("attempts", attempts).
+		Warn("Account locked after too many failed login attempts")
+	return &AccountLockedError{Until: until}
+}
@@ -2,15 +2,24 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"regexp"
+	"strings"
 	"testing"
+	"time"
 
 	"gbt-be-template/internal/config"
 	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/fieldcrypt"
 	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/ratelimit"
+	"gbt-be-template/pkg/utils"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -24,8 +33,15 @@ func (m *MockUserRepository) Create(ctx context.Context, user *models.User) erro
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) GetByID(ctx context.Context, id uint) (*models.User, error) {
-	args := m.Called(ctx, id)
+func (m *MockUserRepository) GetByID(ctx context.Context, id uint, opts ...repository.QueryOption) (*models.User, error) {
+	// Options are functions, which can't be compared, so lookups given any are expected with
+	// mock.Anything as a third argument
+	var args mock.Arguments
+	if len(opts) > 0 {
+		args = m.Called(ctx, id, opts)
+	} else {
+		args = m.Called(ctx, id)
+	}
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
@@ -40,6 +56,14 @@ func (m *MockUserRepository) GetByEmail(ctx context.Context, email string) (*mod
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetByPhone(ctx context.Context, phone string) (*models.User, error) {
+	args := m.Called(ctx, phone)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
 	args := m.Called(ctx, username)
 	if args.Get(0) == nil {
@@ -48,6 +72,14 @@ func (m *MockUserRepository) GetByUsername(ctx context.Context, username string)
 	return args.Get(0).(*models.User), args.Error(1)
 }
 
+func (m *MockUserRepository) GetByEmailOrUsername(ctx context.Context, login string) (*models.User, error) {
+	args := m.Called(ctx, login)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
 func (m *MockUserRepository) Update(ctx context.Context, user *models.User) error {
 	args := m.Called(ctx, user)
 	return args.Error(0)
@@ -58,12 +90,45 @@ func (m *MockUserRepository) Delete(ctx context.Context, id uint) error {
 	return args.Error(0)
 }
 
-func (m *MockUserRepository) List(ctx context.Context, limit, offset int) ([]*models.User, error) {
-	args := m.Called(ctx, limit, offset)
+func (m *MockUserRepository) Restore(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) HardDelete(ctx context.Context, id uint) (bool, error) {
+	args := m.Called(ctx, id)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) PurgeDeleted(ctx context.Context, cutoff time.Time) ([]uint, error) {
+	args := m.Called(ctx, cutoff)
 	if args.Get(0) == nil {
 		return nil, args.Error(1)
 	}
-	return args.Get(0).([]*models.User), args.Error(1)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockUserRepository) List(ctx context.Context, filter models.UserFilter, sort []models.SortField, limit, offset int) ([]*models.User, int64, error) {
+	args := m.Called(ctx, filter, sort, limit, offset)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), args.Error(2)
+	}
+	return args.Get(0).([]*models.User), args.Get(1).(int64), args.Error(2)
+}
+
+func (m *MockUserRepository) ListByCursor(ctx context.Context, filter models.UserFilter, sort []models.SortField, cursor *models.Cursor, limit int) ([]*models.User, *models.Cursor, *models.Cursor, error) {
+	args := m.Called(ctx, filter, sort, cursor, limit)
+	var next, prev *models.Cursor
+	if args.Get(1) != nil {
+		next = args.Get(1).(*models.Cursor)
+	}
+	if args.Get(2) != nil {
+		prev = args.Get(2).(*models.Cursor)
+	}
+	if args.Get(0) == nil {
+		return nil, next, prev, args.Error(3)
+	}
+	return args.Get(0).([]*models.User), next, prev, args.Error(3)
 }
 
 func (m *MockUserRepository) Count(ctx context.Context) (int64, error) {
@@ -86,11 +151,88 @@ func (m *MockUserRepository) UpdateLastLogin(ctx context.Context, userID uint) e
 	return args.Error(0)
 }
 
+func (m *MockUserRepository) ForEach(ctx context.Context, filter models.UserFilter, batchSize int, fn func(*models.User) error) error {
+	args := m.Called(ctx, filter, batchSize, fn)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) RecordFailedLogin(ctx context.Context, userID uint) (int, error) {
+	args := m.Called(ctx, userID)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) LockUntil(ctx context.Context, userID uint, until time.Time) error {
+	args := m.Called(ctx, userID, until)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) UseTOTPStep(ctx context.Context, userID uint, step int64) (bool, error) {
+	args := m.Called(ctx, userID, step)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) MustChangePassword(ctx context.Context, userID uint) (bool, error) {
+	args := m.Called(ctx, userID)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) LiftExpiredSuspensions(ctx context.Context, now time.Time) ([]uint, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockUserRepository) AnonymizeDeletedAccounts(ctx context.Context, now time.Time) ([]uint, error) {
+	args := m.Called(ctx, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockUserRepository) AnonymizeDeletedUsers(ctx context.Context, deletedBefore time.Time) ([]uint, error) {
+	args := m.Called(ctx, deletedBefore)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *MockUserRepository) ReencryptUsers(ctx context.Context, prefix string, limit int) (int, error) {
+	args := m.Called(ctx, prefix, limit)
+	return args.Int(0), args.Error(1)
+}
+
+func (m *MockUserRepository) SetAvatarFile(ctx context.Context, userID, fileID uint) error {
+	args := m.Called(ctx, userID, fileID)
+	return args.Error(0)
+}
+
+func (m *MockUserRepository) SetAvatarURLs(ctx context.Context, userID, fileID uint, urls map[string]string) (bool, error) {
+	args := m.Called(ctx, userID, fileID, urls)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockUserRepository) TransferOwnership(ctx context.Context, fromID, toID uint) error {
+	args := m.Called(ctx, fromID, toID)
+	return args.Error(0)
+}
+
 // MockAuthService is a mock implementation of AuthService
 type MockAuthService struct {
 	mock.Mock
 }
 
+func (m *MockAuthService) UseClaimsEnricher(enricher ClaimsEnricher) {
+	m.Called(enricher)
+}
+
+func (m *MockAuthService) UseRoles(roleRepo repository.RoleRepository) {
+	m.Called(roleRepo)
+}
+
 func (m *MockAuthService) GenerateToken(userID uint, email string, isAdmin bool) (string, error) {
 	args := m.Called(userID, email, isAdmin)
 	return args.String(0), args.Error(1)
@@ -109,19 +251,124 @@ func (m *MockAuthService) RefreshToken(token string) (string, error) {
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockAuthService) IssueRefreshToken(ctx context.Context, userID uint, rememberMe bool) (string, error) {
+	args := m.Called(ctx, userID, rememberMe)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) RotateRefreshToken(ctx context.Context, refreshToken string) (*models.LoginResponse, error) {
+	args := m.Called(ctx, refreshToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginResponse), args.Error(1)
+}
+
+func (m *MockAuthService) GenerateImpersonationToken(user *models.User, impersonatorID uint) (string, error) {
+	args := m.Called(user, impersonatorID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeRefreshTokens(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) ListSessions(ctx context.Context, userID uint) ([]*models.Session, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*models.Session), args.Error(1)
+}
+
+func (m *MockAuthService) RevokeSession(ctx context.Context, userID, id uint) error {
+	args := m.Called(ctx, userID, id)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeAccessToken(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	args := m.Called(ctx, tokenID, expiresAt)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) RevokeUserAccessTokens(ctx context.Context, userID uint) error {
+	args := m.Called(ctx, userID)
+	return args.Error(0)
+}
+
+func (m *MockAuthService) IsAccessTokenRevoked(ctx context.Context, claims *utils.JWTClaims) (bool, error) {
+	args := m.Called(ctx, claims)
+	return args.Bool(0), args.Error(1)
+}
+
+// fakeEmailTokenRepository keeps email tokens in memory
+type fakeEmailTokenRepository struct {
+	tokens []*models.EmailToken
+}
+
+func newFakeEmailTokenRepository() *fakeEmailTokenRepository {
+	return &fakeEmailTokenRepository{}
+}
+
+func (r *fakeEmailTokenRepository) Create(ctx context.Context, token *models.EmailToken) error {
+	token.ID = uint(len(r.tokens) + 1)
+	r.tokens = append(r.tokens, token)
+	return nil
+}
+
+func (r *fakeEmailTokenRepository) GetByHash(ctx context.Context, tokenHash string) (*models.EmailToken, error) {
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			copied := *token
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeEmailTokenRepository) MarkUsed(ctx context.Context, id uint) (bool, error) {
+	for _, token := range r.tokens {
+		if token.ID == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeEmailTokenRepository) InvalidateForUser(ctx context.Context, userID uint, purpose string) error {
+	for _, token := range r.tokens {
+		if token.UserID == userID && token.Purpose == purpose && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+		}
+	}
+	return nil
+}
+
 func setupUserService() (*userService, *MockUserRepository, *MockAuthService) {
 	mockRepo := &MockUserRepository{}
 	mockAuth := &MockAuthService{}
 	cfg := &config.Config{}
 	log := logger.New("info", "text")
-	
+
 	service := &userService{
-		userRepo: mockRepo,
-		authSvc:  mockAuth,
-		cfg:      cfg,
-		log:      log,
+		userRepo:     mockRepo,
+		tokenRepo:    newFakeEmailTokenRepository(),
+		otpRepo:      &fakeOTPRepository{},
+		deviceRepo:   &fakeDeviceRepository{},
+		eventRepo:    &fakeLoginEventRepository{},
+		recoveryRepo: &fakeRecoveryCodeRepository{},
+		authSvc:      mockAuth,
+		backend:      NewLocalAuthBackend(),
+		queue:        &fakeQueue{},
+		otpLimiter:   ratelimit.NewMemoryLimiter(),
+		cfg:          cfg,
+		log:          log,
 	}
-	
+
 	return service, mockRepo, mockAuth
 }
 
@@ -138,15 +385,15 @@ func TestUserService_Create(t *testing.T) {
 	}
 
 	t.Run("successful creation", func(t *testing.T) {
-		mockRepo.On("ExistsByEmail", ctx, req.Email).Return(false, nil)
-		mockRepo.On("ExistsByUsername", ctx, req.Username).Return(false, nil)
-		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		mockRepo.On("ExistsByEmail", ctx, req.Email).Return(false, nil).Once()
+		mockRepo.On("ExistsByUsername", ctx, req.Username).Return(false, nil).Once()
+		mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil).Once().Run(func(args mock.Arguments) {
 			user := args.Get(1).(*models.User)
 			user.ID = 1 // Simulate database setting ID
 		})
 
 		result, err := service.Create(ctx, req)
-		
+
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 		assert.Equal(t, req.Email, result.Email)
@@ -155,10 +402,10 @@ func TestUserService_Create(t *testing.T) {
 	})
 
 	t.Run("email already exists", func(t *testing.T) {
-		mockRepo.On("ExistsByEmail", ctx, req.Email).Return(true, nil)
+		mockRepo.On("ExistsByEmail", ctx, req.Email).Return(true, nil).Once()
 
 		result, err := service.Create(ctx, req)
-		
+
 		assert.Error(t, err)
 		assert.Nil(t, result)
 		assert.Contains(t, err.Error(), "already exists")
@@ -166,6 +413,92 @@ func TestUserService_Create(t *testing.T) {
 	})
 }
 
+func TestUserService_CreateDefaultRoles(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	ctx := context.Background()
+	roleRepo := &fakeRoleRepository{permissions: &fakePermissionRepository{}}
+	auditRepo := &fakeRBACAuditRepository{}
+	service.roleRepo, service.auditRepo = roleRepo, auditRepo
+	service.cfg.Authz.DefaultRoles = []string{models.RoleUser, "missing"}
+	require.NoError(t, roleRepo.Create(ctx, &models.Role{Name: models.RoleUser, IsActive: true}))
+
+	req := &models.UserCreateRequest{Email: "new@example.com", Username: "newuser", Password: "password123"}
+	mockRepo.On("ExistsByEmail", ctx, req.Email).Return(false, nil).Once()
+	mockRepo.On("ExistsByUsername", ctx, req.Username).Return(false, nil).Once()
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil).Once().Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).ID = 5
+	})
+
+	_, err := service.Create(ctx, req)
+	require.NoError(t, err)
+	names, err := userRoleNames(ctx, roleRepo, 5)
+	require.NoError(t, err)
+	assert.Equal(t, []string{models.RoleUser}, names, "missing roles are skipped")
+	require.Len(t, auditRepo.events, 1)
+	assert.Equal(t, models.RBACAuditRolesAssigned, auditRepo.events[0].Action)
+	assert.Equal(t, uint(0), auditRepo.events[0].ActorID)
+}
+
+// fakeTransactor hands units of work its repositories and records whether they were rolled back
+type fakeTransactor struct {
+	repos      *repository.Repositories
+	rolledBack bool
+}
+
+func (t *fakeTransactor) WithTx(ctx context.Context, fn func(txRepos *repository.Repositories) error) error {
+	err := fn(t.repos)
+	t.rolledBack = err != nil
+	return err
+}
+
+// failingRoleRepository fails to assign roles
+type failingRoleRepository struct {
+	*fakeRoleRepository
+}
+
+func (r *failingRoleRepository) AssignToUser(ctx context.Context, userID uint, roleIDs []uint) error {
+	return errors.New("database unavailable")
+}
+
+func TestUserService_CreateInTransaction(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	ctx := context.Background()
+	roleRepo := &fakeRoleRepository{permissions: &fakePermissionRepository{}}
+	auditRepo := &fakeRBACAuditRepository{}
+	service.roleRepo, service.auditRepo = roleRepo, auditRepo
+	service.cfg.Authz.DefaultRoles = []string{models.RoleUser}
+	require.NoError(t, roleRepo.Create(ctx, &models.Role{Name: models.RoleUser, IsActive: true}))
+
+	mockRepo.On("ExistsByEmail", ctx, mock.Anything).Return(false, nil)
+	mockRepo.On("ExistsByUsername", ctx, mock.Anything).Return(false, nil)
+	mockRepo.On("Create", ctx, mock.AnythingOfType("*models.User")).Return(nil).Run(func(args mock.Arguments) {
+		args.Get(1).(*models.User).ID = 5
+	})
+
+	t.Run("a failed role assignment rolls back the user", func(t *testing.T) {
+		tx := &fakeTransactor{repos: &repository.Repositories{User: mockRepo, Role: &failingRoleRepository{roleRepo}}}
+		service.UseTransactions(tx)
+
+		_, err := service.Create(ctx, &models.UserCreateRequest{Email: "new@example.com", Username: "newuser", Password: "password123"})
+		assert.Error(t, err)
+		assert.True(t, tx.rolledBack)
+		assert.Empty(t, auditRepo.events)
+	})
+
+	t.Run("the user and its roles are committed together", func(t *testing.T) {
+		tx := &fakeTransactor{repos: &repository.Repositories{User: mockRepo, Role: roleRepo}}
+		service.UseTransactions(tx)
+
+		_, err := service.Create(ctx, &models.UserCreateRequest{Email: "new@example.com", Username: "newuser", Password: "password123"})
+		require.NoError(t, err)
+		assert.False(t, tx.rolledBack)
+		names, err := userRoleNames(ctx, roleRepo, 5)
+		require.NoError(t, err)
+		assert.Equal(t, []string{models.RoleUser}, names)
+		require.Len(t, auditRepo.events, 1, "the assignment is audited once committed")
+	})
+}
+
 func TestUserService_Login(t *testing.T) {
 	service, mockRepo, mockAuth := setupUserService()
 	ctx := context.Background()
@@ -185,28 +518,44 @@ func TestUserService_Login(t *testing.T) {
 	}
 
 	t.Run("successful login", func(t *testing.T) {
-		mockRepo.On("GetByEmail", ctx, req.Email).Return(user, nil)
-		mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil)
-		mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil)
+		mockRepo.On("GetByEmailOrUsername", ctx, req.Email).Return(user, nil).Once()
+		mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil).Once()
+		mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil).Once()
+		mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+
+		resp, err := service.Login(ctx, req)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "token123", resp.AccessToken)
+		assert.Equal(t, "refresh123", resp.RefreshToken)
+		assert.NotNil(t, resp.User)
+		assert.Equal(t, user.Email, resp.User.Email)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("login with username", func(t *testing.T) {
+		usernameReq := &models.UserLoginRequest{Identifier: "testuser", Password: req.Password}
+		mockRepo.On("GetByEmailOrUsername", ctx, "testuser").Return(user, nil).Once()
+		mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil).Once()
+		mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil).Once()
+		mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+
+		resp, err := service.Login(ctx, usernameReq)
 
-		token, userResp, err := service.Login(ctx, req)
-		
 		assert.NoError(t, err)
-		assert.Equal(t, "token123", token)
-		assert.NotNil(t, userResp)
-		assert.Equal(t, user.Email, userResp.Email)
+		assert.Equal(t, "token123", resp.AccessToken)
 		mockRepo.AssertExpectations(t)
 		mockAuth.AssertExpectations(t)
 	})
 
 	t.Run("user not found", func(t *testing.T) {
-		mockRepo.On("GetByEmail", ctx, req.Email).Return(nil, nil)
+		mockRepo.On("GetByEmailOrUsername", ctx, req.Email).Return(nil, nil).Once()
+
+		resp, err := service.Login(ctx, req)
 
-		token, userResp, err := service.Login(ctx, req)
-		
 		assert.Error(t, err)
-		assert.Empty(t, token)
-		assert.Nil(t, userResp)
+		assert.Nil(t, resp)
 		assert.Contains(t, err.Error(), "invalid credentials")
 		mockRepo.AssertExpectations(t)
 	})
@@ -214,13 +563,12 @@ func TestUserService_Login(t *testing.T) {
 	t.Run("inactive user", func(t *testing.T) {
 		inactiveUser := *user
 		inactiveUser.IsActive = false
-		mockRepo.On("GetByEmail", ctx, req.Email).Return(&inactiveUser, nil)
+		mockRepo.On("GetByEmailOrUsername", ctx, req.Email).Return(&inactiveUser, nil).Once()
+
+		resp, err := service.Login(ctx, req)
 
-		token, userResp, err := service.Login(ctx, req)
-		
 		assert.Error(t, err)
-		assert.Empty(t, token)
-		assert.Nil(t, userResp)
+		assert.Nil(t, resp)
 		assert.Contains(t, err.Error(), "deactivated")
 		mockRepo.AssertExpectations(t)
 	})
@@ -230,18 +578,120 @@ func TestUserService_Login(t *testing.T) {
 			Email:    req.Email,
 			Password: "wrongpassword",
 		}
-		mockRepo.On("GetByEmail", ctx, wrongReq.Email).Return(user, nil)
+		mockRepo.On("GetByEmailOrUsername", ctx, wrongReq.Email).Return(user, nil).Once()
+
+		resp, err := service.Login(ctx, wrongReq)
 
-		token, userResp, err := service.Login(ctx, wrongReq)
-		
 		assert.Error(t, err)
-		assert.Empty(t, token)
-		assert.Nil(t, userResp)
+		assert.Nil(t, resp)
 		assert.Contains(t, err.Error(), "invalid credentials")
 		mockRepo.AssertExpectations(t)
 	})
 }
 
+func TestUserService_LoginPasswordExpired(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.Password.MaxAge = 90 * 24 * time.Hour
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	changedAt := time.Now().Add(-91 * 24 * time.Hour)
+	user := &models.User{
+		ID:                1,
+		Email:             "test@example.com",
+		Password:          string(hashedPassword),
+		IsActive:          true,
+		PasswordChangedAt: &changedAt,
+	}
+	req := &models.UserLoginRequest{Email: user.Email, Password: "password123"}
+
+	t.Run("expired password is refused", func(t *testing.T) {
+		mockRepo.On("GetByEmailOrUsername", ctx, req.Email).Return(user, nil).Once()
+
+		_, err := service.Login(ctx, req)
+
+		var expiredErr *PasswordExpiredError
+		require.ErrorAs(t, err, &expiredErr)
+		assert.WithinDuration(t, changedAt.Add(service.cfg.Password.MaxAge), expiredErr.ExpiredAt, time.Second)
+		mockAuth.AssertNotCalled(t, "GenerateToken", user.ID, user.Email, user.IsAdmin)
+	})
+
+	t.Run("wrong password doesn't reveal the expiry", func(t *testing.T) {
+		mockRepo.On("GetByEmailOrUsername", ctx, req.Email).Return(user, nil).Once()
+		mockRepo.On("RecordFailedLogin", ctx, user.ID).Return(1, nil).Once()
+
+		_, err := service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "wrong"})
+		assert.EqualError(t, err, "invalid credentials")
+	})
+
+	t.Run("expiry can be disabled", func(t *testing.T) {
+		service.cfg.Password.MaxAge = 0
+		mockRepo.On("GetByEmailOrUsername", ctx, req.Email).Return(user, nil).Once()
+		mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil).Once()
+		mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil).Once()
+		mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+
+		resp, err := service.Login(ctx, req)
+		require.NoError(t, err)
+		assert.Equal(t, "token123", resp.AccessToken)
+	})
+}
+
+func TestUserService_LoginLockout(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	service.cfg.Lockout = config.LockoutConfig{MaxFailedAttempts: 3, Duration: 15 * time.Minute}
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
+	user := &models.User{
+		ID:       1,
+		Email:    "test@example.com",
+		Password: string(hashedPassword),
+		IsActive: true,
+	}
+	wrongReq := &models.UserLoginRequest{Email: user.Email, Password: "wrongpassword"}
+
+	t.Run("failed attempt below limit", func(t *testing.T) {
+		mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil).Once()
+		mockRepo.On("RecordFailedLogin", ctx, user.ID).Return(2, nil).Once()
+
+		resp, err := service.Login(ctx, wrongReq)
+
+		assert.Nil(t, resp)
+		assert.EqualError(t, err, "invalid credentials")
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("failed attempt reaching limit locks account", func(t *testing.T) {
+		mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil).Once()
+		mockRepo.On("RecordFailedLogin", ctx, user.ID).Return(3, nil).Once()
+		mockRepo.On("LockUntil", ctx, user.ID, mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+		resp, err := service.Login(ctx, wrongReq)
+
+		assert.Nil(t, resp)
+		var lockedErr *AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		assert.InDelta(t, (15 * time.Minute).Seconds(), lockedErr.RetryAfter().Seconds(), 5)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("locked account is refused even with correct password", func(t *testing.T) {
+		lockedUser := *user
+		lockedUntil := time.Now().Add(10 * time.Minute)
+		lockedUser.LockedUntil = &lockedUntil
+		mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(&lockedUser, nil).Once()
+
+		resp, err := service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "password123"})
+
+		assert.Nil(t, resp)
+		var lockedErr *AccountLockedError
+		assert.ErrorAs(t, err, &lockedErr)
+		assert.Equal(t, lockedUntil, lockedErr.Until)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
 func TestUserService_GetByID(t *testing.T) {
 	service, mockRepo, _ := setupUserService()
 	ctx := context.Background()
@@ -252,10 +702,10 @@ func TestUserService_GetByID(t *testing.T) {
 	}
 
 	t.Run("user found", func(t *testing.T) {
-		mockRepo.On("GetByID", ctx, uint(1)).Return(user, nil)
+		mockRepo.On("GetByID", ctx, uint(1)).Return(user, nil).Once()
 
 		result, err := service.GetByID(ctx, 1)
-		
+
 		assert.NoError(t, err)
 		assert.NotNil(t, result)
 		assert.Equal(t, user.Email, result.Email)
@@ -263,10 +713,10 @@ func TestUserService_GetByID(t *testing.T) {
 	})
 
 	t.Run("user not found", func(t *testing.T) {
-		mockRepo.On("GetByID", ctx, uint(999)).Return(nil, nil)
+		mockRepo.On("GetByID", ctx, uint(999)).Return(nil, nil).Once()
 
 		result, err := service.GetByID(ctx, 999)
-		
+
 		assert.Error(t, err)
 		assert.Nil(t, result)
 		assert.Contains(t, err.Error(), "not found")
@@ -274,12 +724,793 @@ func TestUserService_GetByID(t *testing.T) {
 	})
 
 	t.Run("repository error", func(t *testing.T) {
-		mockRepo.On("GetByID", ctx, uint(1)).Return(nil, errors.New("database error"))
+		mockRepo.On("GetByID", ctx, uint(1)).Return(nil, errors.New("database error")).Once()
 
 		result, err := service.GetByID(ctx, 1)
-		
+
 		assert.Error(t, err)
 		assert.Nil(t, result)
 		mockRepo.AssertExpectations(t)
 	})
 }
+
+func TestUserService_Suspend(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	ctx := context.Background()
+
+	until := time.Now().Add(24 * time.Hour)
+	req := &models.UserSuspendRequest{Reason: "spamming other users", Until: &until}
+
+	t.Run("suspends and signs out the user", func(t *testing.T) {
+		user := &models.User{ID: 2, Email: "spammer@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, uint(2)).Return(nil).Once()
+		mockAuth.On("RevokeUserAccessTokens", ctx, uint(2)).Return(nil).Once()
+
+		result, err := service.Suspend(ctx, 1, 2, req)
+
+		require.NoError(t, err)
+		require.NotNil(t, result.Suspension)
+		assert.Equal(t, req.Reason, result.Suspension.Reason)
+		assert.Equal(t, uint(1), *result.Suspension.SuspendedBy)
+		assert.Equal(t, &until, result.Suspension.Until)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("rejects suspending yourself", func(t *testing.T) {
+		_, err := service.Suspend(ctx, 1, 1, req)
+		assert.ErrorIs(t, err, ErrInvalidSuspension)
+	})
+
+	t.Run("rejects an end time in the past", func(t *testing.T) {
+		past := time.Now().Add(-time.Minute)
+		_, err := service.Suspend(ctx, 1, 2, &models.UserSuspendRequest{Reason: "spam", Until: &past})
+		assert.ErrorIs(t, err, ErrInvalidSuspension)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(3)).Return(nil, nil).Once()
+		_, err := service.Suspend(ctx, 1, 3, req)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+
+	t.Run("blocks login only for the suspension period", func(t *testing.T) {
+		hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+		suspendedAt := time.Now().Add(-time.Hour)
+		user := &models.User{ID: 2, Email: "spammer@example.com", Password: string(hashedPassword), IsActive: true,
+			SuspendedAt: &suspendedAt, SuspendedUntil: &until}
+		loginReq := &models.UserLoginRequest{Email: user.Email, Password: "password123"}
+		mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil).Once()
+
+		_, err := service.Login(ctx, loginReq)
+
+		var suspendedErr *AccountSuspendedError
+		require.ErrorAs(t, err, &suspendedErr)
+		assert.Equal(t, &until, suspendedErr.Until)
+
+		// Once the end time passes the account can log in again
+		expired := time.Now().Add(-time.Minute)
+		user.SuspendedUntil = &expired
+		mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil).Once()
+		mockAuth.On("GenerateToken", user.ID, user.Email, false).Return("token", nil).Once()
+		mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh", nil).Once()
+		mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+
+		_, err = service.Login(ctx, loginReq)
+		assert.NoError(t, err)
+	})
+}
+
+func TestUserService_RequestDeletion(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.Deletion.GracePeriod = 30 * 24 * time.Hour
+	ctx := context.Background()
+
+	hashedPassword, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	user := &models.User{ID: 1, Email: "test@example.com", Password: string(hashedPassword), IsActive: true}
+
+	t.Run("rejects a wrong password", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(1)).Return(user, nil).Once()
+
+		_, err := service.RequestDeletion(ctx, 1, &models.AccountDeleteRequest{Password: "wrong"})
+
+		assert.ErrorIs(t, err, ErrInvalidPassword)
+		assert.True(t, user.IsActive)
+		assert.Nil(t, user.DeletionScheduledAt)
+	})
+
+	t.Run("deactivates the account and schedules deletion", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(1)).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, uint(1)).Return(nil).Once()
+
+		result, err := service.RequestDeletion(ctx, 1, &models.AccountDeleteRequest{Password: "password123"})
+
+		require.NoError(t, err)
+		assert.False(t, user.IsActive)
+		require.NotNil(t, user.DeletionScheduledAt)
+		assert.Equal(t, *user.DeletionScheduledAt, result.ScheduledAt)
+		assert.WithinDuration(t, time.Now().Add(30*24*time.Hour), result.ScheduledAt, time.Minute)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("logging in again cancels the deletion", func(t *testing.T) {
+		mockRepo.On("GetByEmailOrUsername", ctx, user.Email).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		mockAuth.On("GenerateToken", user.ID, user.Email, false).Return("token", nil).Once()
+		mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh", nil).Once()
+		mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+
+		_, err := service.Login(ctx, &models.UserLoginRequest{Email: user.Email, Password: "password123"})
+
+		require.NoError(t, err)
+		assert.True(t, user.IsActive)
+		assert.Nil(t, user.DeletionRequestedAt)
+		assert.Nil(t, user.DeletionScheduledAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("a wrong password does not cancel the deletion", func(t *testing.T) {
+		scheduledAt := time.Now().Add(time.Hour)
+		pending := &models.User{ID: 2, Email: "pending@example.com", Password: string(hashedPassword), DeletionScheduledAt: &scheduledAt}
+		mockRepo.On("GetByEmailOrUsername", ctx, pending.Email).Return(pending, nil).Once()
+
+		_, err := service.Login(ctx, &models.UserLoginRequest{Email: pending.Email, Password: "wrong"})
+
+		assert.Error(t, err)
+		assert.Equal(t, &scheduledAt, pending.DeletionScheduledAt)
+	})
+
+	t.Run("an admin deactivation keeps the deletion pending", func(t *testing.T) {
+		scheduledAt := time.Now().Add(time.Hour)
+		pending := &models.User{ID: 3, Email: "taken-over@example.com", Password: string(hashedPassword), IsActive: true}
+		pending.SetActive(false, pending.ID)
+		pending.DeletionScheduledAt = &scheduledAt
+		mockRepo.On("GetByID", ctx, pending.ID).Return(pending, nil).Once()
+		mockRepo.On("Update", ctx, pending).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, pending.ID).Return(nil).Once()
+		mockAuth.On("RevokeUserAccessTokens", ctx, pending.ID).Return(nil).Once()
+
+		_, err := service.Deactivate(ctx, 9, pending.ID)
+		require.NoError(t, err)
+		assert.False(t, pending.DeactivatedVoluntarily())
+
+		mockRepo.On("GetByEmailOrUsername", ctx, pending.Email).Return(pending, nil).Once()
+		_, err = service.Login(ctx, &models.UserLoginRequest{Email: pending.Email, Password: "password123"})
+
+		var deactivatedErr *AccountDeactivatedError
+		require.ErrorAs(t, err, &deactivatedErr)
+		assert.False(t, deactivatedErr.SelfService)
+		assert.False(t, pending.IsActive)
+		assert.Equal(t, &scheduledAt, pending.DeletionScheduledAt)
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+}
+
+func TestUserService_Reactivation(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	service.cfg.Reactivation.TokenTTL = time.Hour
+	service.cfg.Mail.LinkBaseURL = "https://app.example.com"
+	queue := service.queue.(*fakeQueue)
+	ctx := context.Background()
+
+	self := uint(1)
+	user := &models.User{ID: 1, Email: "jane@example.com", FirstName: "Jane", DeactivatedBy: &self}
+
+	requestLink := func() string {
+		mockRepo.On("GetByEmail", ctx, user.Email).Return(user, nil).Once()
+		require.NoError(t, service.RequestReactivation(ctx, &models.ReactivationRequest{Email: user.Email}))
+		require.NotEmpty(t, queue.jobs)
+		job := queue.jobs[len(queue.jobs)-1]
+		require.True(t, strings.HasPrefix(job, JobSendEmail+" "))
+		var msg struct{ Body string }
+		require.NoError(t, json.Unmarshal([]byte(strings.TrimPrefix(job, JobSendEmail+" ")), &msg))
+		match := regexp.MustCompile(`https://app\.example\.com/account/reactivate\?token=(\S+)`).FindStringSubmatch(msg.Body)
+		require.Len(t, match, 2)
+		return match[1]
+	}
+
+	t.Run("only voluntarily deactivated accounts get a link", func(t *testing.T) {
+		admin := uint(9)
+		byAdmin := &models.User{ID: 2, Email: "banned@example.com", DeactivatedBy: &admin}
+		mockRepo.On("GetByEmail", ctx, byAdmin.Email).Return(byAdmin, nil).Once()
+		mockRepo.On("GetByEmail", ctx, "unknown@example.com").Return(nil, nil).Once()
+
+		require.NoError(t, service.RequestReactivation(ctx, &models.ReactivationRequest{Email: byAdmin.Email}))
+		require.NoError(t, service.RequestReactivation(ctx, &models.ReactivationRequest{Email: "unknown@example.com"}))
+		assert.Empty(t, queue.jobs)
+	})
+
+	t.Run("only the latest link works, once", func(t *testing.T) {
+		stale := requestLink()
+		token := requestLink()
+
+		err := service.ConfirmReactivation(ctx, &models.ReactivationConfirmRequest{Token: stale})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+
+		mockRepo.On("GetByID", ctx, uint(1)).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		require.NoError(t, service.ConfirmReactivation(ctx, &models.ReactivationConfirmRequest{Token: token}))
+		assert.True(t, user.IsActive)
+		assert.Nil(t, user.DeactivatedBy)
+
+		err = service.ConfirmReactivation(ctx, &models.ReactivationConfirmRequest{Token: token})
+		assert.ErrorIs(t, err, ErrInvalidEmailToken)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("admin reactivation", func(t *testing.T) {
+		admin := uint(9)
+		deactivatedAt := time.Now().Add(-time.Hour)
+		banned := &models.User{ID: 2, Email: "banned@example.com", DeactivatedAt: &deactivatedAt, DeactivatedBy: &admin}
+		mockRepo.On("GetByID", ctx, uint(2)).Return(banned, nil).Once()
+		mockRepo.On("Update", ctx, banned).Return(nil).Once()
+
+		result, err := service.Reactivate(ctx, admin, 2)
+
+		require.NoError(t, err)
+		assert.True(t, result.IsActive)
+		assert.Nil(t, result.DeactivatedAt)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_AdminUpdate(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	roleRepo := &fakeRoleRepository{}
+	auditRepo := &fakeRBACAuditRepository{}
+	service.roleRepo = roleRepo
+	service.auditRepo = auditRepo
+	ctx := context.Background()
+
+	admin := &models.Role{Name: models.RoleAdmin, IsActive: true}
+	moderator := &models.Role{Name: models.RoleModerator, IsActive: true}
+	require.NoError(t, roleRepo.Create(ctx, admin))
+	require.NoError(t, roleRepo.Create(ctx, moderator))
+	require.NoError(t, roleRepo.AssignToUser(ctx, 2, []uint{moderator.ID}))
+
+	user := &models.User{ID: 2, Email: "jane@example.com", IsActive: true}
+	mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil)
+	mockRepo.On("Update", ctx, user).Return(nil)
+
+	t.Run("replaces roles and mirrors the admin role", func(t *testing.T) {
+		inactive := false
+		result, err := service.AdminUpdate(ctx, 1, 2, &models.AdminUserUpdateRequest{IsActive: &inactive, RoleIDs: []uint{admin.ID, admin.ID}})
+
+		require.NoError(t, err)
+		assert.True(t, result.IsAdmin)
+		assert.False(t, result.IsActive)
+		assert.Equal(t, []uint{admin.ID}, roleRepo.userRoleIDs[2])
+		require.Len(t, auditRepo.events, 1)
+		assert.Equal(t, models.RBACAuditRolesReplaced, auditRepo.events[0].Action)
+	})
+
+	t.Run("an empty list removes every role", func(t *testing.T) {
+		result, err := service.AdminUpdate(ctx, 1, 2, &models.AdminUserUpdateRequest{RoleIDs: []uint{}})
+
+		require.NoError(t, err)
+		assert.False(t, result.IsAdmin)
+		assert.Empty(t, roleRepo.userRoleIDs[2])
+	})
+
+	t.Run("is_admin wins over role_ids", func(t *testing.T) {
+		isAdmin := true
+		result, err := service.AdminUpdate(ctx, 1, 2, &models.AdminUserUpdateRequest{IsAdmin: &isAdmin, RoleIDs: []uint{moderator.ID}})
+
+		require.NoError(t, err)
+		assert.True(t, result.IsAdmin)
+		assert.ElementsMatch(t, []uint{moderator.ID, admin.ID}, roleRepo.userRoleIDs[2])
+	})
+
+	t.Run("unknown roles change nothing", func(t *testing.T) {
+		_, err := service.AdminUpdate(ctx, 1, 2, &models.AdminUserUpdateRequest{RoleIDs: []uint{moderator.ID, 99}})
+
+		assert.ErrorIs(t, err, ErrRoleNotFound)
+		assert.ElementsMatch(t, []uint{moderator.ID, admin.ID}, roleRepo.userRoleIDs[2])
+	})
+
+	t.Run("setting the email drops a pending email change", func(t *testing.T) {
+		tokens := newFakeEmailTokenRepository()
+		service.tokenRepo = tokens
+		user.PendingEmail = "jane.new@example.com"
+		require.NoError(t, tokens.Create(ctx, &models.EmailToken{UserID: user.ID, Purpose: models.EmailTokenEmailChange, TokenHash: "hash", ExpiresAt: time.Now().Add(time.Hour)}))
+		mockRepo.On("ExistsByEmail", ctx, "jane.doe@example.com").Return(false, nil).Once()
+
+		email := "jane.doe@example.com"
+		result, err := service.AdminUpdate(ctx, 1, 2, &models.AdminUserUpdateRequest{Email: &email})
+
+		require.NoError(t, err)
+		assert.Equal(t, email, result.Email)
+		assert.Empty(t, user.PendingEmail)
+		assert.NotNil(t, tokens.tokens[0].UsedAt, "the confirmation link can't be used anymore")
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(3)).Return(nil, nil).Once()
+		_, err := service.AdminUpdate(ctx, 1, 3, &models.AdminUserUpdateRequest{})
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestUserService_UpdateChecksVersion(t *testing.T) {
+	ctx := context.Background()
+	version := func(v uint) *uint { return &v }
+
+	t.Run("updates based on an older version are refused", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		user := &models.User{ID: 1, Email: "test@example.com", FirstName: "Jane", Version: 3}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+
+		firstName := "Janet"
+		_, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{FirstName: &firstName, Version: version(2)})
+		assert.ErrorIs(t, err, ErrUserChanged)
+		_, err = service.AdminUpdate(ctx, 2, 1, &models.AdminUserUpdateRequest{FirstName: &firstName, Version: version(2)})
+		assert.ErrorIs(t, err, ErrUserChanged)
+		assert.Equal(t, "Jane", user.FirstName)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("users changed while updating are refused", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		user := &models.User{ID: 1, Email: "test@example.com", Version: 3}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("Update", ctx, user).Return(repository.ErrVersionConflict)
+
+		firstName := "Janet"
+		_, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{FirstName: &firstName, Version: version(3)})
+		assert.ErrorIs(t, err, ErrUserChanged)
+		_, err = service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{FirstName: &firstName})
+		assert.ErrorIs(t, err, ErrUserChanged)
+	})
+
+	t.Run("other saves of a changed user are refused", func(t *testing.T) {
+		service, mockRepo, mockAuth := setupUserService()
+		user := &models.User{ID: 2, Email: "spammer@example.com", IsActive: true, Version: 3}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("Update", ctx, user).Return(repository.ErrVersionConflict)
+
+		_, err := service.Suspend(ctx, 1, 2, &models.UserSuspendRequest{Reason: "spamming other users"})
+		assert.ErrorIs(t, err, ErrUserChanged)
+		mockAuth.AssertNotCalled(t, "RevokeRefreshTokens", mock.Anything, mock.Anything)
+	})
+}
+
+func TestUserService_UpdateActiveStatus(t *testing.T) {
+	ctx := context.Background()
+	active, inactive := true, false
+	deactivatedBy := func(id uint) *uint { return &id }
+
+	t.Run("users can't deactivate their account", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		user := &models.User{ID: 1, Email: "test@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+
+		_, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{IsActive: &inactive})
+		assert.ErrorIs(t, err, ErrActiveStatusForbidden)
+		assert.True(t, user.IsActive)
+		mockRepo.AssertNotCalled(t, "Update", mock.Anything, mock.Anything)
+	})
+
+	t.Run("users can't undo a deactivation by an admin", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		user := &models.User{ID: 1, Email: "test@example.com", DeactivatedBy: deactivatedBy(2)}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+
+		_, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{IsActive: &active})
+		assert.ErrorIs(t, err, ErrActiveStatusForbidden)
+		assert.False(t, user.IsActive)
+	})
+
+	t.Run("users can undo their own deactivation", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		user := &models.User{ID: 1, Email: "test@example.com", DeactivatedBy: deactivatedBy(1)}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("Update", ctx, user).Return(nil)
+
+		_, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{IsActive: &active})
+		require.NoError(t, err)
+		assert.True(t, user.IsActive)
+		assert.Nil(t, user.DeactivatedBy)
+	})
+
+	t.Run("unchanged status is accepted", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		user := &models.User{ID: 1, Email: "test@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("Update", ctx, user).Return(nil)
+
+		_, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{IsActive: &active})
+		assert.NoError(t, err)
+	})
+
+	t.Run("admins can deactivate users", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		user := &models.User{ID: 1, Email: "test@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+		mockRepo.On("Update", ctx, user).Return(nil)
+
+		_, err := service.Update(ctx, 2, true, 1, &models.UserUpdateRequest{IsActive: &inactive})
+		require.NoError(t, err)
+		assert.False(t, user.IsActive)
+		assert.Equal(t, uint(2), *user.DeactivatedBy)
+	})
+}
+
+func TestUserService_UpdateMetadata(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	ctx := context.Background()
+
+	user := &models.User{ID: 1, Email: "test@example.com", IsActive: true}
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	mockRepo.On("Update", ctx, user).Return(nil)
+
+	metadata := models.Metadata{
+		"plan":     "pro",
+		"seats":    5.0,
+		"features": []interface{}{"sso", "audit"},
+		"billing":  map[string]interface{}{"address": map[string]interface{}{"country": "NL"}},
+	}
+	result, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{Metadata: metadata})
+	require.NoError(t, err)
+	assert.Equal(t, metadata, result.Metadata)
+
+	deep := map[string]interface{}{"value": 1.0}
+	for i := 0; i < models.MetadataMaxDepth; i++ {
+		deep = map[string]interface{}{"nested": deep}
+	}
+	for name, invalid := range map[string]models.Metadata{
+		"too deep":     {"nested": deep},
+		"too large":    {"blob": strings.Repeat("x", models.MetadataMaxSize)},
+		"empty key":    {"": "value"},
+		"key too long": {strings.Repeat("k", models.MetadataMaxKeyLength+1): "value"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := service.Update(ctx, 1, false, 1, &models.UserUpdateRequest{Metadata: invalid})
+			assert.ErrorIs(t, err, ErrInvalidMetadata)
+			_, err = service.Create(ctx, &models.UserCreateRequest{Email: "new@example.com", Metadata: invalid})
+			assert.ErrorIs(t, err, ErrInvalidMetadata)
+		})
+	}
+	assert.Equal(t, metadata, user.Metadata, "invalid metadata isn't stored")
+}
+
+func TestUserService_Restore(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	ctx := context.Background()
+
+	t.Run("restores a deleted user", func(t *testing.T) {
+		user := &models.User{ID: 2, Email: "jane@example.com", EmailIndex: "index", IsActive: true}
+		user.DeletedAt.Time, user.DeletedAt.Valid = time.Now(), true
+		mockRepo.On("GetByID", ctx, uint(2), mock.Anything).Return(user, nil).Once()
+		mockRepo.On("Restore", ctx, uint(2)).Return(true, nil).Once()
+
+		result, err := service.Restore(ctx, 1, 2)
+
+		require.NoError(t, err)
+		assert.Nil(t, result.DeletedAt)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("is a no-op for users that aren't deleted", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(3), mock.Anything).Return(&models.User{ID: 3, EmailIndex: "index"}, nil).Once()
+
+		_, err := service.Restore(ctx, 1, 3)
+
+		require.NoError(t, err)
+		mockRepo.AssertNotCalled(t, "Restore", ctx, uint(3))
+	})
+
+	t.Run("rejects anonymized users", func(t *testing.T) {
+		user := &models.User{ID: 4, Email: "deleted-4@deleted.invalid"}
+		user.DeletedAt.Time, user.DeletedAt.Valid = time.Now(), true
+		mockRepo.On("GetByID", ctx, uint(4), mock.Anything).Return(user, nil).Once()
+
+		_, err := service.Restore(ctx, 1, 4)
+		assert.ErrorIs(t, err, ErrInvalidRestore)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(5), mock.Anything).Return(nil, nil).Once()
+
+		_, err := service.Restore(ctx, 1, 5)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+	})
+}
+
+func TestUserService_HardDelete(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	ctx := context.Background()
+
+	mockRepo.On("HardDelete", ctx, uint(2)).Return(true, nil).Once()
+	require.NoError(t, service.HardDelete(ctx, 1, 2))
+
+	mockRepo.On("HardDelete", ctx, uint(3)).Return(false, nil).Once()
+	assert.ErrorIs(t, service.HardDelete(ctx, 1, 3), ErrUserNotFound)
+
+	assert.ErrorIs(t, service.HardDelete(ctx, 1, 1), ErrInvalidHardDelete)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_PurgeDeletedUsers(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	ctx := context.Background()
+
+	// Soft-deleted users are kept forever by default
+	purged, err := service.PurgeDeletedUsers(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, purged)
+
+	service.cfg.Deletion.HardDeleteAfter = 24 * time.Hour
+	mockRepo.On("PurgeDeleted", ctx, mock.MatchedBy(func(cutoff time.Time) bool {
+		return time.Since(cutoff) >= 24*time.Hour && time.Since(cutoff) < 25*time.Hour
+	})).Return([]uint{4, 5}, nil).Once()
+
+	purged, err = service.PurgeDeletedUsers(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, purged)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestUserService_AnonymizeDeletedUsers(t *testing.T) {
+	service, mockRepo, _ := setupUserService()
+	ctx := context.Background()
+
+	// Soft-deleted users keep their personal data by default
+	anonymized, err := service.AnonymizeDeletedUsers(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, anonymized)
+
+	t.Run("after a delay", func(t *testing.T) {
+		service.cfg.Deletion.Anonymize = true
+		service.cfg.Deletion.AnonymizeAfter = 24 * time.Hour
+		mockRepo.On("AnonymizeDeletedUsers", ctx, mock.MatchedBy(func(cutoff time.Time) bool {
+			return time.Since(cutoff) >= 24*time.Hour && time.Since(cutoff) < 25*time.Hour
+		})).Return([]uint{4, 5}, nil).Once()
+
+		anonymized, err := service.AnonymizeDeletedUsers(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, 2, anonymized)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("on deletion", func(t *testing.T) {
+		service.cfg.Deletion.AnonymizeAfter = 0
+		mockRepo.On("GetByID", ctx, uint(6)).Return(&models.User{ID: 6}, nil).Once()
+		mockRepo.On("Delete", ctx, uint(6)).Return(nil).Once()
+		mockRepo.On("AnonymizeDeletedUsers", ctx, mock.MatchedBy(func(cutoff time.Time) bool {
+			return time.Since(cutoff) < time.Minute
+		})).Return([]uint{6}, nil).Once()
+
+		require.NoError(t, service.Delete(ctx, 6))
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_Deactivate(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	ctx := context.Background()
+
+	t.Run("deactivates and signs out the user", func(t *testing.T) {
+		user := &models.User{ID: 2, Email: "jane@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+		mockRepo.On("Update", ctx, user).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, uint(2)).Return(nil).Once()
+		mockAuth.On("RevokeUserAccessTokens", ctx, uint(2)).Return(nil).Once()
+
+		result, err := service.Deactivate(ctx, 1, 2)
+
+		require.NoError(t, err)
+		assert.False(t, result.IsActive)
+		assert.NotNil(t, user.DeactivatedAt)
+		assert.False(t, user.DeactivatedVoluntarily(), "only an admin can reactivate the account")
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("rejects deactivating yourself", func(t *testing.T) {
+		_, err := service.Deactivate(ctx, 1, 1)
+		assert.ErrorIs(t, err, ErrInvalidDeactivation)
+	})
+
+	t.Run("unknown user", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(3)).Return(nil, nil).Twice()
+		_, err := service.Deactivate(ctx, 1, 3)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		assert.ErrorIs(t, service.LogoutAll(ctx, 1, 3), ErrUserNotFound)
+	})
+
+	t.Run("logout all revokes refresh and access tokens", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(2)).Return(&models.User{ID: 2, IsActive: true}, nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, uint(2)).Return(nil).Once()
+		mockAuth.On("RevokeUserAccessTokens", ctx, uint(2)).Return(nil).Once()
+
+		require.NoError(t, service.LogoutAll(ctx, 1, 2))
+		mockAuth.AssertExpectations(t)
+	})
+}
+
+func TestUserService_MergeAccounts(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	ctx := context.Background()
+
+	t.Run("moves ownership and deactivates the duplicate", func(t *testing.T) {
+		user := &models.User{ID: 2, Email: "jane@example.com", IsActive: true}
+		duplicate := &models.User{ID: 3, Email: "jane.doe@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+		mockRepo.On("GetByID", ctx, uint(3)).Return(duplicate, nil).Once()
+		mockRepo.On("TransferOwnership", ctx, uint(3), uint(2)).Return(nil).Once()
+		mockRepo.On("Update", ctx, duplicate).Return(nil).Once()
+		mockAuth.On("RevokeRefreshTokens", ctx, uint(3)).Return(nil).Once()
+
+		result, err := service.MergeAccounts(ctx, 1, 2, 3)
+
+		require.NoError(t, err)
+		assert.Equal(t, uint(2), result.ID)
+		assert.True(t, user.IsActive)
+		assert.False(t, duplicate.IsActive)
+		assert.False(t, duplicate.DeactivatedVoluntarily(), "only an admin can reactivate the duplicate")
+		mockRepo.AssertExpectations(t)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("rejects merging an account into itself", func(t *testing.T) {
+		_, err := service.MergeAccounts(ctx, 1, 2, 2)
+		assert.ErrorIs(t, err, ErrInvalidMerge)
+	})
+
+	t.Run("unknown duplicate", func(t *testing.T) {
+		mockRepo.On("GetByID", ctx, uint(2)).Return(&models.User{ID: 2, IsActive: true}, nil).Once()
+		mockRepo.On("GetByID", ctx, uint(4)).Return(nil, nil).Once()
+		_, err := service.MergeAccounts(ctx, 1, 2, 4)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		mockRepo.AssertNotCalled(t, "TransferOwnership", ctx, uint(4), uint(2))
+	})
+}
+
+func TestUserService_Impersonate(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	service.cfg.JWT.ImpersonationExpiry = 15 * time.Minute
+	ctx := context.Background()
+	admin := uint(9)
+
+	t.Run("issues a token naming the admin", func(t *testing.T) {
+		user := &models.User{ID: 2, Email: "user@example.com", IsActive: true}
+		mockRepo.On("GetByID", ctx, uint(2)).Return(user, nil).Once()
+		mockAuth.On("GenerateImpersonationToken", user, admin).Return("impersonation-token", nil).Once()
+
+		resp, err := service.Impersonate(ctx, admin, 2)
+
+		require.NoError(t, err)
+		assert.Equal(t, "impersonation-token", resp.AccessToken)
+		assert.Equal(t, admin, resp.ImpersonatorID)
+		assert.Equal(t, int64(900), resp.ExpiresIn)
+		assert.Equal(t, user.Email, resp.User.Email)
+		mockAuth.AssertExpectations(t)
+	})
+
+	t.Run("refuses admins, themselves and accounts that can't sign in", func(t *testing.T) {
+		_, err := service.Impersonate(ctx, admin, admin)
+		assert.ErrorIs(t, err, ErrInvalidImpersonation)
+
+		mockRepo.On("GetByID", ctx, uint(3)).Return(&models.User{ID: 3, IsActive: true, IsAdmin: true}, nil).Once()
+		_, err = service.Impersonate(ctx, admin, 3)
+		assert.ErrorIs(t, err, ErrInvalidImpersonation)
+
+		mockRepo.On("GetByID", ctx, uint(4)).Return(&models.User{ID: 4}, nil).Once()
+		_, err = service.Impersonate(ctx, admin, 4)
+		assert.ErrorIs(t, err, ErrInvalidImpersonation)
+
+		mockRepo.On("GetByID", ctx, uint(5)).Return(nil, nil).Once()
+		_, err = service.Impersonate(ctx, admin, 5)
+		assert.ErrorIs(t, err, ErrUserNotFound)
+		mockAuth.AssertNumberOfCalls(t, "GenerateImpersonationToken", 1)
+	})
+}
+
+func TestUserService_ReencryptUsers(t *testing.T) {
+	t.Run("encryption disabled", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+
+		n, err := service.ReencryptUsers(context.Background())
+		require.NoError(t, err)
+		assert.Zero(t, n)
+		mockRepo.AssertNotCalled(t, "ReencryptUsers", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("rewrites batches until none are left", func(t *testing.T) {
+		service, mockRepo, _ := setupUserService()
+		keyring, err := fieldcrypt.NewKeyring(map[string][]byte{"k1": make([]byte, 32)}, "k1", make([]byte, 32))
+		require.NoError(t, err)
+		fieldcrypt.SetDefault(keyring)
+		t.Cleanup(func() { fieldcrypt.SetDefault(nil) })
+
+		mockRepo.On("ReencryptUsers", mock.Anything, "enc:k1:", reencryptBatchSize).Return(reencryptBatchSize, nil).Once()
+		mockRepo.On("ReencryptUsers", mock.Anything, "enc:k1:", reencryptBatchSize).Return(3, nil).Once()
+
+		n, err := service.ReencryptUsers(context.Background())
+		require.NoError(t, err)
+		assert.Equal(t, reencryptBatchSize+3, n)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+func TestUserService_Logout(t *testing.T) {
+	service, _, mockAuth := setupUserService()
+	expiresAt := time.Now().Add(time.Hour)
+
+	mockAuth.On("RevokeRefreshTokens", mock.Anything, uint(1)).Return(nil).Once()
+	mockAuth.On("RevokeAccessToken", mock.Anything, "token-id", expiresAt).Return(nil).Once()
+	require.NoError(t, service.Logout(context.Background(), 1, "token-id", expiresAt))
+
+	// Tokens without an ID can only have their refresh tokens revoked
+	mockAuth.On("RevokeRefreshTokens", mock.Anything, uint(1)).Return(nil).Once()
+	require.NoError(t, service.Logout(context.Background(), 1, "", time.Time{}))
+
+	mockAuth.AssertExpectations(t)
+}
+
+// fakeAuthBackend signs in a fixed directory user for the right password
+type fakeAuthBackend struct {
+	user     *models.User
+	password string
+	err      error
+}
+
+func (b *fakeAuthBackend) Authenticate(ctx context.Context, login, password string, user *models.User) (*models.User, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if password != b.password {
+		return nil, ErrInvalidCredentials
+	}
+	return b.user, nil
+}
+
+func (b *fakeAuthBackend) VerifyPassword(ctx context.Context, user *models.User, password string) error {
+	if password != b.password {
+		return ErrInvalidCredentials
+	}
+	return nil
+}
+
+func TestUserService_LoginWithBackend(t *testing.T) {
+	service, mockRepo, mockAuth := setupUserService()
+	ctx := context.Background()
+	directoryUser := &models.User{ID: 7, Email: "jane@corp.example.com", IsActive: true}
+	service.backend = &fakeAuthBackend{user: directoryUser, password: "directory-password"}
+
+	t.Run("provisioned user without a local match", func(t *testing.T) {
+		mockRepo.On("GetByEmailOrUsername", ctx, "jane").Return(nil, nil).Once()
+		mockAuth.On("GenerateToken", directoryUser.ID, directoryUser.Email, false).Return("token123", nil).Once()
+		mockAuth.On("IssueRefreshToken", ctx, directoryUser.ID, false).Return("refresh123", nil).Once()
+		mockRepo.On("UpdateLastLogin", ctx, directoryUser.ID).Return(nil).Once()
+
+		resp, err := service.Login(ctx, &models.UserLoginRequest{Identifier: "jane", Password: "directory-password"})
+		require.NoError(t, err)
+		assert.Equal(t, "token123", resp.AccessToken)
+		assert.Equal(t, directoryUser.Email, resp.User.Email)
+	})
+
+	t.Run("wrong password without a local match", func(t *testing.T) {
+		mockRepo.On("GetByEmailOrUsername", ctx, "jane").Return(nil, nil).Once()
+
+		_, err := service.Login(ctx, &models.UserLoginRequest{Identifier: "jane", Password: "wrong"})
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("backend failure", func(t *testing.T) {
+		service.backend = &fakeAuthBackend{err: errors.New("connection refused")}
+		mockRepo.On("GetByEmailOrUsername", ctx, "jane").Return(nil, nil).Once()
+
+		_, err := service.Login(ctx, &models.UserLoginRequest{Identifier: "jane", Password: "directory-password"})
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	mockRepo.AssertExpectations(t)
+	mockAuth.AssertExpectations(t)
+}
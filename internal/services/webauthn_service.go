@@ -0,0 +1,393 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/internal/repository"
+	"gbt-be-template/pkg/challenge"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+)
+
+// Prefixes keeping registration and login ceremonies apart in the challenge store
+const (
+	webauthnRegisterPrefix = "webauthn:register:"
+	webauthnLoginPrefix    = "webauthn:login:"
+)
+
+// webauthnService implements the WebAuthnService interface
+type webauthnService struct {
+	wa         *webauthn.WebAuthn
+	userRepo   repository.UserRepository
+	credRepo   repository.WebAuthnRepository
+	challenges challenge.Store
+	logins     LoginCompleter
+	cfg        *config.Config
+	log        *logger.Logger
+}
+
+// NewWebAuthnService creates a new passkey registration and login service
+func NewWebAuthnService(wa *webauthn.WebAuthn, userRepo repository.UserRepository, credRepo repository.WebAuthnRepository, challenges challenge.Store, logins LoginCompleter, cfg *config.Config, log *logger.Logger) WebAuthnService {
+	return &webauthnService{
+		wa:         wa,
+		userRepo:   userRepo,
+		credRepo:   credRepo,
+		challenges: challenges,
+		logins:     logins,
+		cfg:        cfg,
+		log:        log,
+	}
+}
+
+// webauthnSession is the state kept between the two steps of a ceremony
+type webauthnSession struct {
+	UserID uint                 `json:"user_id"` // Zero for logins, where the passkey names the user
+	Data   webauthn.SessionData `json:"data"`
+}
+
+// BeginRegistration starts registering a passkey for the user
+func (s *webauthnService) BeginRegistration(ctx context.Context, userID uint) (*models.WebAuthnBeginResponse, error) {
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	// Passkeys must be discoverable so the login doesn't need a username, and exclude
+	// authenticators that already hold one for this user
+	creation, session, err := s.wa.BeginRegistration(user,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(webauthn.Credentials(user.WebAuthnCredentials()).CredentialDescriptors()),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin registration: %w", err)
+	}
+
+	sessionID, err := s.storeSession(ctx, webauthnRegisterPrefix, userID, session)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebAuthnBeginResponse{SessionID: sessionID, Options: creation}, nil
+}
+
+// FinishRegistration verifies the new credential and stores it for the user
+func (s *webauthnService) FinishRegistration(ctx context.Context, userID uint, req *models.WebAuthnRegisterRequest) (*models.WebAuthnCredentialResponse, error) {
+	session, err := s.takeSession(ctx, webauthnRegisterPrefix, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+	if session.UserID != userID {
+		return nil, ErrInvalidWebAuthnSession
+	}
+
+	user, err := s.loadUser(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if user == nil {
+		return nil, ErrUserNotFound
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(req.Credential)
+	if err != nil {
+		return nil, s.rejectCredential(userID, err)
+	}
+	credential, err := s.wa.CreateCredential(user, session.Data, parsed)
+	if err != nil {
+		return nil, s.rejectCredential(userID, err)
+	}
+
+	existing, err := s.credRepo.GetByCredentialID(ctx, credential.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check credential: %w", err)
+	}
+	if existing != nil {
+		return nil, s.rejectCredential(userID, errors.New("credential is already registered"))
+	}
+
+	name := req.Name
+	if name == "" {
+		name = "Passkey"
+	}
+	record := &models.WebAuthnCredential{
+		UserID:          userID,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      transportNames(credential.Transport),
+		Flags:           credentialFlags(credential.Flags),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		Name:            name,
+	}
+	if err := s.credRepo.Create(ctx, record); err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to store WebAuthn credential")
+		return nil, fmt.Errorf("failed to store credential: %w", err)
+	}
+
+	s.log.Security("webauthn_registered", userID).WithField("credential_id", record.ID).Info("Passkey registered")
+	return record.ToResponse(), nil
+}
+
+// BeginLogin starts a passwordless login. The browser offers the user's passkeys for this
+// relying party, so no username is needed.
+func (s *webauthnService) BeginLogin(ctx context.Context) (*models.WebAuthnBeginResponse, error) {
+	assertion, session, err := s.wa.BeginDiscoverableLogin(webauthn.WithUserVerification(protocol.VerificationRequired))
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin login: %w", err)
+	}
+
+	sessionID, err := s.storeSession(ctx, webauthnLoginPrefix, 0, session)
+	if err != nil {
+		return nil, err
+	}
+	return &models.WebAuthnBeginResponse{SessionID: sessionID, Options: assertion}, nil
+}
+
+// FinishLogin verifies the passkey assertion and issues an access token and refresh token.
+// Passkeys require user verification, so they replace both the password and a second factor.
+func (s *webauthnService) FinishLogin(ctx context.Context, req *models.WebAuthnLoginRequest) (*models.LoginResponse, error) {
+	session, err := s.takeSession(ctx, webauthnLoginPrefix, req.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(req.Credential)
+	if err != nil {
+		return nil, s.rejectCredential(0, err)
+	}
+
+	var record *models.WebAuthnCredential
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		found, err := s.credRepo.GetByCredentialID(ctx, rawID)
+		if err != nil {
+			return nil, err
+		}
+		if found == nil {
+			return nil, errors.New("unknown credential")
+		}
+		record = found
+		user, err := s.loadUser(ctx, record.UserID)
+		if err != nil {
+			return nil, err
+		}
+		if user == nil || !bytes.Equal(user.WebAuthnID(), userHandle) {
+			return nil, errors.New("credential does not belong to the user")
+		}
+		return user, nil
+	}
+	found, credential, err := s.wa.ValidatePasskeyLogin(handler, session.Data, parsed)
+	if err != nil {
+		return nil, s.rejectCredential(0, err)
+	}
+	user := found.(*webauthnUser).User
+
+	now := time.Now()
+	record.SignCount = credential.Authenticator.SignCount
+	record.CloneWarning = record.CloneWarning || credential.Authenticator.CloneWarning
+	record.Flags = credentialFlags(credential.Flags)
+	record.LastUsedAt = &now
+	if err := s.credRepo.RecordUse(ctx, record); err != nil {
+		s.log.WithError(err).WithField("user_id", user.ID).Warn("Failed to record WebAuthn credential use")
+	}
+	if credential.Authenticator.CloneWarning {
+		s.log.Security("webauthn_clone_warning", user.ID).WithField("credential_id", record.ID).Warn("Passkey signature counter went backwards, the authenticator may be cloned")
+	}
+
+	response, err := s.logins.CompleteLogin(ctx, user, models.LoginMethodPasskey, req.DeviceToken)
+	if err != nil {
+		return nil, err
+	}
+	if response.AccessToken != "" {
+		s.log.Security("webauthn_login", user.ID).WithField("credential_id", record.ID).Info("User logged in with a passkey")
+	}
+	return response, nil
+}
+
+// ListCredentials returns the passkeys registered by the user
+func (s *webauthnService) ListCredentials(ctx context.Context, userID uint) ([]*models.WebAuthnCredentialResponse, error) {
+	credentials, err := s.credRepo.ListByUser(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to list WebAuthn credentials")
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+
+	responses := make([]*models.WebAuthnCredentialResponse, len(credentials))
+	for i, credential := range credentials {
+		responses[i] = credential.ToResponse()
+	}
+	return responses, nil
+}
+
+// DeleteCredential removes one of the user's passkeys
+func (s *webauthnService) DeleteCredential(ctx context.Context, userID, id uint) error {
+	deleted, err := s.credRepo.Delete(ctx, userID, id)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to delete WebAuthn credential")
+		return fmt.Errorf("failed to delete credential: %w", err)
+	}
+	if !deleted {
+		return ErrCredentialNotFound
+	}
+
+	s.log.Security("webauthn_removed", userID).WithField("credential_id", id).Info("Passkey removed")
+	return nil
+}
+
+// storeSession keeps the ceremony state until the browser returns, under a random session ID
+func (s *webauthnService) storeSession(ctx context.Context, prefix string, userID uint, data *webauthn.SessionData) (string, error) {
+	sessionID, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate session ID: %w", err)
+	}
+	value, err := json.Marshal(&webauthnSession{UserID: userID, Data: *data})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := s.challenges.Put(ctx, prefix+sessionID, value, s.cfg.WebAuthn.Timeout); err != nil {
+		s.log.WithError(err).Error("Failed to store WebAuthn session")
+		return "", fmt.Errorf("failed to store session: %w", err)
+	}
+	return sessionID, nil
+}
+
+// takeSession returns the state of a ceremony, which can only be finished once
+func (s *webauthnService) takeSession(ctx context.Context, prefix, sessionID string) (*webauthnSession, error) {
+	value, err := s.challenges.Take(ctx, prefix+sessionID)
+	if errors.Is(err, challenge.ErrNotFound) {
+		return nil, ErrInvalidWebAuthnSession
+	}
+	if err != nil {
+		s.log.WithError(err).Error("Failed to get WebAuthn session")
+		return nil, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	var session webauthnSession
+	if err := json.Unmarshal(value, &session); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	return &session, nil
+}
+
+// rejectCredential logs why a credential failed verification and returns the error shown to clients
+func (s *webauthnService) rejectCredential(userID uint, err error) error {
+	entry := s.log.WithError(err)
+	var protocolErr *protocol.Error
+	if errors.As(err, &protocolErr) {
+		entry = entry.WithField("details", protocolErr.DevInfo)
+	}
+	if userID != 0 {
+		entry = entry.WithField("user_id", userID)
+	}
+	entry.Warn("WebAuthn credential rejected")
+	return ErrInvalidWebAuthnCredential
+}
+
+// loadUser returns the user together with their credentials, or nil for unknown users
+func (s *webauthnService) loadUser(ctx context.Context, userID uint) (*webauthnUser, error) {
+	user, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		s.log.WithError(err).WithField("user_id", userID).Error("Failed to get user for WebAuthn")
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user == nil {
+		return nil, nil
+	}
+
+	records, err := s.credRepo.ListByUser(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list credentials: %w", err)
+	}
+	credentials := make([]webauthn.Credential, len(records))
+	for i, record := range records {
+		transports := make([]protocol.AuthenticatorTransport, len(record.Transports))
+		for j, transport := range record.Transports {
+			transports[j] = protocol.AuthenticatorTransport(transport)
+		}
+		credentials[i] = webauthn.Credential{
+			ID:              record.CredentialID,
+			PublicKey:       record.PublicKey,
+			AttestationType: record.AttestationType,
+			Transport:       transports,
+			Flags:           webauthn.NewCredentialFlags(protocol.AuthenticatorFlags(record.Flags)),
+			Authenticator: webauthn.Authenticator{
+				AAGUID:       record.AAGUID,
+				SignCount:    record.SignCount,
+				CloneWarning: record.CloneWarning,
+			},
+		}
+	}
+	return &webauthnUser{User: user, credentials: credentials}, nil
+}
+
+// webauthnUser adapts a user and their credentials to webauthn.User
+type webauthnUser struct {
+	*models.User
+	credentials []webauthn.Credential
+}
+
+// WebAuthnID returns the user handle, the user ID as 8 big-endian bytes
+func (u *webauthnUser) WebAuthnID() []byte {
+	return binary.BigEndian.AppendUint64(nil, uint64(u.ID))
+}
+
+// WebAuthnName returns the name the browser shows for the account
+func (u *webauthnUser) WebAuthnName() string {
+	if u.Username != "" {
+		return u.Username
+	}
+	return u.Email
+}
+
+// WebAuthnDisplayName returns the user's full name, or their account name without one
+func (u *webauthnUser) WebAuthnDisplayName() string {
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.WebAuthnName()
+}
+
+// WebAuthnCredentials returns the user's registered credentials
+func (u *webauthnUser) WebAuthnCredentials() []webauthn.Credential {
+	return u.credentials
+}
+
+// credentialFlags packs the flags kept with a credential into authenticator data bits
+func credentialFlags(flags webauthn.CredentialFlags) uint8 {
+	var bits protocol.AuthenticatorFlags
+	if flags.UserPresent {
+		bits |= protocol.FlagUserPresent
+	}
+	if flags.UserVerified {
+		bits |= protocol.FlagUserVerified
+	}
+	if flags.BackupEligible {
+		bits |= protocol.FlagBackupEligible
+	}
+	if flags.BackupState {
+		bits |= protocol.FlagBackupState
+	}
+	return uint8(bits)
+}
+
+func transportNames(transports []protocol.AuthenticatorTransport) []string {
+	names := make([]string, len(transports))
+	for i, transport := range transports {
+		names[i] = string(transport)
+	}
+	return names
+}
@@ -0,0 +1,274 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/internal/models"
+	"gbt-be-template/pkg/challenge"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testWebAuthnOrigin = "https://app.example.com"
+
+// fakeWebAuthnRepository keeps credentials in memory
+type fakeWebAuthnRepository struct {
+	credentials []*models.WebAuthnCredential
+}
+
+func (r *fakeWebAuthnRepository) Create(ctx context.Context, credential *models.WebAuthnCredential) error {
+	credential.ID = uint(len(r.credentials) + 1)
+	credential.CreatedAt = time.Now()
+	copied := *credential
+	r.credentials = append(r.credentials, &copied)
+	return nil
+}
+
+func (r *fakeWebAuthnRepository) GetByCredentialID(ctx context.Context, credentialID []byte) (*models.WebAuthnCredential, error) {
+	for _, credential := range r.credentials {
+		if bytes.Equal(credential.CredentialID, credentialID) {
+			copied := *credential
+			return &copied, nil
+		}
+	}
+	return nil, nil
+}
+
+func (r *fakeWebAuthnRepository) ListByUser(ctx context.Context, userID uint) ([]*models.WebAuthnCredential, error) {
+	var credentials []*models.WebAuthnCredential
+	for _, credential := range r.credentials {
+		if credential.UserID == userID {
+			copied := *credential
+			credentials = append(credentials, &copied)
+		}
+	}
+	return credentials, nil
+}
+
+func (r *fakeWebAuthnRepository) RecordUse(ctx context.Context, credential *models.WebAuthnCredential) error {
+	for _, stored := range r.credentials {
+		if stored.ID == credential.ID {
+			stored.SignCount = credential.SignCount
+			stored.CloneWarning = credential.CloneWarning
+			stored.Flags = credential.Flags
+			stored.LastUsedAt = credential.LastUsedAt
+		}
+	}
+	return nil
+}
+
+func (r *fakeWebAuthnRepository) Delete(ctx context.Context, userID, id uint) (bool, error) {
+	for i, credential := range r.credentials {
+		if credential.ID == id && credential.UserID == userID {
+			r.credentials = append(r.credentials[:i], r.credentials[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// virtualAuthenticator creates and uses an ES256 passkey like a browser would
+type virtualAuthenticator struct {
+	key          *ecdsa.PrivateKey
+	credentialID []byte
+	userHandle   []byte
+	signCount    uint32
+}
+
+func newVirtualAuthenticator(t *testing.T) *virtualAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	credentialID := make([]byte, 16)
+	_, err = rand.Read(credentialID)
+	require.NoError(t, err)
+	return &virtualAuthenticator{key: key, credentialID: credentialID}
+}
+
+func (a *virtualAuthenticator) authData(rpID string, flags byte, attested []byte) []byte {
+	rpIDHash := sha256.Sum256([]byte(rpID))
+	data := append(rpIDHash[:], flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+	return append(data, attested...)
+}
+
+func (a *virtualAuthenticator) clientData(t *testing.T, ceremony, challenge string) []byte {
+	data, err := json.Marshal(map[string]string{"type": ceremony, "challenge": challenge, "origin": testWebAuthnOrigin})
+	require.NoError(t, err)
+	return data
+}
+
+// create answers navigator.credentials.create with a "none" attestation
+func (a *virtualAuthenticator) create(t *testing.T, options *protocol.CredentialCreation) json.RawMessage {
+	a.userHandle = options.Response.User.ID.(protocol.URLEncodedBase64)
+
+	publicKey, err := cbor.Marshal(map[int]interface{}{
+		1: 2, 3: -7, -1: 1,
+		-2: a.key.PublicKey.X.FillBytes(make([]byte, 32)),
+		-3: a.key.PublicKey.Y.FillBytes(make([]byte, 32)),
+	})
+	require.NoError(t, err)
+	attested := make([]byte, 16) // AAGUID
+	attested = binary.BigEndian.AppendUint16(attested, uint16(len(a.credentialID)))
+	attested = append(attested, a.credentialID...)
+	attested = append(attested, publicKey...)
+
+	attestation, err := cbor.Marshal(map[string]interface{}{
+		"fmt":      "none",
+		"attStmt":  map[string]interface{}{},
+		"authData": a.authData(options.Response.RelyingParty.ID, 0x45, attested), // UP, UV and AT
+	})
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(a.clientData(t, "webauthn.create", options.Response.Challenge.String())),
+		"attestationObject": encode(attestation),
+	})
+}
+
+// get answers navigator.credentials.get with a signed assertion
+func (a *virtualAuthenticator) get(t *testing.T, options *protocol.CredentialAssertion) json.RawMessage {
+	a.signCount++
+	authData := a.authData(options.Response.RelyingPartyID, 0x05, nil) // UP and UV
+	clientData := a.clientData(t, "webauthn.get", options.Response.Challenge.String())
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	require.NoError(t, err)
+
+	return a.credential(t, map[string]string{
+		"clientDataJSON":    encode(clientData),
+		"authenticatorData": encode(authData),
+		"signature":         encode(signature),
+		"userHandle":        encode(a.userHandle),
+	})
+}
+
+func (a *virtualAuthenticator) credential(t *testing.T, response map[string]string) json.RawMessage {
+	data, err := json.Marshal(map[string]interface{}{
+		"id":       encode(a.credentialID),
+		"rawId":    encode(a.credentialID),
+		"type":     "public-key",
+		"response": response,
+	})
+	require.NoError(t, err)
+	return data
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func setupWebAuthnService(t *testing.T) (*webauthnService, *MockUserRepository, *MockAuthService, *fakeWebAuthnRepository) {
+	cfg := &config.Config{}
+	cfg.WebAuthn.Timeout = time.Minute
+	cfg.JWT.Expiry = time.Hour
+	wa, err := webauthn.New(&webauthn.Config{
+		RPID:          "app.example.com",
+		RPDisplayName: "Test",
+		RPOrigins:     []string{testWebAuthnOrigin},
+	})
+	require.NoError(t, err)
+
+	logins, mockRepo, mockAuth := setupUserService()
+	credRepo := &fakeWebAuthnRepository{}
+	service := NewWebAuthnService(wa, mockRepo, credRepo, challenge.NewMemoryStore(), logins, cfg, logger.New("info", "text")).(*webauthnService)
+	return service, mockRepo, mockAuth, credRepo
+}
+
+func TestWebAuthnService_RegisterAndLogin(t *testing.T) {
+	service, mockRepo, mockAuth, credRepo := setupWebAuthnService(t)
+	ctx := context.Background()
+
+	user := &models.User{ID: 7, Email: "test@example.com", Username: "testuser", IsActive: true}
+	mockRepo.On("GetByID", ctx, user.ID).Return(user, nil)
+	authenticator := newVirtualAuthenticator(t)
+
+	// Registration
+	begin, err := service.BeginRegistration(ctx, user.ID)
+	require.NoError(t, err)
+	creation := begin.Options.(*protocol.CredentialCreation)
+	assert.Equal(t, protocol.ResidentKeyRequirementRequired, creation.Response.AuthenticatorSelection.ResidentKey)
+
+	credential, err := service.FinishRegistration(ctx, user.ID, &models.WebAuthnRegisterRequest{
+		SessionID:  begin.SessionID,
+		Name:       "Laptop",
+		Credential: authenticator.create(t, creation),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Laptop", credential.Name)
+	require.Len(t, credRepo.credentials, 1)
+
+	// The session can't be used twice
+	_, err = service.FinishRegistration(ctx, user.ID, &models.WebAuthnRegisterRequest{SessionID: begin.SessionID, Credential: authenticator.create(t, creation)})
+	assert.ErrorIs(t, err, ErrInvalidWebAuthnSession)
+
+	// Passwordless login
+	loginBegin, err := service.BeginLogin(ctx)
+	require.NoError(t, err)
+	assertion := loginBegin.Options.(*protocol.CredentialAssertion)
+
+	mockAuth.On("GenerateToken", user.ID, user.Email, user.IsAdmin).Return("token123", nil).Once()
+	mockAuth.On("IssueRefreshToken", ctx, user.ID, false).Return("refresh123", nil).Once()
+	mockRepo.On("UpdateLastLogin", ctx, user.ID).Return(nil).Once()
+
+	response, err := service.FinishLogin(ctx, &models.WebAuthnLoginRequest{
+		SessionID:  loginBegin.SessionID,
+		Credential: authenticator.get(t, assertion),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "token123", response.AccessToken)
+	assert.Equal(t, "refresh123", response.RefreshToken)
+	assert.NotEmpty(t, response.DeviceToken)
+	assert.Equal(t, uint32(1), credRepo.credentials[0].SignCount)
+	assert.NotNil(t, credRepo.credentials[0].LastUsedAt)
+	mockAuth.AssertExpectations(t)
+
+	// An assertion signed by another key is rejected
+	loginBegin, err = service.BeginLogin(ctx)
+	require.NoError(t, err)
+	impostor := newVirtualAuthenticator(t)
+	impostor.credentialID = authenticator.credentialID
+	impostor.userHandle = authenticator.userHandle
+	impostor.signCount = 5
+	_, err = service.FinishLogin(ctx, &models.WebAuthnLoginRequest{
+		SessionID:  loginBegin.SessionID,
+		Credential: impostor.get(t, loginBegin.Options.(*protocol.CredentialAssertion)),
+	})
+	assert.ErrorIs(t, err, ErrInvalidWebAuthnCredential)
+	mockRepo.AssertNumberOfCalls(t, "UpdateLastLogin", 1)
+}
+
+func TestWebAuthnService_Credentials(t *testing.T) {
+	service, _, _, credRepo := setupWebAuthnService(t)
+	ctx := context.Background()
+
+	require.NoError(t, credRepo.Create(ctx, &models.WebAuthnCredential{UserID: 1, CredentialID: []byte{1}, Name: "Phone", Flags: 0x18}))
+	require.NoError(t, credRepo.Create(ctx, &models.WebAuthnCredential{UserID: 2, CredentialID: []byte{2}, Name: "Other"}))
+
+	credentials, err := service.ListCredentials(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, credentials, 1)
+	assert.Equal(t, "Phone", credentials[0].Name)
+	assert.True(t, credentials[0].Synced)
+
+	// Users can't delete passkeys of others
+	assert.ErrorIs(t, service.DeleteCredential(ctx, 1, 2), ErrCredentialNotFound)
+	assert.NoError(t, service.DeleteCredential(ctx, 1, 1))
+	assert.ErrorIs(t, service.DeleteCredential(ctx, 1, 1), ErrCredentialNotFound)
+}
@@ -0,0 +1,4 @@
+-- Drop tables in reverse order due to foreign key constraints
+DROP TABLE IF EXISTS oauth_consents;
+DROP TABLE IF EXISTS oauth_authorization_codes;
+DROP TABLE IF EXISTS oauth_clients;
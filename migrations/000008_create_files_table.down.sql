@@ -0,0 +1,2 @@
+ALTER TABLE upload_sessions DROP COLUMN IF EXISTS file_id;
+DROP TABLE IF EXISTS files;
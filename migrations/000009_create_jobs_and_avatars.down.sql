@@ -0,0 +1,3 @@
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_urls;
+ALTER TABLE users DROP COLUMN IF EXISTS avatar_file_id;
+DROP TABLE IF EXISTS jobs;
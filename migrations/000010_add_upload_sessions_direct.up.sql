@@ -0,0 +1 @@
+ALTER TABLE upload_sessions ADD COLUMN IF NOT EXISTS direct BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS email_tokens;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_by;
+ALTER TABLE users DROP COLUMN IF EXISTS deactivated_at;
@@ -0,0 +1,11 @@
+-- Encrypted values cannot be decrypted in SQL, so only roll back databases that
+-- never had field encryption enabled.
+ALTER TABLE users DROP COLUMN IF EXISTS metadata;
+ALTER TABLE users DROP COLUMN IF EXISTS phone;
+
+DROP INDEX IF EXISTS idx_users_email_index;
+ALTER TABLE users DROP COLUMN IF EXISTS email_index;
+
+ALTER TABLE users ALTER COLUMN email TYPE VARCHAR(255);
+ALTER TABLE users ADD CONSTRAINT uni_users_email UNIQUE (email);
+CREATE INDEX IF NOT EXISTS idx_users_email ON users(email);
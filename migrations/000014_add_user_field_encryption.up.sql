@@ -0,0 +1,13 @@
+-- Emails are encrypted once field encryption is enabled, so uniqueness and lookups
+-- move to a blind index. Existing rows get the unkeyed SHA-256 index used while
+-- encryption is disabled; the re-encryption task replaces it with the keyed one.
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_email;
+DROP INDEX IF EXISTS idx_users_email;
+ALTER TABLE users ALTER COLUMN email TYPE TEXT;
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS email_index VARCHAR(64);
+UPDATE users SET email_index = encode(sha256(convert_to(email, 'UTF8')), 'hex') WHERE email_index IS NULL;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_index ON users(email_index);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone TEXT;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS metadata TEXT;
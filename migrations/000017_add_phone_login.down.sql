@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS otp_codes;
+DROP INDEX IF EXISTS idx_users_phone_index;
+ALTER TABLE users DROP COLUMN IF EXISTS phone_index;
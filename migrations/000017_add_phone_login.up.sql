@@ -0,0 +1,20 @@
+-- Phone numbers are encrypted like emails, so logging in by phone needs a blind
+-- index. Rows with a plaintext phone get the unkeyed SHA-256 index here; the
+-- re-encryption task fills in the keyed index of rows that are already encrypted.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS phone_index VARCHAR(64);
+UPDATE users SET phone_index = encode(sha256(convert_to(phone, 'UTF8')), 'hex')
+    WHERE phone_index IS NULL AND phone <> '' AND phone NOT LIKE 'enc:%';
+CREATE INDEX IF NOT EXISTS idx_users_phone_index ON users(phone_index);
+
+-- One pending SMS login code per user, replaced by every new request
+CREATE TABLE IF NOT EXISTS otp_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_otp_codes_user_id ON otp_codes(user_id);
@@ -0,0 +1,4 @@
+-- Existing passwords count as changed when the migration runs, so they get
+-- the full PASSWORD_MAX_AGE before expiring.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS password_changed_at TIMESTAMP;
+UPDATE users SET password_changed_at = CURRENT_TIMESTAMP WHERE password_changed_at IS NULL;
@@ -0,0 +1 @@
+ALTER TABLE roles DROP COLUMN IF EXISTS parent_role_id;
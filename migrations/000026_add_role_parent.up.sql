@@ -0,0 +1,3 @@
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS parent_role_id INTEGER REFERENCES roles(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS idx_roles_parent_role_id ON roles(parent_role_id);
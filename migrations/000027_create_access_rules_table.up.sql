@@ -0,0 +1,15 @@
+CREATE TABLE IF NOT EXISTS access_rules (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(100) NOT NULL,
+    description VARCHAR(255),
+    resource VARCHAR(100) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    effect VARCHAR(20) NOT NULL,
+    condition VARCHAR(1000) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_access_rules_name ON access_rules(name);
+CREATE INDEX IF NOT EXISTS idx_access_rules_resource_action ON access_rules(resource, action);
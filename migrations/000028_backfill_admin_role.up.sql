@@ -0,0 +1,11 @@
+-- The admin role backs the is_admin flag: make sure the role exists and every admin holds it
+INSERT INTO roles (name, description, is_active) VALUES
+('admin', 'System administrator with full access', true)
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO user_roles (user_id, role_id)
+SELECT users.id, roles.id
+FROM users
+JOIN roles ON roles.name = 'admin'
+WHERE users.is_admin = true AND users.deleted_at IS NULL
+ON CONFLICT (user_id, role_id) DO NOTHING;
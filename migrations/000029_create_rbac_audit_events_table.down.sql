@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS rbac_audit_events_immutable ON rbac_audit_events;
+DROP FUNCTION IF EXISTS rbac_audit_events_immutable();
+DROP TABLE IF EXISTS rbac_audit_events;
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS rbac_audit_events (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER NOT NULL DEFAULT 0,
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(20) NOT NULL,
+    target_id INTEGER NOT NULL,
+    before TEXT,
+    after TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rbac_audit_events_actor_id ON rbac_audit_events(actor_id);
+CREATE INDEX IF NOT EXISTS idx_rbac_audit_events_action ON rbac_audit_events(action);
+CREATE INDEX IF NOT EXISTS idx_rbac_audit_events_target ON rbac_audit_events(target_type, target_id);
+CREATE INDEX IF NOT EXISTS idx_rbac_audit_events_created_at ON rbac_audit_events(created_at);
+
+-- Audit events are never changed or removed
+CREATE OR REPLACE FUNCTION rbac_audit_events_immutable()
+RETURNS TRIGGER AS $$
+BEGIN
+    RAISE EXCEPTION 'rbac_audit_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS rbac_audit_events_immutable ON rbac_audit_events;
+CREATE TRIGGER rbac_audit_events_immutable
+    BEFORE UPDATE OR DELETE ON rbac_audit_events
+    FOR EACH ROW
+    EXECUTE FUNCTION rbac_audit_events_immutable();
@@ -0,0 +1,3 @@
+-- An email change is kept here, encrypted like the email, until it is
+-- confirmed from the new address.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS pending_email TEXT;
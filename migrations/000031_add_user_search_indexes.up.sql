@@ -0,0 +1,7 @@
+-- Indexes for searching and filtering the user list. Each word of a search
+-- matches the start of a lowercased username or name, which text_pattern_ops
+-- indexes serve; emails are encrypted and matched through email_index.
+CREATE INDEX IF NOT EXISTS idx_users_username_lower ON users (LOWER(username) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_first_name_lower ON users (LOWER(first_name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_last_name_lower ON users (LOWER(last_name) text_pattern_ops);
+CREATE INDEX IF NOT EXISTS idx_users_created_at ON users(created_at);
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS user_settings (
+    user_id INTEGER PRIMARY KEY,
+    timezone VARCHAR(64) NOT NULL,
+    locale VARCHAR(35) NOT NULL,
+    notifications TEXT,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
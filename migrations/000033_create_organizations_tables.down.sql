@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS organization_memberships;
+DROP TABLE IF EXISTS organizations;
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_organizations_tenant_id;
+
+-- Fails if a username or email is used by several tenants
+DROP INDEX IF EXISTS idx_users_email_index;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_index ON users(email_index);
+DROP INDEX IF EXISTS idx_users_username_tenant;
+ALTER TABLE users ADD CONSTRAINT uni_users_username UNIQUE (username);
+
+ALTER TABLE organizations DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE users DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,11 @@
+-- Rows created before tenancy was enabled belong to the default tenant, ''.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE organizations ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(64) NOT NULL DEFAULT '';
+
+-- Usernames and emails are unique per tenant
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_username;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_tenant ON users(username, tenant_id);
+DROP INDEX IF EXISTS idx_users_email_index;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_index ON users(email_index, tenant_id);
+
+CREATE INDEX IF NOT EXISTS idx_organizations_tenant_id ON organizations(tenant_id);
@@ -0,0 +1,2 @@
+-- Incremented by every update of a user, which is refused when it changed since the user was read
+ALTER TABLE users ADD COLUMN IF NOT EXISTS version BIGINT NOT NULL DEFAULT 1;
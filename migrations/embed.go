@@ -0,0 +1,16 @@
+// Package migrations embeds the SQL migrations, so that the binary can apply them without the
+// migrations directory. Files are named for golang-migrate: <version>_<name>.(up|down).sql.
+package migrations
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed *.sql
+var files embed.FS
+
+// FS returns the embedded migration files
+func FS() fs.FS {
+	return files
+}
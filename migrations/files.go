@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+)
+
+// versionPattern matches the sequence number of golang-migrate migration files
+var versionPattern = regexp.MustCompile(`^(\d+)_.+\.(?:up|down)\.sql$`)
+
+// namePattern keeps new file names in the style of the existing migrations
+var namePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// ValidName reports whether name can name a new migration, such as add_user_nickname
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// NextVersion returns the sequence number following the highest one in dir
+func NextVersion(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+
+	highest := 0
+	for _, entry := range entries {
+		m := versionPattern.FindStringSubmatch(entry.Name())
+		if m == nil {
+			continue
+		}
+		if version, err := strconv.Atoi(m[1]); err == nil && version > highest {
+			highest = version
+		}
+	}
+	return highest + 1, nil
+}
+
+// BasePath returns the path of the migration files of version and name in dir, without the
+// .up.sql and .down.sql suffixes
+func BasePath(dir string, version int, name string) string {
+	return filepath.Join(dir, fmt.Sprintf("%06d_%s", version, name))
+}
@@ -0,0 +1,41 @@
+package migrations
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextVersion(t *testing.T) {
+	dir := t.TempDir()
+
+	version, err := NextVersion(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 1, version)
+
+	// Only migration files count, whatever the width of their number
+	for _, name := range []string{"000001_init.up.sql", "000001_init.down.sql", "000009_add_tags.up.sql", "12_notes.txt", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	version, err = NextVersion(dir)
+	require.NoError(t, err)
+	assert.Equal(t, 10, version)
+
+	_, err = NextVersion(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestValidName(t *testing.T) {
+	assert.True(t, ValidName("add_user_nickname"))
+	assert.True(t, ValidName("v2_tags"))
+	assert.False(t, ValidName(""))
+	assert.False(t, ValidName("Add-Nickname"))
+	assert.False(t, ValidName("../escape"))
+}
+
+func TestBasePath(t *testing.T) {
+	assert.Equal(t, filepath.Join("migrations", "000038_add_user_nickname"), BasePath("migrations", 38, "add_user_nickname"))
+}
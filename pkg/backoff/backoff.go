@@ -0,0 +1,142 @@
+// Package backoff tracks failed attempts per key and blocks keys for exponentially
+// growing periods once they fail too often, slowing down password guessing. It also
+// retries failing operations after exponentially growing delays.
+package backoff
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Policy configures when a key is blocked and for how long
+type Policy struct {
+	Threshold  int           // Failures allowed before the key is blocked
+	BaseDelay  time.Duration // Block after reaching the threshold, doubled by every further failure
+	MaxDelay   time.Duration // Upper bound of a single block
+	ResetAfter time.Duration // Failures are forgotten after this long without a new one
+}
+
+// delay returns how long a key is blocked after its nth failure
+func (p Policy) delay(failures int) time.Duration {
+	if failures < p.Threshold {
+		return 0
+	}
+	delay := p.BaseDelay
+	for i := p.Threshold; i < failures && delay < p.MaxDelay; i++ {
+		delay *= 2
+	}
+	if delay > p.MaxDelay {
+		delay = p.MaxDelay
+	}
+	return delay
+}
+
+// Stats describes a tracker for monitoring
+type Stats struct {
+	Tracked int    `json:"tracked"` // Keys with recent failures
+	Blocked int    `json:"blocked"` // Keys blocked right now
+	Refused uint64 `json:"refused"` // Attempts refused since startup
+}
+
+// entry is the failure history of a single key
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+}
+
+// Tracker counts failures in process. Counts are not shared between instances.
+type Tracker struct {
+	policy    Policy
+	mu        sync.Mutex
+	entries   map[string]*entry
+	nextSweep time.Time
+	refused   atomic.Uint64
+	now       func() time.Time
+}
+
+// New creates a tracker applying policy
+func New(policy Policy) *Tracker {
+	return &Tracker{
+		policy:  policy,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Check reports how long key stays blocked, zero when an attempt may go ahead.
+// Blocked attempts are counted as refused.
+func (t *Tracker) Check(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	e, ok := t.entries[key]
+	if !ok {
+		return 0
+	}
+	remaining := e.blockedUntil.Sub(t.now())
+	if remaining <= 0 {
+		return 0
+	}
+	t.refused.Add(1)
+	return remaining
+}
+
+// Fail records a failed attempt and returns how long key is blocked because of it
+func (t *Tracker) Fail(key string) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	t.sweep(now)
+
+	e, ok := t.entries[key]
+	if !ok || now.Sub(e.lastFailure) > t.policy.ResetAfter {
+		e = &entry{}
+		t.entries[key] = e
+	}
+	e.failures++
+	e.lastFailure = now
+
+	delay := t.policy.delay(e.failures)
+	if delay > 0 {
+		e.blockedUntil = now.Add(delay)
+	}
+	return delay
+}
+
+// Reset forgets the failures of key, such as after a successful attempt
+func (t *Tracker) Reset(key string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.entries, key)
+}
+
+// Stats returns the current counts
+func (t *Tracker) Stats() Stats {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	stats := Stats{Tracked: len(t.entries), Refused: t.refused.Load()}
+	for _, e := range t.entries {
+		if now.Before(e.blockedUntil) {
+			stats.Blocked++
+		}
+	}
+	return stats
+}
+
+// sweep drops keys whose failures were forgotten so idle keys don't accumulate
+func (t *Tracker) sweep(now time.Time) {
+	if now.Before(t.nextSweep) {
+		return
+	}
+	for key, e := range t.entries {
+		if now.Sub(e.lastFailure) > t.policy.ResetAfter && !now.Before(e.blockedUntil) {
+			delete(t.entries, key)
+		}
+	}
+	t.nextSweep = now.Add(t.policy.ResetAfter)
+}
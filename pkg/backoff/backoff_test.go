@@ -0,0 +1,68 @@
+package backoff
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestTracker() (*Tracker, *time.Time) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tracker := New(Policy{Threshold: 3, BaseDelay: time.Second, MaxDelay: 10 * time.Second, ResetAfter: time.Hour})
+	tracker.now = func() time.Time { return now }
+	return tracker, &now
+}
+
+func TestTracker_Delay(t *testing.T) {
+	tracker, now := newTestTracker()
+
+	assert.Zero(t, tracker.Fail("key"))
+	assert.Zero(t, tracker.Fail("key"))
+	assert.Zero(t, tracker.Check("key"))
+
+	// Blocked from the threshold on, doubling up to the maximum
+	for _, want := range []time.Duration{1, 2, 4, 8, 10, 10} {
+		assert.Equal(t, want*time.Second, tracker.Fail("key"))
+	}
+	assert.Equal(t, 10*time.Second, tracker.Check("key"))
+	assert.Zero(t, tracker.Check("other"))
+
+	*now = now.Add(10 * time.Second)
+	assert.Zero(t, tracker.Check("key"))
+}
+
+func TestTracker_Reset(t *testing.T) {
+	tracker, now := newTestTracker()
+
+	for i := 0; i < 3; i++ {
+		tracker.Fail("key")
+	}
+	assert.Positive(t, tracker.Check("key"))
+
+	tracker.Reset("key")
+	assert.Zero(t, tracker.Check("key"))
+	assert.Zero(t, tracker.Fail("key"))
+
+	// Failures are forgotten after a quiet period
+	tracker.Fail("key")
+	*now = now.Add(2 * time.Hour)
+	assert.Zero(t, tracker.Fail("key"))
+}
+
+func TestTracker_Stats(t *testing.T) {
+	tracker, now := newTestTracker()
+
+	tracker.Fail("a")
+	for i := 0; i < 3; i++ {
+		tracker.Fail("b")
+	}
+	tracker.Check("b")
+	tracker.Check("b")
+	assert.Equal(t, Stats{Tracked: 2, Blocked: 1, Refused: 2}, tracker.Stats())
+
+	// Idle keys are swept on the next failure
+	*now = now.Add(2 * time.Hour)
+	tracker.Fail("c")
+	assert.Equal(t, Stats{Tracked: 1, Blocked: 0, Refused: 2}, tracker.Stats())
+}
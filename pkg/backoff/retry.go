@@ -0,0 +1,59 @@
+package backoff
+
+import (
+	"context"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures how often and for how long a failing operation is retried
+type RetryPolicy struct {
+	Retries   int           // Attempts after the first one
+	BaseDelay time.Duration // Wait before the first retry, doubled before every further one
+	MaxWait   time.Duration // No retry starts later than this after the first attempt; 0 for no limit
+}
+
+// Retry calls fn until it succeeds. Failures are retried after delays growing exponentially
+// from BaseDelay, each shortened by a random jitter of up to half so that several instances
+// don't retry in lockstep. notify, when not nil, is told about each failure that is retried.
+// Retry returns the last error once the retries or the wait are used up, or when ctx is done.
+func Retry(ctx context.Context, policy RetryPolicy, fn func() error, notify func(attempt int, err error, delay time.Duration)) error {
+	deadline := time.Now().Add(policy.MaxWait)
+	delay := policy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt > policy.Retries {
+			return err
+		}
+
+		wait := jitter(delay)
+		if policy.MaxWait > 0 {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return err
+			}
+			wait = min(wait, remaining)
+		}
+		if notify != nil {
+			notify(attempt, err, wait)
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		delay *= 2
+	}
+}
+
+// jitter returns a random delay between half of delay and delay
+func jitter(delay time.Duration) time.Duration {
+	if delay <= 1 {
+		return delay
+	}
+	half := delay / 2
+	return half + rand.N(delay-half)
+}
@@ -0,0 +1,72 @@
+package backoff
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetry(t *testing.T) {
+	failure := errors.New("connection refused")
+
+	t.Run("retries until the operation succeeds", func(t *testing.T) {
+		calls := 0
+		var delays []time.Duration
+		err := Retry(context.Background(), RetryPolicy{Retries: 5, BaseDelay: 4 * time.Millisecond}, func() error {
+			calls++
+			if calls < 4 {
+				return failure
+			}
+			return nil
+		}, func(attempt int, err error, delay time.Duration) {
+			assert.Equal(t, len(delays)+1, attempt)
+			assert.ErrorIs(t, err, failure)
+			delays = append(delays, delay)
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 4, calls)
+
+		// Delays double, with up to half taken off by the jitter
+		for i, delay := range delays {
+			full := 4 * time.Millisecond << i
+			assert.GreaterOrEqual(t, delay, full/2)
+			assert.LessOrEqual(t, delay, full)
+		}
+	})
+
+	t.Run("gives up after the last retry", func(t *testing.T) {
+		calls := 0
+		err := Retry(context.Background(), RetryPolicy{Retries: 2, BaseDelay: time.Millisecond}, func() error {
+			calls++
+			return failure
+		}, nil)
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 3, calls)
+	})
+
+	t.Run("gives up after the max wait", func(t *testing.T) {
+		calls := 0
+		start := time.Now()
+		err := Retry(context.Background(), RetryPolicy{Retries: 100, BaseDelay: 10 * time.Millisecond, MaxWait: 50 * time.Millisecond}, func() error {
+			calls++
+			return failure
+		}, nil)
+		assert.ErrorIs(t, err, failure)
+		assert.Less(t, calls, 10)
+		assert.Less(t, time.Since(start), time.Second)
+	})
+
+	t.Run("stops when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		calls := 0
+		err := Retry(ctx, RetryPolicy{Retries: 5, BaseDelay: time.Hour}, func() error {
+			calls++
+			return failure
+		}, func(int, error, time.Duration) { cancel() })
+		assert.ErrorIs(t, err, failure)
+		assert.Equal(t, 1, calls)
+	})
+}
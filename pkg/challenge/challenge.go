@@ -0,0 +1,23 @@
+// Package challenge keeps short-lived, single-use values between the two steps
+// of a ceremony, such as the challenge of a WebAuthn registration or login.
+//
+// A value can be taken once: Take removes it, so a replayed second step finds
+// nothing. Values also expire on their own when the ceremony is abandoned.
+package challenge
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrNotFound is returned by Take for unknown, expired or already taken keys
+var ErrNotFound = errors.New("challenge not found")
+
+// Store holds values until they are taken or expire
+type Store interface {
+	// Put stores value under key for ttl, replacing any previous value
+	Put(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Take returns the value stored under key and removes it
+	Take(ctx context.Context, key string) ([]byte, error)
+}
@@ -0,0 +1,62 @@
+package challenge
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.clock = func() time.Time { return now }
+
+	require.NoError(t, store.Put(ctx, "a", []byte("one"), time.Minute))
+	require.NoError(t, store.Put(ctx, "b", []byte("two"), time.Minute))
+
+	value, err := store.Take(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), value)
+
+	// Values can only be taken once
+	_, err = store.Take(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = store.Take(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	now = now.Add(2 * time.Minute)
+	_, err = store.Take(ctx, "b")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	store := NewRedisStore(client)
+	require.NoError(t, store.Put(ctx, "a", []byte("one"), time.Minute))
+	require.NoError(t, store.Put(ctx, "b", []byte("two"), time.Minute))
+	assert.InDelta(t, time.Minute.Seconds(), server.TTL("challenge:a").Seconds(), 1)
+
+	// Values stored by other instances are seen through Redis, once
+	other := NewRedisStore(client)
+	value, err := other.Take(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, []byte("one"), value)
+
+	_, err = store.Take(ctx, "a")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	server.FastForward(2 * time.Minute)
+	_, err = store.Take(ctx, "b")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
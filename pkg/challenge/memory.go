@@ -0,0 +1,65 @@
+package challenge
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of writes between removals of expired entries
+const sweepEvery = 1000
+
+type entry struct {
+	value   []byte
+	expires time.Time
+}
+
+// MemoryStore is an in-process Store for single instance deployments and tests.
+// Values are lost on restart and not shared between instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	entries map[string]entry
+	writes  int
+	clock   func() time.Time
+}
+
+// NewMemoryStore creates an in-process challenge store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]entry), clock: time.Now}
+}
+
+// Put stores value under key for ttl
+func (s *MemoryStore) Put(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	s.entries[key] = entry{value: value, expires: now.Add(ttl)}
+
+	// Drop abandoned entries now and then so the map does not grow without bound
+	s.writes++
+	if s.writes%sweepEvery == 0 {
+		for k, e := range s.entries {
+			if !e.expires.After(now) {
+				delete(s.entries, k)
+			}
+		}
+	}
+	return nil
+}
+
+// Take returns the value stored under key and removes it
+func (s *MemoryStore) Take(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.entries[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	delete(s.entries, key)
+	if !e.expires.After(s.clock()) {
+		return nil, ErrNotFound
+	}
+	return e.value, nil
+}
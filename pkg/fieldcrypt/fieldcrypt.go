@@ -0,0 +1,183 @@
+// Package fieldcrypt encrypts individual database columns with AES-GCM and
+// derives blind indexes so encrypted values can still be looked up by equality.
+//
+// Ciphertexts are stored as "enc:<key id>:<base64 nonce and sealed data>". The
+// key ID lets a keyring hold retired keys for decryption while new values are
+// written with the primary key, so keys can be rotated by re-saving rows.
+// Values without the "enc:" prefix are returned as-is, which keeps rows written
+// before encryption was enabled readable.
+package fieldcrypt
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+const prefix = "enc:"
+
+// ErrUnknownKey is returned when a value was encrypted with a key the keyring does not hold
+var ErrUnknownKey = errors.New("value encrypted with unknown key")
+
+// ErrMalformed is returned when an encrypted value cannot be parsed or authenticated
+var ErrMalformed = errors.New("malformed encrypted value")
+
+// Key IDs appear in ciphertexts and in LIKE patterns, so they are kept free of separators and wildcards
+var keyIDPattern = regexp.MustCompile(`^[A-Za-z0-9.-]+$`)
+
+// Keyring encrypts with its primary key and decrypts with any of its keys
+type Keyring struct {
+	primary  string
+	aeads    map[string]cipher.AEAD
+	indexKey []byte
+}
+
+// NewKeyring creates a keyring from 32 byte AES keys by ID. The index key is the
+// HMAC key for blind indexes; changing it invalidates every stored index.
+func NewKeyring(keys map[string][]byte, primary string, indexKey []byte) (*Keyring, error) {
+	if _, ok := keys[primary]; !ok {
+		return nil, fmt.Errorf("primary key %q is not in the keyring", primary)
+	}
+	if len(indexKey) < 32 {
+		return nil, errors.New("blind index key must be at least 32 bytes")
+	}
+
+	k := &Keyring{primary: primary, aeads: make(map[string]cipher.AEAD, len(keys)), indexKey: indexKey}
+	for id, key := range keys {
+		if !keyIDPattern.MatchString(id) {
+			return nil, fmt.Errorf("invalid key ID %q", id)
+		}
+		if len(key) != 32 {
+			return nil, fmt.Errorf("key %q must be 32 bytes, got %d", id, len(key))
+		}
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		k.aeads[id] = aead
+	}
+	return k, nil
+}
+
+// ParseKeys parses a comma separated list of "id:base64 key" pairs.
+// It also returns the ID of the last key listed, the default primary key.
+func ParseKeys(spec string) (map[string][]byte, string, error) {
+	keys := make(map[string][]byte)
+	var last string
+	for _, pair := range strings.Split(spec, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(pair, ":")
+		if !ok {
+			return nil, "", fmt.Errorf("key %q is not in id:base64 form", pair)
+		}
+		key, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, "", fmt.Errorf("key %q is not valid base64: %w", id, err)
+		}
+		keys[id] = key
+		last = id
+	}
+	if len(keys) == 0 {
+		return nil, "", errors.New("no encryption keys given")
+	}
+	return keys, last, nil
+}
+
+// Prefix is the start of every value encrypted with the primary key
+func (k *Keyring) Prefix() string {
+	return prefix + k.primary + ":"
+}
+
+// Encrypt seals plaintext with the primary key. The additional data binds the
+// ciphertext to its column so it cannot be copied into another one.
+func (k *Keyring) Encrypt(plaintext, additionalData []byte) (string, error) {
+	aead := k.aeads[k.primary]
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := aead.Seal(nonce, nonce, plaintext, additionalData)
+	return k.Prefix() + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt with any key of the keyring.
+// Values that are not encrypted are returned unchanged.
+func (k *Keyring) Decrypt(value string, additionalData []byte) ([]byte, error) {
+	if !strings.HasPrefix(value, prefix) {
+		return []byte(value), nil
+	}
+	id, encoded, ok := strings.Cut(strings.TrimPrefix(value, prefix), ":")
+	if !ok {
+		return nil, ErrMalformed
+	}
+	aead, ok := k.aeads[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(sealed) < aead.NonceSize() {
+		return nil, ErrMalformed
+	}
+	plaintext, err := aead.Open(nil, sealed[:aead.NonceSize()], sealed[aead.NonceSize():], additionalData)
+	if err != nil {
+		return nil, ErrMalformed
+	}
+	return plaintext, nil
+}
+
+// BlindIndex returns a keyed hash of value for equality lookups.
+// Without a keyring it falls back to an unkeyed SHA-256, see UnkeyedIndex.
+func (k *Keyring) BlindIndex(value string) string {
+	if k == nil {
+		return UnkeyedIndex(value)
+	}
+	mac := hmac.New(sha256.New, k.indexKey)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// BlindIndexes returns every index value a row holding value may have been stored
+// with: the keyed index, and the unkeyed one of rows written before encryption
+// was enabled and not re-encrypted yet.
+func (k *Keyring) BlindIndexes(value string) []string {
+	if k == nil {
+		return []string{UnkeyedIndex(value)}
+	}
+	return []string{k.BlindIndex(value), UnkeyedIndex(value)}
+}
+
+// UnkeyedIndex is the hex SHA-256 of value, used as the index while encryption is
+// disabled. Migrations can compute it in SQL to backfill existing rows.
+func UnkeyedIndex(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return hex.EncodeToString(sum[:])
+}
+
+var defaultKeyring atomic.Pointer[Keyring]
+
+// SetDefault sets the keyring used by the GORM serializer and model hooks.
+// A nil keyring disables encryption.
+func SetDefault(k *Keyring) {
+	defaultKeyring.Store(k)
+}
+
+// Default returns the keyring set with SetDefault, nil when encryption is disabled
+func Default() *Keyring {
+	return defaultKeyring.Load()
+}
@@ -0,0 +1,124 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testKey(b byte) []byte {
+	return bytes.Repeat([]byte{b}, 32)
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	keyring, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1", testKey(9))
+	require.NoError(t, err)
+
+	sealed, err := keyring.Encrypt([]byte("+15550123"), []byte("phone"))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(sealed, "enc:k1:"))
+	assert.NotContains(t, sealed, "15550123")
+
+	again, err := keyring.Encrypt([]byte("+15550123"), []byte("phone"))
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "nonces are random")
+
+	plaintext, err := keyring.Decrypt(sealed, []byte("phone"))
+	require.NoError(t, err)
+	assert.Equal(t, "+15550123", string(plaintext))
+
+	// Ciphertexts are bound to their column
+	_, err = keyring.Decrypt(sealed, []byte("email"))
+	assert.ErrorIs(t, err, ErrMalformed)
+
+	// Values written before encryption was enabled pass through
+	plaintext, err = keyring.Decrypt("plain@example.com", []byte("email"))
+	require.NoError(t, err)
+	assert.Equal(t, "plain@example.com", string(plaintext))
+
+	_, err = keyring.Decrypt("enc:k1:not-base64!", []byte("phone"))
+	assert.ErrorIs(t, err, ErrMalformed)
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	old, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1", testKey(9))
+	require.NoError(t, err)
+	sealed, err := old.Encrypt([]byte("secret"), nil)
+	require.NoError(t, err)
+
+	rotated, err := NewKeyring(map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, "k2", testKey(9))
+	require.NoError(t, err)
+	assert.Equal(t, "enc:k2:", rotated.Prefix())
+
+	plaintext, err := rotated.Decrypt(sealed, nil)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+
+	resealed, err := rotated.Encrypt(plaintext, nil)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(resealed, rotated.Prefix()))
+
+	// Once the old key is dropped its values can no longer be read
+	_, err = old.Decrypt(resealed, nil)
+	assert.ErrorIs(t, err, ErrUnknownKey)
+}
+
+func TestKeyring_BlindIndex(t *testing.T) {
+	keyring, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1", testKey(9))
+	require.NoError(t, err)
+	other, err := NewKeyring(map[string][]byte{"k1": testKey(1)}, "k1", testKey(8))
+	require.NoError(t, err)
+
+	index := keyring.BlindIndex("user@example.com")
+	assert.Len(t, index, 64)
+	assert.Equal(t, index, keyring.BlindIndex("user@example.com"))
+	assert.NotEqual(t, index, keyring.BlindIndex("other@example.com"))
+	assert.NotEqual(t, index, other.BlindIndex("user@example.com"))
+	assert.NotEqual(t, index, UnkeyedIndex("user@example.com"))
+	assert.Equal(t, []string{index, UnkeyedIndex("user@example.com")}, keyring.BlindIndexes("user@example.com"))
+
+	var disabled *Keyring
+	assert.Equal(t, UnkeyedIndex("user@example.com"), disabled.BlindIndex("user@example.com"))
+	assert.Equal(t, []string{UnkeyedIndex("user@example.com")}, disabled.BlindIndexes("user@example.com"))
+}
+
+func TestNewKeyring_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     map[string][]byte
+		primary  string
+		indexKey []byte
+	}{
+		{"missing primary", map[string][]byte{"k1": testKey(1)}, "k2", testKey(9)},
+		{"short key", map[string][]byte{"k1": testKey(1)[:16]}, "k1", testKey(9)},
+		{"short index key", map[string][]byte{"k1": testKey(1)}, "k1", testKey(9)[:16]},
+		{"key ID with separator", map[string][]byte{"k:1": testKey(1)}, "k:1", testKey(9)},
+		{"key ID with wildcard", map[string][]byte{"k_1": testKey(1)}, "k_1", testKey(9)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewKeyring(tt.keys, tt.primary, tt.indexKey)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestParseKeys(t *testing.T) {
+	k1 := base64.StdEncoding.EncodeToString(testKey(1))
+	k2 := base64.StdEncoding.EncodeToString(testKey(2))
+
+	keys, primary, err := ParseKeys("k1:" + k1 + ", k2:" + k2)
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]byte{"k1": testKey(1), "k2": testKey(2)}, keys)
+	assert.Equal(t, "k2", primary)
+
+	for _, spec := range []string{"", "k1", "k1:not base64"} {
+		_, _, err := ParseKeys(spec)
+		assert.Error(t, err, spec)
+	}
+}
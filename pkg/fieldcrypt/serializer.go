@@ -0,0 +1,89 @@
+package fieldcrypt
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+func init() {
+	schema.RegisterSerializer("encrypted", Serializer{})
+}
+
+// Serializer is a GORM serializer, registered as "encrypted", that stores a field
+// encrypted with the default keyring. String fields are encrypted as they are,
+// other types as JSON. Empty strings and nil values are stored unencrypted.
+// While no default keyring is set values are stored in the clear.
+type Serializer struct{}
+
+// Scan implements schema.SerializerInterface
+func (Serializer) Scan(ctx context.Context, field *schema.Field, dst reflect.Value, dbValue interface{}) error {
+	fieldValue := reflect.New(field.FieldType)
+
+	var stored string
+	switch v := dbValue.(type) {
+	case nil:
+	case string:
+		stored = v
+	case []byte:
+		stored = string(v)
+	default:
+		return fmt.Errorf("unsupported type %T for encrypted field %s", dbValue, field.Name)
+	}
+
+	if stored != "" {
+		plaintext, err := Default().decrypt(stored, field.DBName)
+		if err != nil {
+			return fmt.Errorf("failed to decrypt %s: %w", field.DBName, err)
+		}
+		if field.FieldType.Kind() == reflect.String {
+			fieldValue.Elem().SetString(string(plaintext))
+		} else if err := json.Unmarshal(plaintext, fieldValue.Interface()); err != nil {
+			return err
+		}
+	}
+
+	field.ReflectValueOf(ctx, dst).Set(fieldValue.Elem())
+	return nil
+}
+
+// Value implements schema.SerializerValuerInterface
+func (Serializer) Value(ctx context.Context, field *schema.Field, dst reflect.Value, fieldValue interface{}) (interface{}, error) {
+	var plaintext []byte
+	if s, ok := fieldValue.(string); ok {
+		if s == "" {
+			return "", nil
+		}
+		plaintext = []byte(s)
+	} else {
+		encoded, err := json.Marshal(fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		if string(encoded) == "null" {
+			return nil, nil
+		}
+		plaintext = encoded
+	}
+
+	keyring := Default()
+	if keyring == nil {
+		return string(plaintext), nil
+	}
+	return keyring.Encrypt(plaintext, []byte(field.DBName))
+}
+
+// decrypt opens a stored value, passing it through when encryption is disabled
+func (k *Keyring) decrypt(value, column string) ([]byte, error) {
+	if k == nil {
+		if strings.HasPrefix(value, prefix) {
+			return nil, ErrUnknownKey
+		}
+		return []byte(value), nil
+	}
+	return k.Decrypt(value, []byte(column))
+}
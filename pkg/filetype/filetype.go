@@ -0,0 +1,88 @@
+package filetype
+
+import (
+	"bytes"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// SniffLen is the number of leading bytes Detect looks at
+const SniffLen = 512
+
+// SVG is the content type of SVG images
+const SVG = "image/svg+xml"
+
+// extensionTypes maps filename extensions to the content types their content may be detected as
+var extensionTypes = map[string][]string{
+	".jpg":  {"image/jpeg"},
+	".jpeg": {"image/jpeg"},
+	".png":  {"image/png"},
+	".gif":  {"image/gif"},
+	".webp": {"image/webp"},
+	".svg":  {SVG},
+	".pdf":  {"application/pdf"},
+	".csv":  {"text/csv", "text/plain"},
+	".txt":  {"text/plain"},
+	".json": {"application/json", "text/plain"},
+}
+
+// Detect returns the content type of data based on its leading bytes, ignoring any declared type.
+// Parameters such as charset are stripped.
+func Detect(head []byte) string {
+	if len(head) > SniffLen {
+		head = head[:SniffLen]
+	}
+
+	detected := Normalize(http.DetectContentType(head))
+	// SVG is XML or plain text to the standard sniffer
+	if detected == "text/xml" || detected == "text/plain" {
+		if bytes.Contains(bytes.ToLower(head), []byte("<svg")) {
+			return SVG
+		}
+	}
+	return detected
+}
+
+// Normalize lowercases a content type and strips its parameters
+func Normalize(contentType string) string {
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		return mediaType
+	}
+	return strings.ToLower(strings.TrimSpace(contentType))
+}
+
+// Extension returns the lowercased extension of a filename including the dot
+func Extension(filename string) string {
+	return strings.ToLower(path.Ext(filename))
+}
+
+// MatchesExtension reports whether detected content is plausible for a filename's extension.
+// Extensions without a known mapping match any content.
+func MatchesExtension(filename, detected string) bool {
+	types, ok := extensionTypes[Extension(filename)]
+	if !ok {
+		return true
+	}
+	for _, contentType := range types {
+		if contentType == detected {
+			return true
+		}
+	}
+	return false
+}
+
+// CanonicalType returns the preferred content type for a filename's extension when it matches the
+// detected content, so a CSV sniffed as text/plain is stored as text/csv
+func CanonicalType(filename, detected string) string {
+	if types, ok := extensionTypes[Extension(filename)]; ok && MatchesExtension(filename, detected) {
+		return types[0]
+	}
+	return detected
+}
+
+// IsRasterImage reports whether a content type is a bitmap image format
+func IsRasterImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") && contentType != SVG
+}
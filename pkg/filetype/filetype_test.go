@@ -0,0 +1,72 @@
+package filetype
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		head []byte
+		want string
+	}{
+		{"png", []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"), "image/png"},
+		{"jpeg", []byte("\xff\xd8\xff\xe0\x00\x10JFIF"), "image/jpeg"},
+		{"svg", []byte(`<svg xmlns="http://www.w3.org/2000/svg"></svg>`), SVG},
+		{"svg with xml declaration", []byte(`<?xml version="1.0"?><svg></svg>`), SVG},
+		{"html", []byte("<html><body></body></html>"), "text/html"},
+		{"csv", []byte("id,name\n1,alice\n"), "text/plain"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Detect(tt.head))
+		})
+	}
+}
+
+func TestMatchesExtension(t *testing.T) {
+	assert.True(t, MatchesExtension("photo.JPG", "image/jpeg"))
+	assert.False(t, MatchesExtension("photo.png", "text/html"))
+	assert.True(t, MatchesExtension("data.csv", "text/plain"))
+	assert.Equal(t, "text/csv", CanonicalType("data.csv", "text/plain"))
+	assert.True(t, MatchesExtension("archive.unknown", "application/zip"))
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	input := `<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY xxe SYSTEM "file:///etc/passwd">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" onload="alert(1)">
+  <!-- comment -->
+  <script>alert(2)</script>
+  <foreignObject><div>html</div></foreignObject>
+  <a xlink:href="javascript:alert(3)"><circle r="4" fill="red" onclick="alert(4)"/></a>
+  <use href="#shape"/>
+  <image href="https://evil.example/track.png"/>
+  <rect style="fill: url(https://evil.example/x)" width="1"/>
+  <set attributeName="href" to="javascript:alert(5)"/>
+  <style>@import url(https://evil.example/x.css);</style>
+  <text>a &amp; b</text>
+</svg>`
+
+	out, err := SanitizeSVG([]byte(input))
+	require.NoError(t, err)
+	clean := string(out)
+
+	for _, unwanted := range []string{"alert", "script", "foreignObject", "ENTITY", "comment", "evil.example", "onload", "<set"} {
+		assert.NotContains(t, clean, unwanted)
+	}
+	assert.Contains(t, clean, `xmlns:xlink="http://www.w3.org/1999/xlink"`)
+	assert.Contains(t, clean, `<circle r="4" fill="red">`)
+	assert.Contains(t, clean, `<use href="#shape">`)
+	assert.Contains(t, clean, "a &amp; b")
+}
+
+func TestSanitizeSVG_Invalid(t *testing.T) {
+	for _, input := range []string{"not xml", "<html></html>", "<svg><g></svg>"} {
+		_, err := SanitizeSVG([]byte(input))
+		assert.ErrorIs(t, err, ErrInvalidSVG, input)
+	}
+}
@@ -0,0 +1,174 @@
+package filetype
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// ErrInvalidSVG is returned for content that isn't a well-formed SVG document
+var ErrInvalidSVG = errors.New("invalid SVG document")
+
+// svgForbiddenElements can run script or embed external content and are removed with their children
+var svgForbiddenElements = map[string]bool{
+	"script":        true,
+	"foreignobject": true,
+	"iframe":        true,
+	"embed":         true,
+	"object":        true,
+	"handler":       true,
+	"listener":      true,
+	"audio":         true,
+	"video":         true,
+}
+
+// svgURLAttributes hold references that could point at scripts or external resources
+var svgURLAttributes = map[string]bool{
+	"href":   true,
+	"src":    true,
+	"from":   true,
+	"to":     true,
+	"values": true,
+}
+
+var (
+	cssURLPattern     = regexp.MustCompile(`(?i)url\(\s*['"]?\s*([^'")\s]*)`)
+	safeDataURIPrefix = regexp.MustCompile(`(?i)^data:image/(png|jpeg|gif|webp);`)
+)
+
+// SanitizeSVG returns a copy of an SVG document without scripts, event handlers, external references,
+// comments, processing instructions and DTDs. Everything else is kept as written.
+func SanitizeSVG(data []byte) ([]byte, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	decoder.Strict = true
+
+	var out bytes.Buffer
+	var open []string // RawToken doesn't check nesting, so track it here
+	skipDepth := 0
+	sawRoot := false
+	for {
+		token, err := decoder.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, ErrInvalidSVG
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			local := strings.ToLower(t.Name.Local)
+			if !sawRoot {
+				if local != "svg" {
+					return nil, ErrInvalidSVG
+				}
+				sawRoot = true
+			} else if len(open) == 0 {
+				return nil, ErrInvalidSVG // A second root element
+			}
+			open = append(open, qualifiedName(t.Name))
+			if skipDepth > 0 || svgForbiddenElements[local] || unsafeAnimation(t) {
+				skipDepth++
+				continue
+			}
+			writeStartElement(&out, t)
+		case xml.EndElement:
+			if len(open) == 0 || open[len(open)-1] != qualifiedName(t.Name) {
+				return nil, ErrInvalidSVG
+			}
+			open = open[:len(open)-1]
+			if skipDepth > 0 {
+				skipDepth--
+				continue
+			}
+			out.WriteString("</" + qualifiedName(t.Name) + ">")
+		case xml.CharData:
+			if skipDepth > 0 {
+				continue
+			}
+			if len(open) > 0 && strings.EqualFold(open[len(open)-1], "style") && !safeCSS(string(t)) {
+				continue
+			}
+			xml.EscapeText(&out, t)
+		}
+		// Comments, processing instructions and directives (DOCTYPE, ENTITY) are dropped
+	}
+
+	if !sawRoot || len(open) > 0 {
+		return nil, ErrInvalidSVG
+	}
+	return out.Bytes(), nil
+}
+
+// writeStartElement writes an element keeping only safe attributes
+func writeStartElement(out *bytes.Buffer, element xml.StartElement) {
+	out.WriteString("<" + qualifiedName(element.Name))
+	for _, attr := range element.Attr {
+		if !safeAttribute(element, attr) {
+			continue
+		}
+		out.WriteString(" " + qualifiedName(attr.Name) + `="`)
+		xml.EscapeText(out, []byte(attr.Value))
+		out.WriteString(`"`)
+	}
+	out.WriteString(">")
+}
+
+// safeAttribute reports whether an attribute can be kept
+func safeAttribute(element xml.StartElement, attr xml.Attr) bool {
+	name := strings.ToLower(attr.Name.Local)
+	if strings.HasPrefix(name, "on") {
+		return false
+	}
+	if svgURLAttributes[name] && !safeReference(attr.Value) {
+		return false
+	}
+	if name == "style" && !safeCSS(attr.Value) {
+		return false
+	}
+	return true
+}
+
+// safeReference allows in-document fragments and embedded raster images only
+func safeReference(value string) bool {
+	value = strings.TrimSpace(value)
+	return value == "" || strings.HasPrefix(value, "#") || safeDataURIPrefix.MatchString(value)
+}
+
+// safeCSS rejects styles that import or reference external content or run script
+func safeCSS(value string) bool {
+	lower := strings.ToLower(value)
+	if strings.Contains(lower, "@import") || strings.Contains(lower, "javascript:") || strings.Contains(lower, "expression(") {
+		return false
+	}
+	for _, match := range cssURLPattern.FindAllStringSubmatch(value, -1) {
+		if !safeReference(match[1]) {
+			return false
+		}
+	}
+	return true
+}
+
+// unsafeAnimation reports animation elements that could rewrite a link target
+func unsafeAnimation(element xml.StartElement) bool {
+	switch strings.ToLower(element.Name.Local) {
+	case "set", "animate":
+		for _, attr := range element.Attr {
+			if strings.ToLower(attr.Name.Local) == "attributename" && strings.Contains(strings.ToLower(attr.Value), "href") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// qualifiedName returns prefix:local as written in the source document
+func qualifiedName(name xml.Name) string {
+	if name.Space != "" {
+		return name.Space + ":" + name.Local
+	}
+	return name.Local
+}
@@ -0,0 +1,61 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 128, A: 255})
+		}
+	}
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, img))
+	return buf.Bytes()
+}
+
+func TestDecode(t *testing.T) {
+	t.Run("decodes png", func(t *testing.T) {
+		img, format, err := Decode(bytes.NewReader(encodePNG(t, 40, 20)), 0)
+		require.NoError(t, err)
+		assert.Equal(t, "png", format)
+		assert.Equal(t, 40, img.Bounds().Dx())
+	})
+
+	t.Run("rejects oversized images before decoding", func(t *testing.T) {
+		_, _, err := Decode(bytes.NewReader(encodePNG(t, 40, 20)), 500)
+		assert.ErrorIs(t, err, ErrTooLarge)
+	})
+
+	t.Run("rejects non-images", func(t *testing.T) {
+		_, _, err := Decode(bytes.NewReader([]byte("not an image")), 0)
+		assert.ErrorIs(t, err, ErrUnsupportedFormat)
+	})
+}
+
+func TestThumbnail(t *testing.T) {
+	img, _, err := Decode(bytes.NewReader(encodePNG(t, 200, 100)), 0)
+	require.NoError(t, err)
+
+	thumb := Thumbnail(img, 64)
+	assert.Equal(t, image.Rect(0, 0, 64, 64), thumb.Bounds())
+
+	var buf bytes.Buffer
+	require.NoError(t, EncodeJPEG(&buf, thumb, 85))
+
+	decoded, err := jpeg.DecodeConfig(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 64, decoded.Width)
+	assert.Equal(t, 64, decoded.Height)
+}
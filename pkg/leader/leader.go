@@ -0,0 +1,179 @@
+// Package leader elects a single instance of a deployment to run singleton
+// background work such as the cron scheduler.
+//
+// Leadership is a renewable lease on a distributed lock. The leader renews
+// the lease several times per TTL; if renewal fails it steps down at once, and
+// once the lease expires another instance takes over.
+package leader
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gbt-be-template/pkg/lock"
+	"gbt-be-template/pkg/logger"
+)
+
+// Config holds election timings
+type Config struct {
+	Name          string        // Lock name shared by all candidates
+	LeaseTTL      time.Duration // How long leadership survives without renewal
+	RetryInterval time.Duration // How often followers try to take over
+}
+
+// Status describes this instance's view of the election
+type Status struct {
+	Leader bool       `json:"leader"`
+	Since  *time.Time `json:"since,omitempty"`
+}
+
+// Elector campaigns for leadership and runs registered work while elected
+type Elector struct {
+	locker lock.Locker
+	cfg    Config
+	log    *logger.Logger
+	work   []func(ctx context.Context)
+
+	mu    sync.RWMutex
+	since time.Time // Zero while not leading
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New creates an elector
+func New(locker lock.Locker, cfg Config, log *logger.Logger) *Elector {
+	return &Elector{locker: locker, cfg: cfg, log: log}
+}
+
+// Go registers fn to run whenever this instance becomes leader. The context
+// passed to fn is canceled on losing leadership and fn must return promptly
+// after that. Work must be registered before Start.
+func (e *Elector) Go(fn func(ctx context.Context)) {
+	e.work = append(e.work, fn)
+}
+
+// Start campaigns for leadership in the background until Stop is called
+func (e *Elector) Start(ctx context.Context) {
+	ctx, e.cancel = context.WithCancel(ctx)
+	e.done = make(chan struct{})
+	go e.campaign(ctx)
+}
+
+// Stop steps down, waits for leader work to return and releases the lease
+func (e *Elector) Stop() {
+	if e.cancel == nil {
+		return
+	}
+	e.cancel()
+	<-e.done
+}
+
+// IsLeader reports whether this instance currently holds leadership
+func (e *Elector) IsLeader() bool {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return !e.since.IsZero()
+}
+
+// Status returns the current election status
+func (e *Elector) Status() Status {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	if e.since.IsZero() {
+		return Status{}
+	}
+	since := e.since
+	return Status{Leader: true, Since: &since}
+}
+
+// campaign alternates between trying to acquire the lease and leading
+func (e *Elector) campaign(ctx context.Context) {
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.cfg.RetryInterval)
+	defer ticker.Stop()
+
+	for {
+		held, err := e.locker.TryLock(ctx, e.cfg.Name, e.cfg.LeaseTTL)
+		switch {
+		case err == nil:
+			e.lead(ctx, held)
+		case !errors.Is(err, lock.ErrNotAcquired) && ctx.Err() == nil:
+			e.log.WithError(err).Warn("Leader election attempt failed")
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// lead runs registered work while renewing the lease, and returns once leadership is lost
+func (e *Elector) lead(ctx context.Context, held lock.Lock) {
+	e.setLeading(true)
+	e.log.WithField("election", e.cfg.Name).Info("Elected leader")
+
+	leaderCtx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	for _, fn := range e.work {
+		wg.Add(1)
+		go func(fn func(ctx context.Context)) {
+			defer wg.Done()
+			fn(leaderCtx)
+		}(fn)
+	}
+
+	e.renew(leaderCtx, held)
+
+	// Stop leader work before giving the lease to anyone else
+	cancel()
+	wg.Wait()
+	e.setLeading(false)
+
+	releaseCtx, cancelRelease := context.WithTimeout(context.WithoutCancel(ctx), e.cfg.LeaseTTL)
+	defer cancelRelease()
+	if err := held.Release(releaseCtx); err != nil {
+		e.log.WithError(err).Warn("Failed to release leader lease")
+	}
+	e.log.WithField("election", e.cfg.Name).Info("Stepped down as leader")
+}
+
+// renew extends the lease a few times per TTL until it fails or ctx is canceled
+func (e *Elector) renew(ctx context.Context, held lock.Lock) {
+	interval := e.cfg.LeaseTTL / 3
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			extendCtx, cancel := context.WithTimeout(ctx, interval)
+			err := held.Extend(extendCtx, e.cfg.LeaseTTL)
+			cancel()
+			if err != nil {
+				if ctx.Err() == nil {
+					e.log.WithError(err).Warn("Failed to renew leader lease")
+				}
+				return
+			}
+		}
+	}
+}
+
+// setLeading records a leadership transition
+func (e *Elector) setLeading(leading bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if leading {
+		e.since = time.Now().UTC()
+	} else {
+		e.since = time.Time{}
+	}
+}
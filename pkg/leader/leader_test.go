@@ -0,0 +1,99 @@
+package leader
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gbt-be-template/pkg/lock"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// candidate is an elector together with the number of instances of its work currently running
+type candidate struct {
+	elector *Elector
+	running atomic.Int32
+}
+
+func newCandidate(locker lock.Locker) *candidate {
+	c := &candidate{}
+	c.elector = New(locker, Config{
+		Name:          "leader",
+		LeaseTTL:      60 * time.Millisecond,
+		RetryInterval: 5 * time.Millisecond,
+	}, logger.New("error", "text"))
+	c.elector.Go(func(ctx context.Context) {
+		c.running.Add(1)
+		<-ctx.Done()
+		c.running.Add(-1)
+	})
+	return c
+}
+
+func TestElector_Failover(t *testing.T) {
+	locker := lock.NewLocalLocker()
+	first, second := newCandidate(locker), newCandidate(locker)
+
+	first.elector.Start(context.Background())
+	require.Eventually(t, first.elector.IsLeader, time.Second, time.Millisecond)
+	second.elector.Start(context.Background())
+	defer second.elector.Stop()
+
+	// The lease is renewed, so leadership stays put well past one TTL
+	time.Sleep(150 * time.Millisecond)
+	assert.True(t, first.elector.IsLeader())
+	assert.False(t, second.elector.IsLeader())
+	assert.Equal(t, int32(1), first.running.Load())
+	assert.Equal(t, int32(0), second.running.Load())
+	assert.True(t, first.elector.Status().Leader)
+	assert.NotNil(t, first.elector.Status().Since)
+	assert.Equal(t, Status{}, second.elector.Status())
+
+	// Stepping down stops the leader's work and hands over leadership
+	first.elector.Stop()
+	assert.False(t, first.elector.IsLeader())
+	assert.Equal(t, int32(0), first.running.Load())
+	require.Eventually(t, second.elector.IsLeader, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return second.running.Load() == 1 }, time.Second, time.Millisecond)
+}
+
+// lostLocker grants locks that can never be extended
+type lostLocker struct {
+	lock.Locker
+}
+
+func (l lostLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (lock.Lock, error) {
+	held, err := l.Locker.TryLock(ctx, name, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return lostLock{held}, nil
+}
+
+type lostLock struct {
+	lock.Lock
+}
+
+func (lostLock) Extend(context.Context, time.Duration) error {
+	return lock.ErrLockLost
+}
+
+func TestElector_StepsDownWhenRenewalFails(t *testing.T) {
+	c := newCandidate(lostLocker{lock.NewLocalLocker()})
+
+	var elections atomic.Int32
+	c.elector.Go(func(ctx context.Context) {
+		elections.Add(1)
+		<-ctx.Done()
+	})
+
+	c.elector.Start(context.Background())
+	defer c.elector.Stop()
+
+	// Every lost lease cancels the leader work, after which the candidate campaigns again
+	assert.Eventually(t, func() bool { return elections.Load() >= 2 }, time.Second, time.Millisecond)
+}
@@ -0,0 +1,76 @@
+package lock
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// LocalLocker is an in-process Locker for single instance deployments and tests
+type LocalLocker struct {
+	mu    sync.Mutex
+	held  map[string]localEntry
+	clock func() time.Time
+}
+
+// localEntry records the current holder of a lock
+type localEntry struct {
+	token   string
+	expires time.Time
+}
+
+// NewLocalLocker creates an in-process locker
+func NewLocalLocker() *LocalLocker {
+	return &LocalLocker{held: make(map[string]localEntry), clock: time.Now}
+}
+
+// TryLock acquires the named lock unless it is held and unexpired
+func (l *LocalLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.clock()
+	if entry, ok := l.held[name]; ok && now.Before(entry.expires) {
+		return nil, ErrNotAcquired
+	}
+	l.held[name] = localEntry{token: token, expires: now.Add(ttl)}
+	return &localLock{locker: l, name: name, token: token}, nil
+}
+
+// localLock is a lock held in a LocalLocker
+type localLock struct {
+	locker *LocalLocker
+	name   string
+	token  string
+}
+
+// Release removes the lock if it is still owned by this holder
+func (l *localLock) Release(ctx context.Context) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	if entry, ok := l.locker.held[l.name]; ok && entry.token == l.token {
+		delete(l.locker.held, l.name)
+	}
+	return nil
+}
+
+// Extend pushes the expiry of an unexpired lock forward
+func (l *localLock) Extend(ctx context.Context, ttl time.Duration) error {
+	l.locker.mu.Lock()
+	defer l.locker.mu.Unlock()
+
+	now := l.locker.clock()
+	entry, ok := l.locker.held[l.name]
+	if !ok || entry.token != l.token || !now.Before(entry.expires) {
+		return ErrLockLost
+	}
+	entry.expires = now.Add(ttl)
+	l.locker.held[l.name] = entry
+	return nil
+}
@@ -0,0 +1,46 @@
+// Package lock provides distributed mutual exclusion so that work such as
+// scheduled maintenance runs on exactly one instance of a horizontally scaled
+// deployment.
+//
+// Locks are leases: they expire on their own after the TTL passed to TryLock
+// unless extended, so a crashed holder never blocks other instances for longer
+// than one TTL.
+package lock
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"time"
+)
+
+// ErrNotAcquired is returned when the lock is held by someone else
+var ErrNotAcquired = errors.New("lock not acquired")
+
+// ErrLockLost is returned when extending a lock that has already expired or was taken over
+var ErrLockLost = errors.New("lock lost")
+
+// Locker acquires named locks
+type Locker interface {
+	// TryLock acquires the named lock for ttl without waiting. It returns
+	// ErrNotAcquired when another holder owns the lock.
+	TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, error)
+}
+
+// Lock is a held lock
+type Lock interface {
+	// Release gives up the lock before its TTL passes
+	Release(ctx context.Context) error
+	// Extend resets the remaining lifetime of the lock to ttl
+	Extend(ctx context.Context, ttl time.Duration) error
+}
+
+// newToken returns a random value identifying a single lock holder
+func newToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
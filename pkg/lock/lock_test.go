@@ -0,0 +1,131 @@
+package lock
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalLocker(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	locker := NewLocalLocker()
+	locker.clock = func() time.Time { return now }
+
+	held, err := locker.TryLock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+
+	_, err = locker.TryLock(ctx, "job", time.Minute)
+	assert.ErrorIs(t, err, ErrNotAcquired)
+
+	// Other names are independent
+	_, err = locker.TryLock(ctx, "other", time.Minute)
+	assert.NoError(t, err)
+
+	// Extending keeps the lock past its original TTL
+	require.NoError(t, held.Extend(ctx, 2*time.Minute))
+	now = now.Add(90 * time.Second)
+	_, err = locker.TryLock(ctx, "job", time.Minute)
+	assert.ErrorIs(t, err, ErrNotAcquired)
+
+	// An expired lock can be taken over and the old holder can neither extend nor release it
+	now = now.Add(time.Minute)
+	taken, err := locker.TryLock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+	assert.ErrorIs(t, held.Extend(ctx, time.Minute), ErrLockLost)
+	require.NoError(t, held.Release(ctx))
+	_, err = locker.TryLock(ctx, "job", time.Minute)
+	assert.ErrorIs(t, err, ErrNotAcquired)
+
+	require.NoError(t, taken.Release(ctx))
+	_, err = locker.TryLock(ctx, "job", time.Minute)
+	assert.NoError(t, err)
+}
+
+func newRedisNodes(t *testing.T, n int) ([]*miniredis.Miniredis, []redis.Cmdable) {
+	t.Helper()
+	var servers []*miniredis.Miniredis
+	var clients []redis.Cmdable
+	for i := 0; i < n; i++ {
+		server := miniredis.RunT(t)
+		client := redis.NewClient(&redis.Options{Addr: server.Addr(), MaxRetries: -1})
+		t.Cleanup(func() { client.Close() })
+		servers = append(servers, server)
+		clients = append(clients, client)
+	}
+	return servers, clients
+}
+
+func TestRedisLocker(t *testing.T) {
+	ctx := context.Background()
+	servers, clients := newRedisNodes(t, 1)
+	locker := NewRedisLocker(clients...)
+
+	held, err := locker.TryLock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+	assert.True(t, servers[0].Exists("lock:job"))
+
+	_, err = locker.TryLock(ctx, "job", time.Minute)
+	assert.ErrorIs(t, err, ErrNotAcquired)
+
+	require.NoError(t, held.Extend(ctx, 5*time.Minute))
+	assert.Equal(t, 5*time.Minute, servers[0].TTL("lock:job"))
+
+	// The lock expires on its own
+	servers[0].FastForward(6 * time.Minute)
+	taken, err := locker.TryLock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+
+	// A stale holder cannot touch the new holder's lock
+	assert.ErrorIs(t, held.Extend(ctx, time.Minute), ErrLockLost)
+	require.NoError(t, held.Release(ctx))
+	assert.True(t, servers[0].Exists("lock:job"))
+
+	require.NoError(t, taken.Release(ctx))
+	assert.False(t, servers[0].Exists("lock:job"))
+}
+
+func TestRedisLocker_Quorum(t *testing.T) {
+	ctx := context.Background()
+	servers, clients := newRedisNodes(t, 3)
+	locker := NewRedisLocker(clients...)
+
+	// A holder on a single node does not block a majority
+	require.NoError(t, servers[0].Set("lock:job", "someone-else"))
+	held, err := locker.TryLock(ctx, "job", time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, held.Extend(ctx, time.Minute))
+
+	// Holders on a majority do, and partial acquisitions are rolled back
+	require.NoError(t, servers[0].Set("lock:other", "someone-else"))
+	require.NoError(t, servers[1].Set("lock:other", "someone-else"))
+	_, err = locker.TryLock(ctx, "other", time.Minute)
+	assert.ErrorIs(t, err, ErrNotAcquired)
+	assert.False(t, servers[2].Exists("lock:other"))
+
+	// Losing a minority of nodes still grants the lock
+	servers[2].Close()
+	_, err = locker.TryLock(ctx, "third", time.Minute)
+	assert.NoError(t, err)
+
+	// Losing the majority makes locking fail
+	servers[1].Close()
+	_, err = locker.TryLock(ctx, "fourth", time.Minute)
+	assert.ErrorIs(t, err, ErrNotAcquired)
+
+	// With every node down the connection error is reported instead of contention
+	servers[0].Close()
+	_, err = locker.TryLock(ctx, "fifth", time.Minute)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotAcquired)
+}
+
+func TestAdvisoryKey(t *testing.T) {
+	assert.Equal(t, advisoryKey("scheduler:cleanup"), advisoryKey("scheduler:cleanup"))
+	assert.NotEqual(t, advisoryKey("scheduler:cleanup"), advisoryKey("scheduler:reports"))
+}
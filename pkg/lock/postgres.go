@@ -0,0 +1,101 @@
+package lock
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"hash/fnv"
+	"sync"
+	"time"
+)
+
+// PostgresLocker implements Locker with Postgres session level advisory locks.
+//
+// Each held lock pins one pooled connection, because advisory locks belong to
+// the session that took them. Postgres has no notion of a lock TTL, so the
+// lease is enforced locally by releasing the lock when the TTL passes; if the
+// process dies the connection drops and Postgres releases the lock at once.
+type PostgresLocker struct {
+	db *sql.DB
+}
+
+// NewPostgresLocker creates a locker backed by the given database
+func NewPostgresLocker(db *sql.DB) *PostgresLocker {
+	return &PostgresLocker{db: db}
+}
+
+// TryLock acquires the advisory lock derived from name without waiting
+func (l *PostgresLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, error) {
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	key := advisoryKey(name)
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", key).Scan(&acquired); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !acquired {
+		conn.Close()
+		return nil, ErrNotAcquired
+	}
+
+	lock := &postgresLock{conn: conn, key: key}
+	lock.timer = time.AfterFunc(ttl, func() { _ = lock.Release(context.Background()) })
+	return lock, nil
+}
+
+// advisoryKey maps a lock name onto the bigint key space of advisory locks
+func advisoryKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte("gbt-lock:" + name))
+	return int64(h.Sum64())
+}
+
+// postgresLock is an advisory lock held on a dedicated connection
+type postgresLock struct {
+	mu       sync.Mutex
+	conn     *sql.Conn
+	key      int64
+	timer    *time.Timer
+	released bool
+}
+
+// Release unlocks the advisory lock and returns the connection to the pool
+func (l *postgresLock) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.released {
+		return nil
+	}
+	l.released = true
+	l.timer.Stop()
+
+	_, err := l.conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", l.key)
+	if err != nil {
+		// The session may still hold the lock, so it must not go back into the pool
+		_ = l.conn.Raw(func(any) error { return driver.ErrBadConn })
+	}
+	l.conn.Close()
+	return err
+}
+
+// Extend restarts the local lease timer of a lock that has not been released
+func (l *postgresLock) Extend(ctx context.Context, ttl time.Duration) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.released || !l.timer.Stop() {
+		return ErrLockLost
+	}
+	if err := l.conn.PingContext(ctx); err != nil {
+		// The session is gone along with its lock; let the timer clean up at once
+		l.timer.Reset(0)
+		return ErrLockLost
+	}
+	l.timer.Reset(ttl)
+	return nil
+}
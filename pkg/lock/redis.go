@@ -0,0 +1,118 @@
+package lock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// clockDriftFactor is the share of the TTL reserved for clock drift between Redis nodes
+const clockDriftFactor = 0.01
+
+var (
+	// releaseScript deletes the key only if it still holds our token
+	releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+
+	// extendScript resets the expiry only if the key still holds our token
+	extendScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("PEXPIRE", KEYS[1], ARGV[2])
+end
+return 0`)
+)
+
+// RedisLocker implements Locker with the Redlock algorithm.
+//
+// With a single client it is a plain SET NX lock. With several independent
+// Redis masters a lock is only granted when a majority of them accepted it
+// within the TTL, so losing a minority of nodes neither blocks nor splits it.
+type RedisLocker struct {
+	clients []redis.Cmdable
+	prefix  string
+}
+
+// NewRedisLocker creates a locker over one or more independent Redis nodes
+func NewRedisLocker(clients ...redis.Cmdable) *RedisLocker {
+	return &RedisLocker{clients: clients, prefix: "lock:"}
+}
+
+// TryLock acquires the named lock on a majority of nodes without waiting
+func (l *RedisLocker) TryLock(ctx context.Context, name string, ttl time.Duration) (Lock, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	lock := &redisLock{locker: l, key: l.prefix + name, token: token}
+	start := time.Now()
+
+	acquired := 0
+	var errs []error
+	for _, client := range l.clients {
+		ok, err := client.SetNX(ctx, lock.key, token, ttl).Result()
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if ok {
+			acquired++
+		}
+	}
+
+	drift := time.Duration(float64(ttl)*clockDriftFactor) + 2*time.Millisecond
+	validity := ttl - time.Since(start) - drift
+	if acquired >= l.quorum() && validity > 0 {
+		return lock, nil
+	}
+
+	// Undo partial acquisitions so the lock frees up before its TTL
+	_ = lock.Release(context.WithoutCancel(ctx))
+	if len(errs) == len(l.clients) {
+		return nil, errors.Join(errs...)
+	}
+	return nil, ErrNotAcquired
+}
+
+// quorum returns the number of nodes that must agree on a lock
+func (l *RedisLocker) quorum() int {
+	return len(l.clients)/2 + 1
+}
+
+// redisLock is a lock held on a majority of Redis nodes
+type redisLock struct {
+	locker *RedisLocker
+	key    string
+	token  string
+}
+
+// Release removes the lock from every node that still holds our token
+func (l *redisLock) Release(ctx context.Context) error {
+	var errs []error
+	for _, client := range l.locker.clients {
+		if err := releaseScript.Run(ctx, client, []string{l.key}, l.token).Err(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Extend resets the TTL on every node and requires a majority to still hold the lock
+func (l *redisLock) Extend(ctx context.Context, ttl time.Duration) error {
+	extended := 0
+	for _, client := range l.locker.clients {
+		n, err := extendScript.Run(ctx, client, []string{l.key}, l.token, ttl.Milliseconds()).Int()
+		if err == nil && n == 1 {
+			extended++
+		}
+	}
+	if extended < l.locker.quorum() {
+		return ErrLockLost
+	}
+	return nil
+}
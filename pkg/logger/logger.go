@@ -93,3 +93,12 @@ func (l *Logger) Auth(userID uint, action, ip string) *logrus.Entry {
 		"type":    "auth",
 	})
 }
+
+// Security creates a logger entry for security events such as detected token theft
+func (l *Logger) Security(event string, userID uint) *logrus.Entry {
+	return l.WithFields(map[string]interface{}{
+		"event":   event,
+		"user_id": userID,
+		"type":    "security",
+	})
+}
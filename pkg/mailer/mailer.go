@@ -0,0 +1,41 @@
+// Package mailer delivers transactional emails such as account confirmations.
+package mailer
+
+import (
+	"context"
+
+	"gbt-be-template/pkg/logger"
+)
+
+// Message is a plain text email
+type Message struct {
+	To      string `json:"to"`
+	Subject string `json:"subject"`
+	Body    string `json:"body"`
+}
+
+// Mailer delivers emails
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Log is a mailer that writes messages to the log instead of sending them.
+// It is meant for development, where confirmation links can be copied from the log.
+type Log struct {
+	log *logger.Logger
+}
+
+// NewLog creates a mailer that logs every message
+func NewLog(log *logger.Logger) *Log {
+	return &Log{log: log}
+}
+
+// Send logs the message
+func (l *Log) Send(ctx context.Context, msg *Message) error {
+	l.log.WithFields(map[string]interface{}{
+		"to":      msg.To,
+		"subject": msg.Subject,
+		"body":    msg.Body,
+	}).Info("Email not sent, logging it instead")
+	return nil
+}
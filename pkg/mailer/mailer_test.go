@@ -0,0 +1,100 @@
+package mailer
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts one session and returns the envelope and data it received
+func fakeSMTPServer(t *testing.T) (string, int, <-chan []string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		var lines []string
+		reader := bufio.NewReader(conn)
+		reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+		reply("220 localhost ESMTP")
+		inData := false
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if inData {
+				if line == "." {
+					inData = false
+					reply("250 OK")
+					continue
+				}
+				lines = append(lines, line)
+				continue
+			}
+			lines = append(lines, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				inData = true
+				reply("354 Go ahead")
+			case line == "QUIT":
+				reply("221 Bye")
+				received <- lines
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+
+	host, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return host, port, received
+}
+
+func TestSMTP_Send(t *testing.T) {
+	host, port, received := fakeSMTPServer(t)
+	m, err := NewSMTP(host, port, "", "", "App <no-reply@example.com>", 5*time.Second)
+	require.NoError(t, err)
+
+	err = m.Send(context.Background(), &Message{
+		To:      "jane@example.com",
+		Subject: "Réactivation",
+		Body:    "Confirm here: https://example.com/confirm?token=abc",
+	})
+	require.NoError(t, err)
+
+	lines := <-received
+	session := strings.Join(lines, "\n")
+	assert.Contains(t, session, "MAIL FROM:<no-reply@example.com>")
+	assert.Contains(t, session, "RCPT TO:<jane@example.com>")
+	assert.Contains(t, session, `From: "App" <no-reply@example.com>`)
+	assert.Contains(t, session, "Subject: =?utf-8?q?R=C3=A9activation?=")
+	assert.Contains(t, session, "token=3Dabc")
+}
+
+func TestSMTP_InvalidAddresses(t *testing.T) {
+	_, err := NewSMTP("localhost", 25, "", "", "not an address", time.Second)
+	assert.Error(t, err)
+
+	m, err := NewSMTP("localhost", 25, "", "", "no-reply@example.com", time.Second)
+	require.NoError(t, err)
+	assert.Error(t, m.Send(context.Background(), &Message{To: "nobody"}))
+}
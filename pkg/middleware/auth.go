@@ -2,11 +2,16 @@ package middleware
 
 import (
 	"context"
+	"crypto/subtle"
 	"net/http"
+	"slices"
 	"strings"
+	"time"
 
 	"gbt-be-template/pkg/logger"
 	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // ContextKey is a custom type for context keys to avoid collisions
@@ -19,14 +24,68 @@ const (
 	UserEmailKey ContextKey = "user_email"
 	// IsAdminKey is the context key for admin status
 	IsAdminKey ContextKey = "is_admin"
+	// ClientIDKey is the context key for the OAuth2 client a token was issued to
+	ClientIDKey ContextKey = "client_id"
+	// ScopeKey is the context key for the scopes granted to an OAuth2 token or API key
+	ScopeKey ContextKey = "scope"
+	// TokenIDKey is the context key for the ID of the access token
+	TokenIDKey ContextKey = "token_id"
+	// TokenExpiresAtKey is the context key for the expiry of the access token
+	TokenExpiresAtKey ContextKey = "token_expires_at"
+	// ImpersonatorIDKey is the context key for the admin acting as the user with an impersonation token
+	ImpersonatorIDKey ContextKey = "impersonator_id"
+	// ExtraClaimsKey is the context key for the custom claims added to the access token
+	ExtraClaimsKey ContextKey = "extra_claims"
+	// RolesKey is the context key for the roles named in the access token
+	RolesKey ContextKey = "roles"
+	// PermissionsKey is the context key for the permissions named in the access token
+	PermissionsKey ContextKey = "permissions"
 )
 
-// JWTAuth middleware validates JWT tokens
-func JWTAuth(log *logger.Logger, jwtSecret string) func(http.Handler) http.Handler {
+// RevocationCheck reports whether the access token with the given claims was revoked
+type RevocationCheck func(ctx context.Context, claims *utils.JWTClaims) (bool, error)
+
+// PasswordChangeCheck reports whether the user has to change their password before using the API
+type PasswordChangeCheck func(ctx context.Context, userID uint) (bool, error)
+
+// PermissionCheck reports whether the user may perform the action on the resource
+type PermissionCheck func(ctx context.Context, userID uint, resource, action string) (bool, error)
+
+// AdminCheck reports whether the user is an admin
+type AdminCheck func(ctx context.Context, userID uint) (bool, error)
+
+// Principal is the local user an externally issued token or API key was mapped to
+type Principal struct {
+	UserID  uint
+	Email   string
+	IsAdmin bool
+	Scopes  []string // Non-nil limits the request to routes requiring one of these scopes
+}
+
+// ExternalAuth authenticates tokens issued by an external identity provider, or API keys.
+// ok is false for tokens it did not issue, which are then validated as local JWTs.
+type ExternalAuth func(ctx context.Context, token string) (principal *Principal, ok bool, err error)
+
+// JWTAuth middleware validates JWT tokens. Tokens for which isRevoked reports true are
+// rejected; when the check fails the token is accepted, so an outage of the revocation
+// store does not log everyone out. Tokens claimed by external are authenticated by it
+// instead. Without an Authorization header, the access token cookie of cookies is used, and
+// requests changing state must pass the CSRF check. isRevoked, external and cookies may be nil.
+func JWTAuth(log *logger.Logger, keys utils.JWTKeys, isRevoked RevocationCheck, external ExternalAuth, cookies *AuthCookies) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get token from Authorization header
 			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" {
+				if cookieToken := cookies.AccessToken(r); cookieToken != "" {
+					if !cookies.validCSRF(r) {
+						log.WithField("path", r.URL.Path).Warn("Missing or invalid CSRF token")
+						utils.WriteErrorResponse(w, http.StatusForbidden, "CSRF token missing or invalid", nil)
+						return
+					}
+					authHeader = "Bearer " + cookieToken
+				}
+			}
 			if authHeader == "" {
 				log.WithField("path", r.URL.Path).Warn("Missing authorization header")
 				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Authorization header required", nil)
@@ -48,18 +107,80 @@ func JWTAuth(log *logger.Logger, jwtSecret string) func(http.Handler) http.Handl
 				return
 			}
 
+			if external != nil {
+				principal, ok, err := external(r.Context(), token)
+				if ok {
+					if err != nil {
+						log.WithError(err).WithField("path", r.URL.Path).Warn("Invalid external token")
+						utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid token", nil)
+						return
+					}
+
+					ctx := context.WithValue(r.Context(), UserIDKey, principal.UserID)
+					ctx = context.WithValue(ctx, UserEmailKey, principal.Email)
+					ctx = context.WithValue(ctx, IsAdminKey, principal.IsAdmin)
+					if principal.Scopes != nil {
+						ctx = context.WithValue(ctx, ScopeKey, strings.Join(principal.Scopes, " "))
+					}
+					next.ServeHTTP(w, r.WithContext(ctx))
+					return
+				}
+			}
+
 			// Validate token and extract claims
-			claims, err := utils.ValidateJWT(token, jwtSecret)
+			claims, err := utils.ValidateJWT(token, keys)
 			if err != nil {
 				log.WithError(err).WithField("path", r.URL.Path).Warn("Invalid token")
 				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid token", nil)
 				return
 			}
 
+			// Tokens delegated to OAuth2 clients are only valid on OAuth resource endpoints
+			if claims.ClientID != "" {
+				log.WithField("path", r.URL.Path).Warn("OAuth client token used on first-party API")
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid token", nil)
+				return
+			}
+
+			if isRevoked != nil {
+				revoked, err := isRevoked(r.Context(), claims)
+				if err != nil {
+					log.WithError(err).WithField("path", r.URL.Path).Error("Failed to check token revocation")
+				}
+				if revoked {
+					log.WithField("path", r.URL.Path).Warn("Revoked token")
+					utils.WriteErrorResponse(w, http.StatusUnauthorized, "Token has been revoked", nil)
+					return
+				}
+			}
+
 			// Add user information to context
 			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
 			ctx = context.WithValue(ctx, UserEmailKey, claims.Email)
 			ctx = context.WithValue(ctx, IsAdminKey, claims.IsAdmin)
+			ctx = context.WithValue(ctx, TokenIDKey, claims.ID)
+			if claims.ExpiresAt != nil {
+				ctx = context.WithValue(ctx, TokenExpiresAtKey, claims.ExpiresAt.Time)
+			}
+			if len(claims.Extra) > 0 {
+				ctx = context.WithValue(ctx, ExtraClaimsKey, claims.Extra)
+			}
+			if claims.Roles != nil {
+				ctx = context.WithValue(ctx, RolesKey, claims.Roles)
+			}
+			if claims.Permissions != nil {
+				ctx = context.WithValue(ctx, PermissionsKey, claims.Permissions)
+			}
+
+			// Everything an admin does as another user is audit logged
+			if claims.ImpersonatorID != 0 {
+				ctx = context.WithValue(ctx, ImpersonatorIDKey, claims.ImpersonatorID)
+				log.Security("impersonated_request", claims.UserID).WithFields(map[string]interface{}{
+					"impersonator_id": claims.ImpersonatorID,
+					"method":          r.Method,
+					"path":            r.URL.Path,
+				}).Info("Request made by an admin impersonating the user")
+			}
 
 			// Continue with the request
 			next.ServeHTTP(w, r.WithContext(ctx))
@@ -67,6 +188,55 @@ func JWTAuth(log *logger.Logger, jwtSecret string) func(http.Handler) http.Handl
 	}
 }
 
+// OAuthBearer middleware validates access tokens issued to OAuth2 clients
+func OAuthBearer(log *logger.Logger, keys utils.JWTKeys) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if !strings.HasPrefix(authHeader, "Bearer ") {
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_request"`)
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Bearer token required", nil)
+				return
+			}
+
+			claims, err := utils.ValidateJWT(strings.TrimPrefix(authHeader, "Bearer "), keys)
+			if err != nil || claims.ClientID == "" {
+				log.WithField("path", r.URL.Path).Warn("Invalid OAuth access token")
+				w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid token", nil)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), UserIDKey, claims.UserID)
+			ctx = context.WithValue(ctx, UserEmailKey, claims.Email)
+			ctx = context.WithValue(ctx, ClientIDKey, claims.ClientID)
+			ctx = context.WithValue(ctx, ScopeKey, claims.Scope)
+
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireStaticToken middleware admits requests carrying a fixed bearer token, such as scrapes
+// of the metrics endpoint. An empty token admits every request.
+func RequireStaticToken(log *logger.Logger, token string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if token == "" {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			presented, found := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+			if !found || subtle.ConstantTimeCompare([]byte(presented), []byte(token)) != 1 {
+				log.WithField("path", r.URL.Path).Warn("Invalid static bearer token")
+				w.Header().Set("WWW-Authenticate", "Bearer")
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Invalid token", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // RequireAdmin middleware ensures the user is an admin
 func RequireAdmin(log *logger.Logger) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -88,8 +258,202 @@ func RequireAdmin(log *logger.Logger) func(http.Handler) http.Handler {
 	}
 }
 
+// ResolveAdmin middleware replaces the admin status of the token with the one isAdmin reports, so
+// that it follows the user's roles rather than the is_admin claim. When the check fails the claim
+// is kept. Requests made with impersonation tokens are never admin requests.
+func ResolveAdmin(log *logger.Logger, isAdmin AdminCheck) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDFromContext(r.Context())
+			if _, impersonated := GetImpersonatorFromContext(r.Context()); !ok || impersonated {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			admin, err := isAdmin(r.Context(), userID)
+			if err != nil {
+				log.WithError(err).WithField("path", r.URL.Path).Error("Failed to check admin role")
+				next.ServeHTTP(w, r)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), IsAdminKey, admin)))
+		})
+	}
+}
+
+// RequirePermission middleware lets through users allowed the action on the resource. Access
+// tokens naming the permission are let through right away; otherwise allowed is asked, which
+// also covers permissions granted after the token was issued. Unlike the password change check,
+// a failing check refuses the request.
+func RequirePermission(log *logger.Logger, allowed PermissionCheck, resource, action string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if permissions, ok := GetPermissionsFromContext(r.Context()); ok && slices.Contains(permissions, utils.PermissionClaim(resource, action)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			userID, _ := GetUserIDFromContext(r.Context())
+			ok, err := allowed(r.Context(), userID, resource, action)
+			if err != nil {
+				log.WithError(err).WithField("path", r.URL.Path).Error("Failed to check permission")
+				utils.WriteErrorResponse(w, http.StatusInternalServerError, "Failed to check permission", nil)
+				return
+			}
+			if !ok {
+				log.WithFields(map[string]interface{}{
+					"user_id":  userID,
+					"path":     r.URL.Path,
+					"resource": resource,
+					"action":   action,
+				}).Warn("Permission denied")
+				utils.WriteErrorResponse(w, http.StatusForbidden, "Permission denied", map[string]interface{}{
+					"required_permission": resource + "." + action,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RoutePermission is the permission required for requests to a route
+type RoutePermission struct {
+	Method   string // HTTP method of the route
+	Pattern  string // Full chi pattern of the route, such as /api/v1/admin/roles/{id}
+	Resource string
+	Action   string
+}
+
+// RequireRoutePermissions middleware applies RequirePermission to requests for the routes listed
+// in permissions. The route is looked up in the router serving the request, so the middleware
+// can be used once for a whole route group. Requests to other routes are let through.
+func RequireRoutePermissions(log *logger.Logger, allowed PermissionCheck, permissions []RoutePermission) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		checked := make(map[string]http.Handler, len(permissions))
+		for _, permission := range permissions {
+			checked[permission.Method+" "+permission.Pattern] = RequirePermission(log, allowed, permission.Resource, permission.Action)(next)
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if rctx := chi.RouteContext(r.Context()); rctx != nil && rctx.Routes != nil {
+				path := r.URL.RawPath
+				if path == "" {
+					path = r.URL.Path
+				}
+				pattern := rctx.Routes.Find(chi.NewRouteContext(), r.Method, path)
+				if handler, ok := checked[r.Method+" "+pattern]; ok {
+					handler.ServeHTTP(w, r)
+					return
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// UnroutedPermissions returns the entries of permissions that don't match a route of routes,
+// which are left over from routes that were changed or removed
+func UnroutedPermissions(routes chi.Routes, permissions []RoutePermission) []RoutePermission {
+	var unrouted []RoutePermission
+	for _, permission := range permissions {
+		if routes.Find(chi.NewRouteContext(), permission.Method, permission.Pattern) != permission.Pattern {
+			unrouted = append(unrouted, permission)
+		}
+	}
+	return unrouted
+}
+
+// RequireScope middleware limits scoped credentials, such as API keys, to routes matching
+// one of their scopes. Requests signed in with a regular session are not affected.
+func RequireScope(log *logger.Logger, scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			granted, scoped := GetScopeFromContext(r.Context())
+			if scoped && !slices.Contains(strings.Fields(granted), scope) {
+				log.WithFields(map[string]interface{}{
+					"user_id": r.Context().Value(UserIDKey),
+					"path":    r.URL.Path,
+					"scope":   scope,
+				}).Warn("Insufficient scope")
+				w.Header().Set("WWW-Authenticate", `Bearer error="insufficient_scope", scope="`+scope+`"`)
+				utils.WriteErrorResponse(w, http.StatusForbidden, "Insufficient scope", map[string]interface{}{
+					"required_scope": scope,
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequireSession middleware refuses scoped credentials, such as API keys, on routes that
+// manage the account itself and must only be reached by the signed-in user. Admins
+// impersonating the user may look at these routes but not change anything through them.
+func RequireSession(log *logger.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if _, scoped := GetScopeFromContext(r.Context()); scoped {
+				log.WithFields(map[string]interface{}{
+					"user_id": r.Context().Value(UserIDKey),
+					"path":    r.URL.Path,
+				}).Warn("Scoped credential used on account route")
+				utils.WriteErrorResponse(w, http.StatusForbidden, "This route requires a signed-in session", nil)
+				return
+			}
+
+			if impersonatorID, ok := GetImpersonatorFromContext(r.Context()); ok && r.Method != http.MethodGet && r.Method != http.MethodHead {
+				log.WithFields(map[string]interface{}{
+					"user_id":         r.Context().Value(UserIDKey),
+					"impersonator_id": impersonatorID,
+					"path":            r.URL.Path,
+				}).Warn("Impersonation token used to change account")
+				utils.WriteErrorResponse(w, http.StatusForbidden, "This route can't be used while impersonating", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RequirePasswordChanged middleware refuses every request of a user who has to change their
+// password, with the code password_change_required so clients can send them to the reset form.
+// Routes they still need, such as logging out, must be mounted outside of it. When the check
+// fails the request is let through, like the revocation check of JWTAuth.
+func RequirePasswordChanged(log *logger.Logger, mustChange PasswordChangeCheck) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			userID, ok := GetUserIDFromContext(r.Context())
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			required, err := mustChange(r.Context(), userID)
+			if err != nil {
+				log.WithError(err).WithField("path", r.URL.Path).Error("Failed to check password change requirement")
+			}
+			if required {
+				log.WithFields(map[string]interface{}{
+					"user_id": userID,
+					"path":    r.URL.Path,
+				}).Warn("Request refused until the password is changed")
+				utils.WriteErrorResponse(w, http.StatusForbidden, "Password change required", map[string]interface{}{
+					"code": "password_change_required",
+				})
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 // OptionalAuth middleware validates JWT tokens but doesn't require them
-func OptionalAuth(log *logger.Logger, jwtSecret string) func(http.Handler) http.Handler {
+func OptionalAuth(log *logger.Logger, keys utils.JWTKeys) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 			// Get token from Authorization header
@@ -116,8 +480,8 @@ func OptionalAuth(log *logger.Logger, jwtSecret string) func(http.Handler) http.
 			}
 
 			// Validate token and extract claims
-			claims, err := utils.ValidateJWT(token, jwtSecret)
-			if err != nil {
+			claims, err := utils.ValidateJWT(token, keys)
+			if err != nil || claims.ClientID != "" {
 				// Invalid token, continue without authentication
 				log.WithError(err).WithField("path", r.URL.Path).Debug("Invalid optional token")
 				next.ServeHTTP(w, r)
@@ -147,8 +511,50 @@ func GetUserEmailFromContext(ctx context.Context) (string, bool) {
 	return email, ok
 }
 
-// GetIsAdminFromContext extracts admin status from context
+// GetIsAdminFromContext extracts admin status from context. It comes from the user's roles behind
+// ResolveAdmin, and from the is_admin claim of the token otherwise.
 func GetIsAdminFromContext(ctx context.Context) (bool, bool) {
 	isAdmin, ok := ctx.Value(IsAdminKey).(bool)
 	return isAdmin, ok
 }
+
+// GetRolesFromContext extracts the roles named in the access token, for tokens issued with
+// roles in them
+func GetRolesFromContext(ctx context.Context) ([]string, bool) {
+	roles, ok := ctx.Value(RolesKey).([]string)
+	return roles, ok
+}
+
+// GetPermissionsFromContext extracts the resource:action permissions named in the access token,
+// for tokens issued with permissions in them
+func GetPermissionsFromContext(ctx context.Context) ([]string, bool) {
+	permissions, ok := ctx.Value(PermissionsKey).([]string)
+	return permissions, ok
+}
+
+// GetTokenFromContext extracts the ID and expiry of the current access token
+func GetTokenFromContext(ctx context.Context) (string, time.Time, bool) {
+	tokenID, _ := ctx.Value(TokenIDKey).(string)
+	expiresAt, ok := ctx.Value(TokenExpiresAtKey).(time.Time)
+	return tokenID, expiresAt, ok && tokenID != ""
+}
+
+// GetImpersonatorFromContext extracts the ID of the admin impersonating the user, for requests
+// made with an impersonation token
+func GetImpersonatorFromContext(ctx context.Context) (uint, bool) {
+	impersonatorID, ok := ctx.Value(ImpersonatorIDKey).(uint)
+	return impersonatorID, ok
+}
+
+// GetClaimFromContext extracts a custom claim added to the access token by a claims enricher
+func GetClaimFromContext(ctx context.Context, name string) (interface{}, bool) {
+	extra, _ := ctx.Value(ExtraClaimsKey).(map[string]interface{})
+	value, ok := extra[name]
+	return value, ok
+}
+
+// GetScopeFromContext extracts the scopes granted to the current OAuth2 token or API key
+func GetScopeFromContext(ctx context.Context) (string, bool) {
+	scope, ok := ctx.Value(ScopeKey).(string)
+	return scope, ok
+}
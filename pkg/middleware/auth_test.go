@@ -0,0 +1,386 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJWTAuth_Revocation(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	token, err := utils.GenerateJWT(1, "user@example.com", false, keys.Current, time.Hour)
+	require.NoError(t, err)
+	claims, err := utils.ValidateJWT(token, keys)
+	require.NoError(t, err)
+	require.NotEmpty(t, claims.ID)
+
+	serve := func(isRevoked RevocationCheck) (*httptest.ResponseRecorder, string) {
+		var tokenID string
+		handler := JWTAuth(logger.New("error", "text"), keys, isRevoked, nil, nil)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tokenID, _, _ = GetTokenFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}),
+		)
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder, tokenID
+	}
+
+	t.Run("valid token exposes its ID", func(t *testing.T) {
+		recorder, tokenID := serve(func(ctx context.Context, claims *utils.JWTClaims) (bool, error) { return false, nil })
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, claims.ID, tokenID)
+	})
+
+	t.Run("revoked token is rejected", func(t *testing.T) {
+		recorder, _ := serve(func(ctx context.Context, c *utils.JWTClaims) (bool, error) { return c.ID == claims.ID, nil })
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	})
+
+	t.Run("failing check lets the token through", func(t *testing.T) {
+		recorder, _ := serve(func(ctx context.Context, claims *utils.JWTClaims) (bool, error) {
+			return false, errors.New("redis down")
+		})
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+}
+
+func TestJWTAuth_ExternalTokens(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	localToken, err := utils.GenerateJWT(1, "local@example.com", false, keys.Current, time.Hour)
+	require.NoError(t, err)
+
+	external := func(ctx context.Context, token string) (*Principal, bool, error) {
+		switch token {
+		case "idp-valid":
+			return &Principal{UserID: 7, Email: "idp@example.com", IsAdmin: true}, true, nil
+		case "idp-invalid":
+			return nil, true, errors.New("signature mismatch")
+		}
+		return nil, false, nil
+	}
+
+	serve := func(token string) (*httptest.ResponseRecorder, uint, bool) {
+		var userID uint
+		var isAdmin bool
+		handler := JWTAuth(logger.New("error", "text"), keys, nil, external, nil)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				userID, _ = GetUserIDFromContext(r.Context())
+				isAdmin, _ = GetIsAdminFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}),
+		)
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder, userID, isAdmin
+	}
+
+	recorder, userID, isAdmin := serve("idp-valid")
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, uint(7), userID)
+	assert.True(t, isAdmin)
+
+	recorder, _, _ = serve("idp-invalid")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+
+	// Tokens the provider does not claim are validated as local JWTs
+	recorder, userID, isAdmin = serve(localToken)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, uint(1), userID)
+	assert.False(t, isAdmin)
+
+	recorder, _, _ = serve("garbage")
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+}
+
+func TestRequireScope(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	sessionToken, err := utils.GenerateJWT(1, "local@example.com", false, keys.Current, time.Hour)
+	require.NoError(t, err)
+
+	external := func(ctx context.Context, token string) (*Principal, bool, error) {
+		switch token {
+		case "key-read":
+			return &Principal{UserID: 7, Email: "key@example.com", Scopes: []string{"users:read"}}, true, nil
+		case "key-none":
+			return &Principal{UserID: 7, Email: "key@example.com", Scopes: []string{}}, true, nil
+		}
+		return nil, false, nil
+	}
+
+	log := logger.New("error", "text")
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(token string, guard func(http.Handler) http.Handler) *httptest.ResponseRecorder {
+		handler := JWTAuth(log, keys, nil, external, nil)(guard(ok))
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Sessions are not limited by scopes
+	assert.Equal(t, http.StatusOK, serve(sessionToken, RequireScope(log, "users:write")).Code)
+	assert.Equal(t, http.StatusOK, serve(sessionToken, RequireSession(log)).Code)
+
+	assert.Equal(t, http.StatusOK, serve("key-read", RequireScope(log, "users:read")).Code)
+	recorder := serve("key-read", RequireScope(log, "users:write"))
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Header().Get("WWW-Authenticate"), `scope="users:write"`)
+
+	// Keys without scopes reach nothing, and no key reaches session-only routes
+	assert.Equal(t, http.StatusForbidden, serve("key-none", RequireScope(log, "users:read")).Code)
+	assert.Equal(t, http.StatusForbidden, serve("key-read", RequireSession(log)).Code)
+}
+
+func TestRequireStaticToken(t *testing.T) {
+	log := logger.New("error", "text")
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	serve := func(token, header string) int {
+		request := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+		if header != "" {
+			request.Header.Set("Authorization", header)
+		}
+		recorder := httptest.NewRecorder()
+		RequireStaticToken(log, token)(ok).ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve("secret", "Bearer secret"))
+	assert.Equal(t, http.StatusUnauthorized, serve("secret", "Bearer wrong"))
+	assert.Equal(t, http.StatusUnauthorized, serve("secret", "secret"))
+	assert.Equal(t, http.StatusUnauthorized, serve("secret", ""))
+	assert.Equal(t, http.StatusOK, serve("", ""))
+}
+
+func TestRequirePermission(t *testing.T) {
+	log := logger.New("error", "text")
+	allowed := func(ctx context.Context, userID uint, resource, action string) (bool, error) {
+		if userID == 0 {
+			return false, errors.New("store unavailable")
+		}
+		return userID == 1 && resource == "user" && action == "list", nil
+	}
+	serve := func(userID uint, action string) *httptest.ResponseRecorder {
+		handler := RequirePermission(log, allowed, "user", action)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		request = request.WithContext(context.WithValue(request.Context(), UserIDKey, userID))
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	assert.Equal(t, http.StatusOK, serve(1, "list").Code)
+	recorder := serve(1, "delete")
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "user.delete")
+	assert.Equal(t, http.StatusForbidden, serve(2, "list").Code)
+	// A failing check refuses the request
+	assert.Equal(t, http.StatusInternalServerError, serve(0, "list").Code)
+
+	// Permissions named in the token are let through without a check
+	handler := RequirePermission(log, allowed, "user", "delete")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	request := httptest.NewRequest(http.MethodDelete, "/api/v1/users/3", nil)
+	request = request.WithContext(context.WithValue(request.Context(), PermissionsKey, []string{"user:delete"}))
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+}
+
+func TestRequireRoutePermissions(t *testing.T) {
+	log := logger.New("error", "text")
+	allowed := func(ctx context.Context, userID uint, resource, action string) (bool, error) {
+		return userID == 1 && resource == "role" && action == "list", nil
+	}
+	permissions := []RoutePermission{
+		{Method: http.MethodGet, Pattern: "/admin/roles/", Resource: "role", Action: "list"},
+		{Method: http.MethodDelete, Pattern: "/admin/roles/{id}", Resource: "role", Action: "delete"},
+		{Method: http.MethodGet, Pattern: "/admin/removed", Resource: "role", Action: "read"},
+	}
+
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(RequireRoutePermissions(log, allowed, permissions))
+		r.Route("/admin/roles", func(r chi.Router) {
+			ok := func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) }
+			r.Get("/", ok)
+			r.Get("/{id}", ok)
+			r.Delete("/{id}", ok)
+		})
+	})
+	serve := func(userID uint, method, path string) int {
+		request := httptest.NewRequest(method, path, nil)
+		request = request.WithContext(context.WithValue(request.Context(), UserIDKey, userID))
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(1, http.MethodGet, "/admin/roles"))
+	assert.Equal(t, http.StatusForbidden, serve(2, http.MethodGet, "/admin/roles/"))
+	assert.Equal(t, http.StatusForbidden, serve(1, http.MethodDelete, "/admin/roles/3"))
+	assert.Equal(t, http.StatusOK, serve(2, http.MethodGet, "/admin/roles/3"), "routes without a permission are let through")
+
+	unrouted := UnroutedPermissions(router, permissions)
+	require.Len(t, unrouted, 1)
+	assert.Equal(t, "/admin/removed", unrouted[0].Pattern)
+}
+
+func TestResolveAdmin(t *testing.T) {
+	log := logger.New("error", "text")
+	isAdmin := func(ctx context.Context, userID uint) (bool, error) {
+		if userID == 0 {
+			return false, errors.New("store unavailable")
+		}
+		return userID == 1, nil
+	}
+	serve := func(userID uint, claim bool, impersonated bool) bool {
+		var admin bool
+		handler := ResolveAdmin(log, isAdmin)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			admin, _ = GetIsAdminFromContext(r.Context())
+		}))
+		ctx := context.WithValue(context.Background(), UserIDKey, userID)
+		ctx = context.WithValue(ctx, IsAdminKey, claim)
+		if impersonated {
+			ctx = context.WithValue(ctx, ImpersonatorIDKey, uint(9))
+		}
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/users", nil).WithContext(ctx))
+		return admin
+	}
+
+	assert.True(t, serve(1, false, false), "the admin role wins over the claim")
+	assert.False(t, serve(2, true, false))
+	assert.True(t, serve(0, true, false), "the claim is kept when the check fails")
+	assert.False(t, serve(1, false, true), "impersonation tokens keep their claim")
+}
+
+func TestRequirePasswordChanged(t *testing.T) {
+	log := logger.New("error", "text")
+	serve := func(mustChange PasswordChangeCheck, userID uint) *httptest.ResponseRecorder {
+		handler := RequirePasswordChanged(log, mustChange)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}))
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		if userID != 0 {
+			request = request.WithContext(context.WithValue(request.Context(), UserIDKey, userID))
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+	flagged := func(ctx context.Context, userID uint) (bool, error) { return userID == 1, nil }
+
+	recorder := serve(flagged, 1)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "password_change_required")
+	assert.Equal(t, http.StatusOK, serve(flagged, 2).Code)
+	assert.Equal(t, http.StatusOK, serve(flagged, 0).Code)
+
+	// A failing check lets the request through
+	assert.Equal(t, http.StatusOK, serve(func(ctx context.Context, userID uint) (bool, error) {
+		return false, errors.New("database down")
+	}, 1).Code)
+}
+
+func TestJWTAuth_ExtraClaims(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	token, err := utils.GenerateJWTWithClaims(1, "user@example.com", false, map[string]interface{}{"tenant_id": "acme"}, keys.Current, time.Minute)
+	require.NoError(t, err)
+
+	var tenantID interface{}
+	handler := JWTAuth(logger.New("error", "text"), keys, nil, nil, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			tenantID, _ = GetClaimFromContext(r.Context(), "tenant_id")
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "acme", tenantID)
+}
+
+func TestJWTAuth_RoleClaims(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	token, err := utils.GenerateJWTFromClaims(utils.JWTClaims{
+		UserID:      1,
+		Email:       "user@example.com",
+		Roles:       []string{"moderator"},
+		Permissions: []string{"user:list"},
+	}, keys.Current, time.Minute)
+	require.NoError(t, err)
+
+	var roles, permissions []string
+	handler := JWTAuth(logger.New("error", "text"), keys, nil, nil, nil)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			roles, _ = GetRolesFromContext(r.Context())
+			permissions, _ = GetPermissionsFromContext(r.Context())
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+	request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+	request.Header.Set("Authorization", "Bearer "+token)
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, []string{"moderator"}, roles)
+	assert.Equal(t, []string{"user:list"}, permissions)
+}
+
+func TestJWTAuth_Impersonation(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	token, err := utils.GenerateImpersonationJWT(2, "user@example.com", 1, keys.Current, time.Minute)
+	require.NoError(t, err)
+	log := logger.New("error", "text")
+
+	serve := func(method string) (*httptest.ResponseRecorder, uint) {
+		var impersonatorID uint
+		handler := JWTAuth(log, keys, nil, nil, nil)(RequireSession(log)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				impersonatorID, _ = GetImpersonatorFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}),
+		))
+		request := httptest.NewRequest(method, "/api/v1/auth/sessions", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder, impersonatorID
+	}
+
+	// Handlers can tell the admin is acting as the user
+	recorder, impersonatorID := serve(http.MethodGet)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, uint(1), impersonatorID)
+
+	// Account routes can be looked at but not changed
+	recorder, _ = serve(http.MethodPost)
+	assert.Equal(t, http.StatusForbidden, recorder.Code)
+}
@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"gbt-be-template/pkg/backoff"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+)
+
+// LoginIdentifierFunc returns the account a login request is for, such as its email,
+// or an empty string when the request doesn't name one
+type LoginIdentifierFunc func(r *http.Request) string
+
+// LoginBackoff slows down password guessing on a login route. Failed logins, answered with
+// 401, are counted per client IP and per account. Once either fails too often, further
+// attempts get 429 with a Retry-After header for exponentially growing periods. A successful
+// login clears the account's failures but not the IP's, so logging in to an own account doesn't
+// reset guessing at others. Either tracker may be nil to skip that key.
+func LoginBackoff(log *logger.Logger, byIP, byAccount *backoff.Tracker, identify LoginIdentifierFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ipKey := getClientIP(r)
+			accountKey := strings.ToLower(strings.TrimSpace(identify(r)))
+
+			var retryAfter time.Duration
+			if byIP != nil {
+				if d := byIP.Check(ipKey); d > 0 {
+					log.WithField("ip", ipKey).Warn("Login refused, too many failed attempts from this IP")
+					retryAfter = d
+				}
+			}
+			if byAccount != nil && accountKey != "" {
+				if d := byAccount.Check(accountKey); d > 0 {
+					log.WithField("login", accountKey).Warn("Login refused, too many failed attempts for this account")
+					retryAfter = max(retryAfter, d)
+				}
+			}
+			if retryAfter > 0 {
+				seconds := ceilSeconds(retryAfter)
+				w.Header().Set("Retry-After", strconv.Itoa(seconds))
+				utils.WriteErrorResponse(w, http.StatusTooManyRequests, "Too many failed login attempts", map[string]interface{}{
+					"retry_after": seconds,
+				})
+				return
+			}
+
+			wrapped := &responseWriter{ResponseWriter: w, statusCode: http.StatusOK}
+			next.ServeHTTP(wrapped, r)
+
+			switch {
+			case wrapped.statusCode == http.StatusUnauthorized:
+				if byIP != nil {
+					byIP.Fail(ipKey)
+				}
+				if byAccount != nil && accountKey != "" {
+					byAccount.Fail(accountKey)
+				}
+			case wrapped.statusCode >= 200 && wrapped.statusCode < 300:
+				if byAccount != nil && accountKey != "" {
+					byAccount.Reset(accountKey)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gbt-be-template/pkg/backoff"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginBackoff(t *testing.T) {
+	policy := backoff.Policy{Threshold: 2, BaseDelay: time.Minute, MaxDelay: time.Hour, ResetAfter: time.Hour}
+	byIP := backoff.New(backoff.Policy{Threshold: 4, BaseDelay: time.Minute, MaxDelay: time.Hour, ResetAfter: time.Hour})
+	byAccount := backoff.New(policy)
+
+	handler := LoginBackoff(logger.New("error", "text"), byIP, byAccount, func(r *http.Request) string {
+		return r.Header.Get("X-Login")
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Password") == "correct" {
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+
+	login := func(ip, account, password string) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/auth/login", nil)
+		request.RemoteAddr = ip + ":1234"
+		request.Header.Set("X-Login", account)
+		request.Header.Set("X-Password", password)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// A success clears the account's failures
+	assert.Equal(t, http.StatusUnauthorized, login("10.0.0.1", "jane@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusOK, login("10.0.0.1", "jane@example.com", "correct").Code)
+	assert.Equal(t, http.StatusUnauthorized, login("10.0.0.1", "jane@example.com", "wrong").Code)
+
+	// The account is blocked from any IP once it reaches the threshold, keys are case-insensitive
+	assert.Equal(t, http.StatusUnauthorized, login("10.0.0.2", "Jane@Example.com", "wrong").Code)
+	recorder := login("10.0.0.3", "jane@example.com", "correct")
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+
+	// The IP keeps its failures across accounts and successes
+	assert.Equal(t, http.StatusUnauthorized, login("10.0.0.1", "john@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusOK, login("10.0.0.1", "alex@example.com", "correct").Code)
+	assert.Equal(t, http.StatusUnauthorized, login("10.0.0.1", "sam@example.com", "wrong").Code)
+	assert.Equal(t, http.StatusTooManyRequests, login("10.0.0.1", "alex@example.com", "correct").Code)
+
+	assert.Equal(t, backoff.Stats{Tracked: 2, Blocked: 1, Refused: 1}, byIP.Stats())
+}
@@ -0,0 +1,31 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// ClientIPKey is the context key for the IP address of the client
+	ClientIPKey ContextKey = "client_ip"
+	// UserAgentKey is the context key for the User-Agent header of the client
+	UserAgentKey ContextKey = "user_agent"
+)
+
+// ClientInfo middleware stores the client's IP address and user agent in the request
+// context, so services can record where a request came from
+func ClientInfo(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), ClientIPKey, getClientIP(r))
+		ctx = context.WithValue(ctx, UserAgentKey, r.UserAgent())
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// GetClientFromContext extracts the IP address and user agent of the client. Both are
+// empty outside of a request.
+func GetClientFromContext(ctx context.Context) (ip, userAgent string) {
+	ip, _ = ctx.Value(ClientIPKey).(string)
+	userAgent, _ = ctx.Value(UserAgentKey).(string)
+	return ip, userAgent
+}
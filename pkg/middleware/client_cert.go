@@ -0,0 +1,74 @@
+package middleware
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+	"slices"
+
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+)
+
+// ServiceIdentityKey is the context key for the service that made a request with a client certificate
+const ServiceIdentityKey ContextKey = "service_identity"
+
+// ClientCertAuth middleware admits services presenting a client certificate the TLS handshake
+// verified. The certificate's URI SANs, DNS SANs and common name are matched against allowed, in
+// that order, and the first match becomes the service identity. With no allowed identities, any
+// verified certificate is let in under the first of those names.
+func ClientCertAuth(log *logger.Logger, allowed []string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+				utils.WriteErrorResponse(w, http.StatusUnauthorized, "Client certificate required", nil)
+				return
+			}
+
+			identity, ok := serviceIdentity(r.TLS.VerifiedChains[0][0], allowed)
+			if !ok {
+				log.WithFields(map[string]interface{}{
+					"identity": identity,
+					"path":     r.URL.Path,
+				}).Warn("Client certificate of an unknown service")
+				utils.WriteErrorResponse(w, http.StatusForbidden, "Service not allowed", nil)
+				return
+			}
+
+			ctx := context.WithValue(r.Context(), ServiceIdentityKey, identity)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// serviceIdentity maps a client certificate to a service identity. It reports false, along with
+// the certificate's preferred name, when none of its names is allowed.
+func serviceIdentity(cert *x509.Certificate, allowed []string) (string, bool) {
+	var names []string
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	names = append(names, cert.DNSNames...)
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+
+	if len(allowed) == 0 {
+		return names[0], true
+	}
+	for _, name := range names {
+		if slices.Contains(allowed, name) {
+			return name, true
+		}
+	}
+	return names[0], false
+}
+
+// GetServiceFromContext extracts the identity of the service that made the request
+func GetServiceFromContext(ctx context.Context) (string, bool) {
+	identity, ok := ctx.Value(ServiceIdentityKey).(string)
+	return identity, ok
+}
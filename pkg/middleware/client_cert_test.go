@@ -0,0 +1,56 @@
+package middleware
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientCertAuth(t *testing.T) {
+	spiffeID, _ := url.Parse("spiffe://example.org/billing")
+	cert := &x509.Certificate{
+		Subject:  pkix.Name{CommonName: "billing"},
+		DNSNames: []string{"billing.internal"},
+		URIs:     []*url.URL{spiffeID},
+	}
+
+	serve := func(allowed []string, state *tls.ConnectionState) (int, string) {
+		var identity string
+		handler := ClientCertAuth(logger.New("error", "text"), allowed)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				identity, _ = GetServiceFromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}),
+		)
+		request := httptest.NewRequest(http.MethodGet, "/internal/v1/users/1", nil)
+		request.TLS = state
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code, identity
+	}
+	verified := &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+
+	code, _ := serve(nil, nil)
+	assert.Equal(t, http.StatusUnauthorized, code)
+	code, _ = serve(nil, &tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+	assert.Equal(t, http.StatusUnauthorized, code, "unverified certificates are refused")
+
+	code, identity := serve(nil, verified)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "spiffe://example.org/billing", identity)
+
+	code, identity = serve([]string{"billing"}, verified)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "billing", identity)
+
+	code, _ = serve([]string{"reporting"}, verified)
+	assert.Equal(t, http.StatusForbidden, code)
+}
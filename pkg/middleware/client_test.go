@@ -0,0 +1,29 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientInfo(t *testing.T) {
+	var ip, userAgent string
+	handler := ClientInfo(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ip, userAgent = GetClientFromContext(r.Context())
+	}))
+
+	request := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", nil)
+	request.RemoteAddr = "192.0.2.10:54321"
+	request.Header.Set("User-Agent", "Mozilla/5.0")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+
+	assert.Equal(t, "192.0.2.10", ip)
+	assert.Equal(t, "Mozilla/5.0", userAgent)
+
+	ip, userAgent = GetClientFromContext(context.Background())
+	assert.Empty(t, ip)
+	assert.Empty(t, userAgent)
+}
@@ -0,0 +1,99 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+)
+
+// ConcurrencyLimiter caps the number of requests processed at once.
+// Excess requests either wait in a bounded queue for up to QueueTimeout or are shed immediately.
+type ConcurrencyLimiter struct {
+	slots        chan struct{}
+	queue        chan struct{}
+	queueTimeout time.Duration
+}
+
+// NewConcurrencyLimiter creates a limiter allowing maxInFlight concurrent requests.
+// A zero queueSize or queueTimeout disables queueing so excess requests are rejected right away.
+func NewConcurrencyLimiter(maxInFlight, queueSize int, queueTimeout time.Duration) *ConcurrencyLimiter {
+	limiter := &ConcurrencyLimiter{
+		slots:        make(chan struct{}, maxInFlight),
+		queueTimeout: queueTimeout,
+	}
+	if queueSize > 0 && queueTimeout > 0 {
+		limiter.queue = make(chan struct{}, queueSize)
+	}
+	return limiter
+}
+
+// InFlight returns the number of requests currently being processed
+func (l *ConcurrencyLimiter) InFlight() int {
+	return len(l.slots)
+}
+
+// acquire takes a slot, waiting in the queue when queueing is enabled
+func (l *ConcurrencyLimiter) acquire(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	if l.queue == nil {
+		return false
+	}
+
+	// Bound the number of waiters so a spike can't pile up goroutines
+	select {
+	case l.queue <- struct{}{}:
+	default:
+		return false
+	}
+	defer func() { <-l.queue }()
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// release frees a slot
+func (l *ConcurrencyLimiter) release() {
+	<-l.slots
+}
+
+// ConcurrencyLimit middleware sheds load with a fast 503 once the limiter is saturated.
+// A nil limiter disables the middleware.
+func ConcurrencyLimit(log *logger.Logger, name string, limiter *ConcurrencyLimiter) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !limiter.acquire(r.Context()) {
+				log.WithFields(map[string]interface{}{
+					"group":     name,
+					"in_flight": limiter.InFlight(),
+					"path":      r.URL.Path,
+				}).Warn("Request shed due to concurrency limit")
+				w.Header().Set("Retry-After", "1")
+				utils.WriteErrorResponse(w, http.StatusServiceUnavailable, "Server is busy, please retry", nil)
+				return
+			}
+			defer limiter.release()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// blockingHandler holds requests until released
+func blockingHandler(started chan<- struct{}, release <-chan struct{}) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		started <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+}
+
+func TestConcurrencyLimit_Shed(t *testing.T) {
+	log := logger.New("error", "text")
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handler := ConcurrencyLimit(log, "test", NewConcurrencyLimiter(1, 0, 0))(blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	<-started
+
+	// The slot is taken, so the next request is shed immediately
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+	assert.Equal(t, "1", recorder.Header().Get("Retry-After"))
+
+	close(release)
+	wg.Wait()
+}
+
+func TestConcurrencyLimit_Queue(t *testing.T) {
+	log := logger.New("error", "text")
+	started := make(chan struct{}, 2)
+	release := make(chan struct{})
+	limiter := NewConcurrencyLimiter(1, 1, time.Second)
+	handler := ConcurrencyLimit(log, "test", limiter)(blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	<-started
+
+	// The queued request waits for the slot instead of being shed
+	queued := httptest.NewRecorder()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(queued, httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	assert.Eventually(t, func() bool { return len(limiter.queue) == 1 }, time.Second, time.Millisecond)
+
+	// With the single queue position taken, further requests are shed
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	close(release)
+	wg.Wait()
+	assert.Equal(t, http.StatusOK, queued.Code)
+}
+
+func TestConcurrencyLimit_QueueTimeout(t *testing.T) {
+	log := logger.New("error", "text")
+	started := make(chan struct{}, 1)
+	release := make(chan struct{})
+	handler := ConcurrencyLimit(log, "test", NewConcurrencyLimiter(1, 1, 20*time.Millisecond))(blockingHandler(started, release))
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	}()
+	<-started
+
+	// The queued request gives up once the timeout expires
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+	assert.Equal(t, http.StatusServiceUnavailable, recorder.Code)
+
+	close(release)
+	wg.Wait()
+}
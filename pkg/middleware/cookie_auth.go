@@ -0,0 +1,163 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+)
+
+// Cookies and header of cookie auth mode
+const (
+	AccessTokenCookie  = "access_token"
+	RefreshTokenCookie = "refresh_token"
+	CSRFCookie         = "csrf_token"
+	CSRFHeader         = "X-CSRF-Token"
+)
+
+// refreshCookiePath limits the refresh token cookie to the auth routes that use it
+const refreshCookiePath = "/api/v1/auth"
+
+// AuthCookies hands tokens to browsers in HttpOnly cookies instead of response bodies.
+// Requests authenticated by cookie must echo the CSRF cookie in the X-CSRF-Token header
+// when they change state, since browsers attach cookies to cross-site requests too.
+// A nil *AuthCookies means cookie auth is disabled; all its methods are safe to call on nil.
+type AuthCookies struct {
+	domain     string
+	secure     bool
+	sameSite   http.SameSite
+	refreshTTL time.Duration
+}
+
+// NewAuthCookies creates the cookie settings, or returns nil when cookie auth is disabled
+func NewAuthCookies(cfg *config.Config) *AuthCookies {
+	if !cfg.AuthCookies.Enabled {
+		return nil
+	}
+
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(cfg.AuthCookies.SameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	// The cookie outlives the token; the server enforces the token's own expiry
+	return &AuthCookies{
+		domain:     cfg.AuthCookies.Domain,
+		secure:     cfg.AuthCookies.Secure,
+		sameSite:   sameSite,
+		refreshTTL: max(cfg.JWT.RefreshExpiry, cfg.JWT.RememberMeExpiry),
+	}
+}
+
+// Enabled reports whether cookie auth is on
+func (c *AuthCookies) Enabled() bool {
+	return c != nil
+}
+
+// SetTokens stores the tokens of a login or refresh in cookies, along with a new CSRF token.
+// refreshToken may be empty to keep the current refresh cookie.
+func (c *AuthCookies) SetTokens(w http.ResponseWriter, accessToken string, accessTTL time.Duration, refreshToken string) error {
+	if c == nil {
+		return nil
+	}
+
+	csrfToken, err := utils.GenerateRandomToken(32)
+	if err != nil {
+		return fmt.Errorf("failed to generate CSRF token: %w", err)
+	}
+
+	http.SetCookie(w, c.cookie(AccessTokenCookie, accessToken, "/", accessTTL, true))
+	if refreshToken != "" {
+		http.SetCookie(w, c.cookie(RefreshTokenCookie, refreshToken, refreshCookiePath, c.refreshTTL, true))
+	}
+	// Readable by the frontend, which sends it back in the CSRF header
+	http.SetCookie(w, c.cookie(CSRFCookie, csrfToken, "/", c.refreshTTL, false))
+	return nil
+}
+
+// Clear removes the auth cookies, such as on logout
+func (c *AuthCookies) Clear(w http.ResponseWriter) {
+	if c == nil {
+		return
+	}
+	http.SetCookie(w, c.cookie(AccessTokenCookie, "", "/", -1, true))
+	http.SetCookie(w, c.cookie(RefreshTokenCookie, "", refreshCookiePath, -1, true))
+	http.SetCookie(w, c.cookie(CSRFCookie, "", "/", -1, false))
+}
+
+// AccessToken returns the access token cookie of a request, or an empty string
+func (c *AuthCookies) AccessToken(r *http.Request) string {
+	return c.value(r, AccessTokenCookie)
+}
+
+// RefreshToken returns the refresh token cookie of a request, or an empty string
+func (c *AuthCookies) RefreshToken(r *http.Request) string {
+	return c.value(r, RefreshTokenCookie)
+}
+
+// validCSRF reports whether a request may go ahead: it doesn't change state, or it sends
+// the CSRF cookie back in the CSRF header
+func (c *AuthCookies) validCSRF(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+	expected := c.value(r, CSRFCookie)
+	sent := r.Header.Get(CSRFHeader)
+	return expected != "" && subtle.ConstantTimeCompare([]byte(expected), []byte(sent)) == 1
+}
+
+func (c *AuthCookies) cookie(name, value, path string, ttl time.Duration, httpOnly bool) *http.Cookie {
+	maxAge := int(ttl.Seconds())
+	if ttl < 0 {
+		maxAge = -1
+	}
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     path,
+		Domain:   c.domain,
+		MaxAge:   maxAge,
+		Secure:   c.secure,
+		HttpOnly: httpOnly,
+		SameSite: c.sameSite,
+	}
+}
+
+func (c *AuthCookies) value(r *http.Request, name string) string {
+	if c == nil {
+		return ""
+	}
+	cookie, err := r.Cookie(name)
+	if err != nil {
+		return ""
+	}
+	return cookie.Value
+}
+
+// CSRF middleware protects routes that read the refresh token cookie, such as token refresh.
+// Requests with an Authorization header or without auth cookies are not affected.
+func CSRF(log *logger.Logger, cookies *AuthCookies) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if cookies == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			usesCookies := cookies.AccessToken(r) != "" || cookies.RefreshToken(r) != ""
+			if r.Header.Get("Authorization") == "" && usesCookies && !cookies.validCSRF(r) {
+				log.WithField("path", r.URL.Path).Warn("Missing or invalid CSRF token")
+				utils.WriteErrorResponse(w, http.StatusForbidden, "CSRF token missing or invalid", nil)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,113 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthCookies(t *testing.T) {
+	cfg := &config.Config{
+		JWT:         config.JWTConfig{RefreshExpiry: time.Hour, RememberMeExpiry: 24 * time.Hour},
+		AuthCookies: config.AuthCookieConfig{Enabled: true, Secure: true, SameSite: "strict"},
+	}
+	cookies := NewAuthCookies(cfg)
+	require.NotNil(t, cookies)
+	assert.Nil(t, NewAuthCookies(&config.Config{}))
+
+	recorder := httptest.NewRecorder()
+	require.NoError(t, cookies.SetTokens(recorder, "access", time.Minute, "refresh"))
+	set := map[string]*http.Cookie{}
+	for _, cookie := range recorder.Result().Cookies() {
+		set[cookie.Name] = cookie
+	}
+	require.Len(t, set, 3)
+
+	assert.Equal(t, "access", set[AccessTokenCookie].Value)
+	assert.Equal(t, 60, set[AccessTokenCookie].MaxAge)
+	assert.True(t, set[AccessTokenCookie].HttpOnly)
+	assert.True(t, set[AccessTokenCookie].Secure)
+	assert.Equal(t, http.SameSiteStrictMode, set[AccessTokenCookie].SameSite)
+
+	assert.Equal(t, "refresh", set[RefreshTokenCookie].Value)
+	assert.Equal(t, refreshCookiePath, set[RefreshTokenCookie].Path)
+	assert.Equal(t, 24*60*60, set[RefreshTokenCookie].MaxAge)
+
+	assert.NotEmpty(t, set[CSRFCookie].Value)
+	assert.False(t, set[CSRFCookie].HttpOnly)
+
+	recorder = httptest.NewRecorder()
+	cookies.Clear(recorder)
+	for _, cookie := range recorder.Result().Cookies() {
+		assert.Negative(t, cookie.MaxAge, cookie.Name)
+	}
+}
+
+func TestJWTAuth_Cookies(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	token, err := utils.GenerateJWT(1, "user@example.com", false, keys.Current, time.Hour)
+	require.NoError(t, err)
+	cookies := NewAuthCookies(&config.Config{AuthCookies: config.AuthCookieConfig{Enabled: true}})
+
+	serve := func(cookies *AuthCookies, method, csrfHeader string) int {
+		handler := JWTAuth(logger.New("error", "text"), keys, nil, nil, cookies)(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			}),
+		)
+		request := httptest.NewRequest(method, "/api/v1/users", nil)
+		request.AddCookie(&http.Cookie{Name: AccessTokenCookie, Value: token})
+		request.AddCookie(&http.Cookie{Name: CSRFCookie, Value: "csrf-token"})
+		if csrfHeader != "" {
+			request.Header.Set(CSRFHeader, csrfHeader)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(cookies, http.MethodGet, ""))
+	assert.Equal(t, http.StatusOK, serve(cookies, http.MethodPost, "csrf-token"))
+	assert.Equal(t, http.StatusForbidden, serve(cookies, http.MethodPost, ""))
+	assert.Equal(t, http.StatusForbidden, serve(cookies, http.MethodDelete, "other-token"))
+
+	// Cookies are ignored unless cookie auth is enabled
+	assert.Equal(t, http.StatusUnauthorized, serve(nil, http.MethodGet, ""))
+}
+
+func TestCSRF(t *testing.T) {
+	cookies := NewAuthCookies(&config.Config{AuthCookies: config.AuthCookieConfig{Enabled: true}})
+	handler := CSRF(logger.New("error", "text"), cookies)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	serve := func(setup func(r *http.Request)) int {
+		request := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", nil)
+		setup(request)
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	assert.Equal(t, http.StatusOK, serve(func(r *http.Request) {}))
+	assert.Equal(t, http.StatusForbidden, serve(func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: RefreshTokenCookie, Value: "refresh"})
+	}))
+	assert.Equal(t, http.StatusOK, serve(func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: RefreshTokenCookie, Value: "refresh"})
+		r.AddCookie(&http.Cookie{Name: CSRFCookie, Value: "csrf-token"})
+		r.Header.Set(CSRFHeader, "csrf-token")
+	}))
+	assert.Equal(t, http.StatusOK, serve(func(r *http.Request) {
+		r.AddCookie(&http.Cookie{Name: RefreshTokenCookie, Value: "refresh"})
+		r.Header.Set("Authorization", "Bearer token")
+	}))
+}
@@ -0,0 +1,132 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+)
+
+// OpenAPIValidator matches requests to the operations of an OpenAPI document
+type OpenAPIValidator struct {
+	router routers.Router
+}
+
+// OpenAPIFieldError describes one part of a request that does not match the spec
+type OpenAPIFieldError struct {
+	In     string `json:"in"`              // path, query, header, cookie or body
+	Field  string `json:"field,omitempty"` // Parameter name or dotted path into the body
+	Reason string `json:"reason"`
+}
+
+// NewOpenAPIValidator parses and validates an OpenAPI 3 document
+func NewOpenAPIValidator(spec []byte) (*OpenAPIValidator, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI spec: %w", err)
+	}
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build OpenAPI router: %w", err)
+	}
+	return &OpenAPIValidator{router: router}, nil
+}
+
+// Validate checks the parameters and body of a request against its operation in the spec.
+// Requests for operations the spec does not describe are not checked.
+func (v *OpenAPIValidator) Validate(r *http.Request) []OpenAPIFieldError {
+	// chi serves /users and /users/ alike, the spec only lists the former
+	lookup := r
+	if path := r.URL.Path; len(path) > 1 && strings.HasSuffix(path, "/") {
+		u := *r.URL
+		u.Path = strings.TrimSuffix(path, "/")
+		lookup = r.Clone(r.Context())
+		lookup.URL = &u
+	}
+
+	route, pathParams, err := v.router.FindRoute(lookup)
+	if err != nil {
+		return nil
+	}
+
+	input := &openapi3filter.RequestValidationInput{
+		Request:    r,
+		PathParams: pathParams,
+		Route:      route,
+		Options: &openapi3filter.Options{
+			MultiError:          true,
+			SkipSettingDefaults: true,                                  // Pass the body through untouched
+			AuthenticationFunc:  openapi3filter.NoopAuthenticationFunc, // Authentication is enforced by the route middleware
+		},
+	}
+	if err := openapi3filter.ValidateRequest(r.Context(), input); err != nil {
+		return collectOpenAPIErrors(err, "body", "", nil)
+	}
+	return nil
+}
+
+// collectOpenAPIErrors flattens the nested errors returned by openapi3filter
+func collectOpenAPIErrors(err error, in, field string, out []OpenAPIFieldError) []OpenAPIFieldError {
+	switch e := err.(type) {
+	case openapi3.MultiError:
+		for _, inner := range e {
+			out = collectOpenAPIErrors(inner, in, field, out)
+		}
+		return out
+	case *openapi3filter.RequestError:
+		in, field = "body", ""
+		if e.Parameter != nil {
+			in, field = e.Parameter.In, e.Parameter.Name
+		}
+		switch e.Err.(type) {
+		case openapi3.MultiError, *openapi3.SchemaError:
+			return collectOpenAPIErrors(e.Err, in, field, out)
+		}
+		reason := e.Reason
+		if e.Err != nil {
+			if reason == "" {
+				reason = e.Err.Error()
+			} else {
+				reason += ": " + e.Err.Error()
+			}
+		}
+		return append(out, OpenAPIFieldError{In: in, Field: field, Reason: reason})
+	case *openapi3.SchemaError:
+		if pointer := e.JSONPointer(); len(pointer) > 0 {
+			field = strings.Join(pointer, ".")
+		}
+		return append(out, OpenAPIFieldError{In: in, Field: field, Reason: e.Reason})
+	}
+	return append(out, OpenAPIFieldError{In: in, Field: field, Reason: err.Error()})
+}
+
+// OpenAPIValidation rejects requests that do not match the OpenAPI spec with a 400 listing every mismatch
+func OpenAPIValidation(log *logger.Logger, validator *OpenAPIValidator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if fieldErrors := validator.Validate(r); len(fieldErrors) > 0 {
+				log.WithFields(map[string]interface{}{
+					"method": r.Method,
+					"path":   r.URL.Path,
+					"errors": len(fieldErrors),
+				}).Warn("Request does not match OpenAPI spec")
+				utils.WriteErrorResponse(w, http.StatusBadRequest, "Validation failed", fieldErrors)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
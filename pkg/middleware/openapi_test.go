@@ -0,0 +1,141 @@
+package middleware
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"gbt-be-template/api"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIValidation(t *testing.T) {
+	validator, err := NewOpenAPIValidator(api.Spec())
+	require.NoError(t, err)
+
+	var received string
+	handler := OpenAPIValidation(logger.New("error", "text"), validator)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received = string(body)
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	serve := func(method, target, body string) *httptest.ResponseRecorder {
+		var reader io.Reader
+		if body != "" {
+			reader = strings.NewReader(body)
+		}
+		request := httptest.NewRequest(method, target, reader)
+		if body != "" {
+			request.Header.Set("Content-Type", "application/json")
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	t.Run("valid request reaches the handler with its body", func(t *testing.T) {
+		body := `{"email":"john@example.com","username":"john","password":"secret1","first_name":"John","last_name":"Doe"}`
+		recorder := serve(http.MethodPost, "/api/v1/auth/register", body)
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.JSONEq(t, body, received)
+	})
+
+	t.Run("patch media types are validated", func(t *testing.T) {
+		request := httptest.NewRequest(http.MethodPatch, "/api/v1/users/1", strings.NewReader(`{"phone":null,"metadata":{"team":null}}`))
+		request.Header.Set("Content-Type", "application/merge-patch+json")
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		request = httptest.NewRequest(http.MethodPatch, "/api/v1/users/1", strings.NewReader(`[{"op":"rename","path":"/email"}]`))
+		request.Header.Set("Content-Type", "application/json-patch+json")
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	t.Run("routes missing from the spec are not checked", func(t *testing.T) {
+		recorder := serve(http.MethodGet, "/api/v1/media/avatars/1/small.webp", "")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+	})
+
+	t.Run("trailing slash matches the spec path", func(t *testing.T) {
+		recorder := serve(http.MethodGet, "/api/v1/users/?limit=500", "")
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	})
+
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		errors []OpenAPIFieldError
+	}{
+		{
+			name:   "body field violations",
+			method: http.MethodPost,
+			target: "/api/v1/auth/register",
+			body:   `{"email":"john@example.com","username":"jo","password":"secret1","first_name":"John","last_name":"Doe"}`,
+			errors: []OpenAPIFieldError{{In: "body", Field: "username"}},
+		},
+		{
+			name:   "invalid path parameter",
+			method: http.MethodGet,
+			target: "/api/v1/users/abc",
+			errors: []OpenAPIFieldError{{In: "path", Field: "id"}},
+		},
+		{
+			name:   "query parameter out of range",
+			method: http.MethodGet,
+			target: "/api/v1/users?page=0",
+			errors: []OpenAPIFieldError{{In: "query", Field: "page"}},
+		},
+		{
+			name:   "missing body",
+			method: http.MethodPost,
+			target: "/api/v1/auth/login",
+			errors: []OpenAPIFieldError{{In: "body"}},
+		},
+		{
+			name:   "enum violation",
+			method: http.MethodPost,
+			target: "/api/v1/uploads",
+			body:   `{"filename":"a.png","size":10,"purpose":"backup"}`,
+			errors: []OpenAPIFieldError{{In: "body", Field: "purpose"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := serve(tt.method, tt.target, tt.body)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code)
+
+			var response struct {
+				Success bool                `json:"success"`
+				Error   []OpenAPIFieldError `json:"error"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &response))
+			assert.False(t, response.Success)
+			require.Len(t, response.Error, len(tt.errors))
+			for i, expected := range tt.errors {
+				assert.Equal(t, expected.In, response.Error[i].In)
+				assert.Equal(t, expected.Field, response.Error[i].Field)
+				assert.NotEmpty(t, response.Error[i].Reason)
+			}
+		})
+	}
+}
+
+func TestNewOpenAPIValidator_InvalidSpec(t *testing.T) {
+	_, err := NewOpenAPIValidator([]byte("openapi: 3.0.3\ninfo: {}\npaths: {}\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/ratelimit"
+	"gbt-be-template/pkg/utils"
+)
+
+// nearLimitRatio is the fraction of the limit left at which rate limit headers start being sent
+const nearLimitRatio = 0.2
+
+// RateLimitKeyFunc derives the rate limit bucket for a request
+type RateLimitKeyFunc func(r *http.Request) string
+
+// RateLimitByIP buckets requests by client IP
+func RateLimitByIP(r *http.Request) string {
+	return "ip:" + getClientIP(r)
+}
+
+// RateLimitByUser buckets requests by authenticated user, falling back to client IP
+func RateLimitByUser(r *http.Request) string {
+	if userID, ok := GetUserIDFromContext(r.Context()); ok {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return RateLimitByIP(r)
+}
+
+// Throttle applies a named rate limit policy. Each policy counts in its own buckets.
+// Policies keyed by user must be attached after authentication middleware.
+func Throttle(log *logger.Logger, limiter ratelimit.Limiter, policy config.RateLimitPolicy) func(http.Handler) http.Handler {
+	if policy.Unlimited {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	keyFunc := RateLimitByIP
+	if policy.Key == "user" {
+		keyFunc = RateLimitByUser
+	}
+
+	return RateLimit(log, limiter, policy.Requests, policy.Window, func(r *http.Request) string {
+		return policy.Name + ":" + keyFunc(r)
+	})
+}
+
+// RateLimit middleware limits requests per key within a window.
+// RateLimit-* headers are sent on throttled and near-limit responses, and Retry-After on 429s.
+func RateLimit(log *logger.Logger, limiter ratelimit.Limiter, limit int, window time.Duration, keyFunc RateLimitKeyFunc) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := keyFunc(r)
+			result, err := limiter.Allow(r.Context(), key, limit, window)
+			if err != nil {
+				// Fail open: an unavailable limiter should not take the API down
+				log.WithError(err).WithField("key", key).Error("Rate limiter unavailable")
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if !result.Allowed {
+				SetRateLimitHeaders(w, result)
+				w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(result.Reset)))
+				log.WithField("key", key).Warn("Rate limit exceeded")
+				utils.WriteErrorResponse(w, http.StatusTooManyRequests, "Too many requests", nil)
+				return
+			}
+
+			if float64(result.Remaining) <= float64(result.Limit)*nearLimitRatio {
+				SetRateLimitHeaders(w, result)
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// SetRateLimitHeaders writes the standard RateLimit-Limit, RateLimit-Remaining and RateLimit-Reset headers
+func SetRateLimitHeaders(w http.ResponseWriter, result ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", strconv.Itoa(result.Limit))
+	w.Header().Set("RateLimit-Remaining", strconv.Itoa(result.Remaining))
+	w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(result.Reset)))
+}
+
+// ceilSeconds rounds a duration up to whole seconds so clients never retry early
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gbt-be-template/internal/config"
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/ratelimit"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	log := logger.New("error", "text")
+	handler := RateLimit(log, ratelimit.NewMemoryLimiter(), 5, time.Minute, RateLimitByIP)(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+	)
+
+	serve := func() *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.RemoteAddr = "192.0.2.1"
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder
+	}
+
+	// Requests well below the limit carry no headers
+	recorder := serve()
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Empty(t, recorder.Header().Get("RateLimit-Limit"))
+
+	for i := 0; i < 3; i++ {
+		recorder = serve()
+	}
+
+	// Near the limit the headers are sent
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "5", recorder.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", recorder.Header().Get("RateLimit-Remaining"))
+	assert.Empty(t, recorder.Header().Get("Retry-After"))
+
+	serve()
+	recorder = serve()
+
+	// Throttled responses include Retry-After
+	assert.Equal(t, http.StatusTooManyRequests, recorder.Code)
+	assert.Equal(t, "0", recorder.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "60", recorder.Header().Get("Retry-After"))
+}
+
+func TestThrottle(t *testing.T) {
+	log := logger.New("error", "text")
+	limiter := ratelimit.NewMemoryLimiter()
+	ok := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	serve := func(handler http.Handler, userID uint) int {
+		request := httptest.NewRequest(http.MethodGet, "/", nil)
+		request.RemoteAddr = "192.0.2.1"
+		if userID != 0 {
+			request = request.WithContext(context.WithValue(request.Context(), UserIDKey, userID))
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder.Code
+	}
+
+	t.Run("unlimited policy never throttles", func(t *testing.T) {
+		handler := Throttle(log, limiter, config.RateLimitPolicy{Name: "admin", Unlimited: true})(ok)
+		for i := 0; i < 10; i++ {
+			assert.Equal(t, http.StatusOK, serve(handler, 1))
+		}
+	})
+
+	t.Run("user policy counts per user", func(t *testing.T) {
+		handler := Throttle(log, limiter, config.RateLimitPolicy{Name: "read", Requests: 1, Window: time.Minute, Key: "user"})(ok)
+		assert.Equal(t, http.StatusOK, serve(handler, 1))
+		assert.Equal(t, http.StatusTooManyRequests, serve(handler, 1))
+
+		// Another user behind the same IP has their own bucket
+		assert.Equal(t, http.StatusOK, serve(handler, 2))
+	})
+
+	t.Run("policies count separately", func(t *testing.T) {
+		handler := Throttle(log, limiter, config.RateLimitPolicy{Name: "write", Requests: 1, Window: time.Minute, Key: "user"})(ok)
+		assert.Equal(t, http.StatusOK, serve(handler, 1))
+	})
+}
@@ -0,0 +1,86 @@
+package middleware
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/tenant"
+	"gbt-be-template/pkg/utils"
+)
+
+// TenantNamedKey is the context key recording that the request itself named its tenant
+const TenantNamedKey ContextKey = "tenant_named"
+
+// ResolveTenant middleware scopes each request to the tenant named by the header, or else by
+// the subdomain of baseDomain the request was sent to. Requests naming neither belong to the
+// default tenant. Either source is skipped when empty. Invalid tenant IDs are refused with 400.
+func ResolveTenant(log *logger.Logger, header, baseDomain string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			id := ""
+			if header != "" {
+				id = r.Header.Get(header)
+			}
+			if id == "" && baseDomain != "" {
+				id = subdomain(r.Host, baseDomain)
+			}
+			if id != "" && !tenant.ValidID(id) {
+				log.WithField("tenant_id", id).Warn("Invalid tenant ID")
+				utils.WriteErrorResponse(w, http.StatusBadRequest, "Invalid tenant", nil)
+				return
+			}
+
+			ctx := tenant.WithID(r.Context(), id)
+			ctx = context.WithValue(ctx, TenantNamedKey, id != "")
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// TenantFromClaim middleware, used after authentication, scopes requests to the tenant in the
+// claim of the access token. Tokens of another tenant than the one the request named are
+// refused with 403. Tokens without the claim keep the tenant of the request.
+func TenantFromClaim(log *logger.Logger, claim string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			value, ok := GetClaimFromContext(r.Context(), claim)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			id, _ := value.(string)
+			current, _ := tenant.FromContext(r.Context())
+			named, _ := r.Context().Value(TenantNamedKey).(bool)
+			if named && id != current {
+				userID, _ := GetUserIDFromContext(r.Context())
+				log.Security("cross_tenant_request", userID).WithFields(map[string]interface{}{
+					"tenant_id":       current,
+					"token_tenant_id": id,
+					"path":            r.URL.Path,
+				}).Warn("Token used for another tenant")
+				utils.WriteErrorResponse(w, http.StatusForbidden, "Token belongs to another tenant", nil)
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(tenant.WithID(r.Context(), id)))
+		})
+	}
+}
+
+// subdomain returns the label in front of baseDomain in host, or "" when host isn't a direct
+// subdomain of it
+func subdomain(host, baseDomain string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(host)
+	label, ok := strings.CutSuffix(host, "."+strings.ToLower(baseDomain))
+	if !ok || strings.Contains(label, ".") {
+		return ""
+	}
+	return label
+}
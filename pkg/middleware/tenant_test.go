@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"gbt-be-template/pkg/logger"
+	"gbt-be-template/pkg/tenant"
+	"gbt-be-template/pkg/utils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolveTenant(t *testing.T) {
+	serve := func(host, header string) (*httptest.ResponseRecorder, string, bool) {
+		var tenantID string
+		var scoped bool
+		handler := ResolveTenant(logger.New("error", "text"), "X-Tenant-ID", "example.com")(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenantID, scoped = tenant.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}),
+		)
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		request.Host = host
+		if header != "" {
+			request.Header.Set("X-Tenant-ID", header)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder, tenantID, scoped
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		header string
+		code   int
+		tenant string
+	}{
+		{"subdomain", "acme.example.com:8080", "", http.StatusOK, "acme"},
+		{"header before subdomain", "acme.example.com", "globex", http.StatusOK, "globex"},
+		{"base domain is the default tenant", "example.com", "", http.StatusOK, ""},
+		{"nested subdomain is ignored", "api.acme.example.com", "", http.StatusOK, ""},
+		{"other domain is ignored", "acme.example.org", "", http.StatusOK, ""},
+		{"invalid tenant", "example.com", "../acme", http.StatusBadRequest, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, tenantID, scoped := serve(tt.host, tt.header)
+			assert.Equal(t, tt.code, recorder.Code)
+			if tt.code == http.StatusOK {
+				assert.True(t, scoped)
+				assert.Equal(t, tt.tenant, tenantID)
+			}
+		})
+	}
+}
+
+func TestTenantFromClaim(t *testing.T) {
+	keys := utils.JWTKeys{Current: utils.JWTKey{ID: "v1", Secret: "test-secret"}}
+	log := logger.New("error", "text")
+	token, err := utils.GenerateJWTWithClaims(1, "user@example.com", false, map[string]interface{}{"tenant_id": "acme"}, keys.Current, time.Minute)
+	require.NoError(t, err)
+
+	serve := func(header string) (*httptest.ResponseRecorder, string) {
+		var tenantID string
+		handler := ResolveTenant(log, "X-Tenant-ID", "")(JWTAuth(log, keys, nil, nil, nil)(TenantFromClaim(log, "tenant_id")(
+			http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenantID, _ = tenant.FromContext(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}),
+		)))
+		request := httptest.NewRequest(http.MethodGet, "/api/v1/users", nil)
+		request.Header.Set("Authorization", "Bearer "+token)
+		if header != "" {
+			request.Header.Set("X-Tenant-ID", header)
+		}
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		return recorder, tenantID
+	}
+
+	t.Run("claim scopes requests naming no tenant", func(t *testing.T) {
+		recorder, tenantID := serve("")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "acme", tenantID)
+	})
+
+	t.Run("same tenant is allowed", func(t *testing.T) {
+		recorder, tenantID := serve("acme")
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Equal(t, "acme", tenantID)
+	})
+
+	t.Run("token of another tenant is refused", func(t *testing.T) {
+		recorder, _ := serve("globex")
+		assert.Equal(t, http.StatusForbidden, recorder.Code)
+	})
+}
@@ -0,0 +1,185 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ProviderMetadata holds the subset of the OpenID Provider discovery document we use
+type ProviderMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Discover fetches the discovery document of an issuer
+func Discover(ctx context.Context, client *http.Client, issuer string) (*ProviderMetadata, error) {
+	wellKnown := strings.TrimRight(issuer, "/") + "/.well-known/openid-configuration"
+
+	var metadata ProviderMetadata
+	if err := getJSON(ctx, client, wellKnown, &metadata); err != nil {
+		return nil, fmt.Errorf("failed to fetch discovery document: %w", err)
+	}
+
+	// The issuer in the document must match the one we were configured with
+	if strings.TrimRight(metadata.Issuer, "/") != strings.TrimRight(issuer, "/") {
+		return nil, fmt.Errorf("issuer mismatch: expected %q, got %q", issuer, metadata.Issuer)
+	}
+	if metadata.JWKSURI == "" {
+		return nil, errors.New("discovery document has no jwks_uri")
+	}
+
+	return &metadata, nil
+}
+
+// Claims is a verified set of token claims
+type Claims map[string]interface{}
+
+// String returns a string claim or an empty string
+func (c Claims) String(key string) string {
+	value, _ := c[key].(string)
+	return value
+}
+
+// Bool returns a boolean claim, accepting the "true" string form some IdPs emit
+func (c Claims) Bool(key string) bool {
+	switch value := c[key].(type) {
+	case bool:
+		return value
+	case string:
+		return value == "true"
+	}
+	return false
+}
+
+// Strings returns a claim that may be a single string or an array of strings
+func (c Claims) Strings(key string) []string {
+	switch value := c[key].(type) {
+	case string:
+		return []string{value}
+	case []interface{}:
+		result := make([]string, 0, len(value))
+		for _, item := range value {
+			if s, ok := item.(string); ok {
+				result = append(result, s)
+			}
+		}
+		return result
+	}
+	return nil
+}
+
+// Verifier validates tokens issued by a single OpenID Provider
+type Verifier struct {
+	issuer   string
+	audience string
+	client   *http.Client
+
+	mu   sync.Mutex
+	keys *KeySet
+}
+
+// NewVerifier creates a verifier for an issuer.
+// Discovery happens lazily on first use so an unavailable IdP doesn't block startup.
+func NewVerifier(issuer, audience string, client *http.Client) *Verifier {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &Verifier{
+		issuer:   issuer,
+		audience: audience,
+		client:   client,
+	}
+}
+
+// Issuer returns the issuer this verifier accepts
+func (v *Verifier) Issuer() string {
+	return v.issuer
+}
+
+// Verify checks a token's signature, issuer, audience and lifetime and returns its claims
+func (v *Verifier) Verify(ctx context.Context, rawToken string) (Claims, error) {
+	keys, err := v.keySet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512"}),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(30 * time.Second),
+	}
+	if v.audience != "" {
+		options = append(options, jwt.WithAudience(v.audience))
+	}
+
+	claims := jwt.MapClaims{}
+	_, err = jwt.NewParser(options...).ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return keys.Key(ctx, kid)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return Claims(claims), nil
+}
+
+// keySet returns the issuer's key set, running discovery on first use
+func (v *Verifier) keySet(ctx context.Context) (*KeySet, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if v.keys != nil {
+		return v.keys, nil
+	}
+
+	metadata, err := Discover(ctx, v.client, v.issuer)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = NewKeySet(metadata.JWKSURI, v.client)
+	return v.keys, nil
+}
+
+// UnverifiedIssuer reads the iss claim of a token without verifying it.
+// It is only used to pick which verifier to run.
+func UnverifiedIssuer(rawToken string) (string, error) {
+	claims := jwt.MapClaims{}
+	if _, _, err := jwt.NewParser().ParseUnverified(rawToken, claims); err != nil {
+		return "", err
+	}
+	return claims.GetIssuer()
+}
+
+// getJSON performs a GET request and decodes a JSON response
+func getJSON(ctx context.Context, client *http.Client, url string, target interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, url)
+	}
+
+	return json.NewDecoder(resp.Body).Decode(target)
+}
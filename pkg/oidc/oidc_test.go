@@ -0,0 +1,116 @@
+package oidc
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupProvider(t *testing.T, key *rsa.PrivateKey) *httptest.Server {
+	mux := http.NewServeMux()
+	server := httptest.NewServer(mux)
+
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":   server.URL,
+			"jwks_uri": server.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"keys": []map[string]string{{
+				"kid": "key-1",
+				"kty": "RSA",
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			}},
+		})
+	})
+
+	t.Cleanup(server.Close)
+	return server
+}
+
+func signToken(t *testing.T, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "key-1"
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+func TestVerifier_Verify(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	server := setupProvider(t, key)
+	verifier := NewVerifier(server.URL, "my-api", nil)
+	ctx := context.Background()
+
+	t.Run("valid token", func(t *testing.T) {
+		token := signToken(t, key, jwt.MapClaims{
+			"iss":            server.URL,
+			"aud":            "my-api",
+			"sub":            "external-user",
+			"email":          "user@example.com",
+			"email_verified": true,
+			"groups":         []string{"staff", "admins"},
+			"exp":            time.Now().Add(time.Hour).Unix(),
+		})
+
+		claims, err := verifier.Verify(ctx, token)
+		require.NoError(t, err)
+		assert.Equal(t, "external-user", claims.String("sub"))
+		assert.True(t, claims.Bool("email_verified"))
+		assert.Equal(t, []string{"staff", "admins"}, claims.Strings("groups"))
+	})
+
+	t.Run("wrong audience", func(t *testing.T) {
+		token := signToken(t, key, jwt.MapClaims{
+			"iss": server.URL,
+			"aud": "other-api",
+			"sub": "external-user",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+
+		_, err := verifier.Verify(ctx, token)
+		assert.Error(t, err)
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		token := signToken(t, key, jwt.MapClaims{
+			"iss": server.URL,
+			"aud": "my-api",
+			"sub": "external-user",
+			"exp": time.Now().Add(-time.Hour).Unix(),
+		})
+
+		_, err := verifier.Verify(ctx, token)
+		assert.Error(t, err)
+	})
+
+	t.Run("signed by unknown key", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		token := signToken(t, otherKey, jwt.MapClaims{
+			"iss": server.URL,
+			"aud": "my-api",
+			"sub": "external-user",
+			"exp": time.Now().Add(time.Hour).Unix(),
+		})
+
+		_, err = verifier.Verify(ctx, token)
+		assert.Error(t, err)
+	})
+}
@@ -0,0 +1,78 @@
+// Package pwned checks passwords against the Pwned Passwords service of Have I Been Pwned.
+// Only the first five characters of the password's SHA-1 hash leave the process; the service
+// answers with every hash suffix under that prefix, and the match is made locally.
+package pwned
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultBaseURL = "https://api.pwnedpasswords.com"
+
+// Checker reports how often a password appears in known data breaches
+type Checker interface {
+	Count(ctx context.Context, password string) (int, error)
+}
+
+// Client queries the Pwned Passwords range API
+type Client struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewClient creates a client giving up on the service after timeout
+func NewClient(timeout time.Duration) *Client {
+	return &Client{
+		baseURL: defaultBaseURL,
+		client:  &http.Client{Timeout: timeout},
+	}
+}
+
+// Count returns how many times the password was seen in breaches, 0 for passwords never seen
+func (c *Client) Count(ctx context.Context, password string) (int, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/range/"+prefix, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create request: %w", err)
+	}
+	// Padding hides the number of suffixes, and with it the prefix, from anyone watching the traffic
+	req.Header.Set("Add-Padding", "true")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("failed to reach Pwned Passwords: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("unexpected status %d from Pwned Passwords", resp.StatusCode)
+	}
+
+	// Lines are SUFFIX:COUNT; padding lines have a count of 0
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		candidate, count, ok := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !ok || !strings.EqualFold(candidate, suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(count)
+		if err != nil {
+			return 0, fmt.Errorf("invalid count in Pwned Passwords response: %w", err)
+		}
+		return n, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read Pwned Passwords response: %w", err)
+	}
+	return 0, nil
+}
@@ -0,0 +1,42 @@
+package pwned
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient_Count(t *testing.T) {
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "true", r.Header.Get("Add-Padding"))
+		if r.URL.Path != "/range/5BAA6" {
+			w.WriteHeader(http.StatusOK)
+			_, _ = w.Write([]byte("0018A45C4D1DEF81644B54AB7F969B88D65:0\r\n"))
+			return
+		}
+		_, _ = w.Write([]byte("003D68EB55068C33ACE09247EE4C639306B:3\r\n1E4C9B93F3F0682250B6CF8331B7EE68FD8:9659365\r\n"))
+	}))
+	defer server.Close()
+
+	client := NewClient(time.Second)
+	client.baseURL = server.URL
+	ctx := context.Background()
+
+	count, err := client.Count(ctx, "password")
+	require.NoError(t, err)
+	assert.Equal(t, 9659365, count)
+
+	count, err = client.Count(ctx, "correct horse battery staple 42")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	server.Close()
+	_, err = client.Count(ctx, "password")
+	assert.Error(t, err)
+}
@@ -0,0 +1,83 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Result describes the state of a rate limit window after a request was counted
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the current window ends
+	Reset time.Duration
+}
+
+// Limiter counts requests per key within a fixed window
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit int, window time.Duration) (Result, error)
+}
+
+// window tracks the request count of a single key
+type window struct {
+	count   int
+	resetAt time.Time
+}
+
+// MemoryLimiter is an in-process fixed window limiter.
+// Counts are not shared between instances.
+type MemoryLimiter struct {
+	mu        sync.Mutex
+	windows   map[string]*window
+	nextSweep time.Time
+}
+
+// NewMemoryLimiter creates an in-memory limiter
+func NewMemoryLimiter() *MemoryLimiter {
+	return &MemoryLimiter{
+		windows: make(map[string]*window),
+	}
+}
+
+// Allow counts a request for key and reports whether it is within the limit
+func (l *MemoryLimiter) Allow(ctx context.Context, key string, limit int, windowSize time.Duration) (Result, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	l.sweep(now, windowSize)
+
+	w, ok := l.windows[key]
+	if !ok || !now.Before(w.resetAt) {
+		w = &window{resetAt: now.Add(windowSize)}
+		l.windows[key] = w
+	}
+	w.count++
+
+	remaining := limit - w.count
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	return Result{
+		Allowed:   w.count <= limit,
+		Limit:     limit,
+		Remaining: remaining,
+		Reset:     w.resetAt.Sub(now),
+	}, nil
+}
+
+// sweep drops expired windows so idle keys don't accumulate
+func (l *MemoryLimiter) sweep(now time.Time, interval time.Duration) {
+	if now.Before(l.nextSweep) {
+		return
+	}
+	for key, w := range l.windows {
+		if !now.Before(w.resetAt) {
+			delete(l.windows, key)
+		}
+	}
+	l.nextSweep = now.Add(interval)
+}
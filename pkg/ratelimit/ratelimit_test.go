@@ -0,0 +1,51 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryLimiter_Allow(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	ctx := context.Background()
+
+	for i := 1; i <= 3; i++ {
+		result, err := limiter.Allow(ctx, "client", 3, time.Minute)
+		require.NoError(t, err)
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 3-i, result.Remaining)
+	}
+
+	// The fourth request exceeds the limit
+	result, err := limiter.Allow(ctx, "client", 3, time.Minute)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+	assert.Equal(t, 0, result.Remaining)
+	assert.True(t, result.Reset > 0 && result.Reset <= time.Minute)
+
+	// Other keys have their own window
+	result, err = limiter.Allow(ctx, "other", 3, time.Minute)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
+
+func TestMemoryLimiter_WindowReset(t *testing.T) {
+	limiter := NewMemoryLimiter()
+	ctx := context.Background()
+
+	_, err := limiter.Allow(ctx, "client", 1, 20*time.Millisecond)
+	require.NoError(t, err)
+	result, err := limiter.Allow(ctx, "client", 1, 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.False(t, result.Allowed)
+
+	time.Sleep(30 * time.Millisecond)
+
+	result, err = limiter.Allow(ctx, "client", 1, 20*time.Millisecond)
+	require.NoError(t, err)
+	assert.True(t, result.Allowed)
+}
@@ -0,0 +1,108 @@
+package revocation
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepEvery is the number of revocations between removals of expired entries
+const sweepEvery = 1000
+
+// MemoryStore is an in-process Store for single instance deployments and tests.
+// Revocations are lost on restart and not shared between instances.
+type MemoryStore struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+	users   map[uint]userRevocation
+	writes  int
+	clock   func() time.Time
+}
+
+// userRevocation revokes the tokens of a user issued up to before
+type userRevocation struct {
+	before    time.Time
+	expiresAt time.Time
+}
+
+// NewMemoryStore creates an in-process revocation store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{revoked: make(map[string]time.Time), users: make(map[uint]userRevocation), clock: time.Now}
+}
+
+// Revoke marks the token as revoked until expiresAt
+func (s *MemoryStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	if !expiresAt.After(now) {
+		return nil
+	}
+	s.revoked[tokenID] = expiresAt
+	s.sweep(now)
+	return nil
+}
+
+// IsRevoked reports whether the token was revoked and has not expired yet
+func (s *MemoryStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	expires, ok := s.revoked[tokenID]
+	return ok && expires.After(s.clock()), nil
+}
+
+// RevokeUser marks the tokens of the user issued up to before as revoked until expiresAt
+func (s *MemoryStore) RevokeUser(ctx context.Context, userID uint, before, expiresAt time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.clock()
+	if !expiresAt.After(now) {
+		return nil
+	}
+	// A later revocation covers the tokens of an earlier one
+	if current, ok := s.users[userID]; ok && current.expiresAt.After(now) {
+		if current.before.After(before) {
+			before = current.before
+		}
+		if current.expiresAt.After(expiresAt) {
+			expiresAt = current.expiresAt
+		}
+	}
+	s.users[userID] = userRevocation{before: before, expiresAt: expiresAt}
+	s.sweep(now)
+	return nil
+}
+
+// RevokedBefore returns the time up to which the user's tokens are revoked
+func (s *MemoryStore) RevokedBefore(ctx context.Context, userID uint) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	revocation, ok := s.users[userID]
+	if !ok || !revocation.expiresAt.After(s.clock()) {
+		return time.Time{}, nil
+	}
+	return revocation.before, nil
+}
+
+// sweep drops expired entries now and then so the maps do not grow without bound.
+// The caller holds the lock.
+func (s *MemoryStore) sweep(now time.Time) {
+	s.writes++
+	if s.writes%sweepEvery != 0 {
+		return
+	}
+	for id, expires := range s.revoked {
+		if !expires.After(now) {
+			delete(s.revoked, id)
+		}
+	}
+	for userID, revocation := range s.users {
+		if !revocation.expiresAt.After(now) {
+			delete(s.users, userID)
+		}
+	}
+}
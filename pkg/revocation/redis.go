@@ -0,0 +1,86 @@
+package revocation
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisStore is a Store shared by all instances through Redis, where each
+// revocation is a key expiring with its token.
+//
+// Revocations are also kept in memory, so while Redis is unreachable tokens
+// revoked through this instance are still rejected by it.
+type RedisStore struct {
+	client redis.Cmdable
+	prefix string
+	local  *MemoryStore
+}
+
+// NewRedisStore creates a revocation store on the given Redis client
+func NewRedisStore(client redis.Cmdable) *RedisStore {
+	return &RedisStore{client: client, prefix: "revoked:", local: NewMemoryStore()}
+}
+
+// Revoke marks the token as revoked until expiresAt
+func (s *RedisStore) Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	_ = s.local.Revoke(ctx, tokenID, expiresAt)
+	return s.client.Set(ctx, s.prefix+tokenID, 1, ttl).Err()
+}
+
+// IsRevoked reports whether the token was revoked. When Redis fails it returns
+// the answer of the in-memory copy together with the error.
+func (s *RedisStore) IsRevoked(ctx context.Context, tokenID string) (bool, error) {
+	if revoked, _ := s.local.IsRevoked(ctx, tokenID); revoked {
+		return true, nil
+	}
+
+	err := s.client.Get(ctx, s.prefix+tokenID).Err()
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, redis.Nil):
+		return false, nil
+	}
+	return false, err
+}
+
+// RevokeUser marks the tokens of the user issued up to before as revoked until expiresAt
+func (s *RedisStore) RevokeUser(ctx context.Context, userID uint, before, expiresAt time.Time) error {
+	ttl := time.Until(expiresAt)
+	if ttl <= 0 {
+		return nil
+	}
+	_ = s.local.RevokeUser(ctx, userID, before, expiresAt)
+	return s.client.Set(ctx, s.userKey(userID), before.UnixNano(), ttl).Err()
+}
+
+// RevokedBefore returns the time up to which the user's tokens are revoked. When
+// Redis fails it returns the answer of the in-memory copy together with the error.
+func (s *RedisStore) RevokedBefore(ctx context.Context, userID uint) (time.Time, error) {
+	local, _ := s.local.RevokedBefore(ctx, userID)
+
+	nanos, err := s.client.Get(ctx, s.userKey(userID)).Int64()
+	switch {
+	case errors.Is(err, redis.Nil):
+		return local, nil
+	case err != nil:
+		return local, err
+	}
+	if before := time.Unix(0, nanos); before.After(local) {
+		return before, nil
+	}
+	return local, nil
+}
+
+// userKey returns the key of the revocation of a user's tokens
+func (s *RedisStore) userKey(userID uint) string {
+	return s.prefix + "user:" + strconv.FormatUint(uint64(userID), 10)
+}
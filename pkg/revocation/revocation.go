@@ -0,0 +1,28 @@
+// Package revocation records access tokens that were revoked before they
+// expired, such as on logout, so authentication can reject them. Besides
+// single tokens, every token a user was issued up to some time can be revoked
+// at once, such as when the user is signed out of all sessions.
+//
+// Entries only need to outlive the token they revoke, so each one expires
+// together with its token and the store stays as small as the set of revoked
+// tokens that are still valid.
+package revocation
+
+import (
+	"context"
+	"time"
+)
+
+// Store records revoked token IDs until the tokens expire
+type Store interface {
+	// Revoke marks the token as revoked until expiresAt
+	Revoke(ctx context.Context, tokenID string, expiresAt time.Time) error
+	// IsRevoked reports whether the token was revoked
+	IsRevoked(ctx context.Context, tokenID string) (bool, error)
+	// RevokeUser marks the tokens of the user issued up to before as revoked
+	// until expiresAt, when the last of them expires
+	RevokeUser(ctx context.Context, userID uint, before, expiresAt time.Time) error
+	// RevokedBefore returns the time up to which the user's tokens are revoked,
+	// or the zero time when none are
+	RevokedBefore(ctx context.Context, userID uint) (time.Time, error)
+}
@@ -0,0 +1,134 @@
+package revocation
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.clock = func() time.Time { return now }
+
+	require.NoError(t, store.Revoke(ctx, "a", now.Add(time.Minute)))
+	require.NoError(t, store.Revoke(ctx, "expired", now.Add(-time.Second)))
+
+	revoked, err := store.IsRevoked(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = store.IsRevoked(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = store.IsRevoked(ctx, "expired")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Entries lapse together with their token
+	now = now.Add(2 * time.Minute)
+	revoked, err = store.IsRevoked(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+}
+
+func TestRedisStore(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	store := NewRedisStore(client)
+	require.NoError(t, store.Revoke(ctx, "a", time.Now().Add(time.Minute)))
+	assert.InDelta(t, time.Minute.Seconds(), server.TTL("revoked:a").Seconds(), 1)
+
+	// Revocations made by other instances are seen through Redis
+	other := NewRedisStore(client)
+	revoked, err := other.IsRevoked(ctx, "a")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = other.IsRevoked(ctx, "b")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	server.FastForward(2 * time.Minute)
+	revoked, err = other.IsRevoked(ctx, "a")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	// Without Redis, tokens revoked by this instance are still rejected
+	require.NoError(t, store.Revoke(ctx, "c", time.Now().Add(time.Minute)))
+	server.Close()
+	revoked, err = store.IsRevoked(ctx, "c")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	_, err = store.IsRevoked(ctx, "d")
+	assert.Error(t, err)
+}
+
+func TestMemoryStore_RevokeUser(t *testing.T) {
+	ctx := context.Background()
+	now := time.Now()
+	store := NewMemoryStore()
+	store.clock = func() time.Time { return now }
+
+	before, err := store.RevokedBefore(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, before.IsZero())
+
+	require.NoError(t, store.RevokeUser(ctx, 1, now, now.Add(time.Hour)))
+	// An earlier cutoff doesn't undo a later one
+	require.NoError(t, store.RevokeUser(ctx, 1, now.Add(-time.Minute), now.Add(time.Minute)))
+
+	before, err = store.RevokedBefore(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, before.Equal(now))
+	before, err = store.RevokedBefore(ctx, 2)
+	require.NoError(t, err)
+	assert.True(t, before.IsZero())
+
+	// The cutoff lapses once the tokens it covers have expired
+	now = now.Add(2 * time.Hour)
+	before, err = store.RevokedBefore(ctx, 1)
+	require.NoError(t, err)
+	assert.True(t, before.IsZero())
+}
+
+func TestRedisStore_RevokeUser(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	t.Cleanup(func() { client.Close() })
+
+	store := NewRedisStore(client)
+	cutoff := time.Now()
+	require.NoError(t, store.RevokeUser(ctx, 7, cutoff, cutoff.Add(time.Minute)))
+	assert.InDelta(t, time.Minute.Seconds(), server.TTL("revoked:user:7").Seconds(), 1)
+
+	// Cutoffs set by other instances are seen through Redis
+	other := NewRedisStore(client)
+	before, err := other.RevokedBefore(ctx, 7)
+	require.NoError(t, err)
+	assert.True(t, before.Equal(time.Unix(0, cutoff.UnixNano())))
+
+	server.FastForward(2 * time.Minute)
+	before, err = other.RevokedBefore(ctx, 7)
+	require.NoError(t, err)
+	assert.True(t, before.IsZero())
+
+	// Without Redis, cutoffs set by this instance still apply
+	require.NoError(t, store.RevokeUser(ctx, 8, cutoff, time.Now().Add(time.Minute)))
+	server.Close()
+	before, err = store.RevokedBefore(ctx, 8)
+	assert.Error(t, err)
+	assert.True(t, before.Equal(cutoff))
+}
@@ -0,0 +1,119 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// clamavChunkSize is the size of the chunks streamed to clamd
+const clamavChunkSize = 64 * 1024
+
+// ClamAV scans content with a clamd daemon using the INSTREAM command
+type ClamAV struct {
+	network string
+	address string
+	timeout time.Duration
+}
+
+// NewClamAV creates a ClamAV scanner. The address is host:port, or unix:/path/to/clamd.sock for a socket.
+func NewClamAV(address string, timeout time.Duration) *ClamAV {
+	network := "tcp"
+	if path, ok := strings.CutPrefix(address, "unix:"); ok {
+		network, address = "unix", path
+	}
+	return &ClamAV{network: network, address: address, timeout: timeout}
+}
+
+// Scan streams r to clamd and parses its verdict
+func (c *ClamAV) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, c.network, c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to clamd: %w", err)
+	}
+	defer conn.Close()
+
+	// Abort the exchange when the context ends
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("failed to send command to clamd: %w", err)
+	}
+
+	buf := make([]byte, clamavChunkSize)
+	header := make([]byte, 4)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if c.timeout > 0 {
+				conn.SetWriteDeadline(time.Now().Add(c.timeout))
+			}
+			binary.BigEndian.PutUint32(header, uint32(n))
+			if _, err := conn.Write(header); err != nil {
+				return c.earlyReply(conn, err)
+			}
+			if _, err := conn.Write(buf[:n]); err != nil {
+				return c.earlyReply(conn, err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+
+	// A zero length chunk ends the stream
+	binary.BigEndian.PutUint32(header, 0)
+	if _, err := conn.Write(header); err != nil {
+		return c.earlyReply(conn, err)
+	}
+
+	return c.readReply(conn)
+}
+
+// earlyReply reads the reply clamd sends when it stops reading mid-stream, such as when StreamMaxLength is exceeded
+func (c *ClamAV) earlyReply(conn net.Conn, writeErr error) (*Result, error) {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	result, err := c.readReply(conn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stream to clamd: %w", errors.Join(writeErr, err))
+	}
+	return result, nil
+}
+
+// readReply parses a reply such as "stream: OK" or "stream: Eicar-Signature FOUND"
+func (c *ClamAV) readReply(conn net.Conn) (*Result, error) {
+	if c.timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	reply, err := bufio.NewReader(conn).ReadBytes(0)
+	if err != nil && len(reply) == 0 {
+		return nil, fmt.Errorf("failed to read clamd reply: %w", err)
+	}
+	return parseClamAVReply(string(bytes.TrimRight(reply, "\x00\n")))
+}
+
+// parseClamAVReply converts a clamd reply into a result
+func parseClamAVReply(reply string) (*Result, error) {
+	verdict := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case verdict == "OK":
+		return &Result{}, nil
+	case strings.HasSuffix(verdict, " FOUND"):
+		return &Result{Infected: true, Signature: strings.TrimSuffix(verdict, " FOUND")}, nil
+	}
+	return nil, fmt.Errorf("clamd error: %s", verdict)
+}
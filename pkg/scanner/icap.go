@@ -0,0 +1,129 @@
+package scanner
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// icapInfectionHeaders are the response headers ICAP servers commonly use to name a detected threat
+var icapInfectionHeaders = []string{"X-Infection-Found", "X-Virus-Id", "X-Violations-Found"}
+
+// ICAP scans content with an ICAP server (RFC 3507) using RESPMOD
+type ICAP struct {
+	address string
+	service string
+	timeout time.Duration
+}
+
+// NewICAP creates an ICAP scanner for a server at host:port and a service name such as "avscan"
+func NewICAP(address, service string, timeout time.Duration) *ICAP {
+	return &ICAP{
+		address: address,
+		service: strings.TrimPrefix(service, "/"),
+		timeout: timeout,
+	}
+}
+
+// Scan sends r as an encapsulated HTTP response body. A 204 reply means the content is clean,
+// a 200 reply means the server modified or blocked it.
+func (c *ICAP) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	dialer := net.Dialer{Timeout: c.timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", c.address)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to ICAP server: %w", err)
+	}
+	defer conn.Close()
+
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	httpHeader := "HTTP/1.1 200 OK\r\nContent-Type: application/octet-stream\r\n\r\n"
+	request := fmt.Sprintf("RESPMOD icap://%s/%s ICAP/1.0\r\n"+
+		"Host: %s\r\n"+
+		"Allow: 204\r\n"+
+		"Encapsulated: res-hdr=0, res-body=%d\r\n"+
+		"\r\n%s", c.address, c.service, c.address, len(httpHeader), httpHeader)
+
+	w := bufio.NewWriter(conn)
+	if _, err := w.WriteString(request); err != nil {
+		return nil, fmt.Errorf("failed to send ICAP request: %w", err)
+	}
+
+	// The body is sent with HTTP chunked encoding
+	buf := make([]byte, 64*1024)
+	for {
+		n, readErr := r.Read(buf)
+		if n > 0 {
+			if c.timeout > 0 {
+				conn.SetWriteDeadline(time.Now().Add(c.timeout))
+			}
+			fmt.Fprintf(w, "%x\r\n", n)
+			w.Write(buf[:n])
+			if _, err := w.WriteString("\r\n"); err != nil {
+				return nil, fmt.Errorf("failed to send ICAP body: %w", err)
+			}
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			return nil, readErr
+		}
+	}
+	w.WriteString("0\r\n\r\n")
+	if err := w.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to send ICAP body: %w", err)
+	}
+
+	if c.timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(c.timeout))
+	}
+	return readICAPResponse(bufio.NewReader(conn))
+}
+
+// readICAPResponse parses the status line and headers of an ICAP response
+func readICAPResponse(r *bufio.Reader) (*Result, error) {
+	tp := textproto.NewReader(r)
+	statusLine, err := tp.ReadLine()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ICAP response: %w", err)
+	}
+
+	fields := strings.Fields(statusLine)
+	if len(fields) < 2 || !strings.HasPrefix(fields[0], "ICAP/") {
+		return nil, fmt.Errorf("unexpected ICAP status line %q", statusLine)
+	}
+	status, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return nil, fmt.Errorf("unexpected ICAP status line %q", statusLine)
+	}
+
+	headers, err := tp.ReadMIMEHeader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ICAP headers: %w", err)
+	}
+
+	switch status {
+	case 204:
+		return &Result{}, nil
+	case 200:
+		signature := "blocked by ICAP server"
+		for _, name := range icapInfectionHeaders {
+			if value := headers.Get(name); value != "" {
+				signature = value
+				break
+			}
+		}
+		return &Result{Infected: true, Signature: signature}, nil
+	}
+	return nil, fmt.Errorf("ICAP server returned status %d", status)
+}
@@ -0,0 +1,72 @@
+package scanner
+
+import (
+	"context"
+	"io"
+)
+
+// Result is the verdict of a scan
+type Result struct {
+	Infected  bool
+	Signature string // Name of the detected threat, if any
+}
+
+// Scanner inspects content for malware
+type Scanner interface {
+	// Scan reads r to the end and reports whether it contains malware
+	Scan(ctx context.Context, r io.Reader) (*Result, error)
+}
+
+// Noop is a scanner that reports everything as clean
+type Noop struct{}
+
+// Scan drains r and reports it as clean
+func (Noop) Scan(ctx context.Context, r io.Reader) (*Result, error) {
+	if _, err := io.Copy(io.Discard, r); err != nil {
+		return nil, err
+	}
+	return &Result{}, nil
+}
+
+// Stream scans content while it is being written elsewhere, so the content only has to be read once.
+// It is an io.Writer meant to be used with io.TeeReader or io.MultiWriter.
+type Stream struct {
+	pw   *io.PipeWriter
+	done chan struct{}
+
+	result *Result
+	err    error
+}
+
+// NewStream starts scanning everything written to the returned stream
+func NewStream(ctx context.Context, s Scanner) *Stream {
+	pr, pw := io.Pipe()
+	stream := &Stream{pw: pw, done: make(chan struct{})}
+
+	go func() {
+		defer close(stream.done)
+		stream.result, stream.err = s.Scan(ctx, pr)
+		// Keep consuming if the scanner stopped early so writers never block
+		_, _ = io.Copy(io.Discard, pr)
+	}()
+
+	return stream
+}
+
+// Write passes content to the scanner
+func (s *Stream) Write(p []byte) (int, error) {
+	return s.pw.Write(p)
+}
+
+// Finish signals the end of the content and waits for the verdict
+func (s *Stream) Finish() (*Result, error) {
+	s.pw.Close()
+	<-s.done
+	return s.result, s.err
+}
+
+// Abort stops the scan without a verdict
+func (s *Stream) Abort(err error) {
+	s.pw.CloseWithError(err)
+	<-s.done
+}
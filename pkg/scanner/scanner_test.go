@@ -0,0 +1,169 @@
+package scanner
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"net/textproto"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// serve accepts connections on a local listener and handles each with fn
+func serve(t *testing.T, fn func(conn net.Conn)) string {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				fn(conn)
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// fakeClamd implements the INSTREAM command, flagging content that contains the EICAR string
+func fakeClamd(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	if command, err := r.ReadString(0); err != nil || command != "zINSTREAM\x00" {
+		return
+	}
+
+	var content bytes.Buffer
+	header := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(r, header); err != nil {
+			return
+		}
+		size := binary.BigEndian.Uint32(header)
+		if size == 0 {
+			break
+		}
+		if _, err := io.CopyN(&content, r, int64(size)); err != nil {
+			return
+		}
+	}
+
+	if strings.Contains(content.String(), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+		conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+		return
+	}
+	conn.Write([]byte("stream: OK\x00"))
+}
+
+func TestClamAV(t *testing.T) {
+	scanner := NewClamAV(serve(t, fakeClamd), time.Second)
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader("hello world"))
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+
+	result, err = scanner.Scan(context.Background(), strings.NewReader(eicar))
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, "Eicar-Test-Signature", result.Signature)
+}
+
+func TestClamAV_Unavailable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	_, err = NewClamAV(address, time.Second).Scan(context.Background(), strings.NewReader("hello"))
+	assert.Error(t, err)
+}
+
+func TestParseClamAVReply(t *testing.T) {
+	_, err := parseClamAVReply("INSTREAM size limit exceeded. ERROR")
+	assert.Error(t, err)
+}
+
+// fakeICAP implements RESPMOD, flagging bodies that contain the EICAR string
+func fakeICAP(conn net.Conn) {
+	r := bufio.NewReader(conn)
+	tp := textproto.NewReader(r)
+	if _, err := tp.ReadLine(); err != nil {
+		return
+	}
+	if _, err := tp.ReadMIMEHeader(); err != nil {
+		return
+	}
+	// Encapsulated HTTP response header, then the chunked body
+	if _, err := http.ReadResponse(r, nil); err != nil {
+		return
+	}
+	body, err := io.ReadAll(httputil.NewChunkedReader(r))
+	if err != nil {
+		return
+	}
+
+	if strings.Contains(string(body), "EICAR-STANDARD-ANTIVIRUS-TEST-FILE") {
+		conn.Write([]byte("ICAP/1.0 200 OK\r\nX-Infection-Found: Type=0; Resolution=2; Threat=EICAR-Test;\r\nEncapsulated: null-body=0\r\n\r\n"))
+		return
+	}
+	conn.Write([]byte("ICAP/1.0 204 No Content\r\nEncapsulated: null-body=0\r\n\r\n"))
+}
+
+func TestICAP(t *testing.T) {
+	scanner := NewICAP(serve(t, fakeICAP), "avscan", time.Second)
+
+	result, err := scanner.Scan(context.Background(), strings.NewReader("hello world"))
+	require.NoError(t, err)
+	assert.False(t, result.Infected)
+
+	result, err = scanner.Scan(context.Background(), strings.NewReader(eicar))
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Contains(t, result.Signature, "EICAR-Test")
+}
+
+func TestStream(t *testing.T) {
+	scanner := NewClamAV(serve(t, fakeClamd), time.Second)
+
+	// Content is scanned while being copied elsewhere
+	stream := NewStream(context.Background(), scanner)
+	var copied bytes.Buffer
+	_, err := io.Copy(&copied, io.TeeReader(strings.NewReader(eicar), stream))
+	require.NoError(t, err)
+
+	result, err := stream.Finish()
+	require.NoError(t, err)
+	assert.True(t, result.Infected)
+	assert.Equal(t, eicar, copied.String())
+}
+
+func TestStream_ScannerFailsEarly(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	// Writes keep succeeding after the scanner gave up
+	stream := NewStream(context.Background(), NewClamAV(address, time.Second))
+	_, err = io.Copy(stream, bytes.NewReader(make([]byte, 1<<20)))
+	require.NoError(t, err)
+
+	_, err = stream.Finish()
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"gbt-be-template/pkg/lock"
+	"gbt-be-template/pkg/logger"
+)
+
+// leaseFraction is the share of a task's interval for which a run keeps its lock.
+// Instances tick out of phase with each other, so releasing the lock as soon as
+// the run finishes would let every other instance run the task again within the
+// same interval. Holding it for most of the interval makes one instance win each
+// period while leaving slack for its next tick to reacquire it.
+const leaseFraction = 0.9
+
+// TaskFunc is a unit of periodic background work
+type TaskFunc func(ctx context.Context) error
+
+// task is a registered periodic task
+type task struct {
+	name     string
+	interval time.Duration
+	fn       TaskFunc
+}
+
+// Scheduler runs registered tasks at fixed intervals until stopped
+type Scheduler struct {
+	log    *logger.Logger
+	tasks  []task
+	locker lock.Locker
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// New creates a scheduler
+func New(log *logger.Logger) *Scheduler {
+	return &Scheduler{log: log}
+}
+
+// Every registers fn to run every interval. Tasks must be registered before Start.
+func (s *Scheduler) Every(name string, interval time.Duration, fn TaskFunc) {
+	s.tasks = append(s.tasks, task{name: name, interval: interval, fn: fn})
+}
+
+// UseLocker makes every task run only while holding a lock named after the task,
+// so that across instances sharing the locker each task runs once per interval.
+// It must be called before Start.
+func (s *Scheduler) UseLocker(locker lock.Locker) {
+	s.locker = locker
+}
+
+// Start runs every registered task in its own goroutine
+func (s *Scheduler) Start(ctx context.Context) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	for _, t := range s.tasks {
+		s.wg.Add(1)
+		go s.run(ctx, t)
+	}
+}
+
+// Stop cancels running tasks and waits for them to return
+func (s *Scheduler) Stop() {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+}
+
+// run executes a task on every tick until the context is canceled
+func (s *Scheduler) run(ctx context.Context, t task) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if !s.acquire(ctx, t) {
+				continue
+			}
+			start := time.Now()
+			if err := t.fn(ctx); err != nil {
+				s.log.WithError(err).WithField("task", t.name).Error("Scheduled task failed")
+				continue
+			}
+			s.log.WithFields(map[string]interface{}{
+				"task":        t.name,
+				"duration_ms": time.Since(start).Milliseconds(),
+			}).Debug("Scheduled task completed")
+		}
+	}
+}
+
+// acquire takes the task's lock for this period. The lock is deliberately not
+// released after the run; it expires on its own at the end of the lease.
+func (s *Scheduler) acquire(ctx context.Context, t task) bool {
+	if s.locker == nil {
+		return true
+	}
+
+	ttl := time.Duration(float64(t.interval) * leaseFraction)
+	if _, err := s.locker.TryLock(ctx, "scheduler:"+t.name, ttl); err != nil {
+		if errors.Is(err, lock.ErrNotAcquired) {
+			s.log.WithField("task", t.name).Debug("Scheduled task skipped, running on another instance")
+		} else if ctx.Err() == nil {
+			s.log.WithError(err).WithField("task", t.name).Error("Failed to acquire scheduled task lock")
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,63 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gbt-be-template/pkg/lock"
+	"gbt-be-template/pkg/logger"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	s := New(logger.New("error", "text"))
+
+	var runs, failures atomic.Int32
+	s.Every("count", 5*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+	s.Every("fail", 5*time.Millisecond, func(ctx context.Context) error {
+		failures.Add(1)
+		return errors.New("boom")
+	})
+
+	s.Start(context.Background())
+	assert.Eventually(t, func() bool { return runs.Load() >= 2 && failures.Load() >= 2 }, time.Second, time.Millisecond)
+	s.Stop()
+
+	// No task runs after Stop returns
+	stopped := runs.Load()
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, stopped, runs.Load())
+}
+
+func TestScheduler_SharedLocker(t *testing.T) {
+	locker := lock.NewLocalLocker()
+	var runs atomic.Int32
+
+	// Two instances ticking on the same interval run the task once per period between them
+	var instances []*Scheduler
+	for i := 0; i < 2; i++ {
+		s := New(logger.New("error", "text"))
+		s.UseLocker(locker)
+		s.Every("cleanup", 50*time.Millisecond, func(ctx context.Context) error {
+			runs.Add(1)
+			return nil
+		})
+		s.Start(context.Background())
+		instances = append(instances, s)
+	}
+
+	time.Sleep(220 * time.Millisecond)
+	for _, s := range instances {
+		s.Stop()
+	}
+
+	assert.GreaterOrEqual(t, runs.Load(), int32(3))
+	assert.LessOrEqual(t, runs.Load(), int32(5))
+}
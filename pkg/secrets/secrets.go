@@ -0,0 +1,124 @@
+// Package secrets loads rotating credentials from files or HashiCorp Vault so
+// they can be reloaded without restarting the process.
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrIncomplete is returned when a secret lacks a username or password
+var ErrIncomplete = errors.New("secret is missing username or password")
+
+// Credentials is a username and password pair
+type Credentials struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// Provider fetches the current credentials
+type Provider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// FileProvider reads credentials from a JSON file with "username" and "password"
+// keys, as rendered by Vault Agent, the Secrets Store CSI driver or External Secrets
+type FileProvider struct {
+	path string
+}
+
+// NewFileProvider creates a provider reading the given file on every call
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// Credentials reads and parses the credentials file
+func (p *FileProvider) Credentials(ctx context.Context) (Credentials, error) {
+	data, err := os.ReadFile(p.path)
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse credentials file: %w", err)
+	}
+	return creds, validate(creds)
+}
+
+// VaultProvider reads credentials from a Vault secret. It supports KV version 1
+// and 2 secrets and database static roles, i.e. paths whose reads return the
+// same credentials until Vault rotates them. Dynamic database roles issue new
+// credentials on every read and should be used through Vault Agent instead.
+type VaultProvider struct {
+	addr   string
+	token  string
+	path   string
+	client *http.Client
+}
+
+// NewVaultProvider creates a provider for the secret at path, e.g.
+// "database/static-creds/app" or "secret/data/app/db". If client is nil a
+// client with a 10 second timeout is used.
+func NewVaultProvider(addr, token, path string, client *http.Client) *VaultProvider {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &VaultProvider{
+		addr:   strings.TrimSuffix(addr, "/"),
+		token:  token,
+		path:   strings.Trim(path, "/"),
+		client: client,
+	}
+}
+
+// Credentials reads the secret from Vault
+func (p *VaultProvider) Credentials(ctx context.Context) (Credentials, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.addr+"/v1/"+p.path, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	req.Header.Set("X-Vault-Token", p.token)
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return Credentials{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return Credentials{}, fmt.Errorf("vault returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+
+	var secret struct {
+		Data struct {
+			Credentials
+			Data *Credentials `json:"data"` // KV version 2 nests the secret one level deeper
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&secret); err != nil {
+		return Credentials{}, fmt.Errorf("failed to parse vault response: %w", err)
+	}
+
+	creds := secret.Data.Credentials
+	if secret.Data.Data != nil {
+		creds = *secret.Data.Data
+	}
+	return creds, validate(creds)
+}
+
+// validate checks that both parts of the credentials are present
+func validate(creds Credentials) error {
+	if creds.Username == "" || creds.Password == "" {
+		return ErrIncomplete
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package secrets
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "db.json")
+	provider := NewFileProvider(path)
+
+	_, err := provider.Credentials(context.Background())
+	assert.Error(t, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"username":"app","password":"s3cret"}`), 0o600))
+	creds, err := provider.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{Username: "app", Password: "s3cret"}, creds)
+
+	// Rotated credentials are picked up on the next read
+	require.NoError(t, os.WriteFile(path, []byte(`{"username":"app","password":"rotated"}`), 0o600))
+	creds, err = provider.Credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "rotated", creds.Password)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"username":"app"}`), 0o600))
+	_, err = provider.Credentials(context.Background())
+	assert.ErrorIs(t, err, ErrIncomplete)
+}
+
+func TestVaultProvider(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "token" {
+			http.Error(w, `{"errors":["permission denied"]}`, http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/database/static-creds/app":
+			w.Write([]byte(`{"data":{"username":"app","password":"static","ttl":3600}}`))
+		case "/v1/secret/data/app/db":
+			w.Write([]byte(`{"data":{"data":{"username":"app","password":"kv2"},"metadata":{"version":3}}}`))
+		case "/v1/secret/app/empty":
+			w.Write([]byte(`{"data":{}}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		token    string
+		path     string
+		password string
+		wantErr  bool
+	}{
+		{name: "database static role", token: "token", path: "database/static-creds/app", password: "static"},
+		{name: "kv version 2", token: "token", path: "/secret/data/app/db", password: "kv2"},
+		{name: "missing fields", token: "token", path: "secret/app/empty", wantErr: true},
+		{name: "not found", token: "token", path: "secret/missing", wantErr: true},
+		{name: "bad token", token: "wrong", path: "database/static-creds/app", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			creds, err := NewVaultProvider(server.URL+"/", tt.token, tt.path, nil).Credentials(context.Background())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "app", creds.Username)
+			assert.Equal(t, tt.password, creds.Password)
+		})
+	}
+}
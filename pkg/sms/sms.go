@@ -0,0 +1,44 @@
+// Package sms delivers text messages such as one-time login codes.
+package sms
+
+import (
+	"context"
+	"errors"
+
+	"gbt-be-template/pkg/logger"
+)
+
+// ErrRejected is returned when the provider refuses a message, such as for an invalid
+// number, so retrying it would not help
+var ErrRejected = errors.New("message rejected")
+
+// Message is a text message to a phone number in E.164 format
+type Message struct {
+	To   string `json:"to"`
+	Body string `json:"body"`
+}
+
+// Sender delivers text messages
+type Sender interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// Log is a sender that writes messages to the log instead of sending them.
+// It is meant for development, where login codes can be copied from the log.
+type Log struct {
+	log *logger.Logger
+}
+
+// NewLog creates a sender that logs every message
+func NewLog(log *logger.Logger) *Log {
+	return &Log{log: log}
+}
+
+// Send logs the message
+func (l *Log) Send(ctx context.Context, msg *Message) error {
+	l.log.WithFields(map[string]interface{}{
+		"to":   msg.To,
+		"body": msg.Body,
+	}).Info("SMS not sent, logging it instead")
+	return nil
+}
@@ -0,0 +1,54 @@
+package sms
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwilio_Send(t *testing.T) {
+	var form map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/2010-04-01/Accounts/AC123/Messages.json", r.URL.Path)
+		user, password, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "AC123", user)
+		assert.Equal(t, "secret", password)
+		require.NoError(t, r.ParseForm())
+		form = map[string]string{"To": r.PostForm.Get("To"), "From": r.PostForm.Get("From"), "Body": r.PostForm.Get("Body")}
+
+		if r.PostForm.Get("To") == "+15550000000" {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"code": 21211, "message": "Invalid 'To' Phone Number"}`))
+			return
+		}
+		if r.PostForm.Get("To") == "+15559999999" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusCreated)
+		_, _ = w.Write([]byte(`{"sid": "SM123"}`))
+	}))
+	defer server.Close()
+
+	sender := NewTwilio("AC123", "secret", "+15551234567", time.Second)
+	sender.baseURL = server.URL
+	ctx := context.Background()
+
+	require.NoError(t, sender.Send(ctx, &Message{To: "+15557654321", Body: "Your code is 123456"}))
+	assert.Equal(t, map[string]string{"To": "+15557654321", "From": "+15551234567", "Body": "Your code is 123456"}, form)
+
+	// Refused messages aren't worth retrying, outages are
+	err := sender.Send(ctx, &Message{To: "+15550000000", Body: "hi"})
+	assert.ErrorIs(t, err, ErrRejected)
+	assert.Contains(t, err.Error(), "Invalid 'To' Phone Number")
+
+	err = sender.Send(ctx, &Message{To: "+15559999999", Body: "hi"})
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrRejected)
+}
@@ -0,0 +1,161 @@
+package static
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hashedName matches file names carrying a hex content hash, e.g. app.3f2a9c1b.js or chunk-3f2a9c1b.css
+var hashedName = regexp.MustCompile(`[.-][0-9a-f]{8,}\.[A-Za-z0-9]+$`)
+
+// Options configures how static files are served
+type Options struct {
+	SPAFallback    bool          // Serve index.html for unknown paths without an extension, for client-side routing
+	ImmutablePaths []string      // URL path prefixes whose files are content hashed, such as /assets/
+	MaxAge         time.Duration // Cache lifetime of content hashed files
+}
+
+// handler serves files from a file system
+type handler struct {
+	fsys  fs.FS
+	opts  Options
+	etags sync.Map // name, size and mod time to ETag
+}
+
+// Handler returns a handler serving the files in fsys. Content hashed files get far-future cache headers;
+// everything else, including index.html, must be revalidated so new deployments are picked up immediately.
+func Handler(fsys fs.FS, opts Options) http.Handler {
+	return &handler{fsys: fsys, opts: opts}
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	urlPath := path.Clean("/" + r.URL.Path)
+	file, info, err := h.open(urlPath)
+	if errors.Is(err, fs.ErrNotExist) && h.opts.SPAFallback && path.Ext(urlPath) == "" {
+		urlPath = "/index.html"
+		file, info, err = h.open(urlPath)
+	}
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			http.NotFound(w, r)
+			return
+		}
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	if h.immutable(urlPath) {
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d, immutable", int(h.opts.MaxAge.Seconds())))
+	} else {
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	content, ok := file.(io.ReadSeeker)
+	if !ok {
+		data, err := io.ReadAll(file)
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+		content = bytes.NewReader(data)
+	}
+
+	// Embedded files have no modification time, so revalidation relies on the ETag
+	etag, err := h.etag(urlPath, info, content)
+	if err != nil {
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	http.ServeContent(w, r, info.Name(), info.ModTime(), content)
+}
+
+// etag returns a strong ETag from the file's content hash, cached until its size or modification time changes
+func (h *handler) etag(urlPath string, info fs.FileInfo, content io.ReadSeeker) (string, error) {
+	key := fmt.Sprintf("%s|%d|%d", urlPath, info.Size(), info.ModTime().UnixNano())
+	if etag, ok := h.etags.Load(key); ok {
+		return etag.(string), nil
+	}
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, content); err != nil {
+		return "", err
+	}
+	if _, err := content.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+	etag := `"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+	h.etags.Store(key, etag)
+	return etag, nil
+}
+
+// open opens the file for a URL path, using index.html for directories.
+// Hidden files such as .env are never served.
+func (h *handler) open(urlPath string) (fs.File, fs.FileInfo, error) {
+	name := strings.TrimPrefix(urlPath, "/")
+	if name == "" {
+		name = "index.html"
+	}
+	for _, segment := range strings.Split(name, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return nil, nil, fs.ErrNotExist
+		}
+	}
+
+	file, info, err := h.stat(name)
+	if err == nil && info.IsDir() {
+		file.Close()
+		file, info, err = h.stat(path.Join(name, "index.html"))
+	}
+	if err == nil && info.IsDir() {
+		file.Close()
+		return nil, nil, fs.ErrNotExist
+	}
+	return file, info, err
+}
+
+// stat opens a file and reads its info
+func (h *handler) stat(name string) (fs.File, fs.FileInfo, error) {
+	file, err := h.fsys.Open(name)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return file, info, nil
+}
+
+// immutable reports whether a file's content is fingerprinted in its URL
+func (h *handler) immutable(urlPath string) bool {
+	if h.opts.MaxAge <= 0 {
+		return false
+	}
+	for _, prefix := range h.opts.ImmutablePaths {
+		if prefix != "" && strings.HasPrefix(urlPath, prefix) {
+			return true
+		}
+	}
+	return hashedName.MatchString(path.Base(urlPath))
+}
@@ -0,0 +1,108 @@
+package static
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testHandler() http.Handler {
+	fsys := fstest.MapFS{
+		"index.html":               {Data: []byte("<html>app</html>")},
+		"favicon.ico":              {Data: []byte("icon")},
+		"assets/index-DiwrgTda.js": {Data: []byte(strings.Repeat("console.log('app');", 100))},
+		"js/main.3f2a9c1b.js":      {Data: []byte("main")},
+		"docs/index.html":          {Data: []byte("docs")},
+		".env":                     {Data: []byte("SECRET=1")},
+	}
+	return Handler(fsys, Options{SPAFallback: true, ImmutablePaths: []string{"/assets/"}, MaxAge: 365 * 24 * time.Hour})
+}
+
+func get(t *testing.T, h http.Handler, target string, headers map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, target, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestHandler_CacheHeaders(t *testing.T) {
+	h := testHandler()
+
+	rec := get(t, h, "/assets/index-DiwrgTda.js", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+
+	rec = get(t, h, "/js/main.3f2a9c1b.js", nil)
+	assert.Equal(t, "public, max-age=31536000, immutable", rec.Header().Get("Cache-Control"))
+
+	rec = get(t, h, "/favicon.ico", nil)
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+
+	rec = get(t, h, "/", nil)
+	assert.Equal(t, "<html>app</html>", rec.Body.String())
+	assert.Equal(t, "no-cache", rec.Header().Get("Cache-Control"))
+}
+
+func TestHandler_SPAFallback(t *testing.T) {
+	h := testHandler()
+
+	// Client-side routes get the app shell
+	rec := get(t, h, "/users/42/settings", nil)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "<html>app</html>", rec.Body.String())
+
+	// Directories serve their own index
+	rec = get(t, h, "/docs/", nil)
+	assert.Equal(t, "docs", rec.Body.String())
+
+	// Missing files are real 404s so broken asset links are noticed
+	rec = get(t, h, "/assets/missing.js", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+
+	// Hidden files are never served
+	rec = get(t, h, "/.env", nil)
+	assert.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestHandler_Revalidation(t *testing.T) {
+	h := testHandler()
+
+	rec := get(t, h, "/favicon.ico", nil)
+	etag := rec.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	rec = get(t, h, "/favicon.ico", map[string]string{"If-None-Match": etag})
+	assert.Equal(t, http.StatusNotModified, rec.Code)
+}
+
+func TestHandler_Gzip(t *testing.T) {
+	h := middleware.Compress(5)(testHandler())
+
+	rec := get(t, h, "/assets/index-DiwrgTda.js", map[string]string{"Accept-Encoding": "gzip"})
+	require.Equal(t, "gzip", rec.Header().Get("Content-Encoding"))
+
+	reader, err := gzip.NewReader(rec.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, strings.Repeat("console.log('app');", 100), string(body))
+}
+
+func TestHandler_MethodNotAllowed(t *testing.T) {
+	rec := httptest.NewRecorder()
+	testHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,312 @@
+package storage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// azureAPIVersion is the Blob service REST API version requests and SAS tokens are made for
+const azureAPIVersion = "2021-08-06"
+
+// AzureConfig holds the settings of an Azure Blob Storage container
+type AzureConfig struct {
+	AccountName string
+	AccountKey  string // Base64 encoded shared key
+	Container   string
+	Endpoint    string // Defaults to https://<account>.blob.core.windows.net; for Azurite http://127.0.0.1:10000/<account>
+}
+
+// AzureStorage stores objects as block blobs in an Azure Blob Storage container.
+// Requests are authorized with the account's shared key.
+type AzureStorage struct {
+	endpoint  *url.URL
+	account   string
+	key       []byte
+	container string
+	client    *http.Client
+}
+
+// NewAzureStorage creates an Azure Blob storage. A nil client uses a default client with a timeout.
+func NewAzureStorage(cfg AzureConfig, client *http.Client) (*AzureStorage, error) {
+	if cfg.AccountName == "" || cfg.Container == "" {
+		return nil, errors.New("azure account name and container are required")
+	}
+	key, err := base64.StdEncoding.DecodeString(cfg.AccountKey)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("azure account key must be base64 encoded")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://" + cfg.AccountName + ".blob.core.windows.net"
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid azure endpoint %q", cfg.Endpoint)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	return &AzureStorage{
+		endpoint:  endpoint,
+		account:   cfg.AccountName,
+		key:       key,
+		container: cfg.Container,
+		client:    client,
+	}, nil
+}
+
+// Put uploads the object as a block blob in a single request
+func (s *AzureStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get opens the object for reading
+func (s *AzureStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object; deleting a missing object is not an error
+func (s *AzureStorage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Exists reports whether an object is stored under key
+func (s *AzureStorage) Exists(ctx context.Context, key string) (bool, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// PresignPut returns a SAS URL for uploading the object directly to the container.
+// SAS tokens can't bind the size, which is verified when the upload is confirmed.
+func (s *AzureStorage) PresignPut(ctx context.Context, key string, size int64, contentType string, expires time.Duration) (string, map[string]string, error) {
+	signed, err := s.presign(key, "cw", expires, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	headers := presignHeaders(size, contentType)
+	headers["x-ms-blob-type"] = "BlockBlob"
+	return signed, headers, nil
+}
+
+// PresignGet returns a SAS URL for downloading the object directly from the container
+func (s *AzureStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.presign(key, "r", expires, time.Now())
+}
+
+// presign builds a blob service SAS URL granting permissions on a single blob
+func (s *AzureStorage) presign(key, permissions string, expires time.Duration, now time.Time) (string, error) {
+	u, err := s.blobURL(key)
+	if err != nil {
+		return "", err
+	}
+
+	now = now.UTC()
+	// Start slightly in the past to tolerate clock skew between us and Azure
+	start := now.Add(-5 * time.Minute).Format(time.RFC3339)
+	expiry := now.Add(clampExpiry(expires)).Format(time.RFC3339)
+	protocol := "https"
+	if u.Scheme == "http" {
+		protocol = "https,http"
+	}
+
+	stringToSign := strings.Join([]string{
+		permissions,
+		start,
+		expiry,
+		"/blob/" + s.account + "/" + s.container + "/" + key,
+		"", // signed identifier
+		"", // signed IP
+		protocol,
+		azureAPIVersion,
+		"b",                // signed resource: blob
+		"",                 // snapshot time
+		"",                 // encryption scope
+		"", "", "", "", "", // response header overrides
+	}, "\n")
+
+	query := url.Values{}
+	query.Set("sv", azureAPIVersion)
+	query.Set("sr", "b")
+	query.Set("sp", permissions)
+	query.Set("st", start)
+	query.Set("se", expiry)
+	query.Set("spr", protocol)
+	query.Set("sig", s.signature(stringToSign))
+	u.RawQuery = query.Encode()
+	return u.String(), nil
+}
+
+// blobURL returns the URL of a blob in the container
+func (s *AzureStorage) blobURL(key string) (*url.URL, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	u := *s.endpoint
+	u.Path += "/" + s.container + "/" + key
+	u.RawPath = ""
+	u.RawQuery = ""
+	return &u, nil
+}
+
+// newRequest builds a request for a blob
+func (s *AzureStorage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u, err := s.blobURL(key)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends a request, converting error responses into errors
+func (s *AzureStorage) do(req *http.Request) (*http.Response, error) {
+	s.sign(req, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var azureErr struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&azureErr)
+	if azureErr.Code != "" {
+		return nil, fmt.Errorf("azure %s %s: %s: %s", req.Method, req.URL.Path, azureErr.Code, strings.SplitN(azureErr.Message, "\n", 2)[0])
+	}
+	return nil, fmt.Errorf("azure %s %s: unexpected status %d", req.Method, req.URL.Path, resp.StatusCode)
+}
+
+// sign adds Shared Key authorization to a request
+func (s *AzureStorage) sign(req *http.Request, now time.Time) {
+	req.Header.Set("X-Ms-Date", now.UTC().Format(http.TimeFormat))
+	req.Header.Set("X-Ms-Version", azureAPIVersion)
+	req.Header.Set("Authorization", "SharedKey "+s.account+":"+s.signature(s.stringToSign(req)))
+}
+
+// stringToSign builds the Shared Key string to sign for a request
+func (s *AzureStorage) stringToSign(req *http.Request) string {
+	contentLength := ""
+	if req.ContentLength > 0 {
+		contentLength = strconv.FormatInt(req.ContentLength, 10)
+	}
+
+	var msHeaders []string
+	for name, values := range req.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-ms-") {
+			msHeaders = append(msHeaders, name+":"+strings.TrimSpace(strings.Join(values, ",")))
+		}
+	}
+	sort.Strings(msHeaders)
+
+	var canonical strings.Builder
+	for _, header := range msHeaders {
+		canonical.WriteString(header + "\n")
+	}
+	canonical.WriteString("/" + s.account + req.URL.EscapedPath())
+
+	query := req.URL.Query()
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		values := append([]string(nil), query[name]...)
+		sort.Strings(values)
+		canonical.WriteString("\n" + strings.ToLower(name) + ":" + strings.Join(values, ","))
+	}
+
+	return strings.Join([]string{
+		req.Method,
+		req.Header.Get("Content-Encoding"),
+		req.Header.Get("Content-Language"),
+		contentLength,
+		req.Header.Get("Content-MD5"),
+		req.Header.Get("Content-Type"),
+		"", // Date, superseded by x-ms-date
+		req.Header.Get("If-Modified-Since"),
+		req.Header.Get("If-Match"),
+		req.Header.Get("If-None-Match"),
+		req.Header.Get("If-Unmodified-Since"),
+		req.Header.Get("Range"),
+		canonical.String(),
+	}, "\n")
+}
+
+// signature returns the base64 HMAC-SHA256 of a string with the account key
+func (s *AzureStorage) signature(stringToSign string) string {
+	mac := hmac.New(sha256.New, s.key)
+	mac.Write([]byte(stringToSign))
+	return base64.StdEncoding.EncodeToString(mac.Sum(nil))
+}
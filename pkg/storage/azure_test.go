@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var azureTestKey = base64.StdEncoding.EncodeToString([]byte("azure-test-key"))
+
+// fakeAzure is a minimal in-memory Blob service endpoint that checks Shared Key signatures
+type fakeAzure struct {
+	mu      sync.Mutex
+	store   *AzureStorage // Separate instance used to recompute expected signatures
+	objects map[string][]byte
+}
+
+func (f *fakeAzure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	expected := "SharedKey devstoreaccount1:" + f.store.signature(f.store.stringToSign(r))
+	if r.Header.Get("Authorization") != expected || r.Header.Get("X-Ms-Version") != azureAPIVersion {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?><Error><Code>AuthenticationFailed</Code><Message>Server failed to authenticate the request.
+RequestId:1</Message></Error>`)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		if r.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		if _, ok := f.objects[r.URL.Path]; !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusAccepted)
+	}
+}
+
+func setupAzure(t *testing.T) (*AzureStorage, *fakeAzure) {
+	fake := &fakeAzure{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	cfg := AzureConfig{
+		AccountName: "devstoreaccount1",
+		AccountKey:  azureTestKey,
+		Container:   "files",
+		Endpoint:    server.URL + "/devstoreaccount1",
+	}
+	store, err := NewAzureStorage(cfg, server.Client())
+	require.NoError(t, err)
+	fake.store, err = NewAzureStorage(cfg, nil)
+	require.NoError(t, err)
+	return store, fake
+}
+
+func TestAzureStorage(t *testing.T) {
+	store, fake := setupAzure(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "uploads/a.txt", strings.NewReader("hello"), 5, "text/plain"))
+	assert.Equal(t, []byte("hello"), fake.objects["/devstoreaccount1/files/uploads/a.txt"])
+
+	reader, err := store.Get(ctx, "uploads/a.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "hello", string(data))
+
+	exists, err := store.Exists(ctx, "uploads/a.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, store.Delete(ctx, "uploads/a.txt"))
+	require.NoError(t, store.Delete(ctx, "uploads/a.txt"))
+	exists, err = store.Exists(ctx, "uploads/a.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func TestAzureStorage_StringToSign(t *testing.T) {
+	store, err := NewAzureStorage(AzureConfig{AccountName: "myaccount", AccountKey: azureTestKey, Container: "files"}, nil)
+	require.NoError(t, err)
+
+	req, err := http.NewRequest(http.MethodPut, "https://myaccount.blob.core.windows.net/files/a.txt?timeout=30", strings.NewReader("hello"))
+	require.NoError(t, err)
+	req.Header.Set("Content-Type", "text/plain")
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	req.Header.Set("X-Ms-Date", "Fri, 16 Oct 2026 12:00:00 GMT")
+	req.Header.Set("X-Ms-Version", azureAPIVersion)
+
+	expected := "PUT\n\n\n5\n\ntext/plain\n\n\n\n\n\n\n" +
+		"x-ms-blob-type:BlockBlob\nx-ms-date:Fri, 16 Oct 2026 12:00:00 GMT\nx-ms-version:2021-08-06\n" +
+		"/myaccount/files/a.txt\ntimeout:30"
+	assert.Equal(t, expected, store.stringToSign(req))
+}
+
+func TestAzureStorage_Errors(t *testing.T) {
+	store, _ := setupAzure(t)
+	store.key = []byte("wrong")
+
+	err := store.Put(context.Background(), "a", strings.NewReader("x"), 1, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AuthenticationFailed: Server failed to authenticate the request.")
+}
+
+func TestAzureStorage_Presign(t *testing.T) {
+	store, err := NewAzureStorage(AzureConfig{AccountName: "myaccount", AccountKey: azureTestKey, Container: "files"}, nil)
+	require.NoError(t, err)
+
+	raw, headers, err := store.PresignPut(context.Background(), "uploads/a.png", 42, "image/png", 15*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, "BlockBlob", headers["x-ms-blob-type"])
+
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "myaccount.blob.core.windows.net", u.Host)
+	assert.Equal(t, "/files/uploads/a.png", u.Path)
+	assert.Equal(t, "cw", u.Query().Get("sp"))
+	assert.Equal(t, "b", u.Query().Get("sr"))
+	assert.Equal(t, "https", u.Query().Get("spr"))
+	assert.NotEmpty(t, u.Query().Get("sig"))
+
+	raw, err = store.PresignGet(context.Background(), "uploads/a.png", time.Hour)
+	require.NoError(t, err)
+	u, err = url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "r", u.Query().Get("sp"))
+}
@@ -0,0 +1,363 @@
+package storage
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	gcsDefaultEndpoint = "https://storage.googleapis.com"
+	gcsDefaultTokenURI = "https://oauth2.googleapis.com/token"
+	gcsScope           = "https://www.googleapis.com/auth/devstorage.read_write"
+	gcsSignAlgorithm   = "GOOG4-RSA-SHA256"
+)
+
+// GCSConfig holds the settings of a Google Cloud Storage bucket
+type GCSConfig struct {
+	Bucket          string
+	CredentialsJSON []byte // Service account key file contents; empty for unauthenticated emulators
+	Endpoint        string // Defaults to https://storage.googleapis.com
+}
+
+// gcsServiceAccount is the subset of a service account key file used for signing
+type gcsServiceAccount struct {
+	ClientEmail string `json:"client_email"`
+	PrivateKey  string `json:"private_key"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// GCSStorage stores objects in a Google Cloud Storage bucket using the JSON API.
+// Requests are authorized with OAuth2 access tokens obtained for a service account.
+type GCSStorage struct {
+	endpoint *url.URL
+	bucket   string
+	client   *http.Client
+
+	clientEmail string
+	privateKey  *rsa.PrivateKey
+	tokenURI    string
+
+	mu          sync.Mutex
+	accessToken string
+	tokenExpiry time.Time
+}
+
+// NewGCSStorage creates a GCS storage. A nil client uses a default client with a timeout.
+func NewGCSStorage(cfg GCSConfig, client *http.Client) (*GCSStorage, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("gcs bucket is required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = gcsDefaultEndpoint
+	}
+	endpoint, err := url.Parse(strings.TrimRight(cfg.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid gcs endpoint %q", cfg.Endpoint)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	store := &GCSStorage{endpoint: endpoint, bucket: cfg.Bucket, client: client}
+	if len(cfg.CredentialsJSON) > 0 {
+		var account gcsServiceAccount
+		if err := json.Unmarshal(cfg.CredentialsJSON, &account); err != nil {
+			return nil, fmt.Errorf("invalid gcs credentials: %w", err)
+		}
+		key, err := parseRSAPrivateKey(account.PrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("invalid gcs credentials: %w", err)
+		}
+		store.clientEmail = account.ClientEmail
+		store.privateKey = key
+		store.tokenURI = account.TokenURI
+		if store.tokenURI == "" {
+			store.tokenURI = gcsDefaultTokenURI
+		}
+	}
+	return store, nil
+}
+
+// Put uploads the object in a single media upload request
+func (s *GCSStorage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	if err := validateKey(key); err != nil {
+		return err
+	}
+	u := *s.endpoint
+	u.Path += "/upload/storage/v1/b/" + s.bucket + "/o"
+	u.RawQuery = url.Values{"uploadType": {"media"}, "name": {key}}.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u.String(), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get opens the object for reading
+func (s *GCSStorage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.objectRequest(ctx, http.MethodGet, key, "alt=media")
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object; deleting a missing object is not an error
+func (s *GCSStorage) Delete(ctx context.Context, key string) error {
+	req, err := s.objectRequest(ctx, http.MethodDelete, key, "")
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Exists reports whether an object is stored under key by fetching its metadata
+func (s *GCSStorage) Exists(ctx context.Context, key string) (bool, error) {
+	req, err := s.objectRequest(ctx, http.MethodGet, key, "")
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// PresignPut returns a V4 signed URL for uploading the object directly to the bucket.
+// The content length and type are signed, so the upload must match them exactly.
+func (s *GCSStorage) PresignPut(ctx context.Context, key string, size int64, contentType string, expires time.Duration) (string, map[string]string, error) {
+	headers := presignHeaders(size, contentType)
+	signed, err := s.presign(http.MethodPut, key, headers, expires, time.Now())
+	if err != nil {
+		return "", nil, err
+	}
+	return signed, headers, nil
+}
+
+// PresignGet returns a V4 signed URL for downloading the object directly from the bucket
+func (s *GCSStorage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	return s.presign(http.MethodGet, key, nil, expires, time.Now())
+}
+
+// presign builds a V4 signed URL on the XML API, signed with the service account key
+func (s *GCSStorage) presign(method, key string, headers map[string]string, expires time.Duration, now time.Time) (string, error) {
+	if s.privateKey == nil {
+		return "", errors.New("gcs signed URLs require service account credentials")
+	}
+	if err := validateKey(key); err != nil {
+		return "", err
+	}
+
+	now = now.UTC()
+	u := *s.endpoint
+	u.Path += "/" + s.bucket + "/" + key
+
+	signed := map[string]string{"host": u.Host}
+	for name, value := range headers {
+		signed[strings.ToLower(name)] = value
+	}
+	canonicalHeaders, signedHeaders := canonicalizeHeaders(signed)
+
+	scope := strings.Join([]string{now.Format(sigV4DateFormat), "auto", "storage", "goog4_request"}, "/")
+	query := url.Values{}
+	query.Set("X-Goog-Algorithm", gcsSignAlgorithm)
+	query.Set("X-Goog-Credential", s.clientEmail+"/"+scope)
+	query.Set("X-Goog-Date", now.Format(sigV4TimeFormat))
+	query.Set("X-Goog-Expires", strconv.Itoa(int(clampExpiry(expires).Seconds())))
+	query.Set("X-Goog-SignedHeaders", signedHeaders)
+
+	canonicalRequest := strings.Join([]string{
+		method,
+		canonicalURI(&u),
+		canonicalQuery(query),
+		canonicalHeaders,
+		signedHeaders,
+		unsignedPayload,
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{gcsSignAlgorithm, now.Format(sigV4TimeFormat), scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	digest := sha256.Sum256([]byte(stringToSign))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign gcs url: %w", err)
+	}
+	query.Set("X-Goog-Signature", hex.EncodeToString(signature))
+
+	u.RawQuery = canonicalQuery(query)
+	return u.String(), nil
+}
+
+// objectRequest builds a JSON API request for an object; the name is escaped as a single path segment
+func (s *GCSStorage) objectRequest(ctx context.Context, method, key, rawQuery string) (*http.Request, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+	target := s.endpoint.String() + "/storage/v1/b/" + url.PathEscape(s.bucket) + "/o/" + url.PathEscape(key)
+	if rawQuery != "" {
+		target += "?" + rawQuery
+	}
+	return http.NewRequestWithContext(ctx, method, target, nil)
+}
+
+// do authorizes and sends a request, converting error responses into errors
+func (s *GCSStorage) do(req *http.Request) (*http.Response, error) {
+	if s.privateKey != nil {
+		token, err := s.token(req.Context())
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var gcsErr struct {
+		Error struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	_ = json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&gcsErr)
+	if gcsErr.Error.Message != "" {
+		return nil, fmt.Errorf("gcs %s %s: %d: %s", req.Method, req.URL.Path, resp.StatusCode, gcsErr.Error.Message)
+	}
+	return nil, fmt.Errorf("gcs %s %s: unexpected status %d", req.Method, req.URL.Path, resp.StatusCode)
+}
+
+// token returns a cached access token, exchanging a signed JWT for a new one shortly before it expires
+func (s *GCSStorage) token(ctx context.Context) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if s.accessToken != "" && now.Add(time.Minute).Before(s.tokenExpiry) {
+		return s.accessToken, nil
+	}
+
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   s.clientEmail,
+		"scope": gcsScope,
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign gcs token request: %w", err)
+	}
+
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("gcs token request failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("gcs token request failed with status %d", resp.StatusCode)
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil || token.AccessToken == "" {
+		return "", errors.New("gcs token response is invalid")
+	}
+
+	s.accessToken = token.AccessToken
+	s.tokenExpiry = now.Add(time.Duration(token.ExpiresIn) * time.Second)
+	return s.accessToken, nil
+}
+
+// parseRSAPrivateKey decodes a PEM encoded PKCS#8 or PKCS#1 RSA key
+func parseRSAPrivateKey(data string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, errors.New("private key is not PEM encoded")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, errors.New("private key is not an RSA key")
+		}
+		return rsaKey, nil
+	}
+	return x509.ParsePKCS1PrivateKey(block.Bytes)
+}
+
+// validateKey rejects empty keys and keys that try to escape their prefix
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "..") {
+		return fmt.Errorf("invalid object key %q", key)
+	}
+	return nil
+}
@@ -0,0 +1,151 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeGCS is a minimal in-memory GCS JSON API endpoint with an OAuth2 token endpoint
+type fakeGCS struct {
+	mu          sync.Mutex
+	objects     map[string][]byte
+	tokenIssued int
+}
+
+func (f *fakeGCS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.URL.Path == "/token" {
+		r.ParseForm()
+		if r.PostForm.Get("grant_type") != "urn:ietf:params:oauth:grant-type:jwt-bearer" || r.PostForm.Get("assertion") == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		f.tokenIssued++
+		json.NewEncoder(w).Encode(map[string]interface{}{"access_token": "token", "expires_in": 3600})
+		return
+	}
+
+	if r.Header.Get("Authorization") != "Bearer token" {
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error":{"code":401,"message":"Invalid Credentials"}}`)
+		return
+	}
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == "/upload/storage/v1/b/bucket/o":
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Query().Get("name")] = data
+		io.WriteString(w, `{}`)
+	case strings.HasPrefix(r.URL.Path, "/storage/v1/b/bucket/o/"):
+		name := strings.TrimPrefix(r.URL.Path, "/storage/v1/b/bucket/o/")
+		data, ok := f.objects[name]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(f.objects, name)
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Query().Get("alt") == "media":
+			w.Write(data)
+		default:
+			io.WriteString(w, `{"name":"`+name+`"}`)
+		}
+	default:
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func gcsCredentials(t *testing.T, tokenURI string) []byte {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+
+	credentials, err := json.Marshal(map[string]string{
+		"type":         "service_account",
+		"client_email": "app@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    tokenURI,
+	})
+	require.NoError(t, err)
+	return credentials
+}
+
+func TestGCSStorage(t *testing.T) {
+	fake := &fakeGCS{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewGCSStorage(GCSConfig{
+		Bucket:          "bucket",
+		CredentialsJSON: gcsCredentials(t, server.URL+"/token"),
+		Endpoint:        server.URL,
+	}, server.Client())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "uploads/a b.txt", strings.NewReader("hello"), 5, "text/plain"))
+	assert.Equal(t, []byte("hello"), fake.objects["uploads/a b.txt"])
+
+	reader, err := store.Get(ctx, "uploads/a b.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "hello", string(data))
+
+	exists, err := store.Exists(ctx, "uploads/a b.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	require.NoError(t, store.Delete(ctx, "uploads/a b.txt"))
+	require.NoError(t, store.Delete(ctx, "uploads/a b.txt"))
+	_, err = store.Get(ctx, "uploads/a b.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// The access token is reused until it nears expiry
+	assert.Equal(t, 1, fake.tokenIssued)
+}
+
+func TestGCSStorage_Presign(t *testing.T) {
+	store, err := NewGCSStorage(GCSConfig{Bucket: "bucket", CredentialsJSON: gcsCredentials(t, "")}, nil)
+	require.NoError(t, err)
+
+	raw, headers, err := store.PresignPut(context.Background(), "uploads/a.png", 42, "image/png", 15*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Content-Length": "42", "Content-Type": "image/png"}, headers)
+
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "storage.googleapis.com", u.Host)
+	assert.Equal(t, "/bucket/uploads/a.png", u.Path)
+	assert.Equal(t, "GOOG4-RSA-SHA256", u.Query().Get("X-Goog-Algorithm"))
+	assert.True(t, strings.HasPrefix(u.Query().Get("X-Goog-Credential"), "app@project.iam.gserviceaccount.com/"))
+	assert.Equal(t, "900", u.Query().Get("X-Goog-Expires"))
+	assert.Equal(t, "content-length;content-type;host", u.Query().Get("X-Goog-SignedHeaders"))
+	assert.Len(t, u.Query().Get("X-Goog-Signature"), 512)
+
+	// Signing needs a service account key
+	anonymous, err := NewGCSStorage(GCSConfig{Bucket: "bucket"}, nil)
+	require.NoError(t, err)
+	_, err = anonymous.PresignGet(context.Background(), "a", time.Minute)
+	assert.Error(t, err)
+}
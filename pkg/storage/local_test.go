@@ -0,0 +1,46 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStorage(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "users/1/file.txt", strings.NewReader("hello"), 5, "text/plain"))
+
+	exists, err := store.Exists(ctx, "users/1/file.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	reader, err := store.Get(ctx, "users/1/file.txt")
+	require.NoError(t, err)
+	content, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "hello", string(content))
+
+	require.NoError(t, store.Delete(ctx, "users/1/file.txt"))
+	_, err = store.Get(ctx, "users/1/file.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Deleting a missing object is not an error
+	assert.NoError(t, store.Delete(ctx, "users/1/file.txt"))
+}
+
+func TestLocalStorage_RejectsEscapingKeys(t *testing.T) {
+	store, err := NewLocalStorage(t.TempDir())
+	require.NoError(t, err)
+
+	for _, key := range []string{"../outside", "a/../../outside", ""} {
+		err := store.Put(context.Background(), key, strings.NewReader("x"), 1, "")
+		assert.Error(t, err, key)
+	}
+}
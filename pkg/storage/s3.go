@@ -0,0 +1,264 @@
+package storage
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// maxPresignExpiry is the longest validity S3 accepts for presigned URLs
+const maxPresignExpiry = 7 * 24 * time.Hour
+
+// S3Config holds the settings of an S3-compatible bucket
+type S3Config struct {
+	Endpoint        string // e.g. https://s3.eu-west-1.amazonaws.com or http://localhost:9000 for MinIO
+	Region          string
+	Bucket          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string // Set for temporary credentials such as those of an assumed IAM role
+	PathStyle       bool   // Address the bucket as endpoint/bucket instead of bucket.endpoint, required by MinIO
+}
+
+// S3Storage stores objects in an S3-compatible bucket
+type S3Storage struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	pathStyle bool
+	signer    *sigV4Signer
+	client    *http.Client
+}
+
+// NewS3Storage creates an S3 storage. A nil client uses a default client with a timeout.
+func NewS3Storage(cfg S3Config, client *http.Client) (*S3Storage, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("s3 bucket is required")
+	}
+	endpoint, err := url.Parse(cfg.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", cfg.Endpoint)
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 5 * time.Minute}
+	}
+
+	return &S3Storage{
+		endpoint:  endpoint,
+		region:    cfg.Region,
+		bucket:    cfg.Bucket,
+		pathStyle: cfg.PathStyle,
+		client:    client,
+		signer: &sigV4Signer{
+			accessKeyID:     cfg.AccessKeyID,
+			secretAccessKey: cfg.SecretAccessKey,
+			sessionToken:    cfg.SessionToken,
+			region:          cfg.Region,
+			service:         "s3",
+		},
+	}, nil
+}
+
+// Put uploads the object in a single request
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if size == 0 {
+		req.Body = http.NoBody
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get opens the object for reading
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.do(req)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the object; S3 treats deleting a missing object as success
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Exists reports whether an object is stored under key
+func (s *S3Storage) Exists(ctx context.Context, key string) (bool, error) {
+	req, err := s.newRequest(ctx, http.MethodHead, key, nil)
+	if err != nil {
+		return false, err
+	}
+	resp, err := s.do(req)
+	if errors.Is(err, ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	resp.Body.Close()
+	return true, nil
+}
+
+// PresignPut returns a URL for uploading the object directly to the bucket.
+// The content length and type are signed, so the upload must match them exactly.
+func (s *S3Storage) PresignPut(ctx context.Context, key string, size int64, contentType string, expires time.Duration) (string, map[string]string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", nil, err
+	}
+	headers := presignHeaders(size, contentType)
+	return s.signer.presign(http.MethodPut, u, headers, clampExpiry(expires), time.Now()), headers, nil
+}
+
+// PresignGet returns a URL for downloading the object directly from the bucket
+func (s *S3Storage) PresignGet(ctx context.Context, key string, expires time.Duration) (string, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return "", err
+	}
+	return s.signer.presign(http.MethodGet, u, nil, clampExpiry(expires), time.Now()), nil
+}
+
+// EnsureBucket creates the bucket if it doesn't exist yet, which is convenient for MinIO in development.
+// Production buckets are usually provisioned separately with their own policies.
+func (s *S3Storage) EnsureBucket(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, s.bucketURL("").String(), nil)
+	if err != nil {
+		return err
+	}
+	resp, err := s.do(req)
+	if err == nil {
+		resp.Body.Close()
+		return nil
+	}
+	if !errors.Is(err, ErrNotFound) {
+		return err
+	}
+
+	// us-east-1 is the default location and must not be sent as a constraint
+	var body io.Reader = http.NoBody
+	if s.region != "" && s.region != "us-east-1" {
+		body = strings.NewReader(`<CreateBucketConfiguration xmlns="http://s3.amazonaws.com/doc/2006-03-01/"><LocationConstraint>` + s.region + `</LocationConstraint></CreateBucketConfiguration>`)
+	}
+	req, err = http.NewRequestWithContext(ctx, http.MethodPut, s.bucketURL("").String(), body)
+	if err != nil {
+		return err
+	}
+	resp, err = s.do(req)
+	if err != nil {
+		return fmt.Errorf("failed to create bucket %q: %w", s.bucket, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// objectURL returns the URL of an object in path-style or virtual-hosted-style addressing
+func (s *S3Storage) objectURL(key string) (*url.URL, error) {
+	key = strings.TrimPrefix(key, "/")
+	if key == "" || strings.Contains(key, "..") {
+		return nil, fmt.Errorf("invalid object key %q", key)
+	}
+	return s.bucketURL(key), nil
+}
+
+// bucketURL returns the URL of a path within the bucket, or of the bucket itself for an empty path
+func (s *S3Storage) bucketURL(key string) *url.URL {
+	u := *s.endpoint
+	basePath := strings.TrimRight(u.Path, "/")
+	if s.pathStyle {
+		u.Path = basePath + "/" + s.bucket
+		if key != "" {
+			u.Path += "/" + key
+		}
+	} else {
+		u.Host = s.bucket + "." + u.Host
+		u.Path = basePath + "/" + key
+	}
+	u.RawPath = ""
+	u.RawQuery = ""
+	return &u
+}
+
+// newRequest builds a request for an object. Payloads are sent unsigned so they can be streamed.
+func (s *S3Storage) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	u, err := s.objectURL(key)
+	if err != nil {
+		return nil, err
+	}
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends a request, converting error responses into errors
+func (s *S3Storage) do(req *http.Request) (*http.Response, error) {
+	payloadHash := unsignedPayload
+	if req.Body == nil || req.Body == http.NoBody {
+		payloadHash = emptyPayloadHash
+	}
+	s.signer.sign(req, payloadHash, time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+
+	var s3Err struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	_ = xml.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&s3Err)
+	if s3Err.Code != "" {
+		return nil, fmt.Errorf("s3 %s %s: %s: %s", req.Method, req.URL.Path, s3Err.Code, s3Err.Message)
+	}
+	return nil, fmt.Errorf("s3 %s %s: unexpected status %d", req.Method, req.URL.Path, resp.StatusCode)
+}
+
+// clampExpiry keeps a presigned URL's validity within what S3 accepts
+func clampExpiry(expires time.Duration) time.Duration {
+	if expires <= 0 || expires > maxPresignExpiry {
+		return maxPresignExpiry
+	}
+	return expires
+}
@@ -0,0 +1,169 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 is a minimal in-memory S3 endpoint that requires signed requests
+type fakeS3 struct {
+	mu      sync.Mutex
+	objects map[string][]byte
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=key/") {
+		w.WriteHeader(http.StatusForbidden)
+		io.WriteString(w, `<Error><Code>AccessDenied</Code><Message>Access Denied</Message></Error>`)
+		return
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		data, _ := io.ReadAll(r.Body)
+		f.objects[r.URL.Path] = data
+	case http.MethodGet, http.MethodHead:
+		data, ok := f.objects[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Method == http.MethodGet {
+			w.Write(data)
+		}
+	case http.MethodDelete:
+		delete(f.objects, r.URL.Path)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func setupS3(t *testing.T) (*S3Storage, *fakeS3) {
+	fake := &fakeS3{objects: make(map[string][]byte)}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	store, err := NewS3Storage(S3Config{
+		Endpoint:        server.URL,
+		Region:          "us-east-1",
+		Bucket:          "bucket",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		PathStyle:       true,
+	}, server.Client())
+	require.NoError(t, err)
+	return store, fake
+}
+
+func TestS3Storage(t *testing.T) {
+	store, fake := setupS3(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.Put(ctx, "users/1/a b.txt", strings.NewReader("hello"), 5, "text/plain"))
+	assert.Equal(t, []byte("hello"), fake.objects["/bucket/users/1/a b.txt"])
+
+	exists, err := store.Exists(ctx, "users/1/a b.txt")
+	require.NoError(t, err)
+	assert.True(t, exists)
+
+	reader, err := store.Get(ctx, "users/1/a b.txt")
+	require.NoError(t, err)
+	data, _ := io.ReadAll(reader)
+	reader.Close()
+	assert.Equal(t, "hello", string(data))
+
+	require.NoError(t, store.Delete(ctx, "users/1/a b.txt"))
+	_, err = store.Get(ctx, "users/1/a b.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+	exists, err = store.Exists(ctx, "users/1/a b.txt")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	// Deleting a missing object is not an error
+	assert.NoError(t, store.Delete(ctx, "missing"))
+}
+
+func TestS3Storage_Errors(t *testing.T) {
+	store, _ := setupS3(t)
+	store.signer.accessKeyID = "other"
+
+	err := store.Put(context.Background(), "a", strings.NewReader("x"), 1, "")
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "AccessDenied")
+
+	_, err = store.Get(context.Background(), "../escape")
+	assert.Error(t, err)
+}
+
+func TestS3Storage_EnsureBucket(t *testing.T) {
+	store, fake := setupS3(t)
+	ctx := context.Background()
+
+	require.NoError(t, store.EnsureBucket(ctx))
+	_, created := fake.objects["/bucket"]
+	assert.True(t, created)
+
+	// Existing buckets are left alone
+	fake.objects["/bucket"] = []byte("existing")
+	require.NoError(t, store.EnsureBucket(ctx))
+	assert.Equal(t, []byte("existing"), fake.objects["/bucket"])
+}
+
+func TestS3Storage_SessionToken(t *testing.T) {
+	store, err := NewS3Storage(S3Config{
+		Endpoint:        "https://s3.amazonaws.com",
+		Region:          "us-east-1",
+		Bucket:          "bucket",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+		SessionToken:    "token",
+	}, nil)
+	require.NoError(t, err)
+
+	raw, err := store.PresignGet(context.Background(), "a", time.Minute)
+	require.NoError(t, err)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "token", u.Query().Get("X-Amz-Security-Token"))
+}
+
+func TestS3Storage_Presign(t *testing.T) {
+	store, err := NewS3Storage(S3Config{
+		Endpoint:        "https://s3.eu-west-1.amazonaws.com",
+		Region:          "eu-west-1",
+		Bucket:          "bucket",
+		AccessKeyID:     "key",
+		SecretAccessKey: "secret",
+	}, nil)
+	require.NoError(t, err)
+
+	raw, headers, err := store.PresignPut(context.Background(), "uploads/a.png", 42, "image/png", 15*time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]string{"Content-Length": "42", "Content-Type": "image/png"}, headers)
+	u, err := url.Parse(raw)
+	require.NoError(t, err)
+
+	// Virtual-hosted style addressing with the size and type bound to the signature
+	assert.Equal(t, "bucket.s3.eu-west-1.amazonaws.com", u.Host)
+	assert.Equal(t, "/uploads/a.png", u.Path)
+	assert.Equal(t, "900", u.Query().Get("X-Amz-Expires"))
+	assert.Equal(t, "content-length;content-type;host", u.Query().Get("X-Amz-SignedHeaders"))
+	assert.NotEmpty(t, u.Query().Get("X-Amz-Signature"))
+
+	raw, err = store.PresignGet(context.Background(), "uploads/a.png", 30*24*time.Hour)
+	require.NoError(t, err)
+	u, err = url.Parse(raw)
+	require.NoError(t, err)
+	assert.Equal(t, "604800", u.Query().Get("X-Amz-Expires"))
+}
@@ -0,0 +1,42 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strconv"
+	"time"
+)
+
+// ErrNotFound is returned when an object does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Storage is a minimal object store used for uploaded files
+type Storage interface {
+	// Put stores the content of r under key, replacing any existing object
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the object stored under key
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the object; deleting a missing object is not an error
+	Delete(ctx context.Context, key string) error
+	// Exists reports whether an object is stored under key
+	Exists(ctx context.Context, key string) (bool, error)
+}
+
+// Presigner is implemented by stores that can hand out URLs for clients to transfer objects directly
+type Presigner interface {
+	// PresignPut returns a URL for uploading exactly size bytes to key with a PUT request,
+	// and the headers the client must send with it unchanged
+	PresignPut(ctx context.Context, key string, size int64, contentType string, expires time.Duration) (string, map[string]string, error)
+	// PresignGet returns a URL for downloading the object stored under key
+	PresignGet(ctx context.Context, key string, expires time.Duration) (string, error)
+}
+
+// presignHeaders returns the headers bound to a presigned upload
+func presignHeaders(size int64, contentType string) map[string]string {
+	headers := map[string]string{"Content-Length": strconv.FormatInt(size, 10)}
+	if contentType != "" {
+		headers["Content-Type"] = contentType
+	}
+	return headers
+}
@@ -0,0 +1,32 @@
+// Package tenant carries the tenant a request belongs to, so that one deployment can serve
+// several isolated tenants.
+package tenant
+
+import (
+	"context"
+	"regexp"
+)
+
+// contextKey is the type of the context key holding the tenant ID
+type contextKey struct{}
+
+// validID matches the tenant IDs accepted from requests, such as a subdomain
+var validID = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,63}$`)
+
+// ValidID reports whether id can be used as a tenant ID
+func ValidID(id string) bool {
+	return validID.MatchString(id)
+}
+
+// WithID returns a copy of ctx scoped to the tenant. The empty ID is the default tenant, which
+// rows created before tenancy was enabled belong to.
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ctx is scoped to. ok is false for contexts not scoped to any
+// tenant, such as those of background jobs, which see the rows of every tenant.
+func FromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(contextKey{}).(string)
+	return id, ok
+}
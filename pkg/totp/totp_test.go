@@ -0,0 +1,66 @@
+package totp
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// RFC 6238 appendix B secret for SHA1
+var rfcSecret = base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestCode_RFC6238Vectors(t *testing.T) {
+	// The RFC lists 8 digit codes; ours are their last 6 digits
+	vectors := map[int64]string{
+		59:          "287082",
+		1111111109:  "081804",
+		1111111111:  "050471",
+		1234567890:  "005924",
+		2000000000:  "279037",
+		20000000000: "353130",
+	}
+	for unix, want := range vectors {
+		got, err := Code(rfcSecret, time.Unix(unix, 0))
+		require.NoError(t, err)
+		assert.Equal(t, want, got, "time %d", unix)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	secret, err := GenerateSecret()
+	require.NoError(t, err)
+	now := time.Unix(1700000000, 0)
+
+	current, err := Code(secret, now)
+	require.NoError(t, err)
+	usedStep, ok := Validate(secret, current, now)
+	assert.True(t, ok)
+	assert.Equal(t, now.Unix()/Period, usedStep)
+
+	// Codes of the neighbouring periods are accepted to allow for clock drift
+	previous, _ := Code(secret, now.Add(-Period*time.Second))
+	_, ok = Validate(secret, previous, now)
+	assert.True(t, ok)
+
+	stale, _ := Code(secret, now.Add(-3*Period*time.Second))
+	_, ok = Validate(secret, stale, now)
+	assert.False(t, ok)
+
+	_, ok = Validate(secret, "12345", now)
+	assert.False(t, ok)
+
+	// Secrets typed by hand may be lowercase and spaced
+	_, ok = Validate(strings.ToLower(secret[:4])+" "+secret[4:], current, now)
+	assert.True(t, ok)
+}
+
+func TestProvisioningURI(t *testing.T) {
+	uri := ProvisioningURI("JBSWY3DPEHPK3PXP", "Example App", "jane@example.com")
+	assert.True(t, strings.HasPrefix(uri, "otpauth://totp/Example%20App:jane@example.com?"))
+	assert.Contains(t, uri, "secret=JBSWY3DPEHPK3PXP")
+	assert.Contains(t, uri, "issuer=Example+App")
+}
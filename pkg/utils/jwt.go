@@ -1,47 +1,238 @@
 package utils
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// JWTIssuer is the iss claim of first-party access tokens
+const JWTIssuer = "gbt-be-template"
+
 // JWTClaims represents the JWT claims
 type JWTClaims struct {
-	UserID  uint   `json:"user_id"`
-	Email   string `json:"email"`
-	IsAdmin bool   `json:"is_admin"`
+	UserID   uint   `json:"user_id"`
+	Email    string `json:"email"`
+	IsAdmin  bool   `json:"is_admin"`
+	ClientID string `json:"client_id,omitempty"` // Set on tokens delegated to OAuth2 clients
+	Scope    string `json:"scope,omitempty"`
+
+	// Set when roles and permissions are put in access tokens. Permissions have the form
+	// resource:action, see PermissionClaim.
+	Roles       []string `json:"roles,omitempty"`
+	Permissions []string `json:"permissions,omitempty"`
+
+	// Set on tokens an admin was issued to act as the user
+	ImpersonatorID uint `json:"impersonator_id,omitempty"`
 	jwt.RegisteredClaims
+
+	// Extra holds custom claims, such as a tenant ID, encoded next to the others at the top level.
+	// Claims of the same name as the fields above are dropped.
+	Extra map[string]interface{} `json:"-"`
+}
+
+// jwtClaimsFields has the fields of JWTClaims, but not its JSON methods
+type jwtClaimsFields JWTClaims
+
+// MarshalJSON encodes the claims with the extra claims merged in
+func (c JWTClaims) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(jwtClaimsFields(c))
+	if err != nil || len(c.Extra) == 0 {
+		return data, err
+	}
+
+	var merged map[string]interface{}
+	if err := json.Unmarshal(data, &merged); err != nil {
+		return nil, err
+	}
+	for name, value := range c.Extra {
+		if !IsReservedClaim(name) {
+			merged[name] = value
+		}
+	}
+	return json.Marshal(merged)
+}
+
+// UnmarshalJSON decodes the claims, collecting unknown ones in Extra
+func (c *JWTClaims) UnmarshalJSON(data []byte) error {
+	var fields jwtClaimsFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	var all map[string]interface{}
+	if err := json.Unmarshal(data, &all); err != nil {
+		return err
+	}
+
+	*c = JWTClaims(fields)
+	c.Extra = nil
+	for name, value := range all {
+		if IsReservedClaim(name) {
+			continue
+		}
+		if c.Extra == nil {
+			c.Extra = make(map[string]interface{})
+		}
+		c.Extra[name] = value
+	}
+	return nil
+}
+
+// reservedClaims are the claims set by this package, which extra claims can't replace
+var reservedClaims = map[string]bool{
+	"user_id": true, "email": true, "is_admin": true, "client_id": true, "scope": true, "impersonator_id": true,
+	"roles": true, "permissions": true,
+	"iss": true, "sub": true, "aud": true, "exp": true, "nbf": true, "iat": true, "jti": true,
+}
+
+// PermissionClaim returns the entry of the permissions claim granting the action on the resource
+func PermissionClaim(resource, action string) string {
+	return resource + ":" + action
+}
+
+// IsReservedClaim reports whether a claim is set by this package and can't be used as an extra claim
+func IsReservedClaim(name string) bool {
+	return reservedClaims[name]
+}
+
+// JWTKey is an HMAC signing key, named in the kid header of the tokens it signs
+type JWTKey struct {
+	ID     string
+	Secret string
+}
+
+// JWTKeys are the keys tokens are signed and validated with. New tokens are signed
+// with Current, while tokens signed with a Previous key are accepted until they
+// expire, so the secret can be rotated without ending every session at once.
+type JWTKeys struct {
+	Current  JWTKey
+	Previous []JWTKey
+}
+
+// verificationKey returns the key for a token's kid header. Tokens without one
+// were issued before key IDs were introduced and are tried against every key.
+func (k JWTKeys) verificationKey(kid string) (interface{}, error) {
+	keys := append([]JWTKey{k.Current}, k.Previous...)
+	if kid == "" {
+		set := jwt.VerificationKeySet{}
+		for _, key := range keys {
+			set.Keys = append(set.Keys, []byte(key.Secret))
+		}
+		return set, nil
+	}
+
+	for _, key := range keys {
+		if key.ID == kid {
+			return []byte(key.Secret), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// sign signs the claims with the key and names it in the kid header
+func sign(claims JWTClaims, key JWTKey) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = key.ID
+	return token.SignedString([]byte(key.Secret))
 }
 
 // GenerateJWT generates a new JWT token
-func GenerateJWT(userID uint, email string, isAdmin bool, secret string, expiry time.Duration) (string, error) {
+func GenerateJWT(userID uint, email string, isAdmin bool, key JWTKey, expiry time.Duration) (string, error) {
+	return GenerateJWTWithClaims(userID, email, isAdmin, nil, key, expiry)
+}
+
+// GenerateJWTWithClaims generates a new JWT token carrying extra custom claims. Extra claims
+// named like the standard ones are dropped.
+func GenerateJWTWithClaims(userID uint, email string, isAdmin bool, extra map[string]interface{}, key JWTKey, expiry time.Duration) (string, error) {
+	return GenerateJWTFromClaims(JWTClaims{UserID: userID, Email: email, IsAdmin: isAdmin, Extra: extra}, key, expiry)
+}
+
+// GenerateJWTFromClaims generates a new JWT token with the user claims of claims, such as roles,
+// and fresh registered claims
+func GenerateJWTFromClaims(claims JWTClaims, key JWTKey, expiry time.Duration) (string, error) {
+	// A unique ID lets the token be revoked before it expires
+	tokenID, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims.RegisteredClaims = jwt.RegisteredClaims{
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+		IssuedAt:  jwt.NewNumericDate(time.Now()),
+		NotBefore: jwt.NewNumericDate(time.Now()),
+		Issuer:    JWTIssuer,
+		Subject:   claims.Email,
+		ID:        tokenID,
+	}
+
+	return sign(claims, key)
+}
+
+// GenerateImpersonationJWT generates an access token letting an admin act as a user. The token
+// names the admin in the impersonator_id claim and never carries admin rights.
+func GenerateImpersonationJWT(userID uint, email string, impersonatorID uint, key JWTKey, expiry time.Duration) (string, error) {
+	tokenID, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
 	claims := JWTClaims{
-		UserID:  userID,
-		Email:   email,
-		IsAdmin: isAdmin,
+		UserID:         userID,
+		Email:          email,
+		ImpersonatorID: impersonatorID,
 		RegisteredClaims: jwt.RegisteredClaims{
 			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
-			Issuer:    "gbt-be-template",
+			Issuer:    JWTIssuer,
 			Subject:   email,
+			ID:        tokenID,
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(secret))
+	return sign(claims, key)
+}
+
+// GenerateClientJWT generates a delegated access token for an OAuth2 client.
+// These tokens never carry admin rights and are scoped to the granted scopes.
+func GenerateClientJWT(userID uint, email, clientID, scope, issuer string, key JWTKey, expiry time.Duration) (string, error) {
+	tokenID, err := GenerateRandomToken(16)
+	if err != nil {
+		return "", err
+	}
+
+	claims := JWTClaims{
+		UserID:   userID,
+		Email:    email,
+		ClientID: clientID,
+		Scope:    scope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(expiry)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+			Issuer:    issuer,
+			Subject:   strconv.FormatUint(uint64(userID), 10),
+			Audience:  jwt.ClaimStrings{clientID},
+			ID:        tokenID,
+		},
+	}
+
+	return sign(claims, key)
 }
 
-// ValidateJWT validates a JWT token and returns the claims
-func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
+// ValidateJWT validates a JWT token against the key named in its kid header and returns the claims
+func ValidateJWT(tokenString string, keys JWTKeys) (*JWTClaims, error) {
 	token, err := jwt.ParseWithClaims(tokenString, &JWTClaims{}, func(token *jwt.Token) (interface{}, error) {
 		// Validate the signing method
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, errors.New("invalid signing method")
 		}
-		return []byte(secret), nil
+		kid, _ := token.Header["kid"].(string)
+		return keys.verificationKey(kid)
 	})
 
 	if err != nil {
@@ -55,13 +246,25 @@ func ValidateJWT(tokenString, secret string) (*JWTClaims, error) {
 	return nil, errors.New("invalid token")
 }
 
-// RefreshJWT generates a new token with extended expiry
-func RefreshJWT(tokenString, secret string, newExpiry time.Duration) (string, error) {
-	claims, err := ValidateJWT(tokenString, secret)
+// RefreshJWT generates a new token with extended expiry, signed with the current key
+func RefreshJWT(tokenString string, keys JWTKeys, newExpiry time.Duration) (string, error) {
+	claims, err := ValidateJWT(tokenString, keys)
 	if err != nil {
 		return "", err
 	}
 
+	// Impersonation must not outlast the token the admin was issued
+	if claims.ImpersonatorID != 0 {
+		return "", errors.New("impersonation tokens can't be refreshed")
+	}
+
 	// Generate new token with same claims but extended expiry
-	return GenerateJWT(claims.UserID, claims.Email, claims.IsAdmin, secret, newExpiry)
+	return GenerateJWTFromClaims(JWTClaims{
+		UserID:      claims.UserID,
+		Email:       claims.Email,
+		IsAdmin:     claims.IsAdmin,
+		Roles:       claims.Roles,
+		Permissions: claims.Permissions,
+		Extra:       claims.Extra,
+	}, keys.Current, newExpiry)
 }
@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateJWT_KeyRotation(t *testing.T) {
+	oldKey := JWTKey{ID: "v1", Secret: "old-secret"}
+	newKey := JWTKey{ID: "v2", Secret: "new-secret"}
+	rotated := JWTKeys{Current: newKey, Previous: []JWTKey{oldKey}}
+
+	oldToken, err := GenerateJWT(1, "user@example.com", false, oldKey, time.Hour)
+	require.NoError(t, err)
+	newToken, err := GenerateJWT(1, "user@example.com", false, newKey, time.Hour)
+	require.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &JWTClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "v2", parsed.Header["kid"])
+
+	// Tokens signed with the previous key stay valid after a rotation
+	claims, err := ValidateJWT(oldToken, rotated)
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), claims.UserID)
+
+	_, err = ValidateJWT(newToken, rotated)
+	require.NoError(t, err)
+
+	// Once the previous key is dropped its tokens are rejected
+	_, err = ValidateJWT(oldToken, JWTKeys{Current: newKey})
+	assert.Error(t, err)
+
+	// A kid naming a key with a different secret does not verify
+	_, err = ValidateJWT(oldToken, JWTKeys{Current: JWTKey{ID: "v1", Secret: "other-secret"}})
+	assert.Error(t, err)
+
+	// Refreshed tokens are signed with the current key
+	refreshed, err := RefreshJWT(oldToken, rotated, time.Hour)
+	require.NoError(t, err)
+	_, err = ValidateJWT(refreshed, JWTKeys{Current: newKey})
+	assert.NoError(t, err)
+}
+
+func TestValidateJWT_WithoutKeyID(t *testing.T) {
+	// Tokens issued before key IDs were introduced carry no kid header
+	claims := JWTClaims{
+		UserID:           1,
+		RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour))},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("old-secret"))
+	require.NoError(t, err)
+
+	rotated := JWTKeys{
+		Current:  JWTKey{ID: "v2", Secret: "new-secret"},
+		Previous: []JWTKey{{ID: "v1", Secret: "old-secret"}},
+	}
+	_, err = ValidateJWT(token, rotated)
+	assert.NoError(t, err)
+
+	_, err = ValidateJWT(token, JWTKeys{Current: JWTKey{ID: "v2", Secret: "new-secret"}})
+	assert.Error(t, err)
+}
+
+func TestGenerateImpersonationJWT(t *testing.T) {
+	keys := JWTKeys{Current: JWTKey{ID: "v1", Secret: "test-secret"}}
+	token, err := GenerateImpersonationJWT(2, "user@example.com", 1, keys.Current, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := ValidateJWT(token, keys)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), claims.UserID)
+	assert.Equal(t, uint(1), claims.ImpersonatorID)
+	assert.False(t, claims.IsAdmin)
+
+	// Refreshing would drop the impersonator and extend the impersonation
+	_, err = RefreshJWT(token, keys, time.Hour)
+	assert.Error(t, err)
+}
+
+func TestGenerateJWTFromClaims(t *testing.T) {
+	keys := JWTKeys{Current: JWTKey{ID: "v1", Secret: "test-secret"}}
+	token, err := GenerateJWTFromClaims(JWTClaims{
+		UserID:      1,
+		Email:       "user@example.com",
+		Roles:       []string{"moderator"},
+		Permissions: []string{PermissionClaim("user", "read")},
+	}, keys.Current, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := ValidateJWT(token, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "user@example.com", claims.Subject)
+	assert.NotEmpty(t, claims.ID)
+	assert.Equal(t, []string{"moderator"}, claims.Roles)
+	assert.Equal(t, []string{"user:read"}, claims.Permissions)
+	assert.Nil(t, claims.Extra)
+
+	// Refreshing keeps the roles and permissions
+	refreshed, err := RefreshJWT(token, keys, time.Hour)
+	require.NoError(t, err)
+	claims, err = ValidateJWT(refreshed, keys)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"moderator"}, claims.Roles)
+	assert.Equal(t, []string{"user:read"}, claims.Permissions)
+}
+
+func TestGenerateJWTWithClaims(t *testing.T) {
+	keys := JWTKeys{Current: JWTKey{ID: "v1", Secret: "test-secret"}}
+	extra := map[string]interface{}{
+		"tenant_id": "acme",
+		"features":  []string{"beta"},
+		"is_admin":  true, // Can't grant admin rights
+		"roles":     []string{"admin"},
+		"exp":       0,
+	}
+	token, err := GenerateJWTWithClaims(1, "user@example.com", false, extra, keys.Current, time.Minute)
+	require.NoError(t, err)
+
+	claims, err := ValidateJWT(token, keys)
+	require.NoError(t, err)
+	assert.False(t, claims.IsAdmin)
+	assert.True(t, claims.ExpiresAt.After(time.Now()))
+	assert.Nil(t, claims.Roles)
+	assert.Equal(t, map[string]interface{}{"tenant_id": "acme", "features": []interface{}{"beta"}}, claims.Extra)
+
+	// Refreshing keeps the extra claims
+	refreshed, err := RefreshJWT(token, keys, time.Hour)
+	require.NoError(t, err)
+	claims, err = ValidateJWT(refreshed, keys)
+	require.NoError(t, err)
+	assert.Equal(t, "acme", claims.Extra["tenant_id"])
+
+	// Tokens without extra claims decode without any
+	plain, err := GenerateJWT(1, "user@example.com", false, keys.Current, time.Minute)
+	require.NoError(t, err)
+	claims, err = ValidateJWT(plain, keys)
+	require.NoError(t, err)
+	assert.Nil(t, claims.Extra)
+}
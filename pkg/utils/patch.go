@@ -0,0 +1,52 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+)
+
+// Patch media types accepted by ApplyPatch
+const (
+	MergePatchContentType = "application/merge-patch+json" // RFC 7396
+	JSONPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+// ErrUnsupportedPatchType is returned for a patch that is neither a merge patch nor a JSON patch
+var ErrUnsupportedPatchType = errors.New("unsupported patch media type")
+
+// ErrPatchTestFailed is returned when a JSON patch test operation does not match the document
+var ErrPatchTestFailed = errors.New("patch test operation failed")
+
+// ApplyPatch applies a merge patch or JSON patch, depending on contentType, to a JSON document
+func ApplyPatch(contentType string, doc, patch []byte) ([]byte, error) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, ErrUnsupportedPatchType
+	}
+
+	switch mediaType {
+	case MergePatchContentType:
+		patched, err := jsonpatch.MergePatch(doc, patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid merge patch: %w", err)
+		}
+		return patched, nil
+	case JSONPatchContentType:
+		ops, err := jsonpatch.DecodePatch(patch)
+		if err != nil {
+			return nil, fmt.Errorf("invalid JSON patch: %w", err)
+		}
+		patched, err := ops.Apply(doc)
+		if errors.Is(err, jsonpatch.ErrTestFailed) {
+			return nil, ErrPatchTestFailed
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply JSON patch: %w", err)
+		}
+		return patched, nil
+	}
+	return nil, ErrUnsupportedPatchType
+}
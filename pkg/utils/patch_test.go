@@ -0,0 +1,45 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyPatch(t *testing.T) {
+	doc := []byte(`{"name":"a","tags":{"x":"1","y":"2"},"phone":"+1"}`)
+
+	t.Run("merge patch", func(t *testing.T) {
+		patched, err := ApplyPatch("application/merge-patch+json; charset=utf-8", doc, []byte(`{"name":"b","phone":null,"tags":{"y":null}}`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"b","tags":{"x":"1"}}`, string(patched))
+	})
+
+	t.Run("JSON patch", func(t *testing.T) {
+		patched, err := ApplyPatch(JSONPatchContentType, doc, []byte(`[{"op":"replace","path":"/name","value":"b"},{"op":"remove","path":"/tags/x"}]`))
+		require.NoError(t, err)
+		assert.JSONEq(t, `{"name":"b","tags":{"y":"2"},"phone":"+1"}`, string(patched))
+	})
+
+	t.Run("failed test operation", func(t *testing.T) {
+		_, err := ApplyPatch(JSONPatchContentType, doc, []byte(`[{"op":"test","path":"/name","value":"z"}]`))
+		assert.ErrorIs(t, err, ErrPatchTestFailed)
+	})
+
+	t.Run("malformed patch", func(t *testing.T) {
+		_, err := ApplyPatch(JSONPatchContentType, doc, []byte(`{"op":"replace"}`))
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrUnsupportedPatchType)
+
+		_, err = ApplyPatch(JSONPatchContentType, doc, []byte(`[{"op":"remove","path":"/missing"}]`))
+		assert.Error(t, err)
+	})
+
+	t.Run("unsupported media type", func(t *testing.T) {
+		for _, contentType := range []string{"", "application/json", "text/plain"} {
+			_, err := ApplyPatch(contentType, doc, []byte(`{}`))
+			assert.ErrorIs(t, err, ErrUnsupportedPatchType, contentType)
+		}
+	})
+}
@@ -2,7 +2,11 @@ package utils
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
 )
 
 // APIResponse represents a standard API response
@@ -42,23 +46,50 @@ func WriteErrorResponse(w http.ResponseWriter, statusCode int, message string, e
 
 // PaginationResponse represents a paginated response
 type PaginationResponse struct {
-	Data       interface{} `json:"data"`
-	Total      int64       `json:"total"`
-	Page       int         `json:"page"`
-	Limit      int         `json:"limit"`
-	TotalPages int         `json:"total_pages"`
+	Data       interface{}     `json:"data"`
+	Total      int64           `json:"total"`
+	Page       int             `json:"page"`
+	Limit      int             `json:"limit"`
+	TotalPages int             `json:"total_pages"`
+	Links      PaginationLinks `json:"links"`
 }
 
-// WritePaginatedResponse writes a paginated JSON response
-func WritePaginatedResponse(w http.ResponseWriter, statusCode int, message string, data interface{}, total int64, page, limit int) {
+// CursorPaginationResponse represents a response paginated with cursors. The cursors are
+// empty at either end of the list.
+type CursorPaginationResponse struct {
+	Data       interface{}     `json:"data"`
+	Limit      int             `json:"limit"`
+	NextCursor string          `json:"next_cursor,omitempty"`
+	PrevCursor string          `json:"prev_cursor,omitempty"`
+	Links      PaginationLinks `json:"links"`
+}
+
+// PaginationLinks are the URLs of neighbouring pages, relative to the host.
+// Prev and Next are empty on the first and last page. Last is empty for cursor pagination.
+type PaginationLinks struct {
+	First string `json:"first"`
+	Prev  string `json:"prev,omitempty"`
+	Next  string `json:"next,omitempty"`
+	Last  string `json:"last,omitempty"`
+}
+
+// WritePaginatedResponse writes a paginated JSON response.
+// Page links keep the other query parameters of r and are also sent as an RFC 8288 Link header.
+func WritePaginatedResponse(w http.ResponseWriter, r *http.Request, statusCode int, message string, data interface{}, total int64, page, limit int) {
 	totalPages := int((total + int64(limit) - 1) / int64(limit))
 	
+	links := buildPaginationLinks(r.URL, page, limit, totalPages)
+	if header := links.header(); header != "" {
+		w.Header().Set("Link", header)
+	}
+
 	pagination := PaginationResponse{
 		Data:       data,
 		Total:      total,
 		Page:       page,
 		Limit:      limit,
 		TotalPages: totalPages,
+		Links:      links,
 	}
 
 	response := APIResponse{
@@ -69,3 +100,86 @@ func WritePaginatedResponse(w http.ResponseWriter, statusCode int, message strin
 	
 	WriteJSONResponse(w, statusCode, response)
 }
+
+// WriteCursorPaginatedResponse writes a JSON response paginated with cursors.
+// Page links keep the other query parameters of r and are also sent as an RFC 8288 Link header.
+func WriteCursorPaginatedResponse(w http.ResponseWriter, r *http.Request, statusCode int, message string, data interface{}, limit int, nextCursor, prevCursor string) {
+	cursorURL := func(cursor string) string {
+		query := r.URL.Query()
+		query.Del("page")
+		query.Set("cursor", cursor)
+		query.Set("limit", strconv.Itoa(limit))
+		return r.URL.EscapedPath() + "?" + query.Encode()
+	}
+
+	links := PaginationLinks{First: cursorURL("")}
+	if prevCursor != "" {
+		links.Prev = cursorURL(prevCursor)
+	}
+	if nextCursor != "" {
+		links.Next = cursorURL(nextCursor)
+	}
+	if header := links.header(); header != "" {
+		w.Header().Set("Link", header)
+	}
+
+	WriteJSONResponse(w, statusCode, APIResponse{
+		Success: true,
+		Message: message,
+		Data: CursorPaginationResponse{
+			Data:       data,
+			Limit:      limit,
+			NextCursor: nextCursor,
+			PrevCursor: prevCursor,
+			Links:      links,
+		},
+	})
+}
+
+// buildPaginationLinks derives the page links from the request URL
+func buildPaginationLinks(u *url.URL, page, limit, totalPages int) PaginationLinks {
+	lastPage := totalPages
+	if lastPage < 1 {
+		lastPage = 1
+	}
+
+	pageURL := func(p int) string {
+		query := u.Query()
+		query.Set("page", strconv.Itoa(p))
+		query.Set("limit", strconv.Itoa(limit))
+		return u.EscapedPath() + "?" + query.Encode()
+	}
+
+	links := PaginationLinks{
+		First: pageURL(1),
+		Last:  pageURL(lastPage),
+	}
+	if page > 1 {
+		// Past the end, prev points back to the last page that exists
+		prev := page - 1
+		if prev > lastPage {
+			prev = lastPage
+		}
+		links.Prev = pageURL(prev)
+	}
+	if page < totalPages {
+		links.Next = pageURL(page + 1)
+	}
+	return links
+}
+
+// header formats the links as an RFC 8288 Link header value
+func (l PaginationLinks) header() string {
+	var parts []string
+	for _, link := range []struct{ rel, url string }{
+		{"first", l.First},
+		{"prev", l.Prev},
+		{"next", l.Next},
+		{"last", l.Last},
+	} {
+		if link.url != "" {
+			parts = append(parts, fmt.Sprintf("<%s>; rel=%q", link.url, link.rel))
+		}
+	}
+	return strings.Join(parts, ", ")
+}
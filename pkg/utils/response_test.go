@@ -0,0 +1,109 @@
+package utils
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWritePaginatedResponse_Links(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		total  int64
+		page   int
+		want   PaginationLinks
+		header string
+	}{
+		{
+			name:   "middle page keeps other query params",
+			target: "/api/v1/users?page=2&limit=10&search=bob",
+			total:  35,
+			page:   2,
+			want: PaginationLinks{
+				First: "/api/v1/users?limit=10&page=1&search=bob",
+				Prev:  "/api/v1/users?limit=10&page=1&search=bob",
+				Next:  "/api/v1/users?limit=10&page=3&search=bob",
+				Last:  "/api/v1/users?limit=10&page=4&search=bob",
+			},
+			header: `</api/v1/users?limit=10&page=1&search=bob>; rel="first", ` +
+				`</api/v1/users?limit=10&page=1&search=bob>; rel="prev", ` +
+				`</api/v1/users?limit=10&page=3&search=bob>; rel="next", ` +
+				`</api/v1/users?limit=10&page=4&search=bob>; rel="last"`,
+		},
+		{
+			name:   "first page has no prev",
+			target: "/api/v1/users",
+			total:  15,
+			page:   1,
+			want: PaginationLinks{
+				First: "/api/v1/users?limit=10&page=1",
+				Next:  "/api/v1/users?limit=10&page=2",
+				Last:  "/api/v1/users?limit=10&page=2",
+			},
+		},
+		{
+			name:   "empty result links to page one",
+			target: "/api/v1/users",
+			total:  0,
+			page:   1,
+			want: PaginationLinks{
+				First: "/api/v1/users?limit=10&page=1",
+				Last:  "/api/v1/users?limit=10&page=1",
+			},
+		},
+		{
+			name:   "past the end points prev at the last page",
+			target: "/api/v1/users?page=9",
+			total:  15,
+			page:   9,
+			want: PaginationLinks{
+				First: "/api/v1/users?limit=10&page=1",
+				Prev:  "/api/v1/users?limit=10&page=2",
+				Last:  "/api/v1/users?limit=10&page=2",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			recorder := httptest.NewRecorder()
+
+			WritePaginatedResponse(recorder, req, http.StatusOK, "ok", []string{}, tt.total, tt.page, 10)
+
+			var body struct {
+				Data PaginationResponse `json:"data"`
+			}
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+			assert.Equal(t, tt.want, body.Data.Links)
+			if tt.header != "" {
+				assert.Equal(t, tt.header, recorder.Header().Get("Link"))
+			}
+		})
+	}
+}
+
+func TestWriteCursorPaginatedResponse(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/users?cursor=abc&limit=20&page=3&search=bob", nil)
+	recorder := httptest.NewRecorder()
+
+	WriteCursorPaginatedResponse(recorder, req, http.StatusOK, "ok", []string{}, 20, "def", "")
+
+	var body struct {
+		Data CursorPaginationResponse `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body))
+	assert.Equal(t, "def", body.Data.NextCursor)
+	assert.Empty(t, body.Data.PrevCursor)
+	assert.Equal(t, PaginationLinks{
+		First: "/api/v1/users?cursor=&limit=20&search=bob",
+		Next:  "/api/v1/users?cursor=def&limit=20&search=bob",
+	}, body.Data.Links)
+	assert.Equal(t, `</api/v1/users?cursor=&limit=20&search=bob>; rel="first", `+
+		`</api/v1/users?cursor=def&limit=20&search=bob>; rel="next"`, recorder.Header().Get("Link"))
+}
@@ -0,0 +1,201 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// defaultFlushEvery is how many records are written between flushes when none is given
+const defaultFlushEvery = 100
+
+// CSVRecord is implemented by records that can be streamed as CSV or XLSX rows
+type CSVRecord interface {
+	CSVRow() []string
+}
+
+// StreamWriter writes records to the response one at a time, flushing periodically
+// so large result sets never have to be buffered in memory.
+type StreamWriter struct {
+	w          http.ResponseWriter
+	flusher    http.Flusher
+	encode     func(record interface{}) error
+	finish     func() error
+	close      func() error
+	flushEvery int
+	written    int
+}
+
+// NewNDJSONStreamWriter starts a newline-delimited JSON stream
+func NewNDJSONStreamWriter(w http.ResponseWriter, flushEvery int) *StreamWriter {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+
+	encoder := json.NewEncoder(w)
+	return newStreamWriter(w, flushEvery, encoder.Encode, nil, nil)
+}
+
+// NewCSVStreamWriter starts a CSV stream with the given header row.
+// Records written to it must implement CSVRecord.
+func NewCSVStreamWriter(w http.ResponseWriter, header []string, flushEvery int) (*StreamWriter, error) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.WriteHeader(http.StatusOK)
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(header); err != nil {
+		return nil, err
+	}
+
+	encode := func(record interface{}) error {
+		row, ok := record.(CSVRecord)
+		if !ok {
+			return errors.New("record does not implement CSVRecord")
+		}
+		return writer.Write(row.CSVRow())
+	}
+	finish := func() error {
+		writer.Flush()
+		return writer.Error()
+	}
+	return newStreamWriter(w, flushEvery, encode, finish, nil), nil
+}
+
+// xlsxParts are the fixed parts of a workbook with a single worksheet, written ahead of it
+var xlsxParts = []struct{ name, content string }{
+	{"[Content_Types].xml", xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`},
+	{"_rels/.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`},
+	{"xl/workbook.xml", xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`},
+	{"xl/_rels/workbook.xml.rels", xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`},
+}
+
+// NewXLSXStreamWriter starts an Excel workbook stream with the given header row. Rows are
+// written to a single worksheet as they come, with every cell as a string. Records written
+// to it must implement CSVRecord, and the workbook is only complete once the stream is closed.
+func NewXLSXStreamWriter(w http.ResponseWriter, header []string, flushEvery int) (*StreamWriter, error) {
+	w.Header().Set("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+	w.WriteHeader(http.StatusOK)
+
+	archive := zip.NewWriter(w)
+	for _, part := range xlsxParts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xml.Header+`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`); err != nil {
+		return nil, err
+	}
+	if err := writeXLSXRow(sheet, header); err != nil {
+		return nil, err
+	}
+
+	encode := func(record interface{}) error {
+		row, ok := record.(CSVRecord)
+		if !ok {
+			return errors.New("record does not implement CSVRecord")
+		}
+		return writeXLSXRow(sheet, row.CSVRow())
+	}
+	closeSheet := func() error {
+		if _, err := io.WriteString(sheet, `</sheetData></worksheet>`); err != nil {
+			return err
+		}
+		return archive.Close()
+	}
+	return newStreamWriter(w, flushEvery, encode, archive.Flush, closeSheet), nil
+}
+
+// writeXLSXRow writes a worksheet row of inline string cells
+func writeXLSXRow(w io.Writer, cells []string) error {
+	var row bytes.Buffer
+	row.WriteString("<row>")
+	for _, cell := range cells {
+		row.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+		if err := xml.EscapeText(&row, []byte(cell)); err != nil {
+			return err
+		}
+		row.WriteString("</t></is></c>")
+	}
+	row.WriteString("</row>")
+	_, err := w.Write(row.Bytes())
+	return err
+}
+
+func newStreamWriter(w http.ResponseWriter, flushEvery int, encode func(interface{}) error, finish, close func() error) *StreamWriter {
+	if flushEvery <= 0 {
+		flushEvery = defaultFlushEvery
+	}
+	flusher, _ := w.(http.Flusher)
+	return &StreamWriter{
+		w:          w,
+		flusher:    flusher,
+		encode:     encode,
+		finish:     finish,
+		close:      close,
+		flushEvery: flushEvery,
+	}
+}
+
+// Write encodes a single record
+func (s *StreamWriter) Write(record interface{}) error {
+	if err := s.encode(record); err != nil {
+		return err
+	}
+	s.written++
+	if s.written%s.flushEvery == 0 {
+		return s.Flush()
+	}
+	return nil
+}
+
+// Flush pushes buffered output to the client
+func (s *StreamWriter) Flush() error {
+	if s.finish != nil {
+		if err := s.finish(); err != nil {
+			return err
+		}
+	}
+	if s.flusher != nil {
+		s.flusher.Flush()
+	}
+	return nil
+}
+
+// Close finishes the stream and flushes any remaining output. The stream must not be written
+// to afterwards.
+func (s *StreamWriter) Close() error {
+	if s.close != nil {
+		if err := s.close(); err != nil {
+			return err
+		}
+	}
+	return s.Flush()
+}
+
+// Written returns the number of records written so far
+func (s *StreamWriter) Written() int {
+	return s.written
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type streamRecord struct {
+	Name string `json:"name"`
+}
+
+func (r streamRecord) CSVRow() []string {
+	return []string{r.Name}
+}
+
+func TestNDJSONStreamWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	stream := NewNDJSONStreamWriter(recorder, 1)
+
+	require.NoError(t, stream.Write(streamRecord{Name: "a"}))
+	assert.True(t, recorder.Flushed)
+	require.NoError(t, stream.Write(streamRecord{Name: "b"}))
+	require.NoError(t, stream.Close())
+
+	assert.Equal(t, "application/x-ndjson", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "{\"name\":\"a\"}\n{\"name\":\"b\"}\n", recorder.Body.String())
+	assert.Equal(t, 2, stream.Written())
+}
+
+func TestCSVStreamWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	stream, err := NewCSVStreamWriter(recorder, []string{"name"}, 10)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Write(streamRecord{Name: "a"}))
+	require.NoError(t, stream.Write(streamRecord{Name: "b,c"}))
+	require.NoError(t, stream.Close())
+
+	assert.Equal(t, "text/csv", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "name\na\n\"b,c\"\n", recorder.Body.String())
+
+	// Records must implement CSVRecord
+	assert.Error(t, stream.Write("not a record"))
+}
+
+func TestXLSXStreamWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	stream, err := NewXLSXStreamWriter(recorder, []string{"name"}, 1)
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Write(streamRecord{Name: "a"}))
+	require.NoError(t, stream.Write(streamRecord{Name: "<b & c>"}))
+	require.NoError(t, stream.Close())
+
+	assert.Equal(t, "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", recorder.Header().Get("Content-Type"))
+
+	body := recorder.Body.Bytes()
+	archive, err := zip.NewReader(bytes.NewReader(body), int64(len(body)))
+	require.NoError(t, err)
+
+	var sheet []byte
+	for _, file := range archive.File {
+		if file.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		rc, err := file.Open()
+		require.NoError(t, err)
+		sheet, err = io.ReadAll(rc)
+		require.NoError(t, err)
+		rc.Close()
+	}
+	assert.Len(t, archive.File, 5)
+	assert.Contains(t, string(sheet), `<row><c t="inlineStr"><is><t xml:space="preserve">name</t></is></c></row>`)
+	assert.Contains(t, string(sheet), `<t xml:space="preserve">&lt;b &amp; c&gt;</t>`)
+	assert.Equal(t, 3, bytes.Count(sheet, []byte("<row>")))
+
+	// Records must implement CSVRecord
+	assert.Error(t, stream.Write("not a record"))
+}
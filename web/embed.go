@@ -0,0 +1,20 @@
+// Package web embeds the frontend build served when static file serving is enabled without a directory.
+// Replace the contents of dist with the output of the frontend build before compiling.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Dist returns the embedded frontend build
+func Dist() fs.FS {
+	sub, err := fs.Sub(dist, "dist")
+	if err != nil {
+		panic(err) // dist is always embedded
+	}
+	return sub
+}